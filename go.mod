@@ -3,16 +3,13 @@ module github.com/javanhut/RavenTerminal
 go 1.24.0
 
 require (
+	github.com/BurntSushi/toml v1.6.0
 	github.com/creack/pty v1.1.24
 	github.com/go-gl/gl v0.0.0-20231021071112-07e5d0ea2e71
 	github.com/go-gl/glfw/v3.3/glfw v0.0.0-20250301202403-da16c1255728
 	github.com/srwiley/oksvg v0.0.0-20221011165216-be6e8873101c
 	github.com/srwiley/rasterx v0.0.0-20220730225603-2ab79fcdd4ef
 	golang.org/x/image v0.18.0
-)
-
-require (
-	github.com/BurntSushi/toml v1.6.0 // indirect
-	golang.org/x/net v0.0.0-20211118161319-6a13c67c3ce4 // indirect
-	golang.org/x/text v0.33.0 // indirect
+	golang.org/x/net v0.0.0-20211118161319-6a13c67c3ce4
+	golang.org/x/text v0.33.0
 )
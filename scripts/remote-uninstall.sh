@@ -31,7 +31,9 @@ USER_APP_DIR="$HOME/.local/share/applications"
 USER_ICON_DIR="$HOME/.local/share/icons/hicolor/scalable/apps"
 USER_PIXMAP_DIR="$HOME/.local/share/pixmaps"
 USER_DATA_DIR="$HOME/.local/share/raven-terminal"
-USER_CONFIG_DIR="$HOME/.config/raven-terminal"
+USER_CONFIG_DIR="${XDG_CONFIG_HOME:-$HOME/.config}/raven-terminal"
+USER_STATE_DIR="${XDG_STATE_HOME:-$HOME/.local/state}/raven-terminal"
+USER_CACHE_DIR="${XDG_CACHE_HOME:-$HOME/.cache}/raven-terminal"
 
 GLOBAL_BIN_DIR="/usr/local/bin"
 GLOBAL_APP_DIR="/usr/share/applications"
@@ -334,7 +336,18 @@ uninstall_global() {
 remove_config() {
     print_info "Removing configuration files..."
 
+    local removed_any=false
     if remove_dir "$USER_CONFIG_DIR" false "Configuration directory"; then
+        removed_any=true
+    fi
+    if remove_dir "$USER_STATE_DIR" false "History and logs"; then
+        removed_any=true
+    fi
+    if remove_dir "$USER_CACHE_DIR" false "Cache directory"; then
+        removed_any=true
+    fi
+
+    if [ "$removed_any" = true ]; then
         print_success "Configuration files removed"
     else
         print_info "No configuration files found"
@@ -31,7 +31,9 @@ USER_BIN_DIR="$HOME/.local/bin"
 USER_APP_DIR="$HOME/.local/share/applications"
 USER_ICON_DIR="$HOME/.local/share/icons/hicolor/scalable/apps"
 USER_PIXMAP_DIR="$HOME/.local/share/pixmaps"
-USER_CONFIG_DIR="$HOME/.config/raven-terminal"
+USER_CONFIG_DIR="${XDG_CONFIG_HOME:-$HOME/.config}/raven-terminal"
+USER_STATE_DIR="${XDG_STATE_HOME:-$HOME/.local/state}/raven-terminal"
+USER_CACHE_DIR="${XDG_CACHE_HOME:-$HOME/.cache}/raven-terminal"
 
 GLOBAL_BIN_DIR="/usr/local/bin"
 GLOBAL_APP_DIR="/usr/share/applications"
@@ -396,6 +398,12 @@ remove_config() {
     if remove_dir "$USER_CONFIG_DIR" false "Config dir"; then
         removed_any=true
     fi
+    if remove_dir "$USER_STATE_DIR" false "History and logs"; then
+        removed_any=true
+    fi
+    if remove_dir "$USER_CACHE_DIR" false "Cache dir"; then
+        removed_any=true
+    fi
 
     if [ "$removed_any" = true ]; then
         print_success "Configuration files removed"
@@ -0,0 +1,56 @@
+package a11y
+
+import (
+	"os/exec"
+	"strings"
+
+	"github.com/javanhut/RavenTerminal/src/logging"
+)
+
+// maxQueued caps pending announcements; output arriving faster than it can be
+// spoken is dropped rather than read minutes late
+const maxQueued = 8
+
+// Speaker hands text to the platform text-to-speech service (speech-dispatcher
+// on Linux, the system voice on macOS) one announcement at a time. It only
+// speaks: nothing is exposed to AT-SPI or NSAccessibility, so screen readers
+// cannot navigate or review the terminal.
+type Speaker struct {
+	queue chan string
+	cmd   string
+}
+
+// NewSpeaker returns a speaker, or nil when no speech service is installed
+func NewSpeaker() *Speaker {
+	path, err := exec.LookPath(speechCommand)
+	if err != nil {
+		return nil
+	}
+	s := &Speaker{queue: make(chan string, maxQueued), cmd: path}
+	go s.run()
+	return s
+}
+
+// Speak queues text to be announced. It never blocks.
+func (s *Speaker) Speak(text string) {
+	if s == nil {
+		return
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return
+	}
+	select {
+	case s.queue <- text:
+	default:
+	}
+}
+
+func (s *Speaker) run() {
+	for text := range s.queue {
+		args := append(append([]string{}, speechArgs...), text)
+		if err := exec.Command(s.cmd, args...).Run(); err != nil {
+			logging.Warnf(logging.App, "Speech failed: %v", err)
+		}
+	}
+}
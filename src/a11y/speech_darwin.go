@@ -0,0 +1,5 @@
+package a11y
+
+const speechCommand = "say"
+
+var speechArgs = []string{"--"}
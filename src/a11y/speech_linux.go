@@ -0,0 +1,7 @@
+package a11y
+
+// speech-dispatcher is the service Orca and other AT-SPI screen readers speak through
+const speechCommand = "spd-say"
+
+// -w waits until the message is spoken so announcements do not cut each other off
+var speechArgs = []string{"-w", "--"}
@@ -0,0 +1,50 @@
+package a11y
+
+import (
+	"math"
+	"strings"
+
+	"github.com/javanhut/RavenTerminal/src/grid"
+)
+
+// maxLines caps how many rows one announcement reads; a burst of output is
+// summarized by its last rows
+const maxLines = 20
+
+// Tracker follows a grid and reports rows completed since the last update
+type Tracker struct {
+	grid *grid.Grid
+	mark int
+}
+
+// Update returns the rows of new output in g. Switching to a different grid
+// starts tracking from its current line without reporting its history.
+func (t *Tracker) Update(g *grid.Grid) string {
+	if g == nil {
+		t.grid = nil
+		return ""
+	}
+	if g != t.grid {
+		t.grid = g
+		_, t.mark = g.LinesSince(math.MaxInt)
+		return ""
+	}
+	lines, mark := g.LinesSince(t.mark)
+	t.mark = mark
+
+	var kept []string
+	for _, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			kept = append(kept, line)
+		}
+	}
+	if len(kept) > maxLines {
+		kept = kept[len(kept)-maxLines:]
+	}
+	return strings.Join(kept, "\n")
+}
+
+// Reset forgets the tracked grid so the next update starts fresh
+func (t *Tracker) Reset() {
+	t.grid = nil
+}
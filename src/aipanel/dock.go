@@ -0,0 +1,115 @@
+package aipanel
+
+import "strings"
+
+// Places the panel can be docked
+const (
+	DockRight  = "right"
+	DockLeft   = "left"
+	DockBottom = "bottom"
+	DockTab    = "tab" // Covers the whole window like a tab of its own
+)
+
+// Docks lists the places in the order CycleDock visits them
+var Docks = []string{DockRight, DockLeft, DockBottom, DockTab}
+
+// Size limits, in percent of the window width, or height when docked at the bottom
+const (
+	DefaultSize = 35
+	MinSize     = 20
+	MaxSize     = 80
+)
+
+// edgeGrab is how close to the inner edge, in pixels, a press starts a resize
+const edgeGrab = 6
+
+// SetGeometry sets where the panel is docked and its size. An unknown dock
+// docks it on the right and a size of 0 uses DefaultSize.
+func (p *Panel) SetGeometry(dock string, size float32) {
+	p.Dock = DockRight
+	for _, d := range Docks {
+		if strings.EqualFold(strings.TrimSpace(dock), d) {
+			p.Dock = d
+			break
+		}
+	}
+	if size <= 0 {
+		size = DefaultSize
+	}
+	p.Size = clampSize(size)
+}
+
+// CycleDock moves the panel to the next dock and returns it
+func (p *Panel) CycleDock() string {
+	next := 0
+	for i, d := range Docks {
+		if d == p.dock() {
+			next = (i + 1) % len(Docks)
+			break
+		}
+	}
+	p.Dock = Docks[next]
+	return p.Dock
+}
+
+// Resize grows the panel by delta percent, or shrinks it when delta is negative
+func (p *Panel) Resize(delta float32) float32 {
+	p.Size = clampSize(p.size() + delta)
+	return p.Size
+}
+
+// ResizeTo sizes the panel so its inner edge follows the mouse at x, y
+func (p *Panel) ResizeTo(x, y float32, width, height int) float32 {
+	switch p.dock() {
+	case DockLeft:
+		p.Size = clampSize((x - 10) * 100 / float32(width))
+	case DockBottom:
+		p.Size = clampSize((float32(height) - 10 - y) * 100 / float32(height))
+	case DockRight:
+		p.Size = clampSize((float32(width) - 10 - x) * 100 / float32(width))
+	}
+	return p.Size
+}
+
+// OnEdge reports whether x, y is on the edge that resizes the panel, the
+// one facing the terminal
+func (p *Panel) OnEdge(layout Layout, x, y float32) bool {
+	near := func(v, edge float32) bool {
+		return v >= edge-edgeGrab && v <= edge+edgeGrab
+	}
+	inX := x >= layout.PanelX && x <= layout.PanelX+layout.PanelWidth
+	inY := y >= layout.PanelY && y <= layout.PanelY+layout.PanelHeight
+	switch p.dock() {
+	case DockLeft:
+		return inY && near(x, layout.PanelX+layout.PanelWidth)
+	case DockBottom:
+		return inX && near(y, layout.PanelY)
+	case DockRight:
+		return inY && near(x, layout.PanelX)
+	}
+	return false
+}
+
+func (p *Panel) dock() string {
+	if p.Dock == "" {
+		return DockRight
+	}
+	return p.Dock
+}
+
+func (p *Panel) size() float32 {
+	if p.Size <= 0 {
+		return DefaultSize
+	}
+	return p.Size
+}
+
+func clampSize(size float32) float32 {
+	if size < MinSize {
+		return MinSize
+	}
+	if size > MaxSize {
+		return MaxSize
+	}
+	return size
+}
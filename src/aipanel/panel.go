@@ -2,6 +2,7 @@ package aipanel
 
 import (
 	"fmt"
+	"hash/fnv"
 	"strings"
 	"time"
 )
@@ -15,6 +16,7 @@ type Message struct {
 	Role     string
 	Content  string
 	Thinking string // Thinking/reasoning content (for thinking models)
+	Command  string // Command an assistant message asked to run
 }
 
 type WrappedLine struct {
@@ -39,17 +41,33 @@ type Panel struct {
 	WasAtBottom  bool // Track if user was at bottom before new content
 	WrapChars    int
 	WrappedLines []WrappedLine
+	wrapHash     uint64 // Hash of what WrappedLines were built from
 	RequestID    int
 	ModelLoaded  bool
 	LoadedURL    string
 	LoadedModel  string
 	LoadingStart time.Time
+	KeepState    bool // Keep the conversation when the panel is closed
 
 	// Thinking model support
 	ShowThinking     bool // Whether to show thinking content
 	ThinkingExpanded bool // Whether thinking sections are expanded
 	ThinkingMode     bool // Whether thinking mode is enabled for requests
 
+	// Personas to choose from; Persona is the conversation's, kept until Reset
+	Personas       []Persona
+	Persona        int
+	DefaultPersona int
+
+	// Where the panel is docked and its size in percent of the window
+	Dock     string
+	Size     float32
+	Resizing bool // The inner edge is being dragged
+
+	// Command the model asked to run, waiting for the user to allow or deny it
+	PendingCommand string
+	ToolRounds     int // Commands requested since the user's last message
+
 	// Multiline input support
 	InputCursorPos int      // Cursor position in input string
 	InputScroll    int      // Scroll offset for input area (in lines)
@@ -87,10 +105,12 @@ func New() *Panel {
 }
 
 func (p *Panel) Toggle() {
-	p.Open = !p.Open
 	if p.Open {
-		p.Focused = true
+		p.Close()
+		return
 	}
+	p.Open = true
+	p.Focused = true
 }
 
 func (p *Panel) SetEnabled(enabled bool) {
@@ -101,8 +121,21 @@ func (p *Panel) SetEnabled(enabled bool) {
 	}
 }
 
-func (p *Panel) Reset() {
-	p.Input = ""
+// Close hides the panel. The conversation is kept for the next time it is
+// opened unless KeepState is off.
+func (p *Panel) Close() {
+	p.Open = false
+	p.SelectionActive = false
+	p.Resizing = false
+	if !p.KeepState {
+		p.Reset()
+	}
+}
+
+// Clear starts a new conversation but keeps the loaded model. A reply still
+// streaming in is dropped.
+func (p *Panel) Clear() {
+	p.ClearInput()
 	p.Status = ""
 	p.Loading = false
 	p.Messages = nil
@@ -110,11 +143,40 @@ func (p *Panel) Reset() {
 	p.AutoScroll = false
 	p.WrapChars = 0
 	p.WrappedLines = nil
+	p.RequestID++
+	p.ThinkingExpanded = false
+	p.Persona = p.DefaultPersona
+	p.PendingCommand = ""
+	p.ToolRounds = 0
+}
+
+func (p *Panel) Reset() {
+	p.Clear()
 	p.RequestID = 0
 	p.ModelLoaded = false
 	p.LoadedURL = ""
 	p.LoadedModel = ""
-	p.ThinkingExpanded = false
+}
+
+// RequestCommand records that the reply asks to run command, attaching it to
+// the reply's message, and waits for the user to allow or deny it
+func (p *Panel) RequestCommand(command string) {
+	if n := len(p.Messages); n > 0 && p.Messages[n-1].Role == "assistant" && p.Messages[n-1].Command == "" {
+		p.Messages[n-1].Command = command
+	} else {
+		p.Messages = append(p.Messages, Message{Role: "assistant", Command: command})
+	}
+	p.PendingCommand = command
+	p.ToolRounds++
+	p.AutoScroll = true
+}
+
+// ResolveCommand adds the result of the requested command, or why it was
+// not run, to the conversation
+func (p *Panel) ResolveCommand(result string) {
+	p.PendingCommand = ""
+	p.Messages = append(p.Messages, Message{Role: "tool", Content: strings.TrimRight(result, "\n")})
+	p.AutoScroll = true
 }
 
 // ToggleThinkingExpanded toggles the expanded state of thinking content
@@ -358,7 +420,6 @@ func (p *Panel) RestoreScrollPosition(visibleLines int) {
 }
 
 func (p *Panel) Layout(width, height int, cellWidth, cellHeight float32) Layout {
-	panelWidth := float32(width) * 0.35
 	minPanelWidth := float32(340)
 	if cellWidth > 0 {
 		wideMin := cellWidth * 32
@@ -366,27 +427,42 @@ func (p *Panel) Layout(width, height int, cellWidth, cellHeight float32) Layout
 			minPanelWidth = wideMin
 		}
 	}
-	if panelWidth < minPanelWidth {
-		panelWidth = minPanelWidth
-	}
-	if panelWidth > 560 {
-		panelWidth = 560
-	}
 	maxWidth := float32(width) - 20
-	if panelWidth > maxWidth {
-		panelWidth = maxWidth
-	}
+	maxHeight := float32(height) - 20
 
-	panelHeight := float32(height) - 30
-	if panelHeight < 240 {
-		panelHeight = 240
-	}
-	if panelHeight > float32(height)-20 {
-		panelHeight = float32(height) - 20
-	}
-
-	panelX := float32(width) - panelWidth - 10
+	panelX := float32(10)
 	panelY := float32(10)
+	panelWidth := maxWidth
+	panelHeight := maxHeight
+	switch p.dock() {
+	case DockLeft, DockRight:
+		panelWidth = float32(width) * p.size() / 100
+		if panelWidth < minPanelWidth {
+			panelWidth = minPanelWidth
+		}
+		if panelWidth > maxWidth {
+			panelWidth = maxWidth
+		}
+		panelHeight = float32(height) - 30
+		if panelHeight < 240 {
+			panelHeight = 240
+		}
+		if panelHeight > maxHeight {
+			panelHeight = maxHeight
+		}
+		if p.dock() == DockRight {
+			panelX = float32(width) - panelWidth - 10
+		}
+	case DockBottom:
+		panelHeight = float32(height) * p.size() / 100
+		if panelHeight < 240 {
+			panelHeight = 240
+		}
+		if panelHeight > maxHeight {
+			panelHeight = maxHeight
+		}
+		panelY = float32(height) - panelHeight - 10
+	}
 
 	lineHeight := cellHeight * 1.35
 	contentX := panelX + 18
@@ -430,6 +506,32 @@ func (p *Panel) Layout(width, height int, cellWidth, cellHeight float32) Layout
 	}
 }
 
+// Wrapped returns the messages wrapped to maxChars, reusing the lines built
+// by the last call while the messages, width and thinking display are the same
+func (p *Panel) Wrapped(maxChars int) []WrappedLine {
+	hash := p.wrapSource()
+	if p.WrappedLines != nil && p.WrapChars == maxChars && p.wrapHash == hash {
+		return p.WrappedLines
+	}
+	p.WrappedLines = BuildWrappedLinesWithThinking(p.Messages, maxChars, p.ShowThinking, p.ThinkingExpanded)
+	p.WrapChars = maxChars
+	p.wrapHash = hash
+	return p.WrappedLines
+}
+
+// wrapSource hashes everything the wrapped lines are built from except the width
+func (p *Panel) wrapSource() uint64 {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%t %t %d\x00", p.ShowThinking, p.ThinkingExpanded, len(p.Messages))
+	for _, m := range p.Messages {
+		for _, field := range []string{m.Role, m.Content, m.Thinking, m.Command} {
+			h.Write([]byte(field))
+			h.Write([]byte{0})
+		}
+	}
+	return h.Sum64()
+}
+
 func BuildWrappedLines(messages []Message, maxChars int) []WrappedLine {
 	return BuildWrappedLinesWithThinking(messages, maxChars, true, false)
 }
@@ -444,6 +546,8 @@ func BuildWrappedLinesWithThinking(messages []Message, maxChars int, showThinkin
 			prefix = "You: "
 		} else if role == "error" {
 			prefix = "Error: "
+		} else if role == "tool" {
+			prefix = "Output: "
 		} else if role != "" && role != "assistant" {
 			prefix = role + ": "
 		}
@@ -460,7 +564,11 @@ func BuildWrappedLinesWithThinking(messages []Message, maxChars int, showThinkin
 
 		// Split content by lines first to handle code blocks
 		contentLines := strings.Split(message.Content, "\n")
-		inCode := false
+		if message.Content == "" {
+			contentLines = nil
+		}
+		// Command output is shown as it is, like a code block
+		inCode := role == "tool"
 		isFirstLine := true
 
 		for _, contentLine := range contentLines {
@@ -573,6 +681,16 @@ func BuildWrappedLinesWithThinking(messages []Message, maxChars int, showThinkin
 			}
 		}
 
+		if message.Command != "" {
+			linePrefix := indent
+			if isFirstLine {
+				linePrefix = prefix
+			}
+			for _, wline := range wrapText("Run: $ "+message.Command, maxChars, linePrefix, indent) {
+				lines = append(lines, WrappedLine{Role: role, Text: wline, InCode: true})
+			}
+		}
+
 		if i < len(messages)-1 {
 			lines = append(lines, WrappedLine{Role: "", Text: ""})
 		}
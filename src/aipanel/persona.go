@@ -0,0 +1,74 @@
+package aipanel
+
+import "strings"
+
+// Persona is a named system prompt that sets how the assistant answers
+type Persona struct {
+	Name   string
+	Prompt string // Sent as the system message; empty sends none
+}
+
+// DefaultPersonas are always available. Personas from the config are added
+// after them and replace same-named ones.
+var DefaultPersonas = []Persona{
+	{Name: "Default"},
+	{Name: "Terse sysadmin", Prompt: "You are a senior Unix system administrator. Answer with the exact commands or config needed and at most one short sentence of explanation. Do not repeat the question."},
+	{Name: "Explain like I'm new", Prompt: "You are a patient teacher helping someone new to the command line. Explain each step in plain words, say what every command and flag does, and warn before anything destructive."},
+}
+
+// SetPersonas sets the personas to choose from and the one a new
+// conversation starts with. An unknown defaultName selects the first.
+func (p *Panel) SetPersonas(custom []Persona, defaultName string) {
+	personas := append([]Persona(nil), DefaultPersonas...)
+	for _, persona := range custom {
+		if strings.TrimSpace(persona.Name) == "" {
+			continue
+		}
+		replaced := false
+		for i := range personas {
+			if strings.EqualFold(personas[i].Name, persona.Name) {
+				personas[i] = persona
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			personas = append(personas, persona)
+		}
+	}
+	current := p.CurrentPersona().Name
+	p.Personas = personas
+	p.DefaultPersona = 0
+	for i, persona := range personas {
+		if strings.EqualFold(persona.Name, defaultName) {
+			p.DefaultPersona = i
+			break
+		}
+	}
+	// Keep the conversation's persona across config reloads when it still exists
+	p.Persona = p.DefaultPersona
+	if len(p.Messages) > 0 {
+		for i, persona := range personas {
+			if persona.Name == current {
+				p.Persona = i
+				break
+			}
+		}
+	}
+}
+
+// CurrentPersona returns the persona of the current conversation
+func (p *Panel) CurrentPersona() Persona {
+	if p.Persona < 0 || p.Persona >= len(p.Personas) {
+		return Persona{Name: "Default"}
+	}
+	return p.Personas[p.Persona]
+}
+
+// CyclePersona selects the next persona, or the previous one when delta is negative
+func (p *Panel) CyclePersona(delta int) Persona {
+	if len(p.Personas) > 0 {
+		p.Persona = ((p.Persona+delta)%len(p.Personas) + len(p.Personas)) % len(p.Personas)
+	}
+	return p.CurrentPersona()
+}
@@ -0,0 +1,234 @@
+package aitools
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/javanhut/RavenTerminal/src/ollama"
+)
+
+// ToolName is the name of the command tool offered to the model
+const ToolName = "run_command"
+
+// MaxOutput caps how many bytes of a command's output go back to the model
+const MaxOutput = 16 * 1024
+
+// Timeout is how long a command may run before it is killed
+const Timeout = 10 * time.Second
+
+// DefaultAllowed lists the read-only commands the model may request. An
+// entry with several words, such as "git status", must match the start of
+// the command word for word.
+var DefaultAllowed = []string{
+	"ls", "cat", "head", "tail", "wc", "pwd", "stat", "du", "df", "uname",
+	"whoami", "id", "which", "grep", "find", "ps",
+	"git status", "git log", "git diff", "git show",
+}
+
+// forbiddenArgs turn otherwise read-only commands into ones that write files
+// or run other programs
+var forbiddenArgs = []string{
+	"-delete", "-exec", "-execdir", "-ok", "-okdir", "-fprint", "-fprint0", "-fprintf", "-fls",
+	"--output", "--ext-diff", "--textconv", "--open-files-in-pager",
+}
+
+// RunCommandTool returns the tool definition offered to the model
+func RunCommandTool(allowed []string) ollama.Tool {
+	return ollama.Tool{
+		Type: "function",
+		Function: ollama.ToolFunction{
+			Name: ToolName,
+			Description: "Run a read-only command in the user's current directory and return its output. " +
+				"The user must approve every call. No shell is used, so pipes, redirects and variables do not work. " +
+				"Allowed commands: " + strings.Join(allowed, ", ") + ".",
+			Parameters: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"command": map[string]any{
+						"type":        "string",
+						"description": "The command line, such as \"ls -la\" or \"git status\"",
+					},
+				},
+				"required": []string{"command"},
+			},
+		},
+	}
+}
+
+// Call returns the tool call that requests command, for replaying a
+// conversation to the model
+func Call(command string) ollama.ToolCall {
+	return ollama.ToolCall{Function: ollama.ToolCallFunction{
+		Name:      ToolName,
+		Arguments: map[string]any{"command": command},
+	}}
+}
+
+// CommandOf returns the command line a tool call asks for
+func CommandOf(call ollama.ToolCall) (string, error) {
+	if call.Function.Name != ToolName {
+		return "", fmt.Errorf("unknown tool %q", call.Function.Name)
+	}
+	command, _ := call.Function.Arguments["command"].(string)
+	command = strings.TrimSpace(command)
+	if command == "" {
+		return "", errors.New("no command given")
+	}
+	return command, nil
+}
+
+// Parse splits a command line into words and checks it against allowed.
+// Single and double quotes group words; nothing else is interpreted. Git
+// commands come back with the options of gitOptions added.
+func Parse(command string, allowed []string) ([]string, error) {
+	argv, err := splitWords(command)
+	if err != nil {
+		return nil, err
+	}
+	if len(argv) == 0 {
+		return nil, errors.New("empty command")
+	}
+	if !isAllowed(argv, allowed) {
+		return nil, fmt.Errorf("%q is not an allowed command", command)
+	}
+	for _, arg := range argv[1:] {
+		for _, bad := range forbiddenArgs {
+			if arg == bad || strings.HasPrefix(arg, bad+"=") {
+				return nil, fmt.Errorf("%s is not allowed", bad)
+			}
+		}
+	}
+	return gitOptions(argv), nil
+}
+
+// gitOptions adds options to a git command that stop it running programs a
+// repository's config names: a pager, an fsmonitor hook, external diff
+// drivers and textconv filters
+func gitOptions(argv []string) []string {
+	if argv[0] != "git" || len(argv) < 2 {
+		return argv
+	}
+	out := []string{"git", "-c", "core.fsmonitor=", "--no-pager", argv[1]}
+	switch argv[1] {
+	case "diff", "log", "show":
+		out = append(out, "--no-ext-diff", "--no-textconv")
+	}
+	return append(out, argv[2:]...)
+}
+
+func isAllowed(argv []string, allowed []string) bool {
+	for _, entry := range allowed {
+		words := strings.Fields(entry)
+		if len(words) == 0 || len(words) > len(argv) {
+			continue
+		}
+		match := true
+		for i, word := range words {
+			if argv[i] != word {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+func splitWords(command string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	for _, r := range command {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, errors.New("unterminated quote")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// Run executes argv in dir without a shell, with no input, a minimal
+// environment and a time limit. It returns the combined output, cut at
+// MaxOutput, followed by the exit status when the command failed.
+func Run(ctx context.Context, argv []string, dir string) string {
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Dir = dir
+	cmd.Env = []string{
+		"PATH=" + os.Getenv("PATH"),
+		"HOME=" + os.Getenv("HOME"),
+		"LANG=" + os.Getenv("LANG"),
+		"TERM=dumb",
+		"PAGER=cat",
+		"GIT_PAGER=cat",
+		"GIT_TERMINAL_PROMPT=0",
+	}
+	var out bytes.Buffer
+	cmd.Stdout = &limitedWriter{buf: &out, limit: MaxOutput}
+	cmd.Stderr = cmd.Stdout
+	err := cmd.Run()
+
+	text := strings.TrimRight(out.String(), "\n")
+	if out.Len() >= MaxOutput {
+		text += "\n[output truncated]"
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		text += fmt.Sprintf("\n[killed after %s]", Timeout)
+	} else if err != nil {
+		text += "\n[" + err.Error() + "]"
+	}
+	if strings.TrimSpace(text) == "" {
+		text = "(no output)"
+	}
+	return text
+}
+
+// limitedWriter keeps the first limit bytes and discards the rest
+type limitedWriter struct {
+	buf   *bytes.Buffer
+	limit int
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	if room := w.limit - w.buf.Len(); room > 0 {
+		if len(p) > room {
+			w.buf.Write(p[:room])
+		} else {
+			w.buf.Write(p)
+		}
+	}
+	return len(p), nil
+}
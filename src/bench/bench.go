@@ -0,0 +1,217 @@
+package bench
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/javanhut/RavenTerminal/src/parser"
+)
+
+// Flag runs the throughput benchmark instead of opening a shell
+const Flag = "--bench"
+
+// WorkloadSize is how much output each workload generates
+const WorkloadSize = 4 << 20
+
+// chunkSize is how much output is parsed at a time, the size of a PTY read
+const chunkSize = 4096
+
+// frameInterval is how often a frame is drawn while output is parsed, as
+// the main loop does at 60 frames a second
+const frameInterval = time.Second / 60
+
+// Workload is a named stream of synthetic program output
+type Workload struct {
+	Name string
+	Data []byte
+}
+
+// Result is how one workload ran
+type Result struct {
+	Name    string
+	Bytes   int
+	Elapsed time.Duration   // Parsing and drawing together
+	Frames  []time.Duration // How long each frame took to draw
+}
+
+// MBps returns the throughput in megabytes a second
+func (r Result) MBps() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Bytes) / 1e6 / r.Elapsed.Seconds()
+}
+
+// FrameTime returns the frame time at percentile p (0-100)
+func (r Result) FrameTime(p float64) time.Duration {
+	if len(r.Frames) == 0 {
+		return 0
+	}
+	sorted := slices.Clone(r.Frames)
+	slices.Sort(sorted)
+	i := int(p / 100 * float64(len(sorted)-1))
+	return sorted[i]
+}
+
+// Workloads returns the benchmark workloads, each about size bytes, for a
+// screen cols wide and rows high
+func Workloads(cols, rows, size int) []Workload {
+	return []Workload{
+		{"plain", repeat(size, plainText(cols))},
+		{"sgr", repeat(size, sgrText(cols))},
+		{"scroll", repeat(size, scrollText())},
+		{"alt-screen", altScreen(cols, rows, size)},
+	}
+}
+
+// Run parses each workload on a fresh terminal in PTY-sized reads, calling
+// draw with the terminal every frameInterval as the main loop would. draw
+// may be nil to measure parsing alone.
+func Run(cols, rows int, workloads []Workload, draw func(*parser.Terminal)) []Result {
+	results := make([]Result, 0, len(workloads))
+	for _, w := range workloads {
+		term := parser.NewTerminal(cols, rows)
+		term.SetResponseWriter(func([]byte) {})
+		result := Result{Name: w.Name, Bytes: len(w.Data)}
+
+		frame := func() {
+			if draw == nil {
+				return
+			}
+			start := time.Now()
+			draw(term)
+			result.Frames = append(result.Frames, time.Since(start))
+		}
+
+		start := time.Now()
+		lastFrame := start
+		for data := w.Data; len(data) > 0; {
+			n := min(chunkSize, len(data))
+			term.Process(data[:n])
+			data = data[n:]
+			if time.Since(lastFrame) >= frameInterval {
+				frame()
+				lastFrame = time.Now()
+			}
+		}
+		// The last output is always drawn, as it would be on screen
+		frame()
+		result.Elapsed = time.Since(start)
+		results = append(results, result)
+	}
+	return results
+}
+
+// Report writes a line per result with its throughput and frame times
+func Report(w io.Writer, cols, rows int, results []Result) {
+	width := len("total")
+	for _, r := range results {
+		width = max(width, len(r.Name))
+	}
+	fmt.Fprintf(w, "benchmark (%dx%d):\n", cols, rows)
+	var total Result
+	for _, r := range results {
+		fmt.Fprintf(w, "  %-*s %8.1f MB/s  %5d frames  p50 %6.2f ms  p95 %6.2f ms  max %6.2f ms\n",
+			width, r.Name, r.MBps(), len(r.Frames),
+			milliseconds(r.FrameTime(50)), milliseconds(r.FrameTime(95)), milliseconds(r.FrameTime(100)))
+		total.Bytes += r.Bytes
+		total.Elapsed += r.Elapsed
+		total.Frames = append(total.Frames, r.Frames...)
+	}
+	fmt.Fprintf(w, "  %-*s %8.1f MB/s  %5d frames  p50 %6.2f ms  p95 %6.2f ms  max %6.2f ms\n",
+		width, "total", total.MBps(), len(total.Frames),
+		milliseconds(total.FrameTime(50)), milliseconds(total.FrameTime(95)), milliseconds(total.FrameTime(100)))
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// repeat repeats unit until it is at least size bytes long
+func repeat(size int, unit string) []byte {
+	if unit == "" {
+		return nil
+	}
+	return []byte(strings.Repeat(unit, size/len(unit)+1))
+}
+
+// words is the text the workloads are made of
+var words = strings.Fields("the quick brown fox jumps over the lazy dog while " +
+	"func main return error nil struct interface package import const " +
+	"lorem ipsum dolor sit amet consectetur adipiscing elit sed do eiusmod")
+
+// plainText is lines of words like a source file, some longer than the screen
+func plainText(cols int) string {
+	var b strings.Builder
+	for i := range words {
+		length := 0
+		for j := 0; length < cols*(i%3+1)/2; j++ {
+			word := words[(i+j)%len(words)]
+			b.WriteString(word + " ")
+			length += len(word) + 1
+		}
+		b.WriteString("\r\n")
+	}
+	return b.String()
+}
+
+// sgrText colors every word, like syntax highlighted or ls --color output
+func sgrText(cols int) string {
+	var b strings.Builder
+	for i := range words {
+		length := 0
+		for j := 0; length < cols-10; j++ {
+			word := words[(i+j)%len(words)]
+			switch j % 4 {
+			case 0:
+				fmt.Fprintf(&b, "\x1b[38;5;%dm", (i*7+j)%256)
+			case 1:
+				fmt.Fprintf(&b, "\x1b[1;38;2;%d;%d;%dm", i*13%256, j*29%256, (i+j)*7%256)
+			case 2:
+				fmt.Fprintf(&b, "\x1b[3;48;5;%dm", (i+j)%256)
+			case 3:
+				b.WriteString("\x1b[0;4m")
+			}
+			b.WriteString(word + " ")
+			length += len(word) + 1
+		}
+		b.WriteString("\x1b[0m\r\n")
+	}
+	return b.String()
+}
+
+// scrollText is short numbered lines, like seq, so nearly every read scrolls
+func scrollText() string {
+	var b strings.Builder
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&b, "%d\r\n", 100000+i)
+	}
+	return b.String()
+}
+
+// altScreen redraws a full screen application on the alternate screen, like
+// top or an editor: positioned rows, a highlighted status bar and erases
+func altScreen(cols, rows, size int) []byte {
+	var b strings.Builder
+	b.WriteString("\x1b[?1049h\x1b[?25l")
+	for frame := 0; b.Len() < size; frame++ {
+		b.WriteString("\x1b[H")
+		for row := 1; row < rows; row++ {
+			fmt.Fprintf(&b, "\x1b[%d;1H\x1b[38;5;%dm%5d\x1b[0m ", row, (frame+row)%256, frame+row)
+			width := max(cols-8, 0)
+			line := words[(frame+row)%len(words)]
+			for len(line) < width {
+				line += " " + words[(frame*row+len(line))%len(words)]
+			}
+			b.WriteString(line[:width])
+			b.WriteString("\x1b[K")
+		}
+		status := fmt.Sprintf(" frame %d ", frame)
+		fmt.Fprintf(&b, "\x1b[%d;1H\x1b[7m%s%s\x1b[0m", rows, status, strings.Repeat(" ", max(cols-len(status), 0)))
+	}
+	b.WriteString("\x1b[?25h\x1b[?1049l")
+	return []byte(b.String())
+}
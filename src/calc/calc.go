@@ -0,0 +1,285 @@
+package calc
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Eval evaluates an arithmetic expression such as "2^10 / 3", or a unit
+// conversion such as "5 km to mi" or "72 F in C", and returns the result
+// formatted for typing back into a shell. ans refers to the previous result.
+func Eval(input string, ans float64) (string, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return "", errors.New("empty expression")
+	}
+	if value, from, to, ok := splitConversion(input); ok {
+		return convert(value, from, to, ans)
+	}
+	v, err := evaluate(input, ans)
+	if err != nil {
+		return "", err
+	}
+	return Format(v), nil
+}
+
+// Format prints v with up to 12 significant digits, which hides the rounding
+// noise of binary floating point such as 0.1+0.2
+func Format(v float64) string {
+	if v == 0 {
+		// Avoid printing -0
+		return "0"
+	}
+	return strconv.FormatFloat(v, 'g', 12, 64)
+}
+
+// evaluate parses and computes a whole expression
+func evaluate(input string, ans float64) (float64, error) {
+	p := &parser{input: input, ans: ans}
+	v, err := p.expr()
+	if err != nil {
+		return 0, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.input) {
+		return 0, fmt.Errorf("unexpected %q", p.input[p.pos:])
+	}
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, errors.New("result is not a finite number")
+	}
+	return v, nil
+}
+
+// parser is a recursive descent parser over the grammar
+//
+//	expr   = term { ("+" | "-") term }
+//	term   = unary { ("*" | "/" | "%") unary }
+//	unary  = ("-" | "+") unary | power
+//	power  = atom [ ("^" | "**") unary ]
+//	atom   = number | name | name "(" expr ")" | "(" expr ")"
+type parser struct {
+	input string
+	pos   int
+	ans   float64
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.input) && p.input[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+// accept consumes op if it comes next
+func (p *parser) accept(op string) bool {
+	p.skipSpace()
+	if strings.HasPrefix(p.input[p.pos:], op) {
+		p.pos += len(op)
+		return true
+	}
+	return false
+}
+
+func (p *parser) expr() (float64, error) {
+	v, err := p.term()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		switch {
+		case p.accept("+"):
+			rhs, err := p.term()
+			if err != nil {
+				return 0, err
+			}
+			v += rhs
+		case p.accept("-"):
+			rhs, err := p.term()
+			if err != nil {
+				return 0, err
+			}
+			v -= rhs
+		default:
+			return v, nil
+		}
+	}
+}
+
+func (p *parser) term() (float64, error) {
+	v, err := p.unary()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		p.skipSpace()
+		// "**" is power, not two multiplications
+		if strings.HasPrefix(p.input[p.pos:], "**") {
+			return v, nil
+		}
+		switch {
+		case p.accept("*"):
+			rhs, err := p.unary()
+			if err != nil {
+				return 0, err
+			}
+			v *= rhs
+		case p.accept("/"):
+			rhs, err := p.unary()
+			if err != nil {
+				return 0, err
+			}
+			if rhs == 0 {
+				return 0, errors.New("division by zero")
+			}
+			v /= rhs
+		case p.accept("%"):
+			rhs, err := p.unary()
+			if err != nil {
+				return 0, err
+			}
+			if rhs == 0 {
+				return 0, errors.New("division by zero")
+			}
+			v = math.Mod(v, rhs)
+		default:
+			return v, nil
+		}
+	}
+}
+
+func (p *parser) unary() (float64, error) {
+	if p.accept("-") {
+		v, err := p.unary()
+		return -v, err
+	}
+	if p.accept("+") {
+		return p.unary()
+	}
+	return p.power()
+}
+
+func (p *parser) power() (float64, error) {
+	base, err := p.atom()
+	if err != nil {
+		return 0, err
+	}
+	if p.accept("^") || p.accept("**") {
+		// Right associative: 2^3^2 is 2^9
+		exp, err := p.unary()
+		if err != nil {
+			return 0, err
+		}
+		return math.Pow(base, exp), nil
+	}
+	return base, nil
+}
+
+func (p *parser) atom() (float64, error) {
+	p.skipSpace()
+	if p.pos >= len(p.input) {
+		return 0, errors.New("unexpected end of expression")
+	}
+	c := p.input[p.pos]
+	switch {
+	case c == '(':
+		p.pos++
+		v, err := p.expr()
+		if err != nil {
+			return 0, err
+		}
+		if !p.accept(")") {
+			return 0, errors.New("missing )")
+		}
+		return v, nil
+	case c >= '0' && c <= '9' || c == '.':
+		return p.number()
+	case unicode.IsLetter(rune(c)):
+		return p.name()
+	}
+	return 0, fmt.Errorf("unexpected %q", string(c))
+}
+
+// number reads a decimal number with an optional exponent, or a 0x, 0o or
+// 0b integer. Underscores may group digits.
+func (p *parser) number() (float64, error) {
+	start := p.pos
+	for p.pos < len(p.input) {
+		c := p.input[p.pos]
+		isExpSign := (c == '+' || c == '-') && p.pos > start && (p.input[p.pos-1] == 'e' || p.input[p.pos-1] == 'E') &&
+			!strings.HasPrefix(strings.ToLower(p.input[start:]), "0x")
+		if c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '.' || c == '_' || isExpSign {
+			p.pos++
+			continue
+		}
+		break
+	}
+	text := strings.ReplaceAll(p.input[start:p.pos], "_", "")
+	lower := strings.ToLower(text)
+	if strings.HasPrefix(lower, "0x") || strings.HasPrefix(lower, "0o") || strings.HasPrefix(lower, "0b") {
+		n, err := strconv.ParseInt(text, 0, 64)
+		if err != nil {
+			return 0, fmt.Errorf("bad number %q", text)
+		}
+		return float64(n), nil
+	}
+	v, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return 0, fmt.Errorf("bad number %q", text)
+	}
+	return v, nil
+}
+
+// functions are the one-argument functions expressions may call
+var functions = map[string]func(float64) float64{
+	"sqrt":  math.Sqrt,
+	"cbrt":  math.Cbrt,
+	"abs":   math.Abs,
+	"ln":    math.Log,
+	"log":   math.Log10,
+	"log2":  math.Log2,
+	"exp":   math.Exp,
+	"sin":   math.Sin,
+	"cos":   math.Cos,
+	"tan":   math.Tan,
+	"asin":  math.Asin,
+	"acos":  math.Acos,
+	"atan":  math.Atan,
+	"floor": math.Floor,
+	"ceil":  math.Ceil,
+	"round": math.Round,
+}
+
+// name reads a constant, ans, or a function call
+func (p *parser) name() (float64, error) {
+	start := p.pos
+	for p.pos < len(p.input) && (unicode.IsLetter(rune(p.input[p.pos])) || p.input[p.pos] >= '0' && p.input[p.pos] <= '9') {
+		p.pos++
+	}
+	name := strings.ToLower(p.input[start:p.pos])
+	switch name {
+	case "pi":
+		return math.Pi, nil
+	case "e":
+		return math.E, nil
+	case "ans":
+		return p.ans, nil
+	}
+	fn, ok := functions[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown name %q", name)
+	}
+	if !p.accept("(") {
+		return 0, fmt.Errorf("%s needs an argument in parentheses", name)
+	}
+	v, err := p.expr()
+	if err != nil {
+		return 0, err
+	}
+	if !p.accept(")") {
+		return 0, errors.New("missing )")
+	}
+	return fn(v), nil
+}
@@ -0,0 +1,188 @@
+package calc
+
+import "strconv"
+
+// maxHistory caps how many evaluated expressions the panel remembers
+const maxHistory = 50
+
+// Entry is an expression evaluated earlier and its result
+type Entry struct {
+	Expr   string
+	Result string
+}
+
+// Panel is the calculator overlay. The result is recomputed as the
+// expression is typed.
+type Panel struct {
+	Open    bool
+	Focused bool
+	Input   string
+	Result  string
+	Err     string
+	History []Entry // Newest first
+	Recall  int     // History entry shown in the input while browsing with Up/Down; -1 when typing
+	ans     float64
+}
+
+type Layout struct {
+	PanelX       float32
+	PanelY       float32
+	PanelWidth   float32
+	PanelHeight  float32
+	ContentX     float32
+	ContentWidth float32
+	LineHeight   float32
+	HeaderY      float32
+	InputBoxY    float32
+	ResultY      float32
+	ListStart    float32
+	ListEnd      float32
+	FooterY      float32
+	VisibleLines int
+}
+
+func NewPanel() *Panel {
+	return &Panel{Recall: -1}
+}
+
+// Toggle opens the panel with input as the expression, or closes it
+func (p *Panel) Toggle(input string) {
+	p.Open = !p.Open
+	if p.Open {
+		p.Focused = true
+		p.SetInput(input)
+	}
+}
+
+// SetInput replaces the expression and evaluates it
+func (p *Panel) SetInput(input string) {
+	p.Input = input
+	p.Recall = -1
+	p.evaluate()
+}
+
+func (p *Panel) AppendInput(r rune) {
+	p.SetInput(p.Input + string(r))
+}
+
+func (p *Panel) Backspace() {
+	if p.Input == "" {
+		return
+	}
+	runes := []rune(p.Input)
+	p.SetInput(string(runes[:len(runes)-1]))
+}
+
+// evaluate recomputes the result; an empty expression shows nothing
+func (p *Panel) evaluate() {
+	p.Result, p.Err = "", ""
+	if p.Input == "" {
+		return
+	}
+	result, err := Eval(p.Input, p.ans)
+	if err != nil {
+		p.Err = err.Error()
+		return
+	}
+	p.Result = result
+}
+
+// Commit records the current expression in the history and makes its
+// result the value of ans. It returns the result, or false if the
+// expression has none.
+func (p *Panel) Commit() (string, bool) {
+	if p.Result == "" {
+		return "", false
+	}
+	if v, err := strconv.ParseFloat(p.Result, 64); err == nil {
+		p.ans = v
+	}
+	if len(p.History) == 0 || p.History[0].Expr != p.Input {
+		p.History = append([]Entry{{Expr: p.Input, Result: p.Result}}, p.History...)
+		if len(p.History) > maxHistory {
+			p.History = p.History[:maxHistory]
+		}
+	}
+	return p.Result, true
+}
+
+// RecallHistory moves through earlier expressions: delta 1 goes back in
+// time and -1 forward, returning to an empty input past the newest
+func (p *Panel) RecallHistory(delta int) {
+	if len(p.History) == 0 {
+		return
+	}
+	recall := p.Recall + delta
+	if recall >= len(p.History) {
+		recall = len(p.History) - 1
+	}
+	if recall < 0 {
+		p.SetInput("")
+		return
+	}
+	p.Input = p.History[recall].Expr
+	p.evaluate()
+	p.Recall = recall
+}
+
+func (p *Panel) Layout(width, height int, cellWidth, cellHeight float32) Layout {
+	panelWidth := float32(width) * 0.4
+	minPanelWidth := float32(380)
+	if cellWidth > 0 {
+		wideMin := cellWidth * 40
+		if wideMin > minPanelWidth {
+			minPanelWidth = wideMin
+		}
+	}
+	if panelWidth < minPanelWidth {
+		panelWidth = minPanelWidth
+	}
+	if panelWidth > 700 {
+		panelWidth = 700
+	}
+	maxWidth := float32(width) - 20
+	if panelWidth > maxWidth {
+		panelWidth = maxWidth
+	}
+
+	lineHeight := cellHeight * 1.35
+	panelHeight := lineHeight * 14
+	if panelHeight > float32(height)-40 {
+		panelHeight = float32(height) - 40
+	}
+
+	// Centered near the top like a command palette
+	panelX := (float32(width) - panelWidth) / 2
+	panelY := float32(height) * 0.12
+
+	contentX := panelX + 18
+	contentWidth := panelWidth - 36
+	headerY := panelY + lineHeight*1.2
+	inputBoxY := headerY + lineHeight*0.6
+	resultY := inputBoxY + lineHeight*2.2
+	listStart := resultY + lineHeight*1.6
+	footerY := panelY + panelHeight - lineHeight*0.6
+	listEnd := footerY - lineHeight*1.2
+
+	visibleLines := int((listEnd - listStart) / lineHeight)
+	if visibleLines < 1 {
+		visibleLines = 1
+	}
+
+	return Layout{
+		PanelX:       panelX,
+		PanelY:       panelY,
+		PanelWidth:   panelWidth,
+		PanelHeight:  panelHeight,
+		ContentX:     contentX,
+		ContentWidth: contentWidth,
+		LineHeight:   lineHeight,
+		HeaderY:      headerY,
+		InputBoxY:    inputBoxY,
+		ResultY:      resultY,
+		ListStart:    listStart,
+		ListEnd:      listEnd,
+		FooterY:      footerY,
+		VisibleLines: visibleLines,
+	}
+}
@@ -0,0 +1,141 @@
+package calc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// unit is a unit of measure: how many of the base unit of its kind one of
+// it is. Temperatures are converted separately as they have offsets.
+type unit struct {
+	kind   string
+	factor float64
+}
+
+// units maps lowercase unit names to their kind and size. Data sizes use
+// decimal prefixes for kb, mb, ... and binary ones for kib, mib, ...
+var units = map[string]unit{
+	// Length, in meters
+	"mm": {"length", 0.001}, "cm": {"length", 0.01}, "m": {"length", 1}, "km": {"length", 1000},
+	"in": {"length", 0.0254}, "ft": {"length", 0.3048}, "yd": {"length", 0.9144}, "mi": {"length", 1609.344},
+	"nmi": {"length", 1852},
+	// Mass, in grams
+	"mg": {"mass", 0.001}, "g": {"mass", 1}, "kg": {"mass", 1000}, "t": {"mass", 1e6},
+	"oz": {"mass", 28.349523125}, "lb": {"mass", 453.59237}, "st": {"mass", 6350.29318},
+	// Time, in seconds
+	"ns": {"time", 1e-9}, "us": {"time", 1e-6}, "ms": {"time", 0.001}, "s": {"time", 1},
+	"min": {"time", 60}, "h": {"time", 3600}, "d": {"time", 86400}, "wk": {"time", 604800},
+	// Data, in bytes
+	"bit": {"data", 0.125}, "b": {"data", 1},
+	"kb": {"data", 1e3}, "mb": {"data", 1e6}, "gb": {"data", 1e9}, "tb": {"data", 1e12}, "pb": {"data", 1e15},
+	"kib": {"data", 1 << 10}, "mib": {"data", 1 << 20}, "gib": {"data", 1 << 30}, "tib": {"data", 1 << 40}, "pib": {"data", 1 << 50},
+	// Volume, in liters
+	"ml": {"volume", 0.001}, "l": {"volume", 1}, "gal": {"volume", 3.785411784}, "qt": {"volume", 0.946352946},
+	"cup": {"volume", 0.2365882365}, "floz": {"volume", 0.0295735295625},
+	// Speed, in meters per second
+	"m/s": {"speed", 1}, "km/h": {"speed", 1 / 3.6}, "kph": {"speed", 1 / 3.6}, "mph": {"speed", 0.44704}, "kn": {"speed", 0.514444},
+	// Angle, in radians
+	"rad": {"angle", 1}, "deg": {"angle", 0.017453292519943295},
+}
+
+// unitAliases maps other spellings to the names in units
+var unitAliases = map[string]string{
+	"meter": "m", "meters": "m", "metre": "m", "metres": "m", "kilometer": "km", "kilometers": "km",
+	"inch": "in", "inches": "in", "foot": "ft", "feet": "ft", "yard": "yd", "yards": "yd", "mile": "mi", "miles": "mi",
+	"gram": "g", "grams": "g", "kilogram": "kg", "kilograms": "kg", "pound": "lb", "pounds": "lb", "lbs": "lb", "ounce": "oz", "ounces": "oz",
+	"sec": "s", "second": "s", "seconds": "s", "minute": "min", "minutes": "min", "mins": "min",
+	"hr": "h", "hour": "h", "hours": "h", "day": "d", "days": "d", "week": "wk", "weeks": "wk",
+	"byte": "b", "bytes": "b", "bits": "bit",
+	"liter": "l", "liters": "l", "litre": "l", "litres": "l", "gallon": "gal", "gallons": "gal",
+	"degree": "deg", "degrees": "deg",
+	"c": "°c", "celsius": "°c", "f": "°f", "fahrenheit": "°f", "k": "°k", "kelvin": "°k",
+}
+
+// splitConversion splits "<expression> <unit> to|in <unit>" into its parts
+func splitConversion(input string) (expr, from, to string, ok bool) {
+	lower := strings.ToLower(input)
+	at := -1
+	sep := ""
+	for _, s := range []string{" to ", " in "} {
+		if i := strings.LastIndex(lower, s); i > at {
+			at, sep = i, s
+		}
+	}
+	if at < 0 {
+		return "", "", "", false
+	}
+	to = strings.TrimSpace(input[at+len(sep):])
+	left := strings.TrimSpace(input[:at])
+	// The source unit is the last word on the left, which may be attached
+	// to the number as in "5km"
+	i := len(left)
+	for i > 0 && !isUnitBoundary(left[i-1]) {
+		i--
+	}
+	expr, from = strings.TrimSpace(left[:i]), left[i:]
+	if expr == "" || from == "" || to == "" {
+		return "", "", "", false
+	}
+	return expr, from, to, true
+}
+
+// isUnitBoundary reports whether c ends the expression before a unit name
+func isUnitBoundary(c byte) bool {
+	return c == ' ' || c == ')' || c >= '0' && c <= '9' || c == '.'
+}
+
+// lookupUnit returns the canonical name of a unit
+func lookupUnit(name string) string {
+	name = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), "°"))
+	if alias, ok := unitAliases[name]; ok {
+		return alias
+	}
+	return name
+}
+
+// convert evaluates expr and converts the result between units
+func convert(expr, from, to string, ans float64) (string, error) {
+	v, err := evaluate(expr, ans)
+	if err != nil {
+		return "", err
+	}
+	fromName, toName := lookupUnit(from), lookupUnit(to)
+	if strings.HasPrefix(fromName, "°") || strings.HasPrefix(toName, "°") {
+		if !strings.HasPrefix(fromName, "°") || !strings.HasPrefix(toName, "°") {
+			return "", fmt.Errorf("cannot convert %s to %s", from, to)
+		}
+		return Format(fromKelvin(toKelvin(v, fromName), toName)), nil
+	}
+	fromUnit, ok := units[fromName]
+	if !ok {
+		return "", fmt.Errorf("unknown unit %q", from)
+	}
+	toUnit, ok := units[toName]
+	if !ok {
+		return "", fmt.Errorf("unknown unit %q", to)
+	}
+	if fromUnit.kind != toUnit.kind {
+		return "", fmt.Errorf("cannot convert %s (%s) to %s (%s)", from, fromUnit.kind, to, toUnit.kind)
+	}
+	return Format(v * fromUnit.factor / toUnit.factor), nil
+}
+
+func toKelvin(v float64, scale string) float64 {
+	switch scale {
+	case "°c":
+		return v + 273.15
+	case "°f":
+		return (v-32)*5/9 + 273.15
+	}
+	return v
+}
+
+func fromKelvin(v float64, scale string) float64 {
+	switch scale {
+	case "°c":
+		return v - 273.15
+	case "°f":
+		return (v-273.15)*9/5 + 32
+	}
+	return v
+}
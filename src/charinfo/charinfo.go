@@ -0,0 +1,92 @@
+package charinfo
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/runenames"
+	"golang.org/x/text/width"
+)
+
+// categories are the Unicode general categories shown for a character,
+// most specific first
+var categories = []struct {
+	name  string
+	table *unicode.RangeTable
+}{
+	{"Lu", unicode.Lu}, {"Ll", unicode.Ll}, {"Lt", unicode.Lt}, {"Lm", unicode.Lm}, {"Lo", unicode.Lo},
+	{"Mn", unicode.Mn}, {"Mc", unicode.Mc}, {"Me", unicode.Me},
+	{"Nd", unicode.Nd}, {"Nl", unicode.Nl}, {"No", unicode.No},
+	{"Pc", unicode.Pc}, {"Pd", unicode.Pd}, {"Ps", unicode.Ps}, {"Pe", unicode.Pe},
+	{"Pi", unicode.Pi}, {"Pf", unicode.Pf}, {"Po", unicode.Po},
+	{"Sm", unicode.Sm}, {"Sc", unicode.Sc}, {"Sk", unicode.Sk}, {"So", unicode.So},
+	{"Zs", unicode.Zs}, {"Zl", unicode.Zl}, {"Zp", unicode.Zp},
+	{"Cc", unicode.Cc}, {"Cf", unicode.Cf}, {"Co", unicode.Co}, {"Cs", unicode.Cs},
+}
+
+// Describe returns a few lines about the character in a cell: its code
+// point and name, its UTF-8 bytes, and how wide it is on screen. cells is
+// the width the terminal gave it.
+func Describe(r rune, cells int) []string {
+	name := runenames.Name(r)
+	if name == "" {
+		name = "<unassigned>"
+	}
+	shown := string(r)
+	if !unicode.IsPrint(r) {
+		shown = " "
+	}
+
+	buf := make([]byte, utf8.UTFMax)
+	n := utf8.EncodeRune(buf, r)
+	hexBytes := make([]string, n)
+	for i, b := range buf[:n] {
+		hexBytes[i] = fmt.Sprintf("%02X", b)
+	}
+
+	lines := []string{
+		fmt.Sprintf("U+%04X %s  %s", r, shown, name),
+		fmt.Sprintf("UTF-8: %s  Decimal: %d  Category: %s", strings.Join(hexBytes, " "), r, category(r)),
+		fmt.Sprintf("Width: %d cell%s (%s)", cells, plural(cells), eastAsianWidth(r)),
+	}
+	if r == utf8.RuneError {
+		lines = append(lines, "Output that was not valid UTF-8 is shown as this")
+	}
+	return lines
+}
+
+// category returns the two-letter Unicode general category of r
+func category(r rune) string {
+	for _, c := range categories {
+		if unicode.Is(c.table, r) {
+			return c.name
+		}
+	}
+	return "Cn"
+}
+
+// eastAsianWidth names the East Asian Width property terminals size characters by
+func eastAsianWidth(r rune) string {
+	switch width.LookupRune(r).Kind() {
+	case width.EastAsianWide:
+		return "wide"
+	case width.EastAsianFullwidth:
+		return "fullwidth"
+	case width.EastAsianHalfwidth:
+		return "halfwidth"
+	case width.EastAsianAmbiguous:
+		return "ambiguous"
+	case width.EastAsianNarrow:
+		return "narrow"
+	}
+	return "neutral"
+}
+
+func plural(n int) string {
+	if n == 1 {
+		return ""
+	}
+	return "s"
+}
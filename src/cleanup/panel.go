@@ -0,0 +1,193 @@
+package cleanup
+
+import (
+	"time"
+
+	"github.com/javanhut/RavenTerminal/src/procmon"
+	"github.com/javanhut/RavenTerminal/src/tab"
+)
+
+// Entry is a pane that can be closed: its shell exited, or it has been idle
+type Entry struct {
+	TabIndex  int
+	PaneIndex int
+	Tab       *tab.Tab
+	Pane      *tab.Pane
+	Exited    bool
+	Idle      time.Duration // Time since the last input or output
+	Dir       string
+}
+
+// Scan finds panes whose shell exited while other panes keep their tab open,
+// and panes idle for at least idleAfter whose shell runs nothing. trees holds
+// the process tree of each pane's shell by PID; a pane missing from it is
+// treated as running nothing. idleAfter 0 skips idle detection. Tabs whose
+// panes all exited are closed by the tab manager and are not listed.
+func Scan(tabs []*tab.Tab, trees map[int][]procmon.Process, idleAfter time.Duration, now time.Time) []Entry {
+	var entries []Entry
+	for ti, t := range tabs {
+		panes := t.GetPanes()
+		for pi, pane := range panes {
+			entry := Entry{TabIndex: ti, PaneIndex: pi, Tab: t, Pane: pane, Idle: now.Sub(pane.LastActive())}
+			switch {
+			case pane.HasExited():
+				if len(panes) < 2 {
+					continue
+				}
+				entry.Exited = true
+			case idleAfter > 0 && entry.Idle >= idleAfter && len(trees[pane.PID()]) <= 1:
+				entry.Dir = pane.CurrentDir()
+			default:
+				continue
+			}
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+type Panel struct {
+	Open     bool
+	Focused  bool
+	Entries  []Entry
+	Selected int
+	Scroll   int
+	Status   string
+}
+
+type Layout struct {
+	PanelX       float32
+	PanelY       float32
+	PanelWidth   float32
+	PanelHeight  float32
+	ContentX     float32
+	ContentWidth float32
+	LineHeight   float32
+	HeaderY      float32
+	StatusY      float32
+	ListStart    float32
+	ListEnd      float32
+	FooterY      float32
+	VisibleLines int
+}
+
+func NewPanel() *Panel {
+	return &Panel{}
+}
+
+func (p *Panel) Toggle() {
+	p.Open = !p.Open
+	if p.Open {
+		p.Focused = true
+		p.Status = ""
+		p.Selected = 0
+		p.Scroll = 0
+	}
+}
+
+// SetEntries replaces the pane list, keeping the selection on the same pane when possible
+func (p *Panel) SetEntries(entries []Entry) {
+	var selected *tab.Pane
+	if entry, ok := p.SelectedEntry(); ok {
+		selected = entry.Pane
+	}
+
+	p.Entries = entries
+	p.Selected = 0
+	for i, entry := range entries {
+		if entry.Pane == selected {
+			p.Selected = i
+			break
+		}
+	}
+}
+
+// SelectedEntry returns the currently selected pane
+func (p *Panel) SelectedEntry() (Entry, bool) {
+	if p.Selected < 0 || p.Selected >= len(p.Entries) {
+		return Entry{}, false
+	}
+	return p.Entries[p.Selected], true
+}
+
+func (p *Panel) MoveSelection(delta int, visibleLines int) {
+	if len(p.Entries) == 0 {
+		return
+	}
+	p.Selected += delta
+	if p.Selected < 0 {
+		p.Selected = 0
+	}
+	if p.Selected >= len(p.Entries) {
+		p.Selected = len(p.Entries) - 1
+	}
+	if visibleLines <= 0 {
+		return
+	}
+	if p.Selected < p.Scroll {
+		p.Scroll = p.Selected
+	}
+	if p.Selected >= p.Scroll+visibleLines {
+		p.Scroll = p.Selected - visibleLines + 1
+	}
+}
+
+func (p *Panel) Layout(width, height int, cellWidth, cellHeight float32) Layout {
+	panelWidth := float32(width) * 0.5
+	minPanelWidth := float32(420)
+	if cellWidth > 0 {
+		wideMin := cellWidth * 48
+		if wideMin > minPanelWidth {
+			minPanelWidth = wideMin
+		}
+	}
+	if panelWidth < minPanelWidth {
+		panelWidth = minPanelWidth
+	}
+	if panelWidth > 820 {
+		panelWidth = 820
+	}
+	maxWidth := float32(width) - 20
+	if panelWidth > maxWidth {
+		panelWidth = maxWidth
+	}
+
+	lineHeight := cellHeight * 1.35
+	panelHeight := lineHeight * 16
+	if panelHeight > float32(height)-40 {
+		panelHeight = float32(height) - 40
+	}
+
+	// Centered near the top like a dialog
+	panelX := (float32(width) - panelWidth) / 2
+	panelY := float32(height) * 0.12
+
+	contentX := panelX + 18
+	contentWidth := panelWidth - 36
+	headerY := panelY + lineHeight*1.2
+	statusY := headerY + lineHeight*1.2
+	listStart := statusY + lineHeight*1.4
+	footerY := panelY + panelHeight - lineHeight*0.6
+	listEnd := footerY - lineHeight*1.2
+
+	visibleLines := int((listEnd - listStart) / lineHeight)
+	if visibleLines < 1 {
+		visibleLines = 1
+	}
+
+	return Layout{
+		PanelX:       panelX,
+		PanelY:       panelY,
+		PanelWidth:   panelWidth,
+		PanelHeight:  panelHeight,
+		ContentX:     contentX,
+		ContentWidth: contentWidth,
+		LineHeight:   lineHeight,
+		HeaderY:      headerY,
+		StatusY:      statusY,
+		ListStart:    listStart,
+		ListEnd:      listEnd,
+		FooterY:      footerY,
+		VisibleLines: visibleLines,
+	}
+}
@@ -0,0 +1,77 @@
+package clipboard
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+
+	"github.com/javanhut/RavenTerminal/src/logging"
+)
+
+// Targets accepted by [terminal] copy_target
+const (
+	TargetClipboard = "clipboard" // The clipboard Ctrl+V pastes from
+	TargetPrimary   = "primary"   // The X11/Wayland primary selection middle-click pastes from
+	TargetBoth      = "both"
+)
+
+// Write copies text to target. GLFW cannot set the primary selection, so it
+// is handed to wl-copy, xclip or xsel; where none is installed, or the
+// platform has no primary selection, the clipboard is used instead. Write
+// must be called on the main thread.
+func Write(target, text string) {
+	switch strings.ToLower(strings.TrimSpace(target)) {
+	case TargetPrimary:
+		if !writePrimary(text) {
+			glfw.SetClipboardString(text)
+		}
+	case TargetBoth:
+		glfw.SetClipboardString(text)
+		writePrimary(text)
+	default:
+		glfw.SetClipboardString(text)
+	}
+}
+
+// writePrimary starts setting the primary selection and reports whether a
+// tool to do it was found
+func writePrimary(text string) bool {
+	name, args := primaryCommand()
+	if name == "" {
+		logging.Warnf(logging.App, "Copying to the primary selection needs wl-copy, xclip or xsel; using the clipboard")
+		return false
+	}
+	// The tools keep serving the selection in the background; do not wait
+	// on the main thread for them to hand it over
+	go func() {
+		cmd := exec.Command(name, args...)
+		cmd.Stdin = strings.NewReader(text)
+		if err := cmd.Run(); err != nil {
+			logging.Warnf(logging.App, "Failed to set the primary selection with %s: %v", name, err)
+		}
+	}()
+	return true
+}
+
+// primaryCommand returns the installed tool that sets the primary selection
+func primaryCommand() (string, []string) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "freebsd" && runtime.GOOS != "openbsd" {
+		return "", nil
+	}
+	candidates := [][]string{
+		{"xclip", "-selection", "primary"},
+		{"xsel", "--primary", "--input"},
+	}
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		candidates = append([][]string{{"wl-copy", "--primary"}}, candidates...)
+	}
+	for _, c := range candidates {
+		if path, err := exec.LookPath(c[0]); err == nil {
+			return path, c[1:]
+		}
+	}
+	return "", nil
+}
@@ -3,6 +3,11 @@ package commands
 import (
 	"fmt"
 	"github.com/javanhut/RavenTerminal/src/assets/fonts"
+	"github.com/javanhut/RavenTerminal/src/calc"
+	"github.com/javanhut/RavenTerminal/src/integration"
+	"github.com/javanhut/RavenTerminal/src/logging"
+	"os"
+	"strconv"
 	"strings"
 )
 
@@ -19,11 +24,252 @@ type FontChanger interface {
 	GetAvailableFonts() []fonts.FontInfo
 }
 
+// PaneController runs pane commands against the active tab
+type PaneController interface {
+	// DiffPanes opens a diff of two panes. Panes are numbered from 1; 0 for
+	// both compares the active pane with the next one.
+	DiffPanes(first, second int) (string, error)
+	// PipePane streams the active pane's output to target, or stops an
+	// active pipe when target is empty
+	PipePane(target string) (string, error)
+	// SendText types text followed by Enter into a pane of the active tab
+	SendText(pane int, text string) (string, error)
+	// SendFile pastes a file's contents into a pane of the active tab
+	SendFile(pane int, path string) (string, error)
+	// WatchPane re-runs command in the active pane whenever files under
+	// paths change, or stops watching when command is empty
+	WatchPane(paths []string, command string) (string, error)
+	// HighlightPane adds pattern to the active pane's highlight watches, or
+	// removes it when remove is set; an empty pattern lists them, or with
+	// remove clears them all
+	HighlightPane(pattern string, remove bool) (string, error)
+	// CheckUpdates opens the update panel and looks for a new release
+	CheckUpdates() (string, error)
+	// CleanupPanes opens a dialog listing exited and idle panes to close
+	CleanupPanes() (string, error)
+	// InspectPane opens or closes the escape sequence inspector for the active pane
+	InspectPane() (string, error)
+	// RewindPane replays the active pane's recent output so it can be stepped through
+	RewindPane() (string, error)
+	// ToggleTimestamps shows or hides the active pane's timestamp gutter
+	ToggleTimestamps() (string, error)
+	// ToggleLineNumbers shows or hides the active pane's line number gutter
+	ToggleLineNumbers() (string, error)
+	// RunInSplit runs command in a new pane split off the active one
+	RunInSplit(command string) (string, error)
+	// OpenCalculator opens or closes the calculator overlay
+	OpenCalculator() (string, error)
+	// ToggleArtMode turns art mode on or off for the active pane
+	ToggleArtMode() (string, error)
+	// GroupTab puts the active tab in the named group, or takes it out of its
+	// group when name is empty
+	GroupTab(name string) (string, error)
+	// ListTabGroups describes the tab groups and their tabs
+	ListTabGroups() (string, error)
+	// CollapseTabGroup folds a group to one line in the tab bar, or unfolds
+	// it; an empty name means the active tab's group
+	CollapseTabGroup(name string, collapse bool) (string, error)
+	// CloseTabGroup closes every tab in a group; an empty name means the
+	// active tab's group
+	CloseTabGroup(name string) (string, error)
+	// RespawnPane kills and restarts the shell, or the command, in the active
+	// pane, keeping its scrollback
+	RespawnPane() (string, error)
+	// RunCustomCommand runs the [[commands]] entry called name, or lists
+	// them when name is empty
+	RunCustomCommand(name string) (string, error)
+}
+
 // HandleCommand checks if input is a terminal command and handles it
 // Returns (handled, output) - if handled is true, don't send to shell
-func HandleCommand(input string, fontChanger FontChanger) CommandResult {
+func HandleCommand(input string, fontChanger FontChanger, panes PaneController) CommandResult {
 	input = strings.TrimSpace(input)
 
+	// Check for raven-diff command
+	if fields := strings.Fields(input); len(fields) > 0 && fields[0] == "raven-diff" {
+		return handleDiff(fields[1:], panes)
+	}
+
+	// Check for raven-send and raven-send-file commands
+	if fields := strings.Fields(input); len(fields) > 0 && (fields[0] == "raven-send" || fields[0] == "raven-send-file") {
+		return handleSend(fields[0], strings.TrimSpace(strings.TrimPrefix(input, fields[0])), panes)
+	}
+
+	// Check for raven-watch command
+	if input == "raven-watch" || strings.HasPrefix(input, "raven-watch ") {
+		return handleWatch(strings.TrimSpace(strings.TrimPrefix(input, "raven-watch")), panes)
+	}
+
+	// Check for raven-highlight command
+	if input == "raven-highlight" || strings.HasPrefix(input, "raven-highlight ") {
+		return handleHighlight(strings.TrimSpace(strings.TrimPrefix(input, "raven-highlight")), panes)
+	}
+
+	// Check for raven-update command
+	if input == "raven-update" {
+		message, err := panes.CheckUpdates()
+		if err != nil {
+			return CommandResult{
+				Handled: true,
+				Output:  fmt.Sprintf("\nError: %v\n\n", err),
+			}
+		}
+		return CommandResult{
+			Handled: true,
+			Output:  "\n" + message + "\n\n",
+		}
+	}
+
+	// Check for raven-cleanup command
+	if input == "raven-cleanup" {
+		message, err := panes.CleanupPanes()
+		if err != nil {
+			return CommandResult{
+				Handled: true,
+				Output:  fmt.Sprintf("\nError: %v\n\n", err),
+			}
+		}
+		return CommandResult{
+			Handled: true,
+			Output:  "\n" + message + "\n\n",
+		}
+	}
+
+	// Check for raven-inspect command
+	if input == "raven-inspect" {
+		message, err := panes.InspectPane()
+		if err != nil {
+			return CommandResult{
+				Handled: true,
+				Output:  fmt.Sprintf("\nError: %v\n\n", err),
+			}
+		}
+		return CommandResult{
+			Handled: true,
+			Output:  "\n" + message + "\n\n",
+		}
+	}
+
+	// Check for raven-rewind command
+	if input == "raven-rewind" {
+		message, err := panes.RewindPane()
+		if err != nil {
+			return CommandResult{
+				Handled: true,
+				Output:  fmt.Sprintf("\nError: %v\n\n", err),
+			}
+		}
+		return CommandResult{
+			Handled: true,
+			Output:  "\n" + message + "\n\n",
+		}
+	}
+
+	// Check for raven-respawn command
+	if input == "raven-respawn" {
+		message, err := panes.RespawnPane()
+		if err != nil {
+			return CommandResult{
+				Handled: true,
+				Output:  fmt.Sprintf("\nError: %v\n\n", err),
+			}
+		}
+		return CommandResult{
+			Handled: true,
+			Output:  "\n" + message + "\n\n",
+		}
+	}
+
+	// Check for raven-cmd command
+	if input == "raven-cmd" || strings.HasPrefix(input, "raven-cmd ") {
+		message, err := panes.RunCustomCommand(strings.TrimSpace(strings.TrimPrefix(input, "raven-cmd")))
+		if err != nil {
+			return CommandResult{
+				Handled: true,
+				Output:  fmt.Sprintf("\nError: %v\n\n", err),
+			}
+		}
+		return CommandResult{
+			Handled: true,
+			Output:  "\n" + message + "\n\n",
+		}
+	}
+
+	// Check for raven-timestamps command
+	if input == "raven-timestamps" {
+		message, err := panes.ToggleTimestamps()
+		if err != nil {
+			return CommandResult{
+				Handled: true,
+				Output:  fmt.Sprintf("\nError: %v\n\n", err),
+			}
+		}
+		return CommandResult{
+			Handled: true,
+			Output:  "\n" + message + "\n\n",
+		}
+	}
+
+	// Check for raven-line-numbers command
+	if input == "raven-line-numbers" {
+		message, err := panes.ToggleLineNumbers()
+		if err != nil {
+			return CommandResult{
+				Handled: true,
+				Output:  fmt.Sprintf("\nError: %v\n\n", err),
+			}
+		}
+		return CommandResult{
+			Handled: true,
+			Output:  "\n" + message + "\n\n",
+		}
+	}
+
+	// Check for raven-art-mode command
+	if input == "raven-art-mode" {
+		message, err := panes.ToggleArtMode()
+		if err != nil {
+			return CommandResult{
+				Handled: true,
+				Output:  fmt.Sprintf("\nError: %v\n\n", err),
+			}
+		}
+		return CommandResult{
+			Handled: true,
+			Output:  "\n" + message + "\n\n",
+		}
+	}
+
+	// Check for raven-group command
+	if fields := strings.Fields(input); len(fields) > 0 && fields[0] == "raven-group" {
+		return handleGroup(fields[1:], panes)
+	}
+
+	// Check for raven-run-in-split command
+	if fields := strings.Fields(input); len(fields) > 0 && (fields[0] == "raven-run-in-split" || fields[0] == "run-in-split") {
+		return handleRunInSplit(strings.TrimSpace(strings.TrimPrefix(input, fields[0])), panes)
+	}
+
+	// Check for raven-calc command
+	if input == "raven-calc" || strings.HasPrefix(input, "raven-calc ") {
+		return handleCalc(strings.TrimSpace(strings.TrimPrefix(input, "raven-calc")), panes)
+	}
+
+	// Check for raven-log command
+	if fields := strings.Fields(input); len(fields) > 0 && fields[0] == "raven-log" {
+		return handleLog(fields[1:])
+	}
+
+	// Check for raven-shell-integration command
+	if fields := strings.Fields(input); len(fields) > 0 && fields[0] == "raven-shell-integration" {
+		return handleShellIntegration(fields[1:])
+	}
+
+	// Check for raven-pipe command
+	if input == "raven-pipe" || strings.HasPrefix(input, "raven-pipe ") {
+		return handlePipe(strings.TrimSpace(strings.TrimPrefix(input, "raven-pipe")), panes)
+	}
+
 	// Check for keybindings command
 	if input == "keybindings" || input == "raven-keybindings" {
 		return CommandResult{
@@ -64,8 +310,10 @@ General:
 Tabs:
   Ctrl+Shift+T    New tab
   Ctrl+Shift+X    Close current tab
-  Ctrl+Tab        Next tab
-  Ctrl+Shift+Tab  Previous tab
+  Ctrl+Tab        Recent tabs (hold Ctrl to pick from a list)
+  Ctrl+Shift+Tab  Recent tabs, oldest first
+  Ctrl+1..9       Go to tab 1-9
+  Ctrl+Backtick   Go back to the last used tab
 
 Scrolling:
   Mouse wheel     Scroll up/down (3 lines)
@@ -83,10 +331,320 @@ Terminal Commands:
   change-font     List available fonts
   change-font <name>  Change font (e.g., change-font firacode)
   list-fonts      List available fonts
+  raven-diff [a b]  Diff the text of two panes in a new pane
+  raven-pipe <cmd>  Stream pane output to a command (>file, >>file)
+  raven-pipe        Stop streaming pane output
+  raven-send <pane> <text>       Type text and Enter into a pane
+  raven-send-file <pane> <path>  Paste a file into a pane
+  raven-watch <path>... -- <cmd> Re-run a command when files change
+  raven-watch                    Stop watching
+  raven-highlight <pattern>      Highlight and count a pattern in this pane
+  raven-highlight -d [pattern]   Remove one or all highlights
+  raven-cleanup     Close exited and idle panes
+  raven-inspect     Show the escape sequences the active pane receives
+  raven-rewind      Step back through the active pane's recent output
+  raven-respawn     Restart the shell or command in the active pane
+  raven-cmd [name]  Run a custom command, or list them
+  raven-timestamps  Show or hide when each line arrived in the active pane
+  raven-line-numbers  Show or hide line numbers in the active pane
+  raven-art-mode    Draw ANSI art flush and copy it untrimmed in the active pane
+  raven-group [name]  Put this tab in a colored group, or list the groups
+  raven-group -d      Take this tab out of its group
+  raven-group collapse|expand|close [name]  Fold, unfold or close a group
+  raven-run-in-split <cmd>  Run a command in a new pane
+  raven-calc [expr] Evaluate an expression, or open the calculator
+  raven-log [sub] [level]  Show or change log levels (error, warn, info, debug)
+  raven-shell-integration [shell|all]  Add prompt marks to a shell's rc file
+  raven-update      Check for a new release and show its changelog
 
 `
 }
 
+func handleDiff(args []string, panes PaneController) CommandResult {
+	usage := "\nUsage: raven-diff [pane pane]\nExample: raven-diff 1 2\n\n"
+	var first, second int
+	switch len(args) {
+	case 0:
+	case 2:
+		var err1, err2 error
+		first, err1 = strconv.Atoi(args[0])
+		second, err2 = strconv.Atoi(args[1])
+		if err1 != nil || err2 != nil || first < 1 || second < 1 {
+			return CommandResult{Handled: true, Output: usage}
+		}
+	default:
+		return CommandResult{Handled: true, Output: usage}
+	}
+
+	message, err := panes.DiffPanes(first, second)
+	if err != nil {
+		return CommandResult{
+			Handled: true,
+			Output:  fmt.Sprintf("\nError: %v\n\n", err),
+		}
+	}
+	return CommandResult{
+		Handled: true,
+		Output:  "\n" + message + "\n\n",
+	}
+}
+
+func handleSend(name, args string, panes PaneController) CommandResult {
+	usage := fmt.Sprintf("\nUsage: %s <pane> <text>\nExample: raven-send 2 print('hello')\n\n", name)
+	if name == "raven-send-file" {
+		usage = "\nUsage: raven-send-file <pane> <path>\nExample: raven-send-file 2 setup.py\n\n"
+	}
+	number, rest, _ := strings.Cut(args, " ")
+	pane, err := strconv.Atoi(number)
+	rest = strings.TrimSpace(rest)
+	if err != nil || pane < 1 || rest == "" {
+		return CommandResult{Handled: true, Output: usage}
+	}
+
+	var message string
+	if name == "raven-send-file" {
+		message, err = panes.SendFile(pane, rest)
+	} else {
+		message, err = panes.SendText(pane, rest)
+	}
+	if err != nil {
+		return CommandResult{
+			Handled: true,
+			Output:  fmt.Sprintf("\nError: %v\n\n", err),
+		}
+	}
+	return CommandResult{
+		Handled: true,
+		Output:  "\n" + message + "\n\n",
+	}
+}
+
+func handleWatch(args string, panes PaneController) CommandResult {
+	var paths []string
+	var command string
+	if args != "" {
+		before, after, found := strings.Cut(" "+args+" ", " -- ")
+		paths = strings.Fields(before)
+		command = strings.TrimSpace(after)
+		if !found || len(paths) == 0 || command == "" {
+			return CommandResult{
+				Handled: true,
+				Output:  "\nUsage: raven-watch <path>... -- <command>\nExample: raven-watch src go.mod -- go test ./...\n\n",
+			}
+		}
+	}
+
+	message, err := panes.WatchPane(paths, command)
+	if err != nil {
+		return CommandResult{
+			Handled: true,
+			Output:  fmt.Sprintf("\nError: %v\n\n", err),
+		}
+	}
+	return CommandResult{
+		Handled: true,
+		Output:  "\n" + message + "\n\n",
+	}
+}
+
+func handleRunInSplit(command string, panes PaneController) CommandResult {
+	if command == "" {
+		return CommandResult{
+			Handled: true,
+			Output:  "\nUsage: raven-run-in-split <command>\nExample: raven-run-in-split go test ./...\n\n",
+		}
+	}
+	message, err := panes.RunInSplit(command)
+	if err != nil {
+		return CommandResult{
+			Handled: true,
+			Output:  fmt.Sprintf("\nError: %v\n\n", err),
+		}
+	}
+	return CommandResult{
+		Handled: true,
+		Output:  "\n" + message + "\n\n",
+	}
+}
+
+func handleCalc(expr string, panes PaneController) CommandResult {
+	if expr == "" {
+		message, err := panes.OpenCalculator()
+		if err != nil {
+			return CommandResult{
+				Handled: true,
+				Output:  fmt.Sprintf("\nError: %v\n\n", err),
+			}
+		}
+		return CommandResult{
+			Handled: true,
+			Output:  "\n" + message + "\n\n",
+		}
+	}
+	result, err := calc.Eval(expr, 0)
+	if err != nil {
+		return CommandResult{
+			Handled: true,
+			Output:  fmt.Sprintf("\nError: %v\n\n", err),
+		}
+	}
+	return CommandResult{
+		Handled: true,
+		Output:  "\n" + result + "\n\n",
+	}
+}
+
+func handleHighlight(args string, panes PaneController) CommandResult {
+	remove := false
+	if args == "-d" || strings.HasPrefix(args, "-d ") {
+		remove = true
+		args = strings.TrimSpace(strings.TrimPrefix(args, "-d"))
+	}
+	message, err := panes.HighlightPane(args, remove)
+	if err != nil {
+		return CommandResult{
+			Handled: true,
+			Output:  fmt.Sprintf("\nError: %v\n\n", err),
+		}
+	}
+	return CommandResult{
+		Handled: true,
+		Output:  "\n" + message + "\n\n",
+	}
+}
+
+func handleGroup(args []string, panes PaneController) CommandResult {
+	usage := "\nUsage: raven-group [name | -d | collapse [name] | expand [name] | close [name]]\n\n"
+	var message string
+	var err error
+	switch {
+	case len(args) == 0:
+		message, err = panes.ListTabGroups()
+	case len(args) == 1 && args[0] == "-d":
+		message, err = panes.GroupTab("")
+	case len(args) <= 2 && (args[0] == "collapse" || args[0] == "expand" || args[0] == "close"):
+		name := ""
+		if len(args) == 2 {
+			name = args[1]
+		}
+		if args[0] == "close" {
+			message, err = panes.CloseTabGroup(name)
+		} else {
+			message, err = panes.CollapseTabGroup(name, args[0] == "collapse")
+		}
+	case len(args) == 1 && !strings.HasPrefix(args[0], "-"):
+		message, err = panes.GroupTab(args[0])
+	default:
+		return CommandResult{Handled: true, Output: usage}
+	}
+	if err != nil {
+		return CommandResult{
+			Handled: true,
+			Output:  fmt.Sprintf("\nError: %v\n\n", err),
+		}
+	}
+	return CommandResult{
+		Handled: true,
+		Output:  "\n" + message + "\n\n",
+	}
+}
+
+func handleLog(args []string) CommandResult {
+	usage := "Usage: raven-log [subsystem|all] <error|warn|info|debug>\n"
+	switch len(args) {
+	case 0:
+		var sb strings.Builder
+		sb.WriteString("\nLog levels:\n")
+		for _, sub := range logging.Subsystems() {
+			sb.WriteString(fmt.Sprintf("  %-8s %s\n", sub, logging.LevelOf(sub)))
+		}
+		sb.WriteString("\n" + usage + "\n")
+		return CommandResult{Handled: true, Output: sb.String()}
+	case 1, 2:
+		target := "all"
+		if len(args) == 2 {
+			target = args[0]
+		}
+		level, ok := logging.ParseLevel(args[len(args)-1])
+		if !ok {
+			return CommandResult{Handled: true, Output: fmt.Sprintf("\nUnknown level %q\n%s\n", args[len(args)-1], usage)}
+		}
+		if target == "all" {
+			logging.SetAll(level)
+			return CommandResult{Handled: true, Output: fmt.Sprintf("\nAll subsystems now log at %s\n\n", level)}
+		}
+		sub, ok := logging.ParseSubsystem(target)
+		if !ok {
+			var names []string
+			for _, s := range logging.Subsystems() {
+				names = append(names, string(s))
+			}
+			return CommandResult{Handled: true, Output: fmt.Sprintf("\nUnknown subsystem %q\nSubsystems: %s\n\n", target, strings.Join(names, ", "))}
+		}
+		logging.SetLevel(sub, level)
+		return CommandResult{Handled: true, Output: fmt.Sprintf("\n%s now logs at %s\n\n", sub, level)}
+	}
+	return CommandResult{Handled: true, Output: "\n" + usage + "\n"}
+}
+
+func handleShellIntegration(args []string) CommandResult {
+	usage := "Usage: raven-shell-integration [" + strings.Join(integration.Shells, "|") + "|all]\n"
+	var shells []string
+	switch {
+	case len(args) == 0:
+		shell := integration.Detect(os.Getenv("SHELL"))
+		if shell == "" {
+			return CommandResult{Handled: true, Output: "\nCould not tell which shell to set up from $SHELL\n" + usage + "\n"}
+		}
+		shells = []string{shell}
+	case len(args) == 1 && args[0] == "all":
+		shells = integration.Installed()
+		if len(shells) == 0 {
+			return CommandResult{Handled: true, Output: "\nNone of " + strings.Join(integration.Shells, ", ") + " were found on PATH\n\n"}
+		}
+	default:
+		for _, arg := range args {
+			shell := integration.Detect(arg)
+			if shell == "" {
+				return CommandResult{Handled: true, Output: fmt.Sprintf("\nUnsupported shell %q\n%s\n", arg, usage)}
+			}
+			shells = append(shells, shell)
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("\n")
+	for _, shell := range shells {
+		result, err := integration.Install(shell)
+		switch {
+		case err != nil:
+			sb.WriteString(fmt.Sprintf("%s: Error: %v\n", shell, err))
+		case !result.Changed:
+			sb.WriteString(fmt.Sprintf("%s: already installed in %s\n", shell, result.Path))
+		case result.Backup != "":
+			sb.WriteString(fmt.Sprintf("%s: installed in %s (backup: %s)\n", shell, result.Path, result.Backup))
+		default:
+			sb.WriteString(fmt.Sprintf("%s: installed in %s\n", shell, result.Path))
+		}
+	}
+	sb.WriteString("Start a new shell to use it\n\n")
+	return CommandResult{Handled: true, Output: sb.String()}
+}
+
+func handlePipe(target string, panes PaneController) CommandResult {
+	message, err := panes.PipePane(target)
+	if err != nil {
+		return CommandResult{
+			Handled: true,
+			Output:  fmt.Sprintf("\nError: %v\n\n", err),
+		}
+	}
+	return CommandResult{
+		Handled: true,
+		Output:  "\n" + message + "\n\n",
+	}
+}
+
 func handleChangeFont(fontName string, fontChanger FontChanger) CommandResult {
 	if fontName == "" {
 		return handleListFonts(fontChanger)
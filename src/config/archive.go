@@ -0,0 +1,178 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// ArchiveFormat names the file type written by ExportArchive
+const ArchiveFormat = "raven-terminal-config"
+
+// ArchiveVersion is the archive layout written by this build. Imports accept
+// this version and older ones.
+const ArchiveVersion = 1
+
+// Archive is a portable copy of the whole configuration, for moving settings
+// between machines. Themes, snippets, commands, aliases, exports, host and
+// directory profiles all live in Config, so one file carries everything.
+type Archive struct {
+	Format   string    `toml:"format"`
+	Version  int       `toml:"version"`
+	Exported time.Time `toml:"exported"`
+	Config   *Config   `toml:"config"`
+}
+
+// DefaultArchivePath returns where the settings menu exports to and imports from by default
+func DefaultArchivePath() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "raven-terminal-config.toml"
+	}
+	return filepath.Join(homeDir, "raven-terminal-config.toml")
+}
+
+// ExportArchive writes the configuration to an archive file
+func (c *Config) ExportArchive(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	archive := Archive{
+		Format:   ArchiveFormat,
+		Version:  ArchiveVersion,
+		Exported: time.Now().UTC().Truncate(time.Second),
+		Config:   c,
+	}
+	return toml.NewEncoder(f).Encode(archive)
+}
+
+// ReadArchive loads and validates an archive file. Settings missing from the
+// archive keep their defaults.
+func ReadArchive(path string) (*Archive, error) {
+	archive := &Archive{Config: DefaultConfig()}
+	if _, err := toml.DecodeFile(path, archive); err != nil {
+		return nil, err
+	}
+	if archive.Format != ArchiveFormat {
+		return nil, fmt.Errorf("not a Raven Terminal config archive")
+	}
+	if archive.Version < 1 {
+		return nil, fmt.Errorf("archive has no version")
+	}
+	if archive.Version > ArchiveVersion {
+		return nil, fmt.Errorf("archive version %d is newer than supported version %d", archive.Version, ArchiveVersion)
+	}
+	if archive.Config.Scripts.VCSDetect == defaultVCSDetectLegacy {
+		archive.Config.Scripts.VCSDetect = defaultVCSDetect
+	}
+	return archive, nil
+}
+
+// Merge adds the named entries of another configuration: commands, snippets
+// and AI personas by name, and aliases, exports, host profiles and search bangs by key. Entries
+// from other replace same-named ones; every other setting is left unchanged.
+// It returns how many entries were added or replaced.
+func (c *Config) Merge(other *Config) int {
+	changed := 0
+	for _, cmd := range other.Commands {
+		replaced := false
+		for i := range c.Commands {
+			if c.Commands[i].Name == cmd.Name {
+				if c.Commands[i] != cmd {
+					c.Commands[i] = cmd
+					changed++
+				}
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			c.Commands = append(c.Commands, cmd)
+			changed++
+		}
+	}
+	for _, snippet := range other.Snippets {
+		replaced := false
+		for i := range c.Snippets {
+			if c.Snippets[i].Name == snippet.Name {
+				if c.Snippets[i] != snippet {
+					c.Snippets[i] = snippet
+					changed++
+				}
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			c.Snippets = append(c.Snippets, snippet)
+			changed++
+		}
+	}
+	for _, persona := range other.Ollama.Personas {
+		replaced := false
+		for i := range c.Ollama.Personas {
+			if c.Ollama.Personas[i].Name == persona.Name {
+				if c.Ollama.Personas[i] != persona {
+					c.Ollama.Personas[i] = persona
+					changed++
+				}
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			c.Ollama.Personas = append(c.Ollama.Personas, persona)
+			changed++
+		}
+	}
+	for name, command := range other.Aliases {
+		if current, ok := c.Aliases[name]; !ok || current != command {
+			c.SetAlias(name, command)
+			changed++
+		}
+	}
+	for name, value := range other.Exports {
+		if current, ok := c.Exports[name]; !ok || current != value {
+			c.SetExport(name, value)
+			changed++
+		}
+	}
+	for host, profile := range other.Hosts {
+		if current, ok := c.Hosts[host]; !ok || current != profile {
+			if c.Hosts == nil {
+				c.Hosts = make(map[string]HostProfile)
+			}
+			c.Hosts[host] = profile
+			changed++
+		}
+	}
+	for dir, profile := range other.Directories {
+		if current, ok := c.Directories[dir]; !ok || !reflect.DeepEqual(current, profile) {
+			if c.Directories == nil {
+				c.Directories = make(map[string]DirProfile)
+			}
+			c.Directories[dir] = profile
+			changed++
+		}
+	}
+	for name, sites := range other.WebSearch.Bangs {
+		if current, ok := c.WebSearch.Bangs[name]; !ok || current != sites {
+			if c.WebSearch.Bangs == nil {
+				c.WebSearch.Bangs = make(map[string]string)
+			}
+			c.WebSearch.Bangs[name] = sites
+			changed++
+		}
+	}
+	return changed
+}
@@ -0,0 +1,223 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// MaxBackups is how many config backups are kept; older ones are removed
+const MaxBackups = 10
+
+// backupTimeFormat names backup files by when they were taken, sorting in
+// time order. Names carry microseconds too, which parsing accepts after the
+// seconds, so backups taken in the same second stay apart.
+const backupTimeFormat = "20060102-150405"
+
+// Backup is a copy of the config file taken before the settings menu saved
+// over it
+type Backup struct {
+	Path  string
+	Taken time.Time
+}
+
+// GetBackupDir returns the directory config backups are kept in
+func GetBackupDir() string {
+	return filepath.Join(GetConfigDir(), "backups")
+}
+
+// BackupConfig copies the config file into the backup directory, unless the
+// newest backup already holds the same settings, and removes the backups
+// beyond MaxBackups. It returns the new backup's path, or "" when there was
+// no config file or nothing changed.
+func BackupConfig(now time.Time) (string, error) {
+	data, err := os.ReadFile(GetConfigPath())
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	backups, err := Backups()
+	if err != nil {
+		return "", err
+	}
+	if len(backups) > 0 {
+		if newest, err := os.ReadFile(backups[0].Path); err == nil && bytes.Equal(newest, data) {
+			return "", nil
+		}
+	}
+
+	if err := os.MkdirAll(GetBackupDir(), 0700); err != nil {
+		return "", err
+	}
+	path, err := writeBackup(data, now)
+	if err != nil {
+		return "", err
+	}
+
+	backups, err = Backups()
+	if err != nil {
+		return path, err
+	}
+	for i := MaxBackups; i < len(backups); i++ {
+		if err := os.Remove(backups[i].Path); err != nil {
+			return path, err
+		}
+	}
+	return path, nil
+}
+
+// writeBackup writes data to a new backup file named by now, moving on a
+// microsecond while that name is taken so no earlier backup is overwritten
+func writeBackup(data []byte, now time.Time) (string, error) {
+	for {
+		path := filepath.Join(GetBackupDir(), "config-"+now.Format(backupTimeFormat+".000000")+".toml")
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if os.IsExist(err) {
+			now = now.Add(time.Microsecond)
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if _, err := f.Write(data); err != nil {
+			f.Close()
+			return "", err
+		}
+		return path, f.Close()
+	}
+}
+
+// Backups lists the config backups, newest first
+func Backups() ([]Backup, error) {
+	entries, err := os.ReadDir(GetBackupDir())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var backups []Backup
+	for _, entry := range entries {
+		stamp, ok := strings.CutPrefix(entry.Name(), "config-")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		taken, err := time.ParseInLocation(backupTimeFormat, strings.TrimSuffix(stamp, ".toml"), time.Local)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, Backup{Path: filepath.Join(GetBackupDir(), entry.Name()), Taken: taken})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Taken.After(backups[j].Taken)
+	})
+	return backups, nil
+}
+
+// LoadFile loads settings from a config file other than the one in use,
+// such as a backup. Settings missing from the file keep their defaults.
+func LoadFile(path string) (*Config, error) {
+	cfg := DefaultConfig()
+	if _, err := toml.DecodeFile(path, cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// RestoreBackup writes a backup over the config file, backing up the config
+// file first so the restore can itself be undone. A backup that does not
+// load is refused.
+func RestoreBackup(backup Backup) error {
+	if _, err := LoadFile(backup.Path); err != nil {
+		return err
+	}
+	data, err := os.ReadFile(backup.Path)
+	if err != nil {
+		return err
+	}
+	if _, err := BackupConfig(time.Now()); err != nil {
+		return err
+	}
+	if err := os.WriteFile(GetConfigPath(), data, 0600); err != nil {
+		return err
+	}
+	// WriteFile keeps the mode of a config file that already exists
+	return os.Chmod(GetConfigPath(), 0600)
+}
+
+// Snapshot returns the settings encoded as TOML, for telling whether they
+// changed and for putting them back with FromSnapshot
+func (c *Config) Snapshot() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(c); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// FromSnapshot returns the settings a Snapshot holds
+func FromSnapshot(data []byte) (*Config, error) {
+	cfg := &Config{}
+	if _, err := toml.Decode(string(data), cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// RunRestore implements "raven-terminal restore-config", which puts back a
+// config backup when a bad setting keeps the settings menu out of reach, and
+// returns the process exit code
+func RunRestore(args []string) int {
+	fs := flag.NewFlagSet("restore-config", flag.ContinueOnError)
+	list := fs.Bool("list", false, "list the backups, newest first, without restoring one")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: raven-terminal restore-config [-list] [N]")
+		fmt.Fprintln(fs.Output(), "Restores backup N of -list, by default 1, the config before the last save.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	backups, err := Backups()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "raven-terminal restore-config: %v\n", err)
+		return 1
+	}
+	if len(backups) == 0 {
+		fmt.Fprintf(os.Stderr, "raven-terminal restore-config: no backups in %s\n", GetBackupDir())
+		return 1
+	}
+	if *list {
+		for i, backup := range backups {
+			fmt.Printf("%2d  %s  %s\n", i+1, backup.Taken.Format("2006-01-02 15:04:05"), backup.Path)
+		}
+		return 0
+	}
+
+	n := 1
+	if fs.NArg() > 0 {
+		n, err = strconv.Atoi(fs.Arg(0))
+		if err != nil || n < 1 || n > len(backups) || fs.NArg() > 1 {
+			fs.Usage()
+			return 2
+		}
+	}
+	backup := backups[n-1]
+	if err := RestoreBackup(backup); err != nil {
+		fmt.Fprintf(os.Stderr, "raven-terminal restore-config: %s: %v\n", backup.Path, err)
+		return 1
+	}
+	fmt.Printf("Restored the config from %s; restart Raven Terminal or use Reload Config to apply it\n", backup.Taken.Format("2006-01-02 15:04:05"))
+	return 0
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"io/fs"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -11,13 +12,16 @@ import (
 
 // PromptConfig holds prompt customization settings
 type PromptConfig struct {
-	Style              string `toml:"style"` // "minimal", "simple", "full", "custom"
-	ShowPath           bool   `toml:"show_path"`
-	ShowUsername       bool   `toml:"show_username"`
-	ShowHostname       bool   `toml:"show_hostname"`
-	ShowLanguage       bool   `toml:"show_language"`
-	ShowVCS            bool   `toml:"show_vcs"`
-	CustomPromptScript string `toml:"custom_script"` // Custom script for prompt
+	Style              string            `toml:"style"` // "minimal", "simple", "full", "custom"
+	ShowPath           bool              `toml:"show_path"`
+	ShowUsername       bool              `toml:"show_username"`
+	ShowHostname       bool              `toml:"show_hostname"`
+	ShowLanguage       bool              `toml:"show_language"`
+	ShowVCS            bool              `toml:"show_vcs"`
+	CustomPromptScript string            `toml:"custom_script"`       // Custom script for prompt
+	StatusRow          bool              `toml:"status_row"`          // Draw git, language, venv and k8s context above the input line, detected by the terminal
+	StatusRowSegments  []string          `toml:"status_row_segments"` // Which of "git", "ivaldi", "language", "venv", "kube" and the providers the status row shows, in order
+	Providers          map[string]string `toml:"providers"`           // Extra status row segments by name: a shell command run in the directory, whose first line is shown
 }
 
 // ScriptsConfig holds custom scripts configuration
@@ -39,17 +43,41 @@ type WebSearchConfig struct {
 	UseReaderProxy bool `toml:"use_reader_proxy"`
 	// ReaderProxyURLs lists proxy base URLs to try for text extraction.
 	ReaderProxyURLs []string `toml:"reader_proxy_urls"`
+	// Bangs maps !name shortcuts to the sites they search, added to the built-in ones.
+	Bangs map[string]string `toml:"bangs"`
 }
 
 // OllamaConfig holds local AI chat settings.
 type OllamaConfig struct {
-	Enabled        bool   `toml:"enabled"`
-	URL            string `toml:"url"`
-	Model          string `toml:"model"`
-	ThinkingMode   bool   `toml:"thinking_mode"`    // Enable thinking/reasoning mode for supported models
-	ThinkingBudget int    `toml:"thinking_budget"`  // Max tokens for thinking (0 = no limit)
-	ShowThinking   bool   `toml:"show_thinking"`    // Show thinking content in UI (collapsible)
-	ExtendedTimeout int   `toml:"extended_timeout"` // Extended timeout in seconds for thinking models (0 = default 300s)
+	Enabled         bool      `toml:"enabled"`
+	URL             string    `toml:"url"`
+	Model           string    `toml:"model"`
+	ThinkingMode    bool      `toml:"thinking_mode"`    // Enable thinking/reasoning mode for supported models
+	ThinkingBudget  int       `toml:"thinking_budget"`  // Max tokens for thinking (0 = no limit)
+	ShowThinking    bool      `toml:"show_thinking"`    // Show thinking content in UI (collapsible)
+	ExtendedTimeout int       `toml:"extended_timeout"` // Extended timeout in seconds for thinking models (0 = default 300s)
+	SemanticSearch  bool      `toml:"semantic_search"`  // Embed scrollback into a local index searchable from the search panel
+	EmbeddingModel  string    `toml:"embedding_model"`  // Ollama model that computes the embeddings for semantic search
+	Persona         string    `toml:"persona"`          // Persona new conversations start with
+	Personas        []Persona `toml:"personas"`         // Extra personas, added to the built-in ones
+	Tools           bool      `toml:"tools"`            // Let the model ask to run read-only commands, each confirmed by the user
+	ToolCommands    []string  `toml:"tool_commands"`    // Commands it may ask for, such as "git status" (empty = built-in list)
+}
+
+// Persona is a named system prompt for the AI chat, such as "terse sysadmin"
+type Persona struct {
+	Name   string `toml:"name"`
+	Prompt string `toml:"prompt"`
+}
+
+// NetworkConfig holds proxy, certificate and timeout settings for web search and Ollama requests
+type NetworkConfig struct {
+	Proxy          string `toml:"proxy"`           // http://, https:// or socks5:// proxy URL; empty uses HTTP(S)_PROXY, "direct" uses none
+	NoProxy        string `toml:"no_proxy"`        // Comma-separated hosts, and their subdomains, that skip the proxy
+	CABundle       string `toml:"ca_bundle"`       // PEM file of extra certificate authorities to trust
+	ConnectTimeout int    `toml:"connect_timeout"` // Seconds to connect and finish the TLS handshake
+	SearchTimeout  int    `toml:"search_timeout"`  // Seconds a web search or page preview may take
+	OllamaTimeout  int    `toml:"ollama_timeout"`  // Seconds Ollama may take to start answering, including loading the model
 }
 
 // ShellConfig holds shell-specific settings
@@ -67,28 +95,210 @@ type CustomCommand struct {
 	Name        string `toml:"name"`
 	Command     string `toml:"command"`
 	Description string `toml:"description"`
+	Open        string `toml:"open"`  // Where it runs: "pane" or "tab" opens one for it; empty types it into the active pane
+	Split       string `toml:"split"` // How "pane" splits the active pane: "vertical" (side by side) or "horizontal"
+	Title       string `toml:"title"` // Title of the pane or tab it opens; empty uses the name
+}
+
+// RedactionConfig holds settings for masking secrets in rendered output
+type RedactionConfig struct {
+	Enabled  bool     `toml:"enabled"`  // Start with redaction mode on
+	Patterns []string `toml:"patterns"` // Extra regular expressions to mask; a capture group masks only the group
+}
+
+// LinksConfig controls which words can be clicked open as URLs
+type LinksConfig struct {
+	TrimLeft  string            `toml:"trim_left"`  // Characters stripped from the start of a clicked word
+	TrimRight string            `toml:"trim_right"` // Characters stripped from the end of a clicked word
+	Schemes   []string          `toml:"schemes"`    // URL schemes that can be clicked open
+	Handlers  map[string]string `toml:"handlers"`   // Shell commands that open URLs of a scheme or domain instead of the system opener
+}
+
+// KeysConfig holds the leader key, the chords typed after it and the keys
+// that pick tabs
+type KeysConfig struct {
+	Leader       string            `toml:"leader"`        // Key that starts a chord, e.g. "ctrl+a"; empty turns chords off
+	ChordTimeout int               `toml:"chord_timeout"` // Milliseconds to wait for the key after the leader
+	Chords       map[string]string `toml:"chords"`        // Action run by each key typed after the leader
+	TabIndex     string            `toml:"tab_index"`     // Modifier held with 1-9 to jump to that tab, e.g. "ctrl"; empty turns it off
+	LastTab      string            `toml:"last_tab"`      // Key that goes back to the tab used before; empty turns it off
+	RecentTabs   bool              `toml:"recent_tabs"`   // Ctrl+Tab switches tabs most recently used first, with a switcher while Ctrl is held
+}
+
+// PassthroughRule sends keys straight to the program in a pane, instead of
+// running Raven's action for them, while that program is in the foreground
+type PassthroughRule struct {
+	Keys      []string `toml:"keys"`       // Keys to pass through, e.g. "ctrl+shift+t"
+	Processes []string `toml:"processes"`  // Foreground program names the rule is for; empty for any program
+	AltScreen bool     `toml:"alt_screen"` // Only while the program has the alternate screen up
+}
+
+// LockConfig holds lock screen settings
+type LockConfig struct {
+	PassphraseSHA256 string `toml:"passphrase_sha256"` // Hex SHA-256 of the unlock passphrase; empty unlocks on any key
+}
+
+// AccessibilityConfig holds settings for spoken output, cursor visibility and motion
+type AccessibilityConfig struct {
+	ScreenReader    bool `toml:"screen_reader"`    // Speak new output of the focused pane through the system text-to-speech service
+	CursorThickness int  `toml:"cursor_thickness"` // Bar/underline cursor thickness in pixels; 0 uses the default
+	ReduceMotion    bool `toml:"reduce_motion"`    // Disable cursor blinking, spinners and other animation
+}
+
+// SessionBorderConfig holds the warning borders drawn around panes running as root or over ssh
+type SessionBorderConfig struct {
+	Root      bool    `toml:"root"`       // Border panes whose shell or foreground program runs as root
+	RootColor string  `toml:"root_color"` // Hex color of the root border
+	SSH       bool    `toml:"ssh"`        // Border panes running ssh or reporting a remote host
+	SSHColor  string  `toml:"ssh_color"`  // Hex color of the ssh border
+	Width     float32 `toml:"width"`      // Border width in pixels
+}
+
+// HostProfile customizes panes whose shell reports a matching remote host through OSC 7
+type HostProfile struct {
+	Tint             string `toml:"tint"`               // Hex color, usually translucent, laid over the pane background
+	TitlePrefix      string `toml:"title_prefix"`       // Text shown before the pane and tab titles
+	DisableAIContext bool   `toml:"disable_ai_context"` // Refuse to send AI chat prompts while the focused pane is on this host
+}
+
+// TabGroup styles a named tab group in the tab bar
+type TabGroup struct {
+	Color string `toml:"color"` // Hex color of the strip beside the group's tabs
+}
+
+// DirProfile customizes panes whose shell reports, through OSC 7, a working
+// directory inside a matching path
+type DirProfile struct {
+	Tint        string            `toml:"tint"`         // Hex color, usually translucent, laid over the pane background
+	TitlePrefix string            `toml:"title_prefix"` // Text shown before the pane and tab titles
+	Env         map[string]string `toml:"env"`          // Variables set for shells started in the directory
+}
+
+// NotificationConfig holds which background events are collected in the notification center
+type NotificationConfig struct {
+	Bell          bool `toml:"bell"`           // Record bells rung by programs in any pane
+	AICompletions bool `toml:"ai_completions"` // Record AI chat replies that finish while the AI panel is not focused
+	LongCommand   int  `toml:"long_command"`   // Seconds a command must run to be reported when it finishes out of view; 0 disables
+}
+
+// HooksConfig holds shell commands run, with their output discarded, when terminal events happen
+type HooksConfig struct {
+	Bell            string `toml:"bell"`              // Run when a program rings the bell
+	CommandFinished string `toml:"command_finished"`  // Run when a command finishes in any pane
+	TabOpened       string `toml:"tab_opened"`        // Run when a tab is opened
+	WindowFocusLost string `toml:"window_focus_lost"` // Run when the window loses focus
+}
+
+// UpdateConfig holds whether new releases are looked for and fetched
+type UpdateConfig struct {
+	Check        bool `toml:"check"`         // Look for a new release at most once a day at startup
+	AutoDownload bool `toml:"auto_download"` // Download a new release found at startup so it is installed on exit
+}
+
+// WindowConfig holds which monitor the window uses and whether it remembers where it was
+type WindowConfig struct {
+	FullscreenMonitor  string `toml:"fullscreen_monitor"`   // "current", "primary", a monitor number from 1, or a name such as "DP-1"
+	RememberPlacement  bool   `toml:"remember_placement"`   // Reopen at the last position and size used on the monitor the window was last on
+	FollowMonitorScale bool   `toml:"follow_monitor_scale"` // Resize text when the window moves to a monitor with a different scale
+	VSync              string `toml:"vsync"`                // "on", "off" or "adaptive" (on, but late frames are shown without waiting)
+	MaxFPS             int    `toml:"max_fps"`              // Frames per second with vsync off; 0 uses the monitor's refresh rate
+}
+
+// PaneCleanupConfig holds when exited and idle panes are offered for cleanup or closed automatically
+type PaneCleanupConfig struct {
+	IdleMinutes     int  `toml:"idle_minutes"`      // Minutes without input or output, with nothing running in the shell, before a pane counts as idle; 0 disables
+	AutoCloseExited bool `toml:"auto_close_exited"` // Close panes whose shell exited while other panes keep the tab open
+	AutoCloseIdle   bool `toml:"auto_close_idle"`   // Close idle panes other than the focused one instead of only listing them
+}
+
+// LoggingConfig holds how much each part of the terminal writes to the log
+type LoggingConfig struct {
+	Level      string            `toml:"level"`      // "error", "warn", "info" or "debug" for every subsystem
+	Subsystems map[string]string `toml:"subsystems"` // Level overrides by subsystem: app, parser, render, pty, ai, search, menu
+}
+
+// Snippet is a named block of text inserted into the shell. ${name} and
+// ${name:default} placeholders are prompted for before insertion.
+type Snippet struct {
+	Name        string `toml:"name"`
+	Body        string `toml:"body"`
+	Description string `toml:"description"`
 }
 
 // AppearanceConfig holds visual settings
 type AppearanceConfig struct {
-	CursorStyle      string  `toml:"cursor_style"`       // "block", "underline", "bar"
-	CursorBlink      bool    `toml:"cursor_blink"`       // Whether cursor blinks
-	PanelWidthPercent float32 `toml:"panel_width_percent"` // Width of side panels (25-50)
+	CursorStyle       string   `toml:"cursor_style"`        // "block", "underline", "bar"
+	CursorBlink       bool     `toml:"cursor_blink"`        // Whether cursor blinks
+	PanelWidthPercent float32  `toml:"panel_width_percent"` // Width of side panels (25-50)
+	PaneTitles        bool     `toml:"pane_titles"`         // Show pane number and title on split pane borders
+	TabGitStatus      bool     `toml:"tab_git_status"`      // Show git branch and dirty state for each tab in the tab bar
+	TabStatusSegments []string `toml:"tab_status_segments"` // Status row segments shown under each tab name when tab_git_status is on
+	AIPanelDock       string   `toml:"ai_panel_dock"`       // Where the AI panel sits: "right", "left", "bottom" or "tab"
+	AIPanelSize       float32  `toml:"ai_panel_size"`       // AI panel width, or height when docked at the bottom, in percent of the window (20-80)
+	KeepPanelState    bool     `toml:"keep_panel_state"`    // Keep the AI conversation and search preview when their panels are closed
+	Renderer          string   `toml:"renderer"`            // Drawing backend: "opengl"; "vulkan" and "metal" are reserved and use OpenGL for now
+	Badge             string   `toml:"badge"`               // Text drawn faintly in each pane's corner; \(user.NAME) expands to a shell user variable
+	PowerlineStretch  bool     `toml:"powerline_stretch"`   // Scale powerline separators to fill the cell so they meet the cells beside them
+	StickyPrompt      bool     `toml:"sticky_prompt"`       // While scrolled back, pin the prompt line of the output in view to the top of the pane
+	UIScale           float32  `toml:"ui_scale"`            // Scale of the tab bar, menus, panels and toasts, independent of font zoom (0.5-3)
+	LineNumbers       bool     `toml:"line_numbers"`        // New panes start with a gutter numbering every line since the pane opened
+	WrapMarkers       bool     `toml:"wrap_markers"`        // Mark rows whose line is soft-wrapped onto the next row at the right edge
+	AliasPreview      bool     `toml:"alias_preview"`       // Show what a typed command starting with an alias expands to after the cursor
+}
+
+// TerminalConfig holds terminal emulation settings
+type TerminalConfig struct {
+	Latin1              bool   `toml:"latin1"`                // Treat PTY input/output as ISO-8859-1 instead of UTF-8
+	MinCols             int    `toml:"min_cols"`              // Minimum columns a pane may shrink to
+	MinRows             int    `toml:"min_rows"`              // Minimum rows a pane may shrink to
+	MaxTabs             int    `toml:"max_tabs"`              // Most tabs that can be open (0 = no limit)
+	MaxPanes            int    `toml:"max_panes"`             // Most panes one tab can be split into (0 = no limit)
+	SizeOverlay         bool   `toml:"size_overlay"`          // Show "COLSxROWS" briefly when a pane is resized
+	CopyExactWhitespace bool   `toml:"copy_exact_whitespace"` // Copy tabs and written trailing spaces exactly instead of trimming
+	CopyOnSelect        bool   `toml:"copy_on_select"`        // Copy a mouse selection as soon as the drag ends
+	CopyTarget          string `toml:"copy_target"`           // Where copy_on_select copies to: "clipboard", "primary" or "both"
+	KeepSelection       bool   `toml:"keep_selection"`        // Keep text highlighted after it is copied
+	MemoryCapMB         int    `toml:"memory_cap_mb"`         // Total cell memory across all panes before the oldest scrollback is evicted (0 = no cap)
+	ReplayBufferKB      int    `toml:"replay_buffer_kb"`      // Recent raw output kept per pane for raven-rewind (0 = off)
+	PrintFile           string `toml:"print_file"`            // File text printed with the DEC media copy sequences (CSI i) is appended to; empty discards it
+	RunSplitClose       string `toml:"run_split_close"`       // When a raven-run-in-split pane closes after its command exits: "success", "always" or "never"
+	OnExit              string `toml:"on_exit"`               // What happens to a pane when its shell exits: "close", "hold", "hold_on_failure" or "restart"
+	AltScreenCapture    bool   `toml:"alt_screen_capture"`    // Copy what a full-screen program last showed into the main screen when it exits
+	AltScreenMark       bool   `toml:"alt_screen_mark"`       // Set scroll mark ^ where the main screen picks up after a full-screen program exits
 }
 
 // Config holds the terminal configuration
 type Config struct {
-	Shell      ShellConfig       `toml:"shell"`
-	Prompt     PromptConfig      `toml:"prompt"`
-	Scripts    ScriptsConfig     `toml:"scripts"`
-	WebSearch  WebSearchConfig   `toml:"web_search"`
-	Ollama     OllamaConfig      `toml:"ollama"`
-	Appearance AppearanceConfig  `toml:"appearance"`
-	Commands   []CustomCommand   `toml:"commands"`
-	Aliases    map[string]string `toml:"aliases"`
-	Exports    map[string]string `toml:"exports"`
-	Theme      string            `toml:"theme"`
-	FontSize   float32           `toml:"font_size"`
+	Shell          ShellConfig            `toml:"shell"`
+	Prompt         PromptConfig           `toml:"prompt"`
+	Scripts        ScriptsConfig          `toml:"scripts"`
+	WebSearch      WebSearchConfig        `toml:"web_search"`
+	Ollama         OllamaConfig           `toml:"ollama"`
+	Network        NetworkConfig          `toml:"network"`
+	Appearance     AppearanceConfig       `toml:"appearance"`
+	Terminal       TerminalConfig         `toml:"terminal"`
+	Redaction      RedactionConfig        `toml:"redaction"`
+	Lock           LockConfig             `toml:"lock"`
+	Keys           KeysConfig             `toml:"keys"`
+	Passthrough    []PassthroughRule      `toml:"passthrough"`
+	Accessibility  AccessibilityConfig    `toml:"accessibility"`
+	SessionBorders SessionBorderConfig    `toml:"session_borders"`
+	Notifications  NotificationConfig     `toml:"notifications"`
+	Hooks          HooksConfig            `toml:"hooks"`
+	Update         UpdateConfig           `toml:"update"`
+	Window         WindowConfig           `toml:"window"`
+	Links          LinksConfig            `toml:"links"`
+	PaneCleanup    PaneCleanupConfig      `toml:"pane_cleanup"`
+	Logging        LoggingConfig          `toml:"logging"`
+	Hosts          map[string]HostProfile `toml:"hosts"`
+	Directories    map[string]DirProfile  `toml:"directories"`
+	TabGroups      map[string]TabGroup    `toml:"tab_groups"`
+	Commands       []CustomCommand        `toml:"commands"`
+	Snippets       []Snippet              `toml:"snippets"`
+	Aliases        map[string]string      `toml:"aliases"`
+	Exports        map[string]string      `toml:"exports"`
+	Theme          string                 `toml:"theme"`
+	FontSize       float32                `toml:"font_size"`
 }
 
 const defaultVCSDetectLegacy = `# Detect VCS (Git + Ivaldi)
@@ -279,12 +489,15 @@ func DefaultConfig() *Config {
 			AdditionalEnv: map[string]string{},
 		},
 		Prompt: PromptConfig{
-			Style:        "full",
-			ShowPath:     true,
-			ShowUsername: true,
-			ShowHostname: true,
-			ShowLanguage: true,
-			ShowVCS:      true,
+			Style:             "full",
+			ShowPath:          true,
+			ShowUsername:      true,
+			ShowHostname:      true,
+			ShowLanguage:      true,
+			ShowVCS:           true,
+			StatusRow:         false,
+			StatusRowSegments: []string{"git", "language", "venv", "kube"},
+			Providers:         map[string]string{},
 		},
 		Scripts: ScriptsConfig{
 			Init:      "",
@@ -321,13 +534,141 @@ echo "None"
 			ThinkingBudget:  0,     // No limit
 			ShowThinking:    true,  // Show thinking by default
 			ExtendedTimeout: 600,   // 10 minutes for thinking models
+			SemanticSearch:  false,
+			EmbeddingModel:  "nomic-embed-text",
+			Persona:         "Default",
+			Personas:        []Persona{},
+			Tools:           false,
+			ToolCommands:    []string{},
+		},
+		Network: NetworkConfig{
+			Proxy:          "",
+			NoProxy:        "",
+			CABundle:       "",
+			ConnectTimeout: 30,
+			SearchTimeout:  10,
+			OllamaTimeout:  300,
 		},
 		Appearance: AppearanceConfig{
 			CursorStyle:       "block",
 			CursorBlink:       true,
 			PanelWidthPercent: 35.0,
+			PaneTitles:        true,
+			TabGitStatus:      true,
+			TabStatusSegments: []string{"git"},
+			AIPanelDock:       "right",
+			AIPanelSize:       35.0,
+			KeepPanelState:    true,
+			Renderer:          "opengl",
+			Badge:             "",
+			PowerlineStretch:  true,
+			StickyPrompt:      true,
+			UIScale:           1.0,
+			LineNumbers:       false,
+			WrapMarkers:       false,
+			AliasPreview:      true,
+		},
+		Terminal: TerminalConfig{
+			Latin1:              false,
+			MinCols:             20,
+			MinRows:             5,
+			MaxTabs:             10,
+			MaxPanes:            16,
+			SizeOverlay:         true,
+			CopyExactWhitespace: false,
+			CopyOnSelect:        true,
+			CopyTarget:          "clipboard",
+			KeepSelection:       true,
+			MemoryCapMB:         1024,
+			ReplayBufferKB:      2048,
+			PrintFile:           "",
+			RunSplitClose:       "success",
+			OnExit:              "close",
+			AltScreenCapture:    false,
+			AltScreenMark:       false,
+		},
+		Redaction: RedactionConfig{
+			Enabled:  false,
+			Patterns: []string{},
+		},
+		Lock: LockConfig{
+			PassphraseSHA256: "",
+		},
+		Keys: KeysConfig{
+			Leader:       "",
+			ChordTimeout: 1500,
+			TabIndex:     "ctrl",
+			LastTab:      "ctrl+`",
+			RecentTabs:   true,
+			Chords: map[string]string{
+				"c": "new-tab",
+				"n": "next-tab",
+				"p": "prev-tab",
+				"v": "split-vertical",
+				"s": "split-horizontal",
+				"o": "next-pane",
+				"x": "close-pane",
+			},
+		},
+		Accessibility: AccessibilityConfig{
+			ScreenReader:    false,
+			CursorThickness: 0,
+			ReduceMotion:    false,
+		},
+		SessionBorders: SessionBorderConfig{
+			Root:      true,
+			RootColor: "#8b0000",
+			SSH:       true,
+			SSHColor:  "#b5651d",
+			Width:     3,
+		},
+		Notifications: NotificationConfig{
+			Bell:          true,
+			AICompletions: true,
+			LongCommand:   10,
+		},
+		Hooks: HooksConfig{
+			Bell:            "",
+			CommandFinished: "",
+			TabOpened:       "",
+			WindowFocusLost: "",
+		},
+		Update: UpdateConfig{
+			Check:        false,
+			AutoDownload: false,
+		},
+		Window: WindowConfig{
+			FullscreenMonitor:  "current",
+			RememberPlacement:  true,
+			FollowMonitorScale: true,
+			VSync:              "on",
+			MaxFPS:             0,
+		},
+		Links: LinksConfig{
+			TrimLeft:  "<>\"'()[]{}",
+			TrimRight: "<>\"'()[]{}.,;:!?",
+			Schemes:   []string{"http", "https", "ftp", "sftp", "ssh", "git", "git+ssh", "mailto"},
+			Handlers:  map[string]string{},
+		},
+		PaneCleanup: PaneCleanupConfig{
+			IdleMinutes:     60,
+			AutoCloseExited: false,
+			AutoCloseIdle:   false,
+		},
+		Logging: LoggingConfig{
+			Level:      "info",
+			Subsystems: map[string]string{},
+		},
+		Passthrough: []PassthroughRule{},
+		Hosts:       map[string]HostProfile{},
+		Directories: map[string]DirProfile{},
+		Commands:    []CustomCommand{},
+		Snippets:    []Snippet{},
+		TabGroups: map[string]TabGroup{
+			"work":     {Color: "#3b82f6"},
+			"prod":     {Color: "#dc2626"},
+			"personal": {Color: "#16a34a"},
 		},
-		Commands: []CustomCommand{},
 		Aliases: map[string]string{
 			"ls": getDefaultLsAlias(),
 		},
@@ -337,13 +678,40 @@ echo "None"
 	}
 }
 
-// GetConfigDir returns the config directory path
-func GetConfigDir() string {
+// xdgDir returns raven-terminal under the directory named by an XDG base
+// directory variable, or under the variable's default below the home
+// directory when it is unset or not absolute
+func xdgDir(env string, fallback ...string) string {
+	if dir := os.Getenv(env); filepath.IsAbs(dir) {
+		return filepath.Join(dir, "raven-terminal")
+	}
+	parts := append(fallback, "raven-terminal")
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		return ".config/raven-terminal"
+		return filepath.Join(parts...)
 	}
-	return filepath.Join(homeDir, ".config", "raven-terminal")
+	return filepath.Join(append([]string{homeDir}, parts...)...)
+}
+
+// GetConfigDir returns the config directory path ($XDG_CONFIG_HOME)
+func GetConfigDir() string {
+	return xdgDir("XDG_CONFIG_HOME", ".config")
+}
+
+// GetStateDir returns the directory for history and logs that should
+// survive restarts but are not settings ($XDG_STATE_HOME)
+func GetStateDir() string {
+	return xdgDir("XDG_STATE_HOME", ".local", "state")
+}
+
+// GetCacheDir returns the directory for data that can be rebuilt at any time ($XDG_CACHE_HOME)
+func GetCacheDir() string {
+	return xdgDir("XDG_CACHE_HOME", ".cache")
+}
+
+// GetLogDir returns the directory log files are written to
+func GetLogDir() string {
+	return filepath.Join(GetStateDir(), "logs")
 }
 
 // GetConfigPath returns the path to the config file
@@ -356,8 +724,58 @@ func GetScriptsDir() string {
 	return filepath.Join(GetConfigDir(), "scripts")
 }
 
+// migrateLegacyConfig copies the config tree older versions kept in
+// ~/.config/raven-terminal, whatever XDG_CONFIG_HOME said, into the config
+// directory the first time one is used that differs from it: when the
+// config directory has no config.toml yet and the old one does
+func migrateLegacyConfig() error {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	legacy := filepath.Join(homeDir, ".config", "raven-terminal")
+	dir := GetConfigDir()
+	if filepath.Clean(legacy) == filepath.Clean(dir) {
+		return nil
+	}
+	if _, err := os.Stat(filepath.Join(dir, "config.toml")); !os.IsNotExist(err) {
+		return nil
+	}
+	if _, err := os.Stat(filepath.Join(legacy, "config.toml")); err != nil {
+		return nil
+	}
+	return filepath.WalkDir(legacy, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(legacy, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dir, rel)
+		if entry.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, data, info.Mode().Perm())
+	})
+}
+
 // Load loads the configuration from disk
 func Load() (*Config, error) {
+	if err := migrateLegacyConfig(); err != nil {
+		return nil, err
+	}
 	configPath := GetConfigPath()
 
 	// Ensure config directory exists
@@ -506,12 +924,22 @@ func (c *Config) WriteInitScript() (string, error) {
 	script += "    printf '\\e]7;file://%s%s\\a' \"$_host\" \"$PWD\"\n"
 	script += "}\n\n"
 
+	// Report the finished command's exit status, keeping $? for the prompt
+	script += "# Emit OSC 133;D with the exit status of the last command\n"
+	script += "__raven_command_done() {\n"
+	script += "    local _exit=$?\n"
+	script += "    printf '\\e]133;D;%s\\a' \"$_exit\"\n"
+	script += "    return $_exit\n"
+	script += "}\n\n"
+
 	// Add prompt building function based on style
 	script += c.buildPromptFunction()
 
 	// Add PROMPT_COMMAND
 	script += "\n# Set up prompt\n"
-	script += "PROMPT_COMMAND='__raven_prompt'\n"
+	script += "PROMPT_COMMAND='__raven_command_done; __raven_prompt'\n"
+	// Mark the start of command output (bash 4.4+), keeping the user's PS0
+	script += "PS0=\"${PS0}\"$'\\e]133;C\\a'\n"
 
 	// Add aliases
 	if len(c.Aliases) > 0 {
@@ -636,6 +1064,13 @@ func (c *Config) buildPromptFunction() string {
 		script += `    PS1="$_line1\n$_line2"` + "\n"
 	}
 
+	// Wrap the prompt in OSC 133 marks so the terminal can read the command
+	// being edited from the screen. A custom script may leave PS1 untouched,
+	// so only wrap it once.
+	script += `    case "$PS1" in` + "\n"
+	script += `        (*"133;B"*) ;;` + "\n"
+	script += `        (*) PS1="\[\e]133;A\a\]$PS1\[\e]133;B\a\]" ;;` + "\n"
+	script += `    esac` + "\n"
 	script += "    __raven_emit_osc7\n"
 	script += "}\n"
 	return script
@@ -659,6 +1094,22 @@ func (c *Config) RemoveCustomCommand(index int) {
 	}
 }
 
+// AddSnippet adds a new snippet
+func (c *Config) AddSnippet(name, body, description string) {
+	c.Snippets = append(c.Snippets, Snippet{
+		Name:        name,
+		Body:        body,
+		Description: description,
+	})
+}
+
+// RemoveSnippet removes a snippet by index
+func (c *Config) RemoveSnippet(index int) {
+	if index >= 0 && index < len(c.Snippets) {
+		c.Snippets = append(c.Snippets[:index], c.Snippets[index+1:]...)
+	}
+}
+
 // SetAlias sets an alias
 func (c *Config) SetAlias(name, command string) {
 	if c.Aliases == nil {
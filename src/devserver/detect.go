@@ -0,0 +1,133 @@
+package devserver
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// maxLineBuffer caps the partial line kept between PTY reads
+const maxLineBuffer = 2048
+
+// maxURLsPerPane caps how many distinct URLs are remembered for one pane
+const maxURLsPerPane = 16
+
+// localURLPattern matches URLs served from the local machine, e.g.
+// "Listening on http://localhost:3000" or "Local: http://127.0.0.1:5173/".
+var localURLPattern = regexp.MustCompile(`https?://(?:localhost|127\.0\.0\.1|0\.0\.0\.0|\[::1?\]|[A-Za-z0-9-]+\.local(?:host)?)(?::\d{2,5})?(?:/[^\s"'<>]*)?`)
+
+// Detector scans PTY output for dev-server URLs
+type Detector struct {
+	mu      sync.Mutex
+	line    []byte
+	escape  bool
+	csi     bool
+	osc     bool
+	urls    []string
+	version int
+}
+
+// NewDetector creates a new dev-server URL detector
+func NewDetector() *Detector {
+	return &Detector{}
+}
+
+// Feed consumes raw PTY output. Escape sequences are stripped and complete
+// lines are scanned for local URLs.
+func (d *Detector) Feed(data []byte) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, b := range data {
+		switch {
+		case d.osc:
+			// OSC ends with BEL or ST (ESC \)
+			if b == 0x07 {
+				d.osc = false
+			} else if b == 0x1b {
+				d.osc = false
+				d.escape = true
+			}
+			continue
+		case d.csi:
+			if b >= 0x40 && b <= 0x7e {
+				d.csi = false
+			}
+			continue
+		case d.escape:
+			d.escape = false
+			switch b {
+			case '[':
+				d.csi = true
+			case ']':
+				d.osc = true
+			}
+			continue
+		}
+
+		switch b {
+		case 0x1b:
+			d.escape = true
+		case '\n', '\r':
+			d.scanLine()
+		default:
+			if b >= 0x20 && len(d.line) < maxLineBuffer {
+				d.line = append(d.line, b)
+			}
+		}
+	}
+}
+
+// scanLine extracts URLs from the buffered line and resets it
+func (d *Detector) scanLine() {
+	if len(d.line) == 0 {
+		return
+	}
+	line := string(d.line)
+	d.line = d.line[:0]
+
+	for _, match := range localURLPattern.FindAllString(line, -1) {
+		url := normalizeURL(match)
+		if url == "" || d.hasURL(url) {
+			continue
+		}
+		d.urls = append(d.urls, url)
+		if len(d.urls) > maxURLsPerPane {
+			d.urls = d.urls[1:]
+		}
+		d.version++
+	}
+}
+
+func (d *Detector) hasURL(url string) bool {
+	for _, existing := range d.urls {
+		if existing == url {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeURL trims trailing punctuation and rewrites wildcard hosts to localhost
+func normalizeURL(url string) string {
+	url = strings.TrimRight(url, ".,;:)]}")
+	url = strings.Replace(url, "://0.0.0.0", "://localhost", 1)
+	url = strings.Replace(url, "://[::]", "://localhost", 1)
+	return strings.TrimSuffix(url, "/")
+}
+
+// URLs returns the detected URLs, oldest first
+func (d *Detector) URLs() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	result := make([]string, len(d.urls))
+	copy(result, d.urls)
+	return result
+}
+
+// Version increments each time a new URL is detected
+func (d *Detector) Version() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.version
+}
@@ -0,0 +1,149 @@
+package devserver
+
+// Entry is a detected URL labelled with the pane it was printed in
+type Entry struct {
+	TabIndex  int
+	PaneIndex int
+	URL       string
+}
+
+type Panel struct {
+	Open     bool
+	Focused  bool
+	Entries  []Entry
+	Selected int
+	Scroll   int
+}
+
+type Layout struct {
+	PanelX       float32
+	PanelY       float32
+	PanelWidth   float32
+	PanelHeight  float32
+	ContentX     float32
+	ContentWidth float32
+	LineHeight   float32
+	HeaderY      float32
+	ListStart    float32
+	ListEnd      float32
+	FooterY      float32
+	VisibleLines int
+}
+
+func NewPanel() *Panel {
+	return &Panel{}
+}
+
+func (p *Panel) Toggle() {
+	p.Open = !p.Open
+	if p.Open {
+		p.Focused = true
+	}
+}
+
+// SetEntries replaces the URL list, keeping the selection on the same URL when possible
+func (p *Panel) SetEntries(entries []Entry) {
+	selectedURL := ""
+	if entry, ok := p.SelectedEntry(); ok {
+		selectedURL = entry.URL
+	}
+
+	p.Entries = entries
+	p.Selected = 0
+	for i, entry := range entries {
+		if entry.URL == selectedURL {
+			p.Selected = i
+			break
+		}
+	}
+}
+
+// SelectedEntry returns the currently selected URL
+func (p *Panel) SelectedEntry() (Entry, bool) {
+	if p.Selected < 0 || p.Selected >= len(p.Entries) {
+		return Entry{}, false
+	}
+	return p.Entries[p.Selected], true
+}
+
+func (p *Panel) MoveSelection(delta int, visibleLines int) {
+	if len(p.Entries) == 0 {
+		return
+	}
+	p.Selected += delta
+	if p.Selected < 0 {
+		p.Selected = 0
+	}
+	if p.Selected >= len(p.Entries) {
+		p.Selected = len(p.Entries) - 1
+	}
+	if visibleLines <= 0 {
+		return
+	}
+	if p.Selected < p.Scroll {
+		p.Scroll = p.Selected
+	}
+	if p.Selected >= p.Scroll+visibleLines {
+		p.Scroll = p.Selected - visibleLines + 1
+	}
+}
+
+func (p *Panel) Layout(width, height int, cellWidth, cellHeight float32) Layout {
+	panelWidth := float32(width) * 0.35
+	minPanelWidth := float32(340)
+	if cellWidth > 0 {
+		wideMin := cellWidth * 36
+		if wideMin > minPanelWidth {
+			minPanelWidth = wideMin
+		}
+	}
+	if panelWidth < minPanelWidth {
+		panelWidth = minPanelWidth
+	}
+	if panelWidth > 560 {
+		panelWidth = 560
+	}
+	maxWidth := float32(width) - 20
+	if panelWidth > maxWidth {
+		panelWidth = maxWidth
+	}
+
+	panelHeight := float32(height) - 30
+	if panelHeight < 240 {
+		panelHeight = 240
+	}
+	if panelHeight > float32(height)-20 {
+		panelHeight = float32(height) - 20
+	}
+
+	panelX := float32(width) - panelWidth - 10
+	panelY := float32(10)
+
+	lineHeight := cellHeight * 1.35
+	contentX := panelX + 18
+	contentWidth := panelWidth - 36
+	headerY := panelY + lineHeight*1.2
+	listStart := headerY + lineHeight*1.6
+	footerY := panelY + panelHeight - lineHeight*0.6
+	listEnd := footerY - lineHeight*1.2
+
+	visibleLines := int((listEnd - listStart) / lineHeight)
+	if visibleLines < 1 {
+		visibleLines = 1
+	}
+
+	return Layout{
+		PanelX:       panelX,
+		PanelY:       panelY,
+		PanelWidth:   panelWidth,
+		PanelHeight:  panelHeight,
+		ContentX:     contentX,
+		ContentWidth: contentWidth,
+		LineHeight:   lineHeight,
+		HeaderY:      headerY,
+		ListStart:    listStart,
+		ListEnd:      listEnd,
+		FooterY:      footerY,
+		VisibleLines: visibleLines,
+	}
+}
@@ -0,0 +1,231 @@
+package diagnostics
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/javanhut/RavenTerminal/src/config"
+	"github.com/javanhut/RavenTerminal/src/redact"
+	"github.com/javanhut/RavenTerminal/src/version"
+)
+
+// crashLogName is the file in the log directory fatal errors are appended to
+const crashLogName = "crash.log"
+
+// maxLogBytes is how much of the end of each log file goes into a bundle
+const maxLogBytes = 512 << 10
+
+// envVars are the environment variables that describe the display and shell
+var envVars = []string{"TERM", "SHELL", "LANG", "XDG_SESSION_TYPE", "XDG_CURRENT_DESKTOP", "WAYLAND_DISPLAY", "DISPLAY"}
+
+// CaptureCrashes appends the trace of any fatal panic or runtime error, from
+// any goroutine, to crash.log in the log directory as well as stderr
+func CaptureCrashes() error {
+	path := filepath.Join(config.GetLogDir(), crashLogName)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	// The runtime keeps its own copy of the descriptor
+	defer f.Close()
+	return debug.SetCrashOutput(f, debug.CrashOptions{})
+}
+
+// DefaultPath returns where a new bundle is written: the home directory,
+// named for the current time
+func DefaultPath() string {
+	name := "raven-terminal-diagnostics-" + time.Now().Format("20060102-150405") + ".zip"
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return name
+	}
+	return filepath.Join(homeDir, name)
+}
+
+// Bundle is what goes into a diagnostics archive besides the log files.
+// Nothing is sent anywhere; the archive is only written to disk for the
+// user to attach to a bug report.
+type Bundle struct {
+	Config   *config.Config
+	Graphics []string // GPU and driver details from the window
+	State    []string // Other details of the running terminal, such as open tabs
+}
+
+// Write creates the zip archive at path with system.txt, the configuration
+// with secrets redacted, and the end of each log and crash file
+func (b Bundle) Write(path string) error {
+	var patterns []string
+	if b.Config != nil {
+		patterns = b.Config.Redaction.Patterns
+	}
+	// Broken user patterns are skipped; the built-in ones still apply
+	redactor, _ := redact.New(patterns)
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	archive := zip.NewWriter(f)
+	err = b.writeFiles(archive, redactor)
+	if closeErr := archive.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+	}
+	return err
+}
+
+func (b Bundle) writeFiles(archive *zip.Writer, redactor *redact.Redactor) error {
+	if err := addFile(archive, "system.txt", []byte(b.system())); err != nil {
+		return err
+	}
+	if b.Config != nil {
+		text, err := redactedConfig(b.Config)
+		if err != nil {
+			return err
+		}
+		if err := addFile(archive, "config.toml", redactLines(text, redactor)); err != nil {
+			return err
+		}
+	}
+
+	entries, err := os.ReadDir(config.GetLogDir())
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		data, err := tail(filepath.Join(config.GetLogDir(), entry.Name()), maxLogBytes)
+		if err != nil {
+			return err
+		}
+		if err := addFile(archive, "logs/"+entry.Name(), redactLines(data, redactor)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// system describes the build, platform, graphics and running state
+func (b Bundle) system() string {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	lines := []string{
+		"Raven Terminal " + version.Version,
+		"Created: " + time.Now().Format(time.RFC3339),
+		"Platform: " + runtime.GOOS + "/" + runtime.GOARCH,
+		"Go: " + runtime.Version(),
+		fmt.Sprintf("Goroutines: %d", runtime.NumGoroutine()),
+		fmt.Sprintf("Heap: %.1f MiB in use, %.1f MiB from the system", float64(mem.HeapInuse)/(1<<20), float64(mem.Sys)/(1<<20)),
+		"",
+		"[graphics]",
+	}
+	lines = append(lines, b.Graphics...)
+	lines = append(lines, "", "[environment]")
+	for _, name := range envVars {
+		if value, ok := os.LookupEnv(name); ok {
+			lines = append(lines, name+"="+value)
+		}
+	}
+	if len(b.State) > 0 {
+		lines = append(lines, "", "[state]")
+		lines = append(lines, b.State...)
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// redactedConfig encodes cfg with exported variable values and the lock
+// passphrase hash removed and passwords taken out of URLs; redactLines masks
+// anything else that looks like a credential
+func redactedConfig(cfg *config.Config) ([]byte, error) {
+	c := *cfg
+	c.Lock.PassphraseSHA256 = ""
+	c.Exports = make(map[string]string, len(cfg.Exports))
+	for name := range cfg.Exports {
+		c.Exports[name] = "[redacted]"
+	}
+	c.Network.Proxy = redactURL(c.Network.Proxy)
+	c.Ollama.URL = redactURL(c.Ollama.URL)
+
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(c); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.User == nil {
+		return raw
+	}
+	return u.Redacted()
+}
+
+func redactLines(data []byte, redactor *redact.Redactor) []byte {
+	var out bytes.Buffer
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), len(data)+1)
+	for scanner.Scan() {
+		out.WriteString(redactor.Redact(scanner.Text()))
+		out.WriteByte('\n')
+	}
+	return out.Bytes()
+}
+
+// tail reads up to the last max bytes of path, starting at a line boundary
+func tail(path string, max int64) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() <= max {
+		return io.ReadAll(f)
+	}
+	if _, err := f.Seek(info.Size()-max, io.SeekStart); err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		data = data[i+1:]
+	}
+	return data, nil
+}
+
+func addFile(archive *zip.Writer, name string, data []byte) error {
+	w, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
@@ -0,0 +1,164 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MaxLines caps how many lines of each side are compared
+const MaxLines = 2000
+
+// contextLines is how many unchanged lines surround each change in a hunk
+const contextLines = 3
+
+// op is one line of an edit script
+type op struct {
+	kind byte // ' ', '-' or '+'
+	text string
+	a, b int // Line numbers in a and b before this line
+}
+
+// Unified returns a unified diff of a and b, or "" when they are equal.
+// Each side is truncated to MaxLines lines.
+func Unified(a, b []string, nameA, nameB string) string {
+	if len(a) > MaxLines {
+		a = a[:MaxLines]
+	}
+	if len(b) > MaxLines {
+		b = b[:MaxLines]
+	}
+	ops := script(a, b)
+
+	changed := false
+	for _, o := range ops {
+		if o.kind != ' ' {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return ""
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", nameA, nameB)
+	for start := 0; start < len(ops); {
+		// Find the next change and grow the hunk until a long unchanged run
+		first := start
+		for first < len(ops) && ops[first].kind == ' ' {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+		from := max(first-contextLines, start)
+		end := first
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].kind == ' ' {
+				run++
+			}
+			if run == len(ops) || run-end > 2*contextLines {
+				end = min(end+contextLines, len(ops))
+				break
+			}
+			end = run
+		}
+
+		countA, countB := 0, 0
+		for _, o := range ops[from:end] {
+			if o.kind != '+' {
+				countA++
+			}
+			if o.kind != '-' {
+				countB++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(ops[from].a, countA), hunkRange(ops[from].b, countB))
+		for _, o := range ops[from:end] {
+			out.WriteByte(o.kind)
+			out.WriteString(o.text)
+			out.WriteByte('\n')
+		}
+		start = end
+	}
+	return out.String()
+}
+
+// hunkRange formats a hunk's start line and length the way diff -u does
+func hunkRange(before, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", before)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", before+1)
+	}
+	return fmt.Sprintf("%d,%d", before+1, count)
+}
+
+// script builds the shortest edit script from the longest common subsequence
+func script(a, b []string) []op {
+	n, m := len(a), len(b)
+	// lcs[i][j] is the LCS length of a[i:] and b[j:]
+	lcs := make([][]int32, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	ops := make([]op, 0, n+m)
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && a[i] == b[j]:
+			ops = append(ops, op{kind: ' ', text: a[i], a: i, b: j})
+			i++
+			j++
+		case i < n && (j == m || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, op{kind: '-', text: a[i], a: i, b: j})
+			i++
+		default:
+			ops = append(ops, op{kind: '+', text: b[j], a: i, b: j})
+			j++
+		}
+	}
+	return ops
+}
+
+// Colorize adds ANSI colors to a unified diff: removals red, additions green
+// and hunk headers cyan
+func Colorize(unified string) string {
+	var out strings.Builder
+	for _, line := range strings.SplitAfter(unified, "\n") {
+		if line == "" {
+			continue
+		}
+		text := strings.TrimSuffix(line, "\n")
+		switch {
+		case strings.HasPrefix(text, "---"), strings.HasPrefix(text, "+++"):
+			out.WriteString("\x1b[1m" + text + "\x1b[0m")
+		case strings.HasPrefix(text, "@@"):
+			out.WriteString("\x1b[36m" + text + "\x1b[0m")
+		case strings.HasPrefix(text, "-"):
+			out.WriteString("\x1b[31m" + text + "\x1b[0m")
+		case strings.HasPrefix(text, "+"):
+			out.WriteString("\x1b[32m" + text + "\x1b[0m")
+		default:
+			out.WriteString(text)
+		}
+		out.WriteString("\n")
+	}
+	return out.String()
+}
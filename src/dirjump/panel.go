@@ -0,0 +1,152 @@
+package dirjump
+
+// MaxResults caps how many directories the picker lists
+const MaxResults = 50
+
+type Panel struct {
+	Open     bool
+	Focused  bool
+	Query    string
+	Results  []Entry
+	Selected int
+	Scroll   int
+}
+
+type Layout struct {
+	PanelX       float32
+	PanelY       float32
+	PanelWidth   float32
+	PanelHeight  float32
+	ContentX     float32
+	ContentWidth float32
+	LineHeight   float32
+	HeaderY      float32
+	InputBoxY    float32
+	ListStart    float32
+	ListEnd      float32
+	FooterY      float32
+	VisibleLines int
+}
+
+func NewPanel() *Panel {
+	return &Panel{}
+}
+
+func (p *Panel) Toggle() {
+	p.Open = !p.Open
+	if p.Open {
+		p.Focused = true
+		p.Query = ""
+		p.Selected = 0
+		p.Scroll = 0
+	}
+}
+
+// SetResults replaces the matched directories and resets the selection
+func (p *Panel) SetResults(results []Entry) {
+	p.Results = results
+	p.Selected = 0
+	p.Scroll = 0
+}
+
+// SelectedEntry returns the currently selected directory
+func (p *Panel) SelectedEntry() (Entry, bool) {
+	if p.Selected < 0 || p.Selected >= len(p.Results) {
+		return Entry{}, false
+	}
+	return p.Results[p.Selected], true
+}
+
+func (p *Panel) AppendQuery(r rune) {
+	p.Query += string(r)
+}
+
+func (p *Panel) Backspace() {
+	if p.Query == "" {
+		return
+	}
+	runes := []rune(p.Query)
+	p.Query = string(runes[:len(runes)-1])
+}
+
+func (p *Panel) MoveSelection(delta int, visibleLines int) {
+	if len(p.Results) == 0 {
+		return
+	}
+	p.Selected += delta
+	if p.Selected < 0 {
+		p.Selected = 0
+	}
+	if p.Selected >= len(p.Results) {
+		p.Selected = len(p.Results) - 1
+	}
+	if visibleLines <= 0 {
+		return
+	}
+	if p.Selected < p.Scroll {
+		p.Scroll = p.Selected
+	}
+	if p.Selected >= p.Scroll+visibleLines {
+		p.Scroll = p.Selected - visibleLines + 1
+	}
+}
+
+func (p *Panel) Layout(width, height int, cellWidth, cellHeight float32) Layout {
+	panelWidth := float32(width) * 0.5
+	minPanelWidth := float32(420)
+	if cellWidth > 0 {
+		wideMin := cellWidth * 48
+		if wideMin > minPanelWidth {
+			minPanelWidth = wideMin
+		}
+	}
+	if panelWidth < minPanelWidth {
+		panelWidth = minPanelWidth
+	}
+	if panelWidth > 820 {
+		panelWidth = 820
+	}
+	maxWidth := float32(width) - 20
+	if panelWidth > maxWidth {
+		panelWidth = maxWidth
+	}
+
+	lineHeight := cellHeight * 1.35
+	panelHeight := lineHeight * 16
+	if panelHeight > float32(height)-40 {
+		panelHeight = float32(height) - 40
+	}
+
+	// Centered near the top like a command palette
+	panelX := (float32(width) - panelWidth) / 2
+	panelY := float32(height) * 0.12
+
+	contentX := panelX + 18
+	contentWidth := panelWidth - 36
+	headerY := panelY + lineHeight*1.2
+	inputBoxY := headerY + lineHeight*0.6
+	listStart := inputBoxY + lineHeight*2.2
+	footerY := panelY + panelHeight - lineHeight*0.6
+	listEnd := footerY - lineHeight*1.2
+
+	visibleLines := int((listEnd - listStart) / lineHeight)
+	if visibleLines < 1 {
+		visibleLines = 1
+	}
+
+	return Layout{
+		PanelX:       panelX,
+		PanelY:       panelY,
+		PanelWidth:   panelWidth,
+		PanelHeight:  panelHeight,
+		ContentX:     contentX,
+		ContentWidth: contentWidth,
+		LineHeight:   lineHeight,
+		HeaderY:      headerY,
+		InputBoxY:    inputBoxY,
+		ListStart:    listStart,
+		ListEnd:      listEnd,
+		FooterY:      footerY,
+		VisibleLines: visibleLines,
+	}
+}
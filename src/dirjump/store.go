@@ -0,0 +1,204 @@
+package dirjump
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/javanhut/RavenTerminal/src/config"
+)
+
+// maxEntries caps the remembered directories; the lowest scoring are dropped first
+const maxEntries = 500
+
+// Entry is a visited directory with its visit statistics
+type Entry struct {
+	Path      string `toml:"path"`
+	Visits    int    `toml:"visits"`
+	LastVisit int64  `toml:"last_visit"` // Unix seconds
+}
+
+// Score ranks the entry by frecency: visit count weighted by how recently it was visited
+func (e Entry) Score(now time.Time) float64 {
+	age := now.Sub(time.Unix(e.LastVisit, 0))
+	weight := 0.25
+	switch {
+	case age < time.Hour:
+		weight = 4
+	case age < 24*time.Hour:
+		weight = 2
+	case age < 7*24*time.Hour:
+		weight = 0.5
+	}
+	return float64(e.Visits) * weight
+}
+
+type storeFile struct {
+	Dirs []Entry `toml:"dirs"`
+}
+
+// Store remembers visited directories across sessions
+type Store struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]*Entry
+	dirty   bool
+}
+
+// DefaultPath returns the directory history file in the state directory.
+// A history left in the config directory by older versions is moved there.
+func DefaultPath() string {
+	path := filepath.Join(config.GetStateDir(), "dirs.toml")
+	legacy := filepath.Join(config.GetConfigDir(), "dirs.toml")
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if _, err := os.Stat(legacy); err == nil && os.MkdirAll(filepath.Dir(path), 0755) == nil {
+			os.Rename(legacy, path)
+		}
+	}
+	return path
+}
+
+// Load reads the store from path. A missing file yields an empty store.
+func Load(path string) (*Store, error) {
+	s := &Store{path: path, entries: make(map[string]*Entry)}
+	var file storeFile
+	if _, err := toml.DecodeFile(path, &file); err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return s, err
+	}
+	for i := range file.Dirs {
+		entry := file.Dirs[i]
+		if entry.Path != "" {
+			s.entries[entry.Path] = &entry
+		}
+	}
+	return s, nil
+}
+
+// Visit records a visit to dir
+func (s *Store) Visit(dir string, now time.Time) {
+	if dir == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[dir]
+	if !ok {
+		entry = &Entry{Path: dir}
+		s.entries[dir] = entry
+	}
+	entry.Visits++
+	entry.LastVisit = now.Unix()
+	s.dirty = true
+
+	if len(s.entries) > maxEntries {
+		s.prune(now)
+	}
+}
+
+// prune drops the lowest scoring entries until the store fits maxEntries
+func (s *Store) prune(now time.Time) {
+	ranked := s.ranked(now)
+	for _, entry := range ranked[maxEntries:] {
+		delete(s.entries, entry.Path)
+	}
+}
+
+// ranked returns all entries sorted by descending score
+func (s *Store) ranked(now time.Time) []Entry {
+	result := make([]Entry, 0, len(s.entries))
+	for _, entry := range s.entries {
+		result = append(result, *entry)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		si, sj := result[i].Score(now), result[j].Score(now)
+		if si != sj {
+			return si > sj
+		}
+		return result[i].Path < result[j].Path
+	})
+	return result
+}
+
+// Remove forgets dir
+func (s *Store) Remove(dir string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.entries[dir]; ok {
+		delete(s.entries, dir)
+		s.dirty = true
+	}
+}
+
+// Clear forgets every directory
+func (s *Store) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = make(map[string]*Entry)
+	s.dirty = true
+}
+
+// Match returns directories that fuzzy match query, best first. Directories
+// that no longer exist are skipped.
+func (s *Store) Match(query string, limit int, now time.Time) []Entry {
+	s.mu.Lock()
+	ranked := s.ranked(now)
+	s.mu.Unlock()
+
+	query = strings.ToLower(strings.TrimSpace(query))
+	var result []Entry
+	for _, entry := range ranked {
+		if limit > 0 && len(result) >= limit {
+			break
+		}
+		if query != "" && !fuzzyMatch(strings.ToLower(entry.Path), query) {
+			continue
+		}
+		if info, err := os.Stat(entry.Path); err != nil || !info.IsDir() {
+			continue
+		}
+		result = append(result, entry)
+	}
+	return result
+}
+
+// fuzzyMatch reports whether every rune of query appears in text in order
+func fuzzyMatch(text, query string) bool {
+	for _, r := range query {
+		i := strings.IndexRune(text, r)
+		if i < 0 {
+			return false
+		}
+		text = text[i+len(string(r)):]
+	}
+	return true
+}
+
+// Save writes the store to disk if it changed since the last save
+func (s *Store) Save() error {
+	s.mu.Lock()
+	if !s.dirty {
+		s.mu.Unlock()
+		return nil
+	}
+	file := storeFile{Dirs: s.ranked(time.Now())}
+	s.dirty = false
+	s.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(s.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return toml.NewEncoder(f).Encode(file)
+}
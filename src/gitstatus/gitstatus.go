@@ -0,0 +1,87 @@
+package gitstatus
+
+import (
+	"bufio"
+	"context"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// QueryTimeout bounds a single git invocation so slow repositories never pile up
+const QueryTimeout = 2 * time.Second
+
+// Status is the branch and working tree state of a git repository
+type Status struct {
+	Branch string
+	Dirty  bool
+	Ahead  int
+	Behind int
+}
+
+// Query runs git in dir and returns its status. ok is false when dir is not
+// inside a git work tree or git is unavailable.
+func Query(ctx context.Context, dir string) (status Status, ok bool) {
+	if dir == "" {
+		return Status{}, false
+	}
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "git", "-C", dir, "status", "--porcelain=v2", "--branch", "--no-renames")
+	cmd.Env = append(cmd.Environ(), "GIT_OPTIONAL_LOCKS=0")
+	out, err := cmd.Output()
+	if err != nil {
+		return Status{}, false
+	}
+	return parse(string(out)), true
+}
+
+// parse reads `git status --porcelain=v2 --branch` output
+func parse(out string) Status {
+	var status Status
+	oid := ""
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "# branch.head "):
+			status.Branch = strings.TrimPrefix(line, "# branch.head ")
+		case strings.HasPrefix(line, "# branch.oid "):
+			oid = strings.TrimPrefix(line, "# branch.oid ")
+		case strings.HasPrefix(line, "# branch.ab "):
+			fields := strings.Fields(strings.TrimPrefix(line, "# branch.ab "))
+			if len(fields) == 2 {
+				status.Ahead, _ = strconv.Atoi(strings.TrimPrefix(fields[0], "+"))
+				status.Behind, _ = strconv.Atoi(strings.TrimPrefix(fields[1], "-"))
+			}
+		case strings.HasPrefix(line, "#"):
+		case line != "":
+			status.Dirty = true
+		}
+	}
+	// A detached HEAD is shown as its abbreviated commit
+	if status.Branch == "(detached)" && len(oid) >= 7 {
+		status.Branch = oid[:7]
+	}
+	return status
+}
+
+// Label formats the status as a compact tab bar indicator, e.g. "main*+2-1"
+func (s Status) Label() string {
+	if s.Branch == "" {
+		return ""
+	}
+	label := s.Branch
+	if s.Dirty {
+		label += "*"
+	}
+	if s.Ahead > 0 {
+		label += "+" + strconv.Itoa(s.Ahead)
+	}
+	if s.Behind > 0 {
+		label += "-" + strconv.Itoa(s.Behind)
+	}
+	return label
+}
@@ -0,0 +1,88 @@
+package grid
+
+import "sort"
+
+// LastJump names the mark holding the position the last jump left, so a
+// second jump can go back there
+const LastJump = '\''
+
+// AltScreenExit names the mark set where the main screen picks up again when
+// a full-screen program exits
+const AltScreenExit = '^'
+
+// SetBookmark saves the scroll position under name. The mark remembers the
+// absolute line at the top of the view, so it follows that text as output
+// pushes it into the scrollback.
+func (g *Grid) SetBookmark(name rune) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.bookmarks == nil {
+		g.bookmarks = make(map[rune]int)
+	}
+	g.bookmarks[name] = g.scrolled - g.scrollOffset
+}
+
+// SetBookmarkAtCursor saves the cursor row under name, so jumping to the
+// mark brings that row to the top of the view
+func (g *Grid) SetBookmarkAtCursor(name rune) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.bookmarks == nil {
+		g.bookmarks = make(map[rune]int)
+	}
+	g.bookmarks[name] = g.scrolled + g.CursorRow
+}
+
+// JumpToBookmark scrolls back to the position saved under name and saves the
+// position it leaves under LastJump. It returns false when there is no such
+// mark or its text has left the scrollback.
+func (g *Grid) JumpToBookmark(name rune) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	line, ok := g.bookmarks[name]
+	if !ok {
+		return false
+	}
+	if line < g.scrolled-len(g.scrollback) {
+		delete(g.bookmarks, name)
+		return false
+	}
+	g.bookmarks[LastJump] = g.scrolled - g.scrollOffset
+	g.scrollOffset = min(max(g.scrolled-line, 0), len(g.scrollback))
+	return true
+}
+
+// Bookmarks returns the names of the marks whose text is still in the
+// scrollback or on screen, in order
+func (g *Grid) Bookmarks() []rune {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	oldest := g.scrolled - len(g.scrollback)
+	var names []rune
+	for name, line := range g.bookmarks {
+		if line >= oldest {
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
+
+// DisplayBookmarks returns the names of the marks set at a displayed row
+func (g *Grid) DisplayBookmarks(row int) []rune {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.displayBookmarksLocked(row)
+}
+
+func (g *Grid) displayBookmarksLocked(row int) []rune {
+	line := g.scrolled - g.scrollOffset + row
+	var names []rune
+	for name, marked := range g.bookmarks {
+		if marked == line && name != LastJump {
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
@@ -0,0 +1,55 @@
+package grid
+
+// ScreenRows returns a copy of the screen's rows, without the blank rows
+// at the bottom
+func (g *Grid) ScreenRows() [][]Cell {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	last := g.Rows - 1
+	for ; last >= 0; last-- {
+		if !blankRow(g.cells[last*g.Cols : (last+1)*g.Cols]) {
+			break
+		}
+	}
+	rows := make([][]Cell, last+1)
+	for row := range rows {
+		rows[row] = make([]Cell, g.Cols)
+		copy(rows[row], g.cells[row*g.Cols:(row+1)*g.Cols])
+	}
+	return rows
+}
+
+// WriteRows writes rows at the cursor as if they were output, each on its
+// own line, scrolling older lines into the scrollback. The cursor is left at
+// the start of the line after them.
+func (g *Grid) WriteRows(rows [][]Cell) {
+	if len(rows) == 0 {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.CursorCol > 0 {
+		g.cursorNewline()
+	}
+	blank := NewCellWithBg(g.eraseBg)
+	for _, row := range rows {
+		line := g.cells[g.CursorRow*g.Cols : (g.CursorRow+1)*g.Cols]
+		copied := copy(line, row)
+		for col := copied; col < len(line); col++ {
+			line[col] = blank
+		}
+		g.cursorNewline()
+	}
+}
+
+// blankRow reports whether a row holds nothing but spaces
+func blankRow(row []Cell) bool {
+	for _, cell := range row {
+		if cell.Char != ' ' && cell.Char != 0 {
+			return false
+		}
+	}
+	return true
+}
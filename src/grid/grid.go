@@ -3,6 +3,7 @@ package grid
 import (
 	"strings"
 	"sync"
+	"time"
 )
 
 const (
@@ -10,7 +11,7 @@ const (
 )
 
 // CellFlags represents text attributes
-type CellFlags uint8
+type CellFlags uint16
 
 const (
 	FlagBold CellFlags = 1 << iota
@@ -20,6 +21,17 @@ const (
 	FlagInverse
 	FlagHidden
 	FlagStrikethrough
+	// FlagWrapped marks the last cell of a row whose text auto-wrapped onto
+	// the next row; it is not a display attribute
+	FlagWrapped
+	// FlagWritten marks cells printed by output, as opposed to blank or
+	// erased cells, so written trailing spaces can be told apart
+	FlagWritten
+	// FlagTab marks the cell where a horizontal tab started
+	FlagTab
+	// FlagWidget marks cells reserved for an inline widget, which is drawn
+	// over them until they are overwritten or erased
+	FlagWidget
 )
 
 // ColorType identifies the type of color
@@ -131,6 +143,26 @@ type Grid struct {
 
 	// BCE (Background Color Erase) - background color for scroll/erase operations
 	eraseBg Color
+
+	// Font scale relative to the renderer font size (per-pane zoom)
+	fontScale float32
+
+	// Art mode draws cells flush and copies selections untrimmed, for ANSI art
+	artMode bool
+
+	// Total rows pushed into the scrollback, used to track marks as output scrolls
+	scrolled int
+
+	// Inline widgets keyed by absolute line and starting column
+	widgets map[widgetKey]Widget
+
+	// When output first reached each row, keyed by absolute line
+	stamps      map[int]time.Time
+	stampedLine int // Absolute line last stamped, plus one; 0 for none
+
+	// Named scroll positions, keyed by name with the absolute line at the top of the view
+	bookmarks map[rune]int
+	prompts   []int // Absolute lines where marked prompts end, in order
 }
 
 // NewGrid creates a new grid with the given dimensions
@@ -152,6 +184,7 @@ func NewGrid(cols, rows int) *Grid {
 		wrapPending:  false,
 		lastChar:     ' ',
 		autoWrap:     true, // DECAWM ?7 default on
+		fontScale:    1.0,
 	}
 }
 
@@ -187,7 +220,7 @@ func (g *Grid) WriteChar(c rune, fg, bg Color, flags CellFlags) {
 
 	if g.wrapPending {
 		if g.autoWrap {
-			g.cursorNewline()
+			g.softWrap()
 		}
 		g.wrapPending = false
 	}
@@ -195,7 +228,7 @@ func (g *Grid) WriteChar(c rune, fg, bg Color, flags CellFlags) {
 	// Handle auto-wrap if at end of line
 	if g.CursorCol >= g.Cols {
 		if g.autoWrap {
-			g.cursorNewline()
+			g.softWrap()
 		} else {
 			// No auto-wrap: stay at last column, overwrite
 			g.CursorCol = g.Cols - 1
@@ -221,7 +254,7 @@ func (g *Grid) WriteChar(c rune, fg, bg Color, flags CellFlags) {
 				Bg:    g.lastBg,
 				Width: CellWidthNormal,
 			}
-			g.cursorNewline()
+			g.softWrap()
 		} else {
 			// No auto-wrap: treat wide char as single width at last column
 			charWidth = 1
@@ -229,12 +262,13 @@ func (g *Grid) WriteChar(c rune, fg, bg Color, flags CellFlags) {
 	}
 
 	// Write the character to current cell
+	g.stampLocked()
 	idx := g.index(g.CursorCol, g.CursorRow)
 	g.cells[idx] = Cell{
 		Char:  c,
 		Fg:    fg,
 		Bg:    bg,
-		Flags: flags,
+		Flags: flags | FlagWritten,
 		Width: uint8(charWidth),
 	}
 	g.CursorCol++
@@ -267,6 +301,23 @@ func (g *Grid) WriteChar(c rune, fg, bg Color, flags CellFlags) {
 	g.lastFlags = flags
 }
 
+// setWrapped puts the wrap state of row on its last cell, clearing
+// FlagWrapped from any other cell a shift or resize moved it to
+func setWrapped(row []Cell, wrapped bool) {
+	for i := range row {
+		row[i].Flags &^= FlagWrapped
+	}
+	if wrapped && len(row) > 0 {
+		row[len(row)-1].Flags |= FlagWrapped
+	}
+}
+
+// softWrap marks the cursor row as wrapped and moves to the next line (internal, no lock)
+func (g *Grid) softWrap() {
+	g.cells[g.index(g.Cols-1, g.CursorRow)].Flags |= FlagWrapped
+	g.cursorNewline()
+}
+
 // cursorNewline moves cursor to next line (internal, no lock)
 func (g *Grid) cursorNewline() {
 	g.wrapPending = false
@@ -302,6 +353,7 @@ func (g *Grid) scrollUpRegionWithBg(bg Color) {
 		topRow := make([]Cell, g.Cols)
 		copy(topRow, g.cells[0:g.Cols])
 		g.scrollback = append(g.scrollback, topRow)
+		g.scrolled++
 
 		if len(g.scrollback) > MaxScrollback {
 			g.scrollback = g.scrollback[1:]
@@ -358,6 +410,9 @@ func (g *Grid) Tab() {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 	g.wrapPending = false
+	if g.CursorCol < g.Cols-1 {
+		g.cells[g.index(g.CursorCol, g.CursorRow)].Flags |= FlagTab
+	}
 	g.CursorCol = ((g.CursorCol / 8) + 1) * 8
 	if g.CursorCol >= g.Cols {
 		g.CursorCol = g.Cols - 1
@@ -464,6 +519,7 @@ func (g *Grid) scrollUpInternalWithBg(bg Color) {
 	topRow := make([]Cell, g.Cols)
 	copy(topRow, g.cells[0:g.Cols])
 	g.scrollback = append(g.scrollback, topRow)
+	g.scrolled++
 
 	// Trim scrollback if too large
 	if len(g.scrollback) > MaxScrollback {
@@ -483,6 +539,7 @@ func (g *Grid) scrollUpInternalWithBg(bg Color) {
 func (g *Grid) ScrollUp(n int) {
 	g.mu.Lock()
 	defer g.mu.Unlock()
+	n = min(n, g.scrollBottom-g.scrollTop+1)
 	for i := 0; i < n; i++ {
 		g.scrollUpRegion()
 	}
@@ -492,6 +549,7 @@ func (g *Grid) ScrollUp(n int) {
 func (g *Grid) ScrollUpWithBg(n int, bg Color) {
 	g.mu.Lock()
 	defer g.mu.Unlock()
+	n = min(n, g.scrollBottom-g.scrollTop+1)
 	for i := 0; i < n; i++ {
 		g.scrollUpRegionWithBg(bg)
 	}
@@ -545,6 +603,7 @@ func (g *Grid) scrollDownRegionWithBg(bg Color) {
 func (g *Grid) ScrollDown(n int) {
 	g.mu.Lock()
 	defer g.mu.Unlock()
+	n = min(n, g.scrollBottom-g.scrollTop+1)
 	for i := 0; i < n; i++ {
 		g.scrollDownRegion()
 	}
@@ -554,6 +613,7 @@ func (g *Grid) ScrollDown(n int) {
 func (g *Grid) ScrollDownWithBg(n int, bg Color) {
 	g.mu.Lock()
 	defer g.mu.Unlock()
+	n = min(n, g.scrollBottom-g.scrollTop+1)
 	for i := 0; i < n; i++ {
 		g.scrollDownRegionWithBg(bg)
 	}
@@ -586,6 +646,35 @@ func (g *Grid) ResetScrollOffset() {
 	g.scrollOffset = 0
 }
 
+// ViewTop returns the absolute line at the top of the view
+func (g *Grid) ViewTop() int {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.scrolled - g.scrollOffset
+}
+
+// DisplayLine returns the 1-based absolute line number of a displayed row,
+// counted from the first line the pane printed, and false for the rows
+// below the cursor that nothing has reached yet
+func (g *Grid) DisplayLine(row int) (int, bool) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.displayLineLocked(row)
+}
+
+func (g *Grid) displayLineLocked(row int) (int, bool) {
+	line := g.scrolled - g.scrollOffset + row
+	return line + 1, line <= g.scrolled+g.CursorRow
+}
+
+// ScrollViewTo scrolls the view so the absolute line is at its top, as near
+// as the scrollback allows
+func (g *Grid) ScrollViewTo(line int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.scrollOffset = min(max(g.scrolled-line, 0), len(g.scrollback))
+}
+
 // GetScrollOffset returns the current scroll offset
 func (g *Grid) GetScrollOffset() int {
 	g.mu.RLock()
@@ -638,6 +727,9 @@ func (g *Grid) VisibleText() string {
 		b.Grow(g.Cols)
 		for col := 0; col < g.Cols; col++ {
 			cell := g.displayCellLocked(col, row)
+			if cell.Width == CellWidthContinuation {
+				continue
+			}
 			ch := cell.Char
 			if ch == 0 {
 				ch = ' '
@@ -650,6 +742,134 @@ func (g *Grid) VisibleText() string {
 	return strings.TrimRight(strings.Join(lines, "\n"), "\n")
 }
 
+// ScrolledLines returns how many rows have been pushed into the scrollback
+func (g *Grid) ScrolledLines() int {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.scrolled
+}
+
+// LinesSince returns the rows completed since the absolute line mark (as
+// returned by a previous call) up to the cursor row, and the new mark. Rows
+// that have already left the scrollback are skipped.
+func (g *Grid) LinesSince(mark int) ([]string, int) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	current := g.scrolled + g.CursorRow
+	if mark < g.scrolled-len(g.scrollback) {
+		mark = g.scrolled - len(g.scrollback)
+	}
+	var lines []string
+	for abs := mark; abs < current; abs++ {
+		lines = append(lines, strings.TrimRight(rowText(g.absRowLocked(abs)), " "))
+	}
+	return lines, current
+}
+
+// TextBetween returns the rows from absolute line start up to but not
+// including end, joining auto-wrapped rows. Rows that have already left the
+// scrollback are skipped and trailing blank lines are dropped.
+func (g *Grid) TextBetween(start, end int) string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	if start < g.scrolled-len(g.scrollback) {
+		start = g.scrolled - len(g.scrollback)
+	}
+	if end > g.scrolled+g.Rows {
+		end = g.scrolled + g.Rows
+	}
+	var out strings.Builder
+	for abs := start; abs < end; abs++ {
+		row := g.absRowLocked(abs)
+		if abs < end-1 && len(row) > 0 && row[len(row)-1].Flags&FlagWrapped != 0 {
+			out.WriteString(rowText(row))
+			continue
+		}
+		out.WriteString(strings.TrimRight(rowText(row), " "))
+		out.WriteByte('\n')
+	}
+	return strings.TrimRight(out.String(), "\n")
+}
+
+// AllText returns the whole scrollback followed by the screen as plain text
+func (g *Grid) AllText() string {
+	g.mu.RLock()
+	start := g.scrolled - len(g.scrollback)
+	end := g.scrolled + g.Rows
+	g.mu.RUnlock()
+	return g.TextBetween(start, end)
+}
+
+// absRowLocked returns the cells of an absolute line, which must still be in
+// the scrollback or on screen
+func (g *Grid) absRowLocked(abs int) []Cell {
+	if abs < g.scrolled {
+		return g.scrollback[len(g.scrollback)-(g.scrolled-abs)]
+	}
+	start := (abs - g.scrolled) * g.Cols
+	return g.cells[start : start+g.Cols]
+}
+
+// rowText returns a row's characters with blanks as spaces
+func rowText(row []Cell) string {
+	var b strings.Builder
+	for _, cell := range row {
+		if cell.Width == CellWidthContinuation {
+			continue
+		}
+		ch := cell.Char
+		if ch == 0 {
+			ch = ' '
+		}
+		b.WriteRune(ch)
+	}
+	return b.String()
+}
+
+// TextFrom returns the text from (col, row) through the cursor row, following
+// auto-wrapped rows below the cursor. Rows are joined without separators so a
+// wrapped line reads back as one line.
+func (g *Grid) TextFrom(col, row int) string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	if row < 0 || row >= g.Rows {
+		return ""
+	}
+	if col < 0 {
+		col = 0
+	}
+	end := g.CursorRow
+	if end < row {
+		end = row
+	}
+	for end+1 < g.Rows && g.cells[g.index(g.Cols-1, end)].Flags&FlagWrapped != 0 {
+		end++
+	}
+
+	var b strings.Builder
+	for r := row; r <= end; r++ {
+		start := 0
+		if r == row {
+			start = col
+		}
+		for c := start; c < g.Cols; c++ {
+			cell := g.cells[g.index(c, r)]
+			if cell.Width == CellWidthContinuation {
+				continue
+			}
+			ch := cell.Char
+			if ch == 0 {
+				ch = ' '
+			}
+			b.WriteRune(ch)
+		}
+	}
+	return strings.TrimRight(b.String(), " ")
+}
+
 // SetSelection sets the selection bounds in display coordinates.
 func (g *Grid) SetSelection(startCol, startRow, endCol, endRow int) {
 	g.mu.Lock()
@@ -664,6 +884,16 @@ func (g *Grid) SetSelection(startCol, startRow, endCol, endRow int) {
 	startRow = clampInt(startRow, 0, g.Rows-1)
 	endRow = clampInt(endRow, 0, g.Rows-1)
 
+	// Widen both ends to whole wide characters, whichever way the
+	// selection was dragged
+	if endRow < startRow || (endRow == startRow && endCol < startCol) {
+		endCol = g.charStartLocked(endCol, endRow)
+		startCol = g.charEndLocked(startCol, startRow)
+	} else {
+		startCol = g.charStartLocked(startCol, startRow)
+		endCol = g.charEndLocked(endCol, endRow)
+	}
+
 	g.selectionActive = true
 	g.selectionStartCol = startCol
 	g.selectionStartRow = startRow
@@ -672,6 +902,31 @@ func (g *Grid) SetSelection(startCol, startRow, endCol, endRow int) {
 	g.selectionScrollOffset = g.scrollOffset
 }
 
+// CharStart returns the column of the first cell of the character displayed
+// at col, row: col itself, or the column before it when col is the second
+// half of a wide character.
+func (g *Grid) CharStart(col, row int) int {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.charStartLocked(col, row)
+}
+
+func (g *Grid) charStartLocked(col, row int) int {
+	if col > 0 && g.displayCellLocked(col, row).Width == CellWidthContinuation &&
+		g.displayCellLocked(col-1, row).Width == CellWidthWide {
+		return col - 1
+	}
+	return col
+}
+
+// charEndLocked returns the column of the last cell of the character displayed at col, row
+func (g *Grid) charEndLocked(col, row int) int {
+	if col < g.Cols-1 && g.displayCellLocked(col, row).Width == CellWidthWide {
+		return col + 1
+	}
+	return col
+}
+
 // ClearSelection clears any active selection.
 func (g *Grid) ClearSelection() {
 	g.mu.Lock()
@@ -720,8 +975,26 @@ func (g *Grid) isSelectedLocked(col, row int) bool {
 	return true
 }
 
-// SelectedText returns the text within the current selection.
+// SelectedText returns the selected text. Rows that auto-wrapped are joined
+// so a long wrapped line copies as one line.
 func (g *Grid) SelectedText() string {
+	return g.selectedText(true, false)
+}
+
+// SelectedVisualText returns the selected text with a newline after every
+// visual row, including rows that auto-wrapped.
+func (g *Grid) SelectedVisualText() string {
+	return g.selectedText(false, false)
+}
+
+// SelectedExactText returns the selected text as it was printed: tabs are
+// restored where output tabbed over blank cells and written trailing spaces
+// are kept. Auto-wrapped rows are joined.
+func (g *Grid) SelectedExactText() string {
+	return g.selectedText(true, true)
+}
+
+func (g *Grid) selectedText(joinWrapped, exact bool) string {
 	g.mu.RLock()
 	defer g.mu.RUnlock()
 
@@ -736,7 +1009,7 @@ func (g *Grid) SelectedText() string {
 		startRow, endRow = endRow, startRow
 	}
 
-	var lines []string
+	var out strings.Builder
 	for row := startRow; row <= endRow; row++ {
 		colStart := 0
 		colEnd := g.Cols - 1
@@ -750,20 +1023,109 @@ func (g *Grid) SelectedText() string {
 			continue
 		}
 
+		// A wrapped row continues on the next one, so its trailing blanks are text
+		wrapped := joinWrapped && row < endRow && colEnd == g.Cols-1 && g.displayRowWrappedLocked(row)
+		// Art mode copies cells as they are shown, so pictures keep their shape
+		if exact && !g.artMode {
+			out.WriteString(g.exactRowTextLocked(row, colStart, colEnd))
+			if !wrapped {
+				out.WriteByte('\n')
+			}
+			continue
+		}
+
 		var b strings.Builder
 		b.Grow(colEnd - colStart + 1)
 		for col := colStart; col <= colEnd; col++ {
 			cell := g.displayCellLocked(col, row)
+			if cell.Width == CellWidthContinuation {
+				continue
+			}
 			ch := cell.Char
 			if ch == 0 {
 				ch = ' '
 			}
 			b.WriteRune(ch)
 		}
-		lines = append(lines, strings.TrimRight(b.String(), " "))
+		if wrapped {
+			out.WriteString(b.String())
+			continue
+		}
+		if g.artMode {
+			out.WriteString(b.String())
+		} else {
+			out.WriteString(strings.TrimRight(b.String(), " "))
+		}
+		out.WriteByte('\n')
 	}
 
-	return strings.TrimRight(strings.Join(lines, "\n"), "\n")
+	return strings.TrimRight(out.String(), "\n")
+}
+
+// exactRowTextLocked returns a display row's text from colStart to colEnd,
+// ending at the last printed cell and turning tabs over blank cells back into '\t'
+func (g *Grid) exactRowTextLocked(row, colStart, colEnd int) string {
+	blank := func(cell Cell) bool {
+		return cell.Flags&(FlagWritten|FlagTab) == 0 && (cell.Char == ' ' || cell.Char == 0)
+	}
+	last := colStart - 1
+	for col := colStart; col <= colEnd; col++ {
+		if !blank(g.displayCellLocked(col, row)) {
+			last = col
+		}
+	}
+
+	var b strings.Builder
+	for col := colStart; col <= last; col++ {
+		cell := g.displayCellLocked(col, row)
+		if cell.Flags&FlagTab != 0 && (cell.Char == ' ' || cell.Char == 0) {
+			stop := min((col/8+1)*8, colEnd+1)
+			skipped := true
+			for c := col + 1; c < stop; c++ {
+				if !blank(g.displayCellLocked(c, row)) {
+					skipped = false
+					break
+				}
+			}
+			if skipped {
+				b.WriteByte('\t')
+				col = stop - 1
+				continue
+			}
+		}
+		if cell.Width == CellWidthContinuation {
+			continue
+		}
+		ch := cell.Char
+		if ch == 0 {
+			ch = ' '
+		}
+		b.WriteRune(ch)
+	}
+	return b.String()
+}
+
+// displayRowWrappedLocked reports whether a display row auto-wrapped onto the next row
+func (g *Grid) displayRowWrappedLocked(row int) bool {
+	if g.scrollOffset == 0 {
+		if row < 0 || row >= g.Rows {
+			return false
+		}
+		return g.cells[g.index(g.Cols-1, row)].Flags&FlagWrapped != 0
+	}
+	scrollbackRow := len(g.scrollback) - g.scrollOffset + row
+	if scrollbackRow < 0 {
+		return false
+	}
+	if scrollbackRow < len(g.scrollback) {
+		cells := g.scrollback[scrollbackRow]
+		return len(cells) > 0 && cells[len(cells)-1].Flags&FlagWrapped != 0
+	}
+	gridRow := scrollbackRow - len(g.scrollback)
+	if gridRow >= g.Rows {
+		return false
+	}
+	return g.cells[g.index(g.Cols-1, gridRow)].Flags&FlagWrapped != 0
 }
 
 func clampInt(value, min, max int) int {
@@ -826,6 +1188,7 @@ func (g *Grid) ClearAllWithBg(bg Color) {
 			rowCopy := make([]Cell, g.Cols)
 			copy(rowCopy, g.cells[row*g.Cols:(row+1)*g.Cols])
 			g.scrollback = append(g.scrollback, rowCopy)
+			g.scrolled++
 		}
 	}
 
@@ -838,6 +1201,8 @@ func (g *Grid) ClearAllWithBg(bg Color) {
 	for i := range g.cells {
 		g.cells[i] = NewCellWithBg(bg)
 	}
+	g.clearScreenStampsLocked()
+	g.clearScreenPromptsLocked()
 }
 
 // ClearToEndWithBg clears from cursor to end of screen with background color (BCE)
@@ -914,6 +1279,11 @@ func (g *Grid) DeleteChars(n int) {
 		}
 	}
 
+	// Deleting past the end of the line clears the rest of it
+	n = min(n, g.Cols-g.CursorCol)
+	line := g.cells[g.index(0, g.CursorRow):g.index(0, g.CursorRow)+g.Cols]
+	defer setWrapped(line, line[g.Cols-1].Flags&FlagWrapped != 0)
+
 	// Check if the end of deletion range would break a wide character
 	endPos := g.CursorCol + n
 	if endPos < g.Cols {
@@ -948,6 +1318,11 @@ func (g *Grid) InsertChars(n int) {
 		}
 	}
 
+	// Inserting past the end of the line clears the rest of it
+	n = min(n, g.Cols-g.CursorCol)
+	line := g.cells[g.index(0, g.CursorRow):g.index(0, g.CursorRow)+g.Cols]
+	defer setWrapped(line, line[g.Cols-1].Flags&FlagWrapped != 0)
+
 	// Check if shifting would break a wide character at the end
 	// If the last cell that would be kept is a wide char start, it would lose its continuation
 	if g.Cols-n >= 0 && g.Cols-n < g.Cols {
@@ -1059,11 +1434,13 @@ func (g *Grid) Resize(cols, rows int) {
 		newCells[i] = NewCellWithBg(g.eraseBg)
 	}
 
-	// Copy existing cells
+	// Copy existing cells, keeping each row's wrap state on its new last cell
 	for row := 0; row < min(rows, g.Rows); row++ {
 		for col := 0; col < min(cols, g.Cols); col++ {
 			newCells[row*cols+col] = g.cells[row*g.Cols+col]
 		}
+		wrapped := g.Cols > 0 && g.cells[row*g.Cols+g.Cols-1].Flags&FlagWrapped != 0
+		setWrapped(newCells[row*cols:(row+1)*cols], wrapped)
 	}
 
 	g.cells = newCells
@@ -1125,7 +1502,7 @@ func (g *Grid) EraseChars(n int) {
 	defer g.mu.Unlock()
 
 	startCol := g.CursorCol
-	endCol := g.CursorCol + n
+	endCol := g.CursorCol + min(n, g.Cols)
 	if endCol > g.Cols {
 		endCol = g.Cols
 	}
@@ -1156,26 +1533,29 @@ func (g *Grid) EraseChars(n int) {
 func (g *Grid) RepeatChar(n int) {
 	g.mu.Lock()
 	defer g.mu.Unlock()
+	// Repeats past a screenful would only push copies of it into the scrollback
+	n = min(n, g.Cols*g.Rows)
 	for i := 0; i < n; i++ {
 		if g.wrapPending {
 			if g.autoWrap {
-				g.cursorNewline()
+				g.softWrap()
 			}
 			g.wrapPending = false
 		}
 		if g.CursorCol >= g.Cols {
 			if g.autoWrap {
-				g.cursorNewline()
+				g.softWrap()
 			} else {
 				g.CursorCol = g.Cols - 1
 			}
 		}
+		g.stampLocked()
 		idx := g.index(g.CursorCol, g.CursorRow)
 		g.cells[idx] = Cell{
 			Char:  g.lastChar,
 			Fg:    g.lastFg,
 			Bg:    g.lastBg,
-			Flags: g.lastFlags,
+			Flags: g.lastFlags | FlagWritten,
 			Width: CellWidthNormal,
 		}
 		g.CursorCol++
@@ -1268,3 +1648,36 @@ func (g *Grid) GetEraseBackground() Color {
 	defer g.mu.RUnlock()
 	return g.eraseBg
 }
+
+// SetFontScale sets the font scale used to render this grid
+func (g *Grid) SetFontScale(scale float32) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if scale <= 0 {
+		scale = 1.0
+	}
+	g.fontScale = scale
+}
+
+// FontScale returns the font scale used to render this grid
+func (g *Grid) FontScale() float32 {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.fontScale
+}
+
+// SetArtMode turns art mode on or off: cells are drawn on whole pixels so
+// blocks meet without seams, and selections are copied with every cell,
+// trailing blanks included
+func (g *Grid) SetArtMode(on bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.artMode = on
+}
+
+// ArtMode reports whether the grid is in art mode
+func (g *Grid) ArtMode() bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.artMode
+}
@@ -0,0 +1,111 @@
+package grid
+
+import "testing"
+
+// writeString writes s at the cursor with default colors
+func writeString(g *Grid, s string) {
+	for _, r := range s {
+		g.WriteChar(r, DefaultFg(), DefaultBg(), 0)
+	}
+}
+
+// wrappedCols returns the columns of row whose cell carries FlagWrapped
+func wrappedCols(g *Grid, row int) []int {
+	var cols []int
+	for col := 0; col < g.Cols; col++ {
+		if g.GetCell(col, row).Flags&FlagWrapped != 0 {
+			cols = append(cols, col)
+		}
+	}
+	return cols
+}
+
+func TestWrapFlagStaysOnLastCell(t *testing.T) {
+	tests := []struct {
+		name string
+		edit func(g *Grid)
+	}{
+		{"grow", func(g *Grid) { g.Resize(14, 4) }},
+		{"shrink", func(g *Grid) { g.Resize(6, 4) }},
+		{"insert chars", func(g *Grid) { g.SetCursorPos(2, 0); g.InsertChars(3) }},
+		{"delete chars", func(g *Grid) { g.SetCursorPos(2, 0); g.DeleteChars(3) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewGrid(10, 4)
+			writeString(g, "0123456789abc")
+			if got := wrappedCols(g, 0); len(got) != 1 || got[0] != 9 {
+				t.Fatalf("before edit: FlagWrapped at columns %v, want [9]", got)
+			}
+			tt.edit(g)
+			if got := wrappedCols(g, 0); len(got) != 1 || got[0] != g.Cols-1 {
+				t.Fatalf("FlagWrapped at columns %v, want [%d]", got, g.Cols-1)
+			}
+			if got := wrappedCols(g, 1); len(got) != 0 {
+				t.Fatalf("unwrapped row has FlagWrapped at columns %v", got)
+			}
+		})
+	}
+}
+
+// FuzzGridOps runs the operations data spells out, one byte each with the
+// byte after it as the argument, on a grid of any size: writing narrow and
+// wide characters, line feeds, resizes, inserting and deleting characters
+// and lines, scroll regions, scrolling back, selecting and snapshots. It
+// reaches sizes and offsets the parser's output alone does not.
+func FuzzGridOps(f *testing.F) {
+	f.Add([]byte("a\x00b\x01\x02\x05\x03\x03\x04\x02\x08\x01"), uint8(10), uint8(3))
+	f.Add([]byte("\x0b\x07\x09\x02\x0a\x04\x0c\x00\x06\x02\x05\x50"), uint8(80), uint8(24))
+	f.Add([]byte("\x00\x00\x00\x00\x07\x30\x06\x01\x0d\x00"), uint8(1), uint8(1))
+	f.Fuzz(func(t *testing.T, data []byte, cols, rows uint8) {
+		g := NewGrid(int(cols%200)+1, int(rows%100)+1)
+		for i := 0; i+1 < len(data); i += 2 {
+			arg := int(data[i+1])
+			switch data[i] % 14 {
+			case 0:
+				g.WriteChar(rune(arg)+' ', DefaultFg(), DefaultBg(), 0)
+			case 1:
+				g.WriteChar('中', DefaultFg(), DefaultBg(), 0)
+			case 2:
+				g.CarriageReturn()
+				g.Newline()
+			case 3:
+				g.Resize(arg%200+1, arg%100+1)
+			case 4:
+				g.InsertChars(arg)
+			case 5:
+				g.DeleteChars(arg)
+			case 6:
+				g.InsertLines(arg % 8)
+			case 7:
+				g.DeleteLines(arg % 8)
+			case 8:
+				g.SetCursorPos(arg%g.Cols, arg%g.Rows)
+			case 9:
+				g.SetScrollRegion(arg%g.Rows+1, g.Rows)
+			case 10:
+				g.ScrollViewUp(arg)
+			case 11:
+				g.ScrollViewDown(arg)
+			case 12:
+				g.SetSelection(arg%g.Cols, 0, arg, arg%g.Rows)
+				_ = g.SelectedText()
+			case 13:
+				g.EraseChars(arg)
+			}
+		}
+		_ = g.VisibleText()
+		snap := g.Snapshot()
+		if len(snap.Cells) != snap.Cols*snap.Rows {
+			t.Fatalf("snapshot holds %d cells for %dx%d", len(snap.Cells), snap.Cols, snap.Rows)
+		}
+		if len(snap.Lines) != snap.Rows || len(snap.Wrapped) != snap.Rows {
+			t.Fatalf("snapshot holds %d line numbers and %d wrap states for %d rows", len(snap.Lines), len(snap.Wrapped), snap.Rows)
+		}
+		for row := 0; row < g.Rows; row++ {
+			if got := wrappedCols(g, row); len(got) > 1 || len(got) == 1 && got[0] != g.Cols-1 {
+				t.Fatalf("row %d has FlagWrapped at columns %v", row, got)
+			}
+		}
+	})
+}
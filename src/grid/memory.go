@@ -0,0 +1,63 @@
+package grid
+
+import "unsafe"
+
+// cellBytes and rowBytes are the in-memory sizes of a cell and a row's slice header
+const (
+	cellBytes = int64(unsafe.Sizeof(Cell{}))
+	rowBytes  = int64(unsafe.Sizeof([]Cell(nil)))
+)
+
+// MemoryStats estimates the memory held by a grid's cells
+type MemoryStats struct {
+	Screen         int64 // Bytes in the visible screen
+	Scrollback     int64 // Bytes in the scrollback
+	ScrollbackRows int
+}
+
+// Total returns the bytes held by the screen and the scrollback
+func (m MemoryStats) Total() int64 {
+	return m.Screen + m.Scrollback
+}
+
+// Add returns the sum of two estimates
+func (m MemoryStats) Add(other MemoryStats) MemoryStats {
+	return MemoryStats{
+		Screen:         m.Screen + other.Screen,
+		Scrollback:     m.Scrollback + other.Scrollback,
+		ScrollbackRows: m.ScrollbackRows + other.ScrollbackRows,
+	}
+}
+
+// Memory estimates the memory held by the screen and scrollback cells
+func (g *Grid) Memory() MemoryStats {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	stats := MemoryStats{
+		Screen:         int64(cap(g.cells)) * cellBytes,
+		ScrollbackRows: len(g.scrollback),
+	}
+	for _, row := range g.scrollback {
+		stats.Scrollback += int64(cap(row))*cellBytes + rowBytes
+	}
+	return stats
+}
+
+// EvictScrollback drops up to n of the oldest scrollback rows and returns how
+// many were dropped. A view scrolled into the dropped rows moves to the oldest
+// remaining row.
+func (g *Grid) EvictScrollback(n int) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	n = min(n, len(g.scrollback))
+	if n <= 0 {
+		return 0
+	}
+	// Clear the dropped rows so they can be freed while the backing array lives on
+	clear(g.scrollback[:n])
+	g.scrollback = g.scrollback[n:]
+	g.scrollOffset = min(g.scrollOffset, len(g.scrollback))
+	return n
+}
@@ -0,0 +1,55 @@
+package grid
+
+import "sort"
+
+// MarkPrompt records that the shell's prompt ends on the cursor row, where
+// the command is typed. Lines are absolute, so marks follow their rows into
+// the scrollback; a prompt drawn over earlier marks replaces them.
+func (g *Grid) MarkPrompt() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	line := g.scrolled + g.CursorRow
+	keep := sort.SearchInts(g.prompts, line)
+	g.prompts = append(g.prompts[:keep], line)
+
+	// Forget prompts that left the scrollback once there are more than it holds
+	if len(g.prompts) > MaxScrollback+g.Rows {
+		oldest := sort.SearchInts(g.prompts, g.scrolled-len(g.scrollback))
+		g.prompts = append([]int(nil), g.prompts[oldest:]...)
+	}
+}
+
+// clearScreenPromptsLocked forgets the prompts marked on the screen rows,
+// for when the screen is cleared and its rows will be written afresh
+func (g *Grid) clearScreenPromptsLocked() {
+	g.prompts = g.prompts[:sort.SearchInts(g.prompts, g.scrolled)]
+}
+
+// StickyPrompt returns the cells of the last prompt line above the view while
+// scrolled back, so the command whose output is in view can be shown with it.
+// It reports false at the bottom of the scrollback or when no prompt above
+// the view is still held.
+func (g *Grid) StickyPrompt() ([]Cell, bool) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	row, ok := g.stickyPromptLocked()
+	if !ok {
+		return nil, false
+	}
+	cells := make([]Cell, len(row))
+	copy(cells, row)
+	return cells, true
+}
+
+// stickyPromptLocked returns the grid's own cells of the sticky prompt line
+func (g *Grid) stickyPromptLocked() ([]Cell, bool) {
+	if g.scrollOffset == 0 {
+		return nil, false
+	}
+	top := g.scrolled - g.scrollOffset
+	i := sort.SearchInts(g.prompts, top) - 1
+	if i < 0 || g.prompts[i] < g.scrolled-len(g.scrollback) {
+		return nil, false
+	}
+	return g.absRowLocked(g.prompts[i]), true
+}
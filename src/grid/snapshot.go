@@ -0,0 +1,148 @@
+package grid
+
+import "time"
+
+// Snapshot is a copy of what a grid displays, taken under a single lock so a
+// frame drawn from it never mixes cells from before and after a write.
+type Snapshot struct {
+	Cols, Rows   int
+	Cells        []Cell // Displayed cells row by row, scrollback included while scrolled back
+	Selected     []bool // Selected cells in the same order; empty when nothing is selected
+	CursorCol    int
+	CursorRow    int
+	ScrollOffset int
+	FontScale    float32
+	ArtMode      bool
+
+	// Per displayed row
+	Lines     []int       // 1-based absolute line number, 0 below the cursor where nothing has been written
+	Stamps    []time.Time // When output first reached the row, zero when unknown
+	Wrapped   []bool      // Whether the row's line carries on in the row below
+	Bookmarks [][]rune    // Names of the marks set at the row
+
+	Widgets []PlacedWidget // Inline widgets on the displayed rows
+	Sticky  []Cell         // Prompt line pinned over the top row while scrolled back, nil when none
+}
+
+// Snapshot returns a copy of the viewport. Callers that take one every frame
+// should reuse a Snapshot with CopyViewport instead.
+func (g *Grid) Snapshot() *Snapshot {
+	s := &Snapshot{}
+	g.CopyViewport(s)
+	return s
+}
+
+// CopyViewport copies the viewport into s, reusing its buffers when they are
+// large enough
+func (g *Grid) CopyViewport(s *Snapshot) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	s.Cols, s.Rows = g.Cols, g.Rows
+	s.CursorCol, s.CursorRow = g.CursorCol, g.CursorRow
+	s.ScrollOffset = g.scrollOffset
+	s.FontScale = g.fontScale
+	s.ArtMode = g.artMode
+
+	n := g.Cols * g.Rows
+	if cap(s.Cells) < n {
+		s.Cells = make([]Cell, n)
+	}
+	s.Cells = s.Cells[:n]
+	if g.scrollOffset == 0 {
+		copy(s.Cells, g.cells)
+	} else {
+		blank := NewCellWithBg(g.eraseBg)
+		for row := 0; row < g.Rows; row++ {
+			dst := s.Cells[row*g.Cols : (row+1)*g.Cols]
+			src := g.displayRowLocked(row)
+			copied := copy(dst, src)
+			for col := copied; col < len(dst); col++ {
+				dst[col] = blank
+			}
+		}
+	}
+
+	g.copyRowStateLocked(s)
+	s.Widgets = g.displayWidgetsLocked(s.Widgets[:0])
+	s.Sticky = nil
+	if row, ok := g.stickyPromptLocked(); ok {
+		s.Sticky = append([]Cell(nil), row...)
+	}
+
+	if !g.selectionActive || g.scrollOffset != g.selectionScrollOffset {
+		s.Selected = s.Selected[:0]
+		return
+	}
+	if cap(s.Selected) < n {
+		s.Selected = make([]bool, n)
+	}
+	s.Selected = s.Selected[:n]
+	for row := 0; row < g.Rows; row++ {
+		for col := 0; col < g.Cols; col++ {
+			s.Selected[row*g.Cols+col] = g.isSelectedLocked(col, row)
+		}
+	}
+}
+
+// copyRowStateLocked copies the line number, timestamp, wrap state and marks
+// of each displayed row into s
+func (g *Grid) copyRowStateLocked(s *Snapshot) {
+	s.Lines = resize(s.Lines, g.Rows)
+	s.Stamps = resize(s.Stamps, g.Rows)
+	s.Wrapped = resize(s.Wrapped, g.Rows)
+	s.Bookmarks = resize(s.Bookmarks, g.Rows)
+	for row := 0; row < g.Rows; row++ {
+		s.Lines[row] = 0
+		if line, ok := g.displayLineLocked(row); ok {
+			s.Lines[row] = line
+		}
+		s.Stamps[row] = g.stamps[g.scrolled-g.scrollOffset+row]
+		s.Wrapped[row] = g.displayRowWrappedLocked(row)
+		s.Bookmarks[row] = nil
+		if len(g.bookmarks) > 0 {
+			s.Bookmarks[row] = g.displayBookmarksLocked(row)
+		}
+	}
+}
+
+// resize returns buf with n elements, reusing its array when large enough
+func resize[T any](buf []T, n int) []T {
+	if cap(buf) < n {
+		return make([]T, n)
+	}
+	return buf[:n]
+}
+
+// displayRowLocked returns the cells shown on a display row while scrolled
+// back, or nil for a row above the scrollback
+func (g *Grid) displayRowLocked(row int) []Cell {
+	scrollbackRow := len(g.scrollback) - g.scrollOffset + row
+	if scrollbackRow < 0 {
+		return nil
+	}
+	if scrollbackRow < len(g.scrollback) {
+		return g.scrollback[scrollbackRow]
+	}
+	gridRow := scrollbackRow - len(g.scrollback)
+	if gridRow >= g.Rows {
+		return nil
+	}
+	return g.cells[gridRow*g.Cols : (gridRow+1)*g.Cols]
+}
+
+// Cell returns the displayed cell at col, row, or a blank cell outside the viewport
+func (s *Snapshot) Cell(col, row int) Cell {
+	if col < 0 || col >= s.Cols || row < 0 || row >= s.Rows {
+		return NewCell()
+	}
+	return s.Cells[row*s.Cols+col]
+}
+
+// IsSelected reports whether the cell at col, row is selected
+func (s *Snapshot) IsSelected(col, row int) bool {
+	if len(s.Selected) == 0 || col < 0 || col >= s.Cols || row < 0 || row >= s.Rows {
+		return false
+	}
+	return s.Selected[row*s.Cols+col]
+}
@@ -0,0 +1,54 @@
+package grid
+
+import "time"
+
+// stampSlack is how many timestamps of rows that left the scrollback are
+// tolerated before they are pruned
+const stampSlack = 1024
+
+// stampLocked records when output first reached the cursor row. Rows are
+// keyed by absolute line, so timestamps follow them into the scrollback.
+func (g *Grid) stampLocked() {
+	line := g.scrolled + g.CursorRow
+	if g.stampedLine == line+1 {
+		return
+	}
+	g.stampedLine = line + 1
+	if g.stamps == nil {
+		g.stamps = make(map[int]time.Time)
+	}
+	if _, ok := g.stamps[line]; ok {
+		return
+	}
+	if len(g.stamps) >= MaxScrollback+g.Rows+stampSlack {
+		g.pruneStampsLocked()
+	}
+	g.stamps[line] = time.Now()
+}
+
+// pruneStampsLocked forgets timestamps of rows that left the scrollback
+func (g *Grid) pruneStampsLocked() {
+	oldest := g.scrolled - len(g.scrollback)
+	for line := range g.stamps {
+		if line < oldest {
+			delete(g.stamps, line)
+		}
+	}
+}
+
+// clearScreenStampsLocked forgets the timestamps of the rows on screen, for
+// when the screen is cleared and its rows will be written afresh
+func (g *Grid) clearScreenStampsLocked() {
+	for row := 0; row < g.Rows; row++ {
+		delete(g.stamps, g.scrolled+row)
+	}
+	g.stampedLine = 0
+}
+
+// DisplayStamp returns when output first reached a displayed row
+func (g *Grid) DisplayStamp(row int) (time.Time, bool) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	stamp, ok := g.stamps[g.scrolled-g.scrollOffset+row]
+	return stamp, ok
+}
@@ -0,0 +1,124 @@
+package grid
+
+// maxWidgets caps how many widgets a grid remembers
+const maxWidgets = 1024
+
+// WidgetKind identifies how a widget is drawn
+type WidgetKind uint8
+
+const (
+	WidgetSparkline WidgetKind = iota
+	WidgetProgress
+)
+
+// Widget is a small graphic drawn over a run of reserved cells
+type Widget struct {
+	Kind   WidgetKind
+	Width  int       // Cells covered
+	Values []float64 // Sparkline samples, or the progress percentage in Values[0]
+}
+
+// PlacedWidget is a widget at a display position
+type PlacedWidget struct {
+	Widget
+	Col int
+	Row int
+}
+
+// widgetKey is a widget's absolute line and starting column
+type widgetKey struct {
+	line int
+	col  int
+}
+
+// PlaceWidget reserves cells from the cursor for a widget and advances the
+// cursor past them. The widget is clipped at the right edge of the row.
+func (g *Grid) PlaceWidget(w Widget, bg Color) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.wrapPending {
+		if g.autoWrap {
+			g.softWrap()
+		}
+		g.wrapPending = false
+	}
+	w.Width = min(w.Width, g.Cols-g.CursorCol)
+	if w.Width <= 0 {
+		return
+	}
+
+	for i := 0; i < w.Width; i++ {
+		g.cells[g.index(g.CursorCol+i, g.CursorRow)] = Cell{
+			Char:  ' ',
+			Fg:    DefaultFg(),
+			Bg:    bg,
+			Flags: FlagWritten | FlagWidget,
+			Width: CellWidthNormal,
+		}
+	}
+
+	if g.widgets == nil {
+		g.widgets = make(map[widgetKey]Widget)
+	}
+	if len(g.widgets) >= maxWidgets {
+		g.pruneWidgetsLocked()
+	}
+	g.widgets[widgetKey{line: g.scrolled + g.CursorRow, col: g.CursorCol}] = w
+
+	g.CursorCol += w.Width
+	if g.CursorCol >= g.Cols {
+		if g.autoWrap {
+			g.wrapPending = true
+		}
+		g.CursorCol = g.Cols - 1
+	}
+}
+
+// pruneWidgetsLocked forgets widgets whose rows left the scrollback, and the
+// oldest half if that is not enough
+func (g *Grid) pruneWidgetsLocked() {
+	oldest := g.scrolled - len(g.scrollback)
+	for key := range g.widgets {
+		if key.line < oldest {
+			delete(g.widgets, key)
+		}
+	}
+	if len(g.widgets) < maxWidgets {
+		return
+	}
+	lowest, highest := g.scrolled+g.Rows, oldest
+	for key := range g.widgets {
+		lowest = min(lowest, key.line)
+		highest = max(highest, key.line)
+	}
+	middle := lowest + (highest-lowest)/2
+	for key := range g.widgets {
+		if key.line <= middle {
+			delete(g.widgets, key)
+		}
+	}
+}
+
+// DisplayWidgets returns the widgets on the displayed rows whose cells are still reserved
+func (g *Grid) DisplayWidgets() []PlacedWidget {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.displayWidgetsLocked(nil)
+}
+
+// displayWidgetsLocked appends the placed widgets to placed
+func (g *Grid) displayWidgetsLocked(placed []PlacedWidget) []PlacedWidget {
+	for key, w := range g.widgets {
+		row := key.line - g.scrolled + g.scrollOffset
+		if row < 0 || row >= g.Rows || key.col+w.Width > g.Cols {
+			continue
+		}
+		if g.displayCellLocked(key.col, row).Flags&FlagWidget == 0 ||
+			g.displayCellLocked(key.col+w.Width-1, row).Flags&FlagWidget == 0 {
+			continue
+		}
+		placed = append(placed, PlacedWidget{Widget: w, Col: key.col, Row: row})
+	}
+	return placed
+}
@@ -0,0 +1,134 @@
+package highlight
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/javanhut/RavenTerminal/src/grid"
+)
+
+// Colors are the backgrounds of matches, one per watch in the order they were added
+var Colors = [][4]float32{
+	{0.95, 0.75, 0.15, 0.45},
+	{0.90, 0.30, 0.30, 0.45},
+	{0.30, 0.75, 0.95, 0.45},
+	{0.55, 0.85, 0.35, 0.45},
+	{0.80, 0.45, 0.90, 0.45},
+}
+
+// Watch is a pattern highlighted in a pane and how many times it appeared
+type Watch struct {
+	Pattern string
+	Count   int
+	re      *regexp.Regexp
+}
+
+// Set holds the watches of one pane. Matches are counted in lines as they
+// are completed, so the line being typed is highlighted but not yet counted.
+type Set struct {
+	Watches []*Watch
+	grid    *grid.Grid // Grid counted so far; a switch to the alternate screen starts over at its cursor
+	mark    int        // Absolute line counted up to
+}
+
+// Add watches for pattern, a case-insensitive regular expression; plain
+// words match themselves. The pane's scrollback is counted on the next Scan.
+func (s *Set) Add(pattern string) error {
+	for _, w := range s.Watches {
+		if w.Pattern == pattern {
+			return fmt.Errorf("already highlighting %q", pattern)
+		}
+	}
+	re, err := regexp.Compile("(?i)" + pattern)
+	if err != nil {
+		return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	if re.MatchString("") {
+		return fmt.Errorf("pattern %q matches empty text", pattern)
+	}
+	s.Watches = append(s.Watches, &Watch{Pattern: pattern, re: re})
+	// Count every watch again from the oldest line kept
+	for _, w := range s.Watches {
+		w.Count = 0
+	}
+	s.grid = nil
+	s.mark = 0
+	return nil
+}
+
+// Remove stops watching pattern and reports whether it was watched
+func (s *Set) Remove(pattern string) bool {
+	for i, w := range s.Watches {
+		if w.Pattern == pattern {
+			s.Watches = append(s.Watches[:i], s.Watches[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Len returns the number of watches
+func (s *Set) Len() int {
+	if s == nil {
+		return 0
+	}
+	return len(s.Watches)
+}
+
+// Mask returns, for each rune of line, 1 plus the index of the watch that
+// matches it, or 0; nil when nothing matches. Later watches win on overlap.
+func (s *Set) Mask(line []rune) []int {
+	if s.Len() == 0 || len(line) == 0 {
+		return nil
+	}
+	text := string(line)
+	runeAt := make([]int, len(text)+1)
+	n := 0
+	for i := range text {
+		runeAt[i] = n
+		n++
+	}
+	runeAt[len(text)] = n
+
+	var mask []int
+	for wi, w := range s.Watches {
+		for _, loc := range w.re.FindAllStringIndex(text, -1) {
+			if mask == nil {
+				mask = make([]int, len(line))
+			}
+			for i := runeAt[loc[0]]; i < runeAt[loc[1]]; i++ {
+				mask[i] = wi + 1
+			}
+		}
+	}
+	return mask
+}
+
+// Scan counts the matches in the lines g completed since the last scan
+func (s *Set) Scan(g *grid.Grid) {
+	if s.Len() == 0 || g == nil {
+		return
+	}
+	if s.grid != nil && s.grid != g {
+		// Skip what is already on a screen switched to
+		_, s.mark = g.LinesSince(g.ScrolledLines() + g.Rows)
+	}
+	s.grid = g
+	lines, mark := g.LinesSince(s.mark)
+	s.mark = mark
+	for _, line := range lines {
+		for _, w := range s.Watches {
+			w.Count += len(w.re.FindAllStringIndex(line, -1))
+		}
+	}
+}
+
+// Status returns the watches with their counts for the pane border
+func (s *Set) Status() string {
+	parts := make([]string, len(s.Watches))
+	for i, w := range s.Watches {
+		parts[i] = fmt.Sprintf("%s: %d", w.Pattern, w.Count)
+	}
+	return "highlight " + strings.Join(parts, ", ")
+}
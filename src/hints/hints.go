@@ -0,0 +1,137 @@
+package hints
+
+import (
+	"strings"
+
+	"github.com/javanhut/RavenTerminal/src/grid"
+	"github.com/javanhut/RavenTerminal/src/tab"
+)
+
+// alphabet holds the label characters, home row first
+const alphabet = "asdfghjklqweruiopzxcvbnmty"
+
+// MaxTargets caps how many targets can be labelled with two-letter labels
+const MaxTargets = len(alphabet) * len(alphabet)
+
+// Target is a word on screen that can be picked by typing its label
+type Target struct {
+	Pane  *tab.Pane
+	Row   int    // Display row
+	Start int    // First column
+	End   int    // Last column (inclusive)
+	Text  string // Word text, or the URL when URL is set
+	URL   bool
+	Label string
+}
+
+// State tracks an active hint session
+type State struct {
+	Active  bool
+	Typed   string
+	Targets []Target
+	// Anchor is the first end of a range selection while the second is picked
+	Anchor *Target
+}
+
+// Words returns one target per run of non-blank cells on the visible screen of g
+func Words(pane *tab.Pane, g *grid.Grid) []Target {
+	var targets []Target
+	for row := 0; row < g.Rows; row++ {
+		start := -1
+		var word []rune
+		for col := 0; col <= g.Cols; col++ {
+			ch := ' '
+			if col < g.Cols {
+				cell := g.DisplayCell(col, row)
+				if cell.Width == grid.CellWidthContinuation {
+					continue
+				}
+				if cell.Char != 0 {
+					ch = cell.Char
+				}
+			}
+			if ch != ' ' {
+				if start < 0 {
+					start = col
+				}
+				word = append(word, ch)
+				continue
+			}
+			if start >= 0 {
+				targets = append(targets, Target{Pane: pane, Row: row, Start: start, End: col - 1, Text: string(word)})
+				start = -1
+				word = word[:0]
+			}
+		}
+	}
+	return targets
+}
+
+// Begin starts a hint session over targets, assigning each a label
+func (s *State) Begin(targets []Target) {
+	if len(targets) > MaxTargets {
+		targets = targets[:MaxTargets]
+	}
+	labels := labels(len(targets))
+	for i := range targets {
+		targets[i].Label = labels[i]
+	}
+	s.Active = true
+	s.Typed = ""
+	s.Targets = targets
+}
+
+// Cancel ends the hint session
+func (s *State) Cancel() {
+	s.Active = false
+	s.Typed = ""
+	s.Targets = nil
+	s.Anchor = nil
+}
+
+// Type adds a label character. It returns the picked target once the typed
+// label matches exactly one. Characters that match no label are ignored.
+func (s *State) Type(r rune) (Target, bool) {
+	typed := s.Typed + strings.ToLower(string(r))
+	var match *Target
+	count := 0
+	for i := range s.Targets {
+		if strings.HasPrefix(s.Targets[i].Label, typed) {
+			count++
+			match = &s.Targets[i]
+		}
+	}
+	if count == 0 {
+		return Target{}, false
+	}
+	s.Typed = typed
+	if count == 1 && match.Label == typed {
+		return *match, true
+	}
+	return Target{}, false
+}
+
+// Backspace removes the last typed label character
+func (s *State) Backspace() {
+	if s.Typed != "" {
+		s.Typed = s.Typed[:len(s.Typed)-1]
+	}
+}
+
+// labels returns n prefix-free labels: single letters when they suffice,
+// otherwise two letters each
+func labels(n int) []string {
+	result := make([]string, 0, n)
+	if n <= len(alphabet) {
+		for i := 0; i < n; i++ {
+			result = append(result, alphabet[i:i+1])
+		}
+		return result
+	}
+	for i := 0; i < len(alphabet) && len(result) < n; i++ {
+		for j := 0; j < len(alphabet) && len(result) < n; j++ {
+			result = append(result, string([]byte{alphabet[i], alphabet[j]}))
+		}
+	}
+	return result
+}
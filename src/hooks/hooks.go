@@ -0,0 +1,61 @@
+package hooks
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/javanhut/RavenTerminal/src/config"
+	"github.com/javanhut/RavenTerminal/src/logging"
+)
+
+// Events that can run a hook, as passed in RAVEN_EVENT
+const (
+	Bell            = "bell"
+	CommandFinished = "command_finished"
+	TabOpened       = "tab_opened"
+	WindowFocusLost = "window_focus_lost"
+)
+
+// Timeout is how long a hook may run before it is killed
+const Timeout = time.Minute
+
+// Command returns the hook configured for event, or "" when there is none
+func Command(cfg config.HooksConfig, event string) string {
+	switch event {
+	case Bell:
+		return strings.TrimSpace(cfg.Bell)
+	case CommandFinished:
+		return strings.TrimSpace(cfg.CommandFinished)
+	case TabOpened:
+		return strings.TrimSpace(cfg.TabOpened)
+	case WindowFocusLost:
+		return strings.TrimSpace(cfg.WindowFocusLost)
+	}
+	return ""
+}
+
+// Run starts command with sh -c and returns without waiting. The event and
+// vars are added to its environment as RAVEN_EVENT and RAVEN_<NAME>. Its
+// output is discarded and failures are only logged.
+func Run(event, command string, vars map[string]string) {
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", command)
+	cmd.Env = append(os.Environ(), "RAVEN_EVENT="+event)
+	for name, value := range vars {
+		cmd.Env = append(cmd.Env, "RAVEN_"+strings.ToUpper(name)+"="+value)
+	}
+	if err := cmd.Start(); err != nil {
+		cancel()
+		logging.Warnf(logging.App, "Hook %s failed to start: %v", event, err)
+		return
+	}
+	go func() {
+		defer cancel()
+		if err := cmd.Wait(); err != nil {
+			logging.Debugf(logging.App, "Hook %s: %v", event, err)
+		}
+	}()
+}
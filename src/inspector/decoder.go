@@ -0,0 +1,338 @@
+package inspector
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxEvents caps how many decoded events a recorder keeps; older ones are dropped
+const MaxEvents = 5000
+
+// maxSequence caps how many bytes of one sequence or text run are kept so a
+// runaway OSC or a huge paste cannot grow an event without bound
+const maxSequence = 512
+
+// Kind is the type of a decoded piece of output
+type Kind int
+
+const (
+	KindText Kind = iota
+	KindControl
+	KindESC
+	KindCSI
+	KindOSC
+	KindDCS
+)
+
+// String returns the short label shown in the inspector
+func (k Kind) String() string {
+	switch k {
+	case KindText:
+		return "TEXT"
+	case KindControl:
+		return "CTRL"
+	case KindESC:
+		return "ESC"
+	case KindCSI:
+		return "CSI"
+	case KindOSC:
+		return "OSC"
+	case KindDCS:
+		return "DCS"
+	}
+	return "?"
+}
+
+// Event is one decoded escape sequence, control character or run of text
+type Event struct {
+	Seq       int // Position in the pane's output, counting from 1
+	Time      time.Time
+	Kind      Kind
+	Raw       string // The bytes as received, possibly cut at maxSequence
+	Name      string // Mnemonic such as SGR or DECSET, empty when unknown
+	Truncated bool
+}
+
+// Escaped returns the raw bytes with ESC and other controls made visible
+func (e Event) Escaped() string {
+	var b strings.Builder
+	for _, r := range e.Raw {
+		switch {
+		case r == 0x1b:
+			b.WriteString(`\e`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == 0x07:
+			b.WriteString(`\a`)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, `\x%02x`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	if e.Truncated {
+		b.WriteString("...")
+	}
+	return b.String()
+}
+
+// String formats the event as one inspector line
+func (e Event) String() string {
+	if e.Name == "" {
+		return fmt.Sprintf("%-4s %s", e.Kind, e.Escaped())
+	}
+	return fmt.Sprintf("%-4s %-8s %s", e.Kind, e.Name, e.Escaped())
+}
+
+type decodeState int
+
+const (
+	stateGround decodeState = iota
+	stateEscape
+	stateEscapeIntermediate
+	stateCSI
+	stateString
+	stateStringEscape
+)
+
+// Recorder decodes a pane's output into events as it arrives. It follows the
+// same 7-bit sequences as the parser, so a sequence split across reads is
+// still reported once.
+type Recorder struct {
+	mu      sync.Mutex
+	state   decodeState
+	kind    Kind
+	current []byte
+	cut     bool
+	events  []Event
+	seq     int
+}
+
+// NewRecorder returns an empty recorder
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Write decodes data and appends the resulting events
+func (r *Recorder) Write(data []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	for _, b := range data {
+		r.feed(b, now)
+	}
+	// Text is flushed per read so it shows up without waiting for the next sequence
+	if r.state == stateGround {
+		r.flush(KindText, now)
+	}
+}
+
+// Seq returns the number of the newest event, which changes whenever events are added
+func (r *Recorder) Seq() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.seq
+}
+
+// Events returns a copy of the recorded events, oldest first
+func (r *Recorder) Events() []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	events := make([]Event, len(r.events))
+	copy(events, r.events)
+	return events
+}
+
+// Clear drops the recorded events
+func (r *Recorder) Clear() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = nil
+}
+
+func (r *Recorder) feed(b byte, now time.Time) {
+	switch r.state {
+	case stateGround:
+		switch {
+		case b == 0x1b:
+			r.flush(KindText, now)
+			r.state = stateEscape
+			r.add(b)
+		case b < 0x20 || b == 0x7f:
+			r.flush(KindText, now)
+			r.add(b)
+			r.flush(KindControl, now)
+		default:
+			// Long runs of text are split rather than cut
+			if len(r.current) >= maxSequence {
+				r.flush(KindText, now)
+			}
+			r.add(b)
+		}
+	case stateEscape:
+		r.add(b)
+		switch {
+		case b == '[':
+			r.state = stateCSI
+		case b == ']':
+			r.kind = KindOSC
+			r.state = stateString
+		case b == 'P':
+			r.kind = KindDCS
+			r.state = stateString
+		case b == 'X' || b == '^' || b == '_':
+			// SOS, PM and APC are strings the parser skips like DCS
+			r.kind = KindDCS
+			r.state = stateString
+		case b >= 0x20 && b <= 0x2f:
+			r.state = stateEscapeIntermediate
+		default:
+			r.flush(KindESC, now)
+		}
+	case stateEscapeIntermediate:
+		r.add(b)
+		if b < 0x20 || b > 0x2f {
+			r.flush(KindESC, now)
+		}
+	case stateCSI:
+		r.add(b)
+		if b >= 0x40 && b <= 0x7e {
+			r.flush(KindCSI, now)
+		}
+	case stateString:
+		switch b {
+		case 0x07:
+			r.add(b)
+			r.flush(r.kind, now)
+		case 0x1b:
+			r.state = stateStringEscape
+		default:
+			r.add(b)
+		}
+	case stateStringEscape:
+		if b == '\\' {
+			r.add(0x1b)
+			r.add(b)
+			r.flush(r.kind, now)
+			return
+		}
+		// Any other escape ends the string and starts a new sequence
+		r.flush(r.kind, now)
+		r.state = stateEscape
+		r.add(0x1b)
+		r.feed(b, now)
+	}
+}
+
+func (r *Recorder) add(b byte) {
+	if len(r.current) >= maxSequence {
+		r.cut = true
+		return
+	}
+	r.current = append(r.current, b)
+}
+
+// flush records the pending bytes as an event of kind and returns to the ground state
+func (r *Recorder) flush(kind Kind, now time.Time) {
+	r.state = stateGround
+	if len(r.current) == 0 {
+		return
+	}
+	raw := string(r.current)
+	r.seq++
+	r.events = append(r.events, Event{
+		Seq:       r.seq,
+		Time:      now,
+		Kind:      kind,
+		Raw:       raw,
+		Name:      name(kind, raw),
+		Truncated: r.cut,
+	})
+	if len(r.events) > MaxEvents {
+		r.events = append(r.events[:0], r.events[len(r.events)-MaxEvents:]...)
+	}
+	r.current = r.current[:0]
+	r.cut = false
+}
+
+var controlNames = map[byte]string{
+	0x00: "NUL", 0x05: "ENQ", 0x07: "BEL", 0x08: "BS", 0x09: "HT", 0x0a: "LF",
+	0x0b: "VT", 0x0c: "FF", 0x0d: "CR", 0x0e: "SO", 0x0f: "SI", 0x7f: "DEL",
+}
+
+var escNames = map[string]string{
+	"7": "DECSC", "8": "DECRC", "c": "RIS", "D": "IND", "M": "RI", "E": "NEL",
+	"H": "HTS", "=": "DECKPAM", ">": "DECKPNM", "#8": "DECALN",
+	"(": "SCS G0", ")": "SCS G1", "*": "SCS G2", "+": "SCS G3",
+}
+
+var csiNames = map[string]string{
+	"@": "ICH", "A": "CUU", "B": "CUD", "C": "CUF", "D": "CUB", "E": "CNL",
+	"F": "CPL", "G": "CHA", "H": "CUP", "I": "CHT", "J": "ED", "K": "EL",
+	"L": "IL", "M": "DL", "P": "DCH", "S": "SU", "T": "SD", "X": "ECH",
+	"Z": "CBT", "`": "HPA", "b": "REP", "c": "DA", "d": "VPA", "f": "HVP",
+	"g": "TBC", "h": "SM", "l": "RM", "m": "SGR", "n": "DSR", "r": "DECSTBM",
+	"s": "SCOSC", "t": "XTWINOPS", "u": "SCORC", "q": "DECSCUSR",
+	"?h": "DECSET", "?l": "DECRST", "?n": "DECDSR", "?u": "KITTYKB",
+	">c": "DA2", ">m": "XTMODKEYS", "!p": "DECSTR", "$p": "DECRQM",
+}
+
+var oscNames = map[string]string{
+	"0": "TITLE", "1": "ICON", "2": "TITLE", "4": "PALETTE", "7": "CWD",
+	"8": "LINK", "9": "NOTIFY", "10": "FG", "11": "BG", "12": "CURSOR",
+	"52": "CLIPBRD", "104": "RESETPAL", "133": "PROMPT", "777": "NOTIFY",
+	"1337": "ITERM",
+}
+
+// name returns the mnemonic of a sequence, or "" when it is not known
+func name(kind Kind, raw string) string {
+	switch kind {
+	case KindControl:
+		return controlNames[raw[0]]
+	case KindESC:
+		body := strings.TrimPrefix(raw, "\x1b")
+		if n, ok := escNames[body]; ok {
+			return n
+		}
+		if len(body) > 1 {
+			return escNames[body[:1]]
+		}
+	case KindCSI:
+		body := strings.TrimPrefix(raw, "\x1b[")
+		if body == "" {
+			return ""
+		}
+		final := body[len(body)-1:]
+		key := final
+		if prefix := body[0]; prefix == '?' || prefix == '>' {
+			key = string(prefix) + final
+		} else if len(body) > 1 && (body[len(body)-2] == '!' || body[len(body)-2] == '$') {
+			key = body[len(body)-2:]
+		}
+		if n, ok := csiNames[key]; ok {
+			return n
+		}
+		return csiNames[final]
+	case KindOSC:
+		body := strings.TrimPrefix(raw, "\x1b]")
+		number, _, _ := strings.Cut(body, ";")
+		number = strings.TrimRight(number, "\x07\x1b\\")
+		return oscNames[number]
+	case KindDCS:
+		switch raw[1] {
+		case 'X':
+			return "SOS"
+		case '^':
+			return "PM"
+		case '_':
+			return "APC"
+		}
+	}
+	return ""
+}
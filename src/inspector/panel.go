@@ -0,0 +1,253 @@
+package inspector
+
+import "strings"
+
+// Panel shows the decoded output of the active pane
+type Panel struct {
+	Open     bool
+	Focused  bool
+	Paused   bool
+	Filter   string
+	Entries  []Event // Events matching Filter, oldest first
+	Selected int
+	Scroll   int
+	Follow   bool // Keep the newest event selected as output arrives
+	Status   string
+	Source   string // Label of the pane being inspected
+	Seq      int    // Recorder sequence the entries were taken at
+	all      []Event
+}
+
+type Layout struct {
+	PanelX       float32
+	PanelY       float32
+	PanelWidth   float32
+	PanelHeight  float32
+	ContentX     float32
+	ContentWidth float32
+	LineHeight   float32
+	HeaderY      float32
+	InputBoxY    float32
+	StatusY      float32
+	ListStart    float32
+	ListEnd      float32
+	FooterY      float32
+	VisibleLines int
+}
+
+func NewPanel() *Panel {
+	return &Panel{}
+}
+
+func (p *Panel) Toggle() {
+	p.Open = !p.Open
+	if p.Open {
+		p.Focused = true
+		p.Paused = false
+		p.Filter = ""
+		p.Status = ""
+		p.Follow = true
+		p.Seq = -1
+		p.all = nil
+		p.Entries = nil
+		p.Selected = 0
+		p.Scroll = 0
+	}
+}
+
+// SetEvents replaces the recorded events unless the view is paused
+func (p *Panel) SetEvents(events []Event, seq int, visibleLines int) {
+	if p.Paused {
+		return
+	}
+	p.all = events
+	p.Seq = seq
+	p.applyFilter(visibleLines)
+}
+
+// AppendFilter adds r to the filter
+func (p *Panel) AppendFilter(r rune, visibleLines int) {
+	p.Filter += string(r)
+	p.applyFilter(visibleLines)
+}
+
+// Backspace removes the last rune of the filter
+func (p *Panel) Backspace(visibleLines int) {
+	if p.Filter == "" {
+		return
+	}
+	runes := []rune(p.Filter)
+	p.Filter = string(runes[:len(runes)-1])
+	p.applyFilter(visibleLines)
+}
+
+// TogglePause freezes or resumes the view; events are still recorded while paused
+func (p *Panel) TogglePause() {
+	p.Paused = !p.Paused
+	if !p.Paused {
+		p.Seq = -1
+	}
+}
+
+// Matches reports whether an event passes the filter. Every word of the
+// filter must appear, case-insensitively, in the event's kind, name or bytes.
+func Matches(e Event, filter string) bool {
+	words := strings.Fields(strings.ToLower(filter))
+	if len(words) == 0 {
+		return true
+	}
+	line := strings.ToLower(e.String())
+	for _, word := range words {
+		if !strings.Contains(line, word) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *Panel) applyFilter(visibleLines int) {
+	selectedSeq := 0
+	if entry, ok := p.SelectedEntry(); ok {
+		selectedSeq = entry.Seq
+	}
+
+	p.Entries = p.Entries[:0]
+	for _, e := range p.all {
+		if Matches(e, p.Filter) {
+			p.Entries = append(p.Entries, e)
+		}
+	}
+
+	p.Selected = len(p.Entries) - 1
+	if !p.Follow {
+		for i, e := range p.Entries {
+			if e.Seq >= selectedSeq {
+				p.Selected = i
+				break
+			}
+		}
+	}
+	if p.Selected < 0 {
+		p.Selected = 0
+	}
+	p.ensureSelectionVisible(visibleLines)
+}
+
+// SelectedEntry returns the currently selected event
+func (p *Panel) SelectedEntry() (Event, bool) {
+	if p.Selected < 0 || p.Selected >= len(p.Entries) {
+		return Event{}, false
+	}
+	return p.Entries[p.Selected], true
+}
+
+// Text returns the shown events, one per line, for copying
+func (p *Panel) Text() string {
+	lines := make([]string, len(p.Entries))
+	for i, e := range p.Entries {
+		lines[i] = e.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// MoveSelection moves the selection; selecting the last event follows new output again
+func (p *Panel) MoveSelection(delta int, visibleLines int) {
+	if len(p.Entries) == 0 {
+		return
+	}
+	p.Selected += delta
+	if p.Selected < 0 {
+		p.Selected = 0
+	}
+	if p.Selected >= len(p.Entries) {
+		p.Selected = len(p.Entries) - 1
+	}
+	p.Follow = p.Selected == len(p.Entries)-1
+	p.ensureSelectionVisible(visibleLines)
+}
+
+func (p *Panel) ensureSelectionVisible(visibleLines int) {
+	if visibleLines <= 0 {
+		return
+	}
+	if p.Selected < p.Scroll {
+		p.Scroll = p.Selected
+	}
+	if p.Selected >= p.Scroll+visibleLines {
+		p.Scroll = p.Selected - visibleLines + 1
+	}
+	maxScroll := len(p.Entries) - visibleLines
+	if maxScroll < 0 {
+		maxScroll = 0
+	}
+	if p.Scroll > maxScroll {
+		p.Scroll = maxScroll
+	}
+	if p.Scroll < 0 {
+		p.Scroll = 0
+	}
+}
+
+func (p *Panel) Layout(width, height int, cellWidth, cellHeight float32) Layout {
+	panelWidth := float32(width) * 0.5
+	minPanelWidth := float32(460)
+	if cellWidth > 0 {
+		wideMin := cellWidth * 56
+		if wideMin > minPanelWidth {
+			minPanelWidth = wideMin
+		}
+	}
+	if panelWidth < minPanelWidth {
+		panelWidth = minPanelWidth
+	}
+	if panelWidth > 900 {
+		panelWidth = 900
+	}
+	maxWidth := float32(width) - 20
+	if panelWidth > maxWidth {
+		panelWidth = maxWidth
+	}
+
+	panelHeight := float32(height) - 30
+	if panelHeight < 260 {
+		panelHeight = 260
+	}
+	if panelHeight > float32(height)-20 {
+		panelHeight = float32(height) - 20
+	}
+
+	panelX := float32(width) - panelWidth - 10
+	panelY := float32(10)
+
+	lineHeight := cellHeight * 1.35
+	contentX := panelX + 18
+	contentWidth := panelWidth - 36
+	headerY := panelY + lineHeight*1.2
+	inputBoxY := headerY + lineHeight*0.6
+	statusY := inputBoxY + lineHeight*2
+	listStart := statusY + lineHeight*1.3
+	footerY := panelY + panelHeight - lineHeight*0.6
+	listEnd := footerY - lineHeight*1.2
+
+	visibleLines := int((listEnd - listStart) / lineHeight)
+	if visibleLines < 1 {
+		visibleLines = 1
+	}
+
+	return Layout{
+		PanelX:       panelX,
+		PanelY:       panelY,
+		PanelWidth:   panelWidth,
+		PanelHeight:  panelHeight,
+		ContentX:     contentX,
+		ContentWidth: contentWidth,
+		LineHeight:   lineHeight,
+		HeaderY:      headerY,
+		InputBoxY:    inputBoxY,
+		StatusY:      statusY,
+		ListStart:    listStart,
+		ListEnd:      listEnd,
+		FooterY:      footerY,
+		VisibleLines: visibleLines,
+	}
+}
@@ -0,0 +1,209 @@
+package integration
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Markers around the installed snippet, so installing again replaces it
+const (
+	beginMarker = "# >>> raven-terminal shell integration >>>"
+	endMarker   = "# <<< raven-terminal shell integration <<<"
+)
+
+// Shells are the shells a snippet can be installed for
+var Shells = []string{"bash", "zsh", "fish", "nu"}
+
+// snippets report the working directory with OSC 7 and mark the prompt,
+// command start and exit status with OSC 133 A, B, C and D
+var snippets = map[string]string{
+	"bash": `if [ -n "$BASH_VERSION" ] && [ "$TERM" != dumb ] && [ -z "$__raven_integration" ]; then
+    __raven_integration=1
+    __raven_save_status() { __raven_status=$?; }
+    __raven_mark_prompt() {
+        printf '\e]133;D;%s\a\e]7;file://%s%s\a' "$__raven_status" "${HOSTNAME:-$(hostname)}" "$PWD"
+        case "$PS1" in
+            (*"133;B"*) ;;
+            (*) PS1="\[\e]133;A\a\]$PS1\[\e]133;B\a\]" ;;
+        esac
+        return $__raven_status
+    }
+    PROMPT_COMMAND="__raven_save_status"$'\n'"${PROMPT_COMMAND}"$'\n'"__raven_mark_prompt"
+    PS0="${PS0}"$'\e]133;C\a'
+fi`,
+	"zsh": `if [[ -o interactive && "$TERM" != dumb && -z "$__raven_integration" ]]; then
+    __raven_integration=1
+    __raven_precmd() {
+        print -n "\e]133;D;$?\a\e]7;file://${HOST}${PWD}\a"
+        [[ "$PS1" == *"133;B"* ]] || PS1=$'%{\e]133;A\a%}'"$PS1"$'%{\e]133;B\a%}'
+    }
+    __raven_preexec() { print -n "\e]133;C\a" }
+    autoload -Uz add-zsh-hook
+    add-zsh-hook precmd __raven_precmd
+    add-zsh-hook preexec __raven_preexec
+fi`,
+	"fish": `if status is-interactive; and test "$TERM" != dumb; and not set -q __raven_integration
+    set -g __raven_integration 1
+    functions -q fish_prompt; and functions -c fish_prompt __raven_original_prompt
+    function __raven_return
+        return $argv[1]
+    end
+    function fish_prompt
+        set -l last $status
+        printf '\e]133;A\a'
+        if functions -q __raven_original_prompt
+            __raven_return $last
+            __raven_original_prompt
+        end
+        printf '\e]133;B\a'
+    end
+    function __raven_preexec --on-event fish_preexec
+        printf '\e]133;C\a'
+    end
+    function __raven_postexec --on-event fish_postexec
+        printf '\e]133;D;%s\a' $status
+    end
+    function __raven_osc7 --on-variable PWD
+        printf '\e]7;file://%s%s\a' $hostname $PWD
+    end
+    __raven_osc7
+end`,
+	"nu": `$env.config.shell_integration.osc7 = true
+$env.config.shell_integration.osc133 = true`,
+}
+
+// Result describes what Install did to a shell's rc file
+type Result struct {
+	Shell   string
+	Path    string // The rc file
+	Backup  string // Copy of the rc file as it was, or "" if there was none to copy
+	Changed bool   // False when the snippet was already installed as it is
+}
+
+// Detect returns which supported shell a shell path runs, or "" if none
+func Detect(shell string) string {
+	name := filepath.Base(shell)
+	if name == "nushell" {
+		name = "nu"
+	}
+	for _, s := range Shells {
+		if name == s {
+			return s
+		}
+	}
+	return ""
+}
+
+// Installed returns the supported shells found on PATH
+func Installed() []string {
+	var found []string
+	for _, s := range Shells {
+		if _, err := exec.LookPath(s); err == nil {
+			found = append(found, s)
+		}
+	}
+	return found
+}
+
+// RCFile returns the rc file the snippet for shell goes into
+func RCFile(shell string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	configDir := os.Getenv("XDG_CONFIG_HOME")
+	if configDir == "" {
+		configDir = filepath.Join(home, ".config")
+	}
+	switch shell {
+	case "bash":
+		return filepath.Join(home, ".bashrc"), nil
+	case "zsh":
+		if dir := os.Getenv("ZDOTDIR"); dir != "" {
+			return filepath.Join(dir, ".zshrc"), nil
+		}
+		return filepath.Join(home, ".zshrc"), nil
+	case "fish":
+		return filepath.Join(configDir, "fish", "config.fish"), nil
+	case "nu":
+		// Nushell keeps its config under Application Support on macOS unless XDG_CONFIG_HOME is set
+		if runtime.GOOS == "darwin" && os.Getenv("XDG_CONFIG_HOME") == "" {
+			return filepath.Join(home, "Library", "Application Support", "nushell", "config.nu"), nil
+		}
+		return filepath.Join(configDir, "nushell", "config.nu"), nil
+	}
+	return "", fmt.Errorf("unsupported shell %q (supported: %s)", shell, strings.Join(Shells, ", "))
+}
+
+// Install adds the integration snippet for shell to the end of its rc file,
+// or replaces the one installed before. An existing rc file is copied next
+// to itself with a timestamped .raven-backup suffix before it is changed.
+func Install(shell string) (Result, error) {
+	path, err := RCFile(shell)
+	if err != nil {
+		return Result{}, err
+	}
+	result := Result{Shell: shell, Path: path}
+	block := beginMarker + "\n" + snippets[shell] + "\n" + endMarker + "\n"
+
+	perm := os.FileMode(0644)
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return result, err
+		}
+	case err != nil:
+		return result, err
+	default:
+		if info, err := os.Stat(path); err == nil {
+			perm = info.Mode().Perm()
+		}
+	}
+
+	old := string(data)
+	updated := replaceBlock(old, block)
+	if updated == old {
+		return result, nil
+	}
+
+	if data != nil {
+		result.Backup = path + ".raven-backup-" + time.Now().Format("20060102-150405")
+		if err := os.WriteFile(result.Backup, data, perm); err != nil {
+			return result, fmt.Errorf("backing up %s: %w", path, err)
+		}
+	}
+	if err := os.WriteFile(path, []byte(updated), perm); err != nil {
+		return result, err
+	}
+	result.Changed = true
+	return result, nil
+}
+
+// replaceBlock puts block in place of the marked snippet in rc, or appends
+// it when rc has none
+func replaceBlock(rc, block string) string {
+	start := strings.Index(rc, beginMarker)
+	if start >= 0 {
+		if end := strings.Index(rc[start:], endMarker); end >= 0 {
+			end += start + len(endMarker)
+			if end < len(rc) && rc[end] == '\n' {
+				end++
+			}
+			return rc[:start] + block + rc[end:]
+		}
+	}
+	if rc != "" && !strings.HasSuffix(rc, "\n") {
+		rc += "\n"
+	}
+	if rc != "" {
+		rc += "\n"
+	}
+	return rc + block
+}
@@ -0,0 +1,64 @@
+package ipc
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// RunClient implements "raven-terminal send", which sends text or a file to a
+// pane of a running terminal, and returns the process exit code
+func RunClient(args []string) int {
+	fs := flag.NewFlagSet("send", flag.ContinueOnError)
+	tab := fs.Int("tab", 0, "tab number (0 = active tab)")
+	pane := fs.Int("pane", 0, "pane number within the tab (0 = active pane)")
+	file := fs.String("file", "", "send the contents of this file")
+	bracketed := fs.Bool("bracketed", false, "wrap the text in bracketed paste when the program supports it")
+	socket := fs.String("socket", os.Getenv(SocketEnv), "control socket of the terminal")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: raven-terminal send [-tab N] [-pane N] [-bracketed] (-file PATH | TEXT... | -)")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *socket == "" {
+		fmt.Fprintf(os.Stderr, "raven-terminal send: %s is not set; run inside Raven Terminal or pass -socket\n", SocketEnv)
+		return 2
+	}
+
+	req := Request{Tab: *tab, Pane: *pane, Bracketed: *bracketed}
+	switch {
+	case *file != "":
+		path, err := filepath.Abs(*file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "raven-terminal send: %v\n", err)
+			return 1
+		}
+		req.Method = MethodSendFile
+		req.Path = path
+	case fs.NArg() == 1 && fs.Arg(0) == "-":
+		data, err := io.ReadAll(io.LimitReader(os.Stdin, MaxSendBytes+1))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "raven-terminal send: %v\n", err)
+			return 1
+		}
+		req.Method = MethodSendText
+		req.Text = string(data)
+	case fs.NArg() > 0:
+		req.Method = MethodSendText
+		req.Text = strings.Join(fs.Args(), " ")
+	default:
+		fs.Usage()
+		return 2
+	}
+
+	if err := Send(*socket, req); err != nil {
+		fmt.Fprintf(os.Stderr, "raven-terminal send: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,203 @@
+package ipc
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// SocketEnv is the environment variable that tells programs in a pane where
+// the terminal's control socket is
+const SocketEnv = "RAVEN_TERMINAL_SOCKET"
+
+// MaxSendBytes caps the text or file a single request may send to a pane
+const MaxSendBytes = 1 << 20
+
+// Methods understood by the server
+const (
+	MethodSendText = "send-text"
+	MethodSendFile = "send-file"
+)
+
+// Request asks the terminal to act on a pane. Tab and Pane are numbered from
+// 1 as shown in the tab bar and by Ctrl+Shift+D; 0 means the active one.
+type Request struct {
+	Method    string `json:"method"`
+	Tab       int    `json:"tab"`
+	Pane      int    `json:"pane"`
+	Text      string `json:"text,omitempty"`
+	Path      string `json:"path,omitempty"`
+	Bracketed bool   `json:"bracketed,omitempty"` // Wrap in bracketed paste when the pane's program enabled it
+}
+
+// Response reports whether a request succeeded
+type Response struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// Call is a request waiting for the main loop to answer it
+type Call struct {
+	Request Request
+	reply   chan Response
+}
+
+// Reply answers the call; err nil means success
+func (c Call) Reply(err error) {
+	if err != nil {
+		c.reply <- Response{Error: err.Error()}
+		return
+	}
+	c.reply <- Response{OK: true}
+}
+
+// SocketPath returns the control socket path for this process, in a
+// directory of its own under XDG_RUNTIME_DIR, or under the shared temporary
+// directory with the user ID in its name when that is unset
+func SocketPath() string {
+	dir := filepath.Join(os.Getenv("XDG_RUNTIME_DIR"), "raven-terminal")
+	if os.Getenv("XDG_RUNTIME_DIR") == "" {
+		dir = filepath.Join(os.TempDir(), "raven-terminal-"+strconv.Itoa(os.Getuid()))
+	}
+	return filepath.Join(dir, "raven-terminal-"+strconv.Itoa(os.Getpid())+".sock")
+}
+
+// privateDir creates dir readable only by the current user, or checks that
+// the one already there is a real directory the user owns and nobody else
+// can enter, so no other user can reach or swap the socket inside
+func privateDir(dir string) error {
+	if err := os.Mkdir(dir, 0700); err != nil && !os.IsExist(err) {
+		return err
+	}
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	if sys, ok := info.Sys().(*syscall.Stat_t); ok && int(sys.Uid) != os.Getuid() {
+		return fmt.Errorf("%s is owned by another user", dir)
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		return os.Chmod(dir, 0700)
+	}
+	return nil
+}
+
+// Server accepts requests on a Unix socket and queues them for the main loop
+type Server struct {
+	path     string
+	listener net.Listener
+	calls    chan Call
+}
+
+// Listen opens the control socket at path, readable only by the current
+// user. The directory holding it is made private first, so the socket is
+// never reachable by others, even before its own mode is set.
+func Listen(path string) (*Server, error) {
+	if err := privateDir(filepath.Dir(path)); err != nil {
+		return nil, err
+	}
+	os.Remove(path)
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	s := &Server{path: path, listener: listener, calls: make(chan Call, 8)}
+	go s.acceptLoop()
+	return s, nil
+}
+
+// Calls delivers requests that the main loop must answer with Call.Reply
+func (s *Server) Calls() <-chan Call {
+	return s.calls
+}
+
+// Close stops accepting requests and removes the socket
+func (s *Server) Close() {
+	s.listener.Close()
+	os.Remove(s.path)
+}
+
+func (s *Server) acceptLoop() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.serve(conn)
+	}
+}
+
+// serve answers one JSON request per line until the client disconnects
+func (s *Server) serve(conn net.Conn) {
+	defer conn.Close()
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 64*1024), 2*MaxSendBytes)
+	encoder := json.NewEncoder(conn)
+	for scanner.Scan() {
+		var req Request
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			encoder.Encode(Response{Error: "invalid request: " + err.Error()})
+			continue
+		}
+		call := Call{Request: req, reply: make(chan Response, 1)}
+		s.calls <- call
+		select {
+		case resp := <-call.reply:
+			encoder.Encode(resp)
+		case <-time.After(5 * time.Second):
+			encoder.Encode(Response{Error: "terminal did not answer"})
+		}
+	}
+}
+
+// Send delivers a request to the terminal listening on path
+func Send(path string, req Request) error {
+	conn, err := net.DialTimeout("unix", path, 2*time.Second)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return err
+	}
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return fmt.Errorf("reading reply: %w", err)
+	}
+	if !resp.OK {
+		return errors.New(resp.Error)
+	}
+	return nil
+}
+
+// ReadSendFile reads a file to send to a pane, refusing files over MaxSendBytes
+func ReadSendFile(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if info.Size() > MaxSendBytes {
+		return "", fmt.Errorf("%s is larger than %d bytes", path, MaxSendBytes)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,380 @@
+package keybindings
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+)
+
+// Chords handles tmux-style key sequences: a leader key followed by a key
+// that picks an action. Pressing the leader twice does what the leader key
+// does on its own, so it can still reach the program.
+type Chords struct {
+	leader  chordKey
+	timeout time.Duration
+	actions map[chordKey]KeyAction
+	pending time.Time // When the leader or the last count digit was pressed; zero when no chord is pending
+	count   int       // Count typed after the leader so far
+}
+
+// maxCount is the largest count a chord takes
+const maxCount = 9999
+
+// chordKey is a key with the modifiers held for it
+type chordKey struct {
+	key  glfw.Key
+	mods glfw.ModifierKey
+}
+
+// chordMods are the modifiers that tell chord keys apart; lock keys are ignored
+const chordMods = glfw.ModControl | glfw.ModAlt | glfw.ModShift | glfw.ModSuper
+
+// ChordActions names the actions a chord can run
+var ChordActions = map[string]KeyAction{
+	"new-tab":             ActionNewTab,
+	"close-tab":           ActionCloseTab,
+	"next-tab":            ActionNextTab,
+	"prev-tab":            ActionPrevTab,
+	"split-vertical":      ActionSplitVertical,
+	"split-horizontal":    ActionSplitHorizontal,
+	"close-pane":          ActionClosePane,
+	"next-pane":           ActionNextPane,
+	"prev-pane":           ActionPrevPane,
+	"display-panes":       ActionDisplayPanes,
+	"resize-mode":         ActionToggleResizeMode,
+	"fullscreen":          ActionToggleFullscreen,
+	"help":                ActionShowHelp,
+	"menu":                ActionOpenMenu,
+	"search":              ActionToggleSearchPanel,
+	"ai":                  ActionToggleAIPanel,
+	"copy":                ActionCopy,
+	"paste":               ActionPaste,
+	"zoom-in":             ActionZoomIn,
+	"zoom-out":            ActionZoomOut,
+	"zoom-reset":          ActionZoomReset,
+	"pane-zoom-in":        ActionPaneZoomIn,
+	"pane-zoom-out":       ActionPaneZoomOut,
+	"pane-zoom-reset":     ActionPaneZoomReset,
+	"processes":           ActionToggleProcessPanel,
+	"dev-servers":         ActionToggleDevServerPanel,
+	"dir-jump":            ActionToggleDirJump,
+	"snippets":            ActionToggleSnippets,
+	"redaction":           ActionToggleRedaction,
+	"read-screen":         ActionReadScreen,
+	"hints":               ActionHintMode,
+	"select-screen":       ActionSelectScreen,
+	"select-scrollback":   ActionSelectScrollback,
+	"select-last-command": ActionSelectLastCommand,
+	"scroll-lock":         ActionToggleScrollLock,
+	"notifications":       ActionToggleNotifications,
+	"find-cursor":         ActionFindCursor,
+	"registers":           ActionToggleRegisters,
+	"print-pdf":           ActionPrintPDF,
+	"calc":                ActionToggleCalc,
+	"inspect-char":        ActionInspectChar,
+	"palette":             ActionTogglePalette,
+	"set-mark":            ActionSetMark,
+	"jump-to-mark":        ActionJumpToMark,
+	"pause":               ActionTogglePause,
+	"last-tab":            ActionLastTab,
+	"respawn-pane":        ActionRespawnPane,
+}
+
+// NewChords returns the chords started by leader, such as "ctrl+a", each
+// running the action named for the key typed after it within timeout.
+// Entries that cannot be read are skipped and reported in the error; an
+// empty leader turns chords off.
+func NewChords(leader string, timeout time.Duration, chords map[string]string) (*Chords, error) {
+	if strings.TrimSpace(leader) == "" {
+		return nil, nil
+	}
+	leaderKey, err := parseChordKey(leader)
+	if err != nil {
+		return nil, fmt.Errorf("leader: %w", err)
+	}
+	c := &Chords{
+		leader:  leaderKey,
+		timeout: timeout,
+		actions: make(map[chordKey]KeyAction),
+	}
+	var problems []string
+	for spec, name := range chords {
+		if name == "" || name == "none" {
+			continue
+		}
+		k, err := parseChordKey(spec)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		if isCountDigit(k) {
+			problems = append(problems, fmt.Sprintf("%q types a count and cannot be a chord", spec))
+			continue
+		}
+		action, ok := ChordActions[name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("unknown action %q for %q", name, spec))
+			continue
+		}
+		c.actions[k] = action
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return c, fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return c, nil
+}
+
+// Pending reports whether the leader was pressed and the key after it is
+// still awaited. It is safe to call on nil Chords.
+func (c *Chords) Pending(now time.Time) bool {
+	if c == nil || c.pending.IsZero() {
+		return false
+	}
+	if now.Sub(c.pending) > c.timeout {
+		c.pending = time.Time{}
+		return false
+	}
+	return true
+}
+
+// Handle takes a key press and reports whether it belongs to a chord. The
+// leader and the digits of a count after it give ActionChordPending. The key
+// that follows gives its chord's action, or after a count any of Raven's own
+// shortcuts, with the count in KeyResult.Count; keys with neither give
+// ActionChordUnbound. Escape cancels a pending chord with ActionNone.
+func (c *Chords) Handle(key glfw.Key, mods glfw.ModifierKey, appCursorMode bool, now time.Time) (KeyResult, bool) {
+	if c == nil || isModifier(key) {
+		return KeyResult{}, c.Pending(now)
+	}
+	pressed := chordKey{key, mods & chordMods}
+	if !c.Pending(now) {
+		if pressed != c.leader {
+			return KeyResult{}, false
+		}
+		c.pending, c.count = now, 0
+		return KeyResult{Action: ActionChordPending}, true
+	}
+	if isCountDigit(pressed) {
+		c.pending = now
+		c.count = min(c.count*10+int(key-glfw.Key0), maxCount)
+		return KeyResult{Action: ActionChordPending}, true
+	}
+	count := c.count
+	c.pending, c.count = time.Time{}, 0
+	switch {
+	case pressed == c.leader:
+		return TranslateKey(key, mods, appCursorMode), true
+	case key == glfw.KeyEscape:
+		return KeyResult{Action: ActionNone}, true
+	}
+	if action, ok := c.actions[pressed]; ok {
+		return KeyResult{Action: action, Count: count}, true
+	}
+	if count > 0 {
+		if result := TranslateKey(key, mods, appCursorMode); result.Action != ActionNone && result.Action != ActionInput {
+			result.Count = count
+			return result, true
+		}
+	}
+	return KeyResult{Action: ActionChordUnbound}, true
+}
+
+// Typed returns what has been typed of the pending chord, e.g. "Ctrl+A 20"
+func (c *Chords) Typed() string {
+	if c == nil {
+		return ""
+	}
+	if c.count > 0 {
+		return fmt.Sprintf("%s %d", c.leader.label(), c.count)
+	}
+	return c.leader.label()
+}
+
+// isCountDigit reports whether k is a digit without modifiers, which types
+// a count after the leader
+func isCountDigit(k chordKey) bool {
+	return k.mods == 0 && k.key >= glfw.Key0 && k.key <= glfw.Key9
+}
+
+// Leader returns the leader key as it is shown to the user, e.g. "Ctrl+A"
+func (c *Chords) Leader() string {
+	if c == nil {
+		return ""
+	}
+	return c.leader.label()
+}
+
+// Hint lists the bound keys and their actions for the pending chord
+// indicator, e.g. "c new-tab  v split-vertical"
+func (c *Chords) Hint() string {
+	if c == nil {
+		return ""
+	}
+	names := make(map[KeyAction]string, len(ChordActions))
+	for name, action := range ChordActions {
+		names[action] = name
+	}
+	entries := make([]string, 0, len(c.actions))
+	for k, action := range c.actions {
+		entries = append(entries, k.label()+" "+names[action])
+	}
+	sort.Strings(entries)
+	return strings.Join(entries, "  ")
+}
+
+// KeySet is a set of keys with the modifiers held for them
+type KeySet map[chordKey]bool
+
+// ParseKeys reads keys written as for chords, such as "ctrl+shift+t". Keys
+// that cannot be read are skipped and reported in the error.
+func ParseKeys(specs []string) (KeySet, error) {
+	set := make(KeySet, len(specs))
+	var problems []string
+	for _, spec := range specs {
+		k, err := parseChordKey(spec)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		set[k] = true
+	}
+	if len(problems) > 0 {
+		return set, fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return set, nil
+}
+
+// Has reports whether the set holds key pressed with mods
+func (s KeySet) Has(key glfw.Key, mods glfw.ModifierKey) bool {
+	return s[chordKey{key, mods & chordMods}]
+}
+
+// chordKeyNames are the keys chords can use besides letters and digits
+var chordKeyNames = map[string]glfw.Key{
+	"space":     glfw.KeySpace,
+	"enter":     glfw.KeyEnter,
+	"tab":       glfw.KeyTab,
+	"backspace": glfw.KeyBackspace,
+	"left":      glfw.KeyLeft,
+	"right":     glfw.KeyRight,
+	"up":        glfw.KeyUp,
+	"down":      glfw.KeyDown,
+	"home":      glfw.KeyHome,
+	"end":       glfw.KeyEnd,
+	"pageup":    glfw.KeyPageUp,
+	"pagedown":  glfw.KeyPageDown,
+	"[":         glfw.KeyLeftBracket,
+	"]":         glfw.KeyRightBracket,
+	"-":         glfw.KeyMinus,
+	"=":         glfw.KeyEqual,
+	",":         glfw.KeyComma,
+	".":         glfw.KeyPeriod,
+	"/":         glfw.KeySlash,
+	";":         glfw.KeySemicolon,
+	"'":         glfw.KeyApostrophe,
+	"`":         glfw.KeyGraveAccent,
+	"\\":        glfw.KeyBackslash,
+}
+
+// parseChordKey reads a key such as "v", "V" (Shift+V), "ctrl+a" or
+// "alt+shift+left"
+func parseChordKey(spec string) (chordKey, error) {
+	parts := strings.Split(strings.TrimSpace(spec), "+")
+	name := parts[len(parts)-1]
+	mods, err := parseMods(parts[:len(parts)-1], spec)
+	if err != nil {
+		return chordKey{}, err
+	}
+	k := chordKey{mods: mods}
+	runes := []rune(name)
+	switch {
+	case len(runes) == 1 && runes[0] >= 'a' && runes[0] <= 'z':
+		k.key = glfw.KeyA + glfw.Key(runes[0]-'a')
+	case len(runes) == 1 && runes[0] >= 'A' && runes[0] <= 'Z':
+		k.key = glfw.KeyA + glfw.Key(runes[0]-'A')
+		k.mods |= glfw.ModShift
+	case len(runes) == 1 && runes[0] >= '0' && runes[0] <= '9':
+		k.key = glfw.Key0 + glfw.Key(runes[0]-'0')
+	default:
+		key, ok := chordKeyNames[strings.ToLower(name)]
+		if !ok {
+			return chordKey{}, fmt.Errorf("unknown key %q in %q", name, spec)
+		}
+		k.key = key
+	}
+	return k, nil
+}
+
+// parseMods reads modifier names such as "ctrl" and "shift"; spec is what
+// they were written in, for the error
+func parseMods(names []string, spec string) (glfw.ModifierKey, error) {
+	var mods glfw.ModifierKey
+	for _, mod := range names {
+		switch strings.ToLower(strings.TrimSpace(mod)) {
+		case "ctrl", "control":
+			mods |= glfw.ModControl
+		case "alt", "option":
+			mods |= glfw.ModAlt
+		case "shift":
+			mods |= glfw.ModShift
+		case "super", "cmd":
+			mods |= glfw.ModSuper
+		default:
+			return 0, fmt.Errorf("unknown modifier %q in %q", mod, spec)
+		}
+	}
+	return mods, nil
+}
+
+// label returns the key as it is shown to the user: "v", "V", "Ctrl+A"
+func (k chordKey) label() string {
+	var name string
+	switch {
+	case k.key >= glfw.KeyA && k.key <= glfw.KeyZ:
+		name = string(rune('a' + k.key - glfw.KeyA))
+	case k.key >= glfw.Key0 && k.key <= glfw.Key9:
+		name = string(rune('0' + k.key - glfw.Key0))
+	default:
+		for n, key := range chordKeyNames {
+			if key == k.key {
+				name = n
+				break
+			}
+		}
+	}
+	mods := k.mods
+	if mods == glfw.ModShift && len(name) == 1 && name >= "a" && name <= "z" {
+		return strings.ToUpper(name)
+	}
+	if mods&(glfw.ModControl|glfw.ModAlt|glfw.ModSuper) != 0 && len(name) == 1 {
+		name = strings.ToUpper(name)
+	}
+	prefix := ""
+	if mods&glfw.ModControl != 0 {
+		prefix += "Ctrl+"
+	}
+	if mods&glfw.ModAlt != 0 {
+		prefix += "Alt+"
+	}
+	if mods&glfw.ModShift != 0 {
+		prefix += "Shift+"
+	}
+	if mods&glfw.ModSuper != 0 {
+		prefix += "Super+"
+	}
+	return prefix + name
+}
+
+// isModifier reports whether key is a modifier key pressed on its own
+func isModifier(key glfw.Key) bool {
+	switch key {
+	case glfw.KeyLeftShift, glfw.KeyRightShift, glfw.KeyLeftControl, glfw.KeyRightControl,
+		glfw.KeyLeftAlt, glfw.KeyRightAlt, glfw.KeyLeftSuper, glfw.KeyRightSuper:
+		return true
+	}
+	return false
+}
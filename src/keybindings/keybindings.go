@@ -1,6 +1,8 @@
 package keybindings
 
 import (
+	"fmt"
+
 	"github.com/go-gl/glfw/v3.3/glfw"
 )
 
@@ -35,14 +37,48 @@ const (
 	ActionToggleSearchPanel
 	ActionToggleAIPanel
 	ActionCopy
+	ActionCopySelection
 	ActionPaste
 	ActionToggleResizeMode
+	ActionPaneZoomIn
+	ActionPaneZoomOut
+	ActionPaneZoomReset
+	ActionDisplayPanes
+	ActionToggleProcessPanel
+	ActionToggleDevServerPanel
+	ActionToggleDirJump
+	ActionToggleSnippets
+	ActionToggleRedaction
+	ActionLockScreen
+	ActionReadScreen
+	ActionHintMode
+	ActionSelectScreen
+	ActionSelectScrollback
+	ActionSelectLastCommand
+	ActionToggleScrollLock
+	ActionToggleNotifications
+	ActionFindCursor
+	ActionToggleRegisters
+	ActionPrintPDF
+	ActionToggleCalc
+	ActionInspectChar
+	ActionTogglePalette
+	ActionSetMark
+	ActionJumpToMark
+	ActionTogglePause
+	ActionRespawnPane
+	ActionSelectTab // Jump to the tab numbered in KeyResult.Tab
+	ActionLastTab
+	ActionChordPending // The leader key was pressed; the next key picks the action
+	ActionChordUnbound // The key typed after the leader has no action
 )
 
 // KeyResult contains the result of processing a key
 type KeyResult struct {
 	Action KeyAction
 	Data   []byte
+	Count  int // Times to repeat the action, from a count typed after the leader; 0 when none was
+	Tab    int // Tab to jump to for ActionSelectTab, counting from 0
 }
 
 // TranslateKey translates a GLFW key event to terminal input
@@ -94,6 +130,10 @@ func TranslateKey(key glfw.Key, mods glfw.ModifierKey, appCursorMode bool) KeyRe
 		return KeyResult{Action: ActionPrevPane}
 	}
 
+	if ctrl && shift && key == glfw.KeyD {
+		return KeyResult{Action: ActionDisplayPanes}
+	}
+
 	// Zoom controls: Ctrl+Shift++ (Equal key with shift), Ctrl+Shift+-, Ctrl+Shift+0
 	if ctrl && shift && key == glfw.KeyEqual {
 		return KeyResult{Action: ActionZoomIn}
@@ -107,6 +147,54 @@ func TranslateKey(key glfw.Key, mods glfw.ModifierKey, appCursorMode bool) KeyRe
 		return KeyResult{Action: ActionZoomReset}
 	}
 
+	// Ctrl+Alt+P to print the selection or the visible screen to a PDF
+	if ctrl && alt && !shift && key == glfw.KeyP {
+		return KeyResult{Action: ActionPrintPDF}
+	}
+
+	// Ctrl+Alt+C to open the calculator
+	if ctrl && alt && !shift && key == glfw.KeyC {
+		return KeyResult{Action: ActionToggleCalc}
+	}
+
+	// Ctrl+Alt+U to describe the character under the mouse or at the cursor
+	if ctrl && alt && !shift && key == glfw.KeyU {
+		return KeyResult{Action: ActionInspectChar}
+	}
+
+	// Ctrl+Alt+K to show the color palette
+	if ctrl && alt && !shift && key == glfw.KeyK {
+		return KeyResult{Action: ActionTogglePalette}
+	}
+
+	// Ctrl+Alt+M names the scroll position with the next letter typed, and
+	// Ctrl+Alt+' jumps back to a named one
+	if ctrl && alt && !shift && key == glfw.KeyM {
+		return KeyResult{Action: ActionSetMark}
+	}
+
+	if ctrl && alt && !shift && key == glfw.KeyApostrophe {
+		return KeyResult{Action: ActionJumpToMark}
+	}
+
+	// Ctrl+Alt+S pauses the active pane's output, and resumes it
+	if ctrl && alt && !shift && key == glfw.KeyS {
+		return KeyResult{Action: ActionTogglePause}
+	}
+
+	// Per-pane zoom: Ctrl+Alt+=, Ctrl+Alt+-, Ctrl+Alt+0
+	if ctrl && alt && !shift && key == glfw.KeyEqual {
+		return KeyResult{Action: ActionPaneZoomIn}
+	}
+
+	if ctrl && alt && !shift && key == glfw.KeyMinus {
+		return KeyResult{Action: ActionPaneZoomOut}
+	}
+
+	if ctrl && alt && !shift && key == glfw.Key0 {
+		return KeyResult{Action: ActionPaneZoomReset}
+	}
+
 	// Ctrl+Shift+S to open settings menu (like command palette)
 	if ctrl && shift && key == glfw.KeyS {
 		return KeyResult{Action: ActionOpenMenu}
@@ -120,6 +208,81 @@ func TranslateKey(key glfw.Key, mods glfw.ModifierKey, appCursorMode bool) KeyRe
 		return KeyResult{Action: ActionToggleAIPanel}
 	}
 
+	// Ctrl+Shift+M to toggle the process monitor panel
+	if ctrl && shift && key == glfw.KeyM {
+		return KeyResult{Action: ActionToggleProcessPanel}
+	}
+
+	// Ctrl+Shift+U to toggle the detected dev-server URL panel
+	if ctrl && shift && key == glfw.KeyU {
+		return KeyResult{Action: ActionToggleDevServerPanel}
+	}
+
+	// Ctrl+Shift+J to open the directory jump list
+	if ctrl && shift && key == glfw.KeyJ {
+		return KeyResult{Action: ActionToggleDirJump}
+	}
+
+	// Ctrl+Shift+N to open the snippets picker
+	if ctrl && shift && key == glfw.KeyN {
+		return KeyResult{Action: ActionToggleSnippets}
+	}
+
+	// Ctrl+Shift+E to toggle masking of secrets on screen
+	if ctrl && shift && key == glfw.KeyE {
+		return KeyResult{Action: ActionToggleRedaction}
+	}
+
+	// Ctrl+Shift+L to hide all panes behind the lock screen
+	if ctrl && shift && key == glfw.KeyL {
+		return KeyResult{Action: ActionLockScreen}
+	}
+
+	// Ctrl+Shift+G to label words and URLs for keyboard clicking
+	if ctrl && shift && key == glfw.KeyG {
+		return KeyResult{Action: ActionHintMode}
+	}
+
+	// Ctrl+Shift+O to read the focused pane aloud
+	if ctrl && shift && key == glfw.KeyO {
+		return KeyResult{Action: ActionReadScreen}
+	}
+
+	// Ctrl+Shift+Y to select and copy the visible screen
+	if ctrl && shift && key == glfw.KeyY {
+		return KeyResult{Action: ActionSelectScreen}
+	}
+
+	// Ctrl+Shift+B to copy the whole scrollback buffer
+	if ctrl && shift && key == glfw.KeyB {
+		return KeyResult{Action: ActionSelectScrollback}
+	}
+
+	// Ctrl+Shift+I to copy everything since the last command's prompt
+	if ctrl && shift && key == glfw.KeyI {
+		return KeyResult{Action: ActionSelectLastCommand}
+	}
+
+	// Ctrl+Shift+Z to lock scrolling between two panes
+	if ctrl && shift && key == glfw.KeyZ {
+		return KeyResult{Action: ActionToggleScrollLock}
+	}
+
+	// Ctrl+Shift+R to list recent bells, finished commands and AI replies
+	if ctrl && shift && key == glfw.KeyR {
+		return KeyResult{Action: ActionToggleNotifications}
+	}
+
+	// Ctrl+Shift+' (Ctrl+") to yank into or paste from a named register
+	if ctrl && shift && key == glfw.KeyApostrophe {
+		return KeyResult{Action: ActionToggleRegisters}
+	}
+
+	// Ctrl+Shift+Space to spotlight the cursor
+	if ctrl && shift && key == glfw.KeySpace {
+		return KeyResult{Action: ActionFindCursor}
+	}
+
 	if ctrl && !shift && key == glfw.KeyR {
 		return KeyResult{Action: ActionToggleResizeMode}
 	}
@@ -190,6 +353,11 @@ func TranslateKey(key glfw.Key, mods glfw.ModifierKey, appCursorMode bool) KeyRe
 		return KeyResult{Action: ActionInput, Data: []byte("\x1b[6~")}
 	}
 
+	// Ctrl+Insert to copy the selection, and only the selection
+	if ctrl && !shift && key == glfw.KeyInsert {
+		return KeyResult{Action: ActionCopySelection}
+	}
+
 	// Insert/Delete
 	if key == glfw.KeyInsert {
 		return KeyResult{Action: ActionInput, Data: []byte("\x1b[2~")}
@@ -275,6 +443,76 @@ func TranslateKey(key glfw.Key, mods glfw.ModifierKey, appCursorMode bool) KeyRe
 	return KeyResult{Action: ActionNone}
 }
 
+// cursorKeyFinals are the final bytes of the cursor and Home/End keys, which
+// carry modifiers as CSI 1 ; m <final>
+var cursorKeyFinals = map[glfw.Key]byte{
+	glfw.KeyUp: 'A', glfw.KeyDown: 'B', glfw.KeyRight: 'C', glfw.KeyLeft: 'D',
+	glfw.KeyHome: 'H', glfw.KeyEnd: 'F',
+	glfw.KeyF1: 'P', glfw.KeyF2: 'Q', glfw.KeyF3: 'R', glfw.KeyF4: 'S',
+}
+
+// tildeKeyCodes are the keys sent as CSI <code> ; m ~
+var tildeKeyCodes = map[glfw.Key]int{
+	glfw.KeyInsert: 2, glfw.KeyDelete: 3, glfw.KeyPageUp: 5, glfw.KeyPageDown: 6,
+	glfw.KeyF5: 15, glfw.KeyF6: 17, glfw.KeyF7: 18, glfw.KeyF8: 19,
+	glfw.KeyF9: 20, glfw.KeyF10: 21, glfw.KeyF11: 23, glfw.KeyF12: 24,
+}
+
+// EncodeKey returns the bytes that send a key press straight to the program,
+// for keys Raven would otherwise act on itself. Modified keys without a
+// traditional encoding, such as Ctrl+Shift+T, use the CSI u form read by
+// tmux with extended-keys on and other programs that ask for it. It returns
+// nil for printable keys without modifiers, which are typed as characters.
+func EncodeKey(key glfw.Key, mods glfw.ModifierKey, appCursorMode bool) []byte {
+	m := 1
+	if mods&glfw.ModShift != 0 {
+		m += 1
+	}
+	if mods&glfw.ModAlt != 0 {
+		m += 2
+	}
+	if mods&glfw.ModControl != 0 {
+		m += 4
+	}
+	if mods&glfw.ModSuper != 0 {
+		m += 8
+	}
+	if m == 1 {
+		if result := TranslateKey(key, mods, appCursorMode); result.Action == ActionInput {
+			return result.Data
+		}
+		return nil
+	}
+
+	switch {
+	case key >= glfw.KeyA && key <= glfw.KeyZ:
+		return fmt.Appendf(nil, "\x1b[%d;%du", 'a'+int(key-glfw.KeyA), m)
+	case key >= glfw.KeySpace && key <= glfw.KeyGraveAccent:
+		// The other printable keys are numbered by their unshifted ASCII character
+		return fmt.Appendf(nil, "\x1b[%d;%du", int(key), m)
+	}
+	if final, ok := cursorKeyFinals[key]; ok {
+		return fmt.Appendf(nil, "\x1b[1;%d%c", m, final)
+	}
+	if code, ok := tildeKeyCodes[key]; ok {
+		return fmt.Appendf(nil, "\x1b[%d;%d~", code, m)
+	}
+	switch key {
+	case glfw.KeyTab:
+		if m == 2 {
+			return []byte("\x1b[Z") // Back tab
+		}
+		return fmt.Appendf(nil, "\x1b[9;%du", m)
+	case glfw.KeyEnter, glfw.KeyKPEnter:
+		return fmt.Appendf(nil, "\x1b[13;%du", m)
+	case glfw.KeyEscape:
+		return fmt.Appendf(nil, "\x1b[27;%du", m)
+	case glfw.KeyBackspace:
+		return fmt.Appendf(nil, "\x1b[127;%du", m)
+	}
+	return nil
+}
+
 // TranslateChar translates a character input to terminal bytes
 func TranslateChar(char rune, mods glfw.ModifierKey) []byte {
 	alt := mods&glfw.ModAlt != 0
@@ -290,6 +528,32 @@ func TranslateChar(char rune, mods glfw.ModifierKey) []byte {
 	return buf[:n]
 }
 
+// TranslateCharLatin1 converts a character to ISO-8859-1 bytes.
+// Characters outside Latin-1 are sent as '?'.
+func TranslateCharLatin1(char rune, mods glfw.ModifierKey) []byte {
+	b := byte('?')
+	if char >= 0 && char <= 0xff {
+		b = byte(char)
+	}
+	if mods&glfw.ModAlt != 0 {
+		return []byte{0x1b, b}
+	}
+	return []byte{b}
+}
+
+// EncodeText converts pasted text to the bytes sent to the shell, encoding
+// it as TranslateCharLatin1 does typed characters when latin1 is set
+func EncodeText(text string, latin1 bool) []byte {
+	if !latin1 {
+		return []byte(text)
+	}
+	data := make([]byte, 0, len(text))
+	for _, r := range text {
+		data = append(data, TranslateCharLatin1(r, 0)...)
+	}
+	return data
+}
+
 // encodeRune encodes a rune as UTF-8
 func encodeRune(buf []byte, r rune) int {
 	if r < 0x80 {
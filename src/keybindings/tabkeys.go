@@ -0,0 +1,66 @@
+package keybindings
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+)
+
+// TabKeys picks tabs from the keyboard: a modifier held with 1 to 9 jumps to
+// that tab, and one more key goes back to the tab used before.
+type TabKeys struct {
+	mods    glfw.ModifierKey // Held with a digit to jump to a tab; 0 turns the digits off
+	last    chordKey
+	hasLast bool
+}
+
+// NewTabKeys returns the tab keys for modifier, such as "ctrl" or
+// "ctrl+shift", and the last tab key, such as "ctrl+`". An empty setting
+// turns that part off. A setting that cannot be read is reported in the
+// error and left off.
+func NewTabKeys(modifier, last string) (*TabKeys, error) {
+	t := &TabKeys{}
+	var problems []string
+	if modifier = strings.TrimSpace(modifier); modifier != "" {
+		mods, err := parseMods(strings.Split(modifier, "+"), modifier)
+		if err != nil {
+			problems = append(problems, "tab_index: "+err.Error())
+		}
+		t.mods = mods
+	}
+	if strings.TrimSpace(last) != "" {
+		k, err := parseChordKey(last)
+		if err != nil {
+			problems = append(problems, "last_tab: "+err.Error())
+		} else {
+			t.last, t.hasLast = k, true
+		}
+	}
+	if len(problems) > 0 {
+		return t, fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return t, nil
+}
+
+// Handle reports whether key pressed with mods picks a tab, giving
+// ActionSelectTab with the tab in KeyResult.Tab, or ActionLastTab
+func (t *TabKeys) Handle(key glfw.Key, mods glfw.ModifierKey) (KeyResult, bool) {
+	if t == nil {
+		return KeyResult{}, false
+	}
+	mods &= chordMods
+	if t.hasLast && (chordKey{key, mods}) == t.last {
+		return KeyResult{Action: ActionLastTab}, true
+	}
+	if t.mods == 0 || mods != t.mods {
+		return KeyResult{}, false
+	}
+	switch {
+	case key >= glfw.Key1 && key <= glfw.Key9:
+		return KeyResult{Action: ActionSelectTab, Tab: int(key - glfw.Key1)}, true
+	case key >= glfw.KeyKP1 && key <= glfw.KeyKP9:
+		return KeyResult{Action: ActionSelectTab, Tab: int(key - glfw.KeyKP1)}, true
+	}
+	return KeyResult{}, false
+}
@@ -0,0 +1,105 @@
+package logfile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Writer appends to a log file and rotates it once it grows past a size
+// limit, keeping a fixed number of older files named path.1, path.2, ...
+type Writer struct {
+	mu       sync.Mutex
+	path     string
+	maxBytes int64
+	keep     int
+	file     *os.File
+	size     int64
+}
+
+// Open opens path for appending, rotating it first if it is already over maxBytes
+func Open(path string, maxBytes int64, keep int) (*Writer, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	w := &Writer{path: path, maxBytes: maxBytes, keep: keep}
+	if err := w.openLocked(); err != nil {
+		return nil, err
+	}
+	if w.size >= maxBytes {
+		if err := w.rotateLocked(); err != nil {
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// Write appends p, rotating first when it would take the file past the limit
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return 0, os.ErrClosed
+	}
+	if w.size > 0 && w.size+int64(len(p)) > w.maxBytes {
+		if err := w.rotateLocked(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Close closes the current file
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+func (w *Writer) openLocked() error {
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.file = f
+	w.size = info.Size()
+	return nil
+}
+
+// rotateLocked shifts path.N to path.N+1, dropping the oldest, moves the
+// current file to path.1 and starts a new one. If the file cannot be moved,
+// writing carries on at its end.
+func (w *Writer) rotateLocked() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	w.file = nil
+	if w.keep > 0 {
+		os.Remove(rotated(w.path, w.keep))
+		for i := w.keep - 1; i >= 1; i-- {
+			os.Rename(rotated(w.path, i), rotated(w.path, i+1))
+		}
+		os.Rename(w.path, rotated(w.path, 1))
+	} else {
+		os.Remove(w.path)
+	}
+	return w.openLocked()
+}
+
+func rotated(path string, n int) string {
+	return fmt.Sprintf("%s.%d", path, n)
+}
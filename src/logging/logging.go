@@ -0,0 +1,186 @@
+package logging
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// Level is how much a subsystem logs; each level includes the ones above it
+type Level int32
+
+const (
+	LevelError Level = iota
+	LevelWarn
+	LevelInfo
+	LevelDebug
+)
+
+// String returns the level's name as used in config and commands
+func (l Level) String() string {
+	switch l {
+	case LevelError:
+		return "error"
+	case LevelWarn:
+		return "warn"
+	case LevelInfo:
+		return "info"
+	case LevelDebug:
+		return "debug"
+	}
+	return "unknown"
+}
+
+// ParseLevel reads a level name
+func ParseLevel(name string) (Level, bool) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "error":
+		return LevelError, true
+	case "warn", "warning":
+		return LevelWarn, true
+	case "info":
+		return LevelInfo, true
+	case "debug":
+		return LevelDebug, true
+	}
+	return 0, false
+}
+
+// Subsystem is a part of the terminal whose verbosity is set separately
+type Subsystem string
+
+const (
+	App    Subsystem = "app"
+	Parser Subsystem = "parser"
+	Render Subsystem = "render"
+	PTY    Subsystem = "pty"
+	AI     Subsystem = "ai"
+	Search Subsystem = "search"
+	Menu   Subsystem = "menu"
+)
+
+// DefaultLevel is the level every subsystem starts at
+const DefaultLevel = LevelInfo
+
+var levels = map[Subsystem]*atomic.Int32{
+	App:    newLevel(),
+	Parser: newLevel(),
+	Render: newLevel(),
+	PTY:    newLevel(),
+	AI:     newLevel(),
+	Search: newLevel(),
+	Menu:   newLevel(),
+}
+
+func newLevel() *atomic.Int32 {
+	level := &atomic.Int32{}
+	level.Store(int32(DefaultLevel))
+	return level
+}
+
+// Subsystems returns every subsystem name, sorted
+func Subsystems() []Subsystem {
+	subs := make([]Subsystem, 0, len(levels))
+	for sub := range levels {
+		subs = append(subs, sub)
+	}
+	sort.Slice(subs, func(i, j int) bool { return subs[i] < subs[j] })
+	return subs
+}
+
+// ParseSubsystem reads a subsystem name
+func ParseSubsystem(name string) (Subsystem, bool) {
+	sub := Subsystem(strings.ToLower(strings.TrimSpace(name)))
+	_, ok := levels[sub]
+	return sub, ok
+}
+
+// SetLevel changes one subsystem's level
+func SetLevel(sub Subsystem, level Level) {
+	if l, ok := levels[sub]; ok {
+		l.Store(int32(level))
+	}
+}
+
+// SetAll changes every subsystem's level
+func SetAll(level Level) {
+	for _, l := range levels {
+		l.Store(int32(level))
+	}
+}
+
+// Configure sets every subsystem to level, then applies the per-subsystem
+// overrides. Unknown names are skipped and reported in the error.
+func Configure(level string, overrides map[string]string) error {
+	var problems []string
+	base := DefaultLevel
+	if level != "" {
+		if parsed, ok := ParseLevel(level); ok {
+			base = parsed
+		} else {
+			problems = append(problems, fmt.Sprintf("unknown level %q", level))
+		}
+	}
+	SetAll(base)
+	for name, value := range overrides {
+		sub, ok := ParseSubsystem(name)
+		if !ok {
+			problems = append(problems, fmt.Sprintf("unknown subsystem %q", name))
+			continue
+		}
+		parsed, ok := ParseLevel(value)
+		if !ok {
+			problems = append(problems, fmt.Sprintf("unknown level %q for %s", value, sub))
+			continue
+		}
+		SetLevel(sub, parsed)
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("%s", strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+// LevelOf returns a subsystem's level
+func LevelOf(sub Subsystem) Level {
+	if l, ok := levels[sub]; ok {
+		return Level(l.Load())
+	}
+	return DefaultLevel
+}
+
+// Enabled reports whether a message at level from sub would be written.
+// Check it before building expensive debug output.
+func Enabled(sub Subsystem, level Level) bool {
+	return level <= LevelOf(sub)
+}
+
+func logf(sub Subsystem, level Level, format string, args ...interface{}) {
+	if !Enabled(sub, level) {
+		return
+	}
+	log.Printf("%-5s %s: %s", level, sub, fmt.Sprintf(format, args...))
+}
+
+// Errorf logs a failure the user should know about
+func Errorf(sub Subsystem, format string, args ...interface{}) {
+	logf(sub, LevelError, format, args...)
+}
+
+// Warnf logs a problem the terminal recovered from
+func Warnf(sub Subsystem, format string, args ...interface{}) {
+	logf(sub, LevelWarn, format, args...)
+}
+
+// Infof logs a notable event
+func Infof(sub Subsystem, format string, args ...interface{}) {
+	logf(sub, LevelInfo, format, args...)
+}
+
+// Debugf logs detail that is only useful while tracking down a problem
+func Debugf(sub Subsystem, format string, args ...interface{}) {
+	logf(sub, LevelDebug, format, args...)
+}
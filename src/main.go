@@ -2,33 +2,80 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"math"
 	"net/url"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
+	"unicode"
 
+	"github.com/javanhut/RavenTerminal/src/a11y"
 	"github.com/javanhut/RavenTerminal/src/aipanel"
+	"github.com/javanhut/RavenTerminal/src/aitools"
+	"github.com/javanhut/RavenTerminal/src/bench"
+	"github.com/javanhut/RavenTerminal/src/calc"
+	"github.com/javanhut/RavenTerminal/src/charinfo"
+	"github.com/javanhut/RavenTerminal/src/cleanup"
+	"github.com/javanhut/RavenTerminal/src/clipboard"
 	"github.com/javanhut/RavenTerminal/src/commands"
 	"github.com/javanhut/RavenTerminal/src/config"
+	"github.com/javanhut/RavenTerminal/src/devserver"
+	"github.com/javanhut/RavenTerminal/src/diagnostics"
+	"github.com/javanhut/RavenTerminal/src/diff"
+	"github.com/javanhut/RavenTerminal/src/dirjump"
 	"github.com/javanhut/RavenTerminal/src/grid"
+	"github.com/javanhut/RavenTerminal/src/highlight"
+	"github.com/javanhut/RavenTerminal/src/hints"
+	"github.com/javanhut/RavenTerminal/src/hooks"
+	"github.com/javanhut/RavenTerminal/src/inspector"
+	"github.com/javanhut/RavenTerminal/src/ipc"
 	"github.com/javanhut/RavenTerminal/src/keybindings"
+	"github.com/javanhut/RavenTerminal/src/logfile"
+	"github.com/javanhut/RavenTerminal/src/logging"
 	"github.com/javanhut/RavenTerminal/src/menu"
+	"github.com/javanhut/RavenTerminal/src/netconf"
+	"github.com/javanhut/RavenTerminal/src/notifications"
 	"github.com/javanhut/RavenTerminal/src/ollama"
+	"github.com/javanhut/RavenTerminal/src/palette"
+	"github.com/javanhut/RavenTerminal/src/parser"
+	"github.com/javanhut/RavenTerminal/src/printer"
+	"github.com/javanhut/RavenTerminal/src/procmon"
+	"github.com/javanhut/RavenTerminal/src/procpanel"
+	"github.com/javanhut/RavenTerminal/src/promptrow"
+	"github.com/javanhut/RavenTerminal/src/redact"
+	"github.com/javanhut/RavenTerminal/src/registers"
 	"github.com/javanhut/RavenTerminal/src/render"
+	"github.com/javanhut/RavenTerminal/src/replay"
 	"github.com/javanhut/RavenTerminal/src/searchpanel"
+	"github.com/javanhut/RavenTerminal/src/semantic"
+	"github.com/javanhut/RavenTerminal/src/session"
+	"github.com/javanhut/RavenTerminal/src/snippets"
+	"github.com/javanhut/RavenTerminal/src/startup"
 	"github.com/javanhut/RavenTerminal/src/tab"
+	"github.com/javanhut/RavenTerminal/src/update"
+	"github.com/javanhut/RavenTerminal/src/version"
+	"github.com/javanhut/RavenTerminal/src/watch"
 	"github.com/javanhut/RavenTerminal/src/websearch"
 	"github.com/javanhut/RavenTerminal/src/window"
 
 	"github.com/go-gl/glfw/v3.3/glfw"
 )
 
-// lineBuffer tracks the current line being typed for command interception
+// lineBuffer tracks the current line being typed for command interception when
+// the shell does not report its prompt with OSC 133 marks
 type lineBuffer struct {
 	buffer strings.Builder
 }
@@ -66,6 +113,13 @@ type searchResponse struct {
 	err     error
 }
 
+type semanticResponse struct {
+	id      int
+	query   string
+	matches []semantic.Match
+	err     error
+}
+
 type previewResponse struct {
 	id       int
 	url      string
@@ -77,13 +131,52 @@ type previewResponse struct {
 }
 
 type aiResponse struct {
-	id       int
-	content  string
-	thinking string // Thinking content from thinking models
-	err      error
-	loaded   bool
-	token    string // For streaming: incremental token
-	done     bool   // For streaming: indicates final response
+	id        int
+	content   string
+	thinking  string            // Thinking content from thinking models
+	toolCalls []ollama.ToolCall // Commands the model asked to run
+	err       error
+	loaded    bool
+	token     string // For streaming: incremental token
+	done      bool   // For streaming: indicates final response
+}
+
+type aiCommandResponse struct {
+	id     int
+	output string
+}
+
+type updateResponse struct {
+	release update.Release
+	err     error
+	manual  bool // Asked for with raven-update rather than the daily check
+}
+
+type gitStatusResponse struct {
+	tab   *tab.Tab
+	label string
+	done  bool // Every provider has finished; earlier responses are partial
+}
+
+// gitTabState records what a tab's git indicator was last queried for
+type gitTabState struct {
+	dir     string
+	prompt  int
+	pending bool
+}
+
+type promptRowResponse struct {
+	pane *tab.Pane
+	text string
+	done bool // Every provider has finished; earlier responses are partial
+}
+
+// promptRowState records what a pane's prompt status row was last detected for
+type promptRowState struct {
+	dir     string
+	prompt  int
+	pending bool
+	text    string
 }
 
 type modelLoadResponse struct {
@@ -92,6 +185,28 @@ type modelLoadResponse struct {
 	err   error
 }
 
+// aiToolCommands returns the commands the AI chat may ask to run
+func aiToolCommands(cfg *config.Config) []string {
+	if len(cfg.Ollama.ToolCommands) > 0 {
+		return cfg.Ollama.ToolCommands
+	}
+	return aitools.DefaultAllowed
+}
+
+// aiPersonas returns the personas defined in the config
+func aiPersonas(cfg *config.Config) []aipanel.Persona {
+	personas := make([]aipanel.Persona, 0, len(cfg.Ollama.Personas))
+	for _, persona := range cfg.Ollama.Personas {
+		personas = append(personas, aipanel.Persona{Name: persona.Name, Prompt: persona.Prompt})
+	}
+	return personas
+}
+
+// semanticSearchOn reports whether scrollback is embedded for semantic search
+func semanticSearchOn(cfg *config.Config) bool {
+	return cfg != nil && cfg.Ollama.Enabled && cfg.Ollama.SemanticSearch && strings.TrimSpace(cfg.Ollama.EmbeddingModel) != ""
+}
+
 func shellQuote(value string) string {
 	if value == "" {
 		return "''"
@@ -106,6 +221,186 @@ type mouseSelection struct {
 	startRow int
 }
 
+// scrollLock mirrors scrolling between two panes so they move together
+type scrollLock struct {
+	pending *tab.Pane // First pane picked, waiting for the second
+	panes   [2]*tab.Pane
+	grids   [2]*grid.Grid
+	offsets [2]int
+}
+
+func (s *scrollLock) linked() bool {
+	return s.panes[0] != nil
+}
+
+// link locks two panes together from their current scroll offsets
+func (s *scrollLock) link(a, b *tab.Pane) {
+	s.pending = nil
+	s.panes = [2]*tab.Pane{a, b}
+	for i, pane := range s.panes {
+		s.grids[i] = pane.Terminal.GetGrid()
+		s.offsets[i] = s.grids[i].GetScrollOffset()
+	}
+}
+
+// sync scrolls each pane by however far the other scrolled since the last call
+func (s *scrollLock) sync() {
+	for i, pane := range s.panes {
+		// A switch to or from the alternate screen is not a scroll
+		if g := pane.Terminal.GetGrid(); g != s.grids[i] {
+			s.grids[i] = g
+			s.offsets[i] = g.GetScrollOffset()
+		}
+	}
+	for i := range s.grids {
+		delta := s.grids[i].GetScrollOffset() - s.offsets[i]
+		other := s.grids[1-i]
+		if delta > 0 {
+			other.ScrollViewUp(delta)
+		} else if delta < 0 {
+			other.ScrollViewDown(-delta)
+		}
+		if delta != 0 {
+			break
+		}
+	}
+	for i, g := range s.grids {
+		s.offsets[i] = g.GetScrollOffset()
+	}
+}
+
+// paneCommands implements commands.PaneController with closures over main's state
+type paneCommands struct {
+	diff      func(first, second int) (string, error)
+	pipe      func(target string) (string, error)
+	sendText  func(pane int, text string) (string, error)
+	sendFile  func(pane int, path string) (string, error)
+	watch     func(paths []string, command string) (string, error)
+	highlight func(pattern string, remove bool) (string, error)
+	cleanup   func() (string, error)
+	updates   func() (string, error)
+	inspect   func() (string, error)
+	rewind    func() (string, error)
+	stamps    func() (string, error)
+	lines     func() (string, error)
+	runSplit  func(command string) (string, error)
+	calc      func() (string, error)
+	art       func() (string, error)
+	group     func(name string) (string, error)
+	groups    func() (string, error)
+	collapse  func(name string, collapse bool) (string, error)
+	closeTabs func(name string) (string, error)
+	respawn   func() (string, error)
+	custom    func(name string) (string, error)
+}
+
+func (p paneCommands) DiffPanes(first, second int) (string, error) {
+	return p.diff(first, second)
+}
+
+func (p paneCommands) PipePane(target string) (string, error) {
+	return p.pipe(target)
+}
+
+func (p paneCommands) SendText(pane int, text string) (string, error) {
+	return p.sendText(pane, text)
+}
+
+func (p paneCommands) SendFile(pane int, path string) (string, error) {
+	return p.sendFile(pane, path)
+}
+
+func (p paneCommands) WatchPane(paths []string, command string) (string, error) {
+	return p.watch(paths, command)
+}
+
+func (p paneCommands) HighlightPane(pattern string, remove bool) (string, error) {
+	return p.highlight(pattern, remove)
+}
+
+func (p paneCommands) CheckUpdates() (string, error) {
+	return p.updates()
+}
+
+func (p paneCommands) CleanupPanes() (string, error) {
+	return p.cleanup()
+}
+
+func (p paneCommands) InspectPane() (string, error) {
+	return p.inspect()
+}
+
+func (p paneCommands) RewindPane() (string, error) {
+	return p.rewind()
+}
+
+func (p paneCommands) ToggleTimestamps() (string, error) {
+	return p.stamps()
+}
+
+func (p paneCommands) ToggleLineNumbers() (string, error) {
+	return p.lines()
+}
+
+func (p paneCommands) RunInSplit(command string) (string, error) {
+	return p.runSplit(command)
+}
+
+func (p paneCommands) OpenCalculator() (string, error) {
+	return p.calc()
+}
+
+func (p paneCommands) ToggleArtMode() (string, error) {
+	return p.art()
+}
+
+func (p paneCommands) GroupTab(name string) (string, error) {
+	return p.group(name)
+}
+
+func (p paneCommands) ListTabGroups() (string, error) {
+	return p.groups()
+}
+
+func (p paneCommands) CollapseTabGroup(name string, collapse bool) (string, error) {
+	return p.collapse(name, collapse)
+}
+
+func (p paneCommands) CloseTabGroup(name string) (string, error) {
+	return p.closeTabs(name)
+}
+
+func (p paneCommands) RespawnPane() (string, error) {
+	return p.respawn()
+}
+
+func (p paneCommands) RunCustomCommand(name string) (string, error) {
+	return p.custom(name)
+}
+
+// paneWatch re-runs a command in a pane whenever watched files change
+type paneWatch struct {
+	command string
+	watcher *watch.Watcher
+	runs    int
+	lastRun time.Time
+	runAt   time.Time // When to type the command after interrupting the previous run
+}
+
+// highlightScanInterval is how often highlight watches count newly completed lines
+const highlightScanInterval = 500 * time.Millisecond
+
+// cursorSpotlightDuration is how long Ctrl+Shift+Space highlights the cursor
+const cursorSpotlightDuration = 700 * time.Millisecond
+
+// rewindSkip is how many frames PageUp and PageDown move in rewind mode
+const rewindSkip = 20
+
+// logMaxBytes is the size at which the log file is rotated, keeping logKeep older files
+const (
+	logMaxBytes = 1 << 20
+	logKeep     = 3
+)
 
 type toastState struct {
 	message   string
@@ -113,46 +408,178 @@ type toastState struct {
 }
 
 func main() {
+	// "raven-terminal send ..." talks to a running terminal instead of opening one
+	if len(os.Args) > 1 && os.Args[1] == "send" {
+		os.Exit(ipc.RunClient(os.Args[2:]))
+	}
+	// "raven-terminal restore-config" puts back a config backup without opening a window
+	if len(os.Args) > 1 && os.Args[1] == "restore-config" {
+		os.Exit(config.RunRestore(os.Args[2:]))
+	}
+	profileStartup := false
+	runBench := false
+	for _, arg := range os.Args[1:] {
+		switch arg {
+		case startup.Flag:
+			profileStartup = true
+		case bench.Flag:
+			runBench = true
+		}
+	}
+	startupProfile := startup.NewProfile(profileStartup)
+
+	// Log to a rotating file in the state directory as well as stderr
+	if logWriter, err := logfile.Open(filepath.Join(config.GetLogDir(), "raven-terminal.log"), logMaxBytes, logKeep); err != nil {
+		logging.Warnf(logging.App, "Failed to open log file: %v", err)
+	} else {
+		defer logWriter.Close()
+		log.SetOutput(io.MultiWriter(os.Stderr, logWriter))
+	}
+	if err := diagnostics.CaptureCrashes(); err != nil {
+		logging.Warnf(logging.App, "Failed to open crash log: %v", err)
+	}
+
+	// Load settings first so the glyph atlas is built once at the configured
+	// font size and the first shell starts at its final grid size
+	settingsMenu := menu.NewMenu()
+	// applyLogging sets each subsystem's log level from the [logging] settings
+	applyLogging := func(cfg *config.Config) {
+		if err := logging.Configure(cfg.Logging.Level, cfg.Logging.Subsystems); err != nil {
+			logging.Warnf(logging.App, "Invalid [logging] settings: %v", err)
+		}
+		// Kept from before per-subsystem levels existed
+		if os.Getenv("RAVEN_DEBUG_MENU") == "1" {
+			logging.SetLevel(logging.Menu, logging.LevelDebug)
+		}
+	}
+	applyLogging(settingsMenu.Config)
+	// chords are the tmux-style key sequences started by the [keys] leader,
+	// and tabKeys the [keys] that jump to a tab by number or to the last one
+	var chords *keybindings.Chords
+	var tabKeys *keybindings.TabKeys
+	applyKeys := func(cfg *config.Config) {
+		var err error
+		timeout := time.Duration(cfg.Keys.ChordTimeout) * time.Millisecond
+		chords, err = keybindings.NewChords(cfg.Keys.Leader, timeout, cfg.Keys.Chords)
+		if err != nil {
+			logging.Warnf(logging.App, "Invalid [keys] settings: %v", err)
+		}
+		tabKeys, err = keybindings.NewTabKeys(cfg.Keys.TabIndex, cfg.Keys.LastTab)
+		if err != nil {
+			logging.Warnf(logging.App, "Invalid [keys] settings: %v", err)
+		}
+	}
+	applyKeys(settingsMenu.Config)
+	// applyNetwork sets the proxy, certificates and timeouts of web requests from [network]
+	applyNetwork := func(cfg *config.Config) {
+		if err := netconf.Configure(cfg.Network); err != nil {
+			logging.Warnf(logging.App, "Invalid [network] settings: %v", err)
+		}
+	}
+	applyNetwork(settingsMenu.Config)
+	startupProfile.Mark("config")
+
 	// Create window
 	winConfig := window.DefaultConfig()
+	rendererBackend := settingsMenu.Config.Appearance.Renderer
+	winConfig.Software = strings.EqualFold(strings.TrimSpace(rendererBackend), render.BackendSoftware)
 	win, err := window.NewWindow(winConfig)
 	if err != nil {
 		log.Fatalf("Failed to create window: %v", err)
 	}
 	defer win.Destroy()
+	win.SetFullscreenMonitor(settingsMenu.Config.Window.FullscreenMonitor)
+	win.SetVSync(settingsMenu.Config.Window.VSync)
+	if settingsMenu.Config.Window.RememberPlacement {
+		win.RestorePlacement()
+	}
+	startupProfile.Mark("window")
 
 	// Create renderer
-	renderer, err := render.NewRenderer()
+	if win.Software() {
+		// The window fell back to a context only the software renderer can draw to
+		rendererBackend = render.BackendSoftware
+	}
+	renderer, err := render.NewRenderer(settingsMenu.Config.FontSize, rendererBackend)
 	if err != nil {
 		log.Fatalf("Failed to create renderer: %v", err)
 	}
 	defer renderer.Destroy()
+	startupProfile.Mark("renderer")
+	// monitorScale is the content scale of the window's monitor relative to
+	// the one it opened on; font sizes are multiplied by it
+	monitorScale := float32(1)
+	startScale, _ := win.GLFW().GetContentScale()
 
 	// Calculate initial grid size
 	width, height := win.GetFramebufferSize()
 	cols, rows := renderer.CalculateGridSize(width, height)
 
+	// --bench draws synthetic output in this window and exits without a shell
+	if runBench {
+		win.SetVSync(window.VSyncOff)
+		results := bench.Run(cols, rows, bench.Workloads(cols, rows, bench.WorkloadSize), func(term *parser.Terminal) {
+			width, height := win.GetFramebufferSize()
+			renderer.RenderGrid(term.GetGrid(), width, height)
+			renderer.Present()
+			win.SwapBuffers()
+			glfw.PollEvents()
+		})
+		bench.Report(os.Stdout, cols, rows, results)
+		return
+	}
+
+	// Open the control socket before the first shell starts so every pane
+	// inherits its path
+	var ipcCalls <-chan ipc.Call
+	if ipcServer, err := ipc.Listen(ipc.SocketPath()); err != nil {
+		logging.Warnf(logging.App, "Failed to open control socket: %v", err)
+	} else {
+		defer ipcServer.Close()
+		os.Setenv(ipc.SocketEnv, ipc.SocketPath())
+		ipcCalls = ipcServer.Calls()
+	}
+	startupProfile.Mark("control socket")
+
 	// Create tab manager
 	tabManager, err := tab.NewTabManager(uint16(cols), uint16(rows))
 	if err != nil {
 		log.Fatalf("Failed to create tab manager: %v", err)
 	}
-
-	debugMenu := os.Getenv("RAVEN_DEBUG_MENU") == "1"
+	startupProfile.Mark("first shell")
 
 	// Set up input callbacks
 	var currentMods glfw.ModifierKey
 	cursorVisible := true
 	lastBlink := time.Now()
 	blinkInterval := 500 * time.Millisecond
+	cursorBlink := true
+	copyExact := false
+	links := newLinkRules(config.DefaultConfig().Links)
+	passthrough := newPassthroughRules(nil)
+	copyOnSelect := true
+	copyTarget := clipboard.TargetClipboard
+	keepSelection := true
 	lineBuf := &lineBuffer{}
 	showHelp := false
 	resizeMode := false
+	paneNumbersMode := false
+	switcher := &tabSwitcher{}
+	recentTabs := true
+	var spotlightStart time.Time
+	// The cell described by the character inspector, if it is open
+	var charInfoPane *tab.Pane
+	var charInfoCol, charInfoRow int
+	var paneNumbersUntil time.Time
+	swallowChar := false
 	const resizeStep = 0.05
+	const paneZoomStep = 0.1
 	selection := &mouseSelection{}
 	var lastCursorX float64
 	var lastCursorY float64
 	var haveCursorPos bool
+	menuInputDrag := false // Extending the menu input's selection with the mouse
+	menuSliderDrag := -1   // Menu slider item being dragged
 	lastAutoScroll := time.Time{}
 	toast := &toastState{}
 	showToast := func(message string) {
@@ -162,23 +589,240 @@ func main() {
 		toast.message = message
 		toast.expiresAt = time.Now().Add(900 * time.Millisecond)
 	}
+	// splitError explains why a split asked for by a command was refused
+	splitError := func(err error) error {
+		switch {
+		case errors.Is(err, tab.ErrPaneTooSmall):
+			return errors.New("pane too small to split")
+		case errors.Is(err, tab.ErrPaneLimit):
+			return fmt.Errorf("a tab holds at most %d panes; raise [terminal] max_panes, or set it to 0 for no limit", tabManager.MaxPanes())
+		}
+		return err
+	}
+	// warnResources warns when a limit is off and the number of tabs or panes
+	// passes its default limit, and again at each further multiple of it, as
+	// every one runs its own shell and keeps its own scrollback
+	warnResources := func(what string, count, limit, defaultLimit int) {
+		if limit == 0 && count > defaultLimit && (count-1)%defaultLimit == 0 {
+			showToast(fmt.Sprintf("%d %s open; each keeps a shell and scrollback in memory", count, what))
+		}
+	}
+	// reportSplit shows why a split from the keyboard was refused
+	reportSplit := func(t *tab.Tab, err error) {
+		switch {
+		case errors.Is(err, tab.ErrPaneTooSmall):
+			showToast("Pane too small to split")
+		case errors.Is(err, tab.ErrPaneLimit):
+			showToast(fmt.Sprintf("Pane limit reached: %d per tab ([terminal] max_panes)", tabManager.MaxPanes()))
+		case err != nil:
+			showToast("Could not split the pane: " + err.Error())
+		default:
+			warnResources("panes in this tab", t.PaneCount(), tabManager.MaxPanes(), tab.DefaultMaxPanes)
+		}
+	}
+	// runCustomCommand runs a [[commands]] entry, typed into the active pane
+	// or in a new pane or tab as its open setting says
+	runCustomCommand := func(cmd config.CustomCommand) (string, error) {
+		activeTab := tabManager.ActiveTab()
+		if activeTab == nil || activeTab.GetActivePane() == nil {
+			return "", errors.New("no active pane")
+		}
+		title := cmd.Title
+		if title == "" {
+			title = cmd.Name
+		}
+		message := "Ran " + cmd.Name
+		switch strings.ToLower(strings.TrimSpace(cmd.Open)) {
+		case "pane":
+			split := activeTab.SplitVertical
+			if strings.EqualFold(strings.TrimSpace(cmd.Split), "horizontal") {
+				split = activeTab.SplitHorizontal
+			}
+			if err := split(); err != nil {
+				return "", splitError(err)
+			}
+			warnResources("panes in this tab", activeTab.PaneCount(), tabManager.MaxPanes(), tab.DefaultMaxPanes)
+			activeTab.GetActivePane().SetTitle(title)
+			message += " in a new pane"
+		case "tab":
+			if err := tabManager.NewTab(); err != nil {
+				if errors.Is(err, tab.ErrTabLimit) {
+					return "", fmt.Errorf("at most %d tabs can be open; raise [terminal] max_tabs, or set it to 0 for no limit", tabManager.MaxTabs())
+				}
+				return "", err
+			}
+			warnResources("tabs", tabManager.TabCount(), tabManager.MaxTabs(), tab.DefaultMaxTabs)
+			activeTab = tabManager.ActiveTab()
+			activeTab.GetActivePane().SetTitle(title)
+			message += " in a new tab"
+		}
+		lineBuf.clear()
+		activeTab.GetActivePane().Write([]byte(cmd.Command + "\r"))
+		return message, nil
+	}
 	searchPanel := searchpanel.New()
 	aiPanel := aipanel.New()
+	procPanel := procpanel.New()
+	procSampler := procmon.NewSampler()
+	sessionSampler := procmon.NewSampler()
+	localHost, _ := os.Hostname()
+	scrollSync := scrollLock{}
+	hostProfiles := make(map[*tab.Pane]config.HostProfile)
+	devPanel := devserver.NewPanel()
+	dirStore, err := dirjump.Load(dirjump.DefaultPath())
+	if err != nil {
+		logging.Warnf(logging.App, "Failed to load directory history: %v", err)
+	}
+	semanticIndex, err := semantic.Load(semantic.DefaultPath())
+	if err != nil {
+		logging.Warnf(logging.AI, "Failed to load semantic index: %v", err)
+	}
+	semanticMarks := make(map[*tab.Pane]int) // Absolute line each pane's scrollback is indexed up to
+	semanticBusy := false
+	semanticDone := make(chan error, 1)
+	lastSemanticScan := time.Now()
+	dirPanel := dirjump.NewPanel()
+	dirVisits := make(map[*tab.Pane]string)
+	lastDirSave := time.Now()
+	snippetPanel := snippets.NewPanel()
+	notifyPanel := notifications.NewPanel()
+	bellCounts := make(map[*tab.Pane]int)
+	commandCounts := make(map[*tab.Pane]int)
+	var knownTabs map[*tab.Tab]bool
+	cleanupPanel := cleanup.NewPanel()
+	exitedPanes := make(map[*tab.Pane]bool)
+	// exitHandled marks exited panes that on_exit or raven-run-in-split has dealt with
+	exitHandled := make(map[*tab.Pane]bool)
+	// heldPanes marks panes on_exit keeps open after their shell exited, until Enter closes them
+	heldPanes := make(map[*tab.Pane]bool)
+	// restartedAt records when on_exit last restarted each pane's shell
+	restartedAt := make(map[*tab.Pane]time.Time)
+	lastCleanupScan := time.Time{}
+	lastMemoryCheck := time.Time{}
+	memoryCapWarned := false
+	inspectPanel := inspector.NewPanel()
+	var inspectedPane *tab.Pane
+	var inspectRecorder *inspector.Recorder
+	var rewindPane *tab.Pane
+	var rewindPlayer *replay.Player
+	updatePanel := update.NewPanel()
+	updateResponses := make(chan updateResponse, 1)
+	updateDownloads := make(chan error, 1)
+	registerPanel := registers.NewPanel(registers.New())
+	calcPanel := calc.NewPanel()
+	palettePanel := palette.NewPanel()
+	// closeToolPanels hides the process, dev-server, directory jump, snippet,
+	// notification, pane cleanup, inspector, update, register, calculator and
+	// palette panels
+	closeToolPanels := func() {
+		procPanel.Open = false
+		devPanel.Open = false
+		dirPanel.Open = false
+		snippetPanel.Open = false
+		notifyPanel.Open = false
+		cleanupPanel.Open = false
+		inspectPanel.Open = false
+		updatePanel.Open = false
+		registerPanel.Open = false
+		calcPanel.Open = false
+		palettePanel.Open = false
+	}
+	urlChip := &toastState{}
+	urlChipTarget := ""
+	devVersions := make(map[*tab.Pane]int)
+	lastDevScan := time.Time{}
+	gitStates := make(map[*tab.Tab]*gitTabState)
+	gitResponses := make(chan gitStatusResponse, 8)
+	promptDetector := promptrow.NewDetector()
+	promptRowStates := make(map[*tab.Pane]*promptRowState)
+	promptRowResponses := make(chan promptRowResponse, 8)
+	lastGitScan := time.Time{}
 	searchResponses := make(chan searchResponse, 4)
+	semanticResponses := make(chan semanticResponse, 4)
 	previewResponses := make(chan previewResponse, 4)
 	aiResponses := make(chan aiResponse, 4)
+	aiCommandResponses := make(chan aiCommandResponse, 2)
 	modelLoadResponses := make(chan modelLoadResponse, 2)
 	const maxSearchResults = 8
+	const semanticBatchLines = 32 * semantic.ChunkLines
 	const maxChatMessages = 6
-	settingsMenu := menu.NewMenu()
+	const maxToolRounds = 5 // Commands the model may request per user message
+	redactor, _ := redact.New(nil)
+	redactionOn := false
+	// applyRedaction rebuilds the redaction patterns and hands them to the renderer
+	// while redaction mode is on
+	applyRedaction := func(cfg *config.Config) {
+		r, err := redact.New(cfg.Redaction.Patterns)
+		if err != nil {
+			logging.Warnf(logging.Render, "Invalid redaction pattern: %v", err)
+		}
+		redactor = r
+		if redactionOn {
+			renderer.SetRedactor(redactor)
+		} else {
+			renderer.SetRedactor(nil)
+		}
+	}
+	speaker := a11y.NewSpeaker()
+	outputTracker := &a11y.Tracker{}
+	screenReaderOn := false
+	lastAnnounce := time.Time{}
+	// applyAccessibility applies spoken output and cursor settings
+	applyAccessibility := func(cfg *config.Config) {
+		screenReaderOn = cfg.Accessibility.ScreenReader
+		if screenReaderOn && speaker == nil {
+			logging.Warnf(logging.App, "Spoken output enabled but no speech service was found")
+		}
+		outputTracker.Reset()
+		renderer.SetCursorThickness(float32(cfg.Accessibility.CursorThickness))
+		renderer.SetReduceMotion(cfg.Accessibility.ReduceMotion)
+	}
+	// speakText announces terminal text, masking secrets while redaction mode is on
+	speakText := func(text string) {
+		if redactionOn {
+			lines := strings.Split(text, "\n")
+			for i, line := range lines {
+				runes := []rune(line)
+				mask := redactor.Mask(runes)
+				if mask == nil {
+					continue
+				}
+				var b strings.Builder
+				for j, r := range runes {
+					if !mask[j] {
+						b.WriteRune(r)
+					} else if j == 0 || !mask[j-1] {
+						b.WriteString(" hidden ")
+					}
+				}
+				lines[i] = b.String()
+			}
+			text = strings.Join(lines, "\n")
+		}
+		speaker.Speak(text)
+	}
+	locked := false
+	lockInput := ""
+	hintState := &hints.State{}
+	markAction := "" // "set" or "jump" while waiting for the letter that names a scroll mark
+	lockStatus := ""
 	settingsMenu.OnConfigReload = func(cfg *config.Config) error {
 		if cfg == nil {
 			return nil
 		}
-		searchPanel.SetEnabled(cfg.WebSearch.Enabled)
+		searchPanel.SetEnabled(cfg.WebSearch.Enabled, semanticSearchOn(cfg))
 		aiPanel.SetEnabled(cfg.Ollama.Enabled)
+		aiPanel.SetPersonas(aiPersonas(cfg), cfg.Ollama.Persona)
+		aiPanel.SetGeometry(cfg.Appearance.AIPanelDock, cfg.Appearance.AIPanelSize)
 		aiPanel.ShowThinking = cfg.Ollama.ShowThinking
+		aiPanel.KeepState = cfg.Appearance.KeepPanelState
+		searchPanel.KeepState = cfg.Appearance.KeepPanelState
 		aiPanel.ThinkingMode = cfg.Ollama.ThinkingMode
+		applyRedaction(cfg)
+		applyAccessibility(cfg)
+		applyLogging(cfg)
+		applyKeys(cfg)
+		applyNetwork(cfg)
 		settingsMenu.OllamaModels = nil
 		if aiPanel.LoadedURL != cfg.Ollama.URL || aiPanel.LoadedModel != cfg.Ollama.Model {
 			aiPanel.ModelLoaded = false
@@ -186,7 +830,28 @@ func main() {
 			aiPanel.LoadedModel = cfg.Ollama.Model
 		}
 		renderer.SetThemeByName(cfg.Theme)
-		if err := renderer.SetDefaultFontSize(cfg.FontSize); err != nil {
+		tabManager.ApplyConfig(cfg)
+		renderer.SetPaneTitles(cfg.Appearance.PaneTitles)
+		renderer.SetPowerlineStretch(cfg.Appearance.PowerlineStretch)
+		renderer.SetStickyPrompt(cfg.Appearance.StickyPrompt)
+		renderer.SetWrapMarkers(cfg.Appearance.WrapMarkers)
+		renderer.SetTabGroupColors(tabGroupColors(cfg.TabGroups))
+		renderer.SetUIScale(cfg.Appearance.UIScale)
+		promptDetector.SetCommands(cfg.Prompt.Providers)
+		cursorBlink = cfg.Appearance.CursorBlink
+		copyExact = cfg.Terminal.CopyExactWhitespace
+		links = newLinkRules(cfg.Links)
+		passthrough = newPassthroughRules(cfg.Passthrough)
+		recentTabs = cfg.Keys.RecentTabs
+		copyOnSelect = cfg.Terminal.CopyOnSelect
+		copyTarget = cfg.Terminal.CopyTarget
+		keepSelection = cfg.Terminal.KeepSelection
+		win.SetFullscreenMonitor(cfg.Window.FullscreenMonitor)
+		win.SetVSync(cfg.Window.VSync)
+		if !cfg.Window.FollowMonitorScale {
+			monitorScale = 1
+		}
+		if err := renderer.SetDefaultFontSize(cfg.FontSize * monitorScale); err != nil {
 			return err
 		}
 		width, height := win.GetFramebufferSize()
@@ -224,23 +889,94 @@ func main() {
 		aiPanel.ModelLoaded = false
 		// Load model in background
 		go func(url, m string) {
-			ctx, cancel := context.WithTimeout(context.Background(), 300*time.Second) // 5 min for slow remote APIs
+			ctx, cancel := context.WithTimeout(context.Background(), netconf.OllamaTimeout()) // Slow remote APIs may need minutes
 			defer cancel()
 			client := ollama.NewClient(url, m)
 			err := client.LoadModel(ctx)
 			modelLoadResponses <- modelLoadResponse{url: url, model: m, err: err}
 		}(baseURL, model)
 	}
+	settingsMenu.OnClearHistory = func() error {
+		dirStore.Clear()
+		if err := dirStore.Save(); err != nil {
+			return err
+		}
+		semanticIndex.Clear()
+		if err := semanticIndex.Save(); err != nil {
+			return err
+		}
+		searchPanel.History = searchPanel.History[:0]
+		searchPanel.ResetHistory()
+		notifyPanel.Clear()
+		cacheDir := config.GetCacheDir()
+		entries, err := os.ReadDir(cacheDir)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		for _, entry := range entries {
+			if err := os.RemoveAll(filepath.Join(cacheDir, entry.Name())); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	settingsMenu.OnCreateDiagnostics = func() (string, error) {
+		var state []string
+		for ti, t := range tabManager.GetTabs() {
+			for pi, pane := range t.GetPanes() {
+				g := pane.Terminal.GetGrid()
+				state = append(state, fmt.Sprintf("Pane %d.%d: %dx%d, %d lines scrolled, exited %v",
+					ti+1, pi+1, g.Cols, g.Rows, g.ScrolledLines(), pane.HasExited()))
+			}
+		}
+		bundle := diagnostics.Bundle{Config: settingsMenu.Config, Graphics: append([]string{"Renderer backend: " + renderer.BackendName()}, win.GraphicsInfo()...), State: state}
+		path := diagnostics.DefaultPath()
+		if err := bundle.Write(path); err != nil {
+			return "", err
+		}
+		return path, nil
+	}
+	settingsMenu.OnRunCommand = func(cmd config.CustomCommand) error {
+		message, err := runCustomCommand(cmd)
+		if err != nil {
+			return err
+		}
+		showToast(message)
+		return nil
+	}
 	currentTheme := ""
 	if settingsMenu.Config != nil {
 		currentTheme = settingsMenu.Config.Theme
-		searchPanel.SetEnabled(settingsMenu.Config.WebSearch.Enabled)
+		searchPanel.SetEnabled(settingsMenu.Config.WebSearch.Enabled, semanticSearchOn(settingsMenu.Config))
 		aiPanel.SetEnabled(settingsMenu.Config.Ollama.Enabled)
+		aiPanel.SetPersonas(aiPersonas(settingsMenu.Config), settingsMenu.Config.Ollama.Persona)
+		aiPanel.SetGeometry(settingsMenu.Config.Appearance.AIPanelDock, settingsMenu.Config.Appearance.AIPanelSize)
 		aiPanel.ShowThinking = settingsMenu.Config.Ollama.ShowThinking
+		aiPanel.KeepState = settingsMenu.Config.Appearance.KeepPanelState
+		searchPanel.KeepState = settingsMenu.Config.Appearance.KeepPanelState
 		aiPanel.ThinkingMode = settingsMenu.Config.Ollama.ThinkingMode
 		aiPanel.LoadedURL = settingsMenu.Config.Ollama.URL
 		aiPanel.LoadedModel = settingsMenu.Config.Ollama.Model
 		renderer.SetThemeByName(currentTheme)
+		tabManager.ApplyConfig(settingsMenu.Config)
+		renderer.SetPaneTitles(settingsMenu.Config.Appearance.PaneTitles)
+		renderer.SetPowerlineStretch(settingsMenu.Config.Appearance.PowerlineStretch)
+		renderer.SetStickyPrompt(settingsMenu.Config.Appearance.StickyPrompt)
+		renderer.SetWrapMarkers(settingsMenu.Config.Appearance.WrapMarkers)
+		renderer.SetTabGroupColors(tabGroupColors(settingsMenu.Config.TabGroups))
+		renderer.SetUIScale(settingsMenu.Config.Appearance.UIScale)
+		promptDetector.SetCommands(settingsMenu.Config.Prompt.Providers)
+		cursorBlink = settingsMenu.Config.Appearance.CursorBlink
+		copyExact = settingsMenu.Config.Terminal.CopyExactWhitespace
+		links = newLinkRules(settingsMenu.Config.Links)
+		passthrough = newPassthroughRules(settingsMenu.Config.Passthrough)
+		recentTabs = settingsMenu.Config.Keys.RecentTabs
+		copyOnSelect = settingsMenu.Config.Terminal.CopyOnSelect
+		copyTarget = settingsMenu.Config.Terminal.CopyTarget
+		keepSelection = settingsMenu.Config.Terminal.KeepSelection
+		redactionOn = settingsMenu.Config.Redaction.Enabled
+		applyRedaction(settingsMenu.Config)
+		applyAccessibility(settingsMenu.Config)
 		if err := renderer.SetDefaultFontSize(settingsMenu.Config.FontSize); err == nil {
 			width, height := win.GetFramebufferSize()
 			cols, rows := renderer.CalculateGridSize(width, height)
@@ -248,9 +984,55 @@ func main() {
 		}
 	}
 
+	// startSemanticSearch embeds the query and looks it up in the scrollback index
+	startSemanticSearch := func(query string) {
+		cfg := settingsMenu.Config
+		searchPanel.Mode = searchpanel.ModeResults
+		if semanticIndex.Len() == 0 {
+			searchPanel.SetResults(query, nil, nil)
+			searchPanel.Status = "Scrollback not indexed yet"
+			return
+		}
+		searchPanel.Status = fmt.Sprintf("Searching %d scrollback chunks...", semanticIndex.Len())
+		searchPanel.StartLoading()
+		searchPanel.Results = nil
+		searchPanel.Selected = 0
+		searchPanel.ResultsScroll = 0
+		searchPanel.ResetHistory()
+		searchPanel.SearchID++
+		go func(id int, q, baseURL, model string) {
+			ctx, cancel := context.WithTimeout(context.Background(), netconf.OllamaTimeout())
+			defer cancel()
+			vectors, err := ollama.NewClient(baseURL, model).Embed(ctx, []string{q})
+			var matches []semantic.Match
+			if err == nil {
+				matches = semanticIndex.Search(model, vectors[0], maxSearchResults)
+			}
+			semanticResponses <- semanticResponse{id: id, query: q, matches: matches, err: err}
+		}(searchPanel.SearchID, query, cfg.Ollama.URL, cfg.Ollama.EmbeddingModel)
+	}
+
 	startSearch := func(query string) {
+		if searchPanel.Semantic {
+			startSemanticSearch(query)
+			return
+		}
+		var bangs map[string]string
+		if settingsMenu.Config != nil {
+			bangs = settingsMenu.Config.WebSearch.Bangs
+		}
+		parsed, err := websearch.ParseQuery(query, bangs)
+		if err != nil {
+			searchPanel.Mode = searchpanel.ModeResults
+			searchPanel.SetResults(query, nil, err)
+			searchPanel.Status = err.Error()
+			return
+		}
 		searchPanel.Mode = searchpanel.ModeResults
 		searchPanel.Status = "Searching..."
+		if len(parsed.Sites) > 0 {
+			searchPanel.Status = "Searching " + strings.Join(parsed.Sites, ", ") + "..."
+		}
 		searchPanel.StartLoading()
 		searchPanel.Results = nil
 		searchPanel.Selected = 0
@@ -259,14 +1041,23 @@ func main() {
 		searchPanel.SearchID++
 		searchID := searchPanel.SearchID
 		go func(id int, q string) {
-			ctx, cancel := context.WithTimeout(context.Background(), 8*time.Second)
+			ctx, cancel := context.WithTimeout(context.Background(), netconf.SearchTimeout())
 			defer cancel()
-			results, err := websearch.SearchDuckDuckGo(ctx, q, maxSearchResults)
-			searchResponses <- searchResponse{id: id, query: q, results: results, err: err}
+			results, err := websearch.SearchDuckDuckGo(ctx, parsed.String(), maxSearchResults)
+			searchResponses <- searchResponse{id: id, query: q, results: parsed.Filter(results), err: err}
 		}(searchID, query)
 	}
 
 	startPreview := func(result searchpanel.Result) {
+		if searchPanel.ReopenPreview(result) {
+			return
+		}
+		if result.Text != "" {
+			// Scrollback results carry their text, so nothing is fetched
+			searchPanel.PreviewID++
+			searchPanel.SetPreview(result.URL, result.Title, strings.Split(result.Text, "\n"), nil)
+			return
+		}
 		searchPanel.Mode = searchpanel.ModePreview
 		searchPanel.Status = "Loading preview..."
 		searchPanel.StartLoading()
@@ -282,31 +1073,46 @@ func main() {
 			proxyURLs = settingsMenu.Config.WebSearch.ReaderProxyURLs
 		}
 		go func(id int, url, title string, useProxy bool) {
-			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			ctx, cancel := context.WithTimeout(context.Background(), netconf.SearchTimeout())
 			defer cancel()
 			lines, source, proxyErr, err := websearch.FetchText(ctx, url, 12000, useProxy, proxyURLs)
 			previewResponses <- previewResponse{id: id, url: url, title: title, lines: lines, source: source, proxyErr: proxyErr, err: err}
 		}(previewID, result.URL, result.Title, useReaderProxy)
 	}
 
-	startAIChat := func(prompt string) {
-		if settingsMenu.Config == nil {
-			aiPanel.Status = "Missing config"
-			return
+	// openReaderPane shows the previewed page in a new split running less, so
+	// it stays open beside the shell. The page is saved under the cache dir.
+	openReaderPane := func() (string, error) {
+		activeTab := tabManager.ActiveTab()
+		if activeTab == nil {
+			return "", errors.New("no active tab")
 		}
-		trimmed := strings.TrimSpace(prompt)
-		if trimmed == "" {
-			return
+		if len(searchPanel.PreviewLines) == 0 {
+			return "", errors.New("nothing to read yet")
+		}
+		dir := filepath.Join(config.GetCacheDir(), "reader")
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return "", err
 		}
+		path := filepath.Join(dir, "page-"+time.Now().Format("20060102-150405")+".txt")
+		if err := os.WriteFile(path, []byte(searchPanel.ReaderText()), 0644); err != nil {
+			return "", err
+		}
+		if err := activeTab.SplitVertical(); err != nil {
+			return "", splitError(err)
+		}
+		// The leading space keeps the command out of shell history where ignorespace is set
+		activeTab.GetActivePane().Write([]byte(" less -R -- " + shellQuote(path) + "\r"))
+		return "Opened in a reader pane; q closes the reader", nil
+	}
 
+	// requestAIReply sends the conversation and streams the reply into the panel
+	requestAIReply := func() {
 		cfg := settingsMenu.Config.Ollama
 		if aiPanel.LoadedURL != cfg.URL || aiPanel.LoadedModel != cfg.Model {
 			aiPanel.ModelLoaded = false
 		}
 
-		aiPanel.AddMessage("user", trimmed)
-		aiPanel.TrimMessages(maxChatMessages)
-		aiPanel.ClearInput()
 		if !aiPanel.ModelLoaded {
 			aiPanel.Status = "Loading model..."
 		} else {
@@ -317,12 +1123,26 @@ func main() {
 		requestID := aiPanel.RequestID
 		needLoad := !aiPanel.ModelLoaded
 
-		messages := make([]ollama.Message, 0, len(aiPanel.Messages))
+		messages := make([]ollama.Message, 0, len(aiPanel.Messages)+1)
+		if persona := aiPanel.CurrentPersona(); persona.Prompt != "" {
+			messages = append(messages, ollama.Message{Role: "system", Content: persona.Prompt})
+		}
 		for _, msg := range aiPanel.Messages {
-			messages = append(messages, ollama.Message{
+			message := ollama.Message{
 				Role:    msg.Role,
 				Content: msg.Content,
-			})
+			}
+			if msg.Command != "" {
+				message.ToolCalls = []ollama.ToolCall{aitools.Call(msg.Command)}
+			}
+			if msg.Role == "tool" {
+				message.ToolName = aitools.ToolName
+			}
+			messages = append(messages, message)
+		}
+		var tools []ollama.Tool
+		if cfg.Tools && aiPanel.ToolRounds < maxToolRounds {
+			tools = []ollama.Tool{aitools.RunCommandTool(aiToolCommands(settingsMenu.Config))}
 		}
 
 		// Configure timeout based on thinking mode
@@ -336,6 +1156,7 @@ func main() {
 			defer cancel()
 
 			client := ollama.NewClient(baseURL, model)
+			client.Tools = tools
 			// Configure thinking mode
 			client.Thinking = ollama.ThinkingOptions{
 				Enabled: thinkingEnabled,
@@ -358,75 +1179,2353 @@ func main() {
 			result, err := client.ChatStreamWithThinking(ctx, messages, func(token string) {
 				aiResponses <- aiResponse{id: id, token: token, done: false}
 			}, nil)
-			aiResponses <- aiResponse{id: id, thinking: result.Thinking, err: err, done: true, loaded: loadSuccess}
+			aiResponses <- aiResponse{id: id, thinking: result.Thinking, toolCalls: result.ToolCalls, err: err, done: true, loaded: loadSuccess}
 		}(requestID, cfg.URL, cfg.Model, messages, needLoad, cfg.ThinkingMode, cfg.ThinkingBudget)
 	}
 
-	win.GLFW().SetKeyCallback(func(w *glfw.Window, key glfw.Key, scancode int, action glfw.Action, mods glfw.ModifierKey) {
-		if action == glfw.Release {
+	startAIChat := func(prompt string) {
+		if settingsMenu.Config == nil {
+			aiPanel.Status = "Missing config"
 			return
 		}
-
-		currentMods = mods
-		activeTab := tabManager.ActiveTab()
-		if activeTab == nil {
+		trimmed := strings.TrimSpace(prompt)
+		if trimmed == "" {
 			return
 		}
 
-		// Handle settings menu input when open
-		if settingsMenu.IsOpen() {
-			appCursor := activeTab.Terminal.AppCursorKeys()
-			result := keybindings.TranslateKey(key, mods, appCursor)
-			if result.Action == keybindings.ActionPaste && settingsMenu.InputMode() {
-				clip := glfw.GetClipboardString()
-				if clip != "" {
-					settingsMenu.HandlePaste(clip)
-					showToast("Pasted from clipboard")
-				}
+		if activeTab := tabManager.ActiveTab(); activeTab != nil {
+			if pane := activeTab.GetActivePane(); pane != nil && hostProfiles[pane].DisableAIContext {
+				aiPanel.Status = "AI chat is disabled on " + pane.Terminal.Host()
 				return
 			}
-			switch key {
-			case glfw.KeyUp:
-				settingsMenu.MoveUp()
-				return
-			case glfw.KeyDown:
-				settingsMenu.MoveDown()
-				return
-			case glfw.KeyEnter, glfw.KeyKPEnter:
-				if action == glfw.Repeat {
-					if debugMenu {
-						log.Printf("menu: key repeat ignored key=%v input=%v title=%s", key, settingsMenu.InputMode(), settingsMenu.GetTitle())
-					}
-					return
-				}
-				if settingsMenu.InputMode() && settingsMenu.InputIsMultiline() && mods&glfw.ModControl == 0 {
-					settingsMenu.HandleChar('\n')
-					return
-				}
-				if debugMenu {
-					log.Printf("menu: key enter key=%v input=%v title=%s", key, settingsMenu.InputMode(), settingsMenu.GetTitle())
+		}
+
+		aiPanel.AddMessage("user", trimmed)
+		aiPanel.TrimMessages(maxChatMessages)
+		aiPanel.ClearInput()
+		aiPanel.ToolRounds = 0
+		requestAIReply()
+	}
+
+	// resolveAICommand runs the command the model asked for, once the user
+	// allowed it, in the focused pane's directory; a denied command is
+	// reported back instead. The reply continues either way.
+	resolveAICommand := func(allow bool) {
+		command := aiPanel.PendingCommand
+		if !allow {
+			aiPanel.ResolveCommand("The user declined to run this command.")
+			requestAIReply()
+			return
+		}
+		argv, err := aitools.Parse(command, aiToolCommands(settingsMenu.Config))
+		if err != nil {
+			aiPanel.ResolveCommand("Not run: " + err.Error())
+			requestAIReply()
+			return
+		}
+		dir := ""
+		if activeTab := tabManager.ActiveTab(); activeTab != nil {
+			dir = activeTab.ActiveDir()
+		}
+		aiPanel.Status = "Running " + command + "..."
+		aiPanel.StartLoading()
+		go func(id int) {
+			aiCommandResponses <- aiCommandResponse{id: id, output: aitools.Run(context.Background(), argv, dir)}
+		}(aiPanel.RequestID)
+	}
+
+	// saveAIPanelGeometry writes where the AI panel is docked and its size
+	// to the config so the next session opens it the same way
+	saveAIPanelGeometry := func() {
+		if settingsMenu.Config == nil {
+			return
+		}
+		settingsMenu.Config.Appearance.AIPanelDock = aiPanel.Dock
+		settingsMenu.Config.Appearance.AIPanelSize = aiPanel.Size
+		if err := settingsMenu.Config.Save(); err != nil {
+			logging.Warnf(logging.App, "Failed to save AI panel geometry: %v", err)
+		}
+	}
+
+	// checkForUpdate fetches the latest release in the background
+	checkForUpdate := func(manual bool) {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), netconf.SearchTimeout())
+			defer cancel()
+			release, err := update.Latest(ctx)
+			updateResponses <- updateResponse{release: release, err: err, manual: manual}
+		}()
+	}
+	// downloadUpdate fetches the shown release to be installed on exit, when
+	// this install can be replaced and the release has a build for it
+	downloadUpdate := func() {
+		if updatePanel.Downloading || !updatePanel.Available {
+			return
+		}
+		asset, ok := update.AssetFor(updatePanel.Release)
+		if !ok {
+			updatePanel.Status = "No download for this system; O opens the release page"
+			return
+		}
+		if !update.CanInstall() {
+			updatePanel.Status = "Installed system-wide; update with the installer or package manager"
+			return
+		}
+		updatePanel.Downloading = true
+		updatePanel.Status = "Downloading " + asset.Name + "..."
+		release := updatePanel.Release
+		go func() {
+			updateDownloads <- update.Download(context.Background(), release, asset)
+		}()
+	}
+	// openUpdates shows the update panel and checks for the latest release
+	openUpdates := func() (string, error) {
+		searchPanel.Close()
+		aiPanel.Close()
+		closeToolPanels()
+		updatePanel.Toggle()
+		if updatePanel.Release.Tag == "" {
+			updatePanel.Status = "Checking for updates..."
+		}
+		checkForUpdate(true)
+		return "Checking for updates (running " + version.Version + ")", nil
+	}
+
+	refreshProcesses := func(now time.Time) {
+		var roots []int
+		type paneRef struct{ tabIndex, paneIndex, pid int }
+		var refs []paneRef
+		for ti, t := range tabManager.GetTabs() {
+			for pi, pane := range t.GetPanes() {
+				if pid := pane.PID(); pid > 0 {
+					roots = append(roots, pid)
+					refs = append(refs, paneRef{tabIndex: ti, paneIndex: pi, pid: pid})
+				}
+			}
+		}
+		trees := procSampler.Trees(roots)
+		var entries []procpanel.Entry
+		for _, ref := range refs {
+			for _, proc := range trees[ref.pid] {
+				entries = append(entries, procpanel.Entry{TabIndex: ref.tabIndex, PaneIndex: ref.paneIndex, Process: proc})
+			}
+		}
+		procPanel.SetEntries(entries, now)
+	}
+	scanDevServers := func(now time.Time) {
+		versions := make(map[*tab.Pane]int)
+		var entries []devserver.Entry
+		for ti, t := range tabManager.GetTabs() {
+			for pi, pane := range t.GetPanes() {
+				version := pane.DevServerVersion()
+				versions[pane] = version
+				urls := pane.DevServerURLs()
+				if prev, seen := devVersions[pane]; version != 0 && (!seen || prev != version) && len(urls) > 0 {
+					urlChipTarget = urls[len(urls)-1]
+					urlChip.message = "Dev server: " + urlChipTarget + " (click to open)"
+					urlChip.expiresAt = now.Add(8 * time.Second)
+				}
+				for _, url := range urls {
+					entries = append(entries, devserver.Entry{TabIndex: ti, PaneIndex: pi, URL: url})
+				}
+			}
+		}
+		devVersions = versions
+		devPanel.SetEntries(entries)
+	}
+	// refreshGitStatus queries git for each tab whose directory changed or whose
+	// shell printed a new prompt since the last query
+	refreshGitStatus := func() {
+		tabs := tabManager.GetTabs()
+		if settingsMenu.Config != nil && !settingsMenu.Config.Appearance.TabGitStatus {
+			for _, t := range tabs {
+				t.SetGitStatus("")
+			}
+			gitStates = make(map[*tab.Tab]*gitTabState)
+			return
+		}
+		segments := []string{promptrow.SegmentGit}
+		if settingsMenu.Config != nil && len(settingsMenu.Config.Appearance.TabStatusSegments) > 0 {
+			segments = settingsMenu.Config.Appearance.TabStatusSegments
+		}
+		live := make(map[*tab.Tab]bool, len(tabs))
+		for _, t := range tabs {
+			live[t] = true
+			pane := t.GetActivePane()
+			if pane == nil {
+				continue
+			}
+			dir := pane.Terminal.WorkingDir()
+			if dir == "" {
+				dir = t.ActiveDir()
+			}
+			prompt := pane.Terminal.PromptCount()
+			state, ok := gitStates[t]
+			if !ok {
+				state = &gitTabState{prompt: -1}
+				gitStates[t] = state
+			}
+			if state.pending || (state.dir == dir && state.prompt == prompt) {
+				continue
+			}
+			state.dir = dir
+			state.prompt = prompt
+			state.pending = true
+			go func(t *tab.Tab, dir string, vars map[string]string) {
+				results := promptDetector.Stream(context.Background(), dir, segments, vars, func(partial promptrow.Results) {
+					gitResponses <- gitStatusResponse{tab: t, label: partial.Compact(segments)}
+				})
+				gitResponses <- gitStatusResponse{tab: t, label: results.Compact(segments), done: true}
+			}(t, dir, pane.Terminal.UserVars())
+		}
+		for t := range gitStates {
+			if !live[t] {
+				delete(gitStates, t)
+			}
+		}
+	}
+	// refreshPromptRows runs the status row detectors for each pane of the
+	// active tab whose directory changed or whose shell printed a new prompt
+	refreshPromptRows := func() {
+		activeTab := tabManager.ActiveTab()
+		if activeTab == nil || settingsMenu.Config == nil || !settingsMenu.Config.Prompt.StatusRow {
+			promptRowStates = make(map[*tab.Pane]*promptRowState)
+			renderer.SetPromptRows(nil)
+			return
+		}
+		segments := settingsMenu.Config.Prompt.StatusRowSegments
+		if len(segments) == 0 {
+			segments = promptrow.DefaultSegments
+		}
+		live := make(map[*tab.Pane]bool)
+		rows := make(map[*tab.Pane]string)
+		for _, pane := range activeTab.GetPanes() {
+			live[pane] = true
+			dir := pane.Terminal.WorkingDir()
+			if dir == "" {
+				dir = pane.CurrentDir()
+			}
+			prompt := pane.Terminal.PromptCount()
+			state, ok := promptRowStates[pane]
+			if !ok {
+				state = &promptRowState{prompt: -1}
+				promptRowStates[pane] = state
+			}
+			rows[pane] = state.text
+			if state.pending || (state.dir == dir && state.prompt == prompt) {
+				continue
+			}
+			state.dir = dir
+			state.prompt = prompt
+			state.pending = true
+			go func(pane *tab.Pane, dir string, vars map[string]string) {
+				results := promptDetector.Stream(context.Background(), dir, segments, vars, func(partial promptrow.Results) {
+					promptRowResponses <- promptRowResponse{pane: pane, text: partial.Row(segments)}
+				})
+				promptRowResponses <- promptRowResponse{pane: pane, text: results.Row(segments), done: true}
+			}(pane, dir, pane.Terminal.UserVars())
+		}
+		for pane := range promptRowStates {
+			if !live[pane] {
+				delete(promptRowStates, pane)
+			}
+		}
+		renderer.SetPromptRows(rows)
+	}
+	// refreshSessionBorders marks panes whose shell runs as root or over ssh
+	refreshSessionBorders := func() {
+		if settingsMenu.Config == nil {
+			return
+		}
+		cfg := settingsMenu.Config.SessionBorders
+		if !cfg.Root && !cfg.SSH {
+			renderer.SetSessionBorders(nil, 0)
+			return
+		}
+		rootColor, ok := render.ParseHexColor(cfg.RootColor)
+		if !ok {
+			rootColor, _ = render.ParseHexColor("#8b0000")
+		}
+		sshColor, ok := render.ParseHexColor(cfg.SSHColor)
+		if !ok {
+			sshColor, _ = render.ParseHexColor("#b5651d")
+		}
+
+		var roots []int
+		var panes []*tab.Pane
+		for _, t := range tabManager.GetTabs() {
+			for _, pane := range t.GetPanes() {
+				if pid := pane.PID(); pid > 0 {
+					roots = append(roots, pid)
+				}
+				panes = append(panes, pane)
+			}
+		}
+		trees := sessionSampler.Trees(roots)
+		borders := make(map[*tab.Pane][4]float32)
+		for _, pane := range panes {
+			state := session.Detect(trees[pane.PID()], pane.Terminal.Host(), localHost, pane.Terminal.GetWindowTitle())
+			switch {
+			case state.Root && cfg.Root:
+				borders[pane] = rootColor
+			case state.Remote && cfg.SSH:
+				borders[pane] = sshColor
+			}
+		}
+		renderer.SetSessionBorders(borders, cfg.Width)
+	}
+	// runHook runs the [hooks] command for event, if one is set
+	runHook := func(event string, vars map[string]string) {
+		if settingsMenu.Config == nil {
+			return
+		}
+		if command := hooks.Command(settingsMenu.Config.Hooks, event); command != "" {
+			hooks.Run(event, command, vars)
+		}
+	}
+	// paneHookVars describes a pane to a hook
+	paneHookVars := func(source string, pane *tab.Pane) map[string]string {
+		return map[string]string{
+			"pane":  source,
+			"title": pane.Terminal.GetWindowTitle(),
+			"dir":   pane.CurrentDir(),
+		}
+	}
+	// collectBells records a notification for each pane that rang the bell since the last check
+	collectBells := func(now time.Time) {
+		enabled := settingsMenu.Config == nil || settingsMenu.Config.Notifications.Bell
+		counts := make(map[*tab.Pane]int)
+		for ti, t := range tabManager.GetTabs() {
+			for pi, pane := range t.GetPanes() {
+				count := pane.Terminal.BellCount()
+				counts[pane] = count
+				if count == bellCounts[pane] {
+					continue
+				}
+				vars := paneHookVars(fmt.Sprintf("%d.%d", ti+1, pi+1), pane)
+				vars["bell_count"] = strconv.Itoa(count - bellCounts[pane])
+				runHook(hooks.Bell, vars)
+				if !enabled {
+					continue
+				}
+				message := "Bell"
+				if title := pane.Terminal.GetWindowTitle(); title != "" {
+					message = "Bell: " + title
+				}
+				notifyPanel.Add(notifications.Entry{
+					Time:    now,
+					Kind:    notifications.KindBell,
+					Pane:    pane,
+					Source:  fmt.Sprintf("%d.%d", ti+1, pi+1),
+					Message: message,
+					Count:   count - bellCounts[pane],
+				})
+			}
+		}
+		bellCounts = counts
+	}
+	// collectLongCommands reports commands that ran longer than the configured
+	// threshold and finished in a pane the user was not looking at
+	collectLongCommands := func(now time.Time) {
+		threshold := 0
+		if settingsMenu.Config != nil {
+			threshold = settingsMenu.Config.Notifications.LongCommand
+		}
+		var focused *tab.Pane
+		if activeTab := tabManager.ActiveTab(); activeTab != nil && win.GLFW().GetAttrib(glfw.Focused) == glfw.True {
+			focused = activeTab.GetActivePane()
+		}
+		counts := make(map[*tab.Pane]int)
+		for ti, t := range tabManager.GetTabs() {
+			for pi, pane := range t.GetPanes() {
+				count, result := pane.Terminal.FinishedCommands()
+				counts[pane] = count
+				if count != commandCounts[pane] {
+					vars := paneHookVars(fmt.Sprintf("%d.%d", ti+1, pi+1), pane)
+					vars["command"] = result.Command
+					vars["duration"] = strconv.Itoa(int(result.Duration.Seconds()))
+					if result.ExitKnown {
+						vars["exit_code"] = strconv.Itoa(result.ExitCode)
+					}
+					runHook(hooks.CommandFinished, vars)
+				}
+				if count == commandCounts[pane] || threshold <= 0 || pane == focused ||
+					result.Duration < time.Duration(threshold)*time.Second {
+					continue
+				}
+				command := strings.Join(strings.Fields(result.Command), " ")
+				if runes := []rune(command); len(runes) > 60 {
+					command = string(runes[:57]) + "..."
+				}
+				if command == "" {
+					command = "Command"
+				}
+				message := fmt.Sprintf("%s finished in %s", command, result.Duration.Round(time.Second))
+				if result.ExitKnown {
+					message += fmt.Sprintf(", exit %d", result.ExitCode)
+				}
+				source := fmt.Sprintf("%d.%d", ti+1, pi+1)
+				notifyPanel.Add(notifications.Entry{
+					Time:    now,
+					Kind:    notifications.KindCommand,
+					Pane:    pane,
+					Source:  source,
+					Message: message,
+				})
+				showToast(source + ": " + message)
+			}
+		}
+		commandCounts = counts
+	}
+	// collectTabs runs the tab_opened hook for each tab opened since the last
+	// check; the tabs open at startup do not count
+	collectTabs := func() {
+		open := make(map[*tab.Tab]bool)
+		for ti, t := range tabManager.GetTabs() {
+			open[t] = true
+			if knownTabs == nil || knownTabs[t] {
+				continue
+			}
+			vars := map[string]string{"tab": strconv.Itoa(ti + 1)}
+			if pane := t.GetActivePane(); pane != nil {
+				vars = paneHookVars(fmt.Sprintf("%d.1", ti+1), pane)
+				vars["tab"] = strconv.Itoa(ti + 1)
+			}
+			runHook(hooks.TabOpened, vars)
+		}
+		knownTabs = open
+	}
+	// closeCleanupEntry closes a listed pane, or its tab when it is the tab's only pane
+	closeCleanupEntry := func(entry cleanup.Entry) bool {
+		if len(entry.Tab.GetPanes()) > 1 {
+			return entry.Tab.RemovePane(entry.Pane)
+		}
+		return tabManager.CloseTab(entry.Tab)
+	}
+	// scanCleanup lists exited and idle panes in the cleanup panel, first
+	// closing those the [pane_cleanup] settings close automatically
+	scanCleanup := func(now time.Time) {
+		cfg := config.DefaultConfig().PaneCleanup
+		if settingsMenu.Config != nil {
+			cfg = settingsMenu.Config.PaneCleanup
+		}
+		scan := func() []cleanup.Entry {
+			tabs := tabManager.GetTabs()
+			var roots []int
+			for _, t := range tabs {
+				for _, pane := range t.GetPanes() {
+					if pid := pane.PID(); pid > 0 && !pane.HasExited() {
+						roots = append(roots, pid)
+					}
+				}
+			}
+			return cleanup.Scan(tabs, sessionSampler.Trees(roots), time.Duration(cfg.IdleMinutes)*time.Minute, now)
+		}
+
+		entries := scan()
+		var focused *tab.Pane
+		if activeTab := tabManager.ActiveTab(); activeTab != nil {
+			focused = activeTab.GetActivePane()
+		}
+		closed := false
+		for _, entry := range entries {
+			if entry.Exited && cfg.AutoCloseExited || !entry.Exited && cfg.AutoCloseIdle && entry.Pane != focused {
+				if closeCleanupEntry(entry) {
+					closed = true
+				}
+			}
+		}
+		if closed {
+			// Closing renumbers the remaining tabs and panes
+			entries = scan()
+		}
+
+		exited := make(map[*tab.Pane]bool)
+		for _, entry := range entries {
+			if !entry.Exited {
+				continue
+			}
+			exited[entry.Pane] = true
+			if !exitedPanes[entry.Pane] {
+				showToast(fmt.Sprintf("Shell in pane %d.%d exited (raven-cleanup to close it)", entry.TabIndex+1, entry.PaneIndex+1))
+			}
+		}
+		exitedPanes = exited
+		cleanupPanel.SetEntries(entries)
+		lastCleanupScan = now
+	}
+	// enforceMemoryCap evicts the oldest scrollback, from the panes holding the
+	// most first, once all panes together exceed the configured memory cap
+	enforceMemoryCap := func() {
+		capMB := config.DefaultConfig().Terminal.MemoryCapMB
+		if settingsMenu.Config != nil {
+			capMB = settingsMenu.Config.Terminal.MemoryCapMB
+		}
+		if capMB <= 0 {
+			return
+		}
+		type paneMemory struct {
+			pane  *tab.Pane
+			stats grid.MemoryStats
+		}
+		var panes []paneMemory
+		var total int64
+		for _, t := range tabManager.GetTabs() {
+			for _, pane := range t.GetPanes() {
+				stats := pane.Terminal.Memory()
+				panes = append(panes, paneMemory{pane: pane, stats: stats})
+				total += stats.Total()
+			}
+		}
+		limit := int64(capMB) << 20
+		if total <= limit {
+			return
+		}
+
+		sort.Slice(panes, func(i, j int) bool {
+			return panes[i].stats.Scrollback > panes[j].stats.Scrollback
+		})
+		excess := total - limit
+		evicted, evictedPanes := 0, 0
+		for _, p := range panes {
+			if excess <= 0 {
+				break
+			}
+			if p.stats.ScrollbackRows == 0 {
+				continue
+			}
+			rowCost := max(p.stats.Scrollback/int64(p.stats.ScrollbackRows), 1)
+			rows := int((min(excess, p.stats.Scrollback) + rowCost - 1) / rowCost)
+			if n := p.pane.Terminal.EvictScrollback(rows); n > 0 {
+				excess -= int64(n) * rowCost
+				evicted += n
+				evictedPanes++
+			}
+		}
+		if evicted == 0 {
+			return
+		}
+		logging.Infof(logging.App, "Memory cap: %d MB in use across %d panes exceeds %d MB, evicted %d scrollback rows from %d panes",
+			total>>20, len(panes), capMB, evicted, evictedPanes)
+		if !memoryCapWarned {
+			memoryCapWarned = true
+			showToast(fmt.Sprintf("Memory cap of %d MB reached: dropping the oldest scrollback", capMB))
+		}
+	}
+	// openCleanup lists exited and idle panes for closing
+	openCleanup := func() (string, error) {
+		scanCleanup(time.Now())
+		if len(cleanupPanel.Entries) == 0 {
+			return "No exited or idle panes", nil
+		}
+		searchPanel.Close()
+		aiPanel.Close()
+		closeToolPanels()
+		cleanupPanel.Toggle()
+		return fmt.Sprintf("%d panes can be closed", len(cleanupPanel.Entries)), nil
+	}
+	// openInspector shows the escape sequences the active pane receives; the
+	// main loop starts and stops recording as the panel and focus change
+	openInspector := func() (string, error) {
+		if inspectPanel.Open {
+			inspectPanel.Open = false
+			return "Inspector closed", nil
+		}
+		searchPanel.Close()
+		aiPanel.Close()
+		closeToolPanels()
+		inspectPanel.Toggle()
+		showHelp = false
+		renderer.ResetHelpScroll()
+		return "Inspecting the active pane; Esc to close", nil
+	}
+	// openCalculator opens or closes the calculator overlay
+	openCalculator := func() (string, error) {
+		if calcPanel.Open {
+			calcPanel.Open = false
+			return "Calculator closed", nil
+		}
+		searchPanel.Close()
+		aiPanel.Close()
+		closeToolPanels()
+		calcPanel.Toggle("")
+		showHelp = false
+		renderer.ResetHelpScroll()
+		return "Calculator opened; Enter inserts the result, Esc closes", nil
+	}
+	// copySwatch copies the selected palette color's escape sequence, or its
+	// hex value
+	copySwatch := func(hex bool) {
+		swatch, ok := palettePanel.Current()
+		if !ok {
+			return
+		}
+		value := swatch.Sequence(palettePanel.Background)
+		if hex {
+			value = swatch.Hex()
+		}
+		glfw.SetClipboardString(value)
+		showToast("Copied " + value)
+	}
+	// showRewind hands the replayed screen and its position to the renderer
+	showRewind := func() {
+		if rewindPlayer == nil {
+			renderer.SetPaneReplay(nil, nil, "")
+			return
+		}
+		label := fmt.Sprintf("rewind %d/%d", rewindPlayer.Index()+1, rewindPlayer.Len())
+		if frame, ok := rewindPlayer.Frame(); ok {
+			label += " " + frame.Time.Format("15:04:05.000")
+		}
+		renderer.SetPaneReplay(rewindPane, rewindPlayer.Grid(), label)
+	}
+	stopRewind := func() {
+		rewindPane, rewindPlayer = nil, nil
+		showRewind()
+	}
+	// startRewind replays the focused pane's recent output so it can be stepped through
+	startRewind := func() (string, error) {
+		activeTab := tabManager.ActiveTab()
+		if activeTab == nil || activeTab.GetActivePane() == nil {
+			return "", fmt.Errorf("no active pane")
+		}
+		pane := activeTab.GetActivePane()
+		frames, trimmed := pane.Replay()
+		if len(frames) == 0 {
+			if settingsMenu.Config != nil && settingsMenu.Config.Terminal.ReplayBufferKB <= 0 {
+				return "Replay is off; set replay_buffer_kb in [terminal]", nil
+			}
+			return "No output recorded yet", nil
+		}
+		g := pane.Terminal.GetGrid()
+		rewindPane = pane
+		rewindPlayer = replay.NewPlayer(frames, trimmed, g.Cols, g.Rows, pane.Terminal.Latin1())
+		showRewind()
+		message := fmt.Sprintf("Rewinding %d frames; Left/Right: step | Esc: back to live", len(frames))
+		if trimmed {
+			message += "\nOlder output was dropped, so early frames may be missing colors or modes"
+		}
+		return message, nil
+	}
+	// jumpToNotification focuses the pane or panel a notification came from
+	jumpToNotification := func(entry notifications.Entry) {
+		if entry.Kind == notifications.KindAI {
+			if !aiPanel.Open {
+				showToast("AI chat was closed")
+				return
+			}
+			notifyPanel.Open = false
+			aiPanel.Focused = true
+			return
+		}
+		for _, t := range tabManager.GetTabs() {
+			for _, pane := range t.GetPanes() {
+				if pane != entry.Pane {
+					continue
+				}
+				tabManager.SetActiveTab(t)
+				t.SetActivePane(pane)
+				pane.Terminal.GetGrid().ResetScrollOffset()
+				notifyPanel.Open = false
+				return
+			}
+		}
+		showToast("Pane was closed")
+	}
+	// refreshHostProfiles applies the [hosts] profile of each pane connected to
+	// a remote host, and the [directories] profile of each local pane
+	refreshHostProfiles := func() {
+		if settingsMenu.Config == nil {
+			return
+		}
+		profiles := make(map[*tab.Pane]config.HostProfile)
+		styles := make(map[*tab.Pane]render.PaneProfile)
+		for _, t := range tabManager.GetTabs() {
+			for _, pane := range t.GetPanes() {
+				host := pane.Terminal.Host()
+				if host == "" || session.SameHost(host, localHost) {
+					if profile, ok := session.MatchDir(settingsMenu.Config.Directories, pane.Terminal.WorkingDir()); ok {
+						tint, tinted := render.ParseHexColor(profile.Tint)
+						styles[pane] = render.PaneProfile{Tint: tint, Tinted: tinted, TitlePrefix: profile.TitlePrefix}
+					}
+					continue
+				}
+				profile, ok := session.MatchHost(settingsMenu.Config.Hosts, host)
+				if !ok {
+					continue
+				}
+				profiles[pane] = profile
+				tint, tinted := render.ParseHexColor(profile.Tint)
+				styles[pane] = render.PaneProfile{Tint: tint, Tinted: tinted, TitlePrefix: profile.TitlePrefix}
+			}
+		}
+		hostProfiles = profiles
+		renderer.SetPaneProfiles(styles)
+	}
+	// refreshBadges expands each pane's badge format, its own from OSC 1337
+	// or [appearance] badge, with the user variables its shell has set
+	refreshBadges := func() {
+		format := ""
+		if settingsMenu.Config != nil {
+			format = settingsMenu.Config.Appearance.Badge
+		}
+		badges := make(map[*tab.Pane]string)
+		for _, t := range tabManager.GetTabs() {
+			for _, pane := range t.GetPanes() {
+				paneFormat := pane.Terminal.BadgeFormat()
+				if paneFormat == "" && pane.Title() != "" {
+					badges[pane] = pane.Title()
+					continue
+				}
+				if paneFormat == "" {
+					paneFormat = format
+				}
+				if paneFormat == "" {
+					continue
+				}
+				if badge := strings.TrimSpace(expandBadge(paneFormat, pane.Terminal.UserVars())); badge != "" {
+					badges[pane] = badge
+				}
+			}
+		}
+		renderer.SetPaneBadges(badges)
+	}
+	// refreshAliasPreview shows what the command being typed in the focused
+	// pane expands to when it starts with one of the [aliases]
+	refreshAliasPreview := func() {
+		activeTab := tabManager.ActiveTab()
+		if activeTab == nil || activeTab.GetActivePane() == nil || settingsMenu.Config == nil || !settingsMenu.Config.Appearance.AliasPreview {
+			renderer.SetAliasPreview(nil, "")
+			return
+		}
+		pane := activeTab.GetActivePane()
+		line, ok := pane.Terminal.CommandLine()
+		if !ok && !pane.Terminal.PromptMarked() && !pane.Terminal.AlternateScreen() {
+			// Without prompt marks, guess from what was typed
+			line = lineBuf.getLine()
+		}
+		renderer.SetAliasPreview(pane, expandAlias(line, settingsMenu.Config.Aliases))
+	}
+	// diffPanes splits the active pane and shows a colored diff of two panes' selections or visible text
+	diffPanes := func(first, second int) (string, error) {
+		activeTab := tabManager.ActiveTab()
+		if activeTab == nil {
+			return "", errors.New("no active tab")
+		}
+		panes := activeTab.GetPanes()
+		if len(panes) < 2 {
+			return "", errors.New("split the tab to compare two panes")
+		}
+		if first == 0 {
+			index := activeTab.ActivePaneIndex()
+			first, second = index+1, (index+1)%len(panes)+1
+		}
+		if first > len(panes) || second > len(panes) {
+			return "", fmt.Errorf("this tab has %d panes", len(panes))
+		}
+		if first == second {
+			return "", errors.New("pick two different panes")
+		}
+		paneLines := func(pane *tab.Pane) []string {
+			g := pane.Terminal.GetGrid()
+			text := g.SelectedText()
+			if text == "" {
+				text = g.VisibleText()
+			}
+			return strings.Split(text, "\n")
+		}
+		unified := diff.Unified(paneLines(panes[first-1]), paneLines(panes[second-1]),
+			fmt.Sprintf("pane %d", first), fmt.Sprintf("pane %d", second))
+		if unified == "" {
+			return fmt.Sprintf("Panes %d and %d are identical", first, second), nil
+		}
+		if err := activeTab.SplitVertical(); err != nil {
+			return "", splitError(err)
+		}
+		output := diff.Colorize(unified)
+		activeTab.GetActivePane().Terminal.Process([]byte(strings.ReplaceAll(output, "\n", "\r\n")))
+		return fmt.Sprintf("Diff of panes %d and %d opened in a new pane", first, second), nil
+	}
+	// runPanes maps panes opened by raven-run-in-split to their command until it exits
+	runPanes := make(map[*tab.Pane]string)
+	// runInSplit splits the active pane and runs command in the new pane
+	runInSplit := func(command string) (string, error) {
+		activeTab := tabManager.ActiveTab()
+		if activeTab == nil {
+			return "", errors.New("no active tab")
+		}
+		pane, err := activeTab.SplitRun(command)
+		if err != nil {
+			return "", splitError(err)
+		}
+		runPanes[pane] = command
+		return "Running in a new pane: " + command, nil
+	}
+	// openLink opens a URL with the [links] handler configured for its
+	// domain or scheme, or else the built-in one for its scheme: ssh connects
+	// in a new split, git remotes are copied for cloning, file URLs open the
+	// local path, and the rest go to the system opener
+	openLink := func(target string) error {
+		parsed, err := url.Parse(target)
+		if err != nil {
+			return openURL(target)
+		}
+		if command := links.handler(parsed); command != "" {
+			return runLinkHandler(command, target, parsed)
+		}
+		switch strings.ToLower(parsed.Scheme) {
+		case "ssh":
+			command, err := sshCommand(parsed)
+			if err != nil {
+				return err
+			}
+			_, err = runInSplit(command)
+			return err
+		case "git", "git+ssh", "ssh+git":
+			glfw.SetClipboardString(target)
+			return nil
+		case "file":
+			if parsed.Path != "" {
+				return openURL(parsed.Path)
+			}
+		}
+		return openURL(target)
+	}
+	// collectRunPanes closes or keeps raven-run-in-split panes whose command
+	// has exited, as [terminal] run_split_close says
+	collectRunPanes := func() {
+		if len(runPanes) == 0 {
+			return
+		}
+		closeWhen := "success"
+		if settingsMenu.Config != nil {
+			closeWhen = strings.ToLower(strings.TrimSpace(settingsMenu.Config.Terminal.RunSplitClose))
+		}
+		open := make(map[*tab.Pane]bool)
+		for _, t := range tabManager.GetTabs() {
+			for _, pane := range t.GetPanes() {
+				command, ok := runPanes[pane]
+				if !ok {
+					continue
+				}
+				open[pane] = true
+				code, exited := pane.ExitCode()
+				if !exited {
+					continue
+				}
+				delete(runPanes, pane)
+				// The pane is handled here; the cleanup scan need not announce it
+				exitedPanes[pane] = true
+				exitHandled[pane] = true
+				closeIt := closeWhen == "always" || closeWhen == "success" && code == 0
+				if !closeIt || !t.RemovePane(pane) {
+					pane.Terminal.Process([]byte(fmt.Sprintf("\r\n[%s exited with status %d]\r\n", command, code)))
+				}
+				if code != 0 {
+					showToast(fmt.Sprintf("%s failed with status %d", command, code))
+				}
+			}
+		}
+		for pane := range runPanes {
+			if !open[pane] {
+				delete(runPanes, pane)
+			}
+		}
+	}
+	// exitBehavior returns [terminal] on_exit, falling back to "close"
+	exitBehavior := func() string {
+		if settingsMenu.Config == nil {
+			return "close"
+		}
+		switch behavior := strings.ToLower(strings.TrimSpace(settingsMenu.Config.Terminal.OnExit)); behavior {
+		case "hold", "hold_on_failure", "restart":
+			return behavior
+		}
+		return "close"
+	}
+	// closeExitedPane closes a pane whose shell exited, or its tab when it is
+	// the tab's only pane, reporting true when that was the last tab
+	closeExitedPane := func(t *tab.Tab, pane *tab.Pane) bool {
+		delete(heldPanes, pane)
+		if len(t.GetPanes()) > 1 {
+			t.RemovePane(pane)
+			return false
+		}
+		if tabManager.CloseTab(t) {
+			return false
+		}
+		tabs := tabManager.GetTabs()
+		return len(tabs) == 1 && tabs[0] == t
+	}
+	// handleExitedPanes holds, restarts or closes panes whose shell has
+	// exited, as [terminal] on_exit says, reporting true once the last tab
+	// has closed
+	handleExitedPanes := func(behavior string, now time.Time) bool {
+		open := make(map[*tab.Pane]bool)
+		for _, t := range tabManager.GetTabs() {
+			for _, pane := range t.GetPanes() {
+				open[pane] = true
+				if _, ok := runPanes[pane]; ok || exitHandled[pane] {
+					continue
+				}
+				code, exited := pane.ExitCode()
+				if !exited {
+					continue
+				}
+				note := fmt.Sprintf("[process exited with code %d] press Enter to close", code)
+				switch {
+				case behavior == "hold_on_failure" && code == 0:
+					if closeExitedPane(t, pane) {
+						return true
+					}
+					continue
+				case behavior == "restart" && now.Sub(restartedAt[pane]) < 5*time.Second:
+					// A shell that dies straight away would restart forever
+					note = fmt.Sprintf("[process exited with code %d again, not restarting] press Enter to close", code)
+				case behavior == "restart":
+					pane.Terminal.Process([]byte(fmt.Sprintf("\r\n[process exited with code %d, restarting]\r\n", code)))
+					err := pane.Respawn()
+					if err == nil {
+						restartedAt[pane] = now
+						continue
+					}
+					note = fmt.Sprintf("[restart failed: %v] press Enter to close", err)
+				}
+				pane.Terminal.Process([]byte("\r\n" + note + "\r\n"))
+				exitHandled[pane] = true
+				heldPanes[pane] = true
+				// The note says it all; the cleanup scan need not announce it
+				exitedPanes[pane] = true
+			}
+		}
+		for pane := range exitHandled {
+			if !open[pane] {
+				delete(exitHandled, pane)
+				delete(heldPanes, pane)
+			}
+		}
+		for pane := range restartedAt {
+			if !open[pane] {
+				delete(restartedAt, pane)
+			}
+		}
+		return false
+	}
+	// respawnPane kills and restarts the shell, or the raven-run-in-split
+	// command, in the active pane, keeping its scrollback
+	respawnPane := func() (string, error) {
+		activeTab := tabManager.ActiveTab()
+		if activeTab == nil || activeTab.GetActivePane() == nil {
+			return "", errors.New("no active pane")
+		}
+		pane := activeTab.GetActivePane()
+		if err := pane.Respawn(); err != nil {
+			return "", fmt.Errorf("cannot restart the pane: %w", err)
+		}
+		delete(exitHandled, pane)
+		delete(heldPanes, pane)
+		delete(restartedAt, pane)
+		delete(exitedPanes, pane)
+		lineBuf.clear()
+		if command := pane.Command(); command != "" {
+			runPanes[pane] = command
+			return "Restarted " + command, nil
+		}
+		return "Restarted the shell", nil
+	}
+	// pipePane starts or stops streaming the focused pane's output
+	pipePane := func(target string) (string, error) {
+		activeTab := tabManager.ActiveTab()
+		if activeTab == nil || activeTab.GetActivePane() == nil {
+			return "", errors.New("no active pane")
+		}
+		pane := activeTab.GetActivePane()
+		if target == "" {
+			pipe := pane.StopPipe()
+			if pipe == nil {
+				return "Usage: raven-pipe <command> | >file | >>file", nil
+			}
+			if dropped := pipe.Dropped(); dropped > 0 {
+				return fmt.Sprintf("Stopped piping to %s (%d chunks dropped)", pipe.Target, dropped), nil
+			}
+			return "Stopped piping to " + pipe.Target, nil
+		}
+		if err := pane.StartPipe(target); err != nil {
+			return "", err
+		}
+		return "Piping output to " + target + " (raven-pipe to stop)", nil
+	}
+	// sendToPane types text into a pane. Tabs and panes are numbered from 1 as
+	// in the tab bar and pane numbers; 0 picks the active one.
+	sendToPane := func(tabNumber, paneNumber int, text string, bracketed bool) error {
+		t := tabManager.ActiveTab()
+		if tabNumber > 0 {
+			tabs := tabManager.GetTabs()
+			if tabNumber > len(tabs) {
+				return fmt.Errorf("no tab %d", tabNumber)
+			}
+			t = tabs[tabNumber-1]
+		}
+		if t == nil {
+			return errors.New("no active tab")
+		}
+		pane := t.GetActivePane()
+		if paneNumber > 0 {
+			panes := t.GetPanes()
+			if paneNumber > len(panes) {
+				return fmt.Errorf("no pane %d", paneNumber)
+			}
+			pane = panes[paneNumber-1]
+		}
+		if pane == nil {
+			return errors.New("no active pane")
+		}
+		if len(text) > ipc.MaxSendBytes {
+			return fmt.Errorf("text is larger than %d bytes", ipc.MaxSendBytes)
+		}
+		if bracketed && pane.Terminal.BracketedPasteEnabled() {
+			text = "\x1b[200~" + text + "\x1b[201~"
+		}
+		if err := pane.Write(keybindings.EncodeText(text, pane.Terminal.Latin1())); err != nil {
+			return err
+		}
+		pane.Terminal.GetGrid().ResetScrollOffset()
+		return nil
+	}
+	paneWatches := make(map[*tab.Pane]*paneWatch)
+	// watchPane starts or stops re-running a command in the focused pane on file changes
+	watchPane := func(paths []string, command string) (string, error) {
+		activeTab := tabManager.ActiveTab()
+		if activeTab == nil || activeTab.GetActivePane() == nil {
+			return "", errors.New("no active pane")
+		}
+		pane := activeTab.GetActivePane()
+		previous := paneWatches[pane]
+		if previous != nil {
+			previous.watcher.Stop()
+			delete(paneWatches, pane)
+		}
+		if command == "" {
+			if previous == nil {
+				return "Usage: raven-watch <path>... -- <command>", nil
+			}
+			return "Stopped watching for " + previous.command, nil
+		}
+		w := &paneWatch{
+			command: command,
+			watcher: watch.Start(paths, pane.CurrentDir()),
+			runs:    1,
+			lastRun: time.Now(),
+		}
+		paneWatches[pane] = w
+		if err := pane.Write([]byte(command + "\r")); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Watching %s; raven-watch to stop", strings.Join(paths, " ")), nil
+	}
+	paneHighlights := make(map[*tab.Pane]*highlight.Set)
+	var lastHighlightScan time.Time
+	renderer.SetPaneHighlights(paneHighlights)
+	// highlightPane adds, removes or lists the focused pane's highlight watches
+	highlightPane := func(pattern string, remove bool) (string, error) {
+		activeTab := tabManager.ActiveTab()
+		if activeTab == nil || activeTab.GetActivePane() == nil {
+			return "", errors.New("no active pane")
+		}
+		pane := activeTab.GetActivePane()
+		set := paneHighlights[pane]
+		switch {
+		case remove && pattern == "":
+			delete(paneHighlights, pane)
+			return "Highlights cleared", nil
+		case remove:
+			if set == nil || !set.Remove(pattern) {
+				return "", fmt.Errorf("not highlighting %q", pattern)
+			}
+			if set.Len() == 0 {
+				delete(paneHighlights, pane)
+			}
+			return "Stopped highlighting " + pattern, nil
+		case pattern == "":
+			if set.Len() == 0 {
+				return "Usage: raven-highlight <pattern>", nil
+			}
+			set.Scan(pane.Terminal.GetGrid())
+			var b strings.Builder
+			b.WriteString("Highlighting:")
+			for _, w := range set.Watches {
+				fmt.Fprintf(&b, "\n  %-20s %d", w.Pattern, w.Count)
+			}
+			return b.String(), nil
+		}
+		if set == nil {
+			set = &highlight.Set{}
+		}
+		if err := set.Add(pattern); err != nil {
+			return "", err
+		}
+		paneHighlights[pane] = set
+		lastHighlightScan = time.Time{}
+		return fmt.Sprintf("Highlighting %s; raven-highlight -d %s to stop", pattern, pattern), nil
+	}
+	paneCmds := paneCommands{
+		diff:      diffPanes,
+		pipe:      pipePane,
+		watch:     watchPane,
+		highlight: highlightPane,
+		cleanup:   openCleanup,
+		updates:   openUpdates,
+		inspect:   openInspector,
+		rewind:    startRewind,
+		runSplit:  runInSplit,
+		calc:      openCalculator,
+		stamps: func() (string, error) {
+			activeTab := tabManager.ActiveTab()
+			if activeTab == nil {
+				return "", fmt.Errorf("no active tab")
+			}
+			if activeTab.ToggleTimestamps() {
+				return "Timestamps shown for this pane", nil
+			}
+			return "Timestamps hidden for this pane", nil
+		},
+		lines: func() (string, error) {
+			activeTab := tabManager.ActiveTab()
+			if activeTab == nil {
+				return "", fmt.Errorf("no active tab")
+			}
+			if activeTab.ToggleLineNumbers() {
+				return "Line numbers shown for this pane", nil
+			}
+			return "Line numbers hidden for this pane", nil
+		},
+		art: func() (string, error) {
+			activeTab := tabManager.ActiveTab()
+			if activeTab == nil {
+				return "", fmt.Errorf("no active tab")
+			}
+			pane := activeTab.GetActivePane()
+			if pane == nil {
+				return "", fmt.Errorf("no active pane")
+			}
+			g := pane.Terminal.GetGrid()
+			g.SetArtMode(!g.ArtMode())
+			if g.ArtMode() {
+				return "Art mode on for this pane: blocks drawn flush, selections copied untrimmed", nil
+			}
+			return "Art mode off for this pane", nil
+		},
+		group: func(name string) (string, error) {
+			activeTab := tabManager.ActiveTab()
+			if activeTab == nil {
+				return "", fmt.Errorf("no active tab")
+			}
+			if name == "" {
+				old := activeTab.Group()
+				if old == "" {
+					return "This tab is not in a group", nil
+				}
+				tabManager.SetGroup(activeTab, "")
+				return fmt.Sprintf("Tab %d left group %s", activeTab.ID(), old), nil
+			}
+			tabManager.SetGroup(activeTab, name)
+			return fmt.Sprintf("Tab %d is in group %s", activeTab.ID(), name), nil
+		},
+		groups: func() (string, error) {
+			groups := tabManager.Groups()
+			if len(groups) == 0 {
+				return "No tab groups. Put this tab in one with: raven-group <name>", nil
+			}
+			var b strings.Builder
+			b.WriteString("Tab groups:")
+			for _, g := range groups {
+				var ids []string
+				for _, t := range tabManager.GetTabs() {
+					if t.Group() == g.Name {
+						ids = append(ids, strconv.Itoa(t.ID()))
+					}
+				}
+				state := ""
+				if g.Collapsed {
+					state = " (collapsed)"
+				}
+				fmt.Fprintf(&b, "\n  %-12s tabs %s%s", g.Name, strings.Join(ids, ", "), state)
+			}
+			return b.String(), nil
+		},
+		collapse: func(name string, collapse bool) (string, error) {
+			name, err := tabGroupName(tabManager, name)
+			if err != nil {
+				return "", err
+			}
+			if !tabManager.SetGroupCollapsed(name, collapse) {
+				return "", fmt.Errorf("no tab is in group %s", name)
+			}
+			if collapse {
+				return fmt.Sprintf("Group %s collapsed", name), nil
+			}
+			return fmt.Sprintf("Group %s expanded", name), nil
+		},
+		closeTabs: func(name string) (string, error) {
+			name, err := tabGroupName(tabManager, name)
+			if err != nil {
+				return "", err
+			}
+			closed := tabManager.CloseGroup(name)
+			if closed == 0 {
+				return "", fmt.Errorf("no tab in group %s can be closed", name)
+			}
+			lineBuf.clear()
+			return fmt.Sprintf("Closed %d tabs in group %s", closed, name), nil
+		},
+		respawn: respawnPane,
+		custom: func(name string) (string, error) {
+			var list []config.CustomCommand
+			if settingsMenu.Config != nil {
+				list = settingsMenu.Config.Commands
+			}
+			if name == "" {
+				if len(list) == 0 {
+					return "No custom commands. Add them under [[commands]] or in the settings menu", nil
+				}
+				var b strings.Builder
+				b.WriteString("Custom commands:")
+				for _, cmd := range list {
+					fmt.Fprintf(&b, "\n  %-12s %s", cmd.Name, cmd.Command)
+				}
+				return b.String(), nil
+			}
+			for _, cmd := range list {
+				if cmd.Name == name {
+					return runCustomCommand(cmd)
+				}
+			}
+			return "", fmt.Errorf("no custom command named %s", name)
+		},
+		sendText: func(pane int, text string) (string, error) {
+			if err := sendToPane(0, pane, text+"\r", false); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Sent to pane %d", pane), nil
+		},
+		sendFile: func(pane int, path string) (string, error) {
+			if !filepath.IsAbs(path) {
+				if active := tabManager.ActiveTab(); active != nil {
+					path = filepath.Join(active.ActiveDir(), path)
+				}
+			}
+			text, err := ipc.ReadSendFile(path)
+			if err != nil {
+				return "", err
+			}
+			if err := sendToPane(0, pane, text, true); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Sent %s to pane %d", filepath.Base(path), pane), nil
+		},
+	}
+	// trackDirVisits records a directory visit whenever a pane's OSC 7 cwd changes
+	trackDirVisits := func(now time.Time) {
+		seen := make(map[*tab.Pane]string)
+		for _, t := range tabManager.GetTabs() {
+			for _, pane := range t.GetPanes() {
+				dir := pane.Terminal.WorkingDir()
+				seen[pane] = dir
+				if dir != "" && dirVisits[pane] != dir {
+					dirStore.Visit(dir, now)
+				}
+			}
+		}
+		dirVisits = seen
+		if now.Sub(lastDirSave) >= 30*time.Second {
+			if err := dirStore.Save(); err != nil {
+				logging.Warnf(logging.App, "Failed to save directory history: %v", err)
+			}
+			lastDirSave = now
+		}
+	}
+	// indexScrollback embeds scrollback that has scrolled off screen since the
+	// last scan, one batch at a time. Panes on hosts that keep their text out
+	// of AI prompts are skipped, and secrets are masked before embedding.
+	indexScrollback := func() {
+		cfg := settingsMenu.Config
+		if !semanticSearchOn(cfg) || semanticBusy {
+			return
+		}
+		model := cfg.Ollama.EmbeddingModel
+		var chunks []semantic.Chunk
+		live := make(map[*tab.Pane]int)
+		for _, t := range tabManager.GetTabs() {
+			for _, pane := range t.GetPanes() {
+				g := pane.Terminal.GetGrid()
+				scrolled := g.ScrolledLines()
+				mark := semanticMarks[pane]
+				live[pane] = mark
+				if hostProfiles[pane].DisableAIContext || scrolled-mark < semantic.ChunkLines {
+					continue
+				}
+				// Long backlogs, such as scrollback from before indexing was
+				// turned on, are worked through over several scans
+				if scrolled-mark > semanticBatchLines {
+					scrolled = mark + semanticBatchLines
+				}
+				source := pane.CurrentDir()
+				if source == "" {
+					source = fmt.Sprintf("pane %d", pane.ID())
+				}
+				for _, text := range semantic.Split(g.TextBetween(mark, scrolled), redactor) {
+					if !semanticIndex.Has(model, text) {
+						chunks = append(chunks, semantic.Chunk{Time: time.Now(), Source: source, Text: text})
+					}
+				}
+				live[pane] = scrolled
+			}
+		}
+		semanticMarks = live
+		if len(chunks) == 0 {
+			return
+		}
+		semanticBusy = true
+		go func(baseURL string, chunks []semantic.Chunk) {
+			ctx, cancel := context.WithTimeout(context.Background(), netconf.OllamaTimeout())
+			defer cancel()
+			texts := make([]string, len(chunks))
+			for i, chunk := range chunks {
+				texts[i] = chunk.Text
+			}
+			vectors, err := ollama.NewClient(baseURL, model).Embed(ctx, texts)
+			if err == nil {
+				for i := range chunks {
+					chunks[i].Vector = vectors[i]
+				}
+				semanticIndex.Add(model, chunks)
+				err = semanticIndex.Save()
+			}
+			semanticDone <- err
+		}(cfg.Ollama.URL, chunks)
+	}
+	refreshDirJump := func() {
+		dirPanel.SetResults(dirStore.Match(dirPanel.Query, dirjump.MaxResults, time.Now()))
+	}
+	refreshSnippets := func() {
+		if settingsMenu.Config == nil {
+			snippetPanel.Filter(nil)
+			return
+		}
+		snippetPanel.Filter(settingsMenu.Config.Snippets)
+	}
+	// insertSnippet types the expanded snippet at the shell prompt without running it
+	insertSnippet := func(activeTab *tab.Tab, text string) {
+		if activeTab.Terminal.BracketedPasteEnabled() {
+			text = "\x1b[200~" + text + "\x1b[201~"
+		} else {
+			text = strings.ReplaceAll(text, "\r\n", "\n")
+			text = strings.ReplaceAll(text, "\n", "\r")
+		}
+		activeTab.Write(keybindings.EncodeText(text, activeTab.Terminal.Latin1()))
+		activeTab.Terminal.GetGrid().ResetScrollOffset()
+		snippetPanel.Open = false
+	}
+	// hintTargets collects the words on screen in panes, widening words that hold a URL to the URL
+	hintTargets := func(panes []*tab.Pane) []hints.Target {
+		var targets []hints.Target
+		for _, pane := range panes {
+			g := pane.Terminal.GetGrid()
+			for _, target := range hints.Words(pane, g) {
+				if urlText, start, end := urlAtCellRange(g, target.Start, target.Row, links); urlText != "" {
+					target.Text = urlText
+					target.URL = true
+					target.Start, target.End = start, end
+				}
+				targets = append(targets, target)
+			}
+		}
+		return targets
+	}
+	// copySelection copies a pane's selection to target, then clears the
+	// selection unless keep_selection is on. It reports whether there was
+	// anything to copy.
+	copySelection := func(g *grid.Grid, mods glfw.ModifierKey, target string) bool {
+		text := selectionText(g, mods, copyExact)
+		if text == "" {
+			return false
+		}
+		clipboard.Write(target, text)
+		if strings.EqualFold(target, clipboard.TargetPrimary) {
+			showToast("Copied to primary selection")
+		} else {
+			showToast("Copied to clipboard")
+		}
+		if !keepSelection {
+			g.ClearSelection()
+		}
+		return true
+	}
+	// useRegister yanks the active pane's selection into the named register,
+	// or pastes the register into the pane, as the register viewer's mode
+	// says, and closes the viewer
+	useRegister := func(name rune) {
+		activeTab := tabManager.ActiveTab()
+		if activeTab == nil || activeTab.Terminal == nil {
+			return
+		}
+		if !registers.Valid(name) {
+			showToast("Registers are named a-z and 0-9")
+			return
+		}
+		if registerPanel.Yank {
+			text := selectionText(activeTab.Terminal.GetGrid(), 0, copyExact)
+			if text == "" {
+				showToast("Nothing selected")
+				return
+			}
+			registerPanel.Registers.Set(name, text)
+			showToast(fmt.Sprintf("Yanked into register %c", unicode.ToLower(name)))
+		} else {
+			text, ok := registerPanel.Registers.Get(name)
+			if !ok {
+				showToast(fmt.Sprintf("Register %c is empty", unicode.ToLower(name)))
+				return
+			}
+			text = strings.ReplaceAll(text, "\r\n", "\n")
+			text = strings.ReplaceAll(text, "\n", "\r")
+			activeTab.Write(keybindings.EncodeText(text, activeTab.Terminal.Latin1()))
+			activeTab.Terminal.GetGrid().ResetScrollOffset()
+		}
+		registerPanel.Open = false
+	}
+	// copyPaneRange selects a range in a pane and copies it, as a mouse drag would
+	copyPaneRange := func(pane *tab.Pane, startCol, startRow, endCol, endRow int) {
+		if selection.pane != nil && selection.pane != pane {
+			selection.pane.Terminal.GetGrid().ClearSelection()
+		}
+		selection.active = false
+		selection.pane = pane
+		g := pane.Terminal.GetGrid()
+		g.SetSelection(startCol, startRow, endCol, endRow)
+		if text := selectionText(g, 0, copyExact); text != "" {
+			glfw.SetClipboardString(text)
+			showToast("Copied to clipboard")
+		}
+	}
+	// pickHint types a hint label character and acts on the picked target: a
+	// lowercase label clicks it (opening URLs, otherwise copying the word) and
+	// an uppercase label anchors a selection whose end is picked next
+	pickHint := func(char rune) {
+		target, ok := hintState.Type(char)
+		if !ok {
+			return
+		}
+		activeTab := tabManager.ActiveTab()
+		if activeTab == nil {
+			hintState.Cancel()
+			return
+		}
+		activeTab.SetActivePane(target.Pane)
+		if anchor := hintState.Anchor; anchor != nil {
+			hintState.Cancel()
+			if target.Row < anchor.Row || (target.Row == anchor.Row && target.Start < anchor.Start) {
+				copyPaneRange(target.Pane, target.Start, target.Row, anchor.End, anchor.Row)
+			} else {
+				copyPaneRange(target.Pane, anchor.Start, anchor.Row, target.End, target.Row)
+			}
+			return
+		}
+		if unicode.IsUpper(char) {
+			hintState.Begin(hintTargets([]*tab.Pane{target.Pane}))
+			hintState.Anchor = &target
+			showToast("Pick the end of the selection")
+			return
+		}
+		hintState.Cancel()
+		if target.URL {
+			if err := openLink(target.Text); err != nil {
+				showToast("Failed to open URL")
+			} else {
+				showToast(links.openedMessage(target.Text))
+			}
+			return
+		}
+		copyPaneRange(target.Pane, target.Start, target.Row, target.End, target.Row)
+	}
+	// useMark names the active pane's scroll position with the letter typed
+	// after Ctrl+Alt+M, or jumps to the mark typed after Ctrl+Alt+'
+	useMark := func(name rune) {
+		action := markAction
+		markAction = ""
+		activeTab := tabManager.ActiveTab()
+		if activeTab == nil {
+			return
+		}
+		g := activeTab.Terminal.GetGrid()
+		switch action {
+		case "set":
+			if !unicode.IsLetter(name) {
+				showToast("Marks are named by a letter")
+				return
+			}
+			g.SetBookmark(name)
+			showToast(fmt.Sprintf("Mark %c set", name))
+		case "jump":
+			if !g.JumpToBookmark(name) {
+				showToast(fmt.Sprintf("No mark %c in this pane", name))
+				return
+			}
+			showToast(fmt.Sprintf("At mark %c; Ctrl+Alt+' then ' jumps back", name))
+		}
+	}
+	signalSelectedProcess := func(sig syscall.Signal, name string) {
+		entry, ok := procPanel.SelectedEntry()
+		if !ok {
+			return
+		}
+		if err := procmon.Signal(entry.Process.PID, sig); err != nil {
+			procPanel.Status = fmt.Sprintf("%s %d failed: %v", name, entry.Process.PID, err)
+		} else {
+			procPanel.Status = fmt.Sprintf("Sent %s to %d (%s)", name, entry.Process.PID, entry.Process.Name)
+		}
+		procPanel.LastRefresh = time.Time{}
+	}
+
+	win.GLFW().SetKeyCallback(func(w *glfw.Window, key glfw.Key, scancode int, action glfw.Action, mods glfw.ModifierKey) {
+		if action == glfw.Release {
+			// Letting go of Ctrl picks the tab selected in the switcher
+			if switcher.active() && (key == glfw.KeyLeftControl || key == glfw.KeyRightControl) {
+				if t := switcher.close(); t != nil && tabManager.SetActiveTab(t) {
+					lineBuf.clear()
+				}
+			}
+			return
+		}
+
+		currentMods = mods
+		swallowChar = false
+
+		// While locked, keys only go towards unlocking
+		if locked {
+			passphrase := ""
+			if settingsMenu.Config != nil {
+				passphrase = settingsMenu.Config.Lock.PassphraseSHA256
+			}
+			if passphrase == "" {
+				if !isModifierKey(key) {
+					locked = false
+					swallowChar = true
+				}
+				return
+			}
+			switch key {
+			case glfw.KeyEnter, glfw.KeyKPEnter:
+				if passphraseMatches(lockInput, passphrase) {
+					locked = false
+					lockStatus = ""
+				} else {
+					lockStatus = "Wrong passphrase"
+				}
+				lockInput = ""
+			case glfw.KeyBackspace:
+				if runes := []rune(lockInput); len(runes) > 0 {
+					lockInput = string(runes[:len(runes)-1])
+				}
+			case glfw.KeyEscape:
+				lockInput = ""
+			}
+			return
+		}
+		if keybindings.TranslateKey(key, mods, false).Action == keybindings.ActionLockScreen {
+			locked = true
+			lockInput = ""
+			lockStatus = ""
+			selection.active = false
+			return
+		}
+
+		activeTab := tabManager.ActiveTab()
+		if activeTab == nil {
+			return
+		}
+
+		// The tab switcher takes every key while Ctrl is held
+		if switcher.active() {
+			swallowChar = true
+			switch {
+			case key == glfw.KeyTab && mods&glfw.ModShift != 0, key == glfw.KeyUp:
+				switcher.move(-1)
+			case key == glfw.KeyTab, key == glfw.KeyDown:
+				switcher.move(1)
+			case key == glfw.KeyEnter, key == glfw.KeyKPEnter:
+				if t := switcher.close(); t != nil && tabManager.SetActiveTab(t) {
+					lineBuf.clear()
+				}
+			case key == glfw.KeyEscape:
+				switcher.close()
+			}
+			return
+		}
+
+		// Hint labels are typed through the char callback; only editing keys land here
+		if hintState.Active {
+			switch {
+			case key == glfw.KeyEscape:
+				hintState.Cancel()
+			case key == glfw.KeyBackspace:
+				hintState.Backspace()
+			case keybindings.TranslateKey(key, mods, false).Action == keybindings.ActionHintMode:
+				hintState.Cancel()
+			}
+			return
+		}
+
+		// A pending mark is named through the char callback; Escape cancels it
+		if markAction != "" {
+			if key == glfw.KeyEscape {
+				markAction = ""
+				showToast("Mark cancelled")
+			}
+			return
+		}
+
+		// The character inspector moves with the arrow keys; any other key
+		// closes it and goes on to do what it normally does
+		if charInfoPane != nil && activeTab.PaneIndex(charInfoPane) < 0 {
+			charInfoPane = nil
+		}
+		if charInfoPane != nil && !isModifierKey(key) {
+			g := charInfoPane.Terminal.GetGrid()
+			switch {
+			case key == glfw.KeyLeft:
+				charInfoCol = g.CharStart(max(charInfoCol-1, 0), charInfoRow)
+				return
+			case key == glfw.KeyRight:
+				next := charInfoCol + 1
+				if g.DisplayCell(charInfoCol, charInfoRow).Width == grid.CellWidthWide {
+					next++
+				}
+				if next < g.Cols {
+					charInfoCol = next
+				}
+				return
+			case key == glfw.KeyUp:
+				charInfoRow = max(charInfoRow-1, 0)
+				charInfoCol = g.CharStart(charInfoCol, charInfoRow)
+				return
+			case key == glfw.KeyDown:
+				charInfoRow = min(charInfoRow+1, g.Rows-1)
+				charInfoCol = g.CharStart(charInfoCol, charInfoRow)
+				return
+			case key == glfw.KeyC && mods&glfw.ModControl != 0:
+				cell := g.DisplayCell(charInfoCol, charInfoRow)
+				glfw.SetClipboardString(strings.Join(charinfo.Describe(cell.Char, int(cell.Width)), "\n"))
+				showToast("Copied character details")
+				return
+			case key == glfw.KeyEscape:
+				charInfoPane = nil
+				return
+			}
+			charInfoPane = nil
+		}
+
+		// Handle settings menu input when open
+		if settingsMenu.IsOpen() {
+			appCursor := activeTab.Terminal.AppCursorKeys()
+			result := keybindings.TranslateKey(key, mods, appCursor)
+			if result.Action == keybindings.ActionPaste && settingsMenu.InputMode() {
+				clip := glfw.GetClipboardString()
+				if clip != "" {
+					settingsMenu.HandlePaste(clip)
+					showToast("Pasted from clipboard")
+				}
+				return
+			}
+			if settingsMenu.InputMode() {
+				// Edit the input at its caret; Ctrl or Alt moves and deletes by word
+				ctrl := mods&glfw.ModControl != 0
+				shift := mods&glfw.ModShift != 0
+				word := mods&(glfw.ModControl|glfw.ModAlt) != 0
+				switch {
+				case key == glfw.KeyLeft:
+					settingsMenu.MoveInputCursor(-1, word, shift)
+					return
+				case key == glfw.KeyRight:
+					settingsMenu.MoveInputCursor(1, word, shift)
+					return
+				case key == glfw.KeyHome:
+					settingsMenu.MoveInputLineEdge(false, shift)
+					return
+				case key == glfw.KeyEnd:
+					settingsMenu.MoveInputLineEdge(true, shift)
+					return
+				case key == glfw.KeyUp && settingsMenu.MoveInputLine(-1, shift):
+					return
+				case key == glfw.KeyDown && settingsMenu.MoveInputLine(1, shift):
+					return
+				case key == glfw.KeyBackspace:
+					settingsMenu.DeleteInput(false, word)
+					return
+				case key == glfw.KeyDelete:
+					settingsMenu.DeleteInput(true, word)
+					return
+				case ctrl && key == glfw.KeyW:
+					settingsMenu.DeleteInput(false, true)
+					return
+				case ctrl && key == glfw.KeyA:
+					settingsMenu.SelectAllInput()
+					return
+				case ctrl && (key == glfw.KeyC || key == glfw.KeyX):
+					if selected := settingsMenu.SelectedInput(); selected != "" {
+						glfw.SetClipboardString(selected)
+						if key == glfw.KeyX {
+							settingsMenu.DeleteInput(false, false)
+						}
+						showToast("Copied to clipboard")
+					}
+					return
+				}
+			}
+			// Undo and redo changes to the settings
+			if !settingsMenu.InputMode() && mods&glfw.ModControl != 0 {
+				redo := key == glfw.KeyY || (key == glfw.KeyZ && mods&glfw.ModShift != 0)
+				if redo {
+					settingsMenu.Redo()
+					return
+				}
+				if key == glfw.KeyZ {
+					settingsMenu.Undo()
+					return
+				}
+			}
+			switch key {
+			case glfw.KeyUp:
+				settingsMenu.MoveUp()
+				return
+			case glfw.KeyDown:
+				settingsMenu.MoveDown()
+				return
+			case glfw.KeyLeft, glfw.KeyRight:
+				// Move a slider, ten steps at a time with Shift, or cycle a dropdown
+				steps := 1
+				if mods&glfw.ModShift != 0 {
+					steps = 10
+				}
+				if key == glfw.KeyLeft {
+					steps = -steps
+				}
+				settingsMenu.Adjust(steps)
+				return
+			case glfw.KeyEnter, glfw.KeyKPEnter:
+				if action == glfw.Repeat {
+					logging.Debugf(logging.Menu, "key repeat ignored key=%v input=%v title=%s", key, settingsMenu.InputMode(), settingsMenu.GetTitle())
+					return
+				}
+				if mods&glfw.ModControl != 0 && settingsMenu.RunSelected() {
+					return
+				}
+				if settingsMenu.InputMode() && settingsMenu.InputIsMultiline() && mods&glfw.ModControl == 0 {
+					settingsMenu.HandleChar('\n')
+					return
+				}
+				logging.Debugf(logging.Menu, "key enter key=%v input=%v title=%s", key, settingsMenu.InputMode(), settingsMenu.GetTitle())
+				if settingsMenu.InputMode() {
+					settingsMenu.HandleEnter()
+				} else {
+					settingsMenu.Select()
+				}
+				return
+			case glfw.KeyEscape:
+				logging.Debugf(logging.Menu, "key escape input=%v title=%s", settingsMenu.InputMode(), settingsMenu.GetTitle())
+				settingsMenu.HandleEscape()
+				return
+			case glfw.KeyBackspace:
+				settingsMenu.HandleBackspace()
+				return
+			case glfw.KeyDelete:
+				settingsMenu.HandleDelete()
+				return
+			}
+			return
+		}
+
+		// Step through the replayed output of a rewound pane
+		if rewindPlayer != nil {
+			switch key {
+			case glfw.KeyLeft, glfw.KeyUp:
+				rewindPlayer.Step(-1)
+			case glfw.KeyRight, glfw.KeyDown:
+				rewindPlayer.Step(1)
+			case glfw.KeyPageUp:
+				rewindPlayer.Step(-rewindSkip)
+			case glfw.KeyPageDown:
+				rewindPlayer.Step(rewindSkip)
+			case glfw.KeyHome:
+				rewindPlayer.Seek(0)
+			case glfw.KeyEnd:
+				rewindPlayer.Seek(rewindPlayer.Len() - 1)
+			case glfw.KeyC:
+				glfw.SetClipboardString(rewindPlayer.Grid().VisibleText())
+				showToast("Copied replayed screen")
+			case glfw.KeyEscape, glfw.KeyQ, glfw.KeyEnter, glfw.KeyKPEnter:
+				stopRewind()
+				return
+			}
+			showRewind()
+			return
+		}
+
+		// Handle escape sequence inspector input
+		if inspectPanel.Open {
+			appCursor := activeTab.Terminal.AppCursorKeys()
+			result := keybindings.TranslateKey(key, mods, appCursor)
+			if result.Action == keybindings.ActionNextPane || result.Action == keybindings.ActionPrevPane {
+				inspectPanel.Focused = !inspectPanel.Focused
+				if inspectPanel.Focused {
+					showToast("Inspector focused")
+				} else {
+					showToast("Terminal focused")
+				}
+				return
+			}
+			if !inspectPanel.Focused {
+				goto handleTerminalInput
+			}
+
+			width, height := win.GetFramebufferSize()
+			cellW, cellH := renderer.UICellDimensions()
+			layout := inspectPanel.Layout(width, height, cellW, cellH)
+			if mods&glfw.ModControl != 0 {
+				switch key {
+				case glfw.KeyP:
+					inspectPanel.TogglePause()
+				case glfw.KeyC:
+					if entry, ok := inspectPanel.SelectedEntry(); ok {
+						glfw.SetClipboardString(entry.String())
+						inspectPanel.Status = "Copied event"
+					}
+				case glfw.KeyA:
+					glfw.SetClipboardString(inspectPanel.Text())
+					inspectPanel.Status = fmt.Sprintf("Copied %d events", len(inspectPanel.Entries))
+				case glfw.KeyL:
+					if inspectRecorder != nil {
+						inspectRecorder.Clear()
+					}
+					inspectPanel.Paused = false
+					inspectPanel.Follow = true
+					inspectPanel.SetEvents(nil, -1, layout.VisibleLines)
+					inspectPanel.Status = "Cleared"
+				}
+				return
+			}
+			switch key {
+			case glfw.KeyUp:
+				inspectPanel.MoveSelection(-1, layout.VisibleLines)
+			case glfw.KeyDown:
+				inspectPanel.MoveSelection(1, layout.VisibleLines)
+			case glfw.KeyPageUp:
+				inspectPanel.MoveSelection(-layout.VisibleLines, layout.VisibleLines)
+			case glfw.KeyPageDown:
+				inspectPanel.MoveSelection(layout.VisibleLines, layout.VisibleLines)
+			case glfw.KeyHome:
+				inspectPanel.MoveSelection(-len(inspectPanel.Entries), layout.VisibleLines)
+			case glfw.KeyEnd:
+				inspectPanel.MoveSelection(len(inspectPanel.Entries), layout.VisibleLines)
+			case glfw.KeyBackspace:
+				inspectPanel.Backspace(layout.VisibleLines)
+			case glfw.KeyEscape:
+				inspectPanel.Open = false
+			}
+			return
+		}
+
+		// Handle pane cleanup dialog input
+		if cleanupPanel.Open {
+			appCursor := activeTab.Terminal.AppCursorKeys()
+			result := keybindings.TranslateKey(key, mods, appCursor)
+			if result.Action == keybindings.ActionNextPane || result.Action == keybindings.ActionPrevPane {
+				cleanupPanel.Focused = !cleanupPanel.Focused
+				if cleanupPanel.Focused {
+					showToast("Pane cleanup focused")
+				} else {
+					showToast("Terminal focused")
+				}
+				return
+			}
+			if !cleanupPanel.Focused {
+				goto handleTerminalInput
+			}
+
+			width, height := win.GetFramebufferSize()
+			cellW, cellH := renderer.UICellDimensions()
+			layout := cleanupPanel.Layout(width, height, cellW, cellH)
+			switch key {
+			case glfw.KeyUp:
+				cleanupPanel.MoveSelection(-1, layout.VisibleLines)
+			case glfw.KeyDown:
+				cleanupPanel.MoveSelection(1, layout.VisibleLines)
+			case glfw.KeyPageUp:
+				cleanupPanel.MoveSelection(-layout.VisibleLines, layout.VisibleLines)
+			case glfw.KeyPageDown:
+				cleanupPanel.MoveSelection(layout.VisibleLines, layout.VisibleLines)
+			case glfw.KeyEnter, glfw.KeyKPEnter, glfw.KeyDelete:
+				if entry, ok := cleanupPanel.SelectedEntry(); ok {
+					if closeCleanupEntry(entry) {
+						cleanupPanel.Status = fmt.Sprintf("Closed pane %d.%d", entry.TabIndex+1, entry.PaneIndex+1)
+					} else {
+						cleanupPanel.Status = "The last tab cannot be closed"
+					}
+					scanCleanup(time.Now())
+				}
+			case glfw.KeyA:
+				count := 0
+				for _, entry := range cleanupPanel.Entries {
+					if closeCleanupEntry(entry) {
+						count++
+					}
+				}
+				cleanupPanel.Status = fmt.Sprintf("Closed %d panes", count)
+				scanCleanup(time.Now())
+			case glfw.KeyEscape:
+				cleanupPanel.Open = false
+			}
+			return
+		}
+
+		// Handle notification center focus and input
+		if notifyPanel.Open {
+			appCursor := activeTab.Terminal.AppCursorKeys()
+			result := keybindings.TranslateKey(key, mods, appCursor)
+			if result.Action == keybindings.ActionToggleNotifications {
+				notifyPanel.Open = false
+				return
+			}
+			if result.Action == keybindings.ActionNextPane || result.Action == keybindings.ActionPrevPane {
+				notifyPanel.Focused = !notifyPanel.Focused
+				if notifyPanel.Focused {
+					showToast("Notifications focused")
+				} else {
+					showToast("Terminal focused")
 				}
-				if settingsMenu.InputMode() {
-					settingsMenu.HandleEnter()
+				return
+			}
+			if !notifyPanel.Focused {
+				goto handleTerminalInput
+			}
+
+			width, height := win.GetFramebufferSize()
+			cellW, cellH := renderer.UICellDimensions()
+			layout := notifyPanel.Layout(width, height, cellW, cellH)
+			switch key {
+			case glfw.KeyUp:
+				notifyPanel.MoveSelection(-1, layout.VisibleLines)
+			case glfw.KeyDown:
+				notifyPanel.MoveSelection(1, layout.VisibleLines)
+			case glfw.KeyPageUp:
+				notifyPanel.MoveSelection(-layout.VisibleLines, layout.VisibleLines)
+			case glfw.KeyPageDown:
+				notifyPanel.MoveSelection(layout.VisibleLines, layout.VisibleLines)
+			case glfw.KeyEnter, glfw.KeyKPEnter:
+				if entry, ok := notifyPanel.SelectedEntry(); ok {
+					jumpToNotification(entry)
+				}
+			case glfw.KeyDelete:
+				notifyPanel.Clear()
+			case glfw.KeyEscape:
+				notifyPanel.Open = false
+			}
+			return
+		}
+
+		// Handle process monitor panel focus and input
+		if procPanel.Open {
+			appCursor := activeTab.Terminal.AppCursorKeys()
+			result := keybindings.TranslateKey(key, mods, appCursor)
+			if result.Action == keybindings.ActionToggleProcessPanel {
+				procPanel.Open = false
+				return
+			}
+			if result.Action == keybindings.ActionNextPane || result.Action == keybindings.ActionPrevPane {
+				procPanel.Focused = !procPanel.Focused
+				if procPanel.Focused {
+					showToast("Process panel focused")
 				} else {
-					settingsMenu.Select()
+					showToast("Terminal focused")
 				}
 				return
+			}
+			if !procPanel.Focused {
+				goto handleTerminalInput
+			}
+
+			width, height := win.GetFramebufferSize()
+			cellW, cellH := renderer.UICellDimensions()
+			layout := procPanel.Layout(width, height, cellW, cellH)
+			switch key {
+			case glfw.KeyUp:
+				procPanel.MoveSelection(-1, layout.VisibleLines)
+			case glfw.KeyDown:
+				procPanel.MoveSelection(1, layout.VisibleLines)
+			case glfw.KeyPageUp:
+				procPanel.MoveSelection(-layout.VisibleLines, layout.VisibleLines)
+			case glfw.KeyPageDown:
+				procPanel.MoveSelection(layout.VisibleLines, layout.VisibleLines)
+			case glfw.KeyI:
+				signalSelectedProcess(syscall.SIGINT, "SIGINT")
+			case glfw.KeyK:
+				if mods&glfw.ModShift != 0 {
+					signalSelectedProcess(syscall.SIGKILL, "SIGKILL")
+				}
 			case glfw.KeyEscape:
-				if debugMenu {
-					log.Printf("menu: key escape input=%v title=%s", settingsMenu.InputMode(), settingsMenu.GetTitle())
+				procPanel.Open = false
+			}
+			return
+		}
+
+		// Handle update panel input
+		if updatePanel.Open {
+			appCursor := activeTab.Terminal.AppCursorKeys()
+			result := keybindings.TranslateKey(key, mods, appCursor)
+			if result.Action == keybindings.ActionNextPane || result.Action == keybindings.ActionPrevPane {
+				updatePanel.Focused = !updatePanel.Focused
+				if updatePanel.Focused {
+					showToast("Update panel focused")
+				} else {
+					showToast("Terminal focused")
+				}
+				return
+			}
+			if !updatePanel.Focused {
+				goto handleTerminalInput
+			}
+
+			width, height := win.GetFramebufferSize()
+			cellW, cellH := renderer.UICellDimensions()
+			layout := updatePanel.Layout(width, height, cellW, cellH)
+			switch key {
+			case glfw.KeyUp:
+				updatePanel.ScrollBy(-1, layout.VisibleLines)
+			case glfw.KeyDown:
+				updatePanel.ScrollBy(1, layout.VisibleLines)
+			case glfw.KeyPageUp:
+				updatePanel.ScrollBy(-layout.VisibleLines, layout.VisibleLines)
+			case glfw.KeyPageDown:
+				updatePanel.ScrollBy(layout.VisibleLines, layout.VisibleLines)
+			case glfw.KeyD:
+				downloadUpdate()
+			case glfw.KeyO:
+				if updatePanel.Release.URL != "" {
+					if err := openLink(updatePanel.Release.URL); err != nil {
+						showToast("Failed to open URL")
+					}
+				}
+			case glfw.KeyEscape:
+				updatePanel.Open = false
+			}
+			return
+		}
+
+		// Handle dev-server URL panel focus and input
+		if devPanel.Open {
+			appCursor := activeTab.Terminal.AppCursorKeys()
+			result := keybindings.TranslateKey(key, mods, appCursor)
+			if result.Action == keybindings.ActionToggleDevServerPanel {
+				devPanel.Open = false
+				return
+			}
+			if result.Action == keybindings.ActionNextPane || result.Action == keybindings.ActionPrevPane {
+				devPanel.Focused = !devPanel.Focused
+				if devPanel.Focused {
+					showToast("Dev server panel focused")
+				} else {
+					showToast("Terminal focused")
+				}
+				return
+			}
+			if !devPanel.Focused {
+				goto handleTerminalInput
+			}
+
+			width, height := win.GetFramebufferSize()
+			cellW, cellH := renderer.UICellDimensions()
+			layout := devPanel.Layout(width, height, cellW, cellH)
+			switch key {
+			case glfw.KeyUp:
+				devPanel.MoveSelection(-1, layout.VisibleLines)
+			case glfw.KeyDown:
+				devPanel.MoveSelection(1, layout.VisibleLines)
+			case glfw.KeyEnter, glfw.KeyKPEnter:
+				if entry, ok := devPanel.SelectedEntry(); ok {
+					if err := openLink(entry.URL); err != nil {
+						showToast("Failed to open URL")
+					} else {
+						showToast("Opening " + entry.URL)
+					}
+				}
+			case glfw.KeyEscape:
+				devPanel.Open = false
+			}
+			return
+		}
+
+		// Handle the register viewer; register names arrive as characters
+		if registerPanel.Open {
+			appCursor := activeTab.Terminal.AppCursorKeys()
+			result := keybindings.TranslateKey(key, mods, appCursor)
+			if result.Action == keybindings.ActionToggleRegisters {
+				registerPanel.Open = false
+				return
+			}
+			if result.Action == keybindings.ActionNextPane || result.Action == keybindings.ActionPrevPane {
+				registerPanel.Focused = !registerPanel.Focused
+				if registerPanel.Focused {
+					showToast("Registers focused")
+				} else {
+					showToast("Terminal focused")
+				}
+				return
+			}
+			if !registerPanel.Focused {
+				goto handleTerminalInput
+			}
+
+			width, height := win.GetFramebufferSize()
+			cellW, cellH := renderer.UICellDimensions()
+			layout := registerPanel.Layout(width, height, cellW, cellH)
+			switch key {
+			case glfw.KeyUp:
+				registerPanel.MoveSelection(-1, layout.VisibleLines)
+			case glfw.KeyDown:
+				registerPanel.MoveSelection(1, layout.VisibleLines)
+			case glfw.KeyPageUp:
+				registerPanel.MoveSelection(-layout.VisibleLines, layout.VisibleLines)
+			case glfw.KeyPageDown:
+				registerPanel.MoveSelection(layout.VisibleLines, layout.VisibleLines)
+			case glfw.KeyTab:
+				registerPanel.Yank = !registerPanel.Yank
+			case glfw.KeyDelete:
+				if name, ok := registerPanel.SelectedName(); ok {
+					registerPanel.Registers.Clear(name)
+					registerPanel.MoveSelection(0, layout.VisibleLines)
+				}
+			case glfw.KeyEnter, glfw.KeyKPEnter:
+				if name, ok := registerPanel.SelectedName(); ok {
+					registerPanel.Yank = false
+					useRegister(name)
+				}
+			case glfw.KeyEscape:
+				registerPanel.Open = false
+			}
+			return
+		}
+
+		// Handle the calculator; the expression arrives as characters
+		if calcPanel.Open {
+			appCursor := activeTab.Terminal.AppCursorKeys()
+			result := keybindings.TranslateKey(key, mods, appCursor)
+			if result.Action == keybindings.ActionToggleCalc {
+				calcPanel.Open = false
+				return
+			}
+			if result.Action == keybindings.ActionNextPane || result.Action == keybindings.ActionPrevPane {
+				calcPanel.Focused = !calcPanel.Focused
+				if calcPanel.Focused {
+					showToast("Calculator focused")
+				} else {
+					showToast("Terminal focused")
+				}
+				return
+			}
+			if !calcPanel.Focused {
+				goto handleTerminalInput
+			}
+
+			if mods&glfw.ModControl != 0 && key == glfw.KeyC {
+				if value, ok := calcPanel.Commit(); ok {
+					glfw.SetClipboardString(value)
+					showToast("Copied " + value)
 				}
-				settingsMenu.HandleEscape()
 				return
+			}
+			switch key {
+			case glfw.KeyUp:
+				calcPanel.RecallHistory(1)
+			case glfw.KeyDown:
+				calcPanel.RecallHistory(-1)
 			case glfw.KeyBackspace:
-				if settingsMenu.InputMode() {
-					settingsMenu.HandleBackspace()
+				calcPanel.Backspace()
+			case glfw.KeyEnter, glfw.KeyKPEnter:
+				if value, ok := calcPanel.Commit(); ok {
+					activeTab.Write(keybindings.EncodeText(value, activeTab.Terminal.Latin1()))
+					activeTab.Terminal.GetGrid().ResetScrollOffset()
+					calcPanel.Open = false
+				}
+			case glfw.KeyEscape:
+				calcPanel.Open = false
+			}
+			return
+		}
+
+		// Handle the palette inspector
+		if palettePanel.Open {
+			appCursor := activeTab.Terminal.AppCursorKeys()
+			result := keybindings.TranslateKey(key, mods, appCursor)
+			if result.Action == keybindings.ActionTogglePalette {
+				palettePanel.Open = false
+				return
+			}
+			if result.Action == keybindings.ActionNextPane || result.Action == keybindings.ActionPrevPane {
+				palettePanel.Focused = !palettePanel.Focused
+				if palettePanel.Focused {
+					showToast("Palette focused")
+				} else {
+					showToast("Terminal focused")
+				}
+				return
+			}
+			if !palettePanel.Focused {
+				goto handleTerminalInput
+			}
+
+			switch key {
+			case glfw.KeyUp:
+				palettePanel.Move(0, -1)
+			case glfw.KeyDown:
+				palettePanel.Move(0, 1)
+			case glfw.KeyLeft:
+				palettePanel.Move(-1, 0)
+			case glfw.KeyRight:
+				palettePanel.Move(1, 0)
+			case glfw.KeyTab:
+				palettePanel.Background = !palettePanel.Background
+			case glfw.KeyEnter, glfw.KeyKPEnter:
+				copySwatch(false)
+			case glfw.KeyH:
+				copySwatch(true)
+			case glfw.KeyEscape:
+				palettePanel.Open = false
+			}
+			return
+		}
+
+		// Handle directory jump list input
+		if dirPanel.Open {
+			appCursor := activeTab.Terminal.AppCursorKeys()
+			result := keybindings.TranslateKey(key, mods, appCursor)
+			if result.Action == keybindings.ActionToggleDirJump {
+				dirPanel.Open = false
+				return
+			}
+			if result.Action == keybindings.ActionNextPane || result.Action == keybindings.ActionPrevPane {
+				dirPanel.Focused = !dirPanel.Focused
+				if dirPanel.Focused {
+					showToast("Directory jump focused")
+				} else {
+					showToast("Terminal focused")
 				}
 				return
+			}
+			if !dirPanel.Focused {
+				goto handleTerminalInput
+			}
+
+			width, height := win.GetFramebufferSize()
+			cellW, cellH := renderer.UICellDimensions()
+			layout := dirPanel.Layout(width, height, cellW, cellH)
+			switch key {
+			case glfw.KeyUp:
+				dirPanel.MoveSelection(-1, layout.VisibleLines)
+			case glfw.KeyDown:
+				dirPanel.MoveSelection(1, layout.VisibleLines)
+			case glfw.KeyPageUp:
+				dirPanel.MoveSelection(-layout.VisibleLines, layout.VisibleLines)
+			case glfw.KeyPageDown:
+				dirPanel.MoveSelection(layout.VisibleLines, layout.VisibleLines)
+			case glfw.KeyBackspace:
+				dirPanel.Backspace()
+				refreshDirJump()
 			case glfw.KeyDelete:
-				settingsMenu.HandleDelete()
+				if entry, ok := dirPanel.SelectedEntry(); ok {
+					dirStore.Remove(entry.Path)
+					refreshDirJump()
+				}
+			case glfw.KeyEnter, glfw.KeyKPEnter:
+				if entry, ok := dirPanel.SelectedEntry(); ok {
+					activeTab.Write([]byte("cd " + shellQuote(entry.Path) + "\r"))
+					activeTab.Terminal.GetGrid().ResetScrollOffset()
+					dirPanel.Open = false
+				}
+			case glfw.KeyEscape:
+				dirPanel.Open = false
+			}
+			return
+		}
+
+		// Handle snippet picker and placeholder prompts
+		if snippetPanel.Open {
+			appCursor := activeTab.Terminal.AppCursorKeys()
+			result := keybindings.TranslateKey(key, mods, appCursor)
+			if result.Action == keybindings.ActionToggleSnippets {
+				snippetPanel.Open = false
+				return
+			}
+			if result.Action == keybindings.ActionNextPane || result.Action == keybindings.ActionPrevPane {
+				snippetPanel.Focused = !snippetPanel.Focused
+				if snippetPanel.Focused {
+					showToast("Snippets focused")
+				} else {
+					showToast("Terminal focused")
+				}
+				return
+			}
+			if !snippetPanel.Focused {
+				goto handleTerminalInput
+			}
+
+			if snippetPanel.Mode == snippets.ModePrompt {
+				switch key {
+				case glfw.KeyBackspace:
+					snippetPanel.Backspace()
+				case glfw.KeyTab:
+					if mods&glfw.ModShift != 0 {
+						snippetPanel.PrevField()
+					} else {
+						snippetPanel.NextField()
+					}
+				case glfw.KeyUp:
+					snippetPanel.PrevField()
+				case glfw.KeyDown:
+					snippetPanel.NextField()
+				case glfw.KeyEnter, glfw.KeyKPEnter:
+					if snippetPanel.NextField() {
+						insertSnippet(activeTab, snippetPanel.Result())
+					}
+				case glfw.KeyEscape:
+					snippetPanel.CancelPrompt()
+				}
 				return
 			}
+
+			width, height := win.GetFramebufferSize()
+			cellW, cellH := renderer.UICellDimensions()
+			layout := snippetPanel.Layout(width, height, cellW, cellH)
+			switch key {
+			case glfw.KeyUp:
+				snippetPanel.MoveSelection(-1, layout.VisibleLines)
+			case glfw.KeyDown:
+				snippetPanel.MoveSelection(1, layout.VisibleLines)
+			case glfw.KeyPageUp:
+				snippetPanel.MoveSelection(-layout.VisibleLines, layout.VisibleLines)
+			case glfw.KeyPageDown:
+				snippetPanel.MoveSelection(layout.VisibleLines, layout.VisibleLines)
+			case glfw.KeyBackspace:
+				snippetPanel.Backspace()
+				refreshSnippets()
+			case glfw.KeyEnter, glfw.KeyKPEnter:
+				if item, ok := snippetPanel.SelectedItem(); ok {
+					if snippetPanel.Begin(item.Snippet) {
+						insertSnippet(activeTab, item.Snippet.Body)
+					}
+				}
+			case glfw.KeyEscape:
+				snippetPanel.Open = false
+			}
 			return
 		}
 
@@ -450,13 +3549,11 @@ func main() {
 				return
 			}
 			if result.Action == keybindings.ActionToggleAIPanel {
-				aiPanel.Open = false
-				aiPanel.Reset()
+				aiPanel.Close()
 				return
 			}
 			if result.Action == keybindings.ActionToggleSearchPanel {
-				aiPanel.Open = false
-				aiPanel.Reset()
+				aiPanel.Close()
 				if !searchPanel.Enabled {
 					showToast("Enable web search in settings")
 					return
@@ -466,15 +3563,65 @@ func main() {
 					if settingsMenu.Config != nil {
 						searchPanel.ProxyEnabled = settingsMenu.Config.WebSearch.UseReaderProxy
 					}
-					searchPanel.Focused = true
-					showHelp = false
-					renderer.ResetHelpScroll()
+					searchPanel.Focused = true
+					showHelp = false
+					renderer.ResetHelpScroll()
+				}
+				return
+			}
+			if !aiPanel.Focused {
+				// Let terminal handle input while panel stays visible.
+				goto handleTerminalInput
+			}
+
+			// Ctrl+R: resize the panel with the arrow keys instead of the panes
+			if result.Action == keybindings.ActionToggleResizeMode {
+				resizeMode = !resizeMode
+				if resizeMode {
+					aiPanel.Status = "Resizing panel: arrows to resize, Esc when done"
+				} else {
+					aiPanel.Status = ""
+				}
+				return
+			}
+			if resizeMode {
+				delta := float32(0)
+				switch key {
+				case glfw.KeyLeft:
+					delta = 5
+					if aiPanel.Dock == aipanel.DockLeft {
+						delta = -5
+					}
+				case glfw.KeyRight:
+					delta = -5
+					if aiPanel.Dock == aipanel.DockLeft {
+						delta = 5
+					}
+				case glfw.KeyUp:
+					if aiPanel.Dock == aipanel.DockBottom {
+						delta = 5
+					}
+				case glfw.KeyDown:
+					if aiPanel.Dock == aipanel.DockBottom {
+						delta = -5
+					}
+				case glfw.KeyEscape:
+					resizeMode = false
+					aiPanel.Status = ""
+					return
+				}
+				if delta != 0 {
+					aiPanel.Status = fmt.Sprintf("Panel size: %.0f%%", aiPanel.Resize(delta))
+					saveAIPanelGeometry()
 				}
 				return
 			}
-			if !aiPanel.Focused {
-				// Let terminal handle input while panel stays visible.
-				goto handleTerminalInput
+
+			// Ctrl+D: move the panel to the next dock
+			if mods&glfw.ModControl != 0 && key == glfw.KeyD {
+				aiPanel.Status = "Docked: " + aiPanel.CycleDock()
+				saveAIPanelGeometry()
+				return
 			}
 
 			switch result.Action {
@@ -501,13 +3648,13 @@ func main() {
 			}
 
 			width, height := win.GetFramebufferSize()
-			cellW, cellH := renderer.CellDimensions()
+			cellW, cellH := renderer.UICellDimensions()
 			layout := aiPanel.Layout(width, height, cellW, cellH)
 			maxChars := int(layout.ContentWidth/cellW) - 2
 			if maxChars < 10 {
 				maxChars = 10
 			}
-			wrapped := aipanel.BuildWrappedLinesWithThinking(aiPanel.Messages, maxChars, aiPanel.ShowThinking, aiPanel.ThinkingExpanded)
+			wrapped := aiPanel.Wrapped(maxChars)
 			totalLines := len(wrapped)
 			visibleLines := layout.VisibleLines
 			maxScroll := totalLines - visibleLines
@@ -527,6 +3674,32 @@ func main() {
 				return
 			}
 
+			// Ctrl+L: start a new conversation; closing the panel keeps it
+			if mods&glfw.ModControl != 0 && key == glfw.KeyL {
+				aiPanel.Clear()
+				aiPanel.Status = "Conversation cleared"
+				return
+			}
+
+			// A command the model asked to run waits for Y or N
+			if aiPanel.PendingCommand != "" && !aiPanel.Loading {
+				switch key {
+				case glfw.KeyY:
+					resolveAICommand(true)
+				case glfw.KeyN:
+					resolveAICommand(false)
+				case glfw.KeyEscape:
+					aiPanel.Close()
+				}
+				return
+			}
+
+			// Ctrl+P: switch persona; the conversation keeps it until it is closed
+			if mods&glfw.ModControl != 0 && key == glfw.KeyP {
+				aiPanel.Status = "Persona: " + aiPanel.CyclePersona(1).Name
+				return
+			}
+
 			// Ctrl+T: toggle thinking expansion
 			if mods&glfw.ModControl != 0 && key == glfw.KeyT {
 				if aipanel.HasThinkingContent(aiPanel.Messages) {
@@ -546,8 +3719,7 @@ func main() {
 
 			switch key {
 			case glfw.KeyEscape:
-				aiPanel.Open = false
-				aiPanel.Reset()
+				aiPanel.Close()
 				return
 			case glfw.KeyEnter, glfw.KeyKPEnter:
 				// Regular Enter or Shift+Enter: add newline
@@ -625,7 +3797,7 @@ func main() {
 				return
 			}
 			if result.Action == keybindings.ActionToggleAIPanel {
-				searchPanel.Open = false
+				searchPanel.Close()
 				if !aiPanel.Enabled {
 					showToast("Enable Ollama chat in settings")
 					return
@@ -635,8 +3807,6 @@ func main() {
 					aiPanel.Focused = true
 					showHelp = false
 					renderer.ResetHelpScroll()
-				} else {
-					aiPanel.Reset()
 				}
 				return
 			}
@@ -648,11 +3818,10 @@ func main() {
 			switch result.Action {
 			case keybindings.ActionCopy:
 				g := activeTab.Terminal.GetGrid()
-				text := g.SelectedText()
-				if text == "" {
-					text = g.VisibleText()
+				if copySelection(g, mods, clipboard.TargetClipboard) {
+					return
 				}
-				if text != "" {
+				if text := g.VisibleText(); text != "" {
 					glfw.SetClipboardString(text)
 					showToast("Copied to clipboard")
 				}
@@ -662,7 +3831,7 @@ func main() {
 				if clip != "" {
 					clip = strings.ReplaceAll(clip, "\r\n", "\n")
 					clip = strings.ReplaceAll(clip, "\n", "\r")
-					activeTab.Write([]byte(clip))
+					activeTab.Write(keybindings.EncodeText(clip, activeTab.Terminal.Latin1()))
 					activeTab.Terminal.GetGrid().ResetScrollOffset()
 					showToast("Pasted from clipboard")
 				}
@@ -670,7 +3839,7 @@ func main() {
 			}
 
 			width, height := win.GetFramebufferSize()
-			cellW, cellH := renderer.CellDimensions()
+			cellW, cellH := renderer.UICellDimensions()
 			layout := searchPanel.Layout(width, height, cellW, cellH)
 			previewVisible := layout.VisibleLines - 1
 			if previewVisible < 1 {
@@ -704,6 +3873,30 @@ func main() {
 				return
 			}
 
+			// Ctrl+S: Switch between web and scrollback search
+			if mods&glfw.ModControl != 0 && key == glfw.KeyS {
+				if !searchPanel.ToggleSemantic() {
+					searchPanel.Status = "Enable web search and semantic search to switch"
+				} else if searchPanel.Semantic {
+					searchPanel.Status = fmt.Sprintf("Scrollback search (%d chunks indexed)", semanticIndex.Len())
+				} else {
+					searchPanel.Status = "Web search"
+				}
+				return
+			}
+
+			// Ctrl+R: Open the preview in a reader pane
+			if mods&glfw.ModControl != 0 && key == glfw.KeyR && searchPanel.Mode == searchpanel.ModePreview {
+				message, err := openReaderPane()
+				if err != nil {
+					searchPanel.Status = "Reader failed: " + err.Error()
+					return
+				}
+				searchPanel.Close()
+				showToast(message)
+				return
+			}
+
 			// Ctrl+O: Open selected URL in browser
 			if mods&glfw.ModControl != 0 && key == glfw.KeyO {
 				var urlToOpen string
@@ -713,7 +3906,7 @@ func main() {
 					urlToOpen = searchPanel.GetSelectedURL()
 				}
 				if urlToOpen != "" {
-					if err := openURL(urlToOpen); err != nil {
+					if err := openLink(urlToOpen); err != nil {
 						searchPanel.Status = "Failed to open browser"
 					} else {
 						searchPanel.Status = "Opening in browser..."
@@ -725,16 +3918,14 @@ func main() {
 			switch key {
 			case glfw.KeyEscape:
 				if searchPanel.Mode == searchpanel.ModePreview {
-					searchPanel.Mode = searchpanel.ModeResults
-					searchPanel.PreviewScroll = 0
+					searchPanel.CloseResult()
 				} else {
-					searchPanel.Open = false
+					searchPanel.Close()
 				}
 				return
 			case glfw.KeyEnter, glfw.KeyKPEnter:
 				if searchPanel.Mode == searchpanel.ModePreview {
-					searchPanel.Mode = searchpanel.ModeResults
-					searchPanel.PreviewScroll = 0
+					searchPanel.CloseResult()
 					return
 				}
 				if strings.TrimSpace(searchPanel.Query) == "" {
@@ -800,8 +3991,7 @@ func main() {
 				return
 			case glfw.KeyLeft:
 				if searchPanel.Mode == searchpanel.ModePreview {
-					searchPanel.Mode = searchpanel.ModeResults
-					searchPanel.PreviewScroll = 0
+					searchPanel.CloseResult()
 				}
 				return
 			case glfw.KeyRight:
@@ -866,8 +4056,56 @@ func main() {
 			}
 		}
 
+		if paneNumbersMode {
+			paneNumbersMode = false
+			renderer.SetPaneNumbers(false)
+			n := -1
+			if key >= glfw.Key1 && key <= glfw.Key9 {
+				n = int(key - glfw.Key1)
+			} else if key >= glfw.KeyKP1 && key <= glfw.KeyKP9 {
+				n = int(key - glfw.KeyKP1)
+			}
+			if n >= 0 {
+				layouts := activeTab.GetPaneLayouts()
+				if n < len(layouts) {
+					activeTab.SetActivePane(layouts[n].Pane)
+					lineBuf.clear()
+				}
+				swallowChar = true
+				return
+			}
+			if key == glfw.KeyEscape {
+				return
+			}
+		}
+
+		// Enter closes a pane on_exit kept open after its shell exited
+		if pane := activeTab.GetActivePane(); pane != nil && heldPanes[pane] && action == glfw.Press && (key == glfw.KeyEnter || key == glfw.KeyKPEnter) {
+			if closeExitedPane(activeTab, pane) {
+				win.SetShouldClose(true)
+			}
+			return
+		}
+
 		appCursor := activeTab.Terminal.AppCursorKeys()
+		if pane := activeTab.GetActivePane(); pane != nil && passthrough.match(pane, key, mods) {
+			if data := keybindings.EncodeKey(key, mods, appCursor); data != nil {
+				pane.Write(data)
+				pane.Terminal.GetGrid().ResetScrollOffset()
+				swallowChar = true
+				return
+			}
+		}
 		result := keybindings.TranslateKey(key, mods, appCursor)
+		if tabResult, ok := tabKeys.Handle(key, mods); ok {
+			result = tabResult
+			swallowChar = true
+		}
+		if chordResult, ok := chords.Handle(key, mods, appCursor, time.Now()); ok {
+			result = chordResult
+			// The key after the leader must not also be typed
+			swallowChar = result.Action != keybindings.ActionNone
+		}
 
 		switch result.Action {
 		case keybindings.ActionExit:
@@ -877,16 +4115,30 @@ func main() {
 			if showHelp {
 				return
 			}
+			// Never capture what is typed at a password prompt
+			if activeTab.SecureInput() {
+				lineBuf.clear()
+				activeTab.Write(result.Data)
+				activeTab.Terminal.GetGrid().ResetScrollOffset()
+				return
+			}
 			// Check for Enter key (carriage return)
 			if len(result.Data) == 1 && result.Data[0] == '\r' {
-				line := lineBuf.getLine()
-				cmdResult := commands.HandleCommand(line, renderer)
-				if cmdResult.Handled {
+				// Prefer the command as the shell drew it after its OSC 133 prompt
+				// mark, which stays correct through history recall and completion
+				line, ok := activeTab.Terminal.CommandLine()
+				if !ok {
+					line = lineBuf.getLine()
+				}
+				// Commands such as raven-diff may split the tab, so answer in the pane they were typed in
+				pane := activeTab.GetActivePane()
+				cmdResult := commands.HandleCommand(line, renderer, paneCmds)
+				if cmdResult.Handled && pane != nil {
 					// Echo the command (so it appears in terminal)
-					activeTab.Write([]byte("\r\n"))
+					pane.Write([]byte("\r\n"))
 					// Display command output
 					output := strings.ReplaceAll(cmdResult.Output, "\n", "\r\n")
-					activeTab.Terminal.Process([]byte(output))
+					pane.Terminal.Process([]byte(output))
 					lineBuf.clear()
 					return
 				}
@@ -903,74 +4155,114 @@ func main() {
 			activeTab.Write(result.Data)
 			activeTab.Terminal.GetGrid().ResetScrollOffset()
 		case keybindings.ActionScrollUp:
-			activeTab.Terminal.GetGrid().ScrollViewUp(5)
+			activeTab.Terminal.GetGrid().ScrollViewUp(countOr(result.Count, 5))
 		case keybindings.ActionScrollDown:
-			activeTab.Terminal.GetGrid().ScrollViewDown(5)
+			activeTab.Terminal.GetGrid().ScrollViewDown(countOr(result.Count, 5))
 		case keybindings.ActionScrollUpLine:
-			activeTab.Terminal.GetGrid().ScrollViewUp(1)
+			activeTab.Terminal.GetGrid().ScrollViewUp(countOr(result.Count, 1))
 		case keybindings.ActionScrollDownLine:
-			activeTab.Terminal.GetGrid().ScrollViewDown(1)
+			activeTab.Terminal.GetGrid().ScrollViewDown(countOr(result.Count, 1))
 		case keybindings.ActionToggleFullscreen:
 			win.ToggleFullscreen()
 		case keybindings.ActionCopy:
 			g := activeTab.Terminal.GetGrid()
-			text := g.SelectedText()
-			if text == "" {
-				text = g.VisibleText()
+			if copySelection(g, mods, clipboard.TargetClipboard) {
+				return
 			}
-			if text != "" {
+			if text := g.VisibleText(); text != "" {
 				glfw.SetClipboardString(text)
 				showToast("Copied to clipboard")
 			}
+		case keybindings.ActionCopySelection:
+			if !copySelection(activeTab.Terminal.GetGrid(), mods, clipboard.TargetClipboard) {
+				showToast("Nothing selected")
+			}
 		case keybindings.ActionPaste:
 			clip := glfw.GetClipboardString()
 			if clip != "" {
 				clip = strings.ReplaceAll(clip, "\r\n", "\n")
 				clip = strings.ReplaceAll(clip, "\n", "\r")
-				activeTab.Write([]byte(clip))
+				activeTab.Write(keybindings.EncodeText(clip, activeTab.Terminal.Latin1()))
 				activeTab.Terminal.GetGrid().ResetScrollOffset()
 				showToast("Pasted from clipboard")
 			}
 		case keybindings.ActionNewTab:
 			lineBuf.clear()
-			tabManager.NewTab()
+			switch err := tabManager.NewTab(); {
+			case errors.Is(err, tab.ErrTabLimit):
+				showToast(fmt.Sprintf("Tab limit reached: %d tabs ([terminal] max_tabs)", tabManager.MaxTabs()))
+			case err != nil:
+				showToast("Could not open a tab: " + err.Error())
+			default:
+				warnResources("tabs", tabManager.TabCount(), tabManager.MaxTabs(), tab.DefaultMaxTabs)
+			}
 		case keybindings.ActionCloseTab:
 			tabManager.CloseCurrentTab()
 		case keybindings.ActionNextTab:
+			if recentTabs && key == glfw.KeyTab && mods&glfw.ModControl != 0 && result.Count == 0 {
+				switcher.open(tabManager, false, time.Now())
+				return
+			}
 			lineBuf.clear()
-			tabManager.NextTab()
+			for range countOr(result.Count, 1) {
+				tabManager.NextTab()
+			}
 		case keybindings.ActionPrevTab:
+			if recentTabs && key == glfw.KeyTab && mods&glfw.ModControl != 0 && result.Count == 0 {
+				switcher.open(tabManager, true, time.Now())
+				return
+			}
+			lineBuf.clear()
+			for range countOr(result.Count, 1) {
+				tabManager.PrevTab()
+			}
+		case keybindings.ActionSelectTab:
 			lineBuf.clear()
-			tabManager.PrevTab()
+			if !tabManager.SelectTab(result.Tab) {
+				showToast(fmt.Sprintf("There is no tab %d", result.Tab+1))
+			}
+		case keybindings.ActionLastTab:
+			lineBuf.clear()
+			if !tabManager.LastTab() {
+				showToast("No previous tab to go back to")
+			}
 		case keybindings.ActionSplitVertical:
 			lineBuf.clear()
-			activeTab.SplitVertical()
+			reportSplit(activeTab, activeTab.SplitVertical())
 		case keybindings.ActionSplitHorizontal:
 			lineBuf.clear()
-			activeTab.SplitHorizontal()
+			reportSplit(activeTab, activeTab.SplitHorizontal())
 		case keybindings.ActionClosePane:
 			lineBuf.clear()
 			activeTab.ClosePane()
 		case keybindings.ActionNextPane:
 			lineBuf.clear()
-			activeTab.NextPane()
+			for range countOr(result.Count, 1) {
+				activeTab.NextPane()
+			}
 		case keybindings.ActionPrevPane:
 			lineBuf.clear()
-			activeTab.PrevPane()
+			for range countOr(result.Count, 1) {
+				activeTab.PrevPane()
+			}
 		case keybindings.ActionShowHelp:
 			showHelp = !showHelp
 			if !showHelp {
 				renderer.ResetHelpScroll()
 			}
-		case keybindings.ActionZoomIn:
-			if err := renderer.ZoomIn(); err == nil {
-				// Recalculate grid size after zoom
-				width, height := win.GetFramebufferSize()
-				cols, rows := renderer.CalculateGridSize(width, height)
-				tabManager.ResizeAll(uint16(cols), uint16(rows))
+		case keybindings.ActionZoomIn, keybindings.ActionZoomOut:
+			zoom := renderer.ZoomIn
+			if result.Action == keybindings.ActionZoomOut {
+				zoom = renderer.ZoomOut
+			}
+			before := renderer.GetFontSize()
+			for range countOr(result.Count, 1) {
+				size := renderer.GetFontSize()
+				if zoom() != nil || renderer.GetFontSize() == size {
+					break
+				}
 			}
-		case keybindings.ActionZoomOut:
-			if err := renderer.ZoomOut(); err == nil {
+			if renderer.GetFontSize() != before {
 				// Recalculate grid size after zoom
 				width, height := win.GetFramebufferSize()
 				cols, rows := renderer.CalculateGridSize(width, height)
@@ -983,24 +4275,75 @@ func main() {
 				cols, rows := renderer.CalculateGridSize(width, height)
 				tabManager.ResizeAll(uint16(cols), uint16(rows))
 			}
+		case keybindings.ActionPaneZoomIn, keybindings.ActionPaneZoomOut, keybindings.ActionPaneZoomReset:
+			delta := float32(0)
+			switch result.Action {
+			case keybindings.ActionPaneZoomIn:
+				delta = paneZoomStep * float32(countOr(result.Count, 1))
+			case keybindings.ActionPaneZoomOut:
+				delta = -paneZoomStep * float32(countOr(result.Count, 1))
+			}
+			scale := activeTab.ZoomActivePane(delta)
+			showToast(fmt.Sprintf("Pane zoom %d%%", int(scale*100+0.5)))
 		case keybindings.ActionOpenMenu:
 			if settingsMenu.IsOpen() {
 				settingsMenu.Close()
 			} else {
-				searchPanel.Open = false
-				aiPanel.Open = false
-				aiPanel.Reset()
+				searchPanel.Close()
+				aiPanel.Close()
+				closeToolPanels()
 				settingsMenu.Open()
 			}
 		case keybindings.ActionToggleResizeMode:
 			resizeMode = !resizeMode
+		case keybindings.ActionPrintPDF:
+			// Modifiers are not passed on: Alt would ask for visual rows
+			text := selectionText(activeTab.Terminal.GetGrid(), 0, copyExact)
+			what := "selection"
+			if text == "" {
+				text = activeTab.Terminal.GetGrid().VisibleText()
+				what = "screen"
+			}
+			title := activeTab.Terminal.GetWindowTitle()
+			if title == "" {
+				title = "Raven Terminal"
+			}
+			path := printer.DefaultPath()
+			if err := printer.WritePDF(path, title, text); err != nil {
+				logging.Warnf(logging.App, "Failed to print to %s: %v", path, err)
+				showToast("Print failed: " + err.Error())
+			} else {
+				showToast("Printed " + what + " to " + path)
+			}
+		case keybindings.ActionFindCursor:
+			activeTab.Terminal.GetGrid().ResetScrollOffset()
+			spotlightStart = time.Now()
+		case keybindings.ActionInspectChar:
+			// The cell under the mouse when it is over the window, else the cursor's
+			pane := activeTab.GetActivePane()
+			col, row := pane.Terminal.GetGrid().GetCursor()
+			if haveCursorPos && win.GLFW().GetAttrib(glfw.Hovered) == glfw.True {
+				width, height := win.GetFramebufferSize()
+				if p, c, r, ok := renderer.HitTestPane(activeTab, lastCursorX, lastCursorY, width, height); ok {
+					pane, col, row = p, c, r
+				}
+			}
+			charInfoPane, charInfoCol, charInfoRow = pane, pane.Terminal.GetGrid().CharStart(col, row), row
+		case keybindings.ActionDisplayPanes:
+			if activeTab.PaneCount() < 2 {
+				showToast("Only one pane")
+				return
+			}
+			paneNumbersMode = true
+			paneNumbersUntil = time.Now().Add(3 * time.Second)
+			renderer.SetPaneNumbers(true)
 		case keybindings.ActionToggleSearchPanel:
 			if !searchPanel.Enabled {
 				showToast("Enable web search in settings")
 				return
 			}
-			aiPanel.Open = false
-			aiPanel.Reset()
+			aiPanel.Close()
+			closeToolPanels()
 			searchPanel.Toggle()
 			if searchPanel.Open {
 				if settingsMenu.Config != nil {
@@ -1015,27 +4358,275 @@ func main() {
 				showToast("Enable Ollama chat in settings")
 				return
 			}
-			searchPanel.Open = false
+			searchPanel.Close()
+			closeToolPanels()
 			aiPanel.Toggle()
 			if aiPanel.Open {
 				aiPanel.Focused = true
 				showHelp = false
 				renderer.ResetHelpScroll()
+			}
+		case keybindings.ActionToggleProcessPanel:
+			searchPanel.Close()
+			aiPanel.Close()
+			wasOpen := procPanel.Open
+			closeToolPanels()
+			if !wasOpen {
+				procPanel.Toggle()
+			}
+			if procPanel.Open {
+				showHelp = false
+				renderer.ResetHelpScroll()
+			}
+		case keybindings.ActionToggleDevServerPanel:
+			searchPanel.Close()
+			aiPanel.Close()
+			wasOpen := devPanel.Open
+			closeToolPanels()
+			if !wasOpen {
+				devPanel.Toggle()
+			}
+			if devPanel.Open {
+				scanDevServers(time.Now())
+				showHelp = false
+				renderer.ResetHelpScroll()
+			}
+		case keybindings.ActionToggleRedaction:
+			redactionOn = !redactionOn
+			if redactionOn {
+				renderer.SetRedactor(redactor)
+				showToast("Redaction on")
+			} else {
+				renderer.SetRedactor(nil)
+				showToast("Redaction off")
+			}
+		case keybindings.ActionHintMode:
+			if showHelp {
+				return
+			}
+			hintState.Anchor = nil
+			hintState.Begin(hintTargets(activeTab.GetPanes()))
+			if len(hintState.Targets) == 0 {
+				hintState.Cancel()
+				showToast("Nothing to pick")
+			}
+		case keybindings.ActionReadScreen:
+			if speaker == nil {
+				showToast("No speech service found")
+				return
+			}
+			speakText(activeTab.Terminal.GetGrid().VisibleText())
+		case keybindings.ActionSelectScreen:
+			g := activeTab.Terminal.GetGrid()
+			g.SetSelection(0, 0, g.Cols-1, g.Rows-1)
+			if text := selectionText(g, mods, copyExact); text != "" {
+				glfw.SetClipboardString(text)
+				showToast("Copied screen")
+			}
+		case keybindings.ActionSelectScrollback:
+			if text := activeTab.Terminal.GetGrid().AllText(); text != "" {
+				glfw.SetClipboardString(text)
+				showToast(fmt.Sprintf("Copied scrollback (%d lines)", strings.Count(text, "\n")+1))
+			}
+		case keybindings.ActionSelectLastCommand:
+			text, ok := activeTab.Terminal.LastCommandText()
+			if !ok {
+				showToast("No prompt marks from the shell")
+				return
+			}
+			if text != "" {
+				glfw.SetClipboardString(text)
+				showToast(fmt.Sprintf("Copied last command (%d lines)", strings.Count(text, "\n")+1))
+			}
+		case keybindings.ActionToggleScrollLock:
+			pane := activeTab.GetActivePane()
+			switch {
+			case pane == nil:
+			case scrollSync.linked():
+				scrollSync = scrollLock{}
+				showToast("Scroll lock off")
+			case scrollSync.pending == pane:
+				scrollSync.pending = nil
+				showToast("Scroll lock cancelled")
+			case scrollSync.pending == nil:
+				scrollSync.pending = pane
+				showToast("Focus another pane and press Ctrl+Shift+Z")
+			default:
+				scrollSync.link(scrollSync.pending, pane)
+				showToast("Scroll lock on")
+			}
+		case keybindings.ActionToggleNotifications:
+			// The AI chat stays open underneath so its replies can still be jumped to
+			searchPanel.Close()
+			aiPanel.Focused = false
+			wasOpen := notifyPanel.Open
+			closeToolPanels()
+			if !wasOpen {
+				notifyPanel.Toggle()
+				renderer.SetUnreadNotifications(0)
+				showHelp = false
+				renderer.ResetHelpScroll()
+			}
+		case keybindings.ActionToggleSnippets:
+			searchPanel.Close()
+			aiPanel.Close()
+			wasOpen := snippetPanel.Open
+			closeToolPanels()
+			if !wasOpen {
+				snippetPanel.Toggle()
+				refreshSnippets()
+				showHelp = false
+				renderer.ResetHelpScroll()
+			}
+		case keybindings.ActionToggleRegisters:
+			searchPanel.Close()
+			aiPanel.Close()
+			wasOpen := registerPanel.Open
+			closeToolPanels()
+			if !wasOpen {
+				// With text selected the viewer opens ready to yank it
+				registerPanel.Toggle(activeTab.Terminal.GetGrid().HasSelection())
+				showHelp = false
+				renderer.ResetHelpScroll()
+			}
+		case keybindings.ActionToggleCalc:
+			openCalculator()
+		case keybindings.ActionTogglePalette:
+			searchPanel.Close()
+			aiPanel.Close()
+			wasOpen := palettePanel.Open
+			closeToolPanels()
+			if !wasOpen {
+				palettePanel.Toggle(renderer.PaletteSwatches())
+				showHelp = false
+				renderer.ResetHelpScroll()
+			}
+		case keybindings.ActionSetMark:
+			markAction = "set"
+			showToast("Type a letter to name this scroll position")
+		case keybindings.ActionJumpToMark:
+			names := activeTab.Terminal.GetGrid().Bookmarks()
+			if len(names) == 0 {
+				showToast("No marks in this pane; Ctrl+Alt+M sets one")
+				break
+			}
+			markAction = "jump"
+			showToast("Jump to mark: " + strings.Join(strings.Split(string(names), ""), " "))
+		case keybindings.ActionChordUnbound:
+			showToast("Nothing is bound to that key after " + chords.Leader())
+		case keybindings.ActionRespawnPane:
+			message, err := respawnPane()
+			if err != nil {
+				message = err.Error()
+			}
+			showToast(message)
+		case keybindings.ActionTogglePause:
+			pane := activeTab.GetActivePane()
+			if pane == nil {
+				break
+			}
+			pane.SetPaused(!pane.Paused())
+			if pane.Paused() {
+				showToast("Output paused; Ctrl+Alt+S resumes")
 			} else {
-				aiPanel.Reset()
+				showToast("Output resumed")
+			}
+		case keybindings.ActionToggleDirJump:
+			searchPanel.Close()
+			aiPanel.Close()
+			wasOpen := dirPanel.Open
+			closeToolPanels()
+			if !wasOpen {
+				trackDirVisits(time.Now())
+				dirPanel.Toggle()
+				refreshDirJump()
+				showHelp = false
+				renderer.ResetHelpScroll()
 			}
 		}
 	})
 
 	win.GLFW().SetCharCallback(func(w *glfw.Window, char rune) {
+		// Drop the character produced by a key already consumed as a command
+		if swallowChar {
+			swallowChar = false
+			return
+		}
+
+		if locked {
+			lockInput += string(char)
+			return
+		}
+
+		if hintState.Active {
+			pickHint(char)
+			return
+		}
+
+		if markAction != "" {
+			useMark(char)
+			return
+		}
+
 		// Handle character input for settings menu
 		if settingsMenu.IsOpen() && settingsMenu.InputMode() {
 			settingsMenu.HandleChar(char)
 			return
 		}
+		if settingsMenu.IsOpen() && (settingsMenu.HandleConfirmKey(char) || settingsMenu.HandleSearchChar(char)) {
+			return
+		}
+
+		if (procPanel.Open && procPanel.Focused) || (devPanel.Open && devPanel.Focused) ||
+			(notifyPanel.Open && notifyPanel.Focused) || (cleanupPanel.Open && cleanupPanel.Focused) ||
+			(updatePanel.Open && updatePanel.Focused) {
+			return
+		}
+
+		if rewindPlayer != nil {
+			return
+		}
+
+		if inspectPanel.Open && inspectPanel.Focused {
+			width, height := win.GetFramebufferSize()
+			cellW, cellH := renderer.UICellDimensions()
+			inspectPanel.AppendFilter(char, inspectPanel.Layout(width, height, cellW, cellH).VisibleLines)
+			return
+		}
+
+		if registerPanel.Open && registerPanel.Focused {
+			useRegister(char)
+			return
+		}
+
+		if calcPanel.Open && calcPanel.Focused {
+			calcPanel.AppendInput(char)
+			return
+		}
+
+		// The palette is driven by keys; swallow the characters they type
+		if palettePanel.Open && palettePanel.Focused {
+			return
+		}
+
+		if dirPanel.Open && dirPanel.Focused {
+			dirPanel.AppendQuery(char)
+			refreshDirJump()
+			return
+		}
+
+		if snippetPanel.Open && snippetPanel.Focused {
+			snippetPanel.AppendRune(char)
+			if snippetPanel.Mode == snippets.ModeList {
+				refreshSnippets()
+			}
+			return
+		}
 
 		if aiPanel.Open && aiPanel.Focused {
-			aiPanel.AppendInput(char)
+			if aiPanel.PendingCommand == "" {
+				aiPanel.AppendInput(char)
+			}
 			return
 		}
 
@@ -1054,10 +4645,19 @@ func main() {
 			return
 		}
 
-		// Add character to line buffer
-		lineBuf.addChar(char)
+		// Add character to line buffer unless a password is being typed
+		if activeTab.SecureInput() {
+			lineBuf.clear()
+		} else {
+			lineBuf.addChar(char)
+		}
 
-		data := keybindings.TranslateChar(char, currentMods)
+		var data []byte
+		if activeTab.Terminal.Latin1() {
+			data = keybindings.TranslateCharLatin1(char, currentMods)
+		} else {
+			data = keybindings.TranslateChar(char, currentMods)
+		}
 		activeTab.Write(data)
 		activeTab.Terminal.GetGrid().ResetScrollOffset()
 	})
@@ -1069,13 +4669,16 @@ func main() {
 	})
 
 	win.GLFW().SetScrollCallback(func(w *glfw.Window, xoff, yoff float64) {
+		if locked {
+			return
+		}
+		// Scrolling moves the labelled words out from under their hints
+		hintState.Cancel()
 		if settingsMenu.IsOpen() {
 			if settingsMenu.InputMode() {
 				return
 			}
-			if debugMenu {
-				log.Printf("menu: scroll yoff=%.2f input=%v title=%s", yoff, settingsMenu.InputMode(), settingsMenu.GetTitle())
-			}
+			logging.Debugf(logging.Menu, "scroll yoff=%.2f input=%v title=%s", yoff, settingsMenu.InputMode(), settingsMenu.GetTitle())
 			steps := int(math.Abs(yoff))
 			if steps == 0 {
 				steps = 1
@@ -1134,7 +4737,7 @@ func main() {
 				fy = rectY + rectH - 1
 			}
 
-			cellW, cellH := renderer.CellSize()
+			cellW, cellH := renderer.PaneCellSize(g)
 			col := int((fx - rectX) / cellW)
 			row := int((fy - rectY) / cellH)
 			col = clampInt(col, 0, g.Cols-1)
@@ -1147,13 +4750,13 @@ func main() {
 
 		if aiPanel.Open && aiPanel.Focused {
 			width, height := win.GetFramebufferSize()
-			cellW, cellH := renderer.CellDimensions()
+			cellW, cellH := renderer.UICellDimensions()
 			layout := aiPanel.Layout(width, height, cellW, cellH)
 			maxChars := int(layout.ContentWidth/cellW) - 2
 			if maxChars < 10 {
 				maxChars = 10
 			}
-			totalLines := len(aipanel.BuildWrappedLinesWithThinking(aiPanel.Messages, maxChars, aiPanel.ShowThinking, aiPanel.ThinkingExpanded))
+			totalLines := len(aiPanel.Wrapped(maxChars))
 			visibleLines := layout.VisibleLines
 			maxScroll := totalLines - visibleLines
 			if maxScroll < 0 {
@@ -1179,7 +4782,7 @@ func main() {
 
 		if searchPanel.Open && searchPanel.Focused {
 			width, height := win.GetFramebufferSize()
-			cellW, cellH := renderer.CellDimensions()
+			cellW, cellH := renderer.UICellDimensions()
 			layout := searchPanel.Layout(width, height, cellW, cellH)
 			previewVisible := layout.VisibleLines - 1
 			if previewVisible < 1 {
@@ -1215,7 +4818,27 @@ func main() {
 	})
 
 	win.GLFW().SetMouseButtonCallback(func(w *glfw.Window, button glfw.MouseButton, action glfw.Action, mods glfw.ModifierKey) {
-		if settingsMenu.IsOpen() || showHelp {
+		// Clicking a menu item selects it, and clicking in the input moves its caret
+		if settingsMenu.IsOpen() && !showHelp && !locked && button == glfw.MouseButtonLeft {
+			menuInputDrag = false
+			menuSliderDrag = -1
+			if action != glfw.Press {
+				return
+			}
+			width, height := win.GetFramebufferSize()
+			x, y := w.GetCursorPos()
+			if pos, ok := renderer.MenuInputOffsetAt(settingsMenu, float32(x), float32(y), width, height); ok {
+				settingsMenu.SetInputCursor(pos, mods&glfw.ModShift != 0)
+				menuInputDrag = true
+			} else if index, fraction := renderer.MenuSliderAt(settingsMenu, float32(x), float32(y), width, height); index >= 0 {
+				settingsMenu.SetSliderAt(index, fraction)
+				menuSliderDrag = index
+			} else if index := renderer.MenuItemAt(settingsMenu, float32(x), float32(y), width, height); index >= 0 {
+				settingsMenu.SelectAt(index)
+			}
+			return
+		}
+		if settingsMenu.IsOpen() || showHelp || locked {
 			return
 		}
 
@@ -1231,14 +4854,55 @@ func main() {
 		case glfw.MouseButtonLeft:
 			switch action {
 			case glfw.Press:
+				// Clicking the dev-server chip opens its URL
+				if urlChipTarget != "" && time.Now().Before(urlChip.expiresAt) {
+					cx, cy, cw, ch := renderer.URLChipRect(urlChip.message, width, height)
+					fx, fy := float32(x), float32(y)
+					if fx >= cx && fx <= cx+cw && fy >= cy && fy <= cy+ch {
+						urlChip.expiresAt = time.Time{}
+						if err := openLink(urlChipTarget); err != nil {
+							showToast("Failed to open URL")
+						} else {
+							showToast("Opening " + urlChipTarget)
+						}
+						return
+					}
+				}
+				// Clicking a palette swatch copies its escape sequence
+				if palettePanel.Open {
+					cellW, cellH := renderer.UICellDimensions()
+					layout := palettePanel.Layout(width, height, cellW, cellH)
+					fx, fy := float32(x), float32(y)
+					if fx >= layout.PanelX && fx <= layout.PanelX+layout.PanelWidth &&
+						fy >= layout.PanelY && fy <= layout.PanelY+layout.PanelHeight {
+						palettePanel.Focused = true
+						if i := palettePanel.SwatchAt(layout, fx, fy); i >= 0 {
+							palettePanel.Selected = i
+							copySwatch(false)
+						}
+						return
+					}
+					palettePanel.Focused = false
+				}
 				// Check AI panel first for click-to-focus and text selection
 				if aiPanel.Open {
-					cellW, cellH := renderer.CellDimensions()
+					cellW, cellH := renderer.UICellDimensions()
 					layout := aiPanel.Layout(width, height, cellW, cellH)
 					fx, fy := float32(x), float32(y)
+					// Dragging the edge facing the terminal resizes the panel
+					if aiPanel.OnEdge(layout, fx, fy) {
+						aiPanel.Focused = true
+						aiPanel.Resizing = true
+						return
+					}
 					if fx >= layout.PanelX && fx <= layout.PanelX+layout.PanelWidth &&
 						fy >= layout.PanelY && fy <= layout.PanelY+layout.PanelHeight {
 						aiPanel.Focused = true
+						// A click on the header switches persona
+						if fy <= layout.HeaderY+layout.LineHeight*0.3 {
+							aiPanel.Status = "Persona: " + aiPanel.CyclePersona(1).Name
+							return
+						}
 						// Check if click is in message area for text selection
 						if fx >= layout.ContentX && fx <= layout.ContentX+layout.ContentWidth &&
 							fy >= layout.MessagesStart && fy <= layout.MessagesEnd {
@@ -1254,7 +4918,7 @@ func main() {
 				}
 				// Check search panel for click-to-focus and click-to-select
 				if searchPanel.Open {
-					cellW, cellH := renderer.CellDimensions()
+					cellW, cellH := renderer.UICellDimensions()
 					layout := searchPanel.Layout(width, height, cellW, cellH)
 					fx, fy := float32(x), float32(y)
 					if fx >= layout.PanelX && fx <= layout.PanelX+layout.PanelWidth &&
@@ -1299,9 +4963,11 @@ func main() {
 				}
 
 				if mods&glfw.ModControl != 0 {
-					if urlText, _, _ := urlAtCellRange(pane.Terminal.GetGrid(), col, row); urlText != "" {
-						if err := openURL(urlText); err != nil {
-							log.Printf("failed to open url %q: %v", urlText, err)
+					if urlText, _, _ := urlAtCellRange(pane.Terminal.GetGrid(), col, row, links); urlText != "" {
+						if err := openLink(urlText); err != nil {
+							logging.Warnf(logging.App, "Failed to open URL %q: %v", urlText, err)
+						} else {
+							showToast(links.openedMessage(urlText))
 						}
 						return
 					}
@@ -1314,9 +4980,14 @@ func main() {
 				pane.Terminal.GetGrid().SetSelection(col, row, col, row)
 				activeTab.SetActivePane(pane)
 			case glfw.Release:
+				if aiPanel.Resizing {
+					aiPanel.Resizing = false
+					saveAIPanelGeometry()
+					return
+				}
 				// Handle AI panel text selection release
 				if aiPanel.SelectionActive {
-					cellW, cellH := renderer.CellDimensions()
+					cellW, cellH := renderer.UICellDimensions()
 					layout := aiPanel.Layout(width, height, cellW, cellH)
 					fy := float32(y)
 					if fy < layout.MessagesStart {
@@ -1349,7 +5020,7 @@ func main() {
 				}
 				// Handle search panel preview text selection release
 				if searchPanel.SelectionActive {
-					cellW, cellH := renderer.CellDimensions()
+					cellW, cellH := renderer.UICellDimensions()
 					layout := searchPanel.Layout(width, height, cellW, cellH)
 					fy := float32(y)
 					if fy < layout.ResultsStart+layout.LineHeight {
@@ -1404,12 +5075,13 @@ func main() {
 					fy = rectY + rectH - 1
 				}
 
-				cellW, cellH := renderer.CellSize()
+				g := pane.Terminal.GetGrid()
+				cellW, cellH := renderer.PaneCellSize(g)
 				col := int((fx - rectX) / cellW)
 				row := int((fy - rectY) / cellH)
-				g := pane.Terminal.GetGrid()
 				col = clampInt(col, 0, g.Cols-1)
 				row = clampInt(row, 0, g.Rows-1)
+				col = g.CharStart(col, row)
 
 				if selection.startCol == col && selection.startRow == row {
 					g.ClearSelection()
@@ -1418,9 +5090,8 @@ func main() {
 				}
 
 				g.SetSelection(selection.startCol, selection.startRow, col, row)
-				if text := g.SelectedText(); text != "" {
-					glfw.SetClipboardString(text)
-					showToast("Copied to clipboard")
+				if copyOnSelect {
+					copySelection(g, mods, copyTarget)
 				}
 
 				selection.active = false
@@ -1438,19 +5109,18 @@ func main() {
 			g := pane.Terminal.GetGrid()
 
 			if mods&glfw.ModControl != 0 {
-				if urlText, _, _ := urlAtCellRange(g, col, row); urlText != "" {
-					if err := openURL(urlText); err != nil {
-						log.Printf("failed to open url %q: %v", urlText, err)
+				if urlText, _, _ := urlAtCellRange(g, col, row, links); urlText != "" {
+					if err := openLink(urlText); err != nil {
+						logging.Warnf(logging.App, "Failed to open URL %q: %v", urlText, err)
+					} else {
+						showToast(links.openedMessage(urlText))
 					}
 					return
 				}
 			}
 
 			if g.HasSelection() {
-				if text := g.SelectedText(); text != "" {
-					glfw.SetClipboardString(text)
-					showToast("Copied to clipboard")
-				}
+				copySelection(g, mods, clipboard.TargetClipboard)
 				return
 			}
 
@@ -1458,18 +5128,51 @@ func main() {
 			if clip != "" {
 				clip = strings.ReplaceAll(clip, "\r\n", "\n")
 				clip = strings.ReplaceAll(clip, "\n", "\r")
-				pane.Write([]byte(clip))
+				pane.Write(keybindings.EncodeText(clip, pane.Terminal.Latin1()))
 				g.ResetScrollOffset()
 				showToast("Pasted from clipboard")
 			}
 		}
 	})
 
+	// Keep text the same physical size on monitors with a different scale
+	win.GLFW().SetContentScaleCallback(func(w *glfw.Window, x, y float32) {
+		if settingsMenu.Config == nil || !settingsMenu.Config.Window.FollowMonitorScale || startScale <= 0 {
+			return
+		}
+		monitorScale = x / startScale
+		if err := renderer.SetDefaultFontSize(settingsMenu.Config.FontSize * monitorScale); err != nil {
+			logging.Warnf(logging.Render, "Failed to rescale font: %v", err)
+			return
+		}
+		width, height := win.GetFramebufferSize()
+		cols, rows := renderer.CalculateGridSize(width, height)
+		tabManager.ResizeAll(uint16(cols), uint16(rows))
+	})
+
+	win.GLFW().SetFocusCallback(func(w *glfw.Window, focused bool) {
+		if !focused {
+			// The Ctrl release that would pick a tab goes to another window
+			switcher.close()
+			runHook(hooks.WindowFocusLost, nil)
+		}
+	})
+
 	win.GLFW().SetCursorPosCallback(func(w *glfw.Window, xpos, ypos float64) {
 		lastCursorX = xpos
 		lastCursorY = ypos
 		haveCursorPos = true
 
+		if menuInputDrag && settingsMenu.IsOpen() {
+			width, height := win.GetFramebufferSize()
+			if pos, ok := renderer.MenuInputOffsetAt(settingsMenu, float32(xpos), float32(ypos), width, height); ok {
+				settingsMenu.SetInputCursor(pos, true)
+			}
+		}
+		if menuSliderDrag >= 0 && settingsMenu.IsOpen() {
+			width, height := win.GetFramebufferSize()
+			settingsMenu.SetSliderAt(menuSliderDrag, renderer.MenuSliderFraction(settingsMenu, float32(xpos), width, height))
+		}
 		if settingsMenu.IsOpen() || showHelp {
 			renderer.ClearHoverURL()
 			return
@@ -1481,10 +5184,16 @@ func main() {
 			return
 		}
 
+		if aiPanel.Resizing && aiPanel.Open {
+			width, height := win.GetFramebufferSize()
+			aiPanel.ResizeTo(float32(xpos), float32(ypos), width, height)
+			return
+		}
+
 		// Track AI panel text selection during drag
 		if aiPanel.SelectionActive && aiPanel.Open {
 			width, height := win.GetFramebufferSize()
-			cellW, cellH := renderer.CellDimensions()
+			cellW, cellH := renderer.UICellDimensions()
 			layout := aiPanel.Layout(width, height, cellW, cellH)
 			fy := float32(ypos)
 			if fy < layout.MessagesStart {
@@ -1500,7 +5209,7 @@ func main() {
 		// Track search panel preview text selection during drag
 		if searchPanel.SelectionActive && searchPanel.Open {
 			width, height := win.GetFramebufferSize()
-			cellW, cellH := renderer.CellDimensions()
+			cellW, cellH := renderer.UICellDimensions()
 			layout := searchPanel.Layout(width, height, cellW, cellH)
 			fy := float32(ypos)
 			if fy < layout.ResultsStart+layout.LineHeight {
@@ -1533,10 +5242,10 @@ func main() {
 				fy = rectY + rectH - 1
 			}
 
-			cellW, cellH := renderer.CellSize()
+			g := selection.pane.Terminal.GetGrid()
+			cellW, cellH := renderer.PaneCellSize(g)
 			col := int((fx - rectX) / cellW)
 			row := int((fy - rectY) / cellH)
-			g := selection.pane.Terminal.GetGrid()
 			col = clampInt(col, 0, g.Cols-1)
 			row = clampInt(row, 0, g.Rows-1)
 
@@ -1552,7 +5261,7 @@ func main() {
 			return
 		}
 
-		if _, startCol, endCol := urlAtCellRange(pane.Terminal.GetGrid(), col, row); startCol <= endCol {
+		if _, startCol, endCol := urlAtCellRange(pane.Terminal.GetGrid(), col, row, links); startCol <= endCol {
 			renderer.SetHoverURL(pane.Terminal.GetGrid(), row, startCol, endCol)
 			return
 		}
@@ -1560,11 +5269,40 @@ func main() {
 	})
 
 	// Main loop
+	sizeOverlay := &toastState{}
+	var sizePane *tab.Pane
+	sizeCols, sizeRows := 0, 0
+	if settingsMenu.Config != nil && settingsMenu.Config.Update.Check && update.Due() {
+		checkForUpdate(false)
+	}
+	startupProfile.Mark("setup")
+	// frameInterval is how often a frame is drawn. Input, PTY output and
+	// background results are handled on every pass of the loop; between
+	// frames the loop waits for window events, so keystrokes reach the shell
+	// without waiting for the next frame.
+	frameInterval := func() time.Duration {
+		if win.VSync() != window.VSyncOff {
+			// Swapping buffers waits for the monitor; this only stops a
+			// driver that ignores vsync from spinning
+			return win.RefreshInterval() * 3 / 4
+		}
+		if settingsMenu.Config != nil && settingsMenu.Config.Window.MaxFPS > 0 {
+			return time.Second / time.Duration(settingsMenu.Config.Window.MaxFPS)
+		}
+		return win.RefreshInterval()
+	}
+	var lastFrame time.Time
 	for !win.ShouldClose() {
 		// Check for exited tabs
-		tabManager.CleanupExited()
-		if tabManager.AllExited() {
-			break
+		if behavior := exitBehavior(); behavior != "close" {
+			if handleExitedPanes(behavior, time.Now()) {
+				break
+			}
+		} else {
+			tabManager.CleanupExited()
+			if tabManager.AllExited() {
+				break
+			}
 		}
 
 		if settingsMenu.Config != nil && settingsMenu.Config.Theme != currentTheme {
@@ -1572,7 +5310,7 @@ func main() {
 			currentTheme = settingsMenu.Config.Theme
 		}
 		if settingsMenu.Config != nil {
-			searchPanel.SetEnabled(settingsMenu.Config.WebSearch.Enabled)
+			searchPanel.SetEnabled(settingsMenu.Config.WebSearch.Enabled, semanticSearchOn(settingsMenu.Config))
 			if !searchPanel.Open {
 				searchPanel.ProxyEnabled = settingsMenu.Config.WebSearch.UseReaderProxy
 			}
@@ -1602,6 +5340,30 @@ func main() {
 						searchPanel.Status = fmt.Sprintf("%d results", len(results))
 					}
 				}
+			case resp := <-semanticResponses:
+				if resp.id != searchPanel.SearchID {
+					break
+				}
+				results := make([]searchpanel.Result, 0, len(resp.matches))
+				for _, m := range resp.matches {
+					snippet, _, _ := strings.Cut(m.Text, "\n")
+					results = append(results, searchpanel.Result{
+						Title:   fmt.Sprintf("%s  %s  (%.0f%%)", m.Time.Format("Mon Jan 2 15:04"), m.Source, m.Score*100),
+						Snippet: snippet,
+						Text:    m.Text,
+					})
+				}
+				searchPanel.SetResults(resp.query, results, resp.err)
+				if resp.err != nil {
+					searchPanel.Status = resp.err.Error()
+				} else {
+					searchPanel.AddToHistory(resp.query)
+					if len(results) == 0 {
+						searchPanel.Status = "No matches"
+					} else {
+						searchPanel.Status = fmt.Sprintf("%d scrollback matches", len(results))
+					}
+				}
 			default:
 				goto searchDone
 			}
@@ -1666,7 +5428,46 @@ func main() {
 					}
 				}
 
+				if len(resp.toolCalls) > 0 {
+					// Only the first call is offered; the model can ask again
+					command, err := aitools.CommandOf(resp.toolCalls[0])
+					if err != nil {
+						command = resp.toolCalls[0].Function.Name
+					}
+					if err == nil {
+						// Refuse a command off the allowlist without asking
+						_, err = aitools.Parse(command, aiToolCommands(settingsMenu.Config))
+					}
+					aiPanel.RequestCommand(command)
+					if err != nil {
+						aiPanel.ResolveCommand("Not run: " + err.Error())
+						requestAIReply()
+						break
+					}
+					aiPanel.Status = "Run this command? Y: run | N: deny"
+					if resp.loaded && settingsMenu.Config != nil {
+						aiPanel.ModelLoaded = true
+						aiPanel.LoadedURL = settingsMenu.Config.Ollama.URL
+						aiPanel.LoadedModel = settingsMenu.Config.Ollama.Model
+					}
+					break
+				}
+
 				aiPanel.TrimMessages(maxChatMessages)
+				// Replies that finish while the terminal has focus are easy to miss
+				if !aiPanel.Focused && (settingsMenu.Config == nil || settingsMenu.Config.Notifications.AICompletions) {
+					reply := strings.TrimSpace(aiPanel.GetLastAssistantMessage())
+					if i := strings.IndexByte(reply, '\n'); i >= 0 {
+						reply = reply[:i]
+					}
+					notifyPanel.Add(notifications.Entry{
+						Time:    time.Now(),
+						Kind:    notifications.KindAI,
+						Source:  "ai",
+						Message: "Reply: " + reply,
+					})
+					renderer.SetUnreadNotifications(notifyPanel.Unread)
+				}
 				if resp.loaded {
 					if settingsMenu.Config != nil {
 						aiPanel.ModelLoaded = true
@@ -1680,6 +5481,15 @@ func main() {
 		}
 	aiDone:
 
+		select {
+		case resp := <-aiCommandResponses:
+			if resp.id == aiPanel.RequestID && aiPanel.PendingCommand != "" {
+				aiPanel.ResolveCommand(resp.output)
+				requestAIReply()
+			}
+		default:
+		}
+
 		// Handle model load responses
 		for {
 			select {
@@ -1700,9 +5510,61 @@ func main() {
 		}
 	modelLoadDone:
 
-		// Handle cursor blinking
+		for {
+			select {
+			case resp := <-gitResponses:
+				if state, ok := gitStates[resp.tab]; ok {
+					state.pending = !resp.done
+					resp.tab.SetGitStatus(resp.label)
+				}
+			default:
+				goto gitStatusDone
+			}
+		}
+	gitStatusDone:
+
+		for {
+			select {
+			case resp := <-promptRowResponses:
+				if state, ok := promptRowStates[resp.pane]; ok {
+					state.pending = !resp.done
+					state.text = resp.text
+				}
+			default:
+				goto promptRowsDone
+			}
+		}
+	promptRowsDone:
+
+		for {
+			select {
+			case call := <-ipcCalls:
+				req := call.Request
+				switch req.Method {
+				case ipc.MethodSendText:
+					call.Reply(sendToPane(req.Tab, req.Pane, req.Text, req.Bracketed))
+				case ipc.MethodSendFile:
+					text, err := ipc.ReadSendFile(req.Path)
+					if err == nil {
+						err = sendToPane(req.Tab, req.Pane, text, req.Bracketed)
+					}
+					call.Reply(err)
+				default:
+					call.Reply(fmt.Errorf("unknown method %q", req.Method))
+				}
+			default:
+				goto ipcDone
+			}
+		}
+	ipcDone:
+
+		// Handle cursor blinking; the cursor stays solid when blinking is off
+		// or motion is reduced
 		now := time.Now()
-		if now.Sub(lastBlink) >= blinkInterval {
+		if !cursorBlink || renderer.ReduceMotion() {
+			cursorVisible = true
+			lastBlink = now
+		} else if now.Sub(lastBlink) >= blinkInterval {
 			cursorVisible = !cursorVisible
 			lastBlink = now
 		}
@@ -1714,7 +5576,7 @@ func main() {
 					width, height := win.GetFramebufferSize()
 					rectX, rectY, rectW, rectH, ok := renderer.PaneRectFor(activeTab, selection.pane, width, height)
 					if ok {
-						cellW, cellH := renderer.CellSize()
+						cellW, cellH := renderer.PaneCellSize(selection.pane.Terminal.GetGrid())
 						edge := float64(cellH)
 						var dir int
 						if lastCursorY < float64(rectY)+edge {
@@ -1765,28 +5627,314 @@ func main() {
 			}
 		}
 
-		// Render
+		if now.Sub(lastDevScan) >= 500*time.Millisecond {
+			scanDevServers(now)
+			trackDirVisits(now)
+			refreshSessionBorders()
+			refreshHostProfiles()
+			refreshBadges()
+			collectRunPanes()
+			collectBells(now)
+			collectLongCommands(now)
+			collectTabs()
+			if now.Sub(lastCleanupScan) >= 2*time.Second {
+				scanCleanup(now)
+			}
+			if now.Sub(lastMemoryCheck) >= 2*time.Second {
+				enforceMemoryCap()
+				lastMemoryCheck = now
+			}
+			renderer.SetUnreadNotifications(notifyPanel.Unread)
+			lastDevScan = now
+		}
+
+		if now.Sub(lastSemanticScan) >= 30*time.Second {
+			indexScrollback()
+			lastSemanticScan = now
+		}
+		select {
+		case resp := <-updateResponses:
+			if resp.err != nil {
+				logging.Warnf(logging.App, "Update check failed: %v", resp.err)
+				if resp.manual {
+					updatePanel.Status = "Update check failed: " + resp.err.Error()
+				}
+				break
+			}
+			if err := update.MarkChecked(); err != nil {
+				logging.Warnf(logging.App, "Failed to record update check: %v", err)
+			}
+			updatePanel.SetRelease(resp.release)
+			if resp.manual || !updatePanel.Available {
+				break
+			}
+			showToast("Raven Terminal " + resp.release.Tag + " is available: raven-update shows what's new")
+			if settingsMenu.Config != nil && settingsMenu.Config.Update.AutoDownload {
+				downloadUpdate()
+			}
+		case err := <-updateDownloads:
+			updatePanel.Downloading = false
+			if err != nil {
+				logging.Warnf(logging.App, "Update download failed: %v", err)
+				updatePanel.Status = "Download failed: " + err.Error()
+				break
+			}
+			updatePanel.Status = "Downloaded " + updatePanel.Release.Tag + "; it is installed when Raven Terminal exits"
+			showToast(updatePanel.Status)
+		default:
+		}
+		select {
+		case err := <-semanticDone:
+			semanticBusy = false
+			if err != nil {
+				logging.Warnf(logging.AI, "Failed to index scrollback: %v", err)
+			}
+		default:
+		}
+
+		if now.Sub(lastGitScan) >= 500*time.Millisecond {
+			refreshGitStatus()
+			refreshPromptRows()
+			lastGitScan = now
+		}
+
+		if screenReaderOn && now.Sub(lastAnnounce) >= 500*time.Millisecond {
+			// Announce new output of the focused pane; full-screen programs
+			// redraw rather than append, so they are read on demand instead
+			if activeTab := tabManager.ActiveTab(); activeTab != nil && !locked && !activeTab.Terminal.AlternateScreen() {
+				speakText(outputTracker.Update(activeTab.Terminal.GetGrid()))
+			} else {
+				outputTracker.Reset()
+			}
+			lastAnnounce = now
+		}
+
+		if scrollSync.linked() || scrollSync.pending != nil {
+			// Drop the lock once either pane has closed
+			live := make(map[*tab.Pane]bool)
+			for _, t := range tabManager.GetTabs() {
+				for _, pane := range t.GetPanes() {
+					live[pane] = !pane.HasExited()
+				}
+			}
+			if scrollSync.pending != nil && !live[scrollSync.pending] {
+				scrollSync.pending = nil
+			}
+			if scrollSync.linked() {
+				if live[scrollSync.panes[0]] && live[scrollSync.panes[1]] {
+					scrollSync.sync()
+				} else {
+					scrollSync = scrollLock{}
+				}
+			}
+		}
+
+		status := make(map[*tab.Pane]string)
+		for _, t := range tabManager.GetTabs() {
+			for _, pane := range t.GetPanes() {
+				if pane.Paused() {
+					status[pane] = "paused"
+				}
+			}
+		}
+		if len(paneWatches) > 0 || len(paneHighlights) > 0 {
+			live := make(map[*tab.Pane]bool)
+			for _, t := range tabManager.GetTabs() {
+				for _, pane := range t.GetPanes() {
+					live[pane] = !pane.HasExited()
+				}
+			}
+			for pane, w := range paneWatches {
+				if !live[pane] {
+					w.watcher.Stop()
+					delete(paneWatches, pane)
+					continue
+				}
+				select {
+				case <-w.watcher.Changes():
+					// Interrupt the previous run; the tty discards queued input on
+					// Ctrl+C, so the command is typed once the shell is back
+					pane.Write([]byte{0x03})
+					w.runAt = now.Add(250 * time.Millisecond)
+				default:
+				}
+				if !w.runAt.IsZero() && now.After(w.runAt) {
+					pane.Write([]byte(w.command + "\r"))
+					pane.Terminal.GetGrid().ResetScrollOffset()
+					w.runs++
+					w.lastRun = now
+					w.runAt = time.Time{}
+				}
+				state := fmt.Sprintf("run %d at %s", w.runs, w.lastRun.Format("15:04:05"))
+				if !w.runAt.IsZero() {
+					state = "restarting"
+				}
+				if status[pane] != "" {
+					status[pane] += " | "
+				}
+				status[pane] += "watch: " + w.command + " (" + state + ")"
+			}
+			scan := now.Sub(lastHighlightScan) >= highlightScanInterval
+			if scan {
+				lastHighlightScan = now
+			}
+			for pane, set := range paneHighlights {
+				if _, ok := live[pane]; !ok {
+					delete(paneHighlights, pane)
+					continue
+				}
+				if scan {
+					set.Scan(pane.Terminal.GetGrid())
+				}
+				if status[pane] != "" {
+					status[pane] += " | "
+				}
+				status[pane] += set.Status()
+			}
+		}
+		renderer.SetPaneStatus(status)
+
+		if procPanel.NeedsRefresh(now) {
+			refreshProcesses(now)
+		}
+
+		if paneNumbersMode && now.After(paneNumbersUntil) {
+			paneNumbersMode = false
+			renderer.SetPaneNumbers(false)
+		}
+
+		// Leave rewind mode once its pane loses focus or closes
+		if rewindPane != nil {
+			if activeTab := tabManager.ActiveTab(); activeTab == nil || activeTab.GetActivePane() != rewindPane {
+				stopRewind()
+			}
+		}
+
+		// Record the focused pane's output while the inspector is open
+		var inspectTarget *tab.Pane
+		if activeTab := tabManager.ActiveTab(); inspectPanel.Open && activeTab != nil {
+			inspectTarget = activeTab.GetActivePane()
+		}
+		if inspectTarget != inspectedPane {
+			if inspectedPane != nil {
+				inspectedPane.StopInspect()
+			}
+			inspectedPane, inspectRecorder = inspectTarget, nil
+			if inspectTarget != nil {
+				inspectRecorder = inspectTarget.StartInspect()
+				inspectPanel.Source = fmt.Sprintf("pane %d.%d", tabManager.ActiveIndex()+1, tabManager.ActiveTab().ActivePaneIndex()+1)
+				inspectPanel.Seq = -1
+			}
+		}
+		if inspectRecorder != nil && !inspectPanel.Paused {
+			if seq := inspectRecorder.Seq(); seq != inspectPanel.Seq {
+				width, height := win.GetFramebufferSize()
+				cellW, cellH := renderer.UICellDimensions()
+				inspectPanel.SetEvents(inspectRecorder.Events(), seq, inspectPanel.Layout(width, height, cellW, cellH).VisibleLines)
+			}
+		}
+
+		// Flash the grid dimensions when the focused pane changes size
+		if activeTab := tabManager.ActiveTab(); activeTab != nil {
+			if pane := activeTab.GetActivePane(); pane != nil {
+				g := pane.Terminal.GetGrid()
+				if pane == sizePane && (g.Cols != sizeCols || g.Rows != sizeRows) &&
+					settingsMenu.Config != nil && settingsMenu.Config.Terminal.SizeOverlay {
+					sizeOverlay.message = fmt.Sprintf("%dx%d", g.Cols, g.Rows)
+					sizeOverlay.expiresAt = now.Add(time.Second)
+				}
+				sizePane, sizeCols, sizeRows = pane, g.Cols, g.Rows
+			}
+		}
+
+		// Render once per frame interval
+		if wait := frameInterval() - time.Since(lastFrame); wait > 0 {
+			window.WaitEventsTimeout(wait)
+			continue
+		}
+		lastFrame = time.Now()
+		refreshAliasPreview()
 		width, height := win.GetFramebufferSize()
 		win.SetViewport(width, height)
 		drawCursor := cursorVisible
 		if activeTab := tabManager.ActiveTab(); activeTab != nil && activeTab.Terminal != nil {
 			drawCursor = drawCursor && activeTab.Terminal.IsCursorVisible()
 		}
-		if settingsMenu.IsOpen() {
+		if locked {
+			prompt := "Press any key to unlock"
+			masked := ""
+			if settingsMenu.Config != nil && settingsMenu.Config.Lock.PassphraseSHA256 != "" {
+				prompt = "Enter passphrase to unlock"
+				masked = strings.Repeat("*", len([]rune(lockInput)))
+			}
+			renderer.DrawLockScreen(prompt, masked, lockStatus, width, height)
+		} else if settingsMenu.IsOpen() {
 			renderer.RenderWithMenu(tabManager, width, height, drawCursor, settingsMenu)
 		} else {
 			renderer.RenderWithHelpAndPanels(tabManager, width, height, drawCursor, showHelp, searchPanel, aiPanel)
+			renderer.DrawHints(tabManager.ActiveTab(), hintState, width, height)
+			if elapsed := now.Sub(spotlightStart); elapsed < cursorSpotlightDuration {
+				renderer.DrawCursorSpotlight(tabManager.ActiveTab(), float32(elapsed)/float32(cursorSpotlightDuration), width, height)
+			}
+			if charInfoPane != nil {
+				cell := charInfoPane.Terminal.GetGrid().DisplayCell(charInfoCol, charInfoRow)
+				lines := charinfo.Describe(cell.Char, int(cell.Width))
+				renderer.DrawCharInfo(tabManager.ActiveTab(), charInfoPane, charInfoCol, charInfoRow, lines, width, height)
+			}
+		}
+		if !settingsMenu.IsOpen() && !locked {
+			renderer.DrawProcessPanel(procPanel, width, height)
+			renderer.DrawDevServerPanel(devPanel, width, height)
+			renderer.DrawDirJumpPanel(dirPanel, width, height)
+			renderer.DrawRegisterPanel(registerPanel, width, height)
+			renderer.DrawCalcPanel(calcPanel, width, height)
+			renderer.DrawPalettePanel(palettePanel, width, height)
+			renderer.DrawSnippetPanel(snippetPanel, width, height)
+			renderer.DrawNotificationPanel(notifyPanel, width, height)
+			renderer.DrawCleanupPanel(cleanupPanel, width, height)
+			renderer.DrawInspectorPanel(inspectPanel, width, height)
+			renderer.DrawUpdatePanel(updatePanel, width, height)
+			if now.Before(urlChip.expiresAt) {
+				renderer.DrawURLChip(urlChip.message, width, height)
+			}
+		}
+		if now.Before(sizeOverlay.expiresAt) && !locked {
+			renderer.DrawSizeOverlay(sizeOverlay.message, width, height)
+		}
+		if switcher.visible(now) {
+			renderer.DrawTabSwitcher(switcher.entries, switcher.selected, width, height)
+		}
+		if chords.Pending(now) {
+			renderer.DrawChordHint(chords.Typed(), chords.Hint(), width, height)
 		}
 		if now.Before(toast.expiresAt) {
 			renderer.DrawToast(toast.message, width, height)
 		}
 
 		// Swap buffers and poll events
+		renderer.Present()
 		win.SwapBuffers()
+		if startupProfile.Enabled() {
+			startupProfile.Mark("first frame")
+			startupProfile.Report(os.Stderr)
+		}
 		window.PollEvents()
+	}
 
-		// Small sleep to prevent 100% CPU usage
-		time.Sleep(time.Millisecond * 16) // ~60 FPS
+	if settingsMenu.Config != nil && settingsMenu.Config.Window.RememberPlacement {
+		if err := win.SavePlacement(); err != nil {
+			logging.Warnf(logging.App, "Failed to save window placement: %v", err)
+		}
+	}
+
+	if err := dirStore.Save(); err != nil {
+		logging.Warnf(logging.App, "Failed to save directory history: %v", err)
+	}
+	if err := semanticIndex.Save(); err != nil {
+		logging.Warnf(logging.AI, "Failed to save semantic index: %v", err)
+	}
+	if err := update.ApplyPending(); err != nil {
+		logging.Warnf(logging.App, "Failed to install update: %v", err)
 	}
 }
 
@@ -1800,12 +5948,328 @@ func clampInt(value, min, max int) int {
 	return value
 }
 
-func urlAtCell(g *grid.Grid, col, row int) string {
-	urlText, _, _ := urlAtCellRange(g, col, row)
+// passphraseMatches reports whether input hashes to the hex SHA-256 digest want
+func passphraseMatches(input, want string) bool {
+	sum := sha256.Sum256([]byte(input))
+	got := hex.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(got), []byte(strings.ToLower(strings.TrimSpace(want)))) == 1
+}
+
+// expandBadge replaces each \(user.NAME) in format with the user variable
+// NAME, or nothing if it is not set, as iTerm2 badges do
+func expandBadge(format string, vars map[string]string) string {
+	var b strings.Builder
+	for {
+		start := strings.Index(format, `\(user.`)
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(format[start:], ')')
+		if end < 0 {
+			break
+		}
+		b.WriteString(format[:start])
+		b.WriteString(vars[format[start+len(`\(user.`):start+end]])
+		format = format[start+end+1:]
+	}
+	b.WriteString(format)
+	return b.String()
+}
+
+// expandAlias returns line with its first word replaced by the alias it
+// names, or "" when it does not start with one
+func expandAlias(line string, aliases map[string]string) string {
+	name, rest, _ := strings.Cut(strings.TrimLeft(line, " \t"), " ")
+	expansion, ok := aliases[name]
+	if !ok || name == "" || strings.TrimSpace(expansion) == "" {
+		return ""
+	}
+	if rest = strings.TrimSpace(rest); rest != "" {
+		return expansion + " " + rest
+	}
+	return expansion
+}
+
+// countOr returns the count typed before an action, or n when none was
+func countOr(count, n int) int {
+	if count > 0 {
+		return count
+	}
+	return n
+}
+
+// isModifierKey reports whether key is a bare modifier such as Shift or Ctrl
+func isModifierKey(key glfw.Key) bool {
+	switch key {
+	case glfw.KeyLeftShift, glfw.KeyRightShift, glfw.KeyLeftControl, glfw.KeyRightControl,
+		glfw.KeyLeftAlt, glfw.KeyRightAlt, glfw.KeyLeftSuper, glfw.KeyRightSuper:
+		return true
+	}
+	return false
+}
+
+func urlAtCell(g *grid.Grid, col, row int, rules linkRules) string {
+	urlText, _, _ := urlAtCellRange(g, col, row, rules)
 	return urlText
 }
 
-func urlAtCellRange(g *grid.Grid, col, row int) (string, int, int) {
+// selectionText returns the selected text, keeping a newline after every
+// visual row when Alt is held and tabs and trailing spaces when exact is set
+func selectionText(g *grid.Grid, mods glfw.ModifierKey, exact bool) string {
+	if mods&glfw.ModAlt != 0 {
+		return g.SelectedVisualText()
+	}
+	if exact {
+		return g.SelectedExactText()
+	}
+	return g.SelectedText()
+}
+
+// tabGroupName returns name, or the active tab's group when it is empty
+func tabGroupName(tm *tab.TabManager, name string) (string, error) {
+	if name != "" {
+		return name, nil
+	}
+	if active := tm.ActiveTab(); active != nil {
+		if name = active.Group(); name != "" {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("this tab is not in a group; name one")
+}
+
+// tabGroupColors reads the [tab_groups] strip colors, skipping invalid ones
+func tabGroupColors(groups map[string]config.TabGroup) map[string][4]float32 {
+	colors := make(map[string][4]float32, len(groups))
+	for name, group := range groups {
+		if group.Color == "" {
+			continue
+		}
+		c, ok := render.ParseHexColor(group.Color)
+		if !ok {
+			logging.Warnf(logging.App, "Invalid color %q for tab group %s", group.Color, name)
+			continue
+		}
+		colors[name] = c
+	}
+	return colors
+}
+
+// tabSwitcherDelay is how long Ctrl+Tab is held before the switcher shows, so
+// a quick tap flips to the last tab without it flashing up
+const tabSwitcherDelay = 150 * time.Millisecond
+
+// tabSwitcher lists the tabs most recently used first while Ctrl is held
+// after Ctrl+Tab, and switches to the selected one when Ctrl is let go
+type tabSwitcher struct {
+	tabs     []*tab.Tab
+	entries  []render.TabSwitcherEntry
+	selected int
+	opened   time.Time
+}
+
+// open lists the tabs of tm and selects the one used before the active tab,
+// or with back the least recently used one. It does nothing with one tab.
+func (s *tabSwitcher) open(tm *tab.TabManager, back bool, now time.Time) {
+	tabs := tm.RecentTabs()
+	if len(tabs) < 2 {
+		return
+	}
+	home, _ := os.UserHomeDir()
+	s.tabs, s.entries, s.opened = tabs, make([]render.TabSwitcherEntry, len(tabs)), now
+	for i, t := range tabs {
+		var details []string
+		pane := t.GetActivePane()
+		if pane != nil {
+			if process := pane.ForegroundProcess(); process != "" {
+				details = append(details, process)
+			}
+		}
+		if dir := t.ActiveDir(); dir != "" {
+			if home != "" && (dir == home || strings.HasPrefix(dir, home+"/")) {
+				dir = "~" + strings.TrimPrefix(dir, home)
+			}
+			details = append(details, dir)
+		}
+		if git := t.GitStatus(); git != "" {
+			details = append(details, git)
+		}
+		if group := t.Group(); group != "" {
+			details = append([]string{"[" + group + "]"}, details...)
+		}
+		s.entries[i] = render.TabSwitcherEntry{
+			Title:  fmt.Sprintf("Tab %d", t.ID()),
+			Detail: strings.Join(details, "  "),
+		}
+		if pane != nil {
+			lines := strings.Split(strings.TrimRight(pane.Terminal.GetGrid().VisibleText(), " \n"), "\n")
+			s.entries[i].Preview = strings.TrimSpace(lines[len(lines)-1])
+		}
+	}
+	s.selected = 1
+	if back {
+		s.selected = len(tabs) - 1
+	}
+}
+
+// active reports whether the switcher is waiting for Ctrl to be let go
+func (s *tabSwitcher) active() bool {
+	return len(s.tabs) > 0
+}
+
+// visible reports whether Ctrl has been held long enough to show the switcher
+func (s *tabSwitcher) visible(now time.Time) bool {
+	return s.active() && now.Sub(s.opened) >= tabSwitcherDelay
+}
+
+// move selects the tab delta places down the list, wrapping around
+func (s *tabSwitcher) move(delta int) {
+	if n := len(s.tabs); n > 0 {
+		s.selected = ((s.selected+delta)%n + n) % n
+	}
+}
+
+// close shuts the switcher and returns the selected tab, or nil if it was not open
+func (s *tabSwitcher) close() *tab.Tab {
+	var chosen *tab.Tab
+	if s.active() {
+		chosen = s.tabs[s.selected]
+	}
+	s.tabs, s.entries, s.selected = nil, nil, 0
+	return chosen
+}
+
+// passthroughRule sends keys straight to programs matching it
+type passthroughRule struct {
+	keys      keybindings.KeySet
+	processes map[string]bool // Empty for any program
+	altScreen bool
+}
+
+// passthroughRules are the [[passthrough]] rules, in the order they were written
+type passthroughRules []passthroughRule
+
+func newPassthroughRules(cfg []config.PassthroughRule) passthroughRules {
+	var rules passthroughRules
+	for i, c := range cfg {
+		keys, err := keybindings.ParseKeys(c.Keys)
+		if err != nil {
+			logging.Warnf(logging.App, "Invalid keys in [[passthrough]] rule %d: %v", i+1, err)
+		}
+		if len(keys) == 0 {
+			continue
+		}
+		rule := passthroughRule{keys: keys, processes: make(map[string]bool), altScreen: c.AltScreen}
+		for _, name := range c.Processes {
+			if name = strings.TrimSpace(name); name != "" {
+				rule.processes[name] = true
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// match reports whether a key pressed in pane goes straight to its program.
+// The foreground program is only looked up for keys a rule lists.
+func (rules passthroughRules) match(pane *tab.Pane, key glfw.Key, mods glfw.ModifierKey) bool {
+	process, looked := "", false
+	for _, rule := range rules {
+		if !rule.keys.Has(key, mods) {
+			continue
+		}
+		if rule.altScreen && !pane.Terminal.AlternateScreen() {
+			continue
+		}
+		if len(rule.processes) > 0 {
+			if !looked {
+				process, looked = pane.ForegroundProcess(), true
+			}
+			if !rule.processes[process] {
+				continue
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// linkRules decide which words are clickable URLs, by the characters
+// trimmed from their ends and the schemes they may use, and what opens them
+type linkRules struct {
+	trimLeft  string
+	trimRight string
+	schemes   map[string]bool
+	handlers  map[string]string // Lowercase scheme or domain to shell command
+}
+
+func newLinkRules(cfg config.LinksConfig) linkRules {
+	rules := linkRules{
+		trimLeft:  cfg.TrimLeft,
+		trimRight: cfg.TrimRight,
+		schemes:   make(map[string]bool),
+		handlers:  make(map[string]string),
+	}
+	for _, scheme := range cfg.Schemes {
+		rules.schemes[strings.ToLower(strings.TrimSuffix(strings.TrimSpace(scheme), ":"))] = true
+	}
+	for key, command := range cfg.Handlers {
+		key = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(key), ":"))
+		if key == "" || strings.TrimSpace(command) == "" {
+			continue
+		}
+		rules.handlers[key] = command
+		// A scheme with a handler of its own is clickable
+		if !strings.Contains(key, ".") {
+			rules.schemes[key] = true
+		}
+	}
+	return rules
+}
+
+// handler returns the command configured for u. Keys with a dot are domains
+// and match the host and its subdomains, or with a leading "*." only the
+// subdomains; the longest matching domain wins over a scheme.
+func (rules linkRules) handler(u *url.URL) string {
+	host := strings.ToLower(u.Hostname())
+	command, matched := "", 0
+	for key, cmd := range rules.handlers {
+		if !strings.Contains(key, ".") || host == "" {
+			continue
+		}
+		domain, subdomainsOnly := strings.CutPrefix(key, "*.")
+		if ((host == domain && !subdomainsOnly) || strings.HasSuffix(host, "."+domain)) && len(key) > matched {
+			command, matched = cmd, len(key)
+		}
+	}
+	if command != "" {
+		return command
+	}
+	return rules.handlers[strings.ToLower(u.Scheme)]
+}
+
+// runLinkHandler runs a [links] handler with sh -c, passing the URL in
+// RAVEN_URL, RAVEN_URL_SCHEME and RAVEN_URL_HOST. Handlers usually start
+// long-lived programs such as browsers, so unlike hooks they are not timed out.
+func runLinkHandler(command, target string, u *url.URL) error {
+	cmd := exec.Command("/bin/sh", "-c", command)
+	cmd.Env = append(os.Environ(),
+		"RAVEN_URL="+target,
+		"RAVEN_URL_SCHEME="+u.Scheme,
+		"RAVEN_URL_HOST="+u.Hostname(),
+	)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go func() {
+		if err := cmd.Wait(); err != nil {
+			logging.Debugf(logging.App, "URL handler %q: %v", command, err)
+		}
+	}()
+	return nil
+}
+
+func urlAtCellRange(g *grid.Grid, col, row int, rules linkRules) (string, int, int) {
 	if g == nil || row < 0 || row >= g.Rows || col < 0 || col >= g.Cols {
 		return "", -1, -1
 	}
@@ -1833,12 +6297,10 @@ func urlAtCellRange(g *grid.Grid, col, row int) (string, int, int) {
 		end++
 	}
 
-	trimLeftChars := "<>\"'()[]{}"
-	trimRightChars := "<>\"'()[]{}.,;:!?"
-	for start <= end && strings.ContainsRune(trimLeftChars, line[start]) {
+	for start <= end && strings.ContainsRune(rules.trimLeft, line[start]) {
 		start++
 	}
-	for end >= start && strings.ContainsRune(trimRightChars, line[end]) {
+	for end >= start && strings.ContainsRune(rules.trimRight, line[end]) {
 		end--
 	}
 	if start > end {
@@ -1850,18 +6312,59 @@ func urlAtCellRange(g *grid.Grid, col, row int) (string, int, int) {
 	if strings.HasPrefix(target, "www.") {
 		target = "http://" + target
 	}
-	if !strings.Contains(target, "://") {
-		return "", -1, -1
-	}
 
 	parsed, err := url.Parse(target)
-	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+	if err != nil || !rules.schemes[strings.ToLower(parsed.Scheme)] {
+		return "", -1, -1
+	}
+	// scheme://host/... needs a host, except file:///path; scheme:data
+	// such as mailto:someone@example.com or magnet:?xt=... needs data
+	if strings.HasPrefix(target[len(parsed.Scheme)+1:], "//") {
+		if parsed.Host == "" && (parsed.Scheme != "file" || parsed.Path == "") {
+			return "", -1, -1
+		}
+	} else if parsed.Opaque == "" && parsed.RawQuery == "" {
 		return "", -1, -1
 	}
 
 	return target, start, end
 }
 
+// sshCommand turns an ssh://[user@]host[:port] URL into an ssh command line.
+// A host or user starting with "-" is refused, as ssh would read it as an
+// option such as -oProxyCommand, and "--" ends the options before the host.
+func sshCommand(u *url.URL) (string, error) {
+	host := u.Hostname()
+	if host == "" || strings.HasPrefix(host, "-") {
+		return "", fmt.Errorf("refusing ssh link with host %q", host)
+	}
+	if u.User != nil && u.User.Username() != "" {
+		if strings.HasPrefix(u.User.Username(), "-") {
+			return "", fmt.Errorf("refusing ssh link with user %q", u.User.Username())
+		}
+		host = u.User.Username() + "@" + host
+	}
+	command := "ssh"
+	if port := u.Port(); port != "" {
+		command += " -p " + shellQuote(port)
+	}
+	return command + " -- " + shellQuote(host), nil
+}
+
+// openedMessage is the toast shown after a URL is clicked open
+func (rules linkRules) openedMessage(target string) string {
+	if u, err := url.Parse(target); err == nil && rules.handler(u) != "" {
+		return "Opening " + target
+	}
+	switch scheme, _, _ := strings.Cut(strings.ToLower(target), ":"); scheme {
+	case "git", "git+ssh", "ssh+git":
+		return "Copied " + target
+	case "ssh":
+		return "Connecting to " + target
+	}
+	return "Opening " + target
+}
+
 func openURL(target string) error {
 	var cmd *exec.Cmd
 	switch runtime.GOOS {
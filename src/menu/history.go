@@ -0,0 +1,160 @@
+package menu
+
+import (
+	"bytes"
+
+	"github.com/javanhut/RavenTerminal/src/config"
+)
+
+// maxUndo is how many changes Undo can step back through
+const maxUndo = 100
+
+// track runs action and records the settings from before it when it changes
+// them, so Undo can put them back. Repeated changes to the same widget, as
+// holding an arrow key or dragging a slider makes, are undone together.
+func (m *Menu) track(widget string, action func()) {
+	before, err := m.Config.Snapshot()
+	action()
+	if err != nil {
+		return
+	}
+	after, err := m.Config.Snapshot()
+	if err != nil || bytes.Equal(before, after) {
+		return
+	}
+	m.redo = nil
+	if widget != "" && widget == m.lastTracked && len(m.undo) > 0 {
+		return
+	}
+	m.lastTracked = widget
+	m.undo = append(m.undo, before)
+	if len(m.undo) > maxUndo {
+		m.undo = m.undo[len(m.undo)-maxUndo:]
+	}
+}
+
+// Undo puts back the settings from before the last change made in the menu,
+// reporting false when there is none
+func (m *Menu) Undo() bool {
+	if m.InputActive || len(m.undo) == 0 {
+		return false
+	}
+	if !m.swapSnapshot(&m.undo, &m.redo) {
+		return false
+	}
+	m.StatusMessage = "Undone (save to persist)"
+	return true
+}
+
+// Redo makes the last undone change again, reporting false when there is none
+func (m *Menu) Redo() bool {
+	if m.InputActive || len(m.redo) == 0 {
+		return false
+	}
+	if !m.swapSnapshot(&m.redo, &m.undo) {
+		return false
+	}
+	m.StatusMessage = "Redone (save to persist)"
+	return true
+}
+
+// swapSnapshot replaces the settings with the newest snapshot of from,
+// keeping the settings replaced on to, and shows the page again
+func (m *Menu) swapSnapshot(from, to *[][]byte) bool {
+	current, err := m.Config.Snapshot()
+	if err != nil {
+		m.StatusMessage = "Error: " + err.Error()
+		return false
+	}
+	snapshot := (*from)[len(*from)-1]
+	cfg, err := config.FromSnapshot(snapshot)
+	if err != nil {
+		m.StatusMessage = "Error: " + err.Error()
+		return false
+	}
+	*from = (*from)[:len(*from)-1]
+	*to = append(*to, current)
+	m.Config = cfg
+	m.lastTracked = ""
+	m.rebuildPage()
+	m.debugf("history undo=%d redo=%d", len(m.undo), len(m.redo))
+	return true
+}
+
+// rebuildPage builds the items of the page being shown again, after the
+// settings they show were replaced
+func (m *Menu) rebuildPage() {
+	switch m.State {
+	case MenuMain, MenuCategory:
+		m.buildSettingsPage()
+		return
+	case MenuShellSelect:
+		m.buildShellMenu()
+	case MenuThemeSelect:
+		m.buildThemeMenu()
+	case MenuPromptStyle:
+		m.buildPromptStyleMenu()
+	case MenuPromptSettings:
+		m.buildPromptSettingsMenu()
+	case MenuScripts:
+		m.buildScriptsMenu()
+	case MenuOllamaModels:
+		m.buildOllamaModelsMenu()
+	case MenuCommands:
+		m.buildCommandsMenu()
+	case MenuSnippets:
+		m.buildSnippetsMenu()
+	case MenuAliases:
+		m.buildAliasesMenu()
+	case MenuExports:
+		m.buildExportsMenu()
+	case MenuCursorStyle:
+		m.buildCursorStyleMenu()
+	case MenuBackups:
+		m.buildBackupsMenu()
+	default:
+		// A confirmation refers to an item the old settings may not have
+		m.navigateTo(m.settingsPage(), m.buildSettingsPage)
+		return
+	}
+	if m.SelectedIndex >= len(m.Items) {
+		m.SelectedIndex = m.firstSelectableIndex()
+	}
+	m.adjustScroll()
+}
+
+// buildBackupsMenu lists the config backups taken when the menu saved,
+// newest first
+func (m *Menu) buildBackupsMenu() {
+	m.Items = []MenuItem{}
+	backups, err := config.Backups()
+	if err != nil {
+		m.Items = append(m.Items, MenuItem{Label: "Error: " + err.Error(), Disabled: true})
+	}
+	for _, backup := range backups {
+		m.Items = append(m.Items, MenuItem{Label: backup.Taken.Format("2006-01-02 15:04:05"), Value: backup.Path})
+	}
+	if err == nil && len(backups) == 0 {
+		m.Items = append(m.Items, MenuItem{Label: "No backups yet; one is kept each time settings are saved", Disabled: true})
+	}
+	m.Items = append(m.Items, MenuItem{Label: ""})
+	m.Items = append(m.Items, MenuItem{Label: "Back"})
+}
+
+// handleBackupSelect loads the chosen backup into the menu, to be kept with
+// Save and Close or put back with Undo
+func (m *Menu) handleBackupSelect(item MenuItem) {
+	if item.Label == "Back" {
+		m.goBack()
+		return
+	}
+	cfg, err := config.LoadFile(item.Value)
+	if err != nil {
+		m.StatusMessage = "Restore failed: " + err.Error()
+		return
+	}
+	m.Config = cfg
+	m.OllamaModels = nil
+	m.StatusMessage = "Loaded backup of " + item.Label + " (save to persist)"
+	m.goBack()
+}
@@ -0,0 +1,222 @@
+package menu
+
+import (
+	"strings"
+	"unicode"
+)
+
+// The input field is edited at InputCursor, a rune offset into InputBuffer.
+// The runes between InputAnchor and InputCursor are selected; typing or
+// pasting replaces them and deleting removes them.
+
+// InputSelection returns the selected runes of the input as start and end
+// offsets, equal when nothing is selected
+func (m *Menu) InputSelection() (int, int) {
+	m.clampInput()
+	if m.InputAnchor < m.InputCursor {
+		return m.InputAnchor, m.InputCursor
+	}
+	return m.InputCursor, m.InputAnchor
+}
+
+// SelectedInput returns the selected text of the input
+func (m *Menu) SelectedInput() string {
+	start, end := m.InputSelection()
+	return string([]rune(m.InputBuffer)[start:end])
+}
+
+// MoveInputCursor moves the caret by delta runes, or by delta words when
+// word is set, extending the selection when extend is set
+func (m *Menu) MoveInputCursor(delta int, word, extend bool) {
+	if !m.InputActive {
+		return
+	}
+	start, end := m.InputSelection()
+	runes := []rune(m.InputBuffer)
+	pos := m.InputCursor
+	switch {
+	case !extend && start != end && !word:
+		// An arrow collapses the selection to the side it points at
+		if delta < 0 {
+			pos = start
+		} else {
+			pos = end
+		}
+	case word:
+		for ; delta < 0; delta++ {
+			pos = wordStart(runes, pos)
+		}
+		for ; delta > 0; delta-- {
+			pos = wordEnd(runes, pos)
+		}
+	default:
+		pos += delta
+	}
+	m.setInputCursor(pos, extend)
+}
+
+// MoveInputLineEdge moves the caret to the start or end of its line,
+// extending the selection when extend is set
+func (m *Menu) MoveInputLineEdge(end, extend bool) {
+	if !m.InputActive {
+		return
+	}
+	runes := []rune(m.InputBuffer)
+	m.clampInput()
+	pos := m.InputCursor
+	if end {
+		for pos < len(runes) && runes[pos] != '\n' {
+			pos++
+		}
+	} else {
+		for pos > 0 && runes[pos-1] != '\n' {
+			pos--
+		}
+	}
+	m.setInputCursor(pos, extend)
+}
+
+// MoveInputLine moves the caret delta lines up or down in a multi-line
+// input, keeping its column where the line is long enough. It reports false
+// when the input has one line.
+func (m *Menu) MoveInputLine(delta int, extend bool) bool {
+	if !m.InputActive || !m.InputIsMultiline() {
+		return false
+	}
+	lines := strings.Split(m.InputBuffer, "\n")
+	line, col := InputLineCol(m.InputBuffer, m.InputCursor)
+	line += delta
+	if line < 0 {
+		line, col = 0, 0
+	} else if line >= len(lines) {
+		line, col = len(lines)-1, len([]rune(lines[len(lines)-1]))
+	}
+	if n := len([]rune(lines[line])); col > n {
+		col = n
+	}
+	pos := col
+	for _, l := range lines[:line] {
+		pos += len([]rune(l)) + 1
+	}
+	m.setInputCursor(pos, extend)
+	return true
+}
+
+// SetInputCursor puts the caret at a rune offset, as a mouse click in the
+// input does, extending the selection when extend is set
+func (m *Menu) SetInputCursor(pos int, extend bool) {
+	if !m.InputActive {
+		return
+	}
+	m.setInputCursor(pos, extend)
+}
+
+// SelectAllInput selects the whole input
+func (m *Menu) SelectAllInput() {
+	if !m.InputActive {
+		return
+	}
+	m.InputAnchor = 0
+	m.InputCursor = len([]rune(m.InputBuffer))
+}
+
+// DeleteInput deletes the selection, or else the rune or word before the
+// caret, or after it when forward is set
+func (m *Menu) DeleteInput(forward, word bool) {
+	if !m.InputActive {
+		return
+	}
+	start, end := m.InputSelection()
+	if start == end {
+		runes := []rune(m.InputBuffer)
+		switch {
+		case forward && word:
+			end = wordEnd(runes, start)
+		case forward:
+			end = min(start+1, len(runes))
+		case word:
+			start = wordStart(runes, end)
+		default:
+			start = max(end-1, 0)
+		}
+	}
+	m.replaceInput(start, end, "")
+}
+
+// insertInput types text at the caret, replacing the selection
+func (m *Menu) insertInput(text string) {
+	start, end := m.InputSelection()
+	m.replaceInput(start, end, text)
+}
+
+// replaceInput replaces the runes from start to end with text and puts the
+// caret after it
+func (m *Menu) replaceInput(start, end int, text string) {
+	runes := []rune(m.InputBuffer)
+	inserted := []rune(text)
+	m.InputBuffer = string(runes[:start]) + text + string(runes[end:])
+	m.InputCursor = start + len(inserted)
+	m.InputAnchor = m.InputCursor
+}
+
+func (m *Menu) setInputCursor(pos int, extend bool) {
+	n := len([]rune(m.InputBuffer))
+	m.InputCursor = min(max(pos, 0), n)
+	if !extend {
+		m.InputAnchor = m.InputCursor
+	}
+}
+
+// clampInput keeps the caret and anchor inside the buffer
+func (m *Menu) clampInput() {
+	n := len([]rune(m.InputBuffer))
+	m.InputCursor = min(max(m.InputCursor, 0), n)
+	m.InputAnchor = min(max(m.InputAnchor, 0), n)
+}
+
+// InputLineCol returns the line and column, both from 0, of a rune offset
+// into text
+func InputLineCol(text string, pos int) (int, int) {
+	line, col := 0, 0
+	for i, r := range []rune(text) {
+		if i >= pos {
+			break
+		}
+		if r == '\n' {
+			line++
+			col = 0
+		} else {
+			col++
+		}
+	}
+	return line, col
+}
+
+// wordStart returns the start of the word before pos, skipping the spaces
+// and punctuation before it
+func wordStart(runes []rune, pos int) int {
+	pos = min(pos, len(runes))
+	for pos > 0 && !isWordRune(runes[pos-1]) {
+		pos--
+	}
+	for pos > 0 && isWordRune(runes[pos-1]) {
+		pos--
+	}
+	return pos
+}
+
+// wordEnd returns the end of the word after pos, skipping the spaces and
+// punctuation before it
+func wordEnd(runes []rune, pos int) int {
+	for pos < len(runes) && !isWordRune(runes[pos]) {
+		pos++
+	}
+	for pos < len(runes) && isWordRune(runes[pos]) {
+		pos++
+	}
+	return pos
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
@@ -1,16 +1,16 @@
 package menu
 
 import (
-	"log"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/javanhut/RavenTerminal/src/config"
+	"github.com/javanhut/RavenTerminal/src/integration"
+	"github.com/javanhut/RavenTerminal/src/logging"
 )
 
-var debugMenu = os.Getenv("RAVEN_DEBUG_MENU") == "1"
-
 // MenuState represents the current menu state
 type MenuState int
 
@@ -31,6 +31,10 @@ const (
 	MenuConfirmExport
 	MenuConfirmDelete  // Confirmation before deleting items
 	MenuCursorStyle    // Cursor style selection
+	MenuSnippets
+	MenuConfirmSnippet
+	MenuCategory // Settings of one category
+	MenuBackups  // Config backups to load
 )
 
 // InputState tracks what we're currently inputting
@@ -60,6 +64,14 @@ const (
 	InputFontSize
 	// Panel width input state
 	InputPanelWidth
+	// Snippet input states
+	InputSnippetName
+	InputSnippetBody
+	InputSnippetDesc
+	// Config archive path input states
+	InputArchiveExport
+	InputArchiveMerge
+	InputArchiveReplace
 )
 
 // MenuItem represents a menu item
@@ -70,6 +82,8 @@ type MenuItem struct {
 	IsHeader bool   // Section header (non-selectable, styled differently)
 	IsToggle bool   // Toggle item (shows checkbox indicator)
 	Toggled  bool   // Current toggle state
+	Slider   *Slider   // Slider item: a number Left/Right moves between limits
+	Dropdown *Dropdown // Dropdown item: choices Left/Right cycles through
 }
 
 // Menu manages the configuration menu
@@ -80,6 +94,14 @@ type Menu struct {
 	Items         []MenuItem
 	ScrollOffset  int
 	OllamaModels  []string
+	Query         string    // Search typed on the main menu
+	Category      string    // Category whose page is open, or the one a page below it was opened from
+	hits          []setting // Setting of each search result in Items
+
+	// Undo history of the settings, as snapshots taken before each change
+	undo        [][]byte
+	redo        [][]byte
+	lastTracked string // Widget whose changes the newest undo snapshot covers
 
 	// Position memory - preserve selection when navigating between menus
 	savedIndex  map[MenuState]int
@@ -90,6 +112,8 @@ type Menu struct {
 	InputState  InputState
 	InputBuffer string
 	InputLabel  string
+	InputCursor int // Rune offset of the caret in InputBuffer
+	InputAnchor int // Other end of the selection; equal to InputCursor when nothing is selected
 
 	// Pending values for multi-step input
 	PendingName     string
@@ -97,6 +121,7 @@ type Menu struct {
 	PendingDesc     string
 	PendingAliasCmd string
 	PendingExport   string
+	PendingSnippet  string
 
 	// Edit tracking
 	EditingIndex      int    // -1 for new, >= 0 for existing
@@ -104,9 +129,9 @@ type Menu struct {
 	EditingExportName string
 
 	// Delete confirmation tracking
-	DeleteType   string // "command", "alias", or "export"
+	DeleteType   string // "command", "snippet", "alias", "export", or "history"
 	DeleteTarget string // Name or index of item to delete
-	DeleteIndex  int    // Index for commands
+	DeleteIndex  int    // Index for commands and snippets
 
 	// Messages
 	StatusMessage string
@@ -121,6 +146,12 @@ type Menu struct {
 	OnOllamaFetchModels func(url string) ([]string, error)
 	// Optional hook for pre-loading an Ollama model into memory.
 	OnOllamaLoadModel func(url, model string)
+	// Optional hook for forgetting directory and search history and emptying the cache directory.
+	OnClearHistory func() error
+	// Optional hook for writing a diagnostics bundle; returns where it was saved.
+	OnCreateDiagnostics func() (string, error)
+	// Optional hook for running a custom command from the commands menu.
+	OnRunCommand func(cmd config.CustomCommand) error
 }
 
 // NewMenu creates a new menu instance
@@ -188,6 +219,9 @@ func (m *Menu) Open() {
 	m.InputActive = false
 	m.InputState = InputNone
 	m.StatusMessage = ""
+	m.Query = ""
+	m.Category = ""
+	m.undo, m.redo, m.lastTracked = nil, nil, ""
 	m.buildMainMenu()
 	m.debugf("open state=%s", m.stateName())
 }
@@ -214,7 +248,7 @@ func (m *Menu) InputMode() bool {
 // InputIsMultiline returns true when the active input supports newlines.
 func (m *Menu) InputIsMultiline() bool {
 	switch m.InputState {
-	case InputScriptInit, InputScriptPrePrompt, InputScriptLangDetect, InputScriptVCSDetect:
+	case InputScriptInit, InputScriptPrePrompt, InputScriptLangDetect, InputScriptVCSDetect, InputSnippetBody:
 		return true
 	default:
 		return false
@@ -231,77 +265,6 @@ func (m *Menu) GetInputBuffer() string {
 	return m.InputBuffer
 }
 
-// buildMainMenu builds the main menu items
-func (m *Menu) buildMainMenu() {
-	currentShell := m.Config.Shell.Path
-	if currentShell == "" {
-		currentShell = "(system default)"
-	}
-
-	themeLabel := config.ThemeLabel(m.Config.Theme)
-	promptStyle := m.Config.Prompt.Style
-	if promptStyle == "" {
-		promptStyle = "full"
-	}
-
-	ollamaURL := m.Config.Ollama.URL
-	if ollamaURL == "" {
-		ollamaURL = "(not set)"
-	}
-	ollamaModel := m.Config.Ollama.Model
-	if ollamaModel == "" {
-		ollamaModel = "(not set)"
-	}
-
-	// Get appearance values with defaults
-	cursorStyle := m.Config.Appearance.CursorStyle
-	if cursorStyle == "" {
-		cursorStyle = "block"
-	}
-	panelWidth := m.Config.Appearance.PanelWidthPercent
-	if panelWidth == 0 {
-		panelWidth = 35.0
-	}
-
-	m.Items = []MenuItem{
-		// Shell & Environment
-		{Label: "SHELL & ENVIRONMENT", IsHeader: true},
-		{Label: "Shell: " + currentShell},
-		{Label: "Source RC Files", IsToggle: true, Toggled: m.Config.Shell.SourceRC},
-		{Label: "Scripts..."},
-		{Label: "Commands (" + itoa(len(m.Config.Commands)) + ")..."},
-		{Label: "Aliases (" + itoa(len(m.Config.Aliases)) + ")..."},
-		{Label: "Exports (" + itoa(len(m.Config.Exports)) + ")..."},
-		// Appearance
-		{Label: "APPEARANCE", IsHeader: true},
-		{Label: "Theme: " + themeLabel},
-		{Label: "Font Size: " + formatFloat(m.Config.FontSize)},
-		{Label: "Cursor Style: " + cursorStyle},
-		{Label: "Cursor Blink", IsToggle: true, Toggled: m.Config.Appearance.CursorBlink},
-		{Label: "Panel Width: " + formatFloat(panelWidth) + "%"},
-		{Label: "Prompt Style: " + promptStyle},
-		{Label: "Prompt Options..."},
-		// AI Features
-		{Label: "AI FEATURES", IsHeader: true},
-		{Label: "Web Search", IsToggle: true, Toggled: m.Config.WebSearch.Enabled},
-		{Label: "Reader Proxy", IsToggle: true, Toggled: m.Config.WebSearch.UseReaderProxy},
-		{Label: "Ollama Chat", IsToggle: true, Toggled: m.Config.Ollama.Enabled},
-		{Label: "Ollama URL: " + truncate(ollamaURL, 25)},
-		{Label: "Ollama Model: " + truncate(ollamaModel, 25)},
-		{Label: "Test Ollama Connection"},
-		{Label: "Load Model"},
-		{Label: "Refresh Ollama Models"},
-		{Label: "Ollama Models..."},
-		{Label: "Thinking Mode", IsToggle: true, Toggled: m.Config.Ollama.ThinkingMode},
-		{Label: "Show Thinking", IsToggle: true, Toggled: m.Config.Ollama.ShowThinking},
-		// Actions
-		{Label: "ACTIONS", IsHeader: true},
-		{Label: "Reload Config"},
-		{Label: "Save and Close"},
-		{Label: "Cancel"},
-	}
-}
-
 // buildShellMenu builds the shell selection menu
 func (m *Menu) buildShellMenu() {
 	shells := config.GetAvailableShells()
@@ -373,6 +336,7 @@ func (m *Menu) buildPromptSettingsMenu() {
 		{Label: "Show Hostname", IsToggle: true, Toggled: p.ShowHostname},
 		{Label: "Show Language", IsToggle: true, Toggled: p.ShowLanguage},
 		{Label: "Show VCS", IsToggle: true, Toggled: p.ShowVCS},
+		{Label: "Status Row", IsToggle: true, Toggled: p.StatusRow},
 		{Label: ""},
 		{Label: "Back"},
 	}
@@ -410,6 +374,21 @@ func (m *Menu) buildCommandsMenu() {
 	m.Items = append(m.Items, MenuItem{Label: "Back"})
 }
 
+// buildSnippetsMenu builds the snippets menu
+func (m *Menu) buildSnippetsMenu() {
+	m.Items = []MenuItem{
+		{Label: "+ Add New Snippet"},
+	}
+	for i, snippet := range m.Config.Snippets {
+		m.Items = append(m.Items, MenuItem{
+			Label: snippet.Name + " = " + truncate(escapeNewlines(snippet.Body), 25),
+			Value: itoa(i),
+		})
+	}
+	m.Items = append(m.Items, MenuItem{Label: ""})
+	m.Items = append(m.Items, MenuItem{Label: "Back"})
+}
+
 // buildAliasesMenu builds the aliases menu
 func (m *Menu) buildAliasesMenu() {
 	m.Items = []MenuItem{
@@ -475,6 +454,24 @@ func (m *Menu) buildCommandConfirmMenu() {
 	}
 }
 
+// buildSnippetConfirmMenu builds the snippet confirmation menu
+func (m *Menu) buildSnippetConfirmMenu() {
+	label := "Save Snippet"
+	if m.EditingIndex >= 0 {
+		label = "Save Changes"
+	}
+	m.Items = []MenuItem{
+		{Label: label, Value: "save"},
+		{Label: "Cancel", Value: "cancel"},
+		{Label: ""},
+		{Label: "Name: " + m.PendingName, Disabled: true},
+		{Label: "Snippet: " + truncate(escapeNewlines(m.PendingSnippet), 40), Disabled: true},
+	}
+	if m.PendingDesc != "" {
+		m.Items = append(m.Items, MenuItem{Label: "Description: " + m.PendingDesc, Disabled: true})
+	}
+}
+
 // buildAliasConfirmMenu builds the alias confirmation menu
 func (m *Menu) buildAliasConfirmMenu() {
 	label := "Save Alias"
@@ -515,6 +512,12 @@ func (m *Menu) buildDeleteConfirmMenu() {
 			cmd := m.Config.Commands[m.DeleteIndex]
 			itemLabel = cmd.Name + " = " + truncate(cmd.Command, 30)
 		}
+	case "snippet":
+		typeLabel = "Snippet"
+		if m.DeleteIndex >= 0 && m.DeleteIndex < len(m.Config.Snippets) {
+			snippet := m.Config.Snippets[m.DeleteIndex]
+			itemLabel = snippet.Name + " = " + truncate(escapeNewlines(snippet.Body), 30)
+		}
 	case "alias":
 		typeLabel = "Alias"
 		if val, ok := m.Config.Aliases[m.DeleteTarget]; ok {
@@ -525,6 +528,9 @@ func (m *Menu) buildDeleteConfirmMenu() {
 		if val, ok := m.Config.Exports[m.DeleteTarget]; ok {
 			itemLabel = m.DeleteTarget + " = " + truncate(val, 30)
 		}
+	case "history":
+		typeLabel = "History and Cache"
+		itemLabel = "Directory, search and notification history and the cache"
 	}
 
 	m.Items = []MenuItem{
@@ -532,11 +538,36 @@ func (m *Menu) buildDeleteConfirmMenu() {
 		{Label: ""},
 		{Label: itemLabel, Disabled: true},
 		{Label: ""},
-		{Label: "Yes, Delete", Value: "delete"},
-		{Label: "Cancel", Value: "cancel"},
+		{Label: "Yes, Delete (Y)", Value: "delete"},
+		{Label: "Cancel (N)", Value: "cancel"},
 	}
 }
 
+// HandleConfirmKey answers a delete confirmation with Y or N, reporting
+// false when none is shown or char is neither
+func (m *Menu) HandleConfirmKey(char rune) bool {
+	if m.InputActive || m.State != MenuConfirmDelete {
+		return false
+	}
+	var value string
+	switch char {
+	case 'y', 'Y':
+		value = "delete"
+	case 'n', 'N':
+		value = "cancel"
+	default:
+		return false
+	}
+	for i, item := range m.Items {
+		if item.Value == value {
+			m.SelectedIndex = i
+			m.Select()
+			return true
+		}
+	}
+	return false
+}
+
 // MoveUp moves selection up
 func (m *Menu) MoveUp() {
 	if m.InputActive {
@@ -581,8 +612,24 @@ func (m *Menu) adjustScroll() {
 	}
 }
 
+// SelectAt selects the item at index and activates it, as clicking it
+// does, reporting false when it cannot be selected
+func (m *Menu) SelectAt(index int) bool {
+	if m.InputActive || !m.isSelectable(index) {
+		return false
+	}
+	m.SelectedIndex = index
+	m.Select()
+	return true
+}
+
 // Select handles selection of current item
 func (m *Menu) Select() {
+	m.track("", m.selectItem)
+}
+
+// selectItem acts on the selected item
+func (m *Menu) selectItem() {
 	if m.InputActive || m.SelectedIndex >= len(m.Items) {
 		return
 	}
@@ -594,8 +641,10 @@ func (m *Menu) Select() {
 	m.debugf("select state=%s index=%d label=%q value=%q", m.stateName(), m.SelectedIndex, item.Label, item.Value)
 
 	switch m.State {
-	case MenuMain:
-		m.handleMainSelect()
+	case MenuMain, MenuCategory:
+		m.handleSettingSelect(item)
+	case MenuBackups:
+		m.handleBackupSelect(item)
 	case MenuShellSelect:
 		m.handleShellSelect(item)
 	case MenuThemeSelect:
@@ -610,12 +659,16 @@ func (m *Menu) Select() {
 		m.handleOllamaModelsSelect(item)
 	case MenuCommands:
 		m.handleCommandsSelect(item)
+	case MenuSnippets:
+		m.handleSnippetsSelect(item)
 	case MenuAliases:
 		m.handleAliasesSelect(item)
 	case MenuExports:
 		m.handleExportsSelect(item)
 	case MenuConfirmCommand:
 		m.handleCommandConfirmSelect()
+	case MenuConfirmSnippet:
+		m.handleSnippetConfirmSelect()
 	case MenuConfirmAlias:
 		m.handleAliasConfirmSelect()
 	case MenuConfirmExport:
@@ -627,72 +680,70 @@ func (m *Menu) Select() {
 	}
 }
 
-func (m *Menu) handleMainSelect() {
-	// Menu indices after reorganization with category headers:
-	// 0: SHELL & ENVIRONMENT (header)
-	// 1: Shell, 2: Source RC, 3: Scripts, 4: Commands, 5: Aliases, 6: Exports
-	// 7: APPEARANCE (header)
-	// 8: Theme, 9: Font Size, 10: Cursor Style, 11: Cursor Blink, 12: Panel Width
-	// 13: Prompt Style, 14: Prompt Options
-	// 15: AI FEATURES (header)
-	// 16: Web Search, 17: Reader Proxy, 18: Ollama Chat, 19: Ollama URL, 20: Ollama Model
-	// 21: Test Ollama, 22: Load Model, 23: Refresh Models, 24: Ollama Models
-	// 25: Thinking Mode, 26: Show Thinking
-	// 27: ACTIONS (header)
-	// 28: Reload Config, 29: Save and Close, 30: Cancel
+// handleSettingSelect acts on a setting of the main menu, a category page
+// or the search results
+func (m *Menu) handleSettingSelect(item MenuItem) {
+	if m.State == MenuMain && m.Query != "" && m.SelectedIndex < len(m.hits) {
+		if hit := m.hits[m.SelectedIndex]; hit.Page != MenuCategory {
+			m.jumpTo(hit)
+			return
+		}
+	}
 
-	switch m.SelectedIndex {
-	case 1: // Shell
+	switch item.Value {
+	case "shell":
 		m.navigateTo(MenuShellSelect, m.buildShellMenu)
-	case 2: // Source RC
+	case "source-rc":
 		m.Config.Shell.SourceRC = !m.Config.Shell.SourceRC
-		m.buildMainMenu()
+		m.buildSettingsPage()
 		m.StatusMessage = "Updated (restart tab to apply)"
-	case 3: // Scripts
+	case "scripts":
 		m.navigateTo(MenuScripts, m.buildScriptsMenu)
-	case 4: // Commands
+	case "commands":
 		m.navigateTo(MenuCommands, m.buildCommandsMenu)
-	case 5: // Aliases
+	case "aliases":
 		m.navigateTo(MenuAliases, m.buildAliasesMenu)
-	case 6: // Exports
+	case "exports":
 		m.navigateTo(MenuExports, m.buildExportsMenu)
-	case 8: // Theme
+	case "snippets":
+		m.navigateTo(MenuSnippets, m.buildSnippetsMenu)
+	case "theme":
 		m.navigateTo(MenuThemeSelect, m.buildThemeMenu)
-	case 9: // Font Size
+	case "font-size":
 		m.startInputWithValue(InputFontSize, "Font size (8-32):", formatFloat(m.Config.FontSize))
-	case 10: // Cursor Style
+	case "cursor-style":
 		m.navigateTo(MenuCursorStyle, m.buildCursorStyleMenu)
-	case 11: // Cursor Blink
+	case "cursor-blink":
 		m.Config.Appearance.CursorBlink = !m.Config.Appearance.CursorBlink
-		m.buildMainMenu()
+		m.buildSettingsPage()
 		m.StatusMessage = "Updated (save to persist)"
-	case 12: // Panel Width
+	case "panel-width":
 		pw := m.Config.Appearance.PanelWidthPercent
 		if pw == 0 {
 			pw = 35.0
 		}
 		m.startInputWithValue(InputPanelWidth, "Panel width (25-50%):", formatFloat(pw))
-	case 13: // Prompt Style
+	case "prompt-style":
 		m.navigateTo(MenuPromptStyle, m.buildPromptStyleMenu)
-	case 14: // Prompt Options
+	case "prompt-options":
 		m.navigateTo(MenuPromptSettings, m.buildPromptSettingsMenu)
-	case 16: // Web Search
+	case "web-search":
 		m.Config.WebSearch.Enabled = !m.Config.WebSearch.Enabled
-		m.buildMainMenu()
+		m.buildSettingsPage()
 		m.StatusMessage = "Updated (save to persist)"
-	case 17: // Reader Proxy
+	case "reader-proxy":
 		m.Config.WebSearch.UseReaderProxy = !m.Config.WebSearch.UseReaderProxy
-		m.buildMainMenu()
+		m.buildSettingsPage()
 		m.StatusMessage = "Updated (save to persist)"
-	case 18: // Ollama Chat
+	case "ollama-chat":
 		m.Config.Ollama.Enabled = !m.Config.Ollama.Enabled
-		m.buildMainMenu()
+		m.buildSettingsPage()
 		m.StatusMessage = "Updated (save to persist)"
-	case 19: // Ollama URL
+	case "ollama-url":
 		m.startInputWithValue(InputOllamaURL, "Ollama base URL:", m.Config.Ollama.URL)
-	case 20: // Ollama Model
+	case "ollama-model":
 		m.startInputWithValue(InputOllamaModel, "Ollama model name:", m.Config.Ollama.Model)
-	case 21: // Test Ollama Connection
+	case "ollama-test":
 		if m.OnOllamaTest == nil {
 			m.StatusMessage = "Ollama test unavailable"
 			return
@@ -702,7 +753,7 @@ func (m *Menu) handleMainSelect() {
 			return
 		}
 		m.StatusMessage = "Ollama connection OK"
-	case 22: // Load Model
+	case "ollama-load":
 		if m.OnOllamaLoadModel == nil {
 			m.StatusMessage = "Ollama load unavailable"
 			return
@@ -713,7 +764,7 @@ func (m *Menu) handleMainSelect() {
 		}
 		m.OnOllamaLoadModel(m.Config.Ollama.URL, m.Config.Ollama.Model)
 		m.StatusMessage = "Loading model..."
-	case 23: // Refresh Ollama Models
+	case "ollama-refresh":
 		if m.OnOllamaFetchModels == nil {
 			m.StatusMessage = "Ollama fetch unavailable"
 			return
@@ -729,17 +780,17 @@ func (m *Menu) handleMainSelect() {
 			return
 		}
 		m.StatusMessage = "Models loaded (" + itoa(len(models)) + ")"
-	case 24: // Ollama Models
+	case "ollama-models":
 		m.navigateTo(MenuOllamaModels, m.buildOllamaModelsMenu)
-	case 25: // Thinking Mode
+	case "thinking-mode":
 		m.Config.Ollama.ThinkingMode = !m.Config.Ollama.ThinkingMode
-		m.buildMainMenu()
+		m.buildSettingsPage()
 		m.StatusMessage = "Updated (save to persist)"
-	case 26: // Show Thinking
+	case "show-thinking":
 		m.Config.Ollama.ShowThinking = !m.Config.Ollama.ShowThinking
-		m.buildMainMenu()
+		m.buildSettingsPage()
 		m.StatusMessage = "Updated (save to persist)"
-	case 28: // Reload Config
+	case "reload":
 		cfg, err := config.Load()
 		if err != nil {
 			m.StatusMessage = "Failed to reload config"
@@ -756,26 +807,79 @@ func (m *Menu) handleMainSelect() {
 			}
 		}
 		m.Config = cfg
-		m.buildMainMenu()
+		m.buildSettingsPage()
 		if m.StatusMessage == "" {
 			m.StatusMessage = "Config reloaded"
 		}
-	case 29: // Save and Close
+	case "backups":
+		m.navigateTo(MenuBackups, m.buildBackupsMenu)
+	case "export-config":
+		m.startInputWithValue(InputArchiveExport, "Export config to:", config.DefaultArchivePath())
+	case "import-merge":
+		m.startInputWithValue(InputArchiveMerge, "Merge config entries from:", config.DefaultArchivePath())
+	case "import-replace":
+		m.startInputWithValue(InputArchiveReplace, "Replace all settings with:", config.DefaultArchivePath())
+	case "clear-history":
+		if m.OnClearHistory == nil {
+			m.StatusMessage = "Clearing unavailable"
+			return
+		}
+		m.DeleteType = "history"
+		m.DeleteTarget = ""
+		m.DeleteIndex = -1
+		m.navigateTo(MenuConfirmDelete, m.buildDeleteConfirmMenu)
+	case "diagnostics":
+		if m.OnCreateDiagnostics == nil {
+			m.StatusMessage = "Diagnostics unavailable"
+			return
+		}
+		path, err := m.OnCreateDiagnostics()
+		if err != nil {
+			m.StatusMessage = "Diagnostics failed: " + err.Error()
+			return
+		}
+		m.StatusMessage = "Saved " + path
+	case "shell-integration":
+		shell := m.Config.Shell.Path
+		if shell == "" {
+			shell = os.Getenv("SHELL")
+		}
+		name := integration.Detect(shell)
+		if name == "" {
+			m.StatusMessage = "Shell integration supports " + strings.Join(integration.Shells, ", ")
+			return
+		}
+		result, err := integration.Install(name)
+		switch {
+		case err != nil:
+			m.StatusMessage = "Install failed: " + err.Error()
+		case !result.Changed:
+			m.StatusMessage = "Already installed in " + result.Path
+		default:
+			m.StatusMessage = "Installed in " + result.Path + " (new shells)"
+		}
+	case "save":
 		if !m.saveConfigWithInitScript("Saved") {
-			m.buildMainMenu()
+			m.buildSettingsPage()
 			return
 		}
 		if m.OnConfigReload != nil {
 			if err := m.OnConfigReload(m.Config); err != nil {
 				m.StatusMessage = "Saved (apply failed)"
-				m.buildMainMenu()
+				m.buildSettingsPage()
 				return
 			}
 		}
 		m.Close()
-	case 30: // Cancel
+	case "cancel":
 		m.Config, _ = config.Load()
 		m.Close()
+	case "back":
+		m.goBack()
+	default:
+		if category, ok := strings.CutPrefix(item.Value, "category:"); ok {
+			m.openCategory(category)
+		}
 	}
 }
 
@@ -837,7 +941,13 @@ func (m *Menu) handlePromptSettingsSelect() {
 		m.Config.Prompt.ShowLanguage = !m.Config.Prompt.ShowLanguage
 	case 4:
 		m.Config.Prompt.ShowVCS = !m.Config.Prompt.ShowVCS
-	case 6:
+	case 5:
+		// Drawn by the terminal, so it changes without restarting the tab
+		m.Config.Prompt.StatusRow = !m.Config.Prompt.StatusRow
+		m.buildPromptSettingsMenu()
+		m.StatusMessage = "Updated (save to persist)"
+		return
+	case 7:
 		m.goBack()
 		return
 	}
@@ -898,6 +1008,27 @@ func (m *Menu) handleCommandsSelect(item MenuItem) {
 	}
 }
 
+func (m *Menu) handleSnippetsSelect(item MenuItem) {
+	if item.Label == "Back" {
+		m.goBack()
+		return
+	}
+	if m.SelectedIndex == 0 { // Add new
+		m.EditingIndex = -1
+		m.PendingName = ""
+		m.PendingSnippet = ""
+		m.PendingDesc = ""
+		m.startInputWithValue(InputSnippetName, "Snippet name:", "")
+	} else if item.Value != "" { // Edit existing
+		idx := atoi(item.Value)
+		if idx >= 0 && idx < len(m.Config.Snippets) {
+			m.EditingIndex = idx
+			m.PendingName = m.Config.Snippets[idx].Name
+			m.startInputWithValue(InputSnippetName, "Snippet name:", m.PendingName)
+		}
+	}
+}
+
 func (m *Menu) handleAliasesSelect(item MenuItem) {
 	if item.Label == "Back" {
 		m.goBack()
@@ -946,17 +1077,19 @@ func (m *Menu) startInputWithValue(state InputState, label string, initialValue
 	m.InputState = state
 	m.InputLabel = label
 	m.InputBuffer = initialValue
+	m.InputCursor = len([]rune(initialValue))
+	m.InputAnchor = m.InputCursor
 }
 
-// HandleChar handles character input
+// HandleChar types a character at the input cursor
 func (m *Menu) HandleChar(char rune) {
 	if !m.InputActive {
 		return
 	}
-	m.InputBuffer += string(char)
+	m.insertInput(string(char))
 }
 
-// HandlePaste appends clipboard text to the input buffer.
+// HandlePaste inserts clipboard text at the input cursor.
 func (m *Menu) HandlePaste(text string) {
 	if !m.InputActive || text == "" {
 		return
@@ -966,21 +1099,29 @@ func (m *Menu) HandlePaste(text string) {
 	if !m.InputIsMultiline() {
 		text = strings.ReplaceAll(text, "\n", " ")
 	}
-	m.InputBuffer += text
+	m.insertInput(text)
 }
 
-// HandleBackspace handles backspace
+// HandleBackspace deletes the selection or the character before the cursor,
+// or the last character of the search on the main menu
 func (m *Menu) HandleBackspace() {
-	if !m.InputActive || len(m.InputBuffer) == 0 {
+	if !m.InputActive && m.State == MenuMain && m.Query != "" {
+		query := []rune(m.Query)
+		m.SetQuery(string(query[:len(query)-1]))
 		return
 	}
-	// Remove last character (handle UTF-8)
-	runes := []rune(m.InputBuffer)
-	m.InputBuffer = string(runes[:len(runes)-1])
+	m.DeleteInput(false, false)
 }
 
 // HandleEnter handles enter key - returns true if menu should close
 func (m *Menu) HandleEnter() bool {
+	closed := false
+	m.track("", func() { closed = m.handleEnter() })
+	return closed
+}
+
+// handleEnter stores the input in the setting it was started for
+func (m *Menu) handleEnter() bool {
 	if !m.InputActive {
 		return false
 	}
@@ -1025,6 +1166,41 @@ func (m *Menu) HandleEnter() bool {
 		m.SelectedIndex = m.firstSelectableIndex()
 		m.debugf("confirm command name=%q cmd=%q desc=%q", m.PendingName, m.PendingCmd, m.PendingDesc)
 
+	case InputSnippetName:
+		if value == "" {
+			m.InputState = InputNone
+			m.buildSnippetsMenu()
+			return false
+		}
+		m.PendingName = value
+		initialBody := ""
+		if m.EditingIndex >= 0 {
+			initialBody = m.Config.Snippets[m.EditingIndex].Body
+		}
+		m.startInputWithValue(InputSnippetBody, "Snippet text (${name} or ${name:default} placeholders):", initialBody)
+
+	case InputSnippetBody:
+		if value == "" {
+			m.InputState = InputNone
+			m.buildSnippetsMenu()
+			return false
+		}
+		m.PendingSnippet = value
+		initialDesc := ""
+		if m.EditingIndex >= 0 {
+			initialDesc = m.Config.Snippets[m.EditingIndex].Description
+		}
+		m.startInputWithValue(InputSnippetDesc, "Description (optional):", initialDesc)
+
+	case InputSnippetDesc:
+		m.PendingDesc = value
+		m.State = MenuConfirmSnippet
+		m.SelectedIndex = 0
+		m.ScrollOffset = 0
+		m.buildSnippetConfirmMenu()
+		m.SelectedIndex = m.firstSelectableIndex()
+		m.debugf("confirm snippet name=%q desc=%q", m.PendingName, m.PendingDesc)
+
 	case InputAliasName:
 		if value == "" {
 			m.InputState = InputNone
@@ -1103,29 +1279,29 @@ func (m *Menu) HandleEnter() bool {
 		m.Config.Ollama.URL = strings.TrimSpace(value)
 		m.OllamaModels = nil
 		m.StatusMessage = "Ollama URL updated (save to persist)"
-		m.buildMainMenu()
+		m.buildSettingsPage()
 
 	case InputOllamaModel:
 		m.Config.Ollama.Model = strings.TrimSpace(value)
 		m.StatusMessage = "Ollama model updated (save to persist)"
-		m.buildMainMenu()
+		m.buildSettingsPage()
 
 	case InputFontSize:
 		parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 32)
 		if err != nil {
 			m.StatusMessage = "Invalid font size"
-			m.buildMainMenu()
+			m.buildSettingsPage()
 			break
 		}
 		m.Config.FontSize = float32(parsed)
 		m.StatusMessage = "Font size updated (save to persist)"
-		m.buildMainMenu()
+		m.buildSettingsPage()
 
 	case InputPanelWidth:
 		parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 32)
 		if err != nil {
 			m.StatusMessage = "Invalid panel width"
-			m.buildMainMenu()
+			m.buildSettingsPage()
 			break
 		}
 		// Clamp to valid range
@@ -1137,7 +1313,32 @@ func (m *Menu) HandleEnter() bool {
 		}
 		m.Config.Appearance.PanelWidthPercent = pw
 		m.StatusMessage = "Panel width updated (save to persist)"
-		m.buildMainMenu()
+		m.buildSettingsPage()
+
+	case InputArchiveExport:
+		path := expandHome(strings.TrimSpace(value))
+		if err := m.Config.ExportArchive(path); err != nil {
+			m.StatusMessage = "Export failed: " + err.Error()
+		} else {
+			m.StatusMessage = "Exported to " + path
+		}
+		m.buildSettingsPage()
+
+	case InputArchiveMerge, InputArchiveReplace:
+		archive, err := config.ReadArchive(expandHome(strings.TrimSpace(value)))
+		if err != nil {
+			m.StatusMessage = "Import failed: " + err.Error()
+			m.buildSettingsPage()
+			break
+		}
+		if m.InputState == InputArchiveReplace {
+			m.Config = archive.Config
+			m.StatusMessage = "Settings replaced (save to persist)"
+		} else {
+			m.StatusMessage = "Merged " + itoa(m.Config.Merge(archive.Config)) + " entries (save to persist)"
+		}
+		m.OllamaModels = nil
+		m.buildSettingsPage()
 	}
 
 	if !m.InputActive {
@@ -1152,11 +1353,14 @@ func (m *Menu) HandleEscape() {
 		m.InputActive = false
 		m.InputState = InputNone
 		m.InputBuffer = ""
+		m.InputCursor, m.InputAnchor = 0, 0
 		m.debugf("escape input state=%s", m.stateName())
 		// Rebuild current menu
 		switch m.State {
 		case MenuCommands:
 			m.buildCommandsMenu()
+		case MenuSnippets:
+			m.buildSnippetsMenu()
 		case MenuAliases:
 			m.buildAliasesMenu()
 		case MenuScripts:
@@ -1170,6 +1374,24 @@ func (m *Menu) HandleEscape() {
 	m.goBack()
 }
 
+// RunSelected runs the selected custom command through OnRunCommand and
+// closes the menu, reporting whether a command was selected
+func (m *Menu) RunSelected() bool {
+	if m.InputActive || m.State != MenuCommands || m.OnRunCommand == nil {
+		return false
+	}
+	if m.SelectedIndex < 1 || m.SelectedIndex > len(m.Config.Commands) {
+		return false
+	}
+	cmd := m.Config.Commands[m.SelectedIndex-1] // Offset for "Add New" item
+	if err := m.OnRunCommand(cmd); err != nil {
+		m.StatusMessage = "Run failed: " + err.Error()
+		return true
+	}
+	m.Close()
+	return true
+}
+
 // HandleDelete handles delete key for removing items
 func (m *Menu) HandleDelete() {
 	if m.InputActive {
@@ -1189,6 +1411,17 @@ func (m *Menu) HandleDelete() {
 			m.SelectedIndex = m.firstSelectableIndex()
 			m.ScrollOffset = 0
 		}
+	case MenuSnippets:
+		if m.SelectedIndex > 0 && m.SelectedIndex <= len(m.Config.Snippets) {
+			m.DeleteType = "snippet"
+			m.DeleteIndex = m.SelectedIndex - 1 // Offset for "Add New" item
+			m.DeleteTarget = ""
+			m.savePosition()
+			m.State = MenuConfirmDelete
+			m.buildDeleteConfirmMenu()
+			m.SelectedIndex = m.firstSelectableIndex()
+			m.ScrollOffset = 0
+		}
 	case MenuAliases:
 		if m.SelectedIndex > 0 {
 			item := m.Items[m.SelectedIndex]
@@ -1233,6 +1466,12 @@ func (m *Menu) handleDeleteConfirmSelect() {
 				m.StatusMessage = "Command deleted"
 			}
 			m.navigateTo(MenuCommands, m.buildCommandsMenu)
+		case "snippet":
+			m.Config.RemoveSnippet(m.DeleteIndex)
+			if m.saveConfig() {
+				m.StatusMessage = "Snippet deleted"
+			}
+			m.navigateTo(MenuSnippets, m.buildSnippetsMenu)
 		case "alias":
 			m.Config.RemoveAlias(m.DeleteTarget)
 			_ = m.saveConfigWithInitScript("Alias deleted")
@@ -1241,6 +1480,13 @@ func (m *Menu) handleDeleteConfirmSelect() {
 			m.Config.RemoveExport(m.DeleteTarget)
 			_ = m.saveConfigWithInitScript("Export deleted")
 			m.navigateTo(MenuExports, m.buildExportsMenu)
+		case "history":
+			if err := m.OnClearHistory(); err != nil {
+				m.StatusMessage = "Clear failed: " + err.Error()
+			} else {
+				m.StatusMessage = "History and cache cleared"
+			}
+			m.navigateTo(m.settingsPage(), m.buildSettingsPage)
 		}
 		// Adjust selection if needed
 		if m.SelectedIndex >= len(m.Items) {
@@ -1251,10 +1497,14 @@ func (m *Menu) handleDeleteConfirmSelect() {
 		switch m.DeleteType {
 		case "command":
 			m.navigateTo(MenuCommands, m.buildCommandsMenu)
+		case "snippet":
+			m.navigateTo(MenuSnippets, m.buildSnippetsMenu)
 		case "alias":
 			m.navigateTo(MenuAliases, m.buildAliasesMenu)
 		case "export":
 			m.navigateTo(MenuExports, m.buildExportsMenu)
+		case "history":
+			m.navigateTo(m.settingsPage(), m.buildSettingsPage)
 		}
 	}
 	// Clear delete tracking
@@ -1266,13 +1516,28 @@ func (m *Menu) handleDeleteConfirmSelect() {
 // goBack goes back to previous menu
 func (m *Menu) goBack() {
 	switch m.State {
-	case MenuShellSelect, MenuThemeSelect, MenuPromptStyle, MenuPromptSettings, MenuScripts, MenuOllamaModels, MenuCommands, MenuAliases, MenuExports, MenuCursorStyle:
+	case MenuShellSelect, MenuThemeSelect, MenuPromptStyle, MenuPromptSettings, MenuScripts, MenuOllamaModels, MenuCommands, MenuSnippets, MenuAliases, MenuExports, MenuCursorStyle, MenuBackups:
+		m.navigateTo(m.settingsPage(), m.buildSettingsPage)
+		m.debugf("go back to settings state=%s", m.stateName())
+	case MenuCategory:
+		m.Category = ""
 		m.navigateTo(MenuMain, m.buildMainMenu)
 		m.debugf("go back to main")
+	case MenuMain:
+		if m.Query == "" {
+			m.Close()
+			return
+		}
+		m.SetQuery("")
+		m.debugf("clear search")
 	case MenuConfirmCommand:
 		m.clearPendingCommand()
 		m.navigateTo(MenuCommands, m.buildCommandsMenu)
 		m.debugf("go back to commands")
+	case MenuConfirmSnippet:
+		m.clearPendingSnippet()
+		m.navigateTo(MenuSnippets, m.buildSnippetsMenu)
+		m.debugf("go back to snippets")
 	case MenuConfirmAlias:
 		m.clearPendingAlias()
 		m.navigateTo(MenuAliases, m.buildAliasesMenu)
@@ -1286,10 +1551,14 @@ func (m *Menu) goBack() {
 		switch m.DeleteType {
 		case "command":
 			m.navigateTo(MenuCommands, m.buildCommandsMenu)
+		case "snippet":
+			m.navigateTo(MenuSnippets, m.buildSnippetsMenu)
 		case "alias":
 			m.navigateTo(MenuAliases, m.buildAliasesMenu)
 		case "export":
 			m.navigateTo(MenuExports, m.buildExportsMenu)
+		case "history":
+			m.navigateTo(m.settingsPage(), m.buildSettingsPage)
 		default:
 			m.navigateTo(MenuMain, m.buildMainMenu)
 		}
@@ -1307,6 +1576,10 @@ func (m *Menu) GetTitle() string {
 	switch m.State {
 	case MenuMain:
 		return "Settings"
+	case MenuCategory:
+		return "Settings > " + m.Category
+	case MenuBackups:
+		return "Restore Previous Config"
 	case MenuShellSelect:
 		return "Select Shell"
 	case MenuThemeSelect:
@@ -1321,12 +1594,16 @@ func (m *Menu) GetTitle() string {
 		return "Ollama Models"
 	case MenuCommands:
 		return "Commands"
+	case MenuSnippets:
+		return "Snippets"
 	case MenuAliases:
 		return "Aliases"
 	case MenuExports:
 		return "Exports"
 	case MenuConfirmCommand:
 		return "Confirm Command"
+	case MenuConfirmSnippet:
+		return "Confirm Snippet"
 	case MenuConfirmAlias:
 		return "Confirm Alias"
 	case MenuConfirmExport:
@@ -1366,6 +1643,32 @@ func (m *Menu) handleCommandConfirmSelect() {
 	}
 }
 
+func (m *Menu) handleSnippetConfirmSelect() {
+	item := m.Items[m.SelectedIndex]
+	m.debugf("confirm snippet select value=%q", item.Value)
+	switch item.Value {
+	case "save":
+		if m.EditingIndex >= 0 {
+			m.Config.Snippets[m.EditingIndex].Name = m.PendingName
+			m.Config.Snippets[m.EditingIndex].Body = m.PendingSnippet
+			m.Config.Snippets[m.EditingIndex].Description = m.PendingDesc
+			if m.saveConfig() {
+				m.StatusMessage = "Snippet updated"
+			}
+		} else {
+			m.Config.AddSnippet(m.PendingName, m.PendingSnippet, m.PendingDesc)
+			if m.saveConfig() {
+				m.StatusMessage = "Snippet added"
+			}
+		}
+		m.clearPendingSnippet()
+		m.navigateTo(MenuSnippets, m.buildSnippetsMenu)
+	case "cancel":
+		m.clearPendingSnippet()
+		m.navigateTo(MenuSnippets, m.buildSnippetsMenu)
+	}
+}
+
 func (m *Menu) handleAliasConfirmSelect() {
 	item := m.Items[m.SelectedIndex]
 	m.debugf("confirm alias select value=%q", item.Value)
@@ -1409,6 +1712,13 @@ func (m *Menu) clearPendingCommand() {
 	m.EditingIndex = -1
 }
 
+func (m *Menu) clearPendingSnippet() {
+	m.PendingName = ""
+	m.PendingSnippet = ""
+	m.PendingDesc = ""
+	m.EditingIndex = -1
+}
+
 func (m *Menu) clearPendingAlias() {
 	m.PendingName = ""
 	m.PendingAliasCmd = ""
@@ -1424,6 +1734,12 @@ func (m *Menu) clearPendingExport() {
 }
 
 func (m *Menu) saveConfig() bool {
+	// Keep the settings being replaced, in case the new ones break input
+	if path, err := config.BackupConfig(time.Now()); err != nil {
+		m.debugf("backup error: %v", err)
+	} else if path != "" {
+		m.debugf("backup saved path=%s", path)
+	}
 	if err := m.Config.Save(); err != nil {
 		m.StatusMessage = "Error: " + err.Error()
 		m.debugf("save error: %v", err)
@@ -1481,10 +1797,7 @@ func (m *Menu) firstSelectableIndex() int {
 }
 
 func (m *Menu) debugf(format string, args ...interface{}) {
-	if !debugMenu {
-		return
-	}
-	log.Printf("menu: "+format, args...)
+	logging.Debugf(logging.Menu, format, args...)
 }
 
 func (m *Menu) stateName() string {
@@ -1493,6 +1806,10 @@ func (m *Menu) stateName() string {
 		return "closed"
 	case MenuMain:
 		return "main"
+	case MenuCategory:
+		return "category"
+	case MenuBackups:
+		return "backups"
 	case MenuShellSelect:
 		return "shell"
 	case MenuThemeSelect:
@@ -1507,12 +1824,16 @@ func (m *Menu) stateName() string {
 		return "ollama_models"
 	case MenuCommands:
 		return "commands"
+	case MenuSnippets:
+		return "snippets"
 	case MenuAliases:
 		return "aliases"
 	case MenuExports:
 		return "exports"
 	case MenuConfirmCommand:
 		return "confirm_command"
+	case MenuConfirmSnippet:
+		return "confirm_snippet"
 	case MenuConfirmAlias:
 		return "confirm_alias"
 	case MenuConfirmExport:
@@ -1560,6 +1881,18 @@ func (m *Menu) inputStateName() string {
 		return "font_size"
 	case InputPanelWidth:
 		return "panel_width"
+	case InputSnippetName:
+		return "snippet_name"
+	case InputSnippetBody:
+		return "snippet_body"
+	case InputSnippetDesc:
+		return "snippet_desc"
+	case InputArchiveExport:
+		return "archive_export"
+	case InputArchiveMerge:
+		return "archive_merge"
+	case InputArchiveReplace:
+		return "archive_replace"
 	default:
 		return "unknown"
 	}
@@ -1639,6 +1972,16 @@ func formatFloat(f float32) string {
 	return strconv.FormatFloat(float64(f), 'f', -1, 32)
 }
 
+// expandHome replaces a leading ~/ with the user's home directory
+func expandHome(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return home + path[1:]
+		}
+	}
+	return path
+}
+
 func escapeNewlines(s string) string {
 	result := ""
 	for _, c := range s {
@@ -0,0 +1,280 @@
+package menu
+
+import (
+	"strings"
+
+	"github.com/javanhut/RavenTerminal/src/config"
+)
+
+// Categories are the settings pages listed on the main menu, in order
+var Categories = []string{"Appearance", "Input", "Panels", "AI", "Advanced"}
+
+// setting is one item of the settings pages. Items of a category page carry
+// the key handleSettingSelect acts on in Value; items of the pages they
+// lead to are found by search at Index on Page.
+type setting struct {
+	Category string
+	Item     MenuItem
+	Page     MenuState // MenuCategory, or the page holding the item
+	Index    int
+	build    func(*Menu)
+}
+
+// searchPages are the pages below the categories whose items search finds
+var searchPages = []struct {
+	category string
+	title    string
+	state    MenuState
+	build    func(*Menu)
+}{
+	{"Appearance", "Prompt Options", MenuPromptSettings, (*Menu).buildPromptSettingsMenu},
+	{"Advanced", "Scripts", MenuScripts, (*Menu).buildScriptsMenu},
+}
+
+// settings lists every setting with its current value
+func (m *Menu) settings() []setting {
+	currentShell := m.Config.Shell.Path
+	if currentShell == "" {
+		currentShell = "(system default)"
+	}
+
+	var themes, themeLabels []string
+	for _, opt := range config.ThemeOptions() {
+		themes = append(themes, opt.Name)
+		themeLabels = append(themeLabels, opt.Label)
+	}
+	promptStyle := m.Config.Prompt.Style
+	if promptStyle == "" {
+		promptStyle = "full"
+	}
+
+	ollamaURL := m.Config.Ollama.URL
+	if ollamaURL == "" {
+		ollamaURL = "(not set)"
+	}
+	ollamaModel := m.Config.Ollama.Model
+	if ollamaModel == "" {
+		ollamaModel = "(not set)"
+	}
+
+	// Get appearance values with defaults
+	cursorStyle := m.Config.Appearance.CursorStyle
+	if cursorStyle == "" {
+		cursorStyle = "block"
+	}
+	panelWidth := m.Config.Appearance.PanelWidthPercent
+	if panelWidth == 0 {
+		panelWidth = 35.0
+	}
+	uiScale := m.Config.Appearance.UIScale
+	if uiScale == 0 {
+		uiScale = 1.0
+	}
+	aiPanelSize := m.Config.Appearance.AIPanelSize
+	if aiPanelSize == 0 {
+		aiPanelSize = 35.0
+	}
+
+	var list []setting
+	add := func(category string, items ...MenuItem) {
+		for _, item := range items {
+			list = append(list, setting{Category: category, Item: item, Page: MenuCategory})
+		}
+	}
+	add("Appearance",
+		MenuItem{Label: "Theme", Value: "theme", Dropdown: newDropdown(themes, themeLabels, m.Config.Theme)},
+		MenuItem{Label: "Font Size", Value: "font-size", Slider: &Slider{Value: float64(m.Config.FontSize), Min: 8, Max: 32, Step: 0.5}},
+		MenuItem{Label: "UI Scale", Value: "ui-scale", Slider: &Slider{Value: float64(uiScale), Min: 0.5, Max: 3, Step: 0.1, Unit: "x"}},
+		MenuItem{Label: "Cursor Style", Value: "cursor-style", Dropdown: newDropdown([]string{"block", "underline", "bar"}, nil, cursorStyle)},
+		MenuItem{Label: "Cursor Blink", Value: "cursor-blink", IsToggle: true, Toggled: m.Config.Appearance.CursorBlink},
+		MenuItem{Label: "Prompt Style", Value: "prompt-style", Dropdown: newDropdown([]string{"minimal", "simple", "full", "custom"}, nil, promptStyle)},
+		MenuItem{Label: "Prompt Options...", Value: "prompt-options"},
+	)
+	add("Input",
+		MenuItem{Label: "Commands (" + itoa(len(m.Config.Commands)) + ")...", Value: "commands"},
+		MenuItem{Label: "Aliases (" + itoa(len(m.Config.Aliases)) + ")...", Value: "aliases"},
+		MenuItem{Label: "Snippets (" + itoa(len(m.Config.Snippets)) + ")...", Value: "snippets"},
+		MenuItem{Label: "Chord Timeout", Value: "chord-timeout", Slider: &Slider{Value: float64(m.Config.Keys.ChordTimeout), Min: 250, Max: 5000, Step: 250, Unit: "ms"}},
+	)
+	add("Panels",
+		MenuItem{Label: "Panel Width", Value: "panel-width", Slider: &Slider{Value: float64(panelWidth), Min: 25, Max: 50, Step: 1, Unit: "%"}},
+		MenuItem{Label: "AI Panel Size", Value: "ai-panel-size", Slider: &Slider{Value: float64(aiPanelSize), Min: 20, Max: 80, Step: 5, Unit: "%"}},
+		MenuItem{Label: "Web Search", Value: "web-search", IsToggle: true, Toggled: m.Config.WebSearch.Enabled},
+		MenuItem{Label: "Reader Proxy", Value: "reader-proxy", IsToggle: true, Toggled: m.Config.WebSearch.UseReaderProxy},
+	)
+	add("AI",
+		MenuItem{Label: "Ollama Chat", Value: "ollama-chat", IsToggle: true, Toggled: m.Config.Ollama.Enabled},
+		MenuItem{Label: "Ollama URL: " + truncate(ollamaURL, 25), Value: "ollama-url"},
+		MenuItem{Label: "Ollama Model: " + truncate(ollamaModel, 25), Value: "ollama-model"},
+		MenuItem{Label: "Test Ollama Connection", Value: "ollama-test"},
+		MenuItem{Label: "Load Model", Value: "ollama-load"},
+		MenuItem{Label: "Refresh Ollama Models", Value: "ollama-refresh"},
+		MenuItem{Label: "Ollama Models...", Value: "ollama-models"},
+		MenuItem{Label: "Thinking Mode", Value: "thinking-mode", IsToggle: true, Toggled: m.Config.Ollama.ThinkingMode},
+		MenuItem{Label: "Show Thinking", Value: "show-thinking", IsToggle: true, Toggled: m.Config.Ollama.ShowThinking},
+		MenuItem{Label: "Ollama Timeout", Value: "ollama-timeout", Slider: &Slider{Value: float64(m.Config.Network.OllamaTimeout), Max: 900, Step: 30, Unit: "s", Zero: "default"}},
+		MenuItem{Label: "Thinking Timeout", Value: "thinking-timeout", Slider: &Slider{Value: float64(m.Config.Ollama.ExtendedTimeout), Max: 1800, Step: 60, Unit: "s", Zero: "default"}},
+	)
+	add("Advanced",
+		MenuItem{Label: "Shell: " + currentShell, Value: "shell"},
+		MenuItem{Label: "Source RC Files", Value: "source-rc", IsToggle: true, Toggled: m.Config.Shell.SourceRC},
+		MenuItem{Label: "Scripts...", Value: "scripts"},
+		MenuItem{Label: "Exports (" + itoa(len(m.Config.Exports)) + ")...", Value: "exports"},
+		MenuItem{Label: "Connect Timeout", Value: "connect-timeout", Slider: &Slider{Value: float64(m.Config.Network.ConnectTimeout), Max: 120, Step: 5, Unit: "s", Zero: "default"}},
+		MenuItem{Label: "Search Timeout", Value: "search-timeout", Slider: &Slider{Value: float64(m.Config.Network.SearchTimeout), Max: 60, Step: 5, Unit: "s", Zero: "default"}},
+		MenuItem{Label: "Scrollback Memory Cap", Value: "memory-cap", Slider: &Slider{Value: float64(m.Config.Terminal.MemoryCapMB), Max: 8192, Step: 256, Unit: " MB", Zero: "no cap"}},
+		MenuItem{Label: "Rewind Buffer", Value: "replay-buffer", Slider: &Slider{Value: float64(m.Config.Terminal.ReplayBufferKB), Max: 16384, Step: 512, Unit: " KB", Zero: "off"}},
+		MenuItem{Label: "Reload Config", Value: "reload"},
+		MenuItem{Label: "Restore Previous Config...", Value: "backups"},
+		MenuItem{Label: "Export Config...", Value: "export-config"},
+		MenuItem{Label: "Import Config (Merge)...", Value: "import-merge"},
+		MenuItem{Label: "Import Config (Replace)...", Value: "import-replace"},
+		MenuItem{Label: "Clear History and Cache", Value: "clear-history"},
+		MenuItem{Label: "Create Diagnostics Bundle", Value: "diagnostics"},
+		MenuItem{Label: "Install Shell Integration", Value: "shell-integration"},
+	)
+
+	// The pages a category leads to are built only to be searched
+	items := m.Items
+	for _, page := range searchPages {
+		page.build(m)
+		for i, item := range m.Items {
+			if item.Label == "" || item.IsHeader || item.Label == "Back" {
+				continue
+			}
+			item.Label = page.title + " > " + item.Label
+			list = append(list, setting{Category: page.category, Item: item, Page: page.state, Index: i, build: page.build})
+		}
+	}
+	m.Items = items
+	return list
+}
+
+// buildMainMenu builds the main menu items: the categories, or the settings
+// matching the search query
+func (m *Menu) buildMainMenu() {
+	if m.Query != "" {
+		m.buildSearchResults()
+		return
+	}
+	m.Items = nil
+	for _, category := range Categories {
+		m.Items = append(m.Items, MenuItem{Label: category + "...", Value: "category:" + category})
+	}
+	m.Items = append(m.Items,
+		MenuItem{Label: ""},
+		MenuItem{Label: "Save and Close", Value: "save"},
+		MenuItem{Label: "Cancel", Value: "cancel"},
+	)
+}
+
+// buildSearchResults lists the settings of every category that match the
+// query, under a header for each category
+func (m *Menu) buildSearchResults() {
+	words := strings.Fields(strings.ToLower(m.Query))
+	all := m.settings()
+	m.Items, m.hits = nil, nil
+	for _, category := range Categories {
+		found := false
+		for _, s := range all {
+			if s.Category != category || !fuzzyMatch(strings.ToLower(s.Item.Label), words) {
+				continue
+			}
+			if !found {
+				m.Items = append(m.Items, MenuItem{Label: strings.ToUpper(category), IsHeader: true})
+				m.hits = append(m.hits, setting{})
+				found = true
+			}
+			m.Items = append(m.Items, s.Item)
+			m.hits = append(m.hits, s)
+		}
+	}
+	if len(m.Items) == 0 {
+		m.Items = []MenuItem{{Label: "No settings match", Disabled: true}}
+		m.hits = []setting{{}}
+	}
+}
+
+// buildCategoryMenu builds the page of the open category
+func (m *Menu) buildCategoryMenu() {
+	m.Items = nil
+	for _, s := range m.settings() {
+		if s.Category == m.Category && s.Page == MenuCategory {
+			m.Items = append(m.Items, s.Item)
+		}
+	}
+	m.Items = append(m.Items, MenuItem{Label: ""}, MenuItem{Label: "Back", Value: "back"})
+}
+
+// buildSettingsPage rebuilds the main menu or category page being shown,
+// after one of its settings changed
+func (m *Menu) buildSettingsPage() {
+	if m.State == MenuCategory {
+		m.buildCategoryMenu()
+	} else {
+		m.buildMainMenu()
+	}
+	if !m.isNavigable(m.SelectedIndex) {
+		m.SelectedIndex = m.firstSelectableIndex()
+	}
+}
+
+// settingsPage is the page a page below the settings returns to: the
+// category it was opened from, or the main menu and its search results
+func (m *Menu) settingsPage() MenuState {
+	if m.Category != "" {
+		return MenuCategory
+	}
+	return MenuMain
+}
+
+// openCategory shows the settings of category
+func (m *Menu) openCategory(category string) {
+	m.Category = category
+	delete(m.savedIndex, MenuCategory)
+	delete(m.savedScroll, MenuCategory)
+	m.navigateTo(MenuCategory, m.buildCategoryMenu)
+}
+
+// jumpTo opens the page holding a setting found by search, with the
+// setting selected
+func (m *Menu) jumpTo(s setting) {
+	m.navigateTo(s.Page, func() { s.build(m) })
+	m.SelectedIndex = s.Index
+	m.adjustScroll()
+}
+
+// HandleSearchChar types char into the search box of the main menu,
+// reporting false when the menu shows no search box
+func (m *Menu) HandleSearchChar(char rune) bool {
+	if m.InputActive || m.State != MenuMain {
+		return false
+	}
+	m.SetQuery(m.Query + string(char))
+	return true
+}
+
+// SetQuery filters the main menu to the settings matching query, or shows
+// the categories again when query is empty
+func (m *Menu) SetQuery(query string) {
+	m.Query = query
+	m.buildMainMenu()
+	m.SelectedIndex = m.firstSelectableIndex()
+	m.ScrollOffset = 0
+}
+
+// fuzzyMatch reports whether every word appears in text with its runes in
+// order
+func fuzzyMatch(text string, words []string) bool {
+	for _, word := range words {
+		rest := text
+		for _, r := range word {
+			i := strings.IndexRune(rest, r)
+			if i < 0 {
+				return false
+			}
+			rest = rest[i+len(string(r)):]
+		}
+	}
+	return true
+}
@@ -0,0 +1,157 @@
+package menu
+
+import (
+	"math"
+	"strconv"
+)
+
+// Slider is the number of a slider item and the range it moves in
+type Slider struct {
+	Value float64
+	Min   float64
+	Max   float64
+	Step  float64
+	Unit  string // Shown after the value, such as "%" or "s"
+	Zero  string // Shown instead of 0, which means off or the default
+}
+
+// Text returns the value as shown beside the slider
+func (s *Slider) Text() string {
+	if s.Value == 0 && s.Zero != "" {
+		return s.Zero
+	}
+	// Round away the noise of values stored as float32
+	return strconv.FormatFloat(math.Round(s.Value*1e4)/1e4, 'f', -1, 64) + s.Unit
+}
+
+// Fraction returns how far along its range the value is, from 0 to 1
+func (s *Slider) Fraction() float64 {
+	if s.Max <= s.Min {
+		return 0
+	}
+	return math.Min(math.Max((s.Value-s.Min)/(s.Max-s.Min), 0), 1)
+}
+
+// snap rounds v to a whole number of steps inside the range
+func (s *Slider) snap(v float64) float64 {
+	if s.Step > 0 {
+		v = s.Min + math.Round((v-s.Min)/s.Step)*s.Step
+		// Drop the float noise left by steps like 0.1
+		v = math.Round(v*1e6) / 1e6
+	}
+	return math.Min(math.Max(v, s.Min), s.Max)
+}
+
+// Dropdown is the choices of a dropdown item
+type Dropdown struct {
+	Options  []string // Values to choose from
+	Labels   []string // Shown for each option; the option itself when nil
+	Selected int
+}
+
+// Text returns the chosen option as shown beside the dropdown
+func (d *Dropdown) Text() string {
+	if d.Selected < 0 || d.Selected >= len(d.Options) {
+		return ""
+	}
+	if d.Selected < len(d.Labels) {
+		return d.Labels[d.Selected]
+	}
+	return d.Options[d.Selected]
+}
+
+// newDropdown returns a dropdown of options with value chosen, or the first
+// option when value is not one of them
+func newDropdown(options, labels []string, value string) *Dropdown {
+	d := &Dropdown{Options: options, Labels: labels}
+	for i, option := range options {
+		if option == value {
+			d.Selected = i
+		}
+	}
+	return d
+}
+
+// Adjust moves the selected slider by steps, or chooses the next option of
+// the selected dropdown, or the one before when steps is negative. It
+// reports false when neither is selected.
+func (m *Menu) Adjust(steps int) bool {
+	if m.InputActive || !m.isSelectable(m.SelectedIndex) {
+		return false
+	}
+	item := m.Items[m.SelectedIndex]
+	switch {
+	case item.Slider != nil:
+		m.track(item.Value, func() {
+			m.setWidget(item.Value, item.Slider.snap(item.Slider.Value+float64(steps)*item.Slider.Step), "")
+		})
+	case item.Dropdown != nil && len(item.Dropdown.Options) > 0:
+		next := item.Dropdown.Selected + 1
+		if steps < 0 {
+			next = item.Dropdown.Selected - 1
+		}
+		n := len(item.Dropdown.Options)
+		m.track(item.Value, func() { m.setWidget(item.Value, 0, item.Dropdown.Options[(next+n)%n]) })
+	default:
+		return false
+	}
+	return true
+}
+
+// SetSliderAt selects the slider item at index and moves it to fraction of
+// its range, as clicking its bar does, reporting false when it is no slider
+func (m *Menu) SetSliderAt(index int, fraction float64) bool {
+	if m.InputActive || !m.isSelectable(index) || m.Items[index].Slider == nil {
+		return false
+	}
+	m.SelectedIndex = index
+	item := m.Items[index]
+	s := item.Slider
+	m.track(item.Value, func() {
+		m.setWidget(item.Value, s.snap(s.Min+fraction*(s.Max-s.Min)), "")
+	})
+	return true
+}
+
+// setWidget stores the number of a slider or the option of a dropdown in
+// the setting key and shows the page again
+func (m *Menu) setWidget(key string, number float64, option string) {
+	status := "Updated (save to persist)"
+	switch key {
+	case "font-size":
+		m.Config.FontSize = float32(number)
+	case "ui-scale":
+		m.Config.Appearance.UIScale = float32(number)
+	case "panel-width":
+		m.Config.Appearance.PanelWidthPercent = float32(number)
+	case "ai-panel-size":
+		m.Config.Appearance.AIPanelSize = float32(number)
+	case "chord-timeout":
+		m.Config.Keys.ChordTimeout = int(number)
+	case "ollama-timeout":
+		m.Config.Network.OllamaTimeout = int(number)
+	case "thinking-timeout":
+		m.Config.Ollama.ExtendedTimeout = int(number)
+	case "connect-timeout":
+		m.Config.Network.ConnectTimeout = int(number)
+	case "search-timeout":
+		m.Config.Network.SearchTimeout = int(number)
+	case "memory-cap":
+		m.Config.Terminal.MemoryCapMB = int(number)
+	case "replay-buffer":
+		m.Config.Terminal.ReplayBufferKB = int(number)
+		status = "Updated for new panes (save to persist)"
+	case "theme":
+		m.Config.Theme = option
+	case "cursor-style":
+		m.Config.Appearance.CursorStyle = option
+	case "prompt-style":
+		m.Config.Prompt.Style = option
+		status = "Style updated (restart tab to apply)"
+	default:
+		return
+	}
+	m.debugf("widget key=%s number=%v option=%q", key, number, option)
+	m.StatusMessage = status
+	m.buildSettingsPage()
+}
@@ -0,0 +1,214 @@
+package netconf
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/javanhut/RavenTerminal/src/config"
+)
+
+// Default timeouts, used when the [network] settings leave them at 0
+const (
+	DefaultConnectTimeout = 30 * time.Second
+	DefaultSearchTimeout  = 10 * time.Second
+	DefaultOllamaTimeout  = 300 * time.Second
+)
+
+// settings are the parsed [network] settings shared by every HTTP client
+type settings struct {
+	proxy          func(*http.Request) (*url.URL, error)
+	tls            *tls.Config
+	connectTimeout time.Duration
+	searchTimeout  time.Duration
+	ollamaTimeout  time.Duration
+	transport      *http.Transport // Shared by clients from Client
+}
+
+var (
+	mu      sync.RWMutex
+	current = newSettings(http.ProxyFromEnvironment, nil, 0, 0, 0)
+)
+
+func newSettings(proxy func(*http.Request) (*url.URL, error), tlsConfig *tls.Config, connect, search, ollama time.Duration) *settings {
+	s := &settings{
+		proxy:          proxy,
+		tls:            tlsConfig,
+		connectTimeout: orDefault(connect, DefaultConnectTimeout),
+		searchTimeout:  orDefault(search, DefaultSearchTimeout),
+		ollamaTimeout:  orDefault(ollama, DefaultOllamaTimeout),
+	}
+	s.transport = s.newTransport()
+	return s
+}
+
+func orDefault(d, def time.Duration) time.Duration {
+	if d <= 0 {
+		return def
+	}
+	return d
+}
+
+// Configure applies the [network] settings. On error the previous settings
+// are kept, so a typo in the proxy URL does not drop a working setup.
+func Configure(cfg config.NetworkConfig) error {
+	proxy, err := proxyFunc(cfg.Proxy, cfg.NoProxy)
+	if err != nil {
+		return err
+	}
+	var tlsConfig *tls.Config
+	if path := strings.TrimSpace(cfg.CABundle); path != "" {
+		pool, err := loadCABundle(expandHome(path))
+		if err != nil {
+			return err
+		}
+		tlsConfig = &tls.Config{RootCAs: pool}
+	}
+	s := newSettings(proxy, tlsConfig,
+		time.Duration(cfg.ConnectTimeout)*time.Second,
+		time.Duration(cfg.SearchTimeout)*time.Second,
+		time.Duration(cfg.OllamaTimeout)*time.Second)
+
+	mu.Lock()
+	old := current
+	current = s
+	mu.Unlock()
+	old.transport.CloseIdleConnections()
+	return nil
+}
+
+func get() *settings {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// NewTransport returns a transport using the configured proxy, certificate
+// authorities and connect timeout, for callers that tune it further
+func NewTransport() *http.Transport {
+	return get().newTransport()
+}
+
+func (s *settings) newTransport() *http.Transport {
+	dialer := &net.Dialer{Timeout: s.connectTimeout, KeepAlive: 30 * time.Second}
+	var tlsConfig *tls.Config
+	if s.tls != nil {
+		tlsConfig = s.tls.Clone()
+	}
+	return &http.Transport{
+		Proxy:                 s.proxy,
+		DialContext:           dialer.DialContext,
+		TLSClientConfig:       tlsConfig,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   s.connectTimeout,
+		ExpectContinueTimeout: time.Second,
+	}
+}
+
+// Client returns a client on the shared transport; timeout 0 means none
+func Client(timeout time.Duration) *http.Client {
+	return &http.Client{Timeout: timeout, Transport: get().transport}
+}
+
+// SearchTimeout returns how long a web search or page preview may take
+func SearchTimeout() time.Duration {
+	return get().searchTimeout
+}
+
+// OllamaTimeout returns how long Ollama may take to start answering,
+// which includes loading the model
+func OllamaTimeout() time.Duration {
+	return get().ollamaTimeout
+}
+
+// proxyFunc picks the proxy for each request. An empty proxy falls back to
+// HTTP_PROXY, HTTPS_PROXY and NO_PROXY; "direct" turns proxying off.
+// Loopback hosts, such as a local Ollama server, are never proxied.
+func proxyFunc(rawProxy, noProxy string) (func(*http.Request) (*url.URL, error), error) {
+	rawProxy = strings.TrimSpace(rawProxy)
+	switch strings.ToLower(rawProxy) {
+	case "":
+		return http.ProxyFromEnvironment, nil
+	case "direct", "none":
+		return nil, nil
+	}
+	proxyURL, err := url.Parse(rawProxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy %q: %w", rawProxy, err)
+	}
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("invalid proxy %q: scheme must be http, https or socks5", rawProxy)
+	}
+	if proxyURL.Host == "" {
+		return nil, fmt.Errorf("invalid proxy %q: missing host", rawProxy)
+	}
+	bypass := strings.FieldsFunc(strings.ToLower(noProxy), func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+	return func(req *http.Request) (*url.URL, error) {
+		if Bypass(req.URL.Hostname(), bypass) {
+			return nil, nil
+		}
+		return proxyURL, nil
+	}, nil
+}
+
+// Bypass reports whether host is loopback or matches a no_proxy entry. An
+// entry matches the host itself and its subdomains; "*" matches every host.
+func Bypass(host string, noProxy []string) bool {
+	host = strings.ToLower(host)
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return true
+	}
+	for _, entry := range noProxy {
+		entry = strings.TrimPrefix(strings.TrimPrefix(entry, "*"), ".")
+		if entry == "" {
+			return true
+		}
+		if host == entry || strings.HasSuffix(host, "."+entry) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadCABundle returns the system roots with the certificates of a PEM file
+// added, so a corporate CA is trusted alongside the public ones
+func loadCABundle(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ca_bundle: %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("ca_bundle: no PEM certificates in %s", path)
+	}
+	return pool, nil
+}
+
+// expandHome replaces a leading ~/ with the user's home directory
+func expandHome(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return home + path[1:]
+		}
+	}
+	return path
+}
@@ -0,0 +1,224 @@
+package notifications
+
+import (
+	"time"
+
+	"github.com/javanhut/RavenTerminal/src/tab"
+)
+
+// MaxEntries caps how many notifications are kept; the oldest are dropped first
+const MaxEntries = 200
+
+// mergeWindow is how soon a repeat of the latest notification is counted on
+// it instead of being listed again, so a burst of bells takes one line
+const mergeWindow = 5 * time.Second
+
+// Kind identifies the event behind a notification
+type Kind int
+
+const (
+	KindBell Kind = iota
+	KindCommand
+	KindAI
+)
+
+// String returns the short label shown in the panel
+func (k Kind) String() string {
+	switch k {
+	case KindBell:
+		return "bell"
+	case KindCommand:
+		return "done"
+	case KindAI:
+		return "ai"
+	}
+	return "?"
+}
+
+// Entry is a recorded background event
+type Entry struct {
+	Time    time.Time
+	Kind    Kind
+	Pane    *tab.Pane // Pane the event came from; nil for AI chat replies
+	Source  string    // Tab and pane number of Pane when the event happened, like "2.1"
+	Message string
+	Count   int // How many identical events were merged into this entry
+}
+
+type Panel struct {
+	Open     bool
+	Focused  bool
+	Entries  []Entry // Newest first
+	Selected int
+	Scroll   int
+	Unread   int // Notifications added while the panel was closed
+}
+
+type Layout struct {
+	PanelX       float32
+	PanelY       float32
+	PanelWidth   float32
+	PanelHeight  float32
+	ContentX     float32
+	ContentWidth float32
+	LineHeight   float32
+	HeaderY      float32
+	ListStart    float32
+	ListEnd      float32
+	FooterY      float32
+	VisibleLines int
+}
+
+func NewPanel() *Panel {
+	return &Panel{}
+}
+
+func (p *Panel) Toggle() {
+	p.Open = !p.Open
+	if p.Open {
+		p.Focused = true
+		p.Selected = 0
+		p.Scroll = 0
+		p.Unread = 0
+	}
+}
+
+// Add records an event, merging it into the latest entry when it repeats within mergeWindow
+func (p *Panel) Add(entry Entry) {
+	if entry.Count < 1 {
+		entry.Count = 1
+	}
+	if !p.Open {
+		p.Unread++
+	}
+	if len(p.Entries) > 0 {
+		latest := &p.Entries[0]
+		if latest.Kind == entry.Kind && latest.Pane == entry.Pane && latest.Message == entry.Message &&
+			entry.Time.Sub(latest.Time) < mergeWindow {
+			latest.Count += entry.Count
+			latest.Time = entry.Time
+			return
+		}
+	}
+
+	p.Entries = append([]Entry{entry}, p.Entries...)
+	if len(p.Entries) > MaxEntries {
+		p.Entries = p.Entries[:MaxEntries]
+	}
+	// Keep the selection on the entry it was on
+	if p.Open && len(p.Entries) > 1 {
+		p.Selected = min(p.Selected+1, len(p.Entries)-1)
+	}
+}
+
+// Clear removes every notification
+func (p *Panel) Clear() {
+	p.Entries = nil
+	p.Selected = 0
+	p.Scroll = 0
+	p.Unread = 0
+}
+
+// SelectedEntry returns the currently selected notification
+func (p *Panel) SelectedEntry() (Entry, bool) {
+	if p.Selected < 0 || p.Selected >= len(p.Entries) {
+		return Entry{}, false
+	}
+	return p.Entries[p.Selected], true
+}
+
+func (p *Panel) MoveSelection(delta int, visibleLines int) {
+	if len(p.Entries) == 0 {
+		return
+	}
+	p.Selected += delta
+	if p.Selected < 0 {
+		p.Selected = 0
+	}
+	if p.Selected >= len(p.Entries) {
+		p.Selected = len(p.Entries) - 1
+	}
+	p.ensureSelectionVisible(visibleLines)
+}
+
+func (p *Panel) ensureSelectionVisible(visibleLines int) {
+	if visibleLines <= 0 {
+		return
+	}
+	if p.Selected < p.Scroll {
+		p.Scroll = p.Selected
+	}
+	if p.Selected >= p.Scroll+visibleLines {
+		p.Scroll = p.Selected - visibleLines + 1
+	}
+	maxScroll := len(p.Entries) - visibleLines
+	if maxScroll < 0 {
+		maxScroll = 0
+	}
+	if p.Scroll > maxScroll {
+		p.Scroll = maxScroll
+	}
+	if p.Scroll < 0 {
+		p.Scroll = 0
+	}
+}
+
+func (p *Panel) Layout(width, height int, cellWidth, cellHeight float32) Layout {
+	panelWidth := float32(width) * 0.45
+	minPanelWidth := float32(420)
+	if cellWidth > 0 {
+		wideMin := cellWidth * 48
+		if wideMin > minPanelWidth {
+			minPanelWidth = wideMin
+		}
+	}
+	if panelWidth < minPanelWidth {
+		panelWidth = minPanelWidth
+	}
+	if panelWidth > 760 {
+		panelWidth = 760
+	}
+	maxWidth := float32(width) - 20
+	if panelWidth > maxWidth {
+		panelWidth = maxWidth
+	}
+
+	panelHeight := float32(height) - 30
+	if panelHeight < 240 {
+		panelHeight = 240
+	}
+	if panelHeight > float32(height)-20 {
+		panelHeight = float32(height) - 20
+	}
+
+	panelX := float32(width) - panelWidth - 10
+	panelY := float32(10)
+
+	lineHeight := cellHeight * 1.35
+	contentX := panelX + 18
+	contentWidth := panelWidth - 36
+	headerY := panelY + lineHeight*1.2
+	listStart := headerY + lineHeight*1.6
+	footerY := panelY + panelHeight - lineHeight*0.6
+	listEnd := footerY - lineHeight*1.2
+
+	visibleLines := int((listEnd - listStart) / lineHeight)
+	if visibleLines < 1 {
+		visibleLines = 1
+	}
+
+	return Layout{
+		PanelX:       panelX,
+		PanelY:       panelY,
+		PanelWidth:   panelWidth,
+		PanelHeight:  panelHeight,
+		ContentX:     contentX,
+		ContentWidth: contentWidth,
+		LineHeight:   lineHeight,
+		HeaderY:      headerY,
+		ListStart:    listStart,
+		ListEnd:      listEnd,
+		FooterY:      footerY,
+		VisibleLines: visibleLines,
+	}
+}
@@ -11,11 +11,40 @@ import (
 	"net/url"
 	"strings"
 	"time"
+
+	"github.com/javanhut/RavenTerminal/src/logging"
+	"github.com/javanhut/RavenTerminal/src/netconf"
 )
 
 type Message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role      string     `json:"role"`
+	Content   string     `json:"content"`
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"` // Calls requested by an assistant message
+	ToolName  string     `json:"tool_name,omitempty"`  // Tool whose result a "tool" message carries
+}
+
+// Tool describes a function the model may ask to call
+type Tool struct {
+	Type     string       `json:"type"` // Always "function"
+	Function ToolFunction `json:"function"`
+}
+
+// ToolFunction is a callable function with a JSON schema for its arguments
+type ToolFunction struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Parameters  map[string]any `json:"parameters"`
+}
+
+// ToolCall is a function call requested by the model
+type ToolCall struct {
+	Function ToolCallFunction `json:"function"`
+}
+
+// ToolCallFunction names the function to call and its arguments
+type ToolCallFunction struct {
+	Name      string         `json:"name"`
+	Arguments map[string]any `json:"arguments"`
 }
 
 // ThinkingOptions configures thinking/reasoning mode for supported models
@@ -26,8 +55,9 @@ type ThinkingOptions struct {
 
 // ChatResult contains the response and any thinking content
 type ChatResult struct {
-	Content  string // The main response content
-	Thinking string // Thinking/reasoning content (if any)
+	Content   string     // The main response content
+	Thinking  string     // Thinking/reasoning content (if any)
+	ToolCalls []ToolCall // Tools the model asked to call (if any)
 }
 
 type Client struct {
@@ -36,20 +66,22 @@ type Client struct {
 	KeepAlive string
 	HTTP      *http.Client
 	Thinking  ThinkingOptions
+	Tools     []Tool // Offered to the model in chat requests
 }
 
 func NewClient(baseURL, model string) *Client {
+	// Proxy, certificate and connect settings come from [network]
+	timeout := netconf.OllamaTimeout()
+	transport := netconf.NewTransport()
+	transport.ResponseHeaderTimeout = timeout // Match the context deadline for model loading
+	transport.ExpectContinueTimeout = 5 * time.Second
 	return &Client{
 		BaseURL:   normalizeBaseURL(baseURL),
 		Model:     strings.TrimSpace(model),
 		KeepAlive: "5m",
 		HTTP: &http.Client{
-			Timeout: 360 * time.Second, // Must exceed ResponseHeaderTimeout for model loading
-			Transport: &http.Transport{
-				TLSHandshakeTimeout:   30 * time.Second,
-				ResponseHeaderTimeout: 300 * time.Second, // Match the 300s context deadline for model loading
-				ExpectContinueTimeout: 5 * time.Second,
-			},
+			Timeout:   timeout + 60*time.Second, // Must exceed ResponseHeaderTimeout for model loading
+			Transport: transport,
 		},
 	}
 }
@@ -196,6 +228,7 @@ func (c *Client) ChatStreamWithThinking(ctx context.Context, messages []Message,
 		Model:    c.Model,
 		Messages: messages,
 		Stream:   true,
+		Tools:    c.Tools,
 	}
 
 	// Add thinking options if enabled
@@ -215,7 +248,7 @@ func (c *Client) ChatStreamWithThinking(ctx context.Context, messages []Message,
 	}
 
 	// Use a client without timeout for streaming - context handles cancellation
-	streamClient := &http.Client{}
+	streamClient := &http.Client{Transport: netconf.NewTransport()}
 
 	// Retry loop for connection/pre-stream errors (3 attempts, 5s backoff)
 	const streamMaxRetries = 3
@@ -237,6 +270,7 @@ func (c *Client) ChatStreamWithThinking(ctx context.Context, messages []Message,
 		}
 		httpReq.Header.Set("Content-Type", "application/json")
 
+		logging.Debugf(logging.AI, "POST %s (stream, attempt %d)", endpoint, attempt+1)
 		resp, err = streamClient.Do(httpReq)
 		if err != nil {
 			lastErr = c.wrapError(err)
@@ -288,6 +322,7 @@ func (c *Client) ChatStreamWithThinking(ctx context.Context, messages []Message,
 
 	var fullContent strings.Builder
 	var fullThinking strings.Builder
+	var toolCalls []ToolCall
 	decoder := json.NewDecoder(resp.Body)
 
 	for {
@@ -320,6 +355,7 @@ func (c *Client) ChatStreamWithThinking(ctx context.Context, messages []Message,
 				onToken(streamResp.Message.Content)
 			}
 		}
+		toolCalls = append(toolCalls, streamResp.Message.ToolCalls...)
 		if streamResp.Done {
 			break
 		}
@@ -333,11 +369,11 @@ func (c *Client) ChatStreamWithThinking(ctx context.Context, messages []Message,
 		content, thinking = ExtractThinking(content)
 	}
 
-	if strings.TrimSpace(content) == "" && strings.TrimSpace(thinking) == "" {
+	if strings.TrimSpace(content) == "" && strings.TrimSpace(thinking) == "" && len(toolCalls) == 0 {
 		return ChatResult{}, errors.New("empty response")
 	}
 
-	return ChatResult{Content: content, Thinking: thinking}, nil
+	return ChatResult{Content: content, Thinking: thinking, ToolCalls: toolCalls}, nil
 }
 
 // ExtractThinking extracts thinking content from <think>...</think> tags.
@@ -399,6 +435,36 @@ func (c *Client) ListModels(ctx context.Context) ([]string, error) {
 	return models, nil
 }
 
+// Embed returns an embedding vector for each input, computed by the client's model
+func (c *Client) Embed(ctx context.Context, inputs []string) ([][]float32, error) {
+	if c.BaseURL == "" {
+		return nil, errors.New("ollama url not set")
+	}
+	if c.Model == "" {
+		return nil, errors.New("ollama embedding model not set")
+	}
+	if len(inputs) == 0 {
+		return nil, nil
+	}
+
+	req := embedRequest{
+		Model:     c.Model,
+		Input:     inputs,
+		KeepAlive: c.KeepAlive,
+	}
+	var resp embedResponse
+	if err := c.postJSON(ctx, "/api/embed", req, &resp); err != nil {
+		return nil, c.wrapError(err)
+	}
+	if resp.Error != "" {
+		return nil, c.wrapError(errors.New(resp.Error))
+	}
+	if len(resp.Embeddings) != len(inputs) {
+		return nil, fmt.Errorf("ollama returned %d embeddings for %d inputs", len(resp.Embeddings), len(inputs))
+	}
+	return resp.Embeddings, nil
+}
+
 func (c *Client) postJSON(ctx context.Context, path string, payload any, out any) error {
 	endpoint := c.BaseURL + path
 	body, err := json.Marshal(payload)
@@ -411,11 +477,13 @@ func (c *Client) postJSON(ctx context.Context, path string, payload any, out any
 	}
 	req.Header.Set("Content-Type", "application/json")
 
+	logging.Debugf(logging.AI, "POST %s", endpoint)
 	resp, err := c.HTTP.Do(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
+	logging.Debugf(logging.AI, "POST %s returned %s", endpoint, resp.Status)
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		// Try to read error message from response body
@@ -506,6 +574,7 @@ type chatRequest struct {
 	Stream   bool         `json:"stream"`
 	Think    bool         `json:"think,omitempty"`   // Enable thinking mode (some APIs)
 	Options  *chatOptions `json:"options,omitempty"` // Model options
+	Tools    []Tool       `json:"tools,omitempty"`
 }
 
 type chatResponse struct {
@@ -520,6 +589,17 @@ type chatStreamResponse struct {
 	Error    string  `json:"error"`
 }
 
+type embedRequest struct {
+	Model     string   `json:"model"`
+	Input     []string `json:"input"`
+	KeepAlive string   `json:"keep_alive,omitempty"`
+}
+
+type embedResponse struct {
+	Embeddings [][]float32 `json:"embeddings"`
+	Error      string      `json:"error"`
+}
+
 type tagsResponse struct {
 	Models []struct {
 		Name  string `json:"name"`
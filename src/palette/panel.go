@@ -0,0 +1,207 @@
+package palette
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Columns is how many swatches each row of the grid holds
+const Columns = 16
+
+// Swatch is one color in the overlay: a 256-color palette entry, or a
+// theme color which has no index
+type Swatch struct {
+	Name  string
+	Index int // Palette index, or -1 for a theme color
+	Color [4]float32
+}
+
+// Hex returns the color as #rrggbb
+func (s Swatch) Hex() string {
+	r, g, b := s.rgb()
+	return fmt.Sprintf("#%02x%02x%02x", r, g, b)
+}
+
+// Sequence returns the SGR escape sequence that selects the color, written
+// with \e so it can be pasted into printf, echo -e or a prompt string.
+// Palette entries use their index and theme colors their RGB value.
+func (s Swatch) Sequence(background bool) string {
+	layer := "38"
+	if background {
+		layer = "48"
+	}
+	if s.Index >= 0 {
+		return `\e[` + layer + ";5;" + strconv.Itoa(s.Index) + "m"
+	}
+	r, g, b := s.rgb()
+	return fmt.Sprintf(`\e[%s;2;%d;%d;%dm`, layer, r, g, b)
+}
+
+// Label names the swatch for the info line
+func (s Swatch) Label() string {
+	if s.Index >= 0 {
+		return strconv.Itoa(s.Index)
+	}
+	return s.Name
+}
+
+func (s Swatch) rgb() (uint8, uint8, uint8) {
+	return channel(s.Color[0]), channel(s.Color[1]), channel(s.Color[2])
+}
+
+func channel(v float32) uint8 {
+	if v <= 0 {
+		return 0
+	}
+	if v >= 1 {
+		return 255
+	}
+	return uint8(v*255 + 0.5)
+}
+
+// Panel is the palette inspector overlay. Swatches are laid out Columns to a
+// row, with the theme colors on a row of their own after the palette.
+type Panel struct {
+	Open       bool
+	Focused    bool
+	Swatches   []Swatch
+	Selected   int
+	Background bool // Copy sequences that set the background instead of the foreground
+}
+
+type Layout struct {
+	PanelX       float32
+	PanelY       float32
+	PanelWidth   float32
+	PanelHeight  float32
+	ContentX     float32
+	ContentWidth float32
+	LineHeight   float32
+	HeaderY      float32
+	GridY        float32
+	SwatchWidth  float32
+	SwatchHeight float32
+	ThemeY       float32
+	InfoY        float32
+	FooterY      float32
+	PaletteRows  int
+}
+
+func NewPanel() *Panel {
+	return &Panel{}
+}
+
+// Toggle opens the panel showing swatches, or closes it
+func (p *Panel) Toggle(swatches []Swatch) {
+	p.Open = !p.Open
+	if p.Open {
+		p.Focused = true
+		p.Swatches = swatches
+		if p.Selected >= len(swatches) {
+			p.Selected = 0
+		}
+	}
+}
+
+// Current returns the selected swatch
+func (p *Panel) Current() (Swatch, bool) {
+	if p.Selected < 0 || p.Selected >= len(p.Swatches) {
+		return Swatch{}, false
+	}
+	return p.Swatches[p.Selected], true
+}
+
+// Move shifts the selection by dx columns and dy rows, stopping at the
+// first and last swatch
+func (p *Panel) Move(dx, dy int) {
+	if len(p.Swatches) == 0 {
+		return
+	}
+	selected := p.Selected + dx + dy*Columns
+	if selected < 0 {
+		selected = 0
+	}
+	if selected >= len(p.Swatches) {
+		selected = len(p.Swatches) - 1
+	}
+	p.Selected = selected
+}
+
+// paletteCount is how many swatches are palette entries rather than theme colors
+func (p *Panel) paletteCount() int {
+	n := 0
+	for _, s := range p.Swatches {
+		if s.Index >= 0 {
+			n++
+		}
+	}
+	return n
+}
+
+func (p *Panel) Layout(width, height int, cellWidth, cellHeight float32) Layout {
+	lineHeight := cellHeight * 1.35
+	swatchWidth := cellWidth * 4
+	if swatchWidth < 24 {
+		swatchWidth = 24
+	}
+	maxWidth := float32(width) - 20
+	if swatchWidth*Columns+36 > maxWidth {
+		swatchWidth = (maxWidth - 36) / Columns
+	}
+	swatchHeight := lineHeight
+	panelWidth := swatchWidth*Columns + 36
+
+	paletteRows := (p.paletteCount() + Columns - 1) / Columns
+	themeRows := (len(p.Swatches) - p.paletteCount() + Columns - 1) / Columns
+
+	headerY := lineHeight * 1.2
+	gridY := headerY + lineHeight*0.6
+	themeY := gridY + float32(paletteRows)*swatchHeight + lineHeight*0.6
+	infoY := themeY + float32(themeRows)*swatchHeight + lineHeight*1.2
+	panelHeight := infoY + lineHeight*2.4
+
+	panelX := (float32(width) - panelWidth) / 2
+	panelY := (float32(height) - panelHeight) / 2
+	if panelY < 10 {
+		panelY = 10
+	}
+
+	return Layout{
+		PanelX:       panelX,
+		PanelY:       panelY,
+		PanelWidth:   panelWidth,
+		PanelHeight:  panelHeight,
+		ContentX:     panelX + 18,
+		ContentWidth: panelWidth - 36,
+		LineHeight:   lineHeight,
+		HeaderY:      panelY + headerY,
+		GridY:        panelY + gridY,
+		SwatchWidth:  swatchWidth,
+		SwatchHeight: swatchHeight,
+		ThemeY:       panelY + themeY,
+		InfoY:        panelY + infoY,
+		FooterY:      panelY + panelHeight - lineHeight*0.6,
+		PaletteRows:  paletteRows,
+	}
+}
+
+// SwatchRect returns where swatch i is drawn
+func (l Layout) SwatchRect(i int) (x, y float32) {
+	row, col := i/Columns, i%Columns
+	x = l.ContentX + float32(col)*l.SwatchWidth
+	if row < l.PaletteRows {
+		return x, l.GridY + float32(row)*l.SwatchHeight
+	}
+	return x, l.ThemeY + float32(row-l.PaletteRows)*l.SwatchHeight
+}
+
+// SwatchAt returns the swatch at a window position, or -1
+func (p *Panel) SwatchAt(layout Layout, x, y float32) int {
+	for i := range p.Swatches {
+		sx, sy := layout.SwatchRect(i)
+		if x >= sx && x < sx+layout.SwatchWidth && y >= sy && y < sy+layout.SwatchHeight {
+			return i
+		}
+	}
+	return -1
+}
@@ -1,12 +1,17 @@
 package parser
 
 import (
+	"bytes"
+	"encoding/base64"
 	"fmt"
 	"github.com/javanhut/RavenTerminal/src/grid"
+	"github.com/javanhut/RavenTerminal/src/logging"
+	"math"
 	"net/url"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
 // ParserState represents the current state of the ANSI parser
@@ -24,12 +29,14 @@ const (
 	StateHash
 )
 
-// Charset represents a character set designation (G0/G1).
+// Charset represents a character set designation (G0-G3).
 type Charset int
 
 const (
 	charsetASCII Charset = iota
 	charsetLineDrawing
+	charsetUK
+	charsetLatin1Supplement
 )
 
 type charsetTarget int
@@ -38,6 +45,8 @@ const (
 	charsetTargetNone charsetTarget = iota
 	charsetTargetG0
 	charsetTargetG1
+	charsetTargetG2
+	charsetTargetG3
 )
 
 // CursorStyle represents the rendered cursor style.
@@ -93,8 +102,13 @@ type Terminal struct {
 	Grid            *grid.Grid
 	state           ParserState
 	csiParams       string
-	oscParams       string
-	dcsParams       string
+	csiTooLong      bool // The CSI sequence being read passed maxCSILength and is ignored
+	oscParams       []byte
+	dcsParams       []byte
+	stringTooLong   bool // The OSC or DCS string being read passed its limit; the rest is discarded
+	stringWindow    time.Time
+	stringCount     int // OSC and DCS strings acted on since stringWindow
+	stringsDropped  int // Strings dropped since stringWindow for arriving too fast
 	currentFg       grid.Color
 	currentBg       grid.Color
 	currentFlags    grid.CellFlags
@@ -102,7 +116,15 @@ type Terminal struct {
 	cursorVisible   bool
 	alternateScreen bool
 	savedMainGrid   *grid.Grid
+	altCapture      bool // Copy the alternate screen into the main one when a program leaves it
+	altMark         bool // Mark where the main screen picks up when a program leaves the alternate screen
 	lastWorkingDir  string
+	lastHost        string
+	promptCount     int
+	bellCount       int
+	commandMark     commandMark
+	commandTimer    commandTimer
+	outputMark      outputMark
 	responseWriter  func([]byte)
 	mu              sync.Mutex
 	// UTF-8 decoding state
@@ -114,11 +136,18 @@ type Terminal struct {
 	// Per-screen scroll region state
 	savedMainScrollTop    int
 	savedMainScrollBottom int
-	// Character set handling (DEC line drawing)
+	savedMainViewTop      int // Absolute line at the top of the main screen's view; -1 when it was at the bottom
+	// Character set handling (G0-G3 designation, locking and single shifts)
 	charsetG0      Charset
 	charsetG1      Charset
-	activeCharset  int // 0=G0, 1=G1
+	charsetG2      Charset
+	charsetG3      Charset
+	activeCharset  int // 0=G0, 1=G1, 2=G2, 3=G3
+	singleShift    int // 0=none, 2=SS2, 3=SS3; applies to the next graphic char only
 	charsetPending charsetTarget
+	charset96      bool // pending designation is a 96-character set
+	// Latin-1 mode: decode bytes 0xA0-0xFF as ISO-8859-1 instead of UTF-8
+	latin1 bool
 	// Origin mode (DECOM ?6)
 	originMode bool
 	// Cursor style (DECSCUSR)
@@ -136,8 +165,45 @@ type Terminal struct {
 	savedMainBracketedPaste bool
 	savedMainMouseMode      int
 	savedMainMouseSGRMode   bool
+	// DEC printer controller (media copy): text sent to the printer callback
+	printer           func([]byte)
+	printerController bool   // CSI 5 i: output goes only to the printer until CSI 4 i
+	printerBuf        []byte // Output diverted to the printer and not yet handed over
+	autoPrint         bool   // CSI ? 5 i: each line is also printed as the cursor leaves it
+	// iTerm2 user variables and badge format (OSC 1337)
+	userVars    map[string]string
+	badgeFormat string
 }
 
+// maxPrinterBuffer is how much diverted output is collected before it is
+// handed to the printer
+const maxPrinterBuffer = 4096
+
+// Limits on what a program can store with OSC 1337 SetUserVar
+const (
+	maxUserVars      = 64
+	maxUserVarLength = 1024
+)
+
+// Limits on CSI sequences, so output with pathological parameters cannot
+// stall the parser or make grid operations run for billions of steps
+const (
+	maxCSILength     = 1024  // Parameter and intermediate bytes; longer sequences are ignored
+	maxCSIParams     = 32    // Parameters past this many are dropped
+	maxCSIParamValue = 65535 // Larger parameters are clamped to this
+)
+
+// Limits on OSC and DCS strings, so a program cannot grow them without end
+// or flood the terminal with them
+const (
+	maxOSCLength        = 8192 // Bytes kept of an OSC string; titles are truncated, other commands dropped
+	maxDCSLength        = 4096 // Bytes kept of a DCS string; longer ones are dropped
+	maxStringsPerSecond = 1000 // OSC and DCS strings acted on per second; the rest are dropped
+)
+
+// printerControllerOff ends printer controller mode, with a 7-bit or 8-bit CSI
+var printerControllerOff = [][]byte{[]byte("\x1b[4i"), []byte("\x9b4i")}
+
 // NewTerminal creates a new terminal parser
 func NewTerminal(cols, rows int) *Terminal {
 	return &Terminal{
@@ -151,6 +217,8 @@ func NewTerminal(cols, rows int) *Terminal {
 		savedMainScrollBottom: rows,
 		charsetG0:             charsetASCII,
 		charsetG1:             charsetASCII,
+		charsetG2:             charsetASCII,
+		charsetG3:             charsetASCII,
 		activeCharset:         0,
 		charsetPending:        charsetTargetNone,
 		cursorStyle:           CursorStyleBlock,
@@ -165,10 +233,18 @@ func (t *Terminal) Process(data []byte) {
 	for _, b := range data {
 		t.processByte(b)
 	}
+	if t.printerController {
+		// Keep enough back to recognize a CSI 4 i split across reads
+		t.flushPrinter(len(printerControllerOff[0]) - 1)
+	}
 }
 
 // processByte processes a single byte
 func (t *Terminal) processByte(b byte) {
+	if t.printerController {
+		t.printControllerByte(b)
+		return
+	}
 	switch t.state {
 	case StateGround:
 		t.processGround(b)
@@ -223,13 +299,11 @@ func (t *Terminal) processGround(b byte) {
 		t.state = StateCSI
 		t.csiParams = ""
 	case 0x9d: // OSC (8-bit C1)
-		t.state = StateOSC
-		t.oscParams = ""
+		t.startString(StateOSC)
 	case 0x90: // DCS (8-bit C1)
-		t.state = StateDCS
-		t.dcsParams = ""
+		t.startString(StateDCS)
 	case 0x07: // BEL
-		// Bell - ignore
+		t.bellCount++
 	case 0x08: // BS
 		t.Grid.Backspace()
 	case 0x09: // HT (Tab)
@@ -239,14 +313,34 @@ func (t *Terminal) processGround(b byte) {
 	case 0x0f: // SI (Shift In) - select G0
 		t.activeCharset = 0
 	case 0x0a, 0x0b, 0x0c: // LF, VT, FF
+		if t.autoPrint {
+			line := t.cursorLine()
+			t.toPrinter(t.Grid.TextBetween(line, line+1) + "\n")
+		}
 		t.Grid.Newline()
 		// Scroll position preserved - reset happens on user input instead
 	case 0x0d: // CR
 		t.Grid.CarriageReturn()
 	case 0x9c: // ST (String Terminator) - ignore in ground
 		// No-op
+	case 0x84: // IND (8-bit C1)
+		t.Grid.Newline()
+	case 0x85: // NEL (8-bit C1)
+		t.Grid.CarriageReturn()
+		t.Grid.Newline()
+	case 0x8d: // RI (8-bit C1)
+		t.reverseIndex()
+	case 0x8e: // SS2 (8-bit C1)
+		t.singleShift = 2
+	case 0x8f: // SS3 (8-bit C1)
+		t.singleShift = 3
 	default:
-		if b >= 0x20 && b < 0x7f {
+		if t.latin1 && b >= 0xa0 {
+			// ISO-8859-1 code points map directly to Unicode; a pending
+			// single shift still applies to them
+			r := t.mapCharsetRune(rune(b))
+			t.Grid.WriteChar(r, t.currentFg, t.currentBg, t.currentFlags)
+		} else if b >= 0x20 && b < 0x7f {
 			// ASCII printable character
 			r := t.mapCharsetRune(rune(b))
 			t.Grid.WriteChar(r, t.currentFg, t.currentBg, t.currentFlags)
@@ -332,18 +426,51 @@ func (t *Terminal) moveCursor(dCol, dRow int) {
 	t.Grid.SetCursorPos(col+1, row+1)
 }
 
-// mapCharsetRune applies DEC line drawing mapping if a graphics charset is active.
+// reverseIndex moves the cursor up, scrolling the region down at its top margin.
+func (t *Terminal) reverseIndex() {
+	_, row := t.Grid.GetCursor()
+	top, _ := t.Grid.GetScrollRegion()
+	if row == top-1 { // At top of scroll region (0-based vs 1-based)
+		t.Grid.ScrollDownWithBg(1, t.currentBg)
+	} else if row > 0 {
+		t.Grid.MoveCursor(0, -1)
+	}
+}
+
+// charsetFor returns the charset designated into G0-G3.
+func (t *Terminal) charsetFor(n int) Charset {
+	switch n {
+	case 1:
+		return t.charsetG1
+	case 2:
+		return t.charsetG2
+	case 3:
+		return t.charsetG3
+	}
+	return t.charsetG0
+}
+
+// mapCharsetRune maps a graphic character through the invoked charset.
+// A pending single shift (SS2/SS3) overrides the locking shift for one character.
 func (t *Terminal) mapCharsetRune(r rune) rune {
-	var cs Charset
-	if t.activeCharset == 1 {
-		cs = t.charsetG1
-	} else {
-		cs = t.charsetG0
+	cs := t.charsetFor(t.activeCharset)
+	if t.singleShift != 0 {
+		cs = t.charsetFor(t.singleShift)
+		t.singleShift = 0
 	}
-	if cs == charsetLineDrawing {
+	switch cs {
+	case charsetLineDrawing:
 		if mapped, ok := decLineDrawing[r]; ok {
 			return mapped
 		}
+	case charsetUK:
+		if r == '#' {
+			return '£'
+		}
+	case charsetLatin1Supplement:
+		if r >= 0x20 && r < 0x80 {
+			return r + 0x80
+		}
 	}
 	return r
 }
@@ -355,11 +482,20 @@ func (t *Terminal) setCharset(designator byte) {
 	}
 
 	cs := charsetASCII
-	switch designator {
-	case '0':
-		cs = charsetLineDrawing
-	case 'B':
-		cs = charsetASCII
+	if t.charset96 {
+		// 96-character sets: only ISO Latin-1 supplemental is supported
+		if designator == 'A' {
+			cs = charsetLatin1Supplement
+		}
+	} else {
+		switch designator {
+		case '0':
+			cs = charsetLineDrawing
+		case 'A':
+			cs = charsetUK
+		case 'B':
+			cs = charsetASCII
+		}
 	}
 
 	switch t.charsetPending {
@@ -367,8 +503,25 @@ func (t *Terminal) setCharset(designator byte) {
 		t.charsetG0 = cs
 	case charsetTargetG1:
 		t.charsetG1 = cs
+	case charsetTargetG2:
+		t.charsetG2 = cs
+	case charsetTargetG3:
+		t.charsetG3 = cs
 	}
 	t.charsetPending = charsetTargetNone
+	t.charset96 = false
+}
+
+// resetCharsets restores G0-G3 to ASCII and clears any shifts.
+func (t *Terminal) resetCharsets() {
+	t.charsetG0 = charsetASCII
+	t.charsetG1 = charsetASCII
+	t.charsetG2 = charsetASCII
+	t.charsetG3 = charsetASCII
+	t.activeCharset = 0
+	t.singleShift = 0
+	t.charsetPending = charsetTargetNone
+	t.charset96 = false
 }
 
 func (t *Terminal) setCursorStyle(params []int) {
@@ -393,11 +546,9 @@ func (t *Terminal) processEscape(b byte) {
 		t.state = StateCSI
 		t.csiParams = ""
 	case ']': // OSC
-		t.state = StateOSC
-		t.oscParams = ""
+		t.startString(StateOSC)
 	case 'P': // DCS - Device Control String
-		t.state = StateDCS
-		t.dcsParams = ""
+		t.startString(StateDCS)
 	case '7': // DECSC - Save cursor
 		t.saveCursor()
 		t.state = StateGround
@@ -417,28 +568,37 @@ func (t *Terminal) processEscape(b byte) {
 		}
 		t.state = StateGround
 	case 'M': // RI - Reverse index (up, respects scroll region, with BCE)
-		_, row := t.Grid.GetCursor()
-		top, _ := t.Grid.GetScrollRegion()
-		if row == top-1 { // At top of scroll region (0-based vs 1-based)
-			t.Grid.ScrollDownWithBg(1, t.currentBg)
-		} else if row > 0 {
-			t.Grid.MoveCursor(0, -1)
-		}
+		t.reverseIndex()
 		t.state = StateGround
 	case 'E': // NEL - Next line
 		t.Grid.CarriageReturn()
 		t.Grid.Newline()
 		t.state = StateGround
-	case '(', ')', '*', '+': // Character set designation - need to consume next byte
+	case '(', ')', '*', '+', '-', '.', '/': // Character set designation - need to consume next byte
 		switch b {
 		case '(':
 			t.charsetPending = charsetTargetG0
-		case ')':
+		case ')', '-':
 			t.charsetPending = charsetTargetG1
-		default:
-			t.charsetPending = charsetTargetNone
+		case '*', '.':
+			t.charsetPending = charsetTargetG2
+		case '+', '/':
+			t.charsetPending = charsetTargetG3
 		}
+		t.charset96 = b == '-' || b == '.' || b == '/'
 		t.state = StateCharset
+	case 'N': // SS2 - Single shift G2 for the next character
+		t.singleShift = 2
+		t.state = StateGround
+	case 'O': // SS3 - Single shift G3 for the next character
+		t.singleShift = 3
+		t.state = StateGround
+	case 'n': // LS2 - Lock G2 into GL
+		t.activeCharset = 2
+		t.state = StateGround
+	case 'o': // LS3 - Lock G3 into GL
+		t.activeCharset = 3
+		t.state = StateGround
 	case '=': // DECKPAM - Application keypad mode
 		t.state = StateGround
 	case '>': // DECKPNM - Normal keypad mode
@@ -452,19 +612,26 @@ func (t *Terminal) processEscape(b byte) {
 
 // processCSI handles bytes in CSI state
 func (t *Terminal) processCSI(b byte) {
-	if b >= 0x30 && b <= 0x3f {
-		// Parameter byte
-		t.csiParams += string(b)
-	} else if b >= 0x20 && b <= 0x2f {
-		// Intermediate byte
-		t.csiParams += string(b)
+	if b >= 0x20 && b <= 0x3f {
+		// Parameter or intermediate byte
+		if len(t.csiParams) < maxCSILength {
+			t.csiParams += string(b)
+		} else {
+			t.csiTooLong = true
+		}
 	} else if b >= 0x40 && b <= 0x7e {
 		// Final byte
-		t.executeCSI(b)
+		if t.csiTooLong {
+			logging.Debugf(logging.Parser, "Ignored CSI longer than %d bytes", maxCSILength)
+		} else {
+			t.executeCSI(b)
+		}
 		t.csiParams = "" // Clear params after execution
+		t.csiTooLong = false
 		t.state = StateGround
 	} else {
 		t.csiParams = "" // Clear params on abort
+		t.csiTooLong = false
 		t.state = StateGround
 	}
 }
@@ -577,6 +744,10 @@ func (t *Terminal) executeCSI(final byte) {
 	case 't': // Window manipulation (ignore)
 	case 'q': // DECSCUSR - Set cursor style (ignore for now)
 		t.setCursorStyle(params)
+	case 'i': // MC - Media copy to the printer
+		t.mediaCopy(params)
+	default:
+		logging.Debugf(logging.Parser, "Unhandled CSI %q%c", t.csiParams, final)
 	}
 }
 
@@ -689,7 +860,7 @@ func (t *Terminal) setMode(params []int, set bool) {
 				if set {
 					t.enterAlternateScreen()
 				} else {
-					t.exitAlternateScreen()
+					t.leaveAlternateScreen(false)
 				}
 			case 1048: // Save/restore cursor (xterm)
 				if set {
@@ -702,8 +873,7 @@ func (t *Terminal) setMode(params []int, set bool) {
 					t.saveCursor()
 					t.enterAlternateScreen()
 				} else {
-					t.exitAlternateScreen()
-					t.restoreCursor()
+					t.leaveAlternateScreen(true)
 				}
 			case 2004: // Bracketed paste mode
 				t.bracketedPaste = set
@@ -735,8 +905,12 @@ func (t *Terminal) setMode(params []int, set bool) {
 // enterAlternateScreen switches to alternate screen buffer
 func (t *Terminal) enterAlternateScreen() {
 	if !t.alternateScreen {
-		// Save main screen's scroll region
+		// Save main screen's scroll region and where its view was scrolled to
 		t.savedMainScrollTop, t.savedMainScrollBottom = t.Grid.GetScrollRegion()
+		t.savedMainViewTop = -1
+		if t.Grid.GetScrollOffset() > 0 {
+			t.savedMainViewTop = t.Grid.ViewTop()
+		}
 
 		// Save terminal modes so they can be restored on exit
 		t.savedMainAppCursorKeys = t.appCursorKeys
@@ -746,6 +920,7 @@ func (t *Terminal) enterAlternateScreen() {
 
 		t.savedMainGrid = t.Grid
 		t.Grid = grid.NewGrid(t.Grid.Cols, t.Grid.Rows)
+		t.Grid.SetFontScale(t.savedMainGrid.FontScale())
 		t.alternateScreen = true
 
 		// Clear the alternate screen (standard behavior)
@@ -754,10 +929,46 @@ func (t *Terminal) enterAlternateScreen() {
 	}
 }
 
+// leaveAlternateScreen returns to the main screen for ?47, ?1047 and ?1049,
+// restoring the cursor for ?1049. The main screen gets the alternate one's
+// last contents and a mark where it picks up when those are turned on, and
+// its view goes back to where it was scrolled to.
+func (t *Terminal) leaveAlternateScreen(restoreCursor bool) {
+	wasAlternate := t.alternateScreen
+	captured := t.captureAlternateScreen()
+	t.exitAlternateScreen()
+	if restoreCursor {
+		t.restoreCursor()
+	}
+	if !wasAlternate {
+		return
+	}
+	if t.altMark {
+		t.Grid.SetBookmarkAtCursor(grid.AltScreenExit)
+	}
+	t.Grid.WriteRows(captured)
+	if t.savedMainViewTop >= 0 {
+		t.Grid.ScrollViewTo(t.savedMainViewTop)
+	} else {
+		t.Grid.ResetScrollOffset()
+	}
+}
+
+// captureAlternateScreen returns the rows of the alternate screen to copy
+// into the main screen as a program leaves it, or nil when capture is off
+func (t *Terminal) captureAlternateScreen() [][]grid.Cell {
+	if !t.altCapture || !t.alternateScreen {
+		return nil
+	}
+	return t.Grid.ScreenRows()
+}
+
 // exitAlternateScreen returns to main screen buffer with full state cleanup.
 // Resets all terminal attributes so TUI app state doesn't leak into the main screen.
 func (t *Terminal) exitAlternateScreen() {
 	if t.alternateScreen && t.savedMainGrid != nil {
+		// Carry over any zoom applied while the alternate screen was active
+		t.savedMainGrid.SetFontScale(t.Grid.FontScale())
 		t.Grid = t.savedMainGrid
 		t.savedMainGrid = nil
 		t.alternateScreen = false
@@ -774,10 +985,7 @@ func (t *Terminal) exitAlternateScreen() {
 		t.Grid.SetEraseBackground(grid.DefaultBg())
 
 		// Reset charset state to ASCII defaults
-		t.charsetG0 = charsetASCII
-		t.charsetG1 = charsetASCII
-		t.activeCharset = 0
-		t.charsetPending = charsetTargetNone
+		t.resetCharsets()
 
 		// Reset terminal modes
 		t.originMode = false
@@ -795,67 +1003,130 @@ func (t *Terminal) exitAlternateScreen() {
 	}
 }
 
+// startString begins reading an OSC or DCS string
+func (t *Terminal) startString(state ParserState) {
+	t.state = state
+	t.oscParams = t.oscParams[:0]
+	t.dcsParams = t.dcsParams[:0]
+	t.stringTooLong = false
+}
+
 // processOSC handles OSC sequences (Operating System Command)
 func (t *Terminal) processOSC(b byte) {
 	if b == 0x07 { // BEL terminates OSC
-		t.handleOSC(t.oscParams)
-		t.oscParams = ""
-		t.state = StateGround
+		t.finishOSC()
 	} else if b == 0x9c { // ST (8-bit)
-		t.handleOSC(t.oscParams)
-		t.oscParams = ""
-		t.state = StateGround
+		t.finishOSC()
 	} else if b == 0x1b { // ESC - might be start of ST
 		t.state = StateOSCEscape
+	} else if b == 0x18 || b == 0x1a { // CAN and SUB abort the string
+		t.state = StateGround
 	} else {
-		t.oscParams += string(b)
+		t.oscParams = t.appendString(t.oscParams, maxOSCLength, b)
 	}
 }
 
 // processOSCEscape handles bytes after ESC in OSC state
 func (t *Terminal) processOSCEscape(b byte) {
 	if b == 0x5c { // Backslash completes ST (ESC \)
-		t.handleOSC(t.oscParams)
-		t.oscParams = ""
-		t.state = StateGround
+		t.finishOSC()
 	} else {
 		// Not ST, ESC starts new sequence
-		t.oscParams = ""
 		t.state = StateEscape
 		t.processEscape(b)
 	}
 }
 
+// finishOSC acts on the OSC string just terminated. A title cut short by
+// maxOSCLength is still set from what was kept; other oversized commands
+// would act on partial data and are dropped.
+func (t *Terminal) finishOSC() {
+	t.state = StateGround
+	params := string(t.oscParams)
+	if t.stringTooLong {
+		code, _, _ := strings.Cut(params, ";")
+		if code != "0" && code != "1" && code != "2" {
+			logging.Debugf(logging.Parser, "Dropped OSC %s longer than %d bytes", code, maxOSCLength)
+			return
+		}
+		// The cut may have split a character
+		params = strings.ToValidUTF8(params, "")
+	}
+	if t.allowString() {
+		t.handleOSC(params)
+	}
+}
+
 // processDCS handles Device Control String sequences
 func (t *Terminal) processDCS(b byte) {
 	if b == 0x1b { // ESC - might be start of ST
 		t.state = StateDCSEscape
 	} else if b == 0x9c { // ST (8-bit)
-		t.handleDCS(t.dcsParams)
-		t.dcsParams = ""
-		t.state = StateGround
+		t.finishDCS()
 	} else if b == 0x07 { // BEL also terminates (non-standard but common)
-		t.handleDCS(t.dcsParams)
-		t.dcsParams = ""
+		t.finishDCS()
+	} else if b == 0x18 || b == 0x1a { // CAN and SUB abort the string
 		t.state = StateGround
 	} else {
-		t.dcsParams += string(b)
+		t.dcsParams = t.appendString(t.dcsParams, maxDCSLength, b)
 	}
 }
 
 // processDCSEscape handles bytes after ESC in DCS state
 func (t *Terminal) processDCSEscape(b byte) {
 	if b == 0x5c { // Backslash completes ST (ESC \)
-		t.handleDCS(t.dcsParams)
-		t.dcsParams = ""
-		t.state = StateGround
+		t.finishDCS()
 	} else {
 		// Not ST, treat as part of DCS
-		t.dcsParams += "\x1b" + string(b)
+		t.dcsParams = t.appendString(t.dcsParams, maxDCSLength, 0x1b, b)
 		t.state = StateDCS
 	}
 }
 
+// finishDCS acts on the DCS string just terminated, unless it was too long
+// to have been kept whole
+func (t *Terminal) finishDCS() {
+	t.state = StateGround
+	if t.stringTooLong {
+		logging.Debugf(logging.Parser, "Dropped DCS longer than %d bytes", maxDCSLength)
+		return
+	}
+	if t.allowString() {
+		t.handleDCS(string(t.dcsParams))
+	}
+}
+
+// appendString adds bytes to an OSC or DCS string until it holds limit
+// bytes, after which they are discarded and the string marked too long
+func (t *Terminal) appendString(buf []byte, limit int, b ...byte) []byte {
+	if len(buf)+len(b) > limit {
+		t.stringTooLong = true
+		return buf
+	}
+	return append(buf, b...)
+}
+
+// allowString reports whether another OSC or DCS string may be acted on
+// this second. Past maxStringsPerSecond strings are dropped, so a flood of
+// them cannot keep the parser busy with titles, marks and replies.
+func (t *Terminal) allowString() bool {
+	now := time.Now()
+	if now.Sub(t.stringWindow) >= time.Second {
+		if t.stringsDropped > 0 {
+			logging.Debugf(logging.Parser, "Dropped %d OSC/DCS strings over %d per second", t.stringsDropped, maxStringsPerSecond)
+		}
+		t.stringWindow = now
+		t.stringCount = 0
+		t.stringsDropped = 0
+	}
+	if t.stringCount >= maxStringsPerSecond {
+		t.stringsDropped++
+		return false
+	}
+	t.stringCount++
+	return true
+}
+
 // handleDCS handles DCS sequences like XTGETTCAP
 func (t *Terminal) handleDCS(params string) {
 	if t.responseWriter == nil {
@@ -919,10 +1190,306 @@ func (t *Terminal) handleOSC(params string) {
 		path := parseOSC7Path(value)
 		if path != "" {
 			t.lastWorkingDir = path
+			t.lastHost = parseOSC7Host(value)
+		}
+		t.promptCount++
+	case "133": // Shell integration prompt marks
+		switch {
+		case strings.HasPrefix(value, "A"): // Prompt start
+			t.promptCount++
+			t.commandMark.active = false
+			// Shells that do not send 133;D end the command at the next prompt
+			t.commandTimer.finish(0, false)
+			if !t.alternateScreen {
+				// Output that did not end in a newline shares the prompt's line
+				col, _ := t.Grid.GetCursor()
+				t.outputMark.promptDone(t.cursorLine(), col > 0)
+			}
+		case strings.HasPrefix(value, "B"): // Prompt end, command input starts here
+			if !t.alternateScreen {
+				t.Grid.MarkPrompt()
+			}
+			col, row := t.Grid.GetCursor()
+			t.commandMark = commandMark{
+				active:   true,
+				col:      col,
+				row:      row,
+				scrolled: t.Grid.ScrolledLines(),
+			}
+		case strings.HasPrefix(value, "C"): // Command output starts
+			command := ""
+			if t.commandMark.active {
+				command = t.commandText()
+			}
+			t.commandTimer.start(command)
+			t.commandMark.active = false
+			if !t.alternateScreen {
+				t.outputMark.commandStarted(t.cursorLine())
+			}
+		case strings.HasPrefix(value, "D"): // Command finished, with its exit status
+			code, err := strconv.Atoi(strings.TrimPrefix(value, "D;"))
+			t.commandTimer.finish(code, err == nil)
 		}
+	case "1337": // iTerm2 user variables and badge
+		t.handleITerm(value)
+	case "1338": // Raven inline widgets
+		if w, ok := parseWidget(value); ok {
+			t.Grid.PlaceWidget(w, t.currentBg)
+		}
+	default:
+		logging.Debugf(logging.Parser, "Unhandled OSC %s", code)
+	}
+}
+
+// handleITerm handles the iTerm2 OSC 1337 commands that set user variables
+// and the badge format. Values are base64 encoded.
+func (t *Terminal) handleITerm(value string) {
+	command, arg, _ := strings.Cut(value, "=")
+	switch command {
+	case "SetUserVar":
+		name, encoded, _ := strings.Cut(arg, "=")
+		if name == "" {
+			return
+		}
+		decoded, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(decoded) > maxUserVarLength {
+			logging.Debugf(logging.Parser, "Ignoring user variable %s", name)
+			return
+		}
+		if len(decoded) == 0 {
+			delete(t.userVars, name)
+			return
+		}
+		if t.userVars == nil {
+			t.userVars = make(map[string]string)
+		}
+		if _, ok := t.userVars[name]; !ok && len(t.userVars) >= maxUserVars {
+			logging.Debugf(logging.Parser, "Too many user variables, ignoring %s", name)
+			return
+		}
+		t.userVars[name] = string(decoded)
+	case "SetBadgeFormat":
+		decoded, err := base64.StdEncoding.DecodeString(arg)
+		if err != nil || len(decoded) > maxUserVarLength {
+			logging.Debugf(logging.Parser, "Ignoring badge format")
+			return
+		}
+		t.badgeFormat = string(decoded)
+	default:
+		logging.Debugf(logging.Parser, "Unhandled OSC 1337 %s", command)
+	}
+}
+
+// mediaCopy handles the DEC printer controller sequences: printing the
+// screen or the cursor line, auto print, and printer controller mode
+func (t *Terminal) mediaCopy(params []int) {
+	n := t.getParam(params, 0, 0)
+	if strings.HasPrefix(t.csiParams, "?") {
+		switch n {
+		case 1: // Print the cursor line
+			line := t.cursorLine()
+			t.toPrinter(t.Grid.TextBetween(line, line+1) + "\n")
+		case 4: // Auto print off
+			t.autoPrint = false
+		case 5: // Auto print on
+			t.autoPrint = true
+		}
+		return
+	}
+	switch n {
+	case 0: // Print the screen
+		start := t.Grid.ScrolledLines()
+		t.toPrinter(t.Grid.TextBetween(start, start+t.Grid.Rows) + "\n")
+	case 5: // Printer controller on; CSI 4 i is seen in printControllerByte
+		t.printerController = true
 	}
 }
 
+// printControllerByte diverts a byte of output to the printer, ending
+// printer controller mode at CSI 4 i
+func (t *Terminal) printControllerByte(b byte) {
+	t.printerBuf = append(t.printerBuf, b)
+	for _, end := range printerControllerOff {
+		if bytes.HasSuffix(t.printerBuf, end) {
+			t.printerBuf = t.printerBuf[:len(t.printerBuf)-len(end)]
+			t.flushPrinter(0)
+			t.printerController = false
+			return
+		}
+	}
+	if len(t.printerBuf) >= maxPrinterBuffer {
+		t.flushPrinter(len(printerControllerOff[0]) - 1)
+	}
+}
+
+// flushPrinter hands diverted output to the printer, holding back the last keep bytes
+func (t *Terminal) flushPrinter(keep int) {
+	n := len(t.printerBuf) - keep
+	if n <= 0 {
+		return
+	}
+	if t.printer != nil {
+		t.printer(append([]byte(nil), t.printerBuf[:n]...))
+	}
+	t.printerBuf = append(t.printerBuf[:0], t.printerBuf[n:]...)
+}
+
+func (t *Terminal) toPrinter(text string) {
+	if t.printer != nil {
+		t.printer([]byte(text))
+	}
+}
+
+// commandMark records where the shell's command input begins (OSC 133;B)
+type commandMark struct {
+	active   bool
+	col      int
+	row      int
+	scrolled int // Grid.ScrolledLines when the mark was set
+}
+
+// CommandLine returns the command currently being edited at the shell prompt,
+// read from the screen after the OSC 133;B mark. It reports false when the
+// shell has not marked its prompt or a full-screen program is running.
+func (t *Terminal) CommandLine() (string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.commandMark.active || t.alternateScreen {
+		return "", false
+	}
+	return t.commandText(), true
+}
+
+// PromptRow returns the screen row where the command being edited at the
+// shell prompt starts. It reports false under the same conditions as
+// CommandLine, or when that row has scrolled off screen.
+func (t *Terminal) PromptRow() (int, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.commandMark.active || t.alternateScreen {
+		return 0, false
+	}
+	row := t.commandMark.row - (t.Grid.ScrolledLines() - t.commandMark.scrolled)
+	return row, row >= 0
+}
+
+// commandText reads the command after the OSC 133;B mark (internal, no lock)
+func (t *Terminal) commandText() string {
+	row := t.commandMark.row - (t.Grid.ScrolledLines() - t.commandMark.scrolled)
+	col := t.commandMark.col
+	if row < 0 {
+		// The start of the command scrolled off screen
+		row, col = 0, 0
+	}
+	return t.Grid.TextFrom(col, row)
+}
+
+// CommandResult describes a command that finished at a marked prompt
+type CommandResult struct {
+	Command   string // Command line as shown on screen; empty if the prompt had no 133;B mark
+	Duration  time.Duration
+	ExitCode  int
+	ExitKnown bool // The shell reported the exit status with OSC 133;D
+}
+
+// commandTimer times the command between OSC 133;C and 133;D or the next prompt
+type commandTimer struct {
+	running  bool
+	started  time.Time
+	command  string
+	finished int // Commands finished so far
+	last     CommandResult
+}
+
+func (c *commandTimer) start(command string) {
+	c.running = true
+	c.started = time.Now()
+	c.command = strings.TrimSpace(command)
+}
+
+func (c *commandTimer) finish(exitCode int, exitKnown bool) {
+	if !c.running {
+		return
+	}
+	c.running = false
+	c.finished++
+	c.last = CommandResult{
+		Command:   c.command,
+		Duration:  time.Since(c.started),
+		ExitCode:  exitCode,
+		ExitKnown: exitKnown,
+	}
+}
+
+// FinishedCommands returns how many commands have finished at marked prompts
+// and the result of the latest one
+func (t *Terminal) FinishedCommands() (int, CommandResult) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.commandTimer.finished, t.commandTimer.last
+}
+
+// outputMark records absolute lines around the last command run at a marked
+// prompt so its prompt, command and output can be selected together
+type outputMark struct {
+	prompt  int  // line of the latest OSC 133;A
+	marked  bool // a prompt has been marked
+	start   int  // prompt line of the last command
+	end     int  // first line after its output
+	running bool // OSC 133;C seen and no new prompt yet
+	done    bool // start and end describe a finished command
+}
+
+func (m *outputMark) promptDone(line int, partial bool) {
+	if m.running {
+		m.end = line
+		if partial {
+			m.end++
+		}
+		m.running = false
+		m.done = true
+	}
+	m.prompt = line
+	m.marked = true
+}
+
+func (m *outputMark) commandStarted(line int) {
+	m.start = line
+	if m.marked {
+		m.start = m.prompt
+	}
+	m.running = true
+}
+
+// cursorLine returns the cursor's absolute line, counting rows scrolled away
+func (t *Terminal) cursorLine() int {
+	_, row := t.Grid.GetCursor()
+	return t.Grid.ScrolledLines() + row
+}
+
+// LastCommandText returns everything since the prompt of the last command
+// run at a marked prompt: the prompt, the command and its output. While a
+// command is still running the output so far is returned. It reports false
+// when the shell has not marked any prompt or a full-screen program is running.
+func (t *Terminal) LastCommandText() (string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	m := t.outputMark
+	if t.alternateScreen || !m.marked {
+		return "", false
+	}
+	switch {
+	case m.running:
+		return t.Grid.TextBetween(m.start, t.cursorLine()+1), true
+	case m.done:
+		return t.Grid.TextBetween(m.start, m.end), true
+	}
+	return t.Grid.TextBetween(m.prompt, t.cursorLine()+1), true
+}
+
 func parseOSC7Path(value string) string {
 	if strings.HasPrefix(value, "file://") {
 		parsed, err := url.Parse(value)
@@ -944,6 +1511,80 @@ func parseOSC7Path(value string) string {
 	return ""
 }
 
+// parseOSC7Host returns the hostname of an OSC 7 file:// URL, or "" when none is given
+func parseOSC7Host(value string) string {
+	if !strings.HasPrefix(value, "file://") {
+		return ""
+	}
+	parsed, err := url.Parse(value)
+	if err != nil {
+		return ""
+	}
+	return parsed.Hostname()
+}
+
+// Limits on OSC 1338 widgets
+const (
+	maxWidgetWidth  = 80
+	maxWidgetValues = 512
+)
+
+// parseWidget parses the value of an OSC 1338 widget sequence:
+//
+//	sparkline;<width>;<v1>,<v2>,...
+//	progress;<width>;<percent>
+//
+// Values that are not numbers are skipped; a sparkline keeps only its last
+// maxWidgetValues samples.
+func parseWidget(value string) (grid.Widget, bool) {
+	fields := strings.SplitN(value, ";", 3)
+	if len(fields) != 3 {
+		return grid.Widget{}, false
+	}
+	width, err := strconv.Atoi(fields[1])
+	if err != nil || width < 1 {
+		return grid.Widget{}, false
+	}
+	w := grid.Widget{Width: min(width, maxWidgetWidth)}
+
+	switch fields[0] {
+	case "sparkline":
+		w.Kind = grid.WidgetSparkline
+		samples := strings.Split(fields[2], ",")
+		if len(samples) > maxWidgetValues {
+			samples = samples[len(samples)-maxWidgetValues:]
+		}
+		for _, sample := range samples {
+			v, err := strconv.ParseFloat(strings.TrimSpace(sample), 64)
+			if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+				continue
+			}
+			w.Values = append(w.Values, v)
+		}
+		if len(w.Values) == 0 {
+			return grid.Widget{}, false
+		}
+	case "progress":
+		w.Kind = grid.WidgetProgress
+		percent, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(fields[2], "%")), 64)
+		if err != nil || math.IsNaN(percent) {
+			return grid.Widget{}, false
+		}
+		w.Values = []float64{max(0, min(percent, 100))}
+	default:
+		return grid.Widget{}, false
+	}
+	return w, true
+}
+
+// Host returns the hostname the shell last reported with OSC 7, or "" if it
+// reported none. It differs from the local hostname inside an ssh session.
+func (t *Terminal) Host() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.lastHost
+}
+
 // WorkingDir returns the last known working directory from OSC 7.
 func (t *Terminal) WorkingDir() string {
 	t.mu.Lock()
@@ -951,6 +1592,75 @@ func (t *Terminal) WorkingDir() string {
 	return t.lastWorkingDir
 }
 
+// UserVars returns a copy of the variables the shell set with OSC 1337 SetUserVar
+func (t *Terminal) UserVars() map[string]string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	vars := make(map[string]string, len(t.userVars))
+	for name, value := range t.userVars {
+		vars[name] = value
+	}
+	return vars
+}
+
+// BadgeFormat returns the badge format the shell set with OSC 1337
+// SetBadgeFormat, or "" if it has not set one
+func (t *Terminal) BadgeFormat() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.badgeFormat
+}
+
+// PromptCount increments each time the shell reports a new prompt (OSC 7 or OSC 133;A)
+func (t *Terminal) PromptCount() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.promptCount
+}
+
+// PromptMarked reports whether the shell has marked a prompt with OSC 133;A
+func (t *Terminal) PromptMarked() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.outputMark.marked
+}
+
+// BellCount increments each time a program rings the bell
+func (t *Terminal) BellCount() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.bellCount
+}
+
+// AlternateScreen reports whether a full-screen program has switched to the alternate screen
+func (t *Terminal) AlternateScreen() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.alternateScreen
+}
+
+// Memory estimates the memory held by the main and alternate screens
+func (t *Terminal) Memory() grid.MemoryStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	stats := t.Grid.Memory()
+	if t.savedMainGrid != nil {
+		stats = stats.Add(t.savedMainGrid.Memory())
+	}
+	return stats
+}
+
+// EvictScrollback drops up to n of the oldest scrollback rows from the main
+// screen, even while a full-screen program has the alternate screen up
+func (t *Terminal) EvictScrollback(n int) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.savedMainGrid != nil {
+		return t.savedMainGrid.EvictScrollback(n)
+	}
+	return t.Grid.EvictScrollback(n)
+}
+
 // BracketedPasteEnabled returns whether bracketed paste mode is enabled (?2004)
 func (t *Terminal) BracketedPasteEnabled() bool {
 	t.mu.Lock()
@@ -1031,6 +1741,9 @@ func (t *Terminal) parseSGRParams(s string) []int {
 	var params []int
 	parts := strings.Split(s, ";")
 	for _, part := range parts {
+		if len(params) >= maxCSIParams {
+			break
+		}
 		if strings.Contains(part, ":") {
 			subparts := strings.Split(part, ":")
 			first, _ := strconv.Atoi(subparts[0])
@@ -1038,15 +1751,15 @@ func (t *Terminal) parseSGRParams(s string) []int {
 				// Expand colon sub-params for extended color sequences
 				for _, sp := range subparts {
 					n, _ := strconv.Atoi(sp)
-					params = append(params, n)
+					params = append(params, clampParam(n))
 				}
 			} else {
 				// For other codes (e.g. 4:3 underline style), keep first value only
-				params = append(params, first)
+				params = append(params, clampParam(first))
 			}
 		} else {
 			n, _ := strconv.Atoi(part)
-			params = append(params, n)
+			params = append(params, clampParam(n))
 		}
 	}
 	return params
@@ -1064,6 +1777,9 @@ func (t *Terminal) parseParams(s string) []int {
 	}
 
 	parts := strings.Split(s, ";")
+	if len(parts) > maxCSIParams {
+		parts = parts[:maxCSIParams]
+	}
 	params := make([]int, len(parts))
 	for i, part := range parts {
 		// Handle sub-parameters (colon-separated) by taking the first one
@@ -1074,12 +1790,21 @@ func (t *Terminal) parseParams(s string) []int {
 		if err != nil {
 			params[i] = 0
 		} else {
-			params[i] = n
+			params[i] = clampParam(n)
 		}
 	}
 	return params
 }
 
+// clampParam limits a CSI parameter to maxCSIParamValue. Numbers too large
+// for an int fail to parse and fall back to the default instead.
+func clampParam(n int) int {
+	if n > maxCSIParamValue {
+		return maxCSIParamValue
+	}
+	return n
+}
+
 // getParam gets a parameter with a default value
 func (t *Terminal) getParam(params []int, index, defaultVal int) int {
 	if index < len(params) && params[index] > 0 {
@@ -1099,12 +1824,10 @@ func (t *Terminal) reset() {
 	t.appCursorKeys = false
 	t.cursorVisible = true
 	t.exitAlternateScreen()
-	t.charsetG0 = charsetASCII
-	t.charsetG1 = charsetASCII
-	t.activeCharset = 0
-	t.charsetPending = charsetTargetNone
+	t.resetCharsets()
 	t.originMode = false
 	t.cursorStyle = CursorStyleBlock
+	t.autoPrint = false
 }
 
 // Resize resizes the terminal
@@ -1138,6 +1861,40 @@ func (t *Terminal) AppCursorKeys() bool {
 	return t.appCursorKeys
 }
 
+// SetLatin1 enables or disables decoding of PTY output as ISO-8859-1.
+func (t *Terminal) SetLatin1(enabled bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.latin1 = enabled
+	t.utf8Buf = nil
+	t.utf8Remaining = 0
+}
+
+// SetAltScreenCapture sets whether what a full-screen program last showed on
+// the alternate screen is copied into the main screen when it exits, so it
+// can be scrolled back to.
+func (t *Terminal) SetAltScreenCapture(enabled bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.altCapture = enabled
+}
+
+// SetAltScreenMark sets whether the line where the main screen picks up again
+// after a full-screen program exits is marked as grid.AltScreenExit, to jump
+// back to with the scroll marks.
+func (t *Terminal) SetAltScreenMark(enabled bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.altMark = enabled
+}
+
+// Latin1 reports whether Latin-1 mode is enabled.
+func (t *Terminal) Latin1() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.latin1
+}
+
 // SetResponseWriter sets a callback used to write responses back to the PTY.
 func (t *Terminal) SetResponseWriter(writer func([]byte)) {
 	t.mu.Lock()
@@ -1145,6 +1902,14 @@ func (t *Terminal) SetResponseWriter(writer func([]byte)) {
 	t.responseWriter = writer
 }
 
+// SetPrinter sets the callback that receives text sent to the printer with
+// the media copy sequences (CSI i). Without one, printed text is dropped.
+func (t *Terminal) SetPrinter(printer func([]byte)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.printer = printer
+}
+
 // GetGrid returns the current grid with thread-safe access.
 // Use this from render and main goroutines instead of accessing Terminal.Grid directly.
 func (t *Terminal) GetGrid() *grid.Grid {
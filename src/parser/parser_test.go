@@ -0,0 +1,352 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/javanhut/RavenTerminal/src/grid"
+)
+
+// rowText returns the first n characters of a grid row.
+func rowText(t *Terminal, row, n int) string {
+	g := t.GetGrid()
+	runes := make([]rune, 0, n)
+	for col := 0; col < n; col++ {
+		runes = append(runes, g.GetCell(col, row).Char)
+	}
+	return string(runes)
+}
+
+func TestCharsetDesignation(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"ascii default", "q#", "q#"},
+		{"G0 line drawing", "\x1b(0qx\x1b(Bq", "─│q"},
+		{"G1 via SO/SI", "\x1b)0\x0eq\x0fq", "─q"},
+		{"G0 UK", "\x1b(A#", "£"},
+		{"G2 locking shift", "\x1b*0\x1bnq\x0fq", "─q"},
+		{"G3 locking shift", "\x1b+A\x1bo#\x0f#", "£#"},
+		{"G1 96-set latin1", "\x1b-A\x0eI\x0f", "É"},
+		{"G2 96-set latin1", "\x1b.A\x1bn`", "à"},
+		{"G3 96-set latin1", "\x1b/A\x1bo\x7e", "þ"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			term := NewTerminal(20, 4)
+			term.Process([]byte(tt.input))
+			if got := rowText(term, 0, len([]rune(tt.want))); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSingleShift(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"SS2 7-bit", "\x1b*0\x1bNqq", "─q"},
+		{"SS3 7-bit", "\x1b+A\x1bO##", "£#"},
+		{"SS2 8-bit", "\x1b*0\x8eqq", "─q"},
+		{"SS3 8-bit", "\x1b+A\x8f##", "£#"},
+		{"SS2 then SS3", "\x1b*0\x1b+A\x1bNq\x1bO#q", "─£q"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			term := NewTerminal(20, 4)
+			term.Process([]byte(tt.input))
+			if got := rowText(term, 0, len([]rune(tt.want))); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResetClearsCharsets(t *testing.T) {
+	term := NewTerminal(20, 4)
+	term.Process([]byte("\x1b*0\x1bn\x1bN\x1bcq"))
+	if got := rowText(term, 0, 1); got != "q" {
+		t.Fatalf("got %q after RIS, want %q", got, "q")
+	}
+}
+
+func TestEightBitControls(t *testing.T) {
+	term := NewTerminal(20, 4)
+	term.Process([]byte("ab\x85cd\x84e"))
+	if got := rowText(term, 0, 2); got != "ab" {
+		t.Fatalf("row 0 = %q, want %q", got, "ab")
+	}
+	if got := rowText(term, 1, 2); got != "cd" {
+		t.Fatalf("row 1 = %q, want %q", got, "cd")
+	}
+	if got := rowText(term, 2, 1); got != "e" {
+		t.Fatalf("row 2 = %q, want %q", got, "e")
+	}
+
+	term.Process([]byte("\x8d\x8df"))
+	col, row := term.GetGrid().GetCursor()
+	if row != 0 || col != 2 {
+		t.Fatalf("cursor after RI = (%d,%d), want (2,0)", col, row)
+	}
+}
+
+func TestLatin1Mode(t *testing.T) {
+	term := NewTerminal(20, 4)
+	// UTF-8 "é" followed by Latin-1 "é"; in UTF-8 mode the lone 0xe9 is dropped
+	term.Process([]byte("\xc3\xa9\xe9x"))
+	if got := rowText(term, 0, 2); got != "éx" {
+		t.Fatalf("utf-8 mode got %q, want %q", got, "éx")
+	}
+
+	term = NewTerminal(20, 4)
+	term.SetLatin1(true)
+	if !term.Latin1() {
+		t.Fatal("Latin1() = false after SetLatin1(true)")
+	}
+	term.Process([]byte("caf\xe9 \xa3\xff"))
+	if got := rowText(term, 0, 7); got != "café £ÿ" {
+		t.Fatalf("latin1 mode got %q, want %q", got, "café £ÿ")
+	}
+
+	// C1 controls still apply in Latin-1 mode
+	term.Process([]byte("\x85z"))
+	if got := rowText(term, 1, 1); got != "z" {
+		t.Fatalf("NEL in latin1 mode: row 1 = %q, want %q", got, "z")
+	}
+
+	// A single shift applies to the Latin-1 character after it, not the next ASCII one
+	term.Process([]byte("\r\n\x1b*0\x8e\xe9q"))
+	if got := rowText(term, 2, 2); got != "éq" {
+		t.Fatalf("SS2 before a latin1 byte: row 2 = %q, want %q", got, "éq")
+	}
+}
+
+func TestMediaCopy(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   []string // Written in separate Process calls
+		printed string
+		screen  string
+	}{
+		{"print screen", []string{"ab\r\ncd\x1b[i"}, "ab\ncd\n", "ab"},
+		{"print cursor line", []string{"ab\r\ncd\x1b[?1i"}, "cd\n", "ab"},
+		{"auto print", []string{"\x1b[?5iab\r\ncd\r\n\x1b[?4ief\r\n"}, "ab\ncd\n", "ab"},
+		{"controller", []string{"x\x1b[5ihidden\x1b[4iy"}, "hidden", "xy"},
+		{"controller 8-bit end", []string{"\x1b[5ihidden\x9b4iy"}, "hidden", "y"},
+		{"controller split end", []string{"\x1b[5ihid", "den\x1b[", "4iy"}, "hidden", "y"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			term := NewTerminal(20, 4)
+			var printed []byte
+			term.SetPrinter(func(data []byte) {
+				printed = append(printed, data...)
+			})
+			for _, input := range tt.input {
+				term.Process([]byte(input))
+			}
+			if string(printed) != tt.printed {
+				t.Fatalf("printed %q, want %q", printed, tt.printed)
+			}
+			if got := rowText(term, 0, len([]rune(tt.screen))); got != tt.screen {
+				t.Fatalf("screen row 0 = %q, want %q", got, tt.screen)
+			}
+		})
+	}
+}
+
+func TestUserVars(t *testing.T) {
+	term := NewTerminal(20, 4)
+	// "prod" and "\(user.ctx)" in base64
+	term.Process([]byte("\x1b]1337;SetUserVar=ctx=cHJvZA==\x07"))
+	term.Process([]byte("\x1b]1337;SetBadgeFormat=XCh1c2VyLmN0eCk=\x1b\\"))
+	if got := term.UserVars()["ctx"]; got != "prod" {
+		t.Fatalf("ctx = %q, want %q", got, "prod")
+	}
+	if got := term.BadgeFormat(); got != `\(user.ctx)` {
+		t.Fatalf("badge format = %q", got)
+	}
+
+	term.Process([]byte("\x1b]1337;SetUserVar=ctx=not base64\x07"))
+	if got := term.UserVars()["ctx"]; got != "prod" {
+		t.Fatalf("invalid value replaced ctx with %q", got)
+	}
+	term.Process([]byte("\x1b]1337;SetUserVar=ctx=\x07"))
+	if _, ok := term.UserVars()["ctx"]; ok {
+		t.Fatalf("empty value did not unset ctx")
+	}
+}
+
+// fuzzSeeds are well-formed and malformed sequences the fuzzers start from
+var fuzzSeeds = []string{
+	"plain text\r\nnext line",
+	"\x1b[31;1mred\x1b[0m\x1b[38;2;1;2;3mrgb\x1b[48;5;200mbg",
+	"\x1b[2J\x1b[H\x1b[10;20H\x1b[K\x1b[5@\x1b[3P\x1b[2L\x1b[2M\x1b[4X",
+	"\x1b[?1049h\x1b[?25l\x1b[3;10r\x1b[5S\x1b[5T\x1b[?1049l",
+	"a\x1b[4294967295b\x1b[99999999999999999999@\x1b[2147483647L",
+	"\x1b[" + strings.Repeat("1;", 4096) + "m",
+	"\x1b]0;title\x07\x1b]7;file://host/tmp\x1b\\\x1b]133;A\x07$ \x1b]133;B\x07",
+	"\x1b]1337;SetUserVar=ctx=cHJvZA==\x07\x1bP+q544e\x1b\\",
+	"\x1b(0qx\x1b(B\x0e\x0f\x1b*0\x1bN\x8e\x9b1m\x9d0;t\x9c",
+	"\xc3\xa9\xf0\x9f\x98\x80\xe4\xb8\xad\xff\xfe\xc3",
+	"\x1b[5ihidden\x1b[4i\x1b[?5iab\r\n\x1b[?4i",
+}
+
+// FuzzProcess feeds arbitrary output to a terminal in two reads and checks
+// it neither panics nor leaves the cursor outside the screen
+func FuzzProcess(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add([]byte(seed), uint8(len(seed)/2))
+	}
+	f.Fuzz(func(t *testing.T, data []byte, split uint8) {
+		term := NewTerminal(80, 24)
+		term.SetResponseWriter(func([]byte) {})
+		term.SetPrinter(func([]byte) {})
+		cut := min(int(split), len(data))
+		term.Process(data[:cut])
+		term.Process(data[cut:])
+
+		g := term.GetGrid()
+		col, row := g.GetCursor()
+		if col < 0 || col >= g.Cols || row < 0 || row >= g.Rows {
+			t.Fatalf("cursor (%d,%d) outside %dx%d screen", col, row, g.Cols, g.Rows)
+		}
+	})
+}
+
+func TestPathologicalCSI(t *testing.T) {
+	term := NewTerminal(20, 4)
+	term.Process([]byte("abcdef\x1b[1;3H\x1b[2147483647P\x1b[99999999999999999999@x"))
+	if got := rowText(term, 0, 4); got != "abx " {
+		t.Fatalf("row after huge DCH = %q, want %q", got, "abx ")
+	}
+
+	// A CSI longer than maxCSILength is dropped whole, not executed truncated
+	term = NewTerminal(20, 4)
+	term.Process([]byte("\x1b[" + strings.Repeat("1;", maxCSILength) + "31mok"))
+	if got := rowText(term, 0, 2); got != "ok" {
+		t.Fatalf("text after long CSI = %q, want %q", got, "ok")
+	}
+	if fg := term.GetGrid().GetCell(0, 0).Fg; fg != grid.DefaultFg() {
+		t.Fatalf("long CSI was executed: fg = %v", fg)
+	}
+
+	// Huge counts finish at once instead of repeating billions of times
+	term = NewTerminal(20, 4)
+	term.Process([]byte("x\x1b[4294967295b\x1b[2147483647S\x1b[2147483647T\x1b[2147483647L"))
+	if col, row := term.GetGrid().GetCursor(); row >= 4 || col >= 20 {
+		t.Fatalf("cursor (%d,%d) outside the screen", col, row)
+	}
+}
+
+func TestOversizedStrings(t *testing.T) {
+	// A title past maxOSCLength is cut short, on a character boundary
+	term := NewTerminal(20, 4)
+	term.Process([]byte("\x1b]2;" + strings.Repeat("é", maxOSCLength) + "\x07ok"))
+	title := term.GetWindowTitle()
+	if len(title) == 0 || len(title) > maxOSCLength || strings.Trim(title, "é") != "" {
+		t.Fatalf("truncated title has %d bytes or partial characters", len(title))
+	}
+	if got := rowText(term, 0, 2); got != "ok" {
+		t.Fatalf("text after long title = %q, want %q", got, "ok")
+	}
+
+	// Other commands past the limit are dropped rather than acted on in part
+	term = NewTerminal(20, 4)
+	term.Process([]byte("\x1b]7;file://host/" + strings.Repeat("a", maxOSCLength) + "\x1b\\"))
+	if dir := term.WorkingDir(); dir != "" {
+		t.Fatalf("oversized OSC 7 set working dir to %d bytes", len(dir))
+	}
+
+	var replies []byte
+	term = NewTerminal(20, 4)
+	term.SetResponseWriter(func(data []byte) { replies = append(replies, data...) })
+	term.Process([]byte("\x1bP+q" + strings.Repeat("544e3b", maxDCSLength) + "\x1b\\ok"))
+	if len(replies) != 0 {
+		t.Fatalf("oversized DCS was answered with %q", replies)
+	}
+	if got := rowText(term, 0, 2); got != "ok" {
+		t.Fatalf("text after long DCS = %q, want %q", got, "ok")
+	}
+
+	// CAN aborts a string without acting on it
+	term = NewTerminal(20, 4)
+	term.Process([]byte("\x1b]2;abc\x18xyz"))
+	if title := term.GetWindowTitle(); title != "" {
+		t.Fatalf("aborted OSC set title %q", title)
+	}
+	if got := rowText(term, 0, 3); got != "xyz" {
+		t.Fatalf("text after CAN = %q, want %q", got, "xyz")
+	}
+}
+
+func TestStringRateLimit(t *testing.T) {
+	term := NewTerminal(20, 4)
+	var b strings.Builder
+	for i := 0; i < maxStringsPerSecond+10; i++ {
+		fmt.Fprintf(&b, "\x1b]2;t%d\x07", i)
+	}
+	term.Process([]byte(b.String()))
+	if got, want := term.GetWindowTitle(), fmt.Sprintf("t%d", maxStringsPerSecond-1); got != want {
+		t.Fatalf("title = %q, want %q: strings past the limit were acted on", got, want)
+	}
+}
+
+func TestAltScreenCapture(t *testing.T) {
+	const session = "$ less\r\n\x1b[?1049h\x1b[Hline one\r\nline two\x1b[?1049l$ "
+
+	term := NewTerminal(20, 6)
+	term.Process([]byte(session))
+	if got := rowText(term, 1, 8); got != "$       " {
+		t.Fatalf("row 1 without capture = %q, want the prompt", got)
+	}
+
+	term = NewTerminal(20, 6)
+	term.SetAltScreenCapture(true)
+	term.Process([]byte(session))
+	want := []string{"$ less", "line one", "line two", "$"}
+	for row, line := range want {
+		if got := strings.TrimRight(rowText(term, row, 20), " "); got != line {
+			t.Fatalf("row %d with capture = %q, want %q", row, got, line)
+		}
+	}
+}
+
+func TestAltScreenRestoresView(t *testing.T) {
+	term := NewTerminal(20, 4)
+	term.SetAltScreenCapture(true)
+	term.SetAltScreenMark(true)
+	for i := 0; i < 10; i++ {
+		term.Process([]byte(fmt.Sprintf("line %d\r\n", i)))
+	}
+	term.GetGrid().ScrollViewUp(3)
+	top := term.GetGrid().ViewTop()
+
+	term.Process([]byte("\x1b[?1049hone\r\ntwo\x1b[?1049l"))
+	if got := term.GetGrid().ViewTop(); got != top {
+		t.Fatalf("view top after exit = %d, want %d", got, top)
+	}
+
+	// The mark is on the first captured line
+	g := term.GetGrid()
+	g.ResetScrollOffset()
+	for row := 0; row < 4; row++ {
+		if len(g.DisplayBookmarks(row)) == 0 {
+			continue
+		}
+		if got := strings.TrimRight(rowText(term, row, 20), " "); got != "one" {
+			t.Fatalf("row at mark = %q, want %q", got, "one")
+		}
+		return
+	}
+	t.Fatal("no mark where the main screen picks up")
+}
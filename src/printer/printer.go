@@ -0,0 +1,168 @@
+package printer
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/javanhut/RavenTerminal/src/logging"
+)
+
+// Page layout of printed PDFs: US Letter in 10 point Courier
+const (
+	pageWidth  = 612 // Points
+	pageHeight = 792
+	margin     = 36
+	fontSize   = 10
+	leading    = 12
+	charWidth  = 6 // Courier advances 0.6 em
+)
+
+// lineChars and pageLines are how much text fits on one page
+const (
+	lineChars = (pageWidth - 2*margin) / charWidth
+	pageLines = (pageHeight - 2*margin) / leading
+)
+
+// AppendTo returns a printer for parser.Terminal.SetPrinter that appends
+// what programs print to the file at path
+func AppendTo(path string) func([]byte) {
+	path = expandHome(path)
+	return func(data []byte) {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			logging.Warnf(logging.App, "Failed to print to %s: %v", path, err)
+			return
+		}
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			logging.Warnf(logging.App, "Failed to print to %s: %v", path, err)
+			return
+		}
+		defer f.Close()
+		if _, err := f.Write(data); err != nil {
+			logging.Warnf(logging.App, "Failed to print to %s: %v", path, err)
+		}
+	}
+}
+
+// DefaultPath returns where a new PDF is written: the home directory, named
+// for the current time
+func DefaultPath() string {
+	name := "raven-terminal-print-" + time.Now().Format("20060102-150405") + ".pdf"
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return name
+	}
+	return filepath.Join(homeDir, name)
+}
+
+// WritePDF writes text to path as a PDF, wrapping long lines and starting a
+// new page every pageLines lines. Each page is headed with title and its
+// page number. Characters outside Latin-1 are printed as '?', as the
+// standard PDF fonts cannot show them.
+func WritePDF(path, title, text string) error {
+	pages := paginate(wrapLines(text))
+
+	var out bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	out.WriteString("%PDF-1.4\n")
+	// Objects 1-3 are the catalog, page tree and font; each page then takes
+	// a page object and a content stream
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+2*i)
+	}
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+	for i, lines := range pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, 5+2*i))
+		header := fmt.Sprintf("%s - page %d of %d", title, i+1, len(pages))
+		stream := pageStream(header, lines)
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(stream), stream))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return os.WriteFile(expandHome(path), out.Bytes(), 0644)
+}
+
+// pageStream draws the header in the top margin and the lines below it
+func pageStream(header string, lines []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "BT /F1 8 Tf %d %d Td (%s) Tj ET\n", margin, pageHeight-margin/2-4, pdfString(header))
+	fmt.Fprintf(&b, "BT /F1 %d Tf %d TL %d %d Td\n", fontSize, leading, margin, pageHeight-margin-fontSize)
+	for _, line := range lines {
+		fmt.Fprintf(&b, "(%s) Tj T*\n", pdfString(line))
+	}
+	b.WriteString("ET")
+	return b.String()
+}
+
+// wrapLines splits text into lines of at most lineChars characters
+func wrapLines(text string) []string {
+	text = strings.ReplaceAll(strings.TrimRight(text, "\n"), "\r\n", "\n")
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		runes := []rune(strings.ReplaceAll(line, "\t", "        "))
+		for len(runes) > lineChars {
+			lines = append(lines, string(runes[:lineChars]))
+			runes = runes[lineChars:]
+		}
+		lines = append(lines, string(runes))
+	}
+	return lines
+}
+
+// paginate groups lines into pages; there is always at least one page
+func paginate(lines []string) [][]string {
+	var pages [][]string
+	for len(lines) > pageLines {
+		pages = append(pages, lines[:pageLines])
+		lines = lines[pageLines:]
+	}
+	return append(pages, lines)
+}
+
+// pdfString escapes s for a PDF literal string in WinAnsi encoding
+func pdfString(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 0x20 && r < 0x7f:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// expandHome replaces a leading ~/ with the user's home directory
+func expandHome(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return home + path[1:]
+		}
+	}
+	return path
+}
@@ -0,0 +1,79 @@
+package procmon
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// psFields are the columns readAllStats asks ps for; command comes last as
+// it may contain spaces
+const psFields = "pid=,ppid=,uid=,state=,rss=,time=,command="
+
+// readAllStats lists every running process with ps, as macOS has no /proc
+func readAllStats() map[int]procStat {
+	stats := make(map[int]procStat)
+	out, err := exec.Command("/bin/ps", "-axww", "-o", psFields).Output()
+	if err != nil {
+		return stats
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		if st, ok := parsePSLine(line); ok {
+			stats[st.pid] = st
+		}
+	}
+	return stats
+}
+
+// parsePSLine parses a line of ps output in the order of psFields
+func parsePSLine(line string) (procStat, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 7 {
+		return procStat{}, false
+	}
+	pid, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return procStat{}, false
+	}
+	ppid, _ := strconv.Atoi(fields[1])
+	uid, err := strconv.Atoi(fields[2])
+	if err != nil {
+		uid = -1
+	}
+	rssKB, _ := strconv.ParseUint(fields[4], 10, 64)
+	command := strings.Join(fields[6:], " ")
+	return procStat{
+		pid:     pid,
+		ppid:    ppid,
+		name:    filepath.Base(fields[6]),
+		command: command,
+		// ps adds flags such as "s" and "+" after the state letter
+		state: fields[3][:1],
+		ticks: parseCPUTime(fields[5]),
+		rss:   rssKB * 1024,
+		uid:   uid,
+	}, true
+}
+
+// parseCPUTime converts a ps CPU time such as "1:02.34" or "2-03:04:05.67"
+// to centiseconds
+func parseCPUTime(s string) uint64 {
+	var days uint64
+	if d, rest, ok := strings.Cut(s, "-"); ok {
+		days, _ = strconv.ParseUint(d, 10, 64)
+		s = rest
+	}
+	var seconds float64
+	for _, part := range strings.Split(s, ":") {
+		value, _ := strconv.ParseFloat(part, 64)
+		seconds = seconds*60 + value
+	}
+	return days*24*60*60*clockTicks + uint64(seconds*clockTicks+0.5)
+}
+
+// commandLine returns the full command line of the process, which ps
+// reported along with the rest
+func (st procStat) commandLine() string {
+	return st.command
+}
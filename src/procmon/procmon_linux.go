@@ -0,0 +1,78 @@
+package procmon
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// readAllStats reads /proc/<pid>/stat for every running process
+func readAllStats() map[int]procStat {
+	stats := make(map[int]procStat)
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return stats
+	}
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		if st, ok := readStat(pid); ok {
+			stats[pid] = st
+		}
+	}
+	return stats
+}
+
+// readStat parses /proc/<pid>/stat. The command name is wrapped in parentheses
+// and may itself contain spaces or parentheses, so fields are split after the last ')'.
+func readStat(pid int) (procStat, bool) {
+	dir := "/proc/" + strconv.Itoa(pid)
+	data, err := os.ReadFile(dir + "/stat")
+	if err != nil {
+		return procStat{}, false
+	}
+	// /proc/<pid> is owned by the process's effective user
+	uid := -1
+	if info, err := os.Stat(dir); err == nil {
+		if sys, ok := info.Sys().(*syscall.Stat_t); ok {
+			uid = int(sys.Uid)
+		}
+	}
+	text := string(data)
+	start := strings.IndexByte(text, '(')
+	end := strings.LastIndexByte(text, ')')
+	if start < 0 || end < start {
+		return procStat{}, false
+	}
+	fields := strings.Fields(text[end+1:])
+	// fields[0] is field 3 (state) in proc(5) numbering
+	if len(fields) < 22 {
+		return procStat{}, false
+	}
+	ppid, _ := strconv.Atoi(fields[1])
+	utime, _ := strconv.ParseUint(fields[11], 10, 64)
+	stime, _ := strconv.ParseUint(fields[12], 10, 64)
+	pages, _ := strconv.ParseUint(fields[21], 10, 64)
+	return procStat{
+		pid:   pid,
+		ppid:  ppid,
+		name:  text[start+1 : end],
+		state: fields[0],
+		ticks: utime + stime,
+		rss:   pages * uint64(os.Getpagesize()),
+		uid:   uid,
+	}, true
+}
+
+// commandLine returns the full command line of the process from
+// /proc/<pid>/cmdline
+func (st procStat) commandLine() string {
+	data, err := os.ReadFile("/proc/" + strconv.Itoa(st.pid) + "/cmdline")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(strings.ReplaceAll(string(data), "\x00", " "))
+}
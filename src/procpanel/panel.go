@@ -0,0 +1,185 @@
+package procpanel
+
+import (
+	"time"
+
+	"github.com/javanhut/RavenTerminal/src/procmon"
+)
+
+// RefreshInterval is how often the process list is resampled while the panel is open
+const RefreshInterval = time.Second
+
+// Entry is a process row labelled with the pane it belongs to
+type Entry struct {
+	TabIndex  int
+	PaneIndex int
+	Process   procmon.Process
+}
+
+type Panel struct {
+	Open        bool
+	Focused     bool
+	Entries     []Entry
+	Selected    int
+	Scroll      int
+	Status      string
+	LastRefresh time.Time
+}
+
+type Layout struct {
+	PanelX       float32
+	PanelY       float32
+	PanelWidth   float32
+	PanelHeight  float32
+	ContentX     float32
+	ContentWidth float32
+	LineHeight   float32
+	HeaderY      float32
+	StatusY      float32
+	ListStart    float32
+	ListEnd      float32
+	FooterY      float32
+	VisibleLines int
+}
+
+func New() *Panel {
+	return &Panel{}
+}
+
+func (p *Panel) Toggle() {
+	p.Open = !p.Open
+	if p.Open {
+		p.Focused = true
+		p.Status = ""
+		p.LastRefresh = time.Time{}
+	}
+}
+
+// NeedsRefresh reports whether the process list is due to be resampled
+func (p *Panel) NeedsRefresh(now time.Time) bool {
+	return p.Open && now.Sub(p.LastRefresh) >= RefreshInterval
+}
+
+// SetEntries replaces the process list, keeping the selection on the same PID when possible
+func (p *Panel) SetEntries(entries []Entry, now time.Time) {
+	selectedPID := 0
+	if entry, ok := p.SelectedEntry(); ok {
+		selectedPID = entry.Process.PID
+	}
+
+	p.Entries = entries
+	p.LastRefresh = now
+	p.Selected = 0
+	for i, entry := range entries {
+		if entry.Process.PID == selectedPID {
+			p.Selected = i
+			break
+		}
+	}
+}
+
+// SelectedEntry returns the currently selected process
+func (p *Panel) SelectedEntry() (Entry, bool) {
+	if p.Selected < 0 || p.Selected >= len(p.Entries) {
+		return Entry{}, false
+	}
+	return p.Entries[p.Selected], true
+}
+
+func (p *Panel) MoveSelection(delta int, visibleLines int) {
+	if len(p.Entries) == 0 {
+		return
+	}
+	p.Selected += delta
+	if p.Selected < 0 {
+		p.Selected = 0
+	}
+	if p.Selected >= len(p.Entries) {
+		p.Selected = len(p.Entries) - 1
+	}
+	p.ensureSelectionVisible(visibleLines)
+}
+
+func (p *Panel) ensureSelectionVisible(visibleLines int) {
+	if visibleLines <= 0 {
+		return
+	}
+	if p.Selected < p.Scroll {
+		p.Scroll = p.Selected
+	}
+	if p.Selected >= p.Scroll+visibleLines {
+		p.Scroll = p.Selected - visibleLines + 1
+	}
+	maxScroll := len(p.Entries) - visibleLines
+	if maxScroll < 0 {
+		maxScroll = 0
+	}
+	if p.Scroll > maxScroll {
+		p.Scroll = maxScroll
+	}
+	if p.Scroll < 0 {
+		p.Scroll = 0
+	}
+}
+
+func (p *Panel) Layout(width, height int, cellWidth, cellHeight float32) Layout {
+	panelWidth := float32(width) * 0.45
+	minPanelWidth := float32(420)
+	if cellWidth > 0 {
+		wideMin := cellWidth * 48
+		if wideMin > minPanelWidth {
+			minPanelWidth = wideMin
+		}
+	}
+	if panelWidth < minPanelWidth {
+		panelWidth = minPanelWidth
+	}
+	if panelWidth > 760 {
+		panelWidth = 760
+	}
+	maxWidth := float32(width) - 20
+	if panelWidth > maxWidth {
+		panelWidth = maxWidth
+	}
+
+	panelHeight := float32(height) - 30
+	if panelHeight < 240 {
+		panelHeight = 240
+	}
+	if panelHeight > float32(height)-20 {
+		panelHeight = float32(height) - 20
+	}
+
+	panelX := float32(width) - panelWidth - 10
+	panelY := float32(10)
+
+	lineHeight := cellHeight * 1.35
+	contentX := panelX + 18
+	contentWidth := panelWidth - 36
+	headerY := panelY + lineHeight*1.2
+	statusY := headerY + lineHeight*1.1
+	listStart := statusY + lineHeight*1.3
+	footerY := panelY + panelHeight - lineHeight*0.6
+	listEnd := footerY - lineHeight*1.2
+
+	visibleLines := int((listEnd - listStart) / lineHeight)
+	if visibleLines < 1 {
+		visibleLines = 1
+	}
+
+	return Layout{
+		PanelX:       panelX,
+		PanelY:       panelY,
+		PanelWidth:   panelWidth,
+		PanelHeight:  panelHeight,
+		ContentX:     contentX,
+		ContentWidth: contentWidth,
+		LineHeight:   lineHeight,
+		HeaderY:      headerY,
+		StatusY:      statusY,
+		ListStart:    listStart,
+		ListEnd:      listEnd,
+		FooterY:      footerY,
+		VisibleLines: visibleLines,
+	}
+}
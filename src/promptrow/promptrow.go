@@ -0,0 +1,255 @@
+package promptrow
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/javanhut/RavenTerminal/src/gitstatus"
+)
+
+// Built-in segments
+const (
+	SegmentGit      = "git"
+	SegmentIvaldi   = "ivaldi"
+	SegmentLanguage = "language"
+	SegmentVenv     = "venv"
+	SegmentKube     = "kube"
+)
+
+// DefaultSegments are shown when the config names none
+var DefaultSegments = []string{SegmentGit, SegmentLanguage, SegmentVenv, SegmentKube}
+
+// languages maps project files to the language they mark, checked in order.
+// version, when set, reads the runtime version the project pins.
+var languages = []struct {
+	file    string
+	name    string
+	version func(dir string) string
+}{
+	{"go.mod", "go", goVersion},
+	{"Cargo.toml", "rust", rustVersion},
+	{"package.json", "node", nodeVersion},
+	{"pyproject.toml", "python", pythonVersion},
+	{"requirements.txt", "python", pythonVersion},
+	{"Pipfile", "python", pythonVersion},
+	{"setup.py", "python", pythonVersion},
+	{"Gemfile", "ruby", rubyVersion},
+	{"pom.xml", "java", nil},
+	{"build.gradle", "java", nil},
+	{"build.gradle.kts", "kotlin", nil},
+	{"mix.exs", "elixir", nil},
+	{"composer.json", "php", nil},
+	{"Package.swift", "swift", nil},
+	{"build.zig", "zig", nil},
+	{"CMakeLists.txt", "c/c++", nil},
+}
+
+// venvDirs are the directory names searched for a project's virtualenv
+var venvDirs = []string{".venv", "venv"}
+
+// detectGit returns the branch and state of the git work tree holding dir
+func detectGit(ctx context.Context, dir string, vars map[string]string) string {
+	status, ok := gitstatus.Query(ctx, dir)
+	if !ok {
+		return ""
+	}
+	return status.Label()
+}
+
+// detectIvaldi returns the Ivaldi timeline of the repository in dir, asking
+// ivaldi when it is installed and reading .ivaldi otherwise. Like the shell
+// detector it looks only in dir itself.
+func detectIvaldi(ctx context.Context, dir string, vars map[string]string) string {
+	if _, err := exec.LookPath("ivaldi"); err == nil {
+		ctx, cancel := context.WithTimeout(ctx, gitstatus.QueryTimeout)
+		defer cancel()
+		cmd := exec.CommandContext(ctx, "ivaldi", "whereami")
+		cmd.Dir = dir
+		if out, err := cmd.Output(); err == nil {
+			if timeline := timelineOf(string(out), false); timeline != "" {
+				return timeline
+			}
+		}
+	}
+	path := filepath.Join(dir, ".ivaldi")
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	files := []string{path}
+	if info.IsDir() {
+		files = []string{filepath.Join(path, "timeline"), filepath.Join(path, "whereami"), filepath.Join(path, "wai")}
+	}
+	for _, file := range files {
+		if data, err := os.ReadFile(file); err == nil {
+			if timeline := timelineOf(string(data), true); timeline != "" {
+				return timeline
+			}
+		}
+	}
+	return "?"
+}
+
+// timelineOf finds a "timeline: name" line in out, or with orFirst falls
+// back to its first non-empty line
+func timelineOf(out string, orFirst bool) string {
+	fallback := ""
+	for _, line := range strings.Split(out, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(key), "timeline") {
+			return strings.TrimSpace(value)
+		}
+		if fallback == "" {
+			fallback = strings.TrimSpace(line)
+		}
+	}
+	if orFirst {
+		return fallback
+	}
+	return ""
+}
+
+// detectLanguage returns the language of the nearest project file in dir or
+// the directories above it, with the runtime version the project pins
+func detectLanguage(dir string) string {
+	for ; ; dir = filepath.Dir(dir) {
+		for _, lang := range languages {
+			if _, err := os.Stat(filepath.Join(dir, lang.file)); err == nil {
+				if lang.version != nil {
+					if version := lang.version(dir); version != "" {
+						return lang.name + " " + version
+					}
+				}
+				return lang.name
+			}
+		}
+		if parent := filepath.Dir(dir); parent == dir {
+			return ""
+		}
+	}
+}
+
+// goVersion reads the toolchain or go directive of go.mod
+func goVersion(dir string) string {
+	data, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return ""
+	}
+	version := ""
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		switch fields[0] {
+		case "toolchain":
+			return strings.TrimPrefix(fields[1], "go")
+		case "go":
+			version = fields[1]
+		}
+	}
+	return version
+}
+
+// rustVersion reads the channel of rust-toolchain.toml or rust-toolchain
+func rustVersion(dir string) string {
+	if data, err := os.ReadFile(filepath.Join(dir, "rust-toolchain.toml")); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			if key, value, ok := strings.Cut(line, "="); ok && strings.TrimSpace(key) == "channel" {
+				return strings.Trim(strings.TrimSpace(value), `"'`)
+			}
+		}
+	}
+	return firstLine(filepath.Join(dir, "rust-toolchain"))
+}
+
+// nodeVersion reads .nvmrc, .node-version or the engines field of package.json
+func nodeVersion(dir string) string {
+	for _, name := range []string{".nvmrc", ".node-version"} {
+		if version := firstLine(filepath.Join(dir, name)); version != "" {
+			return strings.TrimPrefix(version, "v")
+		}
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if err != nil {
+		return ""
+	}
+	var pkg struct {
+		Engines struct {
+			Node string `json:"node"`
+		} `json:"engines"`
+	}
+	if json.Unmarshal(data, &pkg) != nil {
+		return ""
+	}
+	return pkg.Engines.Node
+}
+
+// pythonVersion reads .python-version
+func pythonVersion(dir string) string {
+	return firstLine(filepath.Join(dir, ".python-version"))
+}
+
+// rubyVersion reads .ruby-version
+func rubyVersion(dir string) string {
+	return strings.TrimPrefix(firstLine(filepath.Join(dir, ".ruby-version")), "ruby-")
+}
+
+// firstLine returns the first line of a file, trimmed, or "" if it cannot be read
+func firstLine(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	line, _, _ := strings.Cut(string(data), "\n")
+	return strings.TrimSpace(line)
+}
+
+// detectVenv returns the name of the shell's active virtualenv, or of a
+// virtualenv directory in dir or the directories above it
+func detectVenv(dir, active string) string {
+	if active != "" {
+		return filepath.Base(active)
+	}
+	for ; ; dir = filepath.Dir(dir) {
+		for _, name := range venvDirs {
+			if _, err := os.Stat(filepath.Join(dir, name, "pyvenv.cfg")); err == nil {
+				return name
+			}
+		}
+		if parent := filepath.Dir(dir); parent == dir {
+			return ""
+		}
+	}
+}
+
+// detectKube returns the shell's Kubernetes context, or the current context
+// of the first kubeconfig file
+func detectKube(active string) string {
+	if active != "" {
+		return active
+	}
+	path := ""
+	if paths := filepath.SplitList(os.Getenv("KUBECONFIG")); len(paths) > 0 {
+		path = paths[0]
+	} else if home, err := os.UserHomeDir(); err == nil {
+		path = filepath.Join(home, ".kube", "config")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if value, ok := strings.CutPrefix(scanner.Text(), "current-context:"); ok {
+			return strings.Trim(strings.TrimSpace(value), `"'`)
+		}
+	}
+	return ""
+}
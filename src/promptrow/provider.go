@@ -0,0 +1,242 @@
+package promptrow
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CommandTimeout bounds a single run of a provider configured as a command
+const CommandTimeout = 2 * time.Second
+
+// commandTTL is how long the output of a command provider is reused for a directory
+const commandTTL = 10 * time.Second
+
+// maxCached is how many results are kept before expired ones are pruned
+const maxCached = 256
+
+// Provider detects one segment for a directory. Providers run in the
+// background, each in its own goroutine; with Detector.Stream a slow one
+// holds up only its own segment, never the prompt or the others.
+type Provider struct {
+	Name  string
+	Label string        // Put before the result in the status row, e.g. "git"; empty for none
+	TTL   time.Duration // How long a result is reused for the same directory; 0 runs it at every prompt
+	// Detect returns the segment for dir, or "" when it does not apply. vars
+	// are the user variables the shell set with OSC 1337.
+	Detect func(ctx context.Context, dir string, vars map[string]string) string
+}
+
+var (
+	providersMu sync.RWMutex
+	providers   = map[string]Provider{}
+)
+
+// Register adds a provider, replacing any registered under the same name
+func Register(p Provider) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	providers[p.Name] = p
+}
+
+func init() {
+	Register(Provider{Name: SegmentGit, Label: "git", Detect: detectGit})
+	Register(Provider{Name: SegmentIvaldi, Label: "ivaldi", Detect: detectIvaldi})
+	Register(Provider{Name: SegmentLanguage, TTL: 30 * time.Second, Detect: func(ctx context.Context, dir string, vars map[string]string) string {
+		return detectLanguage(dir)
+	}})
+	Register(Provider{Name: SegmentVenv, Label: "venv", Detect: func(ctx context.Context, dir string, vars map[string]string) string {
+		return detectVenv(dir, vars["virtual_env"])
+	}})
+	Register(Provider{Name: SegmentKube, Label: "k8s", Detect: func(ctx context.Context, dir string, vars map[string]string) string {
+		return detectKube(vars["kube_context"])
+	}})
+}
+
+// Results holds what each provider found, by name
+type Results map[string]string
+
+// Row formats the results for segments as the status row, each with its
+// label, e.g. "git main*  go 1.24  k8s prod"
+func (r Results) Row(segments []string) string {
+	var parts []string
+	for _, name := range segments {
+		if value := r[name]; value != "" {
+			if label := labelOf(name); label != "" {
+				value = label + " " + value
+			}
+			parts = append(parts, value)
+		}
+	}
+	return strings.Join(parts, "  ")
+}
+
+// Compact formats the results for segments without labels, for the tab bar
+func (r Results) Compact(segments []string) string {
+	var parts []string
+	for _, name := range segments {
+		if value := r[name]; value != "" {
+			parts = append(parts, value)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func labelOf(name string) string {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	return providers[name].Label
+}
+
+type cacheKey struct {
+	provider string
+	dir      string
+}
+
+type cached struct {
+	value   string
+	expires time.Time
+}
+
+// Detector runs providers and caches their results per directory. It is
+// safe to use from several goroutines.
+type Detector struct {
+	mu       sync.Mutex
+	commands map[string]string
+	cache    map[cacheKey]cached
+}
+
+func NewDetector() *Detector {
+	return &Detector{cache: make(map[cacheKey]cached)}
+}
+
+// SetCommands sets the providers configured as shell commands, by name. A
+// command runs in the directory with sh -c and the first line it prints is
+// its segment. Commands take precedence over registered providers.
+func (d *Detector) SetCommands(commands map[string]string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.commands = commands
+	for key := range d.cache {
+		if _, ok := commands[key.provider]; ok {
+			delete(d.cache, key)
+		}
+	}
+}
+
+// Run runs the providers named by segments for dir concurrently and returns
+// what they found once all have finished. Names with no provider are skipped.
+func (d *Detector) Run(ctx context.Context, dir string, segments []string, vars map[string]string) Results {
+	return d.Stream(ctx, dir, segments, vars, nil)
+}
+
+// Stream runs the providers as Run does, calling update with a copy of the
+// results so far once the cached ones are known and again as each provider
+// finishes, so a slow provider delays only its own segment. Calls to update
+// never overlap.
+func (d *Detector) Stream(ctx context.Context, dir string, segments []string, vars map[string]string, update func(Results)) Results {
+	results := make(Results, len(segments))
+	if dir == "" {
+		return results
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	publish := func() {
+		if update == nil {
+			return
+		}
+		snapshot := make(Results, len(results))
+		for name, value := range results {
+			snapshot[name] = value
+		}
+		update(snapshot)
+	}
+	mu.Lock()
+	for _, name := range segments {
+		p, ok := d.provider(name)
+		if !ok {
+			continue
+		}
+		if value, ok := d.cached(name, dir); ok {
+			results[name] = value
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value := strings.TrimSpace(p.Detect(ctx, dir, vars))
+			d.store(p, dir, value)
+			mu.Lock()
+			defer mu.Unlock()
+			results[name] = value
+			publish()
+		}()
+	}
+	publish()
+	mu.Unlock()
+	wg.Wait()
+	return results
+}
+
+// provider returns the command or registered provider called name
+func (d *Detector) provider(name string) (Provider, bool) {
+	d.mu.Lock()
+	command, ok := d.commands[name]
+	d.mu.Unlock()
+	if ok {
+		return commandProvider(name, command), true
+	}
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	p, ok := providers[name]
+	return p, ok
+}
+
+func (d *Detector) cached(name, dir string) (string, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	entry, ok := d.cache[cacheKey{name, dir}]
+	if !ok || time.Now().After(entry.expires) {
+		return "", false
+	}
+	return entry.value, true
+}
+
+func (d *Detector) store(p Provider, dir, value string) {
+	if p.TTL <= 0 {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := time.Now()
+	if len(d.cache) >= maxCached {
+		for key, entry := range d.cache {
+			if now.After(entry.expires) {
+				delete(d.cache, key)
+			}
+		}
+	}
+	d.cache[cacheKey{p.Name, dir}] = cached{value: value, expires: now.Add(p.TTL)}
+}
+
+// commandProvider runs command with sh -c in the directory
+func commandProvider(name, command string) Provider {
+	return Provider{
+		Name: name,
+		TTL:  commandTTL,
+		Detect: func(ctx context.Context, dir string, vars map[string]string) string {
+			ctx, cancel := context.WithTimeout(ctx, CommandTimeout)
+			defer cancel()
+			cmd := exec.CommandContext(ctx, "/bin/sh", "-c", command)
+			cmd.Dir = dir
+			out, err := cmd.Output()
+			if err != nil {
+				return ""
+			}
+			line, _, _ := strings.Cut(string(out), "\n")
+			return line
+		},
+	}
+}
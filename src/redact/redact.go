@@ -0,0 +1,91 @@
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// defaultPatterns match common credentials. When a pattern has a capture
+// group only the group is masked, so "token=abc" keeps its "token=" label.
+var defaultPatterns = []string{
+	`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`,                                  // AWS access key ID
+	`(?i)aws_secret_access_key\s*[=:]\s*["']?([A-Za-z0-9/+=]{40})`,   // AWS secret key
+	`\bgh[pousr]_[A-Za-z0-9]{36,}\b`,                                 // GitHub token
+	`\bgithub_pat_[A-Za-z0-9_]{22,}\b`,                               // GitHub fine-grained token
+	`\bxox[abprs]-[A-Za-z0-9-]{10,}\b`,                               // Slack token
+	`\bsk-[A-Za-z0-9_-]{20,}\b`,                                      // OpenAI-style API key
+	`\beyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}\b`, // JWT
+	`(?i)\b(?:api[_-]?key|access[_-]?token|auth[_-]?token|secret|password|passwd)\s*[=:]\s*["']?([^\s"']{6,})`, // key=value secrets
+}
+
+// Redactor finds secrets in terminal lines
+type Redactor struct {
+	patterns []*regexp.Regexp
+}
+
+// New compiles the default patterns plus any extra user patterns
+func New(extra []string) (*Redactor, error) {
+	r := &Redactor{}
+	for _, pattern := range append(append([]string{}, defaultPatterns...), extra...) {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return r, fmt.Errorf("redaction pattern %q: %w", pattern, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return r, nil
+}
+
+// Mask returns which runes of line belong to a secret, or nil if none do
+func (r *Redactor) Mask(line []rune) []bool {
+	if r == nil || len(line) == 0 {
+		return nil
+	}
+	text := string(line)
+
+	// Map byte offsets in text back to rune indices; matches always start and
+	// end on rune boundaries so continuation bytes are never looked up
+	runeAt := make([]int, len(text)+1)
+	n := 0
+	for i := range text {
+		runeAt[i] = n
+		n++
+	}
+	runeAt[len(text)] = n
+
+	var mask []bool
+	for _, re := range r.patterns {
+		for _, loc := range re.FindAllStringSubmatchIndex(text, -1) {
+			start, end := loc[0], loc[1]
+			if len(loc) >= 4 && loc[2] >= 0 {
+				start, end = loc[2], loc[3]
+			}
+			if mask == nil {
+				mask = make([]bool, len(line))
+			}
+			for i := runeAt[start]; i < runeAt[end]; i++ {
+				mask[i] = true
+			}
+		}
+	}
+	return mask
+}
+
+// Redact returns line with each secret replaced by "[redacted]"
+func (r *Redactor) Redact(line string) string {
+	runes := []rune(line)
+	mask := r.Mask(runes)
+	if mask == nil {
+		return line
+	}
+	var b strings.Builder
+	for i, c := range runes {
+		if !mask[i] {
+			b.WriteRune(c)
+		} else if i == 0 || !mask[i-1] {
+			b.WriteString("[redacted]")
+		}
+	}
+	return b.String()
+}
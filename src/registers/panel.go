@@ -0,0 +1,134 @@
+package registers
+
+// Panel lists the registers and picks one to paste from or yank into
+type Panel struct {
+	Open      bool
+	Focused   bool
+	Yank      bool // Typing a register name stores the selection instead of pasting
+	Registers *Registers
+	Selected  int
+	Scroll    int
+}
+
+type Layout struct {
+	PanelX       float32
+	PanelY       float32
+	PanelWidth   float32
+	PanelHeight  float32
+	ContentX     float32
+	ContentWidth float32
+	LineHeight   float32
+	HeaderY      float32
+	ModeY        float32
+	ListStart    float32
+	ListEnd      float32
+	FooterY      float32
+	VisibleLines int
+}
+
+func NewPanel(registers *Registers) *Panel {
+	return &Panel{Registers: registers}
+}
+
+// Toggle opens the panel in yank mode when yank is true, or paste mode otherwise
+func (p *Panel) Toggle(yank bool) {
+	p.Open = !p.Open
+	if p.Open {
+		p.Focused = true
+		p.Yank = yank
+		p.Selected = 0
+		p.Scroll = 0
+	}
+}
+
+// SelectedName returns the name of the highlighted register
+func (p *Panel) SelectedName() (rune, bool) {
+	names := p.Registers.Names()
+	if p.Selected < 0 || p.Selected >= len(names) {
+		return 0, false
+	}
+	return names[p.Selected], true
+}
+
+func (p *Panel) MoveSelection(delta int, visibleLines int) {
+	count := len(p.Registers.Names())
+	if count == 0 {
+		return
+	}
+	p.Selected += delta
+	if p.Selected < 0 {
+		p.Selected = 0
+	}
+	if p.Selected >= count {
+		p.Selected = count - 1
+	}
+	if visibleLines <= 0 {
+		return
+	}
+	if p.Selected < p.Scroll {
+		p.Scroll = p.Selected
+	}
+	if p.Selected >= p.Scroll+visibleLines {
+		p.Scroll = p.Selected - visibleLines + 1
+	}
+}
+
+func (p *Panel) Layout(width, height int, cellWidth, cellHeight float32) Layout {
+	panelWidth := float32(width) * 0.5
+	minPanelWidth := float32(420)
+	if cellWidth > 0 {
+		wideMin := cellWidth * 48
+		if wideMin > minPanelWidth {
+			minPanelWidth = wideMin
+		}
+	}
+	if panelWidth < minPanelWidth {
+		panelWidth = minPanelWidth
+	}
+	if panelWidth > 820 {
+		panelWidth = 820
+	}
+	maxWidth := float32(width) - 20
+	if panelWidth > maxWidth {
+		panelWidth = maxWidth
+	}
+
+	lineHeight := cellHeight * 1.35
+	panelHeight := lineHeight * 16
+	if panelHeight > float32(height)-40 {
+		panelHeight = float32(height) - 40
+	}
+
+	// Centered near the top like a command palette
+	panelX := (float32(width) - panelWidth) / 2
+	panelY := float32(height) * 0.12
+
+	contentX := panelX + 18
+	contentWidth := panelWidth - 36
+	headerY := panelY + lineHeight*1.2
+	modeY := headerY + lineHeight*1.1
+	listStart := modeY + lineHeight*1.5
+	footerY := panelY + panelHeight - lineHeight*0.6
+	listEnd := footerY - lineHeight*1.2
+
+	visibleLines := int((listEnd - listStart) / lineHeight)
+	if visibleLines < 1 {
+		visibleLines = 1
+	}
+
+	return Layout{
+		PanelX:       panelX,
+		PanelY:       panelY,
+		PanelWidth:   panelWidth,
+		PanelHeight:  panelHeight,
+		ContentX:     contentX,
+		ContentWidth: contentWidth,
+		LineHeight:   lineHeight,
+		HeaderY:      headerY,
+		ModeY:        modeY,
+		ListStart:    listStart,
+		ListEnd:      listEnd,
+		FooterY:      footerY,
+		VisibleLines: visibleLines,
+	}
+}
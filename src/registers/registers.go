@@ -0,0 +1,59 @@
+package registers
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Registers holds named pieces of copied text, like vim's registers, for
+// the life of the window. Names are the letters a-z and digits 0-9.
+type Registers struct {
+	text map[rune]string
+}
+
+// New returns an empty set of registers
+func New() *Registers {
+	return &Registers{text: make(map[rune]string)}
+}
+
+// Valid reports whether name can be used as a register name. Uppercase
+// letters are accepted and refer to their lowercase register.
+func Valid(name rune) bool {
+	name = unicode.ToLower(name)
+	return (name >= 'a' && name <= 'z') || (name >= '0' && name <= '9')
+}
+
+// Set stores text in the named register. An uppercase name appends to the
+// register instead, on a new line, as in vim.
+func (r *Registers) Set(name rune, text string) {
+	if !Valid(name) {
+		return
+	}
+	lower := unicode.ToLower(name)
+	if name != lower && r.text[lower] != "" {
+		text = strings.TrimRight(r.text[lower], "\n") + "\n" + text
+	}
+	r.text[lower] = text
+}
+
+// Get returns the text in the named register
+func (r *Registers) Get(name rune) (string, bool) {
+	text, ok := r.text[unicode.ToLower(name)]
+	return text, ok
+}
+
+// Clear empties the named register
+func (r *Registers) Clear(name rune) {
+	delete(r.text, unicode.ToLower(name))
+}
+
+// Names returns the registers that hold text: digits first, then letters
+func (r *Registers) Names() []rune {
+	names := make([]rune, 0, len(r.text))
+	for name := range r.text {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
@@ -0,0 +1,56 @@
+package render
+
+import (
+	"strings"
+
+	"github.com/javanhut/RavenTerminal/src/logging"
+)
+
+// Backend draws the primitives every frame is built from. The renderer lays
+// out the tab bar, panes and panels itself and only asks the backend to fill
+// rectangles and copy glyphs out of a single-channel atlas, so supporting
+// another graphics API means implementing these methods. A backend for an
+// API other than OpenGL also needs the window created without a GL context.
+type Backend interface {
+	// Name identifies the backend in logs and diagnostics
+	Name() string
+	// Clear starts a width by height pixel frame filled with clr
+	Clear(width, height int, clr [4]float32)
+	// FillRect fills a rectangle given in pixels
+	FillRect(x, y, w, h float32, clr [4]float32, proj [16]float32)
+	// SetAtlas replaces the glyph atlas with a size by size alpha image
+	SetAtlas(pix []byte, size int)
+	// UpdateAtlas writes a w by h alpha image into the atlas at x, y
+	UpdateAtlas(x, y, w, h int, pix []byte)
+	// DrawGlyph draws the atlas region tx, ty, tw, th, in atlas fractions,
+	// over the pixel rectangle x, y, w, h, tinted with clr
+	DrawGlyph(x, y, w, h, tx, ty, tw, th float32, clr [4]float32, proj [16]float32)
+	// Present finishes the frame before the window swaps buffers
+	Present()
+	// Destroy frees the backend's resources
+	Destroy()
+}
+
+// Backend names accepted by [appearance] renderer
+const (
+	BackendOpenGL   = "opengl"
+	BackendSoftware = "software"
+	BackendVulkan   = "vulkan"
+	BackendMetal    = "metal"
+)
+
+// newBackend creates the named backend. Vulkan and Metal are reserved for
+// backends that are not built in yet; they, and unknown names, use OpenGL.
+// The software backend needs a window created for it (window.Config.Software).
+func newBackend(name string) (Backend, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case BackendSoftware:
+		return newSoftwareBackend(), nil
+	case "", BackendOpenGL:
+	case BackendVulkan, BackendMetal:
+		logging.Warnf(logging.Render, "The %s renderer is not included in this build; using OpenGL", name)
+	default:
+		logging.Warnf(logging.Render, "Unknown renderer %q; using OpenGL", name)
+	}
+	return newGLBackend()
+}
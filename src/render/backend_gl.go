@@ -0,0 +1,265 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+)
+
+// glBackend draws with OpenGL 4.1 core shaders
+type glBackend struct {
+	quadVAO     uint32
+	quadVBO     uint32
+	program     uint32
+	fontProgram uint32
+	fontVAO     uint32
+	fontVBO     uint32
+	fontAtlas   uint32
+
+	// Uniforms
+	colorLoc    int32
+	projLoc     int32
+	texColorLoc int32
+	texProjLoc  int32
+	texLoc      int32
+}
+
+// newGLBackend compiles the shaders and creates the vertex buffers. The
+// window's GL context must be current.
+func newGLBackend() (Backend, error) {
+	b := &glBackend{}
+	var err error
+	// Create quad shader program for colored rectangles
+	vertShader := `
+		#version 410 core
+		layout (location = 0) in vec2 aPos;
+		uniform mat4 projection;
+		void main() {
+			gl_Position = projection * vec4(aPos, 0.0, 1.0);
+		}
+	` + "\x00"
+
+	fragShader := `
+		#version 410 core
+		out vec4 FragColor;
+		uniform vec4 color;
+		void main() {
+			FragColor = color;
+		}
+	` + "\x00"
+
+	b.program, err = createProgram(vertShader, fragShader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create quad shader: %w", err)
+	}
+
+	b.colorLoc = gl.GetUniformLocation(b.program, gl.Str("color\x00"))
+	b.projLoc = gl.GetUniformLocation(b.program, gl.Str("projection\x00"))
+
+	// Create text shader program with smooth alpha blending
+	textVertShader := `
+		#version 410 core
+		layout (location = 0) in vec4 vertex; // <vec2 pos, vec2 tex>
+		out vec2 TexCoords;
+		uniform mat4 projection;
+		void main() {
+			gl_Position = projection * vec4(vertex.xy, 0.0, 1.0);
+			TexCoords = vertex.zw;
+		}
+	` + "\x00"
+
+	textFragShader := `
+		#version 410 core
+		in vec2 TexCoords;
+		out vec4 FragColor;
+		uniform sampler2D text;
+		uniform vec4 textColor;
+		void main() {
+			float alpha = texture(text, TexCoords).r;
+			FragColor = vec4(textColob.rgb, textColob.a * alpha);
+		}
+	` + "\x00"
+
+	b.fontProgram, err = createProgram(textVertShader, textFragShader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create text shader: %w", err)
+	}
+
+	b.texColorLoc = gl.GetUniformLocation(b.fontProgram, gl.Str("textColor\x00"))
+	b.texProjLoc = gl.GetUniformLocation(b.fontProgram, gl.Str("projection\x00"))
+	b.texLoc = gl.GetUniformLocation(b.fontProgram, gl.Str("text\x00"))
+
+	// Create quad VAO/VBO
+	gl.GenVertexArrays(1, &b.quadVAO)
+	gl.GenBuffers(1, &b.quadVBO)
+	gl.BindVertexArray(b.quadVAO)
+	gl.BindBuffer(gl.ARRAY_BUFFER, b.quadVBO)
+	gl.BufferData(gl.ARRAY_BUFFER, 6*2*4, nil, gl.DYNAMIC_DRAW)
+	gl.EnableVertexAttribArray(0)
+	gl.VertexAttribPointerWithOffset(0, 2, gl.FLOAT, false, 2*4, 0)
+	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
+	gl.BindVertexArray(0)
+
+	// Create font VAO/VBO
+	gl.GenVertexArrays(1, &b.fontVAO)
+	gl.GenBuffers(1, &b.fontVBO)
+	gl.BindVertexArray(b.fontVAO)
+	gl.BindBuffer(gl.ARRAY_BUFFER, b.fontVBO)
+	gl.BufferData(gl.ARRAY_BUFFER, 6*4*4, nil, gl.DYNAMIC_DRAW)
+	gl.EnableVertexAttribArray(0)
+	gl.VertexAttribPointerWithOffset(0, 4, gl.FLOAT, false, 4*4, 0)
+	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
+	gl.BindVertexArray(0)
+
+	return b, nil
+}
+
+func (b *glBackend) Name() string {
+	return BackendOpenGL
+}
+
+func (b *glBackend) Clear(width, height int, clr [4]float32) {
+	gl.ClearColor(clr[0], clr[1], clr[2], clr[3])
+	gl.Clear(gl.COLOR_BUFFER_BIT)
+}
+
+func (b *glBackend) FillRect(x, y, w, h float32, clr [4]float32, proj [16]float32) {
+	vertices := []float32{
+		x, y,
+		x + w, y,
+		x + w, y + h,
+		x, y,
+		x + w, y + h,
+		x, y + h,
+	}
+
+	gl.UseProgram(b.program)
+	gl.UniformMatrix4fv(b.projLoc, 1, false, &proj[0])
+	gl.Uniform4fv(b.colorLoc, 1, &clr[0])
+
+	gl.BindVertexArray(b.quadVAO)
+	gl.BindBuffer(gl.ARRAY_BUFFER, b.quadVBO)
+	gl.BufferSubData(gl.ARRAY_BUFFER, 0, len(vertices)*4, gl.Ptr(vertices))
+	gl.DrawArrays(gl.TRIANGLES, 0, 6)
+	gl.BindVertexArray(0)
+}
+
+func (b *glBackend) SetAtlas(pix []byte, size int) {
+	if b.fontAtlas != 0 {
+		gl.DeleteTextures(1, &b.fontAtlas)
+	}
+	gl.GenTextures(1, &b.fontAtlas)
+	gl.BindTexture(gl.TEXTURE_2D, b.fontAtlas)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RED, int32(size), int32(size), 0,
+		gl.RED, gl.UNSIGNED_BYTE, gl.Ptr(pix))
+
+	// Use LINEAR filtering for smooth scaling (anti-aliasing)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+
+	gl.BindTexture(gl.TEXTURE_2D, 0)
+}
+
+func (b *glBackend) UpdateAtlas(x, y, w, h int, pix []byte) {
+	gl.BindTexture(gl.TEXTURE_2D, b.fontAtlas)
+	gl.PixelStorei(gl.UNPACK_ALIGNMENT, 1)
+	gl.TexSubImage2D(gl.TEXTURE_2D, 0, int32(x), int32(y), int32(w), int32(h),
+		gl.RED, gl.UNSIGNED_BYTE, gl.Ptr(pix))
+	gl.PixelStorei(gl.UNPACK_ALIGNMENT, 4)
+	gl.BindTexture(gl.TEXTURE_2D, 0)
+}
+
+func (b *glBackend) DrawGlyph(x, y, w, h, tx, ty, tw, th float32, clr [4]float32, proj [16]float32) {
+	vertices := []float32{
+		x, y, tx, ty,
+		x + w, y, tx + tw, ty,
+		x + w, y + h, tx + tw, ty + th,
+		x, y, tx, ty,
+		x + w, y + h, tx + tw, ty + th,
+		x, y + h, tx, ty + th,
+	}
+
+	gl.UseProgram(b.fontProgram)
+	gl.UniformMatrix4fv(b.texProjLoc, 1, false, &proj[0])
+	gl.Uniform4fv(b.texColorLoc, 1, &clr[0])
+	gl.Uniform1i(b.texLoc, 0)
+
+	gl.ActiveTexture(gl.TEXTURE0)
+	gl.BindTexture(gl.TEXTURE_2D, b.fontAtlas)
+
+	gl.BindVertexArray(b.fontVAO)
+	gl.BindBuffer(gl.ARRAY_BUFFER, b.fontVBO)
+	gl.BufferSubData(gl.ARRAY_BUFFER, 0, len(vertices)*4, gl.Ptr(vertices))
+	gl.DrawArrays(gl.TRIANGLES, 0, 6)
+	gl.BindVertexArray(0)
+}
+
+func (b *glBackend) Present() {}
+
+func (b *glBackend) Destroy() {
+	gl.DeleteVertexArrays(1, &b.quadVAO)
+	gl.DeleteBuffers(1, &b.quadVBO)
+	gl.DeleteVertexArrays(1, &b.fontVAO)
+	gl.DeleteBuffers(1, &b.fontVBO)
+	gl.DeleteProgram(b.program)
+	gl.DeleteProgram(b.fontProgram)
+	gl.DeleteTextures(1, &b.fontAtlas)
+}
+
+// createProgram creates a shader program from vertex and fragment shader sources
+func createProgram(vertexSource, fragmentSource string) (uint32, error) {
+	vertexShader, err := compileShader(vertexSource, gl.VERTEX_SHADER)
+	if err != nil {
+		return 0, err
+	}
+
+	fragmentShader, err := compileShader(fragmentSource, gl.FRAGMENT_SHADER)
+	if err != nil {
+		return 0, err
+	}
+
+	program := gl.CreateProgram()
+	gl.AttachShader(program, vertexShader)
+	gl.AttachShader(program, fragmentShader)
+	gl.LinkProgram(program)
+
+	var status int32
+	gl.GetProgramiv(program, gl.LINK_STATUS, &status)
+	if status == gl.FALSE {
+		var logLength int32
+		gl.GetProgramiv(program, gl.INFO_LOG_LENGTH, &logLength)
+		log := strings.Repeat("\x00", int(logLength+1))
+		gl.GetProgramInfoLog(program, logLength, nil, gl.Str(log))
+		return 0, fmt.Errorf("failed to link program: %v", log)
+	}
+
+	gl.DeleteShader(vertexShader)
+	gl.DeleteShader(fragmentShader)
+
+	return program, nil
+}
+
+// compileShader compiles a shader from source
+func compileShader(source string, shaderType uint32) (uint32, error) {
+	shader := gl.CreateShader(shaderType)
+
+	csources, free := gl.Strs(source)
+	gl.ShaderSource(shader, 1, csources, nil)
+	free()
+	gl.CompileShader(shader)
+
+	var status int32
+	gl.GetShaderiv(shader, gl.COMPILE_STATUS, &status)
+	if status == gl.FALSE {
+		var logLength int32
+		gl.GetShaderiv(shader, gl.INFO_LOG_LENGTH, &logLength)
+		log := strings.Repeat("\x00", int(logLength+1))
+		gl.GetShaderInfoLog(shader, logLength, nil, gl.Str(log))
+		return 0, fmt.Errorf("failed to compile shader: %v", log)
+	}
+
+	return shader, nil
+}
@@ -0,0 +1,156 @@
+package render
+
+import (
+	"image"
+	"math"
+
+	legacygl "github.com/go-gl/gl/v2.1/gl"
+)
+
+// softwareBackend rasterizes frames on the CPU and copies each finished frame
+// to the window with glDrawPixels. It is used when the driver has no OpenGL
+// 4.1, and only needs the OpenGL 2.1 context that even Mesa's CPU driver
+// provides.
+type softwareBackend struct {
+	frame     *image.RGBA
+	flipped   []byte // The frame bottom row first, as glDrawPixels reads it
+	atlas     []byte
+	atlasSize int
+}
+
+func newSoftwareBackend() Backend {
+	return &softwareBackend{frame: image.NewRGBA(image.Rect(0, 0, 0, 0))}
+}
+
+func (b *softwareBackend) Name() string {
+	return BackendSoftware
+}
+
+func (b *softwareBackend) Clear(width, height int, clr [4]float32) {
+	if b.frame.Rect.Dx() != width || b.frame.Rect.Dy() != height {
+		b.frame = image.NewRGBA(image.Rect(0, 0, width, height))
+	}
+	r, g, bl := toByte(clr[0]), toByte(clr[1]), toByte(clr[2])
+	pix := b.frame.Pix
+	for i := 0; i < len(pix); i += 4 {
+		pix[i], pix[i+1], pix[i+2], pix[i+3] = r, g, bl, 255
+	}
+}
+
+func (b *softwareBackend) FillRect(x, y, w, h float32, clr [4]float32, proj [16]float32) {
+	x0, y0, x1, y1, ok := b.pixelRect(x, y, w, h, proj)
+	if !ok {
+		return
+	}
+	for py := y0; py < y1; py++ {
+		for px := x0; px < x1; px++ {
+			b.blend(px, py, clr, clr[3])
+		}
+	}
+}
+
+func (b *softwareBackend) SetAtlas(pix []byte, size int) {
+	b.atlas = append(b.atlas[:0], pix...)
+	b.atlasSize = size
+}
+
+func (b *softwareBackend) UpdateAtlas(x, y, w, h int, pix []byte) {
+	for row := 0; row < h && y+row < b.atlasSize; row++ {
+		dst := (y+row)*b.atlasSize + x
+		copy(b.atlas[dst:dst+min(w, b.atlasSize-x)], pix[row*w:(row+1)*w])
+	}
+}
+
+func (b *softwareBackend) DrawGlyph(x, y, w, h, tx, ty, tw, th float32, clr [4]float32, proj [16]float32) {
+	x0, y0, x1, y1, ok := b.pixelRect(x, y, w, h, proj)
+	if !ok || b.atlasSize == 0 {
+		return
+	}
+	// Sample the atlas at each pixel center, nearest texel
+	left, top := b.toPixel(x, y, proj)
+	right, bottom := b.toPixel(x+w, y+h, proj)
+	spanX, spanY := right-left, bottom-top
+	size := float32(b.atlasSize)
+	for py := y0; py < y1; py++ {
+		v := ty + (float32(py)+0.5-top)/spanY*th
+		ay := int(v * size)
+		if ay < 0 || ay >= b.atlasSize {
+			continue
+		}
+		for px := x0; px < x1; px++ {
+			u := tx + (float32(px)+0.5-left)/spanX*tw
+			ax := int(u * size)
+			if ax < 0 || ax >= b.atlasSize {
+				continue
+			}
+			if alpha := b.atlas[ay*b.atlasSize+ax]; alpha > 0 {
+				b.blend(px, py, clr, clr[3]*float32(alpha)/255)
+			}
+		}
+	}
+}
+
+func (b *softwareBackend) Present() {
+	width, height := b.frame.Rect.Dx(), b.frame.Rect.Dy()
+	if width == 0 || height == 0 {
+		return
+	}
+	stride := width * 4
+	if len(b.flipped) != len(b.frame.Pix) {
+		b.flipped = make([]byte, len(b.frame.Pix))
+	}
+	for row := 0; row < height; row++ {
+		copy(b.flipped[row*stride:(row+1)*stride], b.frame.Pix[(height-1-row)*stride:(height-row)*stride])
+	}
+	legacygl.WindowPos2i(0, 0)
+	legacygl.DrawPixels(int32(width), int32(height), legacygl.RGBA, legacygl.UNSIGNED_BYTE, legacygl.Ptr(b.flipped))
+}
+
+func (b *softwareBackend) Destroy() {
+	b.frame = image.NewRGBA(image.Rect(0, 0, 0, 0))
+	b.flipped = nil
+	b.atlas = nil
+}
+
+// toPixel maps a point through proj to frame pixels, origin at the top left
+func (b *softwareBackend) toPixel(x, y float32, proj [16]float32) (float32, float32) {
+	ndcX := proj[0]*x + proj[4]*y + proj[12]
+	ndcY := proj[1]*x + proj[5]*y + proj[13]
+	return (ndcX + 1) / 2 * float32(b.frame.Rect.Dx()), (1 - ndcY) / 2 * float32(b.frame.Rect.Dy())
+}
+
+// pixelRect returns the frame pixels whose centers fall in the rectangle
+func (b *softwareBackend) pixelRect(x, y, w, h float32, proj [16]float32) (int, int, int, int, bool) {
+	ax, ay := b.toPixel(x, y, proj)
+	bx, by := b.toPixel(x+w, y+h, proj)
+	x0 := max(int(math.Round(float64(min(ax, bx)))), 0)
+	y0 := max(int(math.Round(float64(min(ay, by)))), 0)
+	x1 := min(int(math.Round(float64(max(ax, bx)))), b.frame.Rect.Dx())
+	y1 := min(int(math.Round(float64(max(ay, by)))), b.frame.Rect.Dy())
+	return x0, y0, x1, y1, x0 < x1 && y0 < y1
+}
+
+// blend draws clr over the pixel at px, py with the given opacity
+func (b *softwareBackend) blend(px, py int, clr [4]float32, alpha float32) {
+	if alpha <= 0 {
+		return
+	}
+	if alpha > 1 {
+		alpha = 1
+	}
+	i := b.frame.PixOffset(px, py)
+	pix := b.frame.Pix[i : i+3 : i+3]
+	for c := 0; c < 3; c++ {
+		pix[c] = toByte(clr[c]*alpha + float32(pix[c])/255*(1-alpha))
+	}
+}
+
+func toByte(v float32) uint8 {
+	if v <= 0 {
+		return 0
+	}
+	if v >= 1 {
+		return 255
+	}
+	return uint8(v*255 + 0.5)
+}
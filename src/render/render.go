@@ -4,17 +4,38 @@ import (
 	"fmt"
 	"github.com/javanhut/RavenTerminal/src/aipanel"
 	"github.com/javanhut/RavenTerminal/src/assets/fonts"
+	"github.com/javanhut/RavenTerminal/src/cleanup"
+	"github.com/javanhut/RavenTerminal/src/calc"
+	"github.com/javanhut/RavenTerminal/src/devserver"
+	"github.com/javanhut/RavenTerminal/src/dirjump"
 	"github.com/javanhut/RavenTerminal/src/grid"
+	"github.com/javanhut/RavenTerminal/src/highlight"
+	"github.com/javanhut/RavenTerminal/src/hints"
+	"github.com/javanhut/RavenTerminal/src/inspector"
+	"github.com/javanhut/RavenTerminal/src/logging"
 	"github.com/javanhut/RavenTerminal/src/menu"
+	"github.com/javanhut/RavenTerminal/src/notifications"
+	"github.com/javanhut/RavenTerminal/src/palette"
 	"github.com/javanhut/RavenTerminal/src/parser"
+	"github.com/javanhut/RavenTerminal/src/procmon"
+	"github.com/javanhut/RavenTerminal/src/procpanel"
+	"github.com/javanhut/RavenTerminal/src/redact"
+	"github.com/javanhut/RavenTerminal/src/registers"
 	"github.com/javanhut/RavenTerminal/src/searchpanel"
+	"github.com/javanhut/RavenTerminal/src/snippets"
 	"github.com/javanhut/RavenTerminal/src/tab"
+	"github.com/javanhut/RavenTerminal/src/update"
 	"image"
 	"image/color"
 	"image/draw"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
-	"github.com/go-gl/gl/v4.1-core/gl"
+	xdraw "golang.org/x/image/draw"
 	"golang.org/x/image/font"
 	"golang.org/x/image/font/opentype"
 	"golang.org/x/image/math/fixed"
@@ -65,6 +86,15 @@ func ThemeByName(name string) Theme {
 			TabActive:  [4]float32{0.537, 0.706, 0.980, 1.0}, // #89b4fa
 			Selection:  [4]float32{0.537, 0.706, 0.980, 0.35},
 		}
+	case "high-contrast":
+		return Theme{
+			Background: [4]float32{0.000, 0.000, 0.000, 1.0}, // #000000
+			Foreground: [4]float32{1.000, 1.000, 1.000, 1.0}, // #ffffff
+			Cursor:     [4]float32{1.000, 0.847, 0.000, 1.0}, // #ffd800
+			TabBar:     [4]float32{0.000, 0.000, 0.000, 1.0}, // #000000
+			TabActive:  [4]float32{1.000, 0.847, 0.000, 1.0}, // #ffd800
+			Selection:  [4]float32{0.000, 0.600, 1.000, 0.55},
+		}
 	case "raven-blue":
 		fallthrough
 	default:
@@ -84,16 +114,22 @@ func (r *Renderer) SetThemeByName(name string) {
 	r.theme = ThemeByName(name)
 }
 
-// Glyph contains information about a rendered glyph
+// Glyph contains information about a rendered glyph. The atlas holds only
+// the glyph's ink; the bearings place it within the cell.
 type Glyph struct {
 	X, Y          float32 // Position in atlas (normalized 0-1)
 	Width, Height float32 // Size in atlas (normalized 0-1)
-	PixelWidth    int     // Actual pixel width
-	PixelHeight   int     // Actual pixel height
+	PixelWidth    int     // Ink width in pixels
+	PixelHeight   int     // Ink height in pixels
+	BearingX      int     // Ink offset from the cell's left edge
+	BearingY      int     // Ink offset from the cell's top edge
+	Advance       int     // Horizontal advance in pixels
 }
 
-// Renderer handles OpenGL rendering with smooth fonts
+// Renderer lays out the terminal and its panels and draws them through a
+// Backend, with smooth fonts
 type Renderer struct {
+	backend         Backend
 	theme           Theme
 	cellWidth       float32 // Current cell dimensions (may be zoomed)
 	cellHeight      float32
@@ -105,27 +141,17 @@ type Renderer struct {
 	paddingTop      float32
 	paddingBottom   float32
 	tabBarWidth     float32
+	uiScaleFactor   float32 // UI scale setting applied to the tab bar, menus, panels and toasts
 	currentFont     string
 
 	// Font data
-	glyphs    map[rune]Glyph
-	fontAtlas uint32
-	atlasSize int
-
-	// OpenGL resources
-	quadVAO     uint32
-	quadVBO     uint32
-	program     uint32
-	fontProgram uint32
-	fontVAO     uint32
-	fontVBO     uint32
-
-	// Uniforms
-	colorLoc    int32
-	projLoc     int32
-	texColorLoc int32
-	texProjLoc  int32
-	texLoc      int32
+	glyphs           map[rune]Glyph
+	atlasSize        int
+	glyphAscent      int
+	lazyFace         font.Face     // Open face of the current font for rasterizing lazy glyphs
+	lazySlotBase     int           // Atlas slot of the first lazy glyph
+	missingGlyphs    map[rune]bool // Lazy glyphs the font does not have
+	powerlineStretch bool          // Scale powerline separators to fill their cell
 
 	// Help panel scroll state
 	helpScrollOffset int
@@ -136,6 +162,45 @@ type Renderer struct {
 	hoverStartCol int
 	hoverEndCol   int
 	hoverActive   bool
+
+	// Viewport copy reused by every pane drawn in a frame
+	snapshot grid.Snapshot
+
+	// Wrapped overlay text reused across frames
+	previewWrap wrapCache
+
+	// Pane overlays
+	showPaneTitles  bool
+	showPaneNumbers bool
+	stickyPrompt    bool // Pin the prompt of the output in view to the top of panes scrolled back
+	wrapMarkers     bool // Mark rows soft-wrapped onto the next row at their right edge
+	redactor        *redact.Redactor
+	cursorThickness float32 // Bar/underline cursor thickness in pixels; 0 uses a sixth of the cell
+	reduceMotion    bool    // Draw static frames in place of animations
+
+	// Warning borders for panes running as root or over ssh
+	sessionBorders     map[*tab.Pane][4]float32
+	sessionBorderWidth float32
+	paneProfiles       map[*tab.Pane]PaneProfile
+	paneStatus         map[*tab.Pane]string // Short status such as a watch command, shown on the pane border
+	promptRows         map[*tab.Pane]string // Git, language, venv and k8s context shown above the input line
+	paneBadges         map[*tab.Pane]string // Badge text from shell user variables, drawn faintly in the pane corner
+	paneHighlights     map[*tab.Pane]*highlight.Set
+	replayPane         *tab.Pane            // Pane showing replayed output instead of its live screen
+	replayGrid         *grid.Grid
+	replayLabel        string
+	aliasPane          *tab.Pane // Pane whose typed command aliasPreview expands
+	aliasPreview       string
+
+	unreadNotifications int                   // Shown at the bottom of the tab bar
+	tabGroupColors      map[string][4]float32 // Strip color of each configured tab group
+}
+
+// PaneProfile is the per-host styling applied to a pane connected to a remote host
+type PaneProfile struct {
+	Tint        [4]float32
+	Tinted      bool
+	TitlePrefix string
 }
 
 type paneRect struct {
@@ -146,22 +211,31 @@ type paneRect struct {
 	height float32
 }
 
-// NewRenderer creates a new renderer with smooth font rendering
-func NewRenderer() (*Renderer, error) {
+// NewRenderer creates a new renderer with smooth font rendering, drawing
+// through the named backend (see newBackend). The glyph atlas is built once
+// at fontSize; 0 uses the built-in default size.
+func NewRenderer(fontSize float32, backend string) (*Renderer, error) {
+	if fontSize == 0 {
+		fontSize = defaultFontSize
+	}
+	fontSize = clampFontSize(fontSize)
 	r := &Renderer{
 		theme:           DefaultTheme(),
-		fontSize:        defaultFontSize,
+		fontSize:        fontSize,
 		baseFontSize:    defaultFontSize, // Fixed UI font size
-		defaultFontSize: defaultFontSize,
+		defaultFontSize: fontSize,
 		paddingTop:      12.0,
 		paddingBottom:   12.0,
-		tabBarWidth:     135.0,
+		tabBarWidth:     baseTabBarWidth,
+		uiScaleFactor:   1,
+		showPaneTitles:  true,
 		currentFont:     fonts.DefaultFontName(),
 		glyphs: make(map[rune]Glyph),
 		// atlasSize calculated dynamically in loadFontData based on glyph count
 	}
 
-	if err := r.initGL(); err != nil {
+	var err error
+	if r.backend, err = newBackend(backend); err != nil {
 		return nil, err
 	}
 
@@ -170,8 +244,7 @@ func NewRenderer() (*Renderer, error) {
 	}
 
 	// Store base cell dimensions for UI elements
-	r.baseCellWidth = r.cellWidth
-	r.baseCellHeight = r.cellHeight
+	r.baseCellWidth, r.baseCellHeight = r.UICellDimensions()
 
 	return r, nil
 }
@@ -181,6 +254,50 @@ func (r *Renderer) loadFont() error {
 	return r.loadFontData(fonts.DefaultFont())
 }
 
+// glyphRange is a run of code points placed in the glyph atlas
+type glyphRange struct {
+	start, end rune
+}
+
+// eagerGlyphRanges are rasterized when a font is loaded
+var eagerGlyphRanges = []glyphRange{
+	{32, 126},        // Printable ASCII
+	{160, 255},       // Extended Latin-1
+	{0x2000, 0x206F}, // General Punctuation (includes various spaces, dashes, dots)
+	{0x2100, 0x214F}, // Letterlike Symbols
+	{0x2190, 0x21FF}, // Arrows
+	{0x2200, 0x22FF}, // Mathematical Operators
+	{0x2300, 0x23FF}, // Miscellaneous Technical
+	{0x2500, 0x257F}, // Box Drawing
+	{0x2580, 0x259F}, // Block Elements
+	{0x25A0, 0x25FF}, // Geometric Shapes
+	{0x2600, 0x26FF}, // Miscellaneous Symbols
+	{0x2700, 0x27BF}, // Dingbats
+	{0x27C0, 0x27EF}, // Miscellaneous Mathematical Symbols-A
+	{0x27F0, 0x27FF}, // Supplemental Arrows-A
+	{0x2900, 0x297F}, // Supplemental Arrows-B
+	{0x2B00, 0x2BFF}, // Miscellaneous Symbols and Arrows
+}
+
+// lazyGlyphRanges are the Nerd Font icon sets. They hold most of the atlas
+// but are rarely all used, so each icon is rasterized the first time it is drawn.
+var lazyGlyphRanges = []glyphRange{
+	{0xE0A0, 0xE0D4}, // Powerline symbols
+	{0xE200, 0xE2A9}, // Pomicons
+	{0xE5FA, 0xE6B5}, // Seti-UI + Custom
+	{0xE700, 0xE7C5}, // Devicons
+	{0xEA60, 0xEC1E}, // Codicons
+	{0xED00, 0xEFC1}, // Font Logos
+	{0xF000, 0xF2E0}, // Font Awesome
+	{0xF300, 0xF372}, // Font Awesome Extension
+	{0xF400, 0xF533}, // Octicons
+	{0xF500, 0xFD46}, // Material Design Icons
+}
+
+// powerlineSeparators are the Powerline arrows, slants and curves, which are
+// drawn to meet the cells beside them
+var powerlineSeparators = glyphRange{0xE0B0, 0xE0D4}
+
 // loadFontData loads font from byte data and creates a glyph atlas
 func (r *Renderer) loadFontData(fontData []byte) error {
 	parsedFont, err := opentype.Parse(fontData)
@@ -197,54 +314,34 @@ func (r *Renderer) loadFontData(fontData []byte) error {
 	if err != nil {
 		return fmt.Errorf("failed to create font face: %w", err)
 	}
-	defer face.Close()
+
+	// The face stays open to rasterize lazy glyphs; close the previous font's
+	if r.lazyFace != nil {
+		r.lazyFace.Close()
+	}
+	r.lazyFace = face
+	r.missingGlyphs = make(map[rune]bool)
 
 	// Get font metrics
 	metrics := face.Metrics()
 	r.cellHeight = float32((metrics.Ascent + metrics.Descent).Ceil())
+	r.glyphAscent = metrics.Ascent.Ceil()
 
 	// Calculate cell width from 'M' character
 	advance, _ := face.GlyphAdvance('M')
 	r.cellWidth = float32(advance.Ceil())
 
-	// Character ranges to render (ASCII + Extended + Nerd Font icons)
-	// Defined BEFORE atlas creation so we can calculate required size
-	charRanges := []struct{ start, end rune }{
-		{32, 126},        // Printable ASCII
-		{160, 255},       // Extended Latin-1
-		{0x2000, 0x206F}, // General Punctuation (includes various spaces, dashes, dots)
-		{0x2100, 0x214F}, // Letterlike Symbols
-		{0x2190, 0x21FF}, // Arrows
-		{0x2200, 0x22FF}, // Mathematical Operators
-		{0x2300, 0x23FF}, // Miscellaneous Technical
-		{0x2500, 0x257F}, // Box Drawing
-		{0x2580, 0x259F}, // Block Elements
-		{0x25A0, 0x25FF}, // Geometric Shapes
-		{0x2600, 0x26FF}, // Miscellaneous Symbols
-		{0x2700, 0x27BF}, // Dingbats
-		{0x27C0, 0x27EF}, // Miscellaneous Mathematical Symbols-A
-		{0x27F0, 0x27FF}, // Supplemental Arrows-A
-		{0x2900, 0x297F}, // Supplemental Arrows-B
-		{0x2B00, 0x2BFF}, // Miscellaneous Symbols and Arrows
-		{0xE0A0, 0xE0D4}, // Powerline symbols
-		{0xE200, 0xE2A9}, // Pomicons
-		{0xE5FA, 0xE6B5}, // Seti-UI + Custom
-		{0xE700, 0xE7C5}, // Devicons
-		{0xEA60, 0xEC1E}, // Codicons
-		{0xED00, 0xEFC1}, // Font Logos
-		{0xF000, 0xF2E0}, // Font Awesome
-		{0xF300, 0xF372}, // Font Awesome Extension
-		{0xF400, 0xF533}, // Octicons
-		{0xF500, 0xFD46}, // Material Design Icons
-	}
-
 	// Calculate required atlas size based on glyph count
 	charHeight := int(r.cellHeight)
 	charWidth := int(r.cellWidth)
 
-	totalGlyphs := 0
-	for _, cr := range charRanges {
-		totalGlyphs += int(cr.end - cr.start + 1)
+	eagerGlyphs := 0
+	for _, gr := range eagerGlyphRanges {
+		eagerGlyphs += int(gr.end - gr.start + 1)
+	}
+	totalGlyphs := eagerGlyphs
+	for _, gr := range lazyGlyphRanges {
+		totalGlyphs += int(gr.end - gr.start + 1)
 	}
 
 	// Calculate atlas dimensions to fit all glyphs
@@ -256,168 +353,134 @@ func (r *Renderer) loadFontData(fontData []byte) error {
 
 	// Round to next power of 2 for GPU efficiency
 	r.atlasSize = nextPowerOf2(max(atlasWidth, atlasHeight))
+	// Lazy glyphs get fixed slots after every eager glyph
+	r.lazySlotBase = eagerGlyphs
 
-	// Create atlas image (RGBA for anti-aliasing)
-	atlas := image.NewRGBA(image.Rect(0, 0, r.atlasSize, r.atlasSize))
-	// Fill with transparent
-	draw.Draw(atlas, atlas.Bounds(), image.Transparent, image.Point{}, draw.Src)
-
-	// Drawer for rendering text
-	drawer := &font.Drawer{
-		Dst:  atlas,
-		Src:  image.White,
-		Face: face,
-	}
-
-	x, y := 0, metrics.Ascent.Ceil()
-
-	for _, cr := range charRanges {
-		for c := cr.start; c <= cr.end; c++ {
-			// Check if we need to wrap to next row
-			if x+charWidth > r.atlasSize {
-				x = 0
-				y += charHeight
-			}
-			if y+charHeight > r.atlasSize {
-				// With dynamic sizing this shouldn't happen, but warn if it does
-				fmt.Printf("Warning: Atlas overflow at glyph U+%04X, atlas=%d\n", c, r.atlasSize)
-				continue
-			}
+	// Single-channel alpha atlas for anti-aliased glyphs
+	atlas := image.NewAlpha(image.Rect(0, 0, r.atlasSize, r.atlasSize))
 
+	slot := 0
+	for _, gr := range eagerGlyphRanges {
+		for c := gr.start; c <= gr.end; c++ {
 			// Check if glyph exists in font
 			_, hasGlyph := face.GlyphAdvance(c)
 			if !hasGlyph {
 				continue
 			}
 
-			// Render glyph
-			drawer.Dot = fixed.P(x, y)
-			drawer.DrawString(string(c))
-
-			// Store glyph info (normalized coordinates)
-			r.glyphs[c] = Glyph{
-				X:           float32(x) / float32(r.atlasSize),
-				Y:           float32(y-metrics.Ascent.Ceil()) / float32(r.atlasSize),
-				Width:       float32(charWidth) / float32(r.atlasSize),
-				Height:      float32(charHeight) / float32(r.atlasSize),
-				PixelWidth:  charWidth,
-				PixelHeight: charHeight,
+			x, y, ok := r.atlasSlot(slot)
+			if !ok {
+				// With dynamic sizing this shouldn't happen, but warn if it does
+				logging.Warnf(logging.Render, "Atlas overflow at glyph U+%04X, atlas=%d", c, r.atlasSize)
+				continue
 			}
+			slot++
 
-			x += charWidth
-		}
-	}
+			// Render glyph
+			cell, metrics := r.rasterizeGlyph(face, c)
+			draw.Draw(atlas, image.Rect(x, y, x+charWidth, y+charHeight), cell, image.Point{}, draw.Src)
 
-	// Convert RGBA to single-channel alpha for OpenGL
-	alphaAtlas := make([]byte, r.atlasSize*r.atlasSize)
-	for i := 0; i < r.atlasSize*r.atlasSize; i++ {
-		// Use the alpha channel for anti-aliased edges
-		alphaAtlas[i] = atlas.Pix[i*4+3]
+			r.glyphs[c] = r.atlasGlyph(x, y, metrics)
+		}
 	}
 
-	// Create OpenGL texture
-	gl.GenTextures(1, &r.fontAtlas)
-	gl.BindTexture(gl.TEXTURE_2D, r.fontAtlas)
-	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RED, int32(r.atlasSize), int32(r.atlasSize), 0,
-		gl.RED, gl.UNSIGNED_BYTE, gl.Ptr(alphaAtlas))
-
-	// Use LINEAR filtering for smooth scaling (anti-aliasing)
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
-
-	gl.BindTexture(gl.TEXTURE_2D, 0)
+	r.backend.SetAtlas(atlas.Pix, r.atlasSize)
 
 	return nil
 }
 
-// initGL initializes OpenGL resources
-func (r *Renderer) initGL() error {
-	// Create quad shader program for colored rectangles
-	vertShader := `
-		#version 410 core
-		layout (location = 0) in vec2 aPos;
-		uniform mat4 projection;
-		void main() {
-			gl_Position = projection * vec4(aPos, 0.0, 1.0);
-		}
-	` + "\x00"
-
-	fragShader := `
-		#version 410 core
-		out vec4 FragColor;
-		uniform vec4 color;
-		void main() {
-			FragColor = color;
-		}
-	` + "\x00"
+// atlasSlot returns the top-left pixel of an atlas slot
+func (r *Renderer) atlasSlot(slot int) (int, int, bool) {
+	charWidth, charHeight := int(r.cellWidth), int(r.cellHeight)
+	perRow := r.atlasSize / max(charWidth, 1)
+	x := (slot % perRow) * charWidth
+	y := (slot / perRow) * charHeight
+	return x, y, y+charHeight <= r.atlasSize
+}
 
-	var err error
-	r.program, err = createProgram(vertShader, fragShader)
-	if err != nil {
-		return fmt.Errorf("failed to create quad shader: %w", err)
-	}
+// atlasGlyph describes the glyph whose ink is stored at an atlas position
+func (r *Renderer) atlasGlyph(x, y int, metrics Glyph) Glyph {
+	metrics.X = float32(x) / float32(r.atlasSize)
+	metrics.Y = float32(y) / float32(r.atlasSize)
+	metrics.Width = float32(metrics.PixelWidth) / float32(r.atlasSize)
+	metrics.Height = float32(metrics.PixelHeight) / float32(r.atlasSize)
+	return metrics
+}
 
-	r.colorLoc = gl.GetUniformLocation(r.program, gl.Str("color\x00"))
-	r.projLoc = gl.GetUniformLocation(r.program, gl.Str("projection\x00"))
+// rasterizeGlyph draws c into a cell-sized image with its ink in the top-left
+// corner, and returns the ink's size and where it sits in the cell. With
+// powerline stretching on, separators are scaled to the full cell height and
+// their advance to the cell width, so they line up with the cells beside them
+// whatever the font's own metrics.
+func (r *Renderer) rasterizeGlyph(face font.Face, c rune) (*image.Alpha, Glyph) {
+	charWidth, charHeight := int(r.cellWidth), int(r.cellHeight)
+	cell := image.NewAlpha(image.Rect(0, 0, charWidth, charHeight))
+	dr, mask, maskp, advance, ok := face.Glyph(fixed.P(0, r.glyphAscent), c)
+	metrics := Glyph{Advance: advance.Round()}
+	if !ok || dr.Empty() {
+		return cell, metrics
+	}
+
+	if r.powerlineStretch && c >= powerlineSeparators.start && c <= powerlineSeparators.end && metrics.Advance > 0 {
+		scaleX := float64(charWidth) / float64(metrics.Advance)
+		left := min(max(int(math.Round(float64(dr.Min.X)*scaleX)), 0), charWidth)
+		right := min(max(int(math.Round(float64(dr.Max.X)*scaleX)), left), charWidth)
+		src := image.Rectangle{Min: maskp, Max: maskp.Add(dr.Size())}
+		xdraw.ApproxBiLinear.Scale(cell, image.Rect(0, 0, right-left, charHeight), mask, src, xdraw.Src, nil)
+		metrics.BearingX = left
+		metrics.PixelWidth, metrics.PixelHeight = right-left, charHeight
+		return cell, metrics
+	}
+
+	// Ink that does not fit in a cell keeps the part inside the cell; smaller
+	// ink may overhang it
+	ink := dr
+	if ink.Dx() > charWidth {
+		ink.Min.X, ink.Max.X = max(ink.Min.X, 0), min(ink.Max.X, charWidth)
+	}
+	if ink.Dy() > charHeight {
+		ink.Min.Y, ink.Max.Y = max(ink.Min.Y, 0), min(ink.Max.Y, charHeight)
+	}
+	metrics.BearingX, metrics.BearingY = ink.Min.X, ink.Min.Y
+	metrics.PixelWidth, metrics.PixelHeight = ink.Dx(), ink.Dy()
+	draw.DrawMask(cell, image.Rect(0, 0, ink.Dx(), ink.Dy()), image.Opaque, image.Point{}, mask, maskp.Add(ink.Min.Sub(dr.Min)), draw.Src)
+	return cell, metrics
+}
 
-	// Create text shader program with smooth alpha blending
-	textVertShader := `
-		#version 410 core
-		layout (location = 0) in vec4 vertex; // <vec2 pos, vec2 tex>
-		out vec2 TexCoords;
-		uniform mat4 projection;
-		void main() {
-			gl_Position = projection * vec4(vertex.xy, 0.0, 1.0);
-			TexCoords = vertex.zw;
-		}
-	` + "\x00"
+// glyph returns the atlas entry for a character, rasterizing a lazy glyph on first use
+func (r *Renderer) glyph(c rune) (Glyph, bool) {
+	if g, ok := r.glyphs[c]; ok {
+		return g, true
+	}
+	if r.lazyFace == nil || r.missingGlyphs[c] {
+		return Glyph{}, false
+	}
 
-	textFragShader := `
-		#version 410 core
-		in vec2 TexCoords;
-		out vec4 FragColor;
-		uniform sampler2D text;
-		uniform vec4 textColor;
-		void main() {
-			float alpha = texture(text, TexCoords).r;
-			FragColor = vec4(textColor.rgb, textColor.a * alpha);
+	slot := r.lazySlotBase
+	found := false
+	for _, gr := range lazyGlyphRanges {
+		if c >= gr.start && c <= gr.end {
+			slot += int(c - gr.start)
+			found = true
+			break
 		}
-	` + "\x00"
+		slot += int(gr.end - gr.start + 1)
+	}
+	if !found {
+		return Glyph{}, false
+	}
+	x, y, ok := r.atlasSlot(slot)
+	if _, hasGlyph := r.lazyFace.GlyphAdvance(c); !hasGlyph || !ok {
+		r.missingGlyphs[c] = true
+		return Glyph{}, false
+	}
 
-	r.fontProgram, err = createProgram(textVertShader, textFragShader)
-	if err != nil {
-		return fmt.Errorf("failed to create text shader: %w", err)
-	}
-
-	r.texColorLoc = gl.GetUniformLocation(r.fontProgram, gl.Str("textColor\x00"))
-	r.texProjLoc = gl.GetUniformLocation(r.fontProgram, gl.Str("projection\x00"))
-	r.texLoc = gl.GetUniformLocation(r.fontProgram, gl.Str("text\x00"))
-
-	// Create quad VAO/VBO
-	gl.GenVertexArrays(1, &r.quadVAO)
-	gl.GenBuffers(1, &r.quadVBO)
-	gl.BindVertexArray(r.quadVAO)
-	gl.BindBuffer(gl.ARRAY_BUFFER, r.quadVBO)
-	gl.BufferData(gl.ARRAY_BUFFER, 6*2*4, nil, gl.DYNAMIC_DRAW)
-	gl.EnableVertexAttribArray(0)
-	gl.VertexAttribPointerWithOffset(0, 2, gl.FLOAT, false, 2*4, 0)
-	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
-	gl.BindVertexArray(0)
-
-	// Create font VAO/VBO
-	gl.GenVertexArrays(1, &r.fontVAO)
-	gl.GenBuffers(1, &r.fontVBO)
-	gl.BindVertexArray(r.fontVAO)
-	gl.BindBuffer(gl.ARRAY_BUFFER, r.fontVBO)
-	gl.BufferData(gl.ARRAY_BUFFER, 6*4*4, nil, gl.DYNAMIC_DRAW)
-	gl.EnableVertexAttribArray(0)
-	gl.VertexAttribPointerWithOffset(0, 4, gl.FLOAT, false, 4*4, 0)
-	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
-	gl.BindVertexArray(0)
+	cell, metrics := r.rasterizeGlyph(r.lazyFace, c)
+	r.backend.UpdateAtlas(x, y, int(r.cellWidth), int(r.cellHeight), cell.Pix)
 
-	return nil
+	g := r.atlasGlyph(x, y, metrics)
+	r.glyphs[c] = g
+	return g, true
 }
 
 // Render renders the terminal
@@ -430,8 +493,7 @@ func (r *Renderer) RenderWithHelp(tm *tab.TabManager, width, height int, cursorV
 	proj := orthoMatrix(0, float32(width), float32(height), 0, -1, 1)
 
 	// Clear background
-	gl.ClearColor(r.theme.Background[0], r.theme.Background[1], r.theme.Background[2], r.theme.Background[3])
-	gl.Clear(gl.COLOR_BUFFER_BIT)
+	r.backend.Clear(width, height, r.theme.Background)
 
 	// Render tab bar
 	r.renderTabBar(tm, width, height, proj)
@@ -453,8 +515,7 @@ func (r *Renderer) RenderWithHelpAndPanels(tm *tab.TabManager, width, height int
 	proj := orthoMatrix(0, float32(width), float32(height), 0, -1, 1)
 
 	// Clear background
-	gl.ClearColor(r.theme.Background[0], r.theme.Background[1], r.theme.Background[2], r.theme.Background[3])
-	gl.Clear(gl.COLOR_BUFFER_BIT)
+	r.backend.Clear(width, height, r.theme.Background)
 
 	// Render tab bar
 	r.renderTabBar(tm, width, height, proj)
@@ -478,7 +539,8 @@ func (r *Renderer) RenderWithHelpAndPanels(tm *tab.TabManager, width, height int
 }
 
 func (r *Renderer) renderSearchPanel(panel *searchpanel.Panel, width, height int, proj [16]float32) {
-	layout := panel.Layout(width, height, r.cellWidth, r.cellHeight)
+	cellW, cellH := r.UICellDimensions()
+	layout := panel.Layout(width, height, cellW, cellH)
 
 	panelBg := [4]float32{0.05, 0.06, 0.08, 0.95}
 	borderColor := r.theme.TabActive
@@ -490,14 +552,18 @@ func (r *Renderer) renderSearchPanel(panel *searchpanel.Panel, width, height int
 	r.drawRect(layout.PanelX, layout.PanelY, borderWidth, layout.PanelHeight, borderColor, proj)
 	r.drawRect(layout.PanelX+layout.PanelWidth-borderWidth, layout.PanelY, borderWidth, layout.PanelHeight, borderColor, proj)
 
-	maxChars := int(layout.ContentWidth/r.cellWidth) - 2
+	maxChars := int(layout.ContentWidth/cellW) - 2
 	if maxChars < 10 {
 		maxChars = 10
 	}
 
-	r.drawText(layout.ContentX, layout.HeaderY, "Web Search", r.theme.TabActive, proj)
+	header := "Web Search"
+	if panel.Semantic {
+		header = "Scrollback Search"
+	}
+	r.drawUIText(layout.ContentX, layout.HeaderY, header, r.theme.TabActive, proj)
 
-	r.drawText(layout.ContentX, layout.InputLabelY, "Query", r.theme.Foreground, proj)
+	r.drawUIText(layout.ContentX, layout.InputLabelY, "Query", r.theme.Foreground, proj)
 	inputBoxColor := [4]float32{0.03, 0.03, 0.05, 1.0}
 	r.drawRect(layout.ContentX, layout.InputBoxY, layout.ContentWidth, layout.LineHeight, inputBoxColor, proj)
 
@@ -505,11 +571,11 @@ func (r *Renderer) renderSearchPanel(panel *searchpanel.Panel, width, height int
 	if len(inputText) > maxChars {
 		inputText = "..." + inputText[len(inputText)-maxChars+3:]
 	}
-	r.drawText(layout.ContentX+8, layout.InputBoxY+layout.LineHeight*0.75, inputText+"_", r.theme.TabActive, proj)
+	r.drawUIText(layout.ContentX+8, layout.InputBoxY+layout.LineHeight*0.75, inputText+"_", r.theme.TabActive, proj)
 
 	status := panel.Status
 	if panel.Loading {
-		spinner := panel.SpinnerFrame()
+		spinner := r.spinnerFrame(panel.SpinnerFrame())
 		if status == "Searching..." {
 			status = spinner + " Searching..."
 		} else if status == "Loading preview..." {
@@ -524,7 +590,7 @@ func (r *Renderer) renderSearchPanel(panel *searchpanel.Panel, width, height int
 		if len(status) > maxChars {
 			status = status[:maxChars-3] + "..."
 		}
-		r.drawText(layout.ContentX, layout.StatusY, status, r.theme.Cursor, proj)
+		r.drawUIText(layout.ContentX, layout.StatusY, status, r.theme.Cursor, proj)
 	}
 
 	if panel.Mode == searchpanel.ModePreview {
@@ -539,17 +605,26 @@ func (r *Renderer) renderSearchPanel(panel *searchpanel.Panel, width, height int
 		proxyState = "Proxy: on"
 	}
 	footerText = footerText + " | " + proxyState
+	if panel.Semantic {
+		footerText = "Enter: search | Up/Down: history | Right: show text"
+	}
+	if panel.Semantic && panel.WebEnabled {
+		footerText += " | Ctrl+S: web"
+	} else if panel.SemanticEnabled && panel.Mode != searchpanel.ModePreview {
+		footerText = "Ctrl+S: scrollback | " + footerText
+	}
 	if panel.Mode == searchpanel.ModePreview {
-		footerText = "Esc: back | Ctrl+O: open | " + proxyState
+		footerText = "Esc: back | Ctrl+O: open | Ctrl+R: reader pane | " + proxyState
 	}
 	if len(footerText) > maxChars {
 		footerText = footerText[:maxChars-3] + "..."
 	}
-	r.drawText(layout.ContentX, layout.FooterY, footerText, [4]float32{0.6, 0.6, 0.6, 1.0}, proj)
+	r.drawUIText(layout.ContentX, layout.FooterY, footerText, [4]float32{0.6, 0.6, 0.6, 1.0}, proj)
 }
 
 func (r *Renderer) renderAIPanel(panel *aipanel.Panel, width, height int, proj [16]float32) {
-	layout := panel.Layout(width, height, r.cellWidth, r.cellHeight)
+	cellW, cellH := r.UICellDimensions()
+	layout := panel.Layout(width, height, cellW, cellH)
 
 	panelBg := [4]float32{0.05, 0.06, 0.08, 0.95}
 	borderColor := r.theme.TabActive
@@ -561,16 +636,23 @@ func (r *Renderer) renderAIPanel(panel *aipanel.Panel, width, height int, proj [
 	r.drawRect(layout.PanelX, layout.PanelY, borderWidth, layout.PanelHeight, borderColor, proj)
 	r.drawRect(layout.PanelX+layout.PanelWidth-borderWidth, layout.PanelY, borderWidth, layout.PanelHeight, borderColor, proj)
 
-	maxChars := int(layout.ContentWidth/r.cellWidth) - 2
+	maxChars := int(layout.ContentWidth/cellW) - 2
 	if maxChars < 10 {
 		maxChars = 10
 	}
 
-	r.drawText(layout.ContentX, layout.HeaderY, "AI Chat", r.theme.TabActive, proj)
+	r.drawUIText(layout.ContentX, layout.HeaderY, "AI Chat", r.theme.TabActive, proj)
+	if persona := panel.CurrentPersona().Name; len(panel.Personas) > 1 {
+		label := "[" + persona + "]"
+		if len(label) > maxChars-8 {
+			label = label[:maxChars-11] + "...]"
+		}
+		r.drawUIText(layout.ContentX+layout.ContentWidth-float32(len(label))*cellW, layout.HeaderY, label, r.theme.Cursor, proj)
+	}
 
 	status := panel.Status
 	if panel.Loading {
-		spinner := panel.SpinnerFrame()
+		spinner := r.spinnerFrame(panel.SpinnerFrame())
 		if status == "Loading model..." {
 			status = spinner + " Loading model..."
 		} else if status == "" || status == "Thinking..." {
@@ -583,10 +665,10 @@ func (r *Renderer) renderAIPanel(panel *aipanel.Panel, width, height int, proj [
 		if len(status) > maxChars {
 			status = status[:maxChars-3] + "..."
 		}
-		r.drawText(layout.ContentX, layout.StatusY, status, r.theme.Cursor, proj)
+		r.drawUIText(layout.ContentX, layout.StatusY, status, r.theme.Cursor, proj)
 	}
 
-	r.drawText(layout.ContentX, layout.InputLabelY, "Ask (Shift+Enter: newline)", r.theme.Foreground, proj)
+	r.drawUIText(layout.ContentX, layout.InputLabelY, "Ask (Shift+Enter: newline)", r.theme.Foreground, proj)
 	inputBoxColor := [4]float32{0.03, 0.03, 0.05, 1.0}
 	r.drawRect(layout.ContentX, layout.InputBoxY, layout.ContentWidth, layout.InputBoxH, inputBoxColor, proj)
 
@@ -613,29 +695,27 @@ func (r *Renderer) renderAIPanel(panel *aipanel.Panel, width, height int, proj [
 		if isLastLine {
 			lineText += "_"
 		}
-		r.drawText(layout.ContentX+8, inputY, lineText, r.theme.TabActive, proj)
+		r.drawUIText(layout.ContentX+8, inputY, lineText, r.theme.TabActive, proj)
 		inputY += layout.LineHeight
 	}
 
 	// If no input, show cursor on first line
 	if len(inputLines) == 0 || (len(inputLines) == 1 && inputLines[0] == "") {
-		r.drawText(layout.ContentX+8, layout.InputBoxY+layout.LineHeight*0.75, "_", r.theme.TabActive, proj)
+		r.drawUIText(layout.ContentX+8, layout.InputBoxY+layout.LineHeight*0.75, "_", r.theme.TabActive, proj)
 	}
 
 	// Show scroll indicator if input has more lines
 	if len(inputLines) > visibleInputLines {
 		scrollIndicator := fmt.Sprintf("↕ %d/%d", panel.InputScroll+1, len(inputLines)-visibleInputLines+1)
-		r.drawText(layout.ContentX+layout.ContentWidth-float32(len(scrollIndicator))*r.cellWidth-8,
+		r.drawUIText(layout.ContentX+layout.ContentWidth-float32(len(scrollIndicator))*cellW-8,
 			layout.InputBoxY+layout.InputBoxH-layout.LineHeight*0.3,
 			scrollIndicator, [4]float32{0.5, 0.5, 0.5, 1.0}, proj)
 	}
 
-	lines := aipanel.BuildWrappedLinesWithThinking(panel.Messages, maxChars, panel.ShowThinking, panel.ThinkingExpanded)
-	panel.WrapChars = maxChars
-	panel.WrappedLines = lines
+	lines := panel.Wrapped(maxChars)
 
 	if len(lines) == 0 && !panel.Loading {
-		r.drawText(layout.ContentX, layout.MessagesStart, "Ask a quick question to begin.", [4]float32{0.6, 0.6, 0.6, 1.0}, proj)
+		r.drawUIText(layout.ContentX, layout.MessagesStart, "Ask a quick question to begin.", [4]float32{0.6, 0.6, 0.6, 1.0}, proj)
 	} else {
 		visibleLines := layout.VisibleLines
 		totalLines := len(lines)
@@ -706,26 +786,29 @@ func (r *Renderer) renderAIPanel(panel *aipanel.Panel, width, height int, proj [
 						}
 					}
 				}
-				r.drawText(layout.ContentX, lineY, line.Text, color, proj)
+				r.drawUIText(layout.ContentX, lineY, line.Text, color, proj)
 			}
 			lineY += layout.LineHeight
 		}
 	}
 
-	footerText := "Ctrl+Enter: send | Ctrl+C: copy"
+	footerText := "Ctrl+Enter: send | Ctrl+C: copy | Ctrl+P: persona | Ctrl+D: dock"
 	if aipanel.HasThinkingContent(panel.Messages) {
 		footerText += " | Ctrl+T: thinking"
 	}
+	if panel.PendingCommand != "" {
+		footerText = "Y: run command | N: deny | Esc: close"
+	}
 	if len(footerText) > maxChars {
 		footerText = footerText[:maxChars-3] + "..."
 	}
-	r.drawText(layout.ContentX, layout.FooterY, footerText, [4]float32{0.6, 0.6, 0.6, 1.0}, proj)
+	r.drawUIText(layout.ContentX, layout.FooterY, footerText, [4]float32{0.6, 0.6, 0.6, 1.0}, proj)
 }
 
 func (r *Renderer) renderSearchResults(panel *searchpanel.Panel, layout searchpanel.Layout, maxChars int, proj [16]float32) {
 	if len(panel.Results) == 0 {
 		if !panel.Loading && strings.TrimSpace(panel.Query) != "" {
-			r.drawText(layout.ContentX, layout.ResultsStart, "No results.", [4]float32{0.6, 0.6, 0.6, 1.0}, proj)
+			r.drawUIText(layout.ContentX, layout.ResultsStart, "No results.", [4]float32{0.6, 0.6, 0.6, 1.0}, proj)
 		}
 		return
 	}
@@ -754,7 +837,7 @@ func (r *Renderer) renderSearchResults(panel *searchpanel.Panel, layout searchpa
 		if len(title) > maxChars {
 			title = title[:maxChars-3] + "..."
 		}
-		r.drawText(layout.ContentX, drawY, title, r.theme.TabActive, proj)
+		r.drawUIText(layout.ContentX, drawY, title, r.theme.TabActive, proj)
 
 		subLine := strings.TrimSpace(result.Snippet)
 		if subLine == "" {
@@ -763,7 +846,7 @@ func (r *Renderer) renderSearchResults(panel *searchpanel.Panel, layout searchpa
 		if len(subLine) > maxChars {
 			subLine = subLine[:maxChars-3] + "..."
 		}
-		r.drawText(layout.ContentX+12, drawY+layout.LineHeight, subLine, r.theme.Foreground, proj)
+		r.drawUIText(layout.ContentX+12, drawY+layout.LineHeight, subLine, r.theme.Foreground, proj)
 	}
 }
 
@@ -775,13 +858,15 @@ func (r *Renderer) renderSearchPreview(panel *searchpanel.Panel, layout searchpa
 	if len(header) > maxChars {
 		header = header[:maxChars-3] + "..."
 	}
-	r.drawText(layout.ContentX, layout.ResultsStart, header, r.theme.TabActive, proj)
+	r.drawUIText(layout.ContentX, layout.ResultsStart, header, r.theme.TabActive, proj)
 
-	wrappedLines := buildWrappedPreview(panel.PreviewLines, maxChars, r.theme)
-	panel.PreviewWrapped = nil
-	panel.PreviewWrapChars = maxChars
-	for _, line := range wrappedLines {
-		panel.PreviewWrapped = append(panel.PreviewWrapped, line.text)
+	wrappedLines, rebuilt := r.previewWrap.get(panel.PreviewLines, maxChars, r.theme)
+	if rebuilt || panel.PreviewWrapChars != maxChars || len(panel.PreviewWrapped) != len(wrappedLines) {
+		panel.PreviewWrapped = nil
+		panel.PreviewWrapChars = maxChars
+		for _, line := range wrappedLines {
+			panel.PreviewWrapped = append(panel.PreviewWrapped, line.text)
+		}
 	}
 
 	visibleLines := layout.VisibleLines - 1
@@ -814,7 +899,7 @@ func (r *Renderer) renderSearchPreview(panel *searchpanel.Panel, layout searchpa
 			r.drawRect(layout.ContentX, lineY-layout.LineHeight*0.75, layout.ContentWidth, layout.LineHeight, selColor, proj)
 		}
 
-		r.drawText(layout.ContentX, lineY, line.text, line.color, proj)
+		r.drawUIText(layout.ContentX, lineY, line.text, line.color, proj)
 		lineY += layout.LineHeight
 	}
 }
@@ -1067,15 +1152,41 @@ func (r *Renderer) getHelpSections() []struct {
 			bindings: [][2]string{
 				{"Ctrl+Q", "Exit terminal"},
 				{"Ctrl+Shift+C", "Copy visible screen"},
+				{"Ctrl+Insert", "Copy selection"},
 				{"Ctrl+Shift+P", "Paste clipboard"},
 				{"Shift+Enter", "Toggle fullscreen"},
 				{"Ctrl+Shift+K", "Show/hide help"},
 				{"Ctrl+Shift+S", "Open settings"},
 				{"Ctrl+Shift+F", "Toggle web search"},
 				{"Ctrl+Shift+A", "Toggle AI chat"},
+				{"Ctrl+Shift+M", "Toggle process monitor"},
+				{"Ctrl+Shift+U", "Toggle dev-server URLs"},
+				{"Ctrl+Shift+J", "Jump to directory"},
+				{"Ctrl+Shift+N", "Insert snippet"},
+				{"Ctrl+Shift+E", "Toggle redaction"},
+				{"Ctrl+Shift+L", "Lock screen"},
+				{"Ctrl+Shift+O", "Read pane aloud"},
+				{"Ctrl+Shift+G", "Hint mode (keyboard click)"},
+				{"Ctrl+Shift+Y", "Copy visible screen"},
+				{"Ctrl+Shift+B", "Copy whole scrollback"},
+				{"Ctrl+Shift+I", "Copy last command and output"},
+				{"Ctrl+Shift+Z", "Lock scrolling of two panes"},
+				{"Ctrl+Shift+R", "Toggle notification center"},
+				{"Ctrl+Shift+'", "Yank/paste a register"},
+				{"Ctrl+Shift+Space", "Spotlight the cursor"},
 				{"Ctrl+Shift++", "Zoom in"},
 				{"Ctrl+Shift+-", "Zoom out"},
 				{"Ctrl+Shift+0", "Reset zoom"},
+				{"Ctrl+Alt+=", "Zoom active pane in"},
+				{"Ctrl+Alt+-", "Zoom active pane out"},
+				{"Ctrl+Alt+0", "Reset pane zoom"},
+				{"Ctrl+Alt+P", "Print to PDF"},
+				{"Ctrl+Alt+C", "Calculator"},
+				{"Ctrl+Alt+U", "Inspect a character"},
+				{"Ctrl+Alt+K", "Color palette"},
+				{"Ctrl+Alt+M", "Set a scroll mark"},
+				{"Ctrl+Alt+'", "Jump to a scroll mark"},
+				{"Ctrl+Alt+S", "Pause or resume pane output"},
 			},
 		},
 		{
@@ -1083,8 +1194,10 @@ func (r *Renderer) getHelpSections() []struct {
 			bindings: [][2]string{
 				{"Ctrl+Shift+T", "New tab"},
 				{"Ctrl+Shift+X", "Close current tab"},
-				{"Ctrl+Tab", "Next tab"},
-				{"Ctrl+Shift+Tab", "Previous tab"},
+				{"Ctrl+Tab", "Recent tabs (hold Ctrl)"},
+				{"Ctrl+Shift+Tab", "Recent tabs, oldest first"},
+				{"Ctrl+1..9", "Go to tab 1-9"},
+				{"Ctrl+`", "Last used tab"},
 			},
 		},
 		{
@@ -1099,6 +1212,8 @@ func (r *Renderer) getHelpSections() []struct {
 				{"Ctrl+Shift+[ or ]", "Cycle overlay panel (when open)"},
 				{"Ctrl+R", "Toggle resize mode"},
 				{"Arrow Keys", "Resize active pane"},
+				{"Ctrl+Shift+D", "Show pane numbers"},
+				{"1-9", "Focus numbered pane"},
 			},
 		},
 		{
@@ -1178,6 +1293,7 @@ func (r *Renderer) ResetHelpScroll() {
 
 // renderHelpPanel renders the keybindings help overlay
 func (r *Renderer) renderHelpPanel(width, height int, proj [16]float32) {
+	cellW, cellH := r.UICellDimensions()
 	// Panel dimensions - dynamically sized based on window
 	// Use 80% of window size for the panel
 	panelWidth := float32(width) * 0.80
@@ -1225,7 +1341,7 @@ func (r *Renderer) renderHelpPanel(width, height int, proj [16]float32) {
 	contentX := panelX + marginX
 	contentWidth := panelWidth - marginX*2 - 25 // Leave room for scrollbar
 
-	lineHeight := r.cellHeight * 1.5
+	lineHeight := cellH * 1.5
 	headerY := panelY + 40
 	contentStartY := headerY + lineHeight*2
 	footerHeight := float32(50)
@@ -1235,11 +1351,11 @@ func (r *Renderer) renderHelpPanel(width, height int, proj [16]float32) {
 
 	// Calculate column positions - fixed key column width to prevent overlap
 	// Longest key is "Ctrl+Shift+Tab" or "Shift+PageDown" which needs ~15 chars
-	keyColWidth := r.cellWidth * 18 // 18 characters worth of space
+	keyColWidth := cellW * 18 // 18 characters worth of space
 	descColX := contentX + keyColWidth
 
 	// Title (fixed, doesn't scroll)
-	r.drawText(contentX, headerY, "Keybindings Help", r.theme.TabActive, proj)
+	r.drawUIText(contentX, headerY, "Keybindings Help", r.theme.TabActive, proj)
 
 	// Draw a separator line under the title
 	separatorY := headerY + lineHeight*0.8
@@ -1281,7 +1397,7 @@ func (r *Renderer) renderHelpPanel(width, height int, proj [16]float32) {
 		if currentLine >= r.helpScrollOffset && currentLine < r.helpScrollOffset+visibleLines {
 			drawY := contentStartY + float32(currentLine-r.helpScrollOffset)*lineHeight
 			if drawY+lineHeight <= contentEndY {
-				r.drawText(contentX, drawY, section.title, r.theme.TabActive, proj)
+				r.drawUIText(contentX, drawY, section.title, r.theme.TabActive, proj)
 			}
 		}
 		currentLine++
@@ -1291,8 +1407,8 @@ func (r *Renderer) renderHelpPanel(width, height int, proj [16]float32) {
 			if currentLine >= r.helpScrollOffset && currentLine < r.helpScrollOffset+visibleLines {
 				drawY := contentStartY + float32(currentLine-r.helpScrollOffset)*lineHeight
 				if drawY+lineHeight <= contentEndY {
-					r.drawText(contentX+15, drawY, binding[0], r.theme.Cursor, proj)
-					r.drawText(descColX, drawY, binding[1], r.theme.Foreground, proj)
+					r.drawUIText(contentX+15, drawY, binding[0], r.theme.Cursor, proj)
+					r.drawUIText(descColX, drawY, binding[1], r.theme.Foreground, proj)
 				}
 			}
 			currentLine++
@@ -1306,10 +1422,10 @@ func (r *Renderer) renderHelpPanel(width, height int, proj [16]float32) {
 	// Position text first, then put separator above it
 	footerY := panelY + panelHeight - 20
 	footerText := "Up/Down: scroll | Esc: close"
-	r.drawText(contentX, footerY, footerText, [4]float32{0.5, 0.5, 0.5, 1.0}, proj)
+	r.drawUIText(contentX, footerY, footerText, [4]float32{0.5, 0.5, 0.5, 1.0}, proj)
 
 	// Separator line above the footer text
-	footerSepY := footerY - r.cellHeight - 8
+	footerSepY := footerY - cellH - 8
 	r.drawRect(contentX, footerSepY, contentWidth, 1, r.theme.Foreground, proj)
 }
 
@@ -1318,8 +1434,7 @@ func (r *Renderer) RenderWithMenu(tm *tab.TabManager, width, height int, cursorV
 	proj := orthoMatrix(0, float32(width), float32(height), 0, -1, 1)
 
 	// Clear background
-	gl.ClearColor(r.theme.Background[0], r.theme.Background[1], r.theme.Background[2], r.theme.Background[3])
-	gl.Clear(gl.COLOR_BUFFER_BIT)
+	r.backend.Clear(width, height, r.theme.Background)
 
 	// Render tab bar
 	r.renderTabBar(tm, width, height, proj)
@@ -1336,8 +1451,39 @@ func (r *Renderer) RenderWithMenu(tm *tab.TabManager, width, height int, cursorV
 	}
 }
 
-// renderMenu renders the settings menu overlay
-func (r *Renderer) renderMenu(m *menu.Menu, width, height int, proj [16]float32) {
+// menuLayout is where the settings menu's parts are drawn, shared by
+// renderMenu and the hit tests for mouse clicks
+type menuLayout struct {
+	panelX        float32
+	panelY        float32
+	panelWidth    float32
+	panelHeight   float32
+	contentX      float32
+	contentWidth  float32
+	lineHeight    float32
+	headerY       float32
+	separatorY    float32
+	searchY       float32 // Top of the main menu's search box
+	contentStartY float32
+	contentEndY   float32
+	footerTextY   float32
+	footerSepY    float32
+	inputBoxY     float32 // Top of the input box, or of the multi-line text area
+	inputLines    int     // Lines the input box shows
+	visibleItems  int
+	maxScroll     int
+	maxChars      int // Characters that fit in a menu line after the "> " prefix
+}
+
+// Scroll bar of the settings menu
+const (
+	menuScrollBarWidth   = float32(8)
+	menuScrollBarPadding = float32(8)
+)
+
+// menuLayout places the settings menu in a width x height window
+func (r *Renderer) menuLayout(m *menu.Menu, width, height int) menuLayout {
+	cellW, cellH := r.UICellDimensions()
 	// Fixed panel dimensions - use percentage of window but with sensible limits
 	panelWidth := float32(width) * 0.75
 	panelHeight := float32(height) * 0.80
@@ -1364,28 +1510,12 @@ func (r *Renderer) renderMenu(m *menu.Menu, width, height int, proj [16]float32)
 	panelX := (float32(width) - panelWidth) / 2
 	panelY := (float32(height) - panelHeight) / 2
 
-	// Draw semi-transparent overlay
-	overlayColor := [4]float32{0.0, 0.0, 0.0, 0.8}
-	r.drawRect(0, 0, float32(width), float32(height), overlayColor, proj)
-
-	// Draw panel background
-	panelBg := [4]float32{0.06, 0.07, 0.10, 1.0}
-	r.drawRect(panelX, panelY, panelWidth, panelHeight, panelBg, proj)
-
-	// Draw panel border
-	borderColor := r.theme.TabActive
-	borderThickness := float32(2)
-	r.drawRect(panelX, panelY, panelWidth, borderThickness, borderColor, proj)
-	r.drawRect(panelX, panelY+panelHeight-borderThickness, panelWidth, borderThickness, borderColor, proj)
-	r.drawRect(panelX, panelY, borderThickness, panelHeight, borderColor, proj)
-	r.drawRect(panelX+panelWidth-borderThickness, panelY, borderThickness, panelHeight, borderColor, proj)
-
 	// Content area with margins
 	marginX := float32(20)
 	contentX := panelX + marginX
 	contentWidth := panelWidth - marginX*2
 
-	lineHeight := r.cellHeight * 1.5
+	lineHeight := cellH * 1.5
 	headerY := panelY + 35
 	separatorY := headerY + lineHeight*0.5
 
@@ -1403,8 +1533,12 @@ func (r *Renderer) renderMenu(m *menu.Menu, width, height int, proj [16]float32)
 		footerHeight += lineHeight
 	}
 
-	// Menu items area
+	// Menu items area, below the search box on the main menu
+	searchY := separatorY + lineHeight*0.4
 	contentStartY := separatorY + lineHeight*0.8
+	if m.State == menu.MenuMain {
+		contentStartY += lineHeight * 1.4
+	}
 	contentEndY := panelY + panelHeight - footerHeight
 	visibleHeight := contentEndY - contentStartY
 	visibleItems := int(visibleHeight / lineHeight)
@@ -1412,30 +1546,225 @@ func (r *Renderer) renderMenu(m *menu.Menu, width, height int, proj [16]float32)
 		visibleItems = 1
 	}
 
-	totalItems := len(m.Items)
-	maxScroll := totalItems - visibleItems
+	maxScroll := len(m.Items) - visibleItems
 	if maxScroll < 0 {
 		maxScroll = 0
 	}
-
-	scrollBarWidth := float32(8)
-	scrollBarPadding := float32(8)
 	if maxScroll > 0 {
-		contentWidth -= scrollBarWidth + scrollBarPadding
+		contentWidth -= menuScrollBarWidth + menuScrollBarPadding
 	}
 
 	// Calculate max characters that fit in content width (for truncation)
-	maxChars := int(contentWidth/r.cellWidth) - 3 // -3 for "> " prefix
+	maxChars := int(contentWidth/cellW) - 3 // -3 for "> " prefix
 	if maxChars < 10 {
 		maxChars = 10
 	}
 
+	// Footer area - positioned from bottom
+	footerTextY := panelY + panelHeight - 20
+	footerSepY := footerTextY - lineHeight
+
+	// Input box or text area above the footer, below its prompt
+	inputBoxY := footerSepY - lineHeight*2 + lineHeight*0.3
+	if inputIsMultiline {
+		inputBoxY = footerSepY - lineHeight*float32(inputLines) - lineHeight*0.8 + lineHeight*0.3
+	}
+
+	return menuLayout{
+		panelX:        panelX,
+		panelY:        panelY,
+		panelWidth:    panelWidth,
+		panelHeight:   panelHeight,
+		contentX:      contentX,
+		contentWidth:  contentWidth,
+		lineHeight:    lineHeight,
+		headerY:       headerY,
+		separatorY:    separatorY,
+		searchY:       searchY,
+		contentStartY: contentStartY,
+		contentEndY:   contentEndY,
+		footerTextY:   footerTextY,
+		footerSepY:    footerSepY,
+		inputBoxY:     inputBoxY,
+		inputLines:    inputLines,
+		visibleItems:  visibleItems,
+		maxScroll:     maxScroll,
+		maxChars:      maxChars,
+	}
+}
+
+// MenuItemAt returns the index of the settings menu item at window position
+// x, y, or -1 when there is none
+func (r *Renderer) MenuItemAt(m *menu.Menu, x, y float32, width, height int) int {
+	l := r.menuLayout(m, width, height)
+	if x < l.contentX || x > l.contentX+l.contentWidth {
+		return -1
+	}
+	// An item's highlight starts 8 pixels below the line above its text
+	top := l.contentStartY - l.lineHeight + 8
+	if y < top {
+		return -1
+	}
+	row := int((y - top) / l.lineHeight)
+	if row >= l.visibleItems {
+		return -1
+	}
+	if index := m.ScrollOffset + row; index < len(m.Items) {
+		return index
+	}
+	return -1
+}
+
+// MenuSliderAt returns the index of the slider item whose bar is at window
+// position x, y and how far along the bar x is, or -1 when there is none
+func (r *Renderer) MenuSliderAt(m *menu.Menu, x, y float32, width, height int) (int, float64) {
+	index := r.MenuItemAt(m, x, y, width, height)
+	if index < 0 || m.Items[index].Slider == nil {
+		return -1, 0
+	}
+	barX, barW := r.menuSliderBar(r.menuLayout(m, width, height))
+	if x < barX-6 || x > barX+barW+6 {
+		return -1, 0
+	}
+	return index, r.MenuSliderFraction(m, x, width, height)
+}
+
+// MenuSliderFraction returns how far along the slider bars of the settings
+// menu window position x is, from 0 to 1, for dragging a slider
+func (r *Renderer) MenuSliderFraction(m *menu.Menu, x float32, width, height int) float64 {
+	barX, barW := r.menuSliderBar(r.menuLayout(m, width, height))
+	if barW <= 0 {
+		return 0
+	}
+	return float64(min(max((x-barX)/barW, 0), 1))
+}
+
+// menuSliderBar returns where the bars of slider items start and how long
+// they are, leaving room for the value after them
+func (r *Renderer) menuSliderBar(l menuLayout) (float32, float32) {
+	cellW, _ := r.UICellDimensions()
+	x := l.contentX + l.contentWidth*0.5
+	return x, l.contentX + l.contentWidth - cellW*10 - x
+}
+
+// drawMenuWidget draws the bar and value of a slider item, or the chosen
+// option of a dropdown item, on the right of its row
+func (r *Renderer) drawMenuWidget(item menu.MenuItem, l menuLayout, y float32, color [4]float32, proj [16]float32) {
+	cellW, _ := r.UICellDimensions()
+	barX, barW := r.menuSliderBar(l)
+	dim := [4]float32{0.5, 0.5, 0.5, 1.0}
+	if item.Dropdown != nil {
+		r.drawUIText(barX, y, "<", dim, proj)
+		r.drawUIText(barX+cellW*2, y, item.Dropdown.Text(), color, proj)
+		r.drawUIText(barX+cellW*float32(len([]rune(item.Dropdown.Text()))+3), y, ">", dim, proj)
+		return
+	}
+	midY := y - l.lineHeight/2 + 8
+	filled := barW * float32(item.Slider.Fraction())
+	r.drawRect(barX, midY-2, barW, 4, [4]float32{0.25, 0.25, 0.3, 1.0}, proj)
+	r.drawRect(barX, midY-2, filled, 4, r.theme.TabActive, proj)
+	r.drawRect(barX+filled-3, midY-7, 6, 14, color, proj)
+	r.drawUIText(barX+barW+cellW, y, item.Slider.Text(), color, proj)
+}
+
+// MenuInputOffsetAt returns the rune offset in the settings menu's input at
+// window position x, y, reporting false when that is outside the input box
+func (r *Renderer) MenuInputOffsetAt(m *menu.Menu, x, y float32, width, height int) (int, bool) {
+	if !m.InputMode() {
+		return 0, false
+	}
+	l := r.menuLayout(m, width, height)
+	cellW, _ := r.UICellDimensions()
+	if x < l.contentX || x > l.contentX+l.contentWidth || y < l.inputBoxY || y >= l.inputBoxY+l.lineHeight*float32(l.inputLines) {
+		return 0, false
+	}
+	lines := strings.Split(m.GetInputBuffer(), "\n")
+	first, start := inputView(m.GetInputBuffer(), m.InputCursor, l.inputLines, l.maxChars-2)
+	line := first + int((y-l.inputBoxY)/l.lineHeight)
+	if line >= len(lines) {
+		line = len(lines) - 1
+	}
+	col := start + int((x-(l.contentX+8))/cellW+0.5)
+	col = max(0, min(col, len([]rune(lines[line]))))
+	for _, before := range lines[:line] {
+		col += len([]rune(before)) + 1
+	}
+	return col, true
+}
+
+// inputView returns the first line and column of the menu input shown in a
+// box of rows lines of cols characters, scrolled just enough to keep the
+// caret at cursor, a rune offset, in view
+func inputView(text string, cursor, rows, cols int) (int, int) {
+	line, col := menu.InputLineCol(text, cursor)
+	return max(0, line-rows+1), max(0, col-cols+1)
+}
+
+// drawInputLine draws line from column start in the menu input, with the
+// selected runes highlighted and the caret when it is on this line. offset
+// is where the line starts in the whole input, and y the top of its row.
+func (r *Renderer) drawInputLine(x, y float32, line []rune, offset, start, cols, cursor, selStart, selEnd int, lineHeight float32, proj [16]float32) {
+	cellW, _ := r.UICellDimensions()
+	end := min(len(line), start+cols)
+	start = min(start, end)
+	if a, b := max(selStart-offset, start), min(selEnd-offset, end); a < b {
+		r.drawRect(x+float32(a-start)*cellW, y+2, float32(b-a)*cellW, lineHeight-4, [4]float32{0.2, 0.3, 0.5, 1.0}, proj)
+	}
+	r.drawUIText(x, y+lineHeight*0.75, string(line[start:end]), r.theme.TabActive, proj)
+	if col := cursor - offset; col >= start && col <= end {
+		r.drawRect(x+float32(col-start)*cellW, y+3, 2, lineHeight-6, r.theme.Cursor, proj)
+	}
+}
+
+// renderMenu renders the settings menu overlay
+func (r *Renderer) renderMenu(m *menu.Menu, width, height int, proj [16]float32) {
+	cellW, _ := r.UICellDimensions()
+	l := r.menuLayout(m, width, height)
+	panelX, panelY, panelWidth, panelHeight := l.panelX, l.panelY, l.panelWidth, l.panelHeight
+	contentX, contentWidth, lineHeight := l.contentX, l.contentWidth, l.lineHeight
+	headerY, separatorY := l.headerY, l.separatorY
+	contentStartY, contentEndY := l.contentStartY, l.contentEndY
+	visibleItems, maxScroll, maxChars := l.visibleItems, l.maxScroll, l.maxChars
+	inputIsMultiline := m.InputMode() && m.InputIsMultiline()
+	totalItems := len(m.Items)
+
+	// Draw semi-transparent overlay
+	overlayColor := [4]float32{0.0, 0.0, 0.0, 0.8}
+	r.drawRect(0, 0, float32(width), float32(height), overlayColor, proj)
+
+	// Draw panel background
+	panelBg := [4]float32{0.06, 0.07, 0.10, 1.0}
+	r.drawRect(panelX, panelY, panelWidth, panelHeight, panelBg, proj)
+
+	// Draw panel border
+	borderColor := r.theme.TabActive
+	borderThickness := float32(2)
+	r.drawRect(panelX, panelY, panelWidth, borderThickness, borderColor, proj)
+	r.drawRect(panelX, panelY+panelHeight-borderThickness, panelWidth, borderThickness, borderColor, proj)
+	r.drawRect(panelX, panelY, borderThickness, panelHeight, borderColor, proj)
+	r.drawRect(panelX+panelWidth-borderThickness, panelY, borderThickness, panelHeight, borderColor, proj)
+
 	// Title
-	r.drawText(contentX, headerY, m.GetTitle(), r.theme.TabActive, proj)
+	r.drawUIText(contentX, headerY, m.GetTitle(), r.theme.TabActive, proj)
 
 	// Separator under title
 	r.drawRect(contentX, separatorY, contentWidth, 1, r.theme.Foreground, proj)
 
+	// Search box of the main menu, which typing fills
+	if m.State == menu.MenuMain {
+		r.drawRect(contentX, l.searchY, contentWidth, lineHeight, [4]float32{0.03, 0.03, 0.05, 1.0}, proj)
+		if m.Query == "" {
+			r.drawUIText(contentX+8+cellW, l.searchY+lineHeight*0.75, "Type to search settings", [4]float32{0.5, 0.5, 0.5, 1.0}, proj)
+		}
+		query := []rune(m.Query)
+		_, start := inputView(m.Query, len(query), 1, maxChars-2)
+		caret := len(query)
+		if m.InputMode() {
+			caret = -1 // The input below has the caret
+		}
+		r.drawInputLine(contentX+8, l.searchY, query, 0, start, maxChars-2, caret, 0, 0, lineHeight, proj)
+	}
+
 	// Draw menu items
 	itemIndex := 0
 	headerColor := [4]float32{0.5, 0.5, 0.6, 1.0}   // Dim color for headers
@@ -1460,7 +1789,7 @@ func (r *Renderer) renderMenu(m *menu.Menu, width, height int, proj [16]float32)
 
 		// Section headers - styled differently, not selectable
 		if item.IsHeader {
-			r.drawText(contentX+5, y, item.Label, headerColor, proj)
+			r.drawUIText(contentX+5, y, item.Label, headerColor, proj)
 			itemIndex++
 			continue
 		}
@@ -1475,27 +1804,35 @@ func (r *Renderer) renderMenu(m *menu.Menu, width, height int, proj [16]float32)
 			}
 		}
 
-		// Truncate label to fit
-		if len(label) > maxChars {
-			label = label[:maxChars-3] + "..."
+		// Truncate label to fit, beside the slider or dropdown of the item
+		labelChars := maxChars
+		if item.Slider != nil || item.Dropdown != nil {
+			barX, _ := r.menuSliderBar(l)
+			labelChars = max(int((barX-contentX)/cellW)-4, 4)
+		}
+		if len(label) > labelChars {
+			label = label[:labelChars-3] + "..."
 		}
 
 		// Highlight selected item
 		if i == m.SelectedIndex {
 			highlightColor := [4]float32{0.15, 0.17, 0.25, 1.0}
 			r.drawRect(contentX, y-lineHeight+8, contentWidth, lineHeight, highlightColor, proj)
-			r.drawText(contentX+5, y, ">", r.theme.TabActive, proj)
+			r.drawUIText(contentX+5, y, ">", r.theme.TabActive, proj)
 			if item.IsToggle {
 				// Color the checkbox based on state
 				checkColor := toggleOffColor
 				if item.Toggled {
 					checkColor = toggleOnColor
 				}
-				checkboxEnd := r.cellWidth*4 + 5
-				r.drawText(contentX+r.cellWidth*2+5, y, label[:4], checkColor, proj)
-				r.drawText(contentX+r.cellWidth*2+5+checkboxEnd, y, label[4:], r.theme.TabActive, proj)
+				checkboxEnd := cellW*4 + 5
+				r.drawUIText(contentX+cellW*2+5, y, label[:4], checkColor, proj)
+				r.drawUIText(contentX+cellW*2+5+checkboxEnd, y, label[4:], r.theme.TabActive, proj)
 			} else {
-				r.drawText(contentX+r.cellWidth*2+5, y, label, r.theme.TabActive, proj)
+				r.drawUIText(contentX+cellW*2+5, y, label, r.theme.TabActive, proj)
+			}
+			if item.Slider != nil || item.Dropdown != nil {
+				r.drawMenuWidget(item, l, y, r.theme.TabActive, proj)
 			}
 		} else {
 			if item.IsToggle {
@@ -1504,86 +1841,43 @@ func (r *Renderer) renderMenu(m *menu.Menu, width, height int, proj [16]float32)
 				if item.Toggled {
 					checkColor = toggleOnColor
 				}
-				checkboxEnd := r.cellWidth*4 + 5
-				r.drawText(contentX+r.cellWidth*2+5, y, label[:4], checkColor, proj)
-				r.drawText(contentX+r.cellWidth*2+5+checkboxEnd, y, label[4:], r.theme.Foreground, proj)
+				checkboxEnd := cellW*4 + 5
+				r.drawUIText(contentX+cellW*2+5, y, label[:4], checkColor, proj)
+				r.drawUIText(contentX+cellW*2+5+checkboxEnd, y, label[4:], r.theme.Foreground, proj)
 			} else {
-				r.drawText(contentX+r.cellWidth*2+5, y, label, r.theme.Foreground, proj)
+				r.drawUIText(contentX+cellW*2+5, y, label, r.theme.Foreground, proj)
+			}
+			if item.Slider != nil || item.Dropdown != nil {
+				r.drawMenuWidget(item, l, y, r.theme.Foreground, proj)
 			}
 		}
 		itemIndex++
 	}
 
-	// Footer area - positioned from bottom
-	footerTextY := panelY + panelHeight - 20
-	footerSepY := footerTextY - lineHeight
+	footerTextY, footerSepY := l.footerTextY, l.footerSepY
 
 	// Input mode - draw input box
 	if m.InputMode() {
-		inputText := m.GetInputBuffer()
 		prompt := m.GetInputPrompt()
 		if len(prompt) > maxChars {
 			prompt = prompt[:maxChars-3] + "..."
 		}
+		r.drawUIText(contentX+5, l.inputBoxY-lineHeight*0.3, prompt, r.theme.Foreground, proj)
+		r.drawRect(contentX, l.inputBoxY, contentWidth, lineHeight*float32(l.inputLines), [4]float32{0.03, 0.03, 0.05, 1.0}, proj)
 
-		if inputIsMultiline {
-			textAreaHeight := lineHeight * float32(inputLines)
-			inputAreaY := footerSepY - textAreaHeight - lineHeight*0.8
-
-			// Input prompt
-			r.drawText(contentX+5, inputAreaY, prompt, r.theme.Foreground, proj)
-
-			// Text area background
-			textBoxY := inputAreaY + lineHeight*0.3
-			r.drawRect(contentX, textBoxY, contentWidth, textAreaHeight, [4]float32{0.03, 0.03, 0.05, 1.0}, proj)
-
-			lines := strings.Split(inputText, "\n")
-			if len(lines) == 0 {
-				lines = []string{""}
-			}
-			start := 0
-			if len(lines) > inputLines {
-				start = len(lines) - inputLines
-			}
-			visibleLines := lines[start:]
-
-			lineY := textBoxY + lineHeight*0.75
-			for i, line := range visibleLines {
-				cursor := ""
-				if i == len(visibleLines)-1 {
-					cursor = "_"
-				}
-				maxInputChars := maxChars - 2
-				availableChars := maxInputChars - len(cursor)
-				displayLine := line
-				if availableChars <= 0 {
-					displayLine = ""
-				} else if len(displayLine) > availableChars {
-					if availableChars > 3 {
-						displayLine = "..." + displayLine[len(displayLine)-(availableChars-3):]
-					} else {
-						displayLine = displayLine[len(displayLine)-availableChars:]
-					}
-				}
-				r.drawText(contentX+8, lineY, displayLine+cursor, r.theme.TabActive, proj)
-				lineY += lineHeight
-			}
-		} else {
-			inputAreaY := footerSepY - lineHeight*2
-
-			// Input prompt
-			r.drawText(contentX+5, inputAreaY, prompt, r.theme.Foreground, proj)
-
-			// Input box background
-			inputBoxY := inputAreaY + lineHeight*0.3
-			r.drawRect(contentX, inputBoxY, contentWidth, lineHeight, [4]float32{0.03, 0.03, 0.05, 1.0}, proj)
-
-			// Input text with cursor - truncate from left if too long
-			maxInputChars := maxChars - 2
-			if len(inputText) > maxInputChars {
-				inputText = "..." + inputText[len(inputText)-maxInputChars+3:]
+		// Lines scrolled to keep the cursor in view, with the selection and cursor
+		inputText := m.GetInputBuffer()
+		cols := maxChars - 2
+		first, start := inputView(inputText, m.InputCursor, l.inputLines, cols)
+		selStart, selEnd := m.InputSelection()
+		offset := 0
+		for i, line := range strings.Split(inputText, "\n") {
+			runes := []rune(line)
+			if i >= first && i < first+l.inputLines {
+				y := l.inputBoxY + float32(i-first)*lineHeight
+				r.drawInputLine(contentX+8, y, runes, offset, start, cols, m.InputCursor, selStart, selEnd, lineHeight, proj)
 			}
-			r.drawText(contentX+8, inputBoxY+lineHeight*0.75, inputText+"_", r.theme.TabActive, proj)
+			offset += len(runes) + 1
 		}
 	}
 
@@ -1594,7 +1888,7 @@ func (r *Renderer) renderMenu(m *menu.Menu, width, height int, proj [16]float32)
 		if len(status) > maxChars {
 			status = status[:maxChars-3] + "..."
 		}
-		r.drawText(contentX, statusY, status, r.theme.Cursor, proj)
+		r.drawUIText(contentX, statusY, status, r.theme.Cursor, proj)
 		footerSepY = statusY - lineHeight*0.5
 	}
 
@@ -1611,10 +1905,25 @@ func (r *Renderer) renderMenu(m *menu.Menu, width, height int, proj [16]float32)
 		}
 	} else {
 		footerText = "Up/Down | Enter | Del | Esc"
+		if m.State == menu.MenuMain {
+			footerText = "Type to search | Up/Down | Enter | Ctrl+Z: undo | Esc"
+			if m.Query != "" {
+				footerText = "Up/Down | Enter | Backspace | Esc: clear search"
+			}
+		}
+		if m.SelectedIndex >= 0 && m.SelectedIndex < len(m.Items) {
+			if item := m.Items[m.SelectedIndex]; item.Slider != nil || item.Dropdown != nil {
+				footerText = "Up/Down | Left/Right: change | Enter | Esc"
+			}
+		}
+		if m.State == menu.MenuCommands {
+			footerText = "Up/Down | Enter: edit | Ctrl+Enter: run | Del | Esc"
+		}
 	}
-	r.drawText(contentX, footerTextY, footerText, [4]float32{0.5, 0.5, 0.5, 1.0}, proj)
+	r.drawUIText(contentX, footerTextY, footerText, [4]float32{0.5, 0.5, 0.5, 1.0}, proj)
 
 	if maxScroll > 0 {
+		scrollBarWidth, scrollBarPadding := menuScrollBarWidth, menuScrollBarPadding
 		scrollBarX := contentX + contentWidth + scrollBarPadding
 		scrollBarHeight := contentEndY - contentStartY
 		scrollBarY := contentStartY
@@ -1700,92 +2009,628 @@ func (r *Renderer) renderPanes(t *tab.Tab, width, height int, proj [16]float32,
 			r.drawRect(offsetX+paneWidth-borderWidth, offsetY, borderWidth, paneHeight, borderColor, proj)
 		}
 
+		if profile := r.paneProfiles[layout.Pane]; profile.Tinted {
+			r.drawRect(offsetX, offsetY, paneWidth, paneHeight, profile.Tint, proj)
+		}
+
 		// Render the pane's grid
 		showCursor := cursorVisible && isActive
 		cursorStyle := parser.CursorStyleBlock
 		if layout.Pane != nil && layout.Pane.Terminal != nil {
 			cursorStyle = layout.Pane.Terminal.CursorStyle()
 		}
-		r.renderGridAt(layout.Pane.Terminal.GetGrid(), offsetX, offsetY, paneWidth, paneHeight, proj, showCursor, cursorStyle)
-	}
-}
+		g := layout.Pane.Terminal.GetGrid()
+		if layout.Pane == r.replayPane && r.replayGrid != nil {
+			g, showCursor = r.replayGrid, false
+		}
+		highlights := r.paneHighlights[layout.Pane]
+		// Draw the grid and everything on it from one copy of the viewport
+		// so output arriving mid-frame cannot tear it
+		snap := &r.snapshot
+		g.CopyViewport(snap)
+		gridX, gridWidth := offsetX, paneWidth
+		if gutter := r.gutterWidth(layout.Pane); gutter > 0 {
+			cellW, _ := r.PaneCellSize(layout.Pane.Terminal.GetGrid())
+			x := offsetX
+			if layout.Pane.LineNumbers() {
+				r.drawLineNumberGutter(snap, x, offsetY, paneHeight, proj)
+				x += cellW * tab.LineNumberGutterCols
+			}
+			if layout.Pane.Timestamps() {
+				r.drawTimestampGutter(snap, x, offsetY, paneHeight, proj)
+			}
+			gridX, gridWidth = offsetX+gutter, paneWidth-gutter
+		}
+		r.renderGridAt(snap, r.hoverGrid == g, gridX, offsetY, gridWidth, paneHeight, proj, showCursor, cursorStyle, highlights)
+		if r.stickyPrompt {
+			r.drawStickyPrompt(snap, gridX, offsetY, gridWidth, proj)
+		}
+		r.drawBookmarks(snap, gridX, offsetY, gridWidth, paneHeight, proj)
+		if r.wrapMarkers {
+			r.drawWrapMarkers(snap, gridX, offsetY, gridWidth, paneHeight, proj)
+		}
+		if row := r.promptRows[layout.Pane]; row != "" && layout.Pane != r.replayPane {
+			r.drawPromptRow(layout.Pane.Terminal, snap, gridX, offsetY, gridWidth, row, proj)
+		}
+		if layout.Pane == r.aliasPane && r.aliasPreview != "" && layout.Pane != r.replayPane {
+			r.drawAliasPreview(snap, gridX, offsetY, gridWidth, proj)
+		}
 
-func (r *Renderer) paneRects(t *tab.Tab, width, height int) []paneRect {
-	if t == nil {
-		return nil
-	}
-	layouts := t.GetPaneLayouts()
-	if len(layouts) == 0 {
-		return nil
+		// Warning border for root and ssh sessions, drawn over the grid so it is never hidden
+		if color, ok := r.sessionBorders[layout.Pane]; ok && r.sessionBorderWidth > 0 {
+			w := r.sessionBorderWidth
+			r.drawRect(offsetX, offsetY, paneWidth, w, color, proj)
+			r.drawRect(offsetX, offsetY+paneHeight-w, paneWidth, w, color, proj)
+			r.drawRect(offsetX, offsetY, w, paneHeight, color, proj)
+			r.drawRect(offsetX+paneWidth-w, offsetY, w, paneHeight, color, proj)
+		}
 	}
 
-	baseX := r.tabBarWidth + 5
-	baseY := r.paddingTop
-	availableWidth := float32(width) - r.tabBarWidth - 5
-	availableHeight := float32(height) - r.paddingTop - r.paddingBottom
-	separatorWidth := float32(2)
-
-	rects := make([]paneRect, 0, len(layouts))
-	for _, layout := range layouts {
-		offsetX := baseX + layout.X*availableWidth
-		offsetY := baseY + layout.Y*availableHeight
-		paneWidth := layout.Width * availableWidth
-		paneHeight := layout.Height * availableHeight
-
-		if len(layouts) > 1 {
-			if layout.X > 0 {
-				offsetX += separatorWidth / 2
-				paneWidth -= separatorWidth / 2
-			}
-			if layout.X+layout.Width < 1.0 {
-				paneWidth -= separatorWidth / 2
-			}
-			if layout.Y > 0 {
-				offsetY += separatorWidth / 2
-				paneHeight -= separatorWidth / 2
+	if len(layouts) > 1 && (r.showPaneTitles || r.showPaneNumbers) {
+		for i, rect := range r.paneRects(t, width, height) {
+			isActive := rect.pane == activePane
+			if r.showPaneTitles {
+				r.drawPaneTitle(rect, i+1, r.paneProfiles[rect.pane].TitlePrefix, isActive, proj)
 			}
-			if layout.Y+layout.Height < 1.0 {
-				paneHeight -= separatorWidth / 2
+			if r.showPaneNumbers {
+				r.drawPaneNumber(rect, i+1, isActive, proj)
 			}
 		}
-
-		rects = append(rects, paneRect{
-			pane:   layout.Pane,
-			x:      offsetX,
-			y:      offsetY,
-			width:  paneWidth,
-			height: paneHeight,
-		})
 	}
 
-	return rects
-}
+	if len(r.paneBadges) > 0 {
+		for _, rect := range r.paneRects(t, width, height) {
+			if badge := r.paneBadges[rect.pane]; badge != "" {
+				r.drawPaneBadge(rect, badge, proj)
+			}
+		}
+	}
 
-// HitTestPane returns the pane and cell position for a screen coordinate.
-func (r *Renderer) HitTestPane(t *tab.Tab, x, y float64, width, height int) (*tab.Pane, int, int, bool) {
-	fx := float32(x)
-	fy := float32(y)
-	for _, rect := range r.paneRects(t, width, height) {
-		if fx < rect.x || fx >= rect.x+rect.width || fy < rect.y || fy >= rect.y+rect.height {
-			continue
+	if len(r.paneStatus) > 0 || r.replayPane != nil {
+		for _, rect := range r.paneRects(t, width, height) {
+			status := r.paneStatus[rect.pane]
+			if rect.pane == r.replayPane && r.replayLabel != "" {
+				status = r.replayLabel
+			}
+			if status != "" {
+				r.drawPaneStatus(rect, status, proj)
+			}
 		}
-		g := rect.pane.Terminal.GetGrid()
-		col := int((fx - rect.x) / r.cellWidth)
-		row := int((fy - rect.y) / r.cellHeight)
-		col = clampInt(col, 0, g.Cols-1)
-		row = clampInt(row, 0, g.Rows-1)
-		return rect.pane, col, row, true
 	}
-	return nil, 0, 0, false
 }
 
-// PaneRectFor returns the screen rect for a specific pane.
-func (r *Renderer) PaneRectFor(t *tab.Tab, pane *tab.Pane, width, height int) (float32, float32, float32, float32, bool) {
-	if pane == nil {
-		return 0, 0, 0, 0, false
+// paneTitle returns the label shown on a pane border.
+func paneTitle(pane *tab.Pane) string {
+	if pane == nil || pane.Terminal == nil {
+		return ""
 	}
-	for _, rect := range r.paneRects(t, width, height) {
-		if rect.pane == pane {
+	if title := strings.TrimSpace(pane.Terminal.GetWindowTitle()); title != "" {
+		return title
+	}
+	if dir := pane.Terminal.WorkingDir(); dir != "" {
+		return filepath.Base(dir)
+	}
+	return ""
+}
+
+// drawPaneTitle draws the pane number and title on the top border of a pane.
+func (r *Renderer) drawPaneTitle(rect paneRect, number int, prefix string, active bool, proj [16]float32) {
+	scale := 0.85 * r.uiScale()
+	cellW := r.cellWidth * scale
+	cellH := r.cellHeight * scale
+
+	label := fmt.Sprintf(" %d", number)
+	if title := strings.TrimSpace(prefix + " " + paneTitle(rect.pane)); title != "" {
+		label += ": " + title
+	}
+	label += " "
+
+	maxChars := int(rect.width/cellW) - 2
+	if maxChars < 6 {
+		return
+	}
+	runes := []rune(label)
+	if len(runes) > maxChars {
+		label = string(runes[:maxChars-3]) + "..."
+		runes = []rune(label)
+	}
+
+	boxW := float32(len(runes)) * cellW
+	x := rect.x + rect.width - boxW - cellW
+	y := rect.y
+	bg := r.theme.TabBar
+	clr := r.theme.Foreground
+	if active {
+		clr = r.theme.TabActive
+	}
+	r.drawRect(x, y, boxW, cellH, bg, proj)
+	r.drawTextScaled(x, y+cellH, label, clr, proj, scale)
+}
+
+// drawPaneStatus draws a pane's status label on the left of its top border.
+func (r *Renderer) drawPaneStatus(rect paneRect, status string, proj [16]float32) {
+	scale := 0.85 * r.uiScale()
+	cellW := r.cellWidth * scale
+	cellH := r.cellHeight * scale
+
+	maxChars := int(rect.width/cellW)/2 - 2
+	if maxChars < 6 {
+		return
+	}
+	label := " " + status + " "
+	runes := []rune(label)
+	if len(runes) > maxChars {
+		label = string(runes[:maxChars-3]) + "..."
+		runes = []rune(label)
+	}
+
+	boxW := float32(len(runes)) * cellW
+	x := rect.x + cellW
+	r.drawRect(x, rect.y, boxW, cellH, r.theme.TabBar, proj)
+	r.drawTextScaled(x, rect.y+cellH, label, r.theme.Cursor, proj, scale)
+}
+
+// drawPaneBadge draws a pane's badge large and faint in its top right
+// corner, below the border title, one line per line of the badge.
+func (r *Renderer) drawPaneBadge(rect paneRect, badge string, proj [16]float32) {
+	scale := 1.6 * r.uiScale()
+	cellW := r.cellWidth * scale
+	cellH := r.cellHeight * scale
+	maxChars := int(rect.width/cellW)/2 - 1
+	if maxChars < 4 {
+		return
+	}
+
+	clr := r.theme.Foreground
+	clr[3] = 0.3
+	y := rect.y + r.cellHeight*0.85*r.baseFontSize/r.fontSize + cellH*1.1
+	for _, line := range strings.Split(badge, "\n") {
+		if y > rect.y+rect.height {
+			return
+		}
+		runes := []rune(line)
+		if len(runes) > maxChars {
+			runes = append(runes[:maxChars-3], '.', '.', '.')
+		}
+		x := rect.x + rect.width - float32(len(runes))*cellW - cellW/2
+		r.drawTextScaled(x, y, string(runes), clr, proj, scale)
+		y += cellH
+	}
+}
+
+// drawPaneNumber draws a large pane number centered over a pane.
+func (r *Renderer) drawPaneNumber(rect paneRect, number int, active bool, proj [16]float32) {
+	scale := 4.0 * r.uiScale()
+	text := fmt.Sprintf("%d", number)
+	cellW := r.cellWidth * scale
+	cellH := r.cellHeight * scale
+	boxW := float32(len(text))*cellW + cellW
+	boxH := cellH * 1.2
+	if boxW > rect.width || boxH > rect.height {
+		scale /= 2
+		cellW /= 2
+		cellH /= 2
+		boxW = float32(len(text))*cellW + cellW
+		boxH = cellH * 1.2
+	}
+
+	x := rect.x + (rect.width-boxW)/2
+	y := rect.y + (rect.height-boxH)/2
+	bg := r.theme.TabBar
+	bg[3] = 0.85
+	clr := r.theme.Foreground
+	if active {
+		clr = r.theme.TabActive
+	}
+	r.drawRect(x, y, boxW, boxH, bg, proj)
+	r.drawTextScaled(x+cellW/2, y+cellH*1.1, text, clr, proj, scale)
+}
+
+// SetPaneTitles toggles pane number and title labels on pane borders.
+func (r *Renderer) SetPaneTitles(enabled bool) {
+	r.showPaneTitles = enabled
+}
+
+// SetPowerlineStretch sets whether powerline separators are scaled to fill
+// their cell. Separators already in the atlas are drawn again on next use.
+func (r *Renderer) SetPowerlineStretch(enabled bool) {
+	if enabled == r.powerlineStretch {
+		return
+	}
+	r.powerlineStretch = enabled
+	for c := powerlineSeparators.start; c <= powerlineSeparators.end; c++ {
+		delete(r.glyphs, c)
+	}
+}
+
+// SetCursorThickness sets the bar and underline cursor thickness in pixels; 0 restores the default.
+func (r *Renderer) SetCursorThickness(pixels float32) {
+	r.cursorThickness = pixels
+}
+
+// SetReduceMotion turns off animation such as cursor blinking and loading spinners.
+func (r *Renderer) SetReduceMotion(enabled bool) {
+	r.reduceMotion = enabled
+}
+
+// ReduceMotion reports whether animation is turned off.
+func (r *Renderer) ReduceMotion() bool {
+	return r.reduceMotion
+}
+
+// spinnerFrame returns a static indicator in place of the animated frame when motion is reduced.
+func (r *Renderer) spinnerFrame(frame string) string {
+	if r.reduceMotion && frame != "" {
+		return "*"
+	}
+	return frame
+}
+
+// SetRedactor sets the redactor used to mask secrets on screen; nil disables masking.
+func (r *Renderer) SetRedactor(redactor *redact.Redactor) {
+	r.redactor = redactor
+}
+
+// SetSessionBorders sets the warning border color of each root or ssh pane; nil clears them.
+func (r *Renderer) SetSessionBorders(borders map[*tab.Pane][4]float32, width float32) {
+	r.sessionBorders = borders
+	r.sessionBorderWidth = width
+}
+
+// SetPaneProfiles sets the host profile styling of each pane; nil clears them.
+func (r *Renderer) SetPaneProfiles(profiles map[*tab.Pane]PaneProfile) {
+	r.paneProfiles = profiles
+}
+
+// SetPaneStatus sets the status label shown on each pane's border; nil clears them.
+func (r *Renderer) SetPaneStatus(status map[*tab.Pane]string) {
+	r.paneStatus = status
+}
+
+// SetPaneBadges sets the badge text drawn in each pane's corner; nil clears them.
+func (r *Renderer) SetPaneBadges(badges map[*tab.Pane]string) {
+	r.paneBadges = badges
+}
+
+// SetPaneHighlights sets the highlight watches of each pane; nil clears them.
+func (r *Renderer) SetPaneHighlights(highlights map[*tab.Pane]*highlight.Set) {
+	r.paneHighlights = highlights
+}
+
+// SetPaneReplay shows g in place of pane's live screen, labelled on the pane
+// border, while the pane is being rewound; a nil pane goes back to live output.
+func (r *Renderer) SetPaneReplay(pane *tab.Pane, g *grid.Grid, label string) {
+	r.replayPane = pane
+	r.replayGrid = g
+	r.replayLabel = label
+}
+
+// SetUnreadNotifications sets the unread notification count shown in the tab bar
+func (r *Renderer) SetUnreadNotifications(count int) {
+	r.unreadNotifications = count
+}
+
+// SetTabGroupColors sets the strip color of each named tab group; groups
+// without one use the theme's accent color
+func (r *Renderer) SetTabGroupColors(colors map[string][4]float32) {
+	r.tabGroupColors = colors
+}
+
+// tabGroupColor returns the strip color of the named tab group
+func (r *Renderer) tabGroupColor(name string) [4]float32 {
+	if c, ok := r.tabGroupColors[name]; ok {
+		return c
+	}
+	return r.theme.TabActive
+}
+
+// ParseHexColor parses a "#rrggbb" or "#rrggbbaa" color.
+func ParseHexColor(value string) ([4]float32, bool) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "#")
+	if len(value) != 6 && len(value) != 8 {
+		return [4]float32{}, false
+	}
+	if len(value) == 6 {
+		value += "ff"
+	}
+	n, err := strconv.ParseUint(value, 16, 32)
+	if err != nil {
+		return [4]float32{}, false
+	}
+	return [4]float32{
+		float32(n>>24&0xff) / 255,
+		float32(n>>16&0xff) / 255,
+		float32(n>>8&0xff) / 255,
+		float32(n&0xff) / 255,
+	}, true
+}
+
+// SetPaneNumbers toggles the large pane number overlay used for quick switching.
+func (r *Renderer) SetPaneNumbers(enabled bool) {
+	r.showPaneNumbers = enabled
+}
+
+func (r *Renderer) paneRects(t *tab.Tab, width, height int) []paneRect {
+	if t == nil {
+		return nil
+	}
+	layouts := t.GetPaneLayouts()
+	if len(layouts) == 0 {
+		return nil
+	}
+
+	baseX := r.tabBarWidth + 5
+	baseY := r.paddingTop
+	availableWidth := float32(width) - r.tabBarWidth - 5
+	availableHeight := float32(height) - r.paddingTop - r.paddingBottom
+	separatorWidth := float32(2)
+
+	rects := make([]paneRect, 0, len(layouts))
+	for _, layout := range layouts {
+		// The rect covers the grid, so it starts after the gutters
+		gutter := r.gutterWidth(layout.Pane)
+		offsetX := baseX + layout.X*availableWidth + gutter
+		offsetY := baseY + layout.Y*availableHeight
+		paneWidth := layout.Width*availableWidth - gutter
+		paneHeight := layout.Height * availableHeight
+
+		if len(layouts) > 1 {
+			if layout.X > 0 {
+				offsetX += separatorWidth / 2
+				paneWidth -= separatorWidth / 2
+			}
+			if layout.X+layout.Width < 1.0 {
+				paneWidth -= separatorWidth / 2
+			}
+			if layout.Y > 0 {
+				offsetY += separatorWidth / 2
+				paneHeight -= separatorWidth / 2
+			}
+			if layout.Y+layout.Height < 1.0 {
+				paneHeight -= separatorWidth / 2
+			}
+		}
+
+		rects = append(rects, paneRect{
+			pane:   layout.Pane,
+			x:      offsetX,
+			y:      offsetY,
+			width:  paneWidth,
+			height: paneHeight,
+		})
+	}
+
+	return rects
+}
+
+// gutterWidth returns the width of a pane's line number and timestamp
+// gutters, or 0 when both are hidden.
+func (r *Renderer) gutterWidth(pane *tab.Pane) float32 {
+	if pane == nil || pane.GutterCols() == 0 {
+		return 0
+	}
+	cellW, _ := r.PaneCellSize(pane.Terminal.GetGrid())
+	return cellW * float32(pane.GutterCols())
+}
+
+// drawLineNumberGutter draws the line number of each displayed row of snap,
+// right aligned in a column at x.
+func (r *Renderer) drawLineNumberGutter(snap *grid.Snapshot, x, y, height float32, proj [16]float32) {
+	scale := snap.FontScale
+	cellH := r.cellHeight * scale
+	clr := r.theme.Foreground
+	clr[3] = 0.45
+	for row, line := range snap.Lines {
+		rowY := y + float32(row)*cellH
+		if rowY+cellH > y+height {
+			break
+		}
+		if line > 0 {
+			label := fmt.Sprintf("%*d", tab.LineNumberGutterCols-1, line)
+			r.drawTextScaled(x, rowY+cellH, label, clr, proj, scale)
+		}
+	}
+}
+
+// drawTimestampGutter draws when each displayed row of snap arrived, in a column at x.
+func (r *Renderer) drawTimestampGutter(snap *grid.Snapshot, x, y, height float32, proj [16]float32) {
+	scale := snap.FontScale
+	cellH := r.cellHeight * scale
+	clr := r.theme.Foreground
+	clr[3] = 0.45
+	for row, stamp := range snap.Stamps {
+		rowY := y + float32(row)*cellH
+		if rowY+cellH > y+height {
+			break
+		}
+		if !stamp.IsZero() {
+			r.drawTextScaled(x, rowY+cellH, stamp.Format("15:04:05"), clr, proj, scale)
+		}
+	}
+}
+
+// drawStickyPrompt draws the prompt line of the command whose output is in
+// view over the top row of a pane scrolled back, with a rule beneath it
+func (r *Renderer) drawStickyPrompt(snap *grid.Snapshot, x, y, width float32, proj [16]float32) {
+	cells := snap.Sticky
+	if cells == nil {
+		return
+	}
+	scale := snap.FontScale
+	cw, ch := r.cellWidth*scale, r.cellHeight*scale
+	r.drawRect(x, y, width, ch, r.theme.Background, proj)
+	for col, cell := range cells {
+		cx := x + float32(col)*cw
+		if cx+cw > x+width {
+			break
+		}
+		fg, bg := r.colorToRGBA(cell.Fg, false), r.colorToRGBA(cell.Bg, true)
+		if cell.Flags&grid.FlagInverse != 0 {
+			fg, bg = bg, fg
+		}
+		if bg != r.theme.Background {
+			r.drawRect(cx, y, cw+0.5, ch, bg, proj)
+		}
+		if cell.Width == grid.CellWidthContinuation || cell.Flags&grid.FlagHidden != 0 || cell.Char == ' ' || cell.Char == 0 {
+			continue
+		}
+		if !r.drawBlockElement(cx, y, cw, ch, cell.Char, fg, proj) {
+			r.drawCharScaled(cx, y+ch, cell.Char, fg, proj, scale)
+		}
+	}
+	r.drawRect(x, y+ch, width, 1, r.theme.TabActive, proj)
+}
+
+// drawPromptRow draws a pane's prompt status row right-aligned on the line
+// above the command being edited, or on the command's own line when it is the
+// top row. Nothing is drawn away from the prompt or while scrolled back.
+func (r *Renderer) drawPromptRow(term *parser.Terminal, snap *grid.Snapshot, x, y, width float32, text string, proj [16]float32) {
+	row, ok := term.PromptRow()
+	if !ok || snap.ScrollOffset != 0 {
+		return
+	}
+	if row > 0 {
+		row--
+	}
+	scale := snap.FontScale
+	cw, ch := r.cellWidth*scale, r.cellHeight*scale
+	runes := []rune(" " + text + " ")
+	if maxChars := int(width/cw) / 2; len(runes) > maxChars {
+		if maxChars < 6 {
+			return
+		}
+		runes = append(runes[:maxChars-4], '.', '.', '.', ' ')
+	}
+	boxW := float32(len(runes)) * cw
+	boxX := x + width - boxW - cw/2
+	rowY := y + float32(row)*ch
+	r.drawRect(boxX, rowY, boxW, ch, r.theme.TabBar, proj)
+	r.drawTextScaled(boxX, rowY+ch, string(runes), r.theme.Foreground, proj, scale)
+}
+
+// SetPromptRows sets the prompt status row text of each pane
+func (r *Renderer) SetPromptRows(rows map[*tab.Pane]string) {
+	r.promptRows = rows
+}
+
+// SetAliasPreview sets what the command typed in pane expands to, drawn
+// faintly after the cursor; an empty preview hides it
+func (r *Renderer) SetAliasPreview(pane *tab.Pane, preview string) {
+	r.aliasPane = pane
+	r.aliasPreview = preview
+}
+
+// drawAliasPreview draws the alias preview after the cursor of snap, in the
+// blank cells up to the next text on the cursor row
+func (r *Renderer) drawAliasPreview(snap *grid.Snapshot, x, y, width float32, proj [16]float32) {
+	if snap.ScrollOffset != 0 {
+		return
+	}
+	col, row := snap.CursorCol, snap.CursorRow
+	start := col + 2
+	end := start
+	for end < snap.Cols {
+		if cell := snap.Cell(end, row); cell.Char != 0 && cell.Char != ' ' {
+			break
+		}
+		end++
+	}
+	runes := []rune("=> " + r.aliasPreview)
+	if space := end - start - 1; len(runes) > space {
+		if space < 8 {
+			return
+		}
+		runes = append(runes[:space-3], '.', '.', '.')
+	}
+	scale := snap.FontScale
+	cw, ch := r.cellWidth*scale, r.cellHeight*scale
+	if startX := x + float32(start)*cw; startX+float32(len(runes))*cw <= x+width {
+		clr := r.theme.Foreground
+		clr[3] = 0.4
+		r.drawTextScaled(startX, y+float32(row)*ch+ch, string(runes), clr, proj, scale)
+	}
+}
+
+// SetStickyPrompt sets whether panes scrolled back pin the prompt of the
+// output in view to their top row
+func (r *Renderer) SetStickyPrompt(enabled bool) {
+	r.stickyPrompt = enabled
+}
+
+// SetWrapMarkers sets whether rows soft-wrapped onto the next row are marked
+// at their right edge
+func (r *Renderer) SetWrapMarkers(enabled bool) {
+	r.wrapMarkers = enabled
+}
+
+// drawWrapMarkers draws a faint bar after the last column of each displayed
+// row of snap whose line carries on in the row below.
+func (r *Renderer) drawWrapMarkers(snap *grid.Snapshot, x, y, width, height float32, proj [16]float32) {
+	scale := snap.FontScale
+	cellW, cellH := r.cellWidth*scale, r.cellHeight*scale
+	markX := min(x+float32(snap.Cols)*cellW, x+width-2)
+	clr := r.theme.Foreground
+	clr[3] = 0.45
+	for row, wrapped := range snap.Wrapped {
+		rowY := y + float32(row)*cellH
+		if rowY+cellH > y+height {
+			break
+		}
+		if wrapped {
+			r.drawRect(markX, rowY+cellH/2, 2, cellH/2, clr, proj)
+		}
+	}
+}
+
+// drawBookmarks marks the displayed rows of snap that have named scroll marks
+// with a bar at the left edge and the mark names small at the right.
+func (r *Renderer) drawBookmarks(snap *grid.Snapshot, x, y, width, height float32, proj [16]float32) {
+	scale := snap.FontScale
+	cellW, cellH := r.cellWidth*scale, r.cellHeight*scale
+	labelScale := scale * 0.6
+	clr := r.theme.TabActive
+	for row, names := range snap.Bookmarks {
+		rowY := y + float32(row)*cellH
+		if rowY+cellH > y+height {
+			break
+		}
+		if len(names) == 0 {
+			continue
+		}
+		r.drawRect(x, rowY, 3, cellH, clr, proj)
+		label := string(names)
+		labelX := x + width - float32(len(names))*cellW*0.6 - 4
+		r.drawTextScaled(labelX, rowY+cellH*0.8, label, clr, proj, labelScale)
+	}
+}
+
+// HitTestPane returns the pane and cell position for a screen coordinate.
+func (r *Renderer) HitTestPane(t *tab.Tab, x, y float64, width, height int) (*tab.Pane, int, int, bool) {
+	fx := float32(x)
+	fy := float32(y)
+	for _, rect := range r.paneRects(t, width, height) {
+		if fx < rect.x || fx >= rect.x+rect.width || fy < rect.y || fy >= rect.y+rect.height {
+			continue
+		}
+		g := rect.pane.Terminal.GetGrid()
+		cellW, cellH := r.PaneCellSize(g)
+		col := int((fx - rect.x) / cellW)
+		row := int((fy - rect.y) / cellH)
+		col = clampInt(col, 0, g.Cols-1)
+		row = clampInt(row, 0, g.Rows-1)
+		// Both halves of a wide character hit the character
+		return rect.pane, g.CharStart(col, row), row, true
+	}
+	return nil, 0, 0, false
+}
+
+// PaneRectFor returns the screen rect for a specific pane.
+func (r *Renderer) PaneRectFor(t *tab.Tab, pane *tab.Pane, width, height int) (float32, float32, float32, float32, bool) {
+	if pane == nil {
+		return 0, 0, 0, 0, false
+	}
+	for _, rect := range r.paneRects(t, width, height) {
+		if rect.pane == pane {
 			return rect.x, rect.y, rect.width, rect.height, true
 		}
 	}
@@ -1797,6 +2642,15 @@ func (r *Renderer) CellSize() (float32, float32) {
 	return r.cellWidth, r.cellHeight
 }
 
+// PaneCellSize returns the cell dimensions for a grid, including its font scale.
+func (r *Renderer) PaneCellSize(g *grid.Grid) (float32, float32) {
+	if g == nil {
+		return r.cellWidth, r.cellHeight
+	}
+	scale := g.FontScale()
+	return r.cellWidth * scale, r.cellHeight * scale
+}
+
 // drawPaneSeparators draws separator lines between panes
 func (r *Renderer) drawPaneSeparators(layouts []tab.PaneLayout, baseX, baseY, availableWidth, availableHeight, separatorWidth float32, proj [16]float32) {
 	// Track edges where separators should be drawn
@@ -1922,7 +2776,7 @@ func (r *Renderer) renderTabBar(tm *tab.TabManager, width, height int, proj [16]
 	r.drawRect(r.tabBarWidth-2, 0, 2, float32(height), r.theme.Foreground, proj)
 
 	// Calculate scale to render at base size regardless of zoom
-	scale := r.baseFontSize / r.fontSize
+	scale := r.uiScale()
 	cellH := r.cellHeight * scale
 
 	// Draw header
@@ -1932,209 +2786,1545 @@ func (r *Renderer) renderTabBar(tm *tab.TabManager, width, height int, proj [16]
 	// Draw tabs
 	tabs := tm.GetTabs()
 	activeIdx := tm.ActiveIndex()
+	maxChars := int((r.tabBarWidth - 12) / (r.cellWidth * scale))
+	y := cellH * 2
+	lineH := cellH * 1.2
+	// Grouped tabs get a strip in the group's color down their left edge
+	strip := func(group string) {
+		if group != "" {
+			r.drawRect(2, y-cellH*0.9, 4, lineH, r.tabGroupColor(group), proj)
+		}
+	}
+	hidden := make(map[string]int)
+	for i, t := range tabs {
+		if group := t.Group(); group != "" && i != activeIdx && tm.GroupCollapsed(group) {
+			hidden[group]++
+		}
+	}
 	for i, t := range tabs {
-		y := cellH*2 + float32(i)*cellH*1.2
+		group := t.Group()
+		// A collapsed group is one line where its first tab would be; the
+		// active tab stays visible
+		if n, ok := hidden[group]; ok && i != activeIdx {
+			if n > 0 {
+				strip(group)
+				text := fmt.Sprintf("+ %s (%d)", group, n)
+				if runes := []rune(text); maxChars > 3 && len(runes) > maxChars {
+					text = string(append(runes[:maxChars-1], '~'))
+				}
+				r.drawTextScaled(10, y, text, r.tabGroupColor(group), proj, scale)
+				y += lineH
+				hidden[group] = 0
+			}
+			continue
+		}
+
 		prefix := "  "
 		clr := r.theme.Foreground
 		if i == activeIdx {
 			prefix = "> "
 			clr = r.theme.TabActive
 		}
-		text := fmt.Sprintf("%sTab %d", prefix, t.ID())
+		name := fmt.Sprintf("Tab %d", t.ID())
+		if title := t.GetActivePane().Title(); title != "" {
+			name += ": " + title
+		}
+		text := prefix + name
+		if hostPrefix := r.paneProfiles[t.GetActivePane()].TitlePrefix; hostPrefix != "" {
+			text = fmt.Sprintf("%s%s %s", prefix, hostPrefix, name)
+		}
+		strip(group)
 		r.drawTextScaled(10, y, text, clr, proj, scale)
+		y += lineH
+
+		// Git indicator on its own line, indented under the tab name
+		if label := t.GitStatus(); label != "" {
+			runes := []rune("  " + label)
+			if maxChars > 3 && len(runes) > maxChars {
+				runes = append(runes[:maxChars-1], '~')
+			}
+			strip(group)
+			r.drawTextScaled(10, y, string(runes), r.theme.Cursor, proj, scale)
+			y += lineH
+		}
 	}
+
+	if r.unreadNotifications > 0 {
+		r.drawTextScaled(10, float32(height)-cellH*0.6, fmt.Sprintf("* %d new", r.unreadNotifications), r.theme.TabActive, proj, scale)
+	}
+}
+
+// RenderGrid draws a single grid where the panes go, with no tab bar or
+// overlays, for the benchmark
+func (r *Renderer) RenderGrid(g *grid.Grid, width, height int) {
+	proj := orthoMatrix(0, float32(width), float32(height), 0, -1, 1)
+	r.backend.Clear(width, height, r.theme.Background)
+	r.renderGrid(g, width, height, proj, true, parser.CursorStyleBlock)
 }
 
-// renderGrid renders the terminal grid (backward compatible wrapper)
-func (r *Renderer) renderGrid(g *grid.Grid, width, height int, proj [16]float32, cursorVisible bool, cursorStyle parser.CursorStyle) {
-	offsetX := r.tabBarWidth + 5
-	offsetY := r.paddingTop
-	availableWidth := float32(width) - r.tabBarWidth - 10
-	availableHeight := float32(height) - r.paddingTop - r.paddingBottom
-	r.renderGridAt(g, offsetX, offsetY, availableWidth, availableHeight, proj, cursorVisible, cursorStyle)
+// renderGrid renders the terminal grid (backward compatible wrapper)
+func (r *Renderer) renderGrid(g *grid.Grid, width, height int, proj [16]float32, cursorVisible bool, cursorStyle parser.CursorStyle) {
+	offsetX := r.tabBarWidth + 5
+	offsetY := r.paddingTop
+	availableWidth := float32(width) - r.tabBarWidth - 10
+	availableHeight := float32(height) - r.paddingTop - r.paddingBottom
+	g.CopyViewport(&r.snapshot)
+	r.renderGridAt(&r.snapshot, r.hoverGrid == g, offsetX, offsetY, availableWidth, availableHeight, proj, cursorVisible, cursorStyle, nil)
+}
+
+// renderGridAt renders a snapshot of a terminal grid at a specific position,
+// coloring matches of highlights when it is not nil. hovered says whether
+// the grid is the one whose hovered URL is underlined.
+func (r *Renderer) renderGridAt(snap *grid.Snapshot, hovered bool, offsetX, offsetY, paneWidth, paneHeight float32, proj [16]float32, cursorVisible bool, cursorStyle parser.CursorStyle, highlights *highlight.Set) {
+	cols := snap.Cols
+	rows := snap.Rows
+	scale := snap.FontScale
+	cw, ch := r.cellWidth*scale, r.cellHeight*scale
+	art := snap.ArtMode
+
+	// Render cells
+	var line []rune
+	for row := 0; row < rows; row++ {
+		var mask []bool
+		var marks []int
+		if r.redactor != nil || highlights.Len() > 0 {
+			line = line[:0]
+			for col := 0; col < cols; col++ {
+				line = append(line, snap.Cell(col, row).Char)
+			}
+			mask = r.redactor.Mask(line)
+			marks = highlights.Mask(line)
+		}
+		for col := 0; col < cols; col++ {
+			cell := snap.Cell(col, row)
+			x := offsetX + float32(col)*cw
+			y := offsetY + float32(row)*ch
+
+			// Skip if outside pane bounds
+			if x+cw > offsetX+paneWidth || y+ch > offsetY+paneHeight {
+				continue
+			}
+
+			// +0.5 horizontal overlap eliminates sub-pixel gaps between adjacent
+			// cells; art mode instead puts every edge on a whole pixel so
+			// neighboring cells meet exactly in both directions
+			fillX, fillY, fillW, fillH := x, y, cw+0.5, ch
+			if art {
+				fillX, fillY, fillW, fillH = snapRect(x, y, cw, ch)
+			}
+
+			// Draw background if not default
+			bgColor := r.colorToRGBA(cell.Bg, true)
+			if cell.Flags&grid.FlagInverse != 0 {
+				bgColor, _ = r.colorToRGBA(cell.Fg, false), r.colorToRGBA(cell.Bg, true)
+			}
+			if bgColor != r.theme.Background {
+				r.drawRect(fillX, fillY, fillW, fillH, bgColor, proj)
+			}
+
+			// Matches of the pane's highlight watches
+			if marks != nil && marks[col] > 0 {
+				r.drawRect(fillX, fillY, fillW, fillH, highlight.Colors[(marks[col]-1)%len(highlight.Colors)], proj)
+			}
+
+			// Draw selection highlight
+			if snap.IsSelected(col, row) {
+				r.drawRect(fillX, fillY, fillW, fillH, r.theme.Selection, proj)
+			}
+
+			// Skip character and underline rendering for continuation cells (second half of wide char)
+			if cell.Width == grid.CellWidthContinuation {
+				continue
+			}
+
+			// Draw character
+			fgColor := r.colorToRGBA(cell.Fg, false)
+			if cell.Flags&grid.FlagInverse != 0 {
+				fgColor = r.colorToRGBA(cell.Bg, true)
+			}
+			// Redacted cells are drawn as a solid bar instead of the character
+			if mask != nil && mask[col] {
+				r.drawRect(x, y+ch*0.3, cw+0.5, ch*0.45, fgColor, proj)
+				continue
+			}
+			// Apply dim effect (reduce alpha to 50%)
+			if cell.Flags&grid.FlagDim != 0 {
+				fgColor[3] = fgColor[3] / 2
+			}
+			hidden := cell.Flags&grid.FlagHidden != 0
+			if !hidden && cell.Char != ' ' && cell.Char != 0 {
+				blockX, blockY, blockW, blockH := x, y, cw, ch
+				if art {
+					blockX, blockY, blockW, blockH = fillX, fillY, fillW, fillH
+				}
+				if !r.drawBlockElement(blockX, blockY, blockW, blockH, cell.Char, fgColor, proj) {
+					r.drawCharScaled(x, y+ch, cell.Char, fgColor, proj, scale)
+				}
+			}
+
+			// Draw underline for ANSI styling or hovered URL
+			drawUnderline := cell.Flags&grid.FlagUnderline != 0
+			if r.hoverActive && hovered && row == r.hoverRow && col >= r.hoverStartCol && col <= r.hoverEndCol {
+				drawUnderline = true
+			}
+			if drawUnderline && !hidden {
+				underlineY := y + ch - 1
+				r.drawRect(x, underlineY, cw, 1, fgColor, proj)
+			}
+			if cell.Flags&grid.FlagStrikethrough != 0 && !hidden {
+				strikeY := y + ch/2
+				r.drawRect(x, strikeY, cw, 1, fgColor, proj)
+			}
+		}
+	}
+
+	r.drawWidgets(snap, offsetX, offsetY, paneWidth, paneHeight, cw, ch, proj)
+
+	// Draw cursor
+	if cursorVisible && snap.ScrollOffset == 0 {
+		cursorCol, cursorRow := snap.CursorCol, snap.CursorRow
+		cursorX := offsetX + float32(cursorCol)*cw
+		cursorY := offsetY + float32(cursorRow)*ch
+
+		// Only draw cursor if within pane bounds
+		if cursorX+cw <= offsetX+paneWidth && cursorY+ch <= offsetY+paneHeight {
+			cell := snap.Cell(cursorCol, cursorRow)
+			switch cursorStyle {
+			case parser.CursorStyleUnderline:
+				h := ch / 6
+				if r.cursorThickness > 0 {
+					h = min32(r.cursorThickness, ch)
+				}
+				if h < 1 {
+					h = 1
+				}
+				r.drawRect(cursorX, cursorY+ch-h, cw, h, r.theme.Cursor, proj)
+			case parser.CursorStyleBar:
+				w := cw / 6
+				if r.cursorThickness > 0 {
+					w = min32(r.cursorThickness, cw)
+				}
+				if w < 1 {
+					w = 1
+				}
+				r.drawRect(cursorX, cursorY, w, ch, r.theme.Cursor, proj)
+			default:
+				r.drawRect(cursorX, cursorY, cw, ch, r.theme.Cursor, proj)
+				// Redraw character under cursor in inverse
+				if cell.Char != ' ' && cell.Char != 0 && cell.Flags&grid.FlagHidden == 0 {
+					if !r.drawBlockElement(cursorX, cursorY, cw, ch, cell.Char, r.theme.Background, proj) {
+						r.drawCharScaled(cursorX, cursorY+ch, cell.Char, r.theme.Background, proj, scale)
+					}
+				}
+			}
+		}
+	}
+}
+
+// drawWidgets draws the inline sparkline and progress widgets placed with OSC 1338
+func (r *Renderer) drawWidgets(snap *grid.Snapshot, offsetX, offsetY, paneWidth, paneHeight, cw, ch float32, proj [16]float32) {
+	for _, w := range snap.Widgets {
+		x := offsetX + float32(w.Col)*cw
+		y := offsetY + float32(w.Row)*ch
+		width := float32(w.Width) * cw
+		if x+width > offsetX+paneWidth || y+ch > offsetY+paneHeight {
+			continue
+		}
+		pad := max32(1, ch*0.1)
+		height := ch - 2*pad
+		track := r.theme.Foreground
+		track[3] = 0.15
+
+		switch w.Kind {
+		case grid.WidgetProgress:
+			r.drawRect(x, y+pad, width, height, track, proj)
+			r.drawRect(x, y+pad, width*float32(w.Values[0]/100), height, r.theme.TabActive, proj)
+		case grid.WidgetSparkline:
+			// Each sample gets an equal slice; samples beyond one per pixel are dropped from the front
+			values := w.Values
+			if n := int(width); len(values) > n {
+				values = values[len(values)-n:]
+			}
+			low, high := values[0], values[0]
+			for _, v := range values {
+				low = min(low, v)
+				high = max(high, v)
+			}
+			bar := width / float32(len(values))
+			for i, v := range values {
+				level := float32(1)
+				if high > low {
+					level = float32((v - low) / (high - low))
+				}
+				// A minimum height keeps the lowest samples visible
+				h := max32(1, height*(0.1+0.9*level))
+				r.drawRect(x+float32(i)*bar, y+pad+height-h, max32(1, bar-0.5), h, r.theme.TabActive, proj)
+			}
+		}
+	}
+}
+
+// SetHoverURL sets the hover underline range for a grid.
+func (r *Renderer) SetHoverURL(g *grid.Grid, row, startCol, endCol int) {
+	if g == nil || row < 0 || startCol < 0 || endCol < startCol {
+		r.ClearHoverURL()
+		return
+	}
+	r.hoverGrid = g
+	r.hoverRow = row
+	r.hoverStartCol = startCol
+	r.hoverEndCol = endCol
+	r.hoverActive = true
+}
+
+// ClearHoverURL clears any active hover underline.
+func (r *Renderer) ClearHoverURL() {
+	r.hoverGrid = nil
+	r.hoverActive = false
+}
+
+// DrawToast renders a small notification overlay.
+func (r *Renderer) DrawToast(message string, width, height int) {
+	cellW, cellH := r.UICellDimensions()
+	if strings.TrimSpace(message) == "" {
+		return
+	}
+
+	proj := orthoMatrix(0, float32(width), float32(height), 0, -1, 1)
+
+	paddingX := cellW * 0.8
+	paddingY := cellH * 0.35
+	runes := []rune(message)
+	textWidth := float32(len(runes)) * cellW
+	boxW := textWidth + paddingX*2
+	boxH := cellH + paddingY*2
+	margin := cellW * 0.8
+
+	maxWidth := float32(width) - margin*2
+	if boxW > maxWidth {
+		maxChars := int((maxWidth - paddingX*2) / cellW)
+		if maxChars > 3 {
+			message = string(runes[:maxChars-3]) + "..."
+			runes = []rune(message)
+			textWidth = float32(len(runes)) * cellW
+			boxW = textWidth + paddingX*2
+		} else {
+			return
+		}
+	}
+
+	x := float32(width) - boxW - margin
+	y := float32(height) - boxH - margin
+	bg := r.theme.TabBar
+	bg[3] = 0.85
+
+	r.drawRect(x, y, boxW, boxH, bg, proj)
+	r.drawUIText(x+paddingX, y+boxH-paddingY, message, r.theme.Foreground, proj)
+}
+
+// DrawChordHint draws the pending chord indicator in the bottom left corner:
+// the leader that was pressed and the keys that can follow it.
+func (r *Renderer) DrawChordHint(leader, hint string, width, height int) {
+	cellW, cellH := r.UICellDimensions()
+	proj := orthoMatrix(0, float32(width), float32(height), 0, -1, 1)
+
+	paddingX := cellW * 0.8
+	paddingY := cellH * 0.35
+	margin := cellW * 0.8
+	label := leader + " ..."
+	text := label
+	if hint != "" {
+		text += "  " + hint
+	}
+	maxChars := int((float32(width)/2 - margin - paddingX*2) / cellW)
+	if maxChars < len([]rune(label)) {
+		return
+	}
+	if runes := []rune(text); len(runes) > maxChars {
+		text = string(runes[:maxChars-3]) + "..."
+	}
+
+	boxW := float32(len([]rune(text)))*cellW + paddingX*2
+	boxH := cellH + paddingY*2
+	x := r.tabBarWidth + margin
+	y := float32(height) - boxH - margin
+	bg := r.theme.TabBar
+	bg[3] = 0.9
+	r.drawRect(x, y, boxW, boxH, bg, proj)
+	r.drawRect(x, y+boxH-2, boxW, 2, r.theme.TabActive, proj)
+	r.drawUIText(x+paddingX, y+boxH-paddingY, text, r.theme.Foreground, proj)
+	r.drawUIText(x+paddingX, y+boxH-paddingY, label, r.theme.TabActive, proj)
+}
+
+// TabSwitcherEntry is a tab as the recent tabs switcher lists it
+type TabSwitcherEntry struct {
+	Title   string // e.g. "Tab 3"
+	Detail  string // Foreground program, directory and git status
+	Preview string // Last line of text on the tab's focused pane
+}
+
+// DrawTabSwitcher renders the recent tabs switcher in the middle of the
+// window, most recently used first, with the selected entry highlighted
+func (r *Renderer) DrawTabSwitcher(entries []TabSwitcherEntry, selected, width, height int) {
+	if len(entries) == 0 {
+		return
+	}
+	cellW, cellH := r.UICellDimensions()
+	proj := orthoMatrix(0, float32(width), float32(height), 0, -1, 1)
+
+	panelBg := [4]float32{0.05, 0.06, 0.08, 0.95}
+	borderColor := r.theme.TabActive
+	borderWidth := float32(2)
+	dimColor := [4]float32{0.6, 0.6, 0.6, 1.0}
+	highlightColor := [4]float32{0.12, 0.14, 0.22, 1.0}
+
+	lineH := cellH * 1.3
+	entryH := lineH * 2
+	padding := cellW
+	panelW := float32(width) * 0.6
+	if maxW := cellW*72 + padding*2; panelW > maxW {
+		panelW = maxW
+	}
+	visible := int((float32(height)*0.8 - lineH*3 - padding*2) / entryH)
+	if visible < 1 || panelW < cellW*20 {
+		return
+	}
+	visible = min(visible, len(entries))
+	panelH := entryH*float32(visible) + lineH*3 + padding*2
+	x := (float32(width) - panelW) / 2
+	y := (float32(height) - panelH) / 2
+
+	r.drawRect(x, y, panelW, panelH, panelBg, proj)
+	r.drawRect(x, y, panelW, borderWidth, borderColor, proj)
+	r.drawRect(x, y+panelH-borderWidth, panelW, borderWidth, borderColor, proj)
+	r.drawRect(x, y, borderWidth, panelH, borderColor, proj)
+	r.drawRect(x+panelW-borderWidth, y, borderWidth, panelH, borderColor, proj)
+
+	contentX := x + padding
+	contentW := panelW - padding*2
+	maxChars := int(contentW / cellW)
+	clip := func(text string) string {
+		if runes := []rune(text); len(runes) > maxChars {
+			return string(runes[:maxChars-3]) + "..."
+		}
+		return text
+	}
+
+	r.drawUIText(contentX, y+padding+lineH*0.8, "Recent Tabs", r.theme.TabActive, proj)
+
+	start := 0
+	if selected >= visible {
+		start = selected - visible + 1
+	}
+	listY := y + padding + lineH*1.5
+	for i := start; i < start+visible; i++ {
+		entry := entries[i]
+		top := listY + float32(i-start)*entryH
+		if i == selected {
+			r.drawRect(contentX-padding/2, top, contentW+padding, entryH, highlightColor, proj)
+		}
+		title := entry.Title
+		titleColor := r.theme.Foreground
+		if i == selected {
+			titleColor = r.theme.TabActive
+		}
+		r.drawUIText(contentX, top+lineH*0.8, clip(title), titleColor, proj)
+		if entry.Detail != "" && len([]rune(title))+2 < maxChars {
+			detailX := contentX + float32(len([]rune(title))+2)*cellW
+			detail := []rune(entry.Detail)
+			if room := maxChars - len([]rune(title)) - 2; len(detail) > room {
+				detail = append(detail[:max(room-3, 0)], []rune("...")...)
+			}
+			r.drawUIText(detailX, top+lineH*0.8, string(detail), dimColor, proj)
+		}
+		r.drawUIText(contentX, top+lineH*1.8, clip("    "+entry.Preview), dimColor, proj)
+	}
+
+	footer := "Tab / Shift+Tab: move | release Ctrl: switch | Esc: cancel"
+	r.drawUIText(contentX, y+panelH-padding-lineH*0.2, clip(footer), dimColor, proj)
+}
+
+// DrawProcessPanel renders the process monitor overlay.
+func (r *Renderer) DrawProcessPanel(panel *procpanel.Panel, width, height int) {
+	cellW, cellH := r.UICellDimensions()
+	if panel == nil || !panel.Open {
+		return
+	}
+
+	proj := orthoMatrix(0, float32(width), float32(height), 0, -1, 1)
+	layout := panel.Layout(width, height, cellW, cellH)
+
+	panelBg := [4]float32{0.05, 0.06, 0.08, 0.95}
+	borderColor := r.theme.TabActive
+	borderWidth := float32(2)
+	dimColor := [4]float32{0.6, 0.6, 0.6, 1.0}
+
+	r.drawRect(layout.PanelX, layout.PanelY, layout.PanelWidth, layout.PanelHeight, panelBg, proj)
+	r.drawRect(layout.PanelX, layout.PanelY, layout.PanelWidth, borderWidth, borderColor, proj)
+	r.drawRect(layout.PanelX, layout.PanelY+layout.PanelHeight-borderWidth, layout.PanelWidth, borderWidth, borderColor, proj)
+	r.drawRect(layout.PanelX, layout.PanelY, borderWidth, layout.PanelHeight, borderColor, proj)
+	r.drawRect(layout.PanelX+layout.PanelWidth-borderWidth, layout.PanelY, borderWidth, layout.PanelHeight, borderColor, proj)
+
+	maxChars := int(layout.ContentWidth/cellW) - 2
+	if maxChars < 10 {
+		maxChars = 10
+	}
+
+	r.drawUIText(layout.ContentX, layout.HeaderY, "Processes", r.theme.TabActive, proj)
+
+	columns := fmt.Sprintf("%-5s %7s %6s %7s  %s", "PANE", "PID", "CPU%", "MEM", "COMMAND")
+	if panel.Status != "" {
+		columns = panel.Status
+	}
+	if len(columns) > maxChars {
+		columns = columns[:maxChars-3] + "..."
+	}
+	r.drawUIText(layout.ContentX, layout.StatusY, columns, r.theme.Cursor, proj)
+
+	if len(panel.Entries) == 0 {
+		r.drawUIText(layout.ContentX, layout.ListStart, "No processes.", dimColor, proj)
+	}
+
+	for i := panel.Scroll; i < len(panel.Entries) && i < panel.Scroll+layout.VisibleLines; i++ {
+		entry := panel.Entries[i]
+		proc := entry.Process
+		drawY := layout.ListStart + float32(i-panel.Scroll)*layout.LineHeight
+
+		if i == panel.Selected {
+			highlightColor := [4]float32{0.12, 0.14, 0.22, 1.0}
+			r.drawRect(layout.ContentX, drawY-layout.LineHeight+6, layout.ContentWidth, layout.LineHeight, highlightColor, proj)
+		}
+
+		command := proc.Command
+		if command == "" {
+			command = proc.Name
+		}
+		label := fmt.Sprintf("%d.%d", entry.TabIndex+1, entry.PaneIndex+1)
+		line := fmt.Sprintf("%-5s %7d %6.1f %7s  %s%s", label, proc.PID, proc.CPUPercent,
+			procmon.FormatBytes(proc.RSSBytes), strings.Repeat("  ", proc.Depth), command)
+		if runes := []rune(line); len(runes) > maxChars {
+			line = string(runes[:maxChars-3]) + "..."
+		}
+
+		clr := r.theme.Foreground
+		if proc.Depth == 0 {
+			clr = dimColor
+		}
+		r.drawUIText(layout.ContentX, drawY, line, clr, proj)
+	}
+
+	footerText := "Up/Down: select | I: SIGINT | Shift+K: SIGKILL | Esc: close"
+	if len(footerText) > maxChars {
+		footerText = footerText[:maxChars-3] + "..."
+	}
+	r.drawUIText(layout.ContentX, layout.FooterY, footerText, dimColor, proj)
+}
+
+// DrawCleanupPanel renders the dialog listing exited and idle panes.
+func (r *Renderer) DrawCleanupPanel(panel *cleanup.Panel, width, height int) {
+	cellW, cellH := r.UICellDimensions()
+	if panel == nil || !panel.Open {
+		return
+	}
+
+	proj := orthoMatrix(0, float32(width), float32(height), 0, -1, 1)
+	layout := panel.Layout(width, height, cellW, cellH)
+
+	panelBg := [4]float32{0.05, 0.06, 0.08, 0.95}
+	borderColor := r.theme.TabActive
+	borderWidth := float32(2)
+	dimColor := [4]float32{0.6, 0.6, 0.6, 1.0}
+
+	r.drawRect(layout.PanelX, layout.PanelY, layout.PanelWidth, layout.PanelHeight, panelBg, proj)
+	r.drawRect(layout.PanelX, layout.PanelY, layout.PanelWidth, borderWidth, borderColor, proj)
+	r.drawRect(layout.PanelX, layout.PanelY+layout.PanelHeight-borderWidth, layout.PanelWidth, borderWidth, borderColor, proj)
+	r.drawRect(layout.PanelX, layout.PanelY, borderWidth, layout.PanelHeight, borderColor, proj)
+	r.drawRect(layout.PanelX+layout.PanelWidth-borderWidth, layout.PanelY, borderWidth, layout.PanelHeight, borderColor, proj)
+
+	maxChars := int(layout.ContentWidth/cellW) - 2
+	if maxChars < 10 {
+		maxChars = 10
+	}
+
+	r.drawUIText(layout.ContentX, layout.HeaderY, "Pane Cleanup", r.theme.TabActive, proj)
+
+	status := fmt.Sprintf("%d exited or idle panes", len(panel.Entries))
+	if panel.Status != "" {
+		status = panel.Status
+	}
+	if len(status) > maxChars {
+		status = status[:maxChars-3] + "..."
+	}
+	r.drawUIText(layout.ContentX, layout.StatusY, status, r.theme.Cursor, proj)
+
+	if len(panel.Entries) == 0 {
+		r.drawUIText(layout.ContentX, layout.ListStart, "Nothing to clean up.", dimColor, proj)
+	}
+
+	for i := panel.Scroll; i < len(panel.Entries) && i < panel.Scroll+layout.VisibleLines; i++ {
+		entry := panel.Entries[i]
+		drawY := layout.ListStart + float32(i-panel.Scroll)*layout.LineHeight
+
+		if i == panel.Selected {
+			highlightColor := [4]float32{0.12, 0.14, 0.22, 1.0}
+			r.drawRect(layout.ContentX, drawY-layout.LineHeight+6, layout.ContentWidth, layout.LineHeight, highlightColor, proj)
+		}
+
+		state := "exited"
+		if !entry.Exited {
+			state = "idle " + strings.TrimSuffix(entry.Idle.Truncate(time.Minute).String(), "0s")
+		}
+		line := fmt.Sprintf("%d.%d  %-16s %s", entry.TabIndex+1, entry.PaneIndex+1, state, entry.Dir)
+		if runes := []rune(line); len(runes) > maxChars {
+			line = string(runes[:maxChars-3]) + "..."
+		}
+		r.drawUIText(layout.ContentX, drawY, line, r.theme.Foreground, proj)
+	}
+
+	footerText := "Up/Down: select | Enter: close pane | A: close all | Esc: cancel"
+	if len(footerText) > maxChars {
+		footerText = footerText[:maxChars-3] + "..."
+	}
+	r.drawUIText(layout.ContentX, layout.FooterY, footerText, dimColor, proj)
+}
+
+// DrawInspectorPanel renders the decoded output of the inspected pane.
+func (r *Renderer) DrawInspectorPanel(panel *inspector.Panel, width, height int) {
+	cellW, cellH := r.UICellDimensions()
+	if panel == nil || !panel.Open {
+		return
+	}
+
+	proj := orthoMatrix(0, float32(width), float32(height), 0, -1, 1)
+	layout := panel.Layout(width, height, cellW, cellH)
+
+	panelBg := [4]float32{0.05, 0.06, 0.08, 0.95}
+	borderColor := r.theme.TabActive
+	borderWidth := float32(2)
+	dimColor := [4]float32{0.6, 0.6, 0.6, 1.0}
+
+	r.drawRect(layout.PanelX, layout.PanelY, layout.PanelWidth, layout.PanelHeight, panelBg, proj)
+	r.drawRect(layout.PanelX, layout.PanelY, layout.PanelWidth, borderWidth, borderColor, proj)
+	r.drawRect(layout.PanelX, layout.PanelY+layout.PanelHeight-borderWidth, layout.PanelWidth, borderWidth, borderColor, proj)
+	r.drawRect(layout.PanelX, layout.PanelY, borderWidth, layout.PanelHeight, borderColor, proj)
+	r.drawRect(layout.PanelX+layout.PanelWidth-borderWidth, layout.PanelY, borderWidth, layout.PanelHeight, borderColor, proj)
+
+	maxChars := int(layout.ContentWidth/cellW) - 2
+	if maxChars < 10 {
+		maxChars = 10
+	}
+
+	r.drawUIText(layout.ContentX, layout.HeaderY, "Escape Sequence Inspector", r.theme.TabActive, proj)
+
+	inputBoxColor := [4]float32{0.03, 0.03, 0.05, 1.0}
+	r.drawRect(layout.ContentX, layout.InputBoxY, layout.ContentWidth, layout.LineHeight, inputBoxColor, proj)
+	inputText := panel.Filter
+	if len(inputText) > maxChars {
+		inputText = "..." + inputText[len(inputText)-maxChars+3:]
+	}
+	r.drawUIText(layout.ContentX+8, layout.InputBoxY+layout.LineHeight*0.75, inputText+"_", r.theme.TabActive, proj)
+
+	status := fmt.Sprintf("%s: %d events", panel.Source, len(panel.Entries))
+	if panel.Paused {
+		status += " (paused)"
+	}
+	if panel.Status != "" {
+		status += " | " + panel.Status
+	}
+	if len(status) > maxChars {
+		status = status[:maxChars-3] + "..."
+	}
+	r.drawUIText(layout.ContentX, layout.StatusY, status, r.theme.Cursor, proj)
+
+	if len(panel.Entries) == 0 {
+		r.drawUIText(layout.ContentX, layout.ListStart, "Waiting for output.", dimColor, proj)
+	}
+
+	for i := panel.Scroll; i < len(panel.Entries) && i < panel.Scroll+layout.VisibleLines; i++ {
+		entry := panel.Entries[i]
+		drawY := layout.ListStart + float32(i-panel.Scroll)*layout.LineHeight
+
+		if i == panel.Selected {
+			highlightColor := [4]float32{0.12, 0.14, 0.22, 1.0}
+			r.drawRect(layout.ContentX, drawY-layout.LineHeight+6, layout.ContentWidth, layout.LineHeight, highlightColor, proj)
+		}
+
+		line := entry.String()
+		if runes := []rune(line); len(runes) > maxChars {
+			line = string(runes[:maxChars-3]) + "..."
+		}
+		clr := r.theme.Foreground
+		if entry.Kind == inspector.KindText || entry.Kind == inspector.KindControl {
+			clr = dimColor
+		}
+		r.drawUIText(layout.ContentX, drawY, line, clr, proj)
+	}
+
+	footerText := "Type to filter | Ctrl+P: pause | Ctrl+C: copy | Ctrl+A: copy all | Ctrl+L: clear | Esc: close"
+	if len(footerText) > maxChars {
+		footerText = footerText[:maxChars-3] + "..."
+	}
+	r.drawUIText(layout.ContentX, layout.FooterY, footerText, dimColor, proj)
+}
+
+// DrawDevServerPanel renders the list of detected dev-server URLs.
+func (r *Renderer) DrawDevServerPanel(panel *devserver.Panel, width, height int) {
+	cellW, cellH := r.UICellDimensions()
+	if panel == nil || !panel.Open {
+		return
+	}
+
+	proj := orthoMatrix(0, float32(width), float32(height), 0, -1, 1)
+	layout := panel.Layout(width, height, cellW, cellH)
+
+	panelBg := [4]float32{0.05, 0.06, 0.08, 0.95}
+	borderColor := r.theme.TabActive
+	borderWidth := float32(2)
+	dimColor := [4]float32{0.6, 0.6, 0.6, 1.0}
+
+	r.drawRect(layout.PanelX, layout.PanelY, layout.PanelWidth, layout.PanelHeight, panelBg, proj)
+	r.drawRect(layout.PanelX, layout.PanelY, layout.PanelWidth, borderWidth, borderColor, proj)
+	r.drawRect(layout.PanelX, layout.PanelY+layout.PanelHeight-borderWidth, layout.PanelWidth, borderWidth, borderColor, proj)
+	r.drawRect(layout.PanelX, layout.PanelY, borderWidth, layout.PanelHeight, borderColor, proj)
+	r.drawRect(layout.PanelX+layout.PanelWidth-borderWidth, layout.PanelY, borderWidth, layout.PanelHeight, borderColor, proj)
+
+	maxChars := int(layout.ContentWidth/cellW) - 2
+	if maxChars < 10 {
+		maxChars = 10
+	}
+
+	r.drawUIText(layout.ContentX, layout.HeaderY, "Dev Servers", r.theme.TabActive, proj)
+
+	if len(panel.Entries) == 0 {
+		r.drawUIText(layout.ContentX, layout.ListStart, "No dev-server URLs detected.", dimColor, proj)
+	}
+
+	for i := panel.Scroll; i < len(panel.Entries) && i < panel.Scroll+layout.VisibleLines; i++ {
+		entry := panel.Entries[i]
+		drawY := layout.ListStart + float32(i-panel.Scroll)*layout.LineHeight
+
+		if i == panel.Selected {
+			highlightColor := [4]float32{0.12, 0.14, 0.22, 1.0}
+			r.drawRect(layout.ContentX, drawY-layout.LineHeight+6, layout.ContentWidth, layout.LineHeight, highlightColor, proj)
+		}
+
+		label := fmt.Sprintf("%d.%d  ", entry.TabIndex+1, entry.PaneIndex+1)
+		r.drawUIText(layout.ContentX, drawY, label, dimColor, proj)
+
+		url := entry.URL
+		room := maxChars - len(label)
+		if room > 3 && len(url) > room {
+			url = url[:room-3] + "..."
+		}
+		r.drawUIText(layout.ContentX+float32(len(label))*cellW, drawY, url, r.theme.Foreground, proj)
+	}
+
+	footerText := "Up/Down: select | Enter: open | Esc: close"
+	if len(footerText) > maxChars {
+		footerText = footerText[:maxChars-3] + "..."
+	}
+	r.drawUIText(layout.ContentX, layout.FooterY, footerText, dimColor, proj)
+}
+
+// DrawNotificationPanel renders the notification center overlay.
+func (r *Renderer) DrawNotificationPanel(panel *notifications.Panel, width, height int) {
+	cellW, cellH := r.UICellDimensions()
+	if panel == nil || !panel.Open {
+		return
+	}
+
+	proj := orthoMatrix(0, float32(width), float32(height), 0, -1, 1)
+	layout := panel.Layout(width, height, cellW, cellH)
+
+	panelBg := [4]float32{0.05, 0.06, 0.08, 0.95}
+	borderColor := r.theme.TabActive
+	borderWidth := float32(2)
+	dimColor := [4]float32{0.6, 0.6, 0.6, 1.0}
+
+	r.drawRect(layout.PanelX, layout.PanelY, layout.PanelWidth, layout.PanelHeight, panelBg, proj)
+	r.drawRect(layout.PanelX, layout.PanelY, layout.PanelWidth, borderWidth, borderColor, proj)
+	r.drawRect(layout.PanelX, layout.PanelY+layout.PanelHeight-borderWidth, layout.PanelWidth, borderWidth, borderColor, proj)
+	r.drawRect(layout.PanelX, layout.PanelY, borderWidth, layout.PanelHeight, borderColor, proj)
+	r.drawRect(layout.PanelX+layout.PanelWidth-borderWidth, layout.PanelY, borderWidth, layout.PanelHeight, borderColor, proj)
+
+	maxChars := int(layout.ContentWidth/cellW) - 2
+	if maxChars < 10 {
+		maxChars = 10
+	}
+
+	r.drawUIText(layout.ContentX, layout.HeaderY, "Notifications", r.theme.TabActive, proj)
+
+	if len(panel.Entries) == 0 {
+		r.drawUIText(layout.ContentX, layout.ListStart, "No notifications.", dimColor, proj)
+	}
+
+	for i := panel.Scroll; i < len(panel.Entries) && i < panel.Scroll+layout.VisibleLines; i++ {
+		entry := panel.Entries[i]
+		drawY := layout.ListStart + float32(i-panel.Scroll)*layout.LineHeight
+
+		if i == panel.Selected {
+			highlightColor := [4]float32{0.12, 0.14, 0.22, 1.0}
+			r.drawRect(layout.ContentX, drawY-layout.LineHeight+6, layout.ContentWidth, layout.LineHeight, highlightColor, proj)
+		}
+
+		label := fmt.Sprintf("%s %-4s %-4s ", entry.Time.Format("15:04:05"), entry.Source, entry.Kind)
+		r.drawUIText(layout.ContentX, drawY, label, dimColor, proj)
+
+		message := entry.Message
+		if entry.Count > 1 {
+			message = fmt.Sprintf("%s (x%d)", message, entry.Count)
+		}
+		room := maxChars - len(label)
+		if runes := []rune(message); room > 3 && len(runes) > room {
+			message = string(runes[:room-3]) + "..."
+		}
+		r.drawUIText(layout.ContentX+float32(len(label))*cellW, drawY, message, r.theme.Foreground, proj)
+	}
+
+	footerText := "Up/Down: select | Enter: jump | Del: clear all | Esc: close"
+	if len(footerText) > maxChars {
+		footerText = footerText[:maxChars-3] + "..."
+	}
+	r.drawUIText(layout.ContentX, layout.FooterY, footerText, dimColor, proj)
+}
+
+// DrawDirJumpPanel renders the frecency-ranked directory picker.
+func (r *Renderer) DrawDirJumpPanel(panel *dirjump.Panel, width, height int) {
+	cellW, cellH := r.UICellDimensions()
+	if panel == nil || !panel.Open {
+		return
+	}
+
+	proj := orthoMatrix(0, float32(width), float32(height), 0, -1, 1)
+	layout := panel.Layout(width, height, cellW, cellH)
+
+	panelBg := [4]float32{0.05, 0.06, 0.08, 0.95}
+	borderColor := r.theme.TabActive
+	borderWidth := float32(2)
+	dimColor := [4]float32{0.6, 0.6, 0.6, 1.0}
+
+	r.drawRect(layout.PanelX, layout.PanelY, layout.PanelWidth, layout.PanelHeight, panelBg, proj)
+	r.drawRect(layout.PanelX, layout.PanelY, layout.PanelWidth, borderWidth, borderColor, proj)
+	r.drawRect(layout.PanelX, layout.PanelY+layout.PanelHeight-borderWidth, layout.PanelWidth, borderWidth, borderColor, proj)
+	r.drawRect(layout.PanelX, layout.PanelY, borderWidth, layout.PanelHeight, borderColor, proj)
+	r.drawRect(layout.PanelX+layout.PanelWidth-borderWidth, layout.PanelY, borderWidth, layout.PanelHeight, borderColor, proj)
+
+	maxChars := int(layout.ContentWidth/cellW) - 2
+	if maxChars < 10 {
+		maxChars = 10
+	}
+
+	r.drawUIText(layout.ContentX, layout.HeaderY, "Jump to Directory", r.theme.TabActive, proj)
+
+	inputBoxColor := [4]float32{0.03, 0.03, 0.05, 1.0}
+	r.drawRect(layout.ContentX, layout.InputBoxY, layout.ContentWidth, layout.LineHeight, inputBoxColor, proj)
+	inputText := panel.Query
+	if len(inputText) > maxChars {
+		inputText = "..." + inputText[len(inputText)-maxChars+3:]
+	}
+	r.drawUIText(layout.ContentX+8, layout.InputBoxY+layout.LineHeight*0.75, inputText+"_", r.theme.TabActive, proj)
+
+	if len(panel.Results) == 0 {
+		r.drawUIText(layout.ContentX, layout.ListStart, "No matching directories.", dimColor, proj)
+	}
+
+	home, _ := os.UserHomeDir()
+	for i := panel.Scroll; i < len(panel.Results) && i < panel.Scroll+layout.VisibleLines; i++ {
+		entry := panel.Results[i]
+		drawY := layout.ListStart + float32(i-panel.Scroll)*layout.LineHeight
+
+		if i == panel.Selected {
+			highlightColor := [4]float32{0.12, 0.14, 0.22, 1.0}
+			r.drawRect(layout.ContentX, drawY-layout.LineHeight+6, layout.ContentWidth, layout.LineHeight, highlightColor, proj)
+		}
+
+		path := entry.Path
+		if home != "" && (path == home || strings.HasPrefix(path, home+"/")) {
+			path = "~" + strings.TrimPrefix(path, home)
+		}
+		if len(path) > maxChars {
+			path = "..." + path[len(path)-maxChars+3:]
+		}
+		r.drawUIText(layout.ContentX, drawY, path, r.theme.Foreground, proj)
+	}
+
+	footerText := "Type to filter | Enter: cd | Del: forget | Esc: close"
+	if len(footerText) > maxChars {
+		footerText = footerText[:maxChars-3] + "..."
+	}
+	r.drawUIText(layout.ContentX, layout.FooterY, footerText, dimColor, proj)
+}
+
+// DrawRegisterPanel renders the register viewer with a one-line preview of each register.
+func (r *Renderer) DrawRegisterPanel(panel *registers.Panel, width, height int) {
+	cellW, cellH := r.UICellDimensions()
+	if panel == nil || !panel.Open {
+		return
+	}
+
+	proj := orthoMatrix(0, float32(width), float32(height), 0, -1, 1)
+	layout := panel.Layout(width, height, cellW, cellH)
+
+	panelBg := [4]float32{0.05, 0.06, 0.08, 0.95}
+	borderColor := r.theme.TabActive
+	borderWidth := float32(2)
+	dimColor := [4]float32{0.6, 0.6, 0.6, 1.0}
+
+	r.drawRect(layout.PanelX, layout.PanelY, layout.PanelWidth, layout.PanelHeight, panelBg, proj)
+	r.drawRect(layout.PanelX, layout.PanelY, layout.PanelWidth, borderWidth, borderColor, proj)
+	r.drawRect(layout.PanelX, layout.PanelY+layout.PanelHeight-borderWidth, layout.PanelWidth, borderWidth, borderColor, proj)
+	r.drawRect(layout.PanelX, layout.PanelY, borderWidth, layout.PanelHeight, borderColor, proj)
+	r.drawRect(layout.PanelX+layout.PanelWidth-borderWidth, layout.PanelY, borderWidth, layout.PanelHeight, borderColor, proj)
+
+	maxChars := int(layout.ContentWidth/cellW) - 2
+	if maxChars < 10 {
+		maxChars = 10
+	}
+
+	r.drawUIText(layout.ContentX, layout.HeaderY, "Registers", r.theme.TabActive, proj)
+	mode := "Paste: type a register name to paste it"
+	if panel.Yank {
+		mode = "Yank: type a register name to store the selection (A-Z appends)"
+	}
+	if len(mode) > maxChars {
+		mode = mode[:maxChars-3] + "..."
+	}
+	r.drawUIText(layout.ContentX, layout.ModeY, mode, dimColor, proj)
+
+	names := panel.Registers.Names()
+	if len(names) == 0 {
+		r.drawUIText(layout.ContentX, layout.ListStart, "All registers are empty.", dimColor, proj)
+	}
+	for i := panel.Scroll; i < len(names) && i < panel.Scroll+layout.VisibleLines; i++ {
+		drawY := layout.ListStart + float32(i-panel.Scroll)*layout.LineHeight
+
+		if i == panel.Selected {
+			highlightColor := [4]float32{0.12, 0.14, 0.22, 1.0}
+			r.drawRect(layout.ContentX, drawY-layout.LineHeight+6, layout.ContentWidth, layout.LineHeight, highlightColor, proj)
+		}
+
+		text, _ := panel.Registers.Get(names[i])
+		lines := strings.Count(strings.TrimRight(text, "\n"), "\n") + 1
+		suffix := ""
+		if lines > 1 {
+			suffix = fmt.Sprintf("  (%d lines)", lines)
+		}
+		preview := strings.Join(strings.Fields(text), " ")
+		if room := maxChars - 3 - len(suffix); len([]rune(preview)) > room && room > 3 {
+			preview = string([]rune(preview)[:room-3]) + "..."
+		}
+		r.drawUIText(layout.ContentX, drawY, string(names[i]), r.theme.TabActive, proj)
+		r.drawUIText(layout.ContentX+cellW*3, drawY, preview+suffix, r.theme.Foreground, proj)
+	}
+
+	footerText := "Tab: paste/yank | Enter: paste | Del: clear | Esc: close"
+	if len(footerText) > maxChars {
+		footerText = footerText[:maxChars-3] + "..."
+	}
+	r.drawUIText(layout.ContentX, layout.FooterY, footerText, dimColor, proj)
+}
+
+// DrawCalcPanel renders the calculator with the live result and earlier results.
+func (r *Renderer) DrawCalcPanel(panel *calc.Panel, width, height int) {
+	cellW, cellH := r.UICellDimensions()
+	if panel == nil || !panel.Open {
+		return
+	}
+
+	proj := orthoMatrix(0, float32(width), float32(height), 0, -1, 1)
+	layout := panel.Layout(width, height, cellW, cellH)
+
+	panelBg := [4]float32{0.05, 0.06, 0.08, 0.95}
+	borderColor := r.theme.TabActive
+	borderWidth := float32(2)
+	dimColor := [4]float32{0.6, 0.6, 0.6, 1.0}
+	errorColor := [4]float32{0.9, 0.4, 0.4, 1.0}
+
+	r.drawRect(layout.PanelX, layout.PanelY, layout.PanelWidth, layout.PanelHeight, panelBg, proj)
+	r.drawRect(layout.PanelX, layout.PanelY, layout.PanelWidth, borderWidth, borderColor, proj)
+	r.drawRect(layout.PanelX, layout.PanelY+layout.PanelHeight-borderWidth, layout.PanelWidth, borderWidth, borderColor, proj)
+	r.drawRect(layout.PanelX, layout.PanelY, borderWidth, layout.PanelHeight, borderColor, proj)
+	r.drawRect(layout.PanelX+layout.PanelWidth-borderWidth, layout.PanelY, borderWidth, layout.PanelHeight, borderColor, proj)
+
+	maxChars := int(layout.ContentWidth/cellW) - 2
+	if maxChars < 10 {
+		maxChars = 10
+	}
+	clip := func(text string) string {
+		if runes := []rune(text); len(runes) > maxChars {
+			return string(runes[:maxChars-3]) + "..."
+		}
+		return text
+	}
+
+	r.drawUIText(layout.ContentX, layout.HeaderY, "Calculator", r.theme.TabActive, proj)
+
+	inputBoxColor := [4]float32{0.03, 0.03, 0.05, 1.0}
+	r.drawRect(layout.ContentX, layout.InputBoxY, layout.ContentWidth, layout.LineHeight, inputBoxColor, proj)
+	inputText := panel.Input
+	if runes := []rune(inputText); len(runes) > maxChars {
+		inputText = "..." + string(runes[len(runes)-maxChars+3:])
+	}
+	r.drawUIText(layout.ContentX+8, layout.InputBoxY+layout.LineHeight*0.75, inputText+"_", r.theme.TabActive, proj)
+
+	switch {
+	case panel.Err != "":
+		r.drawUIText(layout.ContentX, layout.ResultY, clip(panel.Err), errorColor, proj)
+	case panel.Result != "":
+		r.drawUIText(layout.ContentX, layout.ResultY, clip("= "+panel.Result), r.theme.Foreground, proj)
+	default:
+		r.drawUIText(layout.ContentX, layout.ResultY, clip("e.g. 2^10/3, sqrt(2)*ans, 5 km to mi, 72 F to C"), dimColor, proj)
+	}
+
+	for i := 0; i < len(panel.History) && i < layout.VisibleLines; i++ {
+		entry := panel.History[i]
+		drawY := layout.ListStart + float32(i)*layout.LineHeight
+		if i == panel.Recall {
+			highlightColor := [4]float32{0.12, 0.14, 0.22, 1.0}
+			r.drawRect(layout.ContentX, drawY-layout.LineHeight+6, layout.ContentWidth, layout.LineHeight, highlightColor, proj)
+		}
+		r.drawUIText(layout.ContentX, drawY, clip(entry.Expr+" = "+entry.Result), dimColor, proj)
+	}
+
+	footerText := "Enter: insert result | Ctrl+C: copy | Up/Down: history | Esc: close"
+	if len(footerText) > maxChars {
+		footerText = footerText[:maxChars-3] + "..."
+	}
+	r.drawUIText(layout.ContentX, layout.FooterY, footerText, dimColor, proj)
+}
+
+// PaletteSwatches returns the 256 palette colors as this renderer draws
+// them, followed by the theme colors.
+func (r *Renderer) PaletteSwatches() []palette.Swatch {
+	swatches := make([]palette.Swatch, 0, 262)
+	for i := 0; i < 256; i++ {
+		swatches = append(swatches, palette.Swatch{Index: i, Color: indexedColor(uint8(i))})
+	}
+	theme := []struct {
+		name  string
+		color [4]float32
+	}{
+		{"background", r.theme.Background},
+		{"foreground", r.theme.Foreground},
+		{"cursor", r.theme.Cursor},
+		{"tab bar", r.theme.TabBar},
+		{"active tab", r.theme.TabActive},
+		{"selection", r.theme.Selection},
+	}
+	for _, c := range theme {
+		swatches = append(swatches, palette.Swatch{Name: c.name, Index: -1, Color: c.color})
+	}
+	return swatches
+}
+
+// DrawPalettePanel renders the palette and theme colors with the selected
+// color's index, hex value and escape sequence.
+func (r *Renderer) DrawPalettePanel(panel *palette.Panel, width, height int) {
+	cellW, cellH := r.UICellDimensions()
+	if panel == nil || !panel.Open {
+		return
+	}
+
+	proj := orthoMatrix(0, float32(width), float32(height), 0, -1, 1)
+	layout := panel.Layout(width, height, cellW, cellH)
+
+	panelBg := [4]float32{0.05, 0.06, 0.08, 0.95}
+	borderColor := r.theme.TabActive
+	borderWidth := float32(2)
+	dimColor := [4]float32{0.6, 0.6, 0.6, 1.0}
+
+	r.drawRect(layout.PanelX, layout.PanelY, layout.PanelWidth, layout.PanelHeight, panelBg, proj)
+	r.drawRect(layout.PanelX, layout.PanelY, layout.PanelWidth, borderWidth, borderColor, proj)
+	r.drawRect(layout.PanelX, layout.PanelY+layout.PanelHeight-borderWidth, layout.PanelWidth, borderWidth, borderColor, proj)
+	r.drawRect(layout.PanelX, layout.PanelY, borderWidth, layout.PanelHeight, borderColor, proj)
+	r.drawRect(layout.PanelX+layout.PanelWidth-borderWidth, layout.PanelY, borderWidth, layout.PanelHeight, borderColor, proj)
+
+	maxChars := int(layout.ContentWidth/cellW) - 2
+	if maxChars < 10 {
+		maxChars = 10
+	}
+	clip := func(text string) string {
+		if runes := []rune(text); len(runes) > maxChars {
+			return string(runes[:maxChars-3]) + "..."
+		}
+		return text
+	}
+
+	r.drawUIText(layout.ContentX, layout.HeaderY, "Palette", r.theme.TabActive, proj)
+
+	// Indexes fit inside the swatches unless the window is narrow
+	showIndexes := layout.SwatchWidth >= cellW*3.5
+	for i, swatch := range panel.Swatches {
+		x, y := layout.SwatchRect(i)
+		r.drawRect(x+1, y+1, layout.SwatchWidth-2, layout.SwatchHeight-2, swatch.Color, proj)
+		if !showIndexes || swatch.Index < 0 {
+			continue
+		}
+		textColor := [4]float32{0, 0, 0, 1}
+		if luminance(swatch.Color) < 0.5 {
+			textColor = [4]float32{1, 1, 1, 1}
+		}
+		label := strconv.Itoa(swatch.Index)
+		textX := x + (layout.SwatchWidth-float32(len(label))*cellW)/2
+		r.drawUIText(textX, y+layout.SwatchHeight*0.75, label, textColor, proj)
+	}
+
+	if current, ok := panel.Current(); ok {
+		x, y := layout.SwatchRect(panel.Selected)
+		outline := r.theme.Cursor
+		r.drawRect(x, y, layout.SwatchWidth, 2, outline, proj)
+		r.drawRect(x, y+layout.SwatchHeight-2, layout.SwatchWidth, 2, outline, proj)
+		r.drawRect(x, y, 2, layout.SwatchHeight, outline, proj)
+		r.drawRect(x+layout.SwatchWidth-2, y, 2, layout.SwatchHeight, outline, proj)
+
+		layer := "foreground"
+		if panel.Background {
+			layer = "background"
+		}
+		info := fmt.Sprintf("%s  %s  %s (%s)", current.Label(), current.Hex(), current.Sequence(panel.Background), layer)
+		r.drawUIText(layout.ContentX, layout.InfoY, clip(info), r.theme.Foreground, proj)
+	}
+
+	footerText := "Arrows: move | Enter/click: copy sequence | H: copy hex | Tab: fg/bg | Esc: close"
+	if len(footerText) > maxChars {
+		footerText = footerText[:maxChars-3] + "..."
+	}
+	r.drawUIText(layout.ContentX, layout.FooterY, footerText, dimColor, proj)
+}
+
+// luminance approximates how bright a color looks, from 0 to 1
+func luminance(c [4]float32) float32 {
+	return 0.299*c[0] + 0.587*c[1] + 0.114*c[2]
+}
+
+// DrawUpdatePanel renders a release's changelog and the update status.
+func (r *Renderer) DrawUpdatePanel(panel *update.Panel, width, height int) {
+	cellW, cellH := r.UICellDimensions()
+	if panel == nil || !panel.Open {
+		return
+	}
+
+	proj := orthoMatrix(0, float32(width), float32(height), 0, -1, 1)
+	layout := panel.Layout(width, height, cellW, cellH)
+
+	panelBg := [4]float32{0.05, 0.06, 0.08, 0.95}
+	borderColor := r.theme.TabActive
+	borderWidth := float32(2)
+	dimColor := [4]float32{0.6, 0.6, 0.6, 1.0}
+
+	r.drawRect(layout.PanelX, layout.PanelY, layout.PanelWidth, layout.PanelHeight, panelBg, proj)
+	r.drawRect(layout.PanelX, layout.PanelY, layout.PanelWidth, borderWidth, borderColor, proj)
+	r.drawRect(layout.PanelX, layout.PanelY+layout.PanelHeight-borderWidth, layout.PanelWidth, borderWidth, borderColor, proj)
+	r.drawRect(layout.PanelX, layout.PanelY, borderWidth, layout.PanelHeight, borderColor, proj)
+	r.drawRect(layout.PanelX+layout.PanelWidth-borderWidth, layout.PanelY, borderWidth, layout.PanelHeight, borderColor, proj)
+
+	maxChars := int(layout.ContentWidth/cellW) - 2
+	if maxChars < 10 {
+		maxChars = 10
+	}
+
+	header := "Raven Terminal Updates"
+	if panel.Release.Tag != "" {
+		header = "What's new in " + panel.Release.Tag
+	}
+	r.drawUIText(layout.ContentX, layout.HeaderY, header, r.theme.TabActive, proj)
+	status := panel.Status
+	if len(status) > maxChars {
+		status = status[:maxChars-3] + "..."
+	}
+	r.drawUIText(layout.ContentX, layout.StatusY, status, dimColor, proj)
+
+	if panel.Release.Tag != "" {
+		lines := panel.Lines(maxChars)
+		for i := panel.Scroll; i < len(lines) && i < panel.Scroll+layout.VisibleLines; i++ {
+			drawY := layout.ListStart + float32(i-panel.Scroll)*layout.LineHeight
+			color := r.theme.Foreground
+			if strings.HasPrefix(lines[i], "#") {
+				color = r.theme.TabActive
+			}
+			r.drawUIText(layout.ContentX, drawY, lines[i], color, proj)
+		}
+	}
+
+	footerText := "Up/Down: scroll | O: open page | Esc: close"
+	if panel.Available && !panel.Downloading {
+		footerText = "D: download | " + footerText
+	}
+	if len(footerText) > maxChars {
+		footerText = footerText[:maxChars-3] + "..."
+	}
+	r.drawUIText(layout.ContentX, layout.FooterY, footerText, dimColor, proj)
 }
 
-// renderGridAt renders the terminal grid at a specific position
-func (r *Renderer) renderGridAt(g *grid.Grid, offsetX, offsetY, paneWidth, paneHeight float32, proj [16]float32, cursorVisible bool, cursorStyle parser.CursorStyle) {
-	cols := g.Cols
-	rows := g.Rows
+// DrawSnippetPanel renders the snippet picker or its placeholder prompts.
+func (r *Renderer) DrawSnippetPanel(panel *snippets.Panel, width, height int) {
+	cellW, cellH := r.UICellDimensions()
+	if panel == nil || !panel.Open {
+		return
+	}
 
-	// Render cells
-	for row := 0; row < rows; row++ {
-		for col := 0; col < cols; col++ {
-			cell := g.DisplayCell(col, row)
-			x := offsetX + float32(col)*r.cellWidth
-			y := offsetY + float32(row)*r.cellHeight
+	proj := orthoMatrix(0, float32(width), float32(height), 0, -1, 1)
+	layout := panel.Layout(width, height, cellW, cellH)
 
-			// Skip if outside pane bounds
-			if x+r.cellWidth > offsetX+paneWidth || y+r.cellHeight > offsetY+paneHeight {
-				continue
-			}
+	panelBg := [4]float32{0.05, 0.06, 0.08, 0.95}
+	borderColor := r.theme.TabActive
+	borderWidth := float32(2)
+	dimColor := [4]float32{0.6, 0.6, 0.6, 1.0}
+	highlightColor := [4]float32{0.12, 0.14, 0.22, 1.0}
 
-			// Draw background if not default
-			bgColor := r.colorToRGBA(cell.Bg, true)
-			if cell.Flags&grid.FlagInverse != 0 {
-				bgColor, _ = r.colorToRGBA(cell.Fg, false), r.colorToRGBA(cell.Bg, true)
-			}
-			if bgColor != r.theme.Background {
-				// +0.5 horizontal overlap eliminates sub-pixel gaps between adjacent cells
-				r.drawRect(x, y, r.cellWidth+0.5, r.cellHeight, bgColor, proj)
-			}
+	r.drawRect(layout.PanelX, layout.PanelY, layout.PanelWidth, layout.PanelHeight, panelBg, proj)
+	r.drawRect(layout.PanelX, layout.PanelY, layout.PanelWidth, borderWidth, borderColor, proj)
+	r.drawRect(layout.PanelX, layout.PanelY+layout.PanelHeight-borderWidth, layout.PanelWidth, borderWidth, borderColor, proj)
+	r.drawRect(layout.PanelX, layout.PanelY, borderWidth, layout.PanelHeight, borderColor, proj)
+	r.drawRect(layout.PanelX+layout.PanelWidth-borderWidth, layout.PanelY, borderWidth, layout.PanelHeight, borderColor, proj)
 
-			// Draw selection highlight
-			if g.IsSelected(col, row) {
-				r.drawRect(x, y, r.cellWidth+0.5, r.cellHeight, r.theme.Selection, proj)
-			}
+	maxChars := int(layout.ContentWidth/cellW) - 2
+	if maxChars < 10 {
+		maxChars = 10
+	}
+	clip := func(text string) string {
+		text = strings.ReplaceAll(text, "\n", " ")
+		if len([]rune(text)) > maxChars {
+			text = string([]rune(text)[:maxChars-3]) + "..."
+		}
+		return text
+	}
+	inputBoxColor := [4]float32{0.03, 0.03, 0.05, 1.0}
 
-			// Skip character and underline rendering for continuation cells (second half of wide char)
-			if cell.Width == grid.CellWidthContinuation {
-				continue
-			}
+	if panel.Mode == snippets.ModePrompt {
+		r.drawUIText(layout.ContentX, layout.HeaderY, clip("Snippet: "+panel.Pending.Name), r.theme.TabActive, proj)
+		r.drawRect(layout.ContentX, layout.InputBoxY, layout.ContentWidth, layout.LineHeight, inputBoxColor, proj)
+		r.drawUIText(layout.ContentX+8, layout.InputBoxY+layout.LineHeight*0.75, clip(panel.Result()), dimColor, proj)
 
-			// Draw character
-			fgColor := r.colorToRGBA(cell.Fg, false)
-			if cell.Flags&grid.FlagInverse != 0 {
-				fgColor = r.colorToRGBA(cell.Bg, true)
-			}
-			// Apply dim effect (reduce alpha to 50%)
-			if cell.Flags&grid.FlagDim != 0 {
-				fgColor[3] = fgColor[3] / 2
+		for i, field := range panel.Fields {
+			if i >= layout.VisibleLines {
+				break
 			}
-			hidden := cell.Flags&grid.FlagHidden != 0
-			if !hidden && cell.Char != ' ' && cell.Char != 0 {
-				if !r.drawBlockElement(x, y, cell.Char, fgColor, proj) {
-					r.drawChar(x, y+r.cellHeight, cell.Char, fgColor, proj)
-				}
+			drawY := layout.ListStart + float32(i)*layout.LineHeight
+			value := panel.Values[i]
+			clr := r.theme.Foreground
+			if i == panel.Field {
+				r.drawRect(layout.ContentX, drawY-layout.LineHeight+6, layout.ContentWidth, layout.LineHeight, highlightColor, proj)
+				value += "_"
+				clr = r.theme.TabActive
 			}
+			r.drawUIText(layout.ContentX, drawY, clip(field.Name+": "+value), clr, proj)
+		}
 
-			// Draw underline for ANSI styling or hovered URL
-			drawUnderline := cell.Flags&grid.FlagUnderline != 0
-			if r.hoverActive && r.hoverGrid == g && row == r.hoverRow && col >= r.hoverStartCol && col <= r.hoverEndCol {
-				drawUnderline = true
-			}
-			if drawUnderline && !hidden {
-				underlineY := y + r.cellHeight - 1
-				r.drawRect(x, underlineY, r.cellWidth, 1, fgColor, proj)
-			}
-			if cell.Flags&grid.FlagStrikethrough != 0 && !hidden {
-				strikeY := y + r.cellHeight/2
-				r.drawRect(x, strikeY, r.cellWidth, 1, fgColor, proj)
-			}
+		footerText := "Tab/Enter: next field | Shift+Tab: previous | Esc: back"
+		if len(footerText) > maxChars {
+			footerText = footerText[:maxChars-3] + "..."
 		}
+		r.drawUIText(layout.ContentX, layout.FooterY, footerText, dimColor, proj)
+		return
 	}
 
-	// Draw cursor
-	if cursorVisible && g.GetScrollOffset() == 0 {
-		cursorCol, cursorRow := g.GetCursor()
-		cursorX := offsetX + float32(cursorCol)*r.cellWidth
-		cursorY := offsetY + float32(cursorRow)*r.cellHeight
+	r.drawUIText(layout.ContentX, layout.HeaderY, "Snippets", r.theme.TabActive, proj)
+	r.drawRect(layout.ContentX, layout.InputBoxY, layout.ContentWidth, layout.LineHeight, inputBoxColor, proj)
+	inputText := panel.Query
+	if len(inputText) > maxChars {
+		inputText = "..." + inputText[len(inputText)-maxChars+3:]
+	}
+	r.drawUIText(layout.ContentX+8, layout.InputBoxY+layout.LineHeight*0.75, inputText+"_", r.theme.TabActive, proj)
 
-		// Only draw cursor if within pane bounds
-		if cursorX+r.cellWidth <= offsetX+paneWidth && cursorY+r.cellHeight <= offsetY+paneHeight {
-			cell := g.DisplayCell(cursorCol, cursorRow)
-			switch cursorStyle {
-			case parser.CursorStyleUnderline:
-				h := r.cellHeight / 6
-				if h < 1 {
-					h = 1
-				}
-				r.drawRect(cursorX, cursorY+r.cellHeight-h, r.cellWidth, h, r.theme.Cursor, proj)
-			case parser.CursorStyleBar:
-				w := r.cellWidth / 6
-				if w < 1 {
-					w = 1
-				}
-				r.drawRect(cursorX, cursorY, w, r.cellHeight, r.theme.Cursor, proj)
-			default:
-				r.drawRect(cursorX, cursorY, r.cellWidth, r.cellHeight, r.theme.Cursor, proj)
-				// Redraw character under cursor in inverse
-				if cell.Char != ' ' && cell.Char != 0 && cell.Flags&grid.FlagHidden == 0 {
-					if !r.drawBlockElement(cursorX, cursorY, cell.Char, r.theme.Background, proj) {
-						r.drawChar(cursorX, cursorY+r.cellHeight, cell.Char, r.theme.Background, proj)
-					}
-				}
+	if len(panel.Items) == 0 {
+		r.drawUIText(layout.ContentX, layout.ListStart, "No snippets. Add them in Settings > Snippets.", dimColor, proj)
+	}
+
+	for i := panel.Scroll; i < len(panel.Items) && i < panel.Scroll+layout.VisibleLines; i++ {
+		snippet := panel.Items[i].Snippet
+		drawY := layout.ListStart + float32(i-panel.Scroll)*layout.LineHeight
+
+		if i == panel.Selected {
+			r.drawRect(layout.ContentX, drawY-layout.LineHeight+6, layout.ContentWidth, layout.LineHeight, highlightColor, proj)
+		}
+
+		name := clip(snippet.Name)
+		r.drawUIText(layout.ContentX, drawY, name, r.theme.Foreground, proj)
+		detail := snippet.Description
+		if detail == "" {
+			detail = snippet.Body
+		}
+		room := maxChars - len([]rune(name)) - 2
+		if room > 3 {
+			detail = strings.ReplaceAll(detail, "\n", " ")
+			if len([]rune(detail)) > room {
+				detail = string([]rune(detail)[:room-3]) + "..."
 			}
+			r.drawUIText(layout.ContentX+float32(len([]rune(name))+2)*cellW, drawY, detail, dimColor, proj)
 		}
 	}
-}
 
-// SetHoverURL sets the hover underline range for a grid.
-func (r *Renderer) SetHoverURL(g *grid.Grid, row, startCol, endCol int) {
-	if g == nil || row < 0 || startCol < 0 || endCol < startCol {
-		r.ClearHoverURL()
-		return
+	footerText := "Type to filter | Enter: insert | Esc: close"
+	if len(footerText) > maxChars {
+		footerText = footerText[:maxChars-3] + "..."
 	}
-	r.hoverGrid = g
-	r.hoverRow = row
-	r.hoverStartCol = startCol
-	r.hoverEndCol = endCol
-	r.hoverActive = true
+	r.drawUIText(layout.ContentX, layout.FooterY, footerText, dimColor, proj)
 }
 
-// ClearHoverURL clears any active hover underline.
-func (r *Renderer) ClearHoverURL() {
-	r.hoverGrid = nil
-	r.hoverActive = false
+// URLChipRect returns the screen rect of the dev-server URL chip.
+func (r *Renderer) URLChipRect(text string, width, height int) (float32, float32, float32, float32) {
+	cellW, cellH := r.UICellDimensions()
+	paddingX := cellW * 0.8
+	paddingY := cellH * 0.35
+	margin := cellW * 0.8
+	boxW := float32(len([]rune(text)))*cellW + paddingX*2
+	boxH := cellH + paddingY*2
+	if maxW := float32(width) - margin*2; boxW > maxW {
+		boxW = maxW
+	}
+	x := float32(width) - boxW - margin
+	// Sit above the toast slot so both can be visible at once
+	y := float32(height) - boxH*2 - margin*2
+	return x, y, boxW, boxH
 }
 
-// DrawToast renders a small notification overlay.
-func (r *Renderer) DrawToast(message string, width, height int) {
-	if strings.TrimSpace(message) == "" {
+// DrawURLChip renders a clickable chip announcing a detected URL.
+func (r *Renderer) DrawURLChip(text string, width, height int) {
+	cellW, cellH := r.UICellDimensions()
+	if strings.TrimSpace(text) == "" {
 		return
 	}
 
 	proj := orthoMatrix(0, float32(width), float32(height), 0, -1, 1)
+	x, y, boxW, boxH := r.URLChipRect(text, width, height)
+	paddingX := cellW * 0.8
+	paddingY := cellH * 0.35
 
-	paddingX := r.cellWidth * 0.8
-	paddingY := r.cellHeight * 0.35
-	runes := []rune(message)
-	textWidth := float32(len(runes)) * r.cellWidth
-	boxW := textWidth + paddingX*2
-	boxH := r.cellHeight + paddingY*2
-	margin := r.cellWidth * 0.8
+	maxChars := int((boxW - paddingX*2) / cellW)
+	if maxChars < 4 {
+		return
+	}
+	if runes := []rune(text); len(runes) > maxChars {
+		text = string(runes[:maxChars-3]) + "..."
+	}
 
-	maxWidth := float32(width) - margin*2
-	if boxW > maxWidth {
-		maxChars := int((maxWidth - paddingX*2) / r.cellWidth)
-		if maxChars > 3 {
-			message = string(runes[:maxChars-3]) + "..."
-			runes = []rune(message)
-			textWidth = float32(len(runes)) * r.cellWidth
-			boxW = textWidth + paddingX*2
-		} else {
-			return
+	bg := r.theme.TabBar
+	bg[3] = 0.9
+	r.drawRect(x, y, boxW, boxH, bg, proj)
+	r.drawRect(x, y+boxH-2, boxW, 2, r.theme.TabActive, proj)
+	r.drawUIText(x+paddingX, y+boxH-paddingY, text, r.theme.TabActive, proj)
+}
+
+// DrawHints draws the label of every hint target still matching the typed prefix.
+func (r *Renderer) DrawHints(t *tab.Tab, state *hints.State, width, height int) {
+	if t == nil || state == nil || !state.Active {
+		return
+	}
+	proj := orthoMatrix(0, float32(width), float32(height), 0, -1, 1)
+	for _, target := range state.Targets {
+		if !strings.HasPrefix(target.Label, state.Typed) {
+			continue
+		}
+		rectX, rectY, _, _, ok := r.PaneRectFor(t, target.Pane, width, height)
+		if !ok {
+			continue
 		}
+		g := target.Pane.Terminal.GetGrid()
+		cellW, cellH := r.PaneCellSize(g)
+		x := rectX + float32(target.Start)*cellW
+		y := rectY + float32(target.Row)*cellH
+		bg := r.theme.Cursor
+		if target.URL {
+			bg = r.theme.TabActive
+		}
+		r.drawRect(x, y, float32(len(target.Label))*cellW, cellH, bg, proj)
+		typedColor := r.theme.Background
+		typedColor[3] = 0.45
+		r.drawTextScaled(x, y+cellH, state.Typed, typedColor, proj, g.FontScale())
+		r.drawTextScaled(x+float32(len(state.Typed))*cellW, y+cellH, target.Label[len(state.Typed):], r.theme.Background, proj, g.FontScale())
+	}
+}
+
+// DrawSizeOverlay renders grid dimensions centered over the window.
+func (r *Renderer) DrawSizeOverlay(text string, width, height int) {
+	if strings.TrimSpace(text) == "" {
+		return
 	}
 
-	x := float32(width) - boxW - margin
-	y := float32(height) - boxH - margin
+	proj := orthoMatrix(0, float32(width), float32(height), 0, -1, 1)
+
+	scale := 2 * r.uiScale()
+	cellW := r.cellWidth * scale
+	cellH := r.cellHeight * scale
+	paddingX := cellW * 0.6
+	paddingY := cellH * 0.3
+	boxW := float32(len([]rune(text)))*cellW + paddingX*2
+	boxH := cellH + paddingY*2
+	if boxW > float32(width) || boxH > float32(height) {
+		return
+	}
+
+	x := (float32(width) - boxW) / 2
+	y := (float32(height) - boxH) / 2
 	bg := r.theme.TabBar
 	bg[3] = 0.85
 
 	r.drawRect(x, y, boxW, boxH, bg, proj)
-	r.drawText(x+paddingX, y+boxH-paddingY, message, r.theme.Foreground, proj)
+	r.drawTextScaled(x+paddingX, y+boxH-paddingY, text, r.theme.TabActive, proj, scale)
 }
 
-// drawRect draws a colored rectangle
-func (r *Renderer) drawRect(x, y, w, h float32, clr [4]float32, proj [16]float32) {
-	vertices := []float32{
-		x, y,
-		x + w, y,
-		x + w, y + h,
-		x, y,
-		x + w, y + h,
-		x, y + h,
+// DrawCharInfo outlines a cell of a pane and shows lines describing its
+// character in a box beside it, below the cell when there is room.
+func (r *Renderer) DrawCharInfo(t *tab.Tab, pane *tab.Pane, col, row int, lines []string, width, height int) {
+	if pane == nil || len(lines) == 0 {
+		return
+	}
+	px, py, _, _, ok := r.PaneRectFor(t, pane, width, height)
+	if !ok {
+		return
+	}
+	g := pane.Terminal.GetGrid()
+	cw, ch := r.PaneCellSize(g)
+	cells := float32(1)
+	if g.DisplayCell(col, row).Width == grid.CellWidthWide {
+		cells = 2
+	}
+	cx := px + float32(col)*cw
+	cy := py + float32(row)*ch
+	proj := orthoMatrix(0, float32(width), float32(height), 0, -1, 1)
+
+	outline := r.theme.Cursor
+	r.drawRect(cx, cy, cw*cells, 1, outline, proj)
+	r.drawRect(cx, cy+ch-1, cw*cells, 1, outline, proj)
+	r.drawRect(cx, cy, 1, ch, outline, proj)
+	r.drawRect(cx+cw*cells-1, cy, 1, ch, outline, proj)
+
+	cellW, cellH := r.UICellDimensions()
+	longest := 0
+	for _, line := range lines {
+		longest = max(longest, len([]rune(line)))
+	}
+	padding := cellW * 0.8
+	boxW := float32(longest)*cellW + padding*2
+	boxH := float32(len(lines))*cellH*1.2 + padding
+	x := min32(cx, float32(width)-boxW-4)
+	x = max32(x, 4)
+	y := cy + ch + 4
+	if y+boxH > float32(height) {
+		y = max32(cy-boxH-4, 4)
+	}
+
+	bg := [4]float32{0.05, 0.06, 0.08, 0.95}
+	r.drawRect(x, y, boxW, boxH, bg, proj)
+	r.drawRect(x, y, boxW, 2, r.theme.TabActive, proj)
+	for i, line := range lines {
+		clr := r.theme.Foreground
+		if i > 0 {
+			clr = [4]float32{0.7, 0.7, 0.7, 1.0}
+		}
+		r.drawUIText(x+padding, y+padding/2+float32(i+1)*cellH*1.2-cellH*0.2, line, clr, proj)
+	}
+}
+
+// DrawCursorSpotlight dims the active pane around its cursor and closes a
+// ring in on it. progress runs from 0 when the spotlight starts to 1 when it
+// has faded out.
+func (r *Renderer) DrawCursorSpotlight(t *tab.Tab, progress float32, width, height int) {
+	if t == nil || progress < 0 || progress >= 1 {
+		return
 	}
+	pane := t.GetActivePane()
+	if pane == nil || pane.Terminal == nil {
+		return
+	}
+	px, py, pw, ph, ok := r.PaneRectFor(t, pane, width, height)
+	if !ok {
+		return
+	}
+	g := pane.Terminal.GetGrid()
+	cw, ch := r.PaneCellSize(g)
+	col, row := g.GetCursor()
+	cx := px + (float32(col)+0.5)*cw
+	cy := py + (float32(row)+0.5)*ch
+	proj := orthoMatrix(0, float32(width), float32(height), 0, -1, 1)
+
+	// The ring eases in from a third of the pane to just around the cursor
+	// cell, and the dimming fades out over the last part
+	ease := 1 - (1-progress)*(1-progress)*(1-progress)
+	start := max32(pw, ph) / 3
+	end := ch * 1.5
+	inner := start + (end-start)*ease
+	outer := inner + max32(ch/4, 2)
+	fade := float32(1)
+	if progress > 0.6 {
+		fade = (1 - progress) / 0.4
+	}
+
+	dim := r.theme.Background
+	dim[3] = 0.6 * fade
+	ring := r.theme.Cursor
+	ring[3] = fade
+	top, bottom := max32(cy-outer, py), min32(cy+outer, py+ph)
+	if top > py {
+		r.drawRect(px, py, pw, top-py, dim, proj)
+	}
+	if bottom < py+ph {
+		r.drawRect(px, bottom, pw, py+ph-bottom, dim, proj)
+	}
+	// Fill the rows the ring crosses one pixel at a time, outside the ring
+	// dimmed and the ring itself solid
+	for y := top; y < bottom; y++ {
+		dy := y + 0.5 - cy
+		ringHalf := float32(math.Sqrt(float64(max32(outer*outer-dy*dy, 0))))
+		holeHalf := float32(math.Sqrt(float64(max32(inner*inner-dy*dy, 0))))
+		left, right := max32(cx-ringHalf, px), min32(cx+ringHalf, px+pw)
+		if left > px {
+			r.drawRect(px, y, left-px, 1, dim, proj)
+		}
+		if right < px+pw {
+			r.drawRect(right, y, px+pw-right, 1, dim, proj)
+		}
+		if holeHalf == 0 {
+			r.drawRect(left, y, right-left, 1, ring, proj)
+			continue
+		}
+		if l := min32(cx-holeHalf, right); l > left {
+			r.drawRect(left, y, l-left, 1, ring, proj)
+		}
+		if rr := max32(cx+holeHalf, left); right > rr {
+			r.drawRect(rr, y, right-rr, 1, ring, proj)
+		}
+	}
+}
+
+// DrawLockScreen covers the whole window while the terminal is locked.
+// prompt is shown under the title; masked is the passphrase typed so far.
+func (r *Renderer) DrawLockScreen(prompt, masked, status string, width, height int) {
+	proj := orthoMatrix(0, float32(width), float32(height), 0, -1, 1)
+
+	bg := r.theme.Background
+	bg[3] = 1.0
+	r.drawRect(0, 0, float32(width), float32(height), bg, proj)
 
-	gl.UseProgram(r.program)
-	gl.UniformMatrix4fv(r.projLoc, 1, false, &proj[0])
-	gl.Uniform4fv(r.colorLoc, 1, &clr[0])
+	cellW, cellH := r.UICellDimensions()
+	scale := 2 * r.uiScale()
+	title := "Locked"
+	titleW := float32(len(title)) * cellW * 2
+	centerY := float32(height) / 2
+	r.drawTextScaled((float32(width)-titleW)/2, centerY-cellH, title, r.theme.TabActive, proj, scale)
+
+	lines := []struct {
+		text string
+		clr  [4]float32
+	}{
+		{prompt, r.theme.Foreground},
+		{masked, r.theme.TabActive},
+		{status, r.theme.Cursor},
+	}
+	y := centerY + cellH*1.5
+	for _, line := range lines {
+		if line.text != "" {
+			lineW := float32(len([]rune(line.text))) * cellW
+			r.drawUIText((float32(width)-lineW)/2, y, line.text, line.clr, proj)
+		}
+		y += cellH * 1.4
+	}
+}
 
-	gl.BindVertexArray(r.quadVAO)
-	gl.BindBuffer(gl.ARRAY_BUFFER, r.quadVBO)
-	gl.BufferSubData(gl.ARRAY_BUFFER, 0, len(vertices)*4, gl.Ptr(vertices))
-	gl.DrawArrays(gl.TRIANGLES, 0, 6)
-	gl.BindVertexArray(0)
+// drawRect draws a colored rectangle
+func (r *Renderer) drawRect(x, y, w, h float32, clr [4]float32, proj [16]float32) {
+	r.backend.FillRect(x, y, w, h, clr, proj)
 }
 
 // boxDrawingFallbacks maps rounded corners and other box chars to simpler equivalents
@@ -2181,23 +4371,31 @@ var quadrantBlockMasks = map[rune]uint8{
 	'\u259F': 0b1110, // Quadrant upper right and lower left and lower right
 }
 
+// snapRect moves a rectangle's edges to the nearest whole pixels, so
+// rectangles that share an edge still share it after rounding
+func snapRect(x, y, w, h float32) (float32, float32, float32, float32) {
+	left, top := float32(math.Round(float64(x))), float32(math.Round(float64(y)))
+	right, bottom := float32(math.Round(float64(x+w))), float32(math.Round(float64(y+h)))
+	return left, top, right - left, bottom - top
+}
+
 // drawBlockElement renders block element characters as geometry to avoid seams.
-func (r *Renderer) drawBlockElement(x, y float32, char rune, clr [4]float32, proj [16]float32) bool {
+func (r *Renderer) drawBlockElement(x, y, cw, ch float32, char rune, clr [4]float32, proj [16]float32) bool {
 	switch char {
 	case '\u2588': // Full block
-		r.drawRect(x, y, r.cellWidth, r.cellHeight, clr, proj)
+		r.drawRect(x, y, cw, ch, clr, proj)
 		return true
 	case '\u2580': // Upper half block
-		r.drawRect(x, y, r.cellWidth, r.cellHeight/2, clr, proj)
+		r.drawRect(x, y, cw, ch/2, clr, proj)
 		return true
 	case '\u2584': // Lower half block
-		r.drawRect(x, y+r.cellHeight/2, r.cellWidth, r.cellHeight/2, clr, proj)
+		r.drawRect(x, y+ch/2, cw, ch/2, clr, proj)
 		return true
 	case '\u258C': // Left half block
-		r.drawRect(x, y, r.cellWidth/2, r.cellHeight, clr, proj)
+		r.drawRect(x, y, cw/2, ch, clr, proj)
 		return true
 	case '\u2590': // Right half block
-		r.drawRect(x+r.cellWidth/2, y, r.cellWidth/2, r.cellHeight, clr, proj)
+		r.drawRect(x+cw/2, y, cw/2, ch, clr, proj)
 		return true
 	case '\u2591', '\u2592', '\u2593': // Light/medium/dark shade
 		shade := clr
@@ -2209,35 +4407,35 @@ func (r *Renderer) drawBlockElement(x, y float32, char rune, clr [4]float32, pro
 		case '\u2593':
 			shade[3] *= 0.75
 		}
-		r.drawRect(x, y, r.cellWidth, r.cellHeight, shade, proj)
+		r.drawRect(x, y, cw, ch, shade, proj)
 		return true
 	case '\u2594': // Upper one eighth block
-		r.drawRect(x, y, r.cellWidth, r.cellHeight/8, clr, proj)
+		r.drawRect(x, y, cw, ch/8, clr, proj)
 		return true
 	case '\u2595': // Right one eighth block
-		r.drawRect(x+r.cellWidth*7/8, y, r.cellWidth/8, r.cellHeight, clr, proj)
+		r.drawRect(x+cw*7/8, y, cw/8, ch, clr, proj)
 		return true
 	}
 
 	if char >= '\u2581' && char <= '\u2587' {
 		// Lower 1/8..7/8 blocks
 		n := float32(char - '\u2580')
-		h := r.cellHeight * n / 8
-		r.drawRect(x, y+r.cellHeight-h, r.cellWidth, h, clr, proj)
+		h := ch * n / 8
+		r.drawRect(x, y+ch-h, cw, h, clr, proj)
 		return true
 	}
 
 	if char >= '\u2589' && char <= '\u258F' {
 		// Left 7/8..1/8 blocks
 		n := float32('\u2590' - char)
-		w := r.cellWidth * n / 8
-		r.drawRect(x, y, w, r.cellHeight, clr, proj)
+		w := cw * n / 8
+		r.drawRect(x, y, w, ch, clr, proj)
 		return true
 	}
 
 	if mask, ok := quadrantBlockMasks[char]; ok {
-		hw := r.cellWidth / 2
-		hh := r.cellHeight / 2
+		hw := cw / 2
+		hh := ch / 2
 		if mask&0b0001 != 0 {
 			r.drawRect(x, y, hw, hh, clr, proj)
 		}
@@ -2258,7 +4456,7 @@ func (r *Renderer) drawBlockElement(x, y float32, char rune, clr [4]float32, pro
 
 // drawChar draws a single character using the font atlas
 func (r *Renderer) drawChar(x, y float32, char rune, clr [4]float32, proj [16]float32) {
-	glyph, ok := r.glyphs[char]
+	glyph, ok := r.glyph(char)
 	if !ok {
 		// Try box-drawing fallbacks first
 		if fallback, hasFallback := boxDrawingFallbacks[char]; hasFallback {
@@ -2279,38 +4477,7 @@ func (r *Renderer) drawChar(x, y float32, char rune, clr [4]float32, proj [16]fl
 		}
 	}
 
-	// Calculate screen coordinates
-	w := float32(glyph.PixelWidth)
-	h := float32(glyph.PixelHeight)
-
-	// Texture coordinates
-	tx := glyph.X
-	ty := glyph.Y
-	tw := glyph.Width
-	th := glyph.Height
-
-	vertices := []float32{
-		x, y - h, tx, ty,
-		x + w, y - h, tx + tw, ty,
-		x + w, y, tx + tw, ty + th,
-		x, y - h, tx, ty,
-		x + w, y, tx + tw, ty + th,
-		x, y, tx, ty + th,
-	}
-
-	gl.UseProgram(r.fontProgram)
-	gl.UniformMatrix4fv(r.texProjLoc, 1, false, &proj[0])
-	gl.Uniform4fv(r.texColorLoc, 1, &clr[0])
-	gl.Uniform1i(r.texLoc, 0)
-
-	gl.ActiveTexture(gl.TEXTURE0)
-	gl.BindTexture(gl.TEXTURE_2D, r.fontAtlas)
-
-	gl.BindVertexArray(r.fontVAO)
-	gl.BindBuffer(gl.ARRAY_BUFFER, r.fontVBO)
-	gl.BufferSubData(gl.ARRAY_BUFFER, 0, len(vertices)*4, gl.Ptr(vertices))
-	gl.DrawArrays(gl.TRIANGLES, 0, 6)
-	gl.BindVertexArray(0)
+	r.drawGlyph(x, y, glyph, clr, proj, 1)
 }
 
 // drawText draws a string of text
@@ -2321,6 +4488,36 @@ func (r *Renderer) drawText(x, y float32, text string, clr [4]float32, proj [16]
 	}
 }
 
+// uiScale returns the glyph scale that draws overlay text at the base font
+// size regardless of zoom, times the UI scale setting
+func (r *Renderer) uiScale() float32 {
+	return r.baseFontSize / r.fontSize * r.uiScaleFactor
+}
+
+// SetUIScale sets how much larger than the base font size the tab bar, menus,
+// panels and toasts are drawn, independently of terminal zoom. The tab bar
+// widens with it, so the grid size must be recalculated afterwards.
+func (r *Renderer) SetUIScale(scale float32) {
+	if scale <= 0 {
+		scale = 1
+	}
+	scale = max(MinUIScale, min(scale, MaxUIScale))
+	r.uiScaleFactor = scale
+	r.tabBarWidth = baseTabBarWidth * scale
+}
+
+// UICellDimensions returns the cell size overlays are laid out with. Unlike
+// CellDimensions it does not change with terminal zoom.
+func (r *Renderer) UICellDimensions() (float32, float32) {
+	scale := r.uiScale()
+	return r.cellWidth * scale, r.cellHeight * scale
+}
+
+// drawUIText draws overlay text at the base font size
+func (r *Renderer) drawUIText(x, y float32, text string, clr [4]float32, proj [16]float32) {
+	r.drawTextScaled(x, y, text, clr, proj, r.uiScale())
+}
+
 // drawTextScaled draws text at a specific scale relative to current font
 func (r *Renderer) drawTextScaled(x, y float32, text string, clr [4]float32, proj [16]float32, scale float32) {
 	for _, char := range text {
@@ -2331,7 +4528,7 @@ func (r *Renderer) drawTextScaled(x, y float32, text string, clr [4]float32, pro
 
 // drawCharScaled draws a character at a specific scale
 func (r *Renderer) drawCharScaled(x, y float32, char rune, clr [4]float32, proj [16]float32, scale float32) {
-	glyph, ok := r.glyphs[char]
+	glyph, ok := r.glyph(char)
 	if !ok {
 		// Try box-drawing fallbacks first
 		if fallback, hasFallback := boxDrawingFallbacks[char]; hasFallback {
@@ -2352,38 +4549,19 @@ func (r *Renderer) drawCharScaled(x, y float32, char rune, clr [4]float32, proj
 		}
 	}
 
-	// Calculate screen coordinates with scale
-	w := float32(glyph.PixelWidth) * scale
-	h := float32(glyph.PixelHeight) * scale
-
-	// Texture coordinates
-	tx := glyph.X
-	ty := glyph.Y
-	tw := glyph.Width
-	th := glyph.Height
+	r.drawGlyph(x, y, glyph, clr, proj, scale)
+}
 
-	vertices := []float32{
-		x, y - h, tx, ty,
-		x + w, y - h, tx + tw, ty,
-		x + w, y, tx + tw, ty + th,
-		x, y - h, tx, ty,
-		x + w, y, tx + tw, ty + th,
-		x, y, tx, ty + th,
+// drawGlyph draws a glyph's ink at its bearing in the cell whose bottom-left
+// corner is x, y
+func (r *Renderer) drawGlyph(x, y float32, glyph Glyph, clr [4]float32, proj [16]float32, scale float32) {
+	if glyph.PixelWidth == 0 || glyph.PixelHeight == 0 {
+		return
 	}
-
-	gl.UseProgram(r.fontProgram)
-	gl.UniformMatrix4fv(r.texProjLoc, 1, false, &proj[0])
-	gl.Uniform4fv(r.texColorLoc, 1, &clr[0])
-	gl.Uniform1i(r.texLoc, 0)
-
-	gl.ActiveTexture(gl.TEXTURE0)
-	gl.BindTexture(gl.TEXTURE_2D, r.fontAtlas)
-
-	gl.BindVertexArray(r.fontVAO)
-	gl.BindBuffer(gl.ARRAY_BUFFER, r.fontVBO)
-	gl.BufferSubData(gl.ARRAY_BUFFER, 0, len(vertices)*4, gl.Ptr(vertices))
-	gl.DrawArrays(gl.TRIANGLES, 0, 6)
-	gl.BindVertexArray(0)
+	top := y - r.cellHeight*scale
+	w := float32(glyph.PixelWidth) * scale
+	h := float32(glyph.PixelHeight) * scale
+	r.backend.DrawGlyph(x+float32(glyph.BearingX)*scale, top+float32(glyph.BearingY)*scale, w, h, glyph.X, glyph.Y, glyph.Width, glyph.Height, clr, proj)
 }
 
 // colorToRGBA converts a grid.Color to RGBA
@@ -2479,11 +4657,6 @@ func (r *Renderer) ChangeFont(name string) error {
 		return fmt.Errorf("font '%s' not found", name)
 	}
 
-	// Delete old texture
-	if r.fontAtlas != 0 {
-		gl.DeleteTextures(1, &r.fontAtlas)
-	}
-
 	// Clear old glyphs
 	r.glyphs = make(map[rune]Glyph)
 
@@ -2496,6 +4669,16 @@ func (r *Renderer) ChangeFont(name string) error {
 	return nil
 }
 
+// BackendName returns the name of the backend frames are drawn with
+func (r *Renderer) BackendName() string {
+	return r.backend.Name()
+}
+
+// Present finishes the frame; call it after the last draw and before swapping buffers
+func (r *Renderer) Present() {
+	r.backend.Present()
+}
+
 // CurrentFont returns the current font name
 func (r *Renderer) CurrentFont() string {
 	return r.currentFont
@@ -2512,6 +4695,11 @@ const minFontSize = 8.0
 const maxFontSize = 32.0
 const zoomStep = 2.0
 
+// Tab bar width at UI scale 1, and the range the UI scale setting is kept in
+const baseTabBarWidth = 135.0
+const MinUIScale = 0.5
+const MaxUIScale = 3.0
+
 // ZoomIn increases the font size
 func (r *Renderer) ZoomIn() error {
 	newSize := r.fontSize + zoomStep
@@ -2543,11 +4731,6 @@ func (r *Renderer) setFontSize(size float32) error {
 
 	r.fontSize = size
 
-	// Delete old texture
-	if r.fontAtlas != 0 {
-		gl.DeleteTextures(1, &r.fontAtlas)
-	}
-
 	// Clear old glyphs
 	r.glyphs = make(map[rune]Glyph)
 
@@ -2589,13 +4772,10 @@ func clampFontSize(size float32) float32 {
 
 // Destroy cleans up renderer resources
 func (r *Renderer) Destroy() {
-	gl.DeleteVertexArrays(1, &r.quadVAO)
-	gl.DeleteBuffers(1, &r.quadVBO)
-	gl.DeleteVertexArrays(1, &r.fontVAO)
-	gl.DeleteBuffers(1, &r.fontVBO)
-	gl.DeleteProgram(r.program)
-	gl.DeleteProgram(r.fontProgram)
-	gl.DeleteTextures(1, &r.fontAtlas)
+	r.backend.Destroy()
+	if r.lazyFace != nil {
+		r.lazyFace.Close()
+	}
 }
 
 // orthoMatrix creates an orthographic projection matrix
@@ -2608,61 +4788,6 @@ func orthoMatrix(left, right, bottom, top, near, far float32) [16]float32 {
 	}
 }
 
-// createProgram creates a shader program from vertex and fragment shader sources
-func createProgram(vertexSource, fragmentSource string) (uint32, error) {
-	vertexShader, err := compileShader(vertexSource, gl.VERTEX_SHADER)
-	if err != nil {
-		return 0, err
-	}
-
-	fragmentShader, err := compileShader(fragmentSource, gl.FRAGMENT_SHADER)
-	if err != nil {
-		return 0, err
-	}
-
-	program := gl.CreateProgram()
-	gl.AttachShader(program, vertexShader)
-	gl.AttachShader(program, fragmentShader)
-	gl.LinkProgram(program)
-
-	var status int32
-	gl.GetProgramiv(program, gl.LINK_STATUS, &status)
-	if status == gl.FALSE {
-		var logLength int32
-		gl.GetProgramiv(program, gl.INFO_LOG_LENGTH, &logLength)
-		log := strings.Repeat("\x00", int(logLength+1))
-		gl.GetProgramInfoLog(program, logLength, nil, gl.Str(log))
-		return 0, fmt.Errorf("failed to link program: %v", log)
-	}
-
-	gl.DeleteShader(vertexShader)
-	gl.DeleteShader(fragmentShader)
-
-	return program, nil
-}
-
-// compileShader compiles a shader from source
-func compileShader(source string, shaderType uint32) (uint32, error) {
-	shader := gl.CreateShader(shaderType)
-
-	csources, free := gl.Strs(source)
-	gl.ShaderSource(shader, 1, csources, nil)
-	free()
-	gl.CompileShader(shader)
-
-	var status int32
-	gl.GetShaderiv(shader, gl.COMPILE_STATUS, &status)
-	if status == gl.FALSE {
-		var logLength int32
-		gl.GetShaderiv(shader, gl.INFO_LOG_LENGTH, &logLength)
-		log := strings.Repeat("\x00", int(logLength+1))
-		gl.GetShaderInfoLog(shader, logLength, nil, gl.Str(log))
-		return 0, fmt.Errorf("failed to compile shader: %v", log)
-	}
-
-	return shader, nil
-}
-
 // Ensure imports are used
 var _ = color.White
 var _ = draw.Draw
@@ -0,0 +1,38 @@
+package render
+
+import "hash/fnv"
+
+// wrapCache keeps the last lines an overlay wrapped, so frames showing the
+// same text at the same width and theme reuse them instead of wrapping again.
+type wrapCache struct {
+	hash  uint64
+	width int
+	color [4]float32
+	lines []styledLine
+	valid bool
+}
+
+// get returns the preview lines wrapped to width, and whether they had to be
+// wrapped again because the text, width or theme changed
+func (c *wrapCache) get(lines []string, width int, theme Theme) ([]styledLine, bool) {
+	hash := hashLines(lines)
+	if c.valid && c.hash == hash && c.width == width && c.color == theme.Foreground {
+		return c.lines, false
+	}
+	c.lines = buildWrappedPreview(lines, width, theme)
+	c.hash = hash
+	c.width = width
+	c.color = theme.Foreground
+	c.valid = true
+	return c.lines, true
+}
+
+// hashLines hashes lines so that different splits of the same text differ
+func hashLines(lines []string) uint64 {
+	h := fnv.New64a()
+	for _, line := range lines {
+		h.Write([]byte(line))
+		h.Write([]byte{0})
+	}
+	return h.Sum64()
+}
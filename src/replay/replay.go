@@ -0,0 +1,147 @@
+package replay
+
+import (
+	"sync"
+	"time"
+
+	"github.com/javanhut/RavenTerminal/src/grid"
+	"github.com/javanhut/RavenTerminal/src/parser"
+)
+
+// Frame is one read of a pane's output
+type Frame struct {
+	Time time.Time
+	Data []byte
+}
+
+// Buffer keeps a pane's most recent output as a timeline of frames, dropping
+// the oldest once the total passes a byte limit
+type Buffer struct {
+	mu      sync.Mutex
+	limit   int
+	size    int
+	frames  []Frame
+	trimmed bool // Output before the first frame was dropped
+}
+
+// NewBuffer returns a buffer holding up to limit bytes; 0 records nothing
+func NewBuffer(limit int) *Buffer {
+	return &Buffer{limit: limit}
+}
+
+// SetLimit changes the byte limit, dropping old frames to fit
+func (b *Buffer) SetLimit(limit int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.limit = limit
+	b.trimLocked()
+}
+
+// Write appends a copy of data as a new frame
+func (b *Buffer) Write(data []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.limit <= 0 || len(data) == 0 {
+		return
+	}
+	frame := Frame{Time: time.Now(), Data: append([]byte(nil), data...)}
+	b.frames = append(b.frames, frame)
+	b.size += len(data)
+	b.trimLocked()
+}
+
+func (b *Buffer) trimLocked() {
+	drop := 0
+	for drop < len(b.frames) && b.size > b.limit {
+		b.size -= len(b.frames[drop].Data)
+		drop++
+	}
+	if drop > 0 {
+		b.frames = append(b.frames[:0], b.frames[drop:]...)
+		b.trimmed = true
+	}
+}
+
+// Frames returns the recorded frames, oldest first, and whether earlier
+// output was dropped. Frame data is shared and must not be modified.
+func (b *Buffer) Frames() ([]Frame, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	frames := make([]Frame, len(b.frames))
+	copy(frames, b.frames)
+	return frames, b.trimmed
+}
+
+// Player rebuilds a pane's screen as it was after any recorded frame by
+// feeding the frames into a fresh terminal of the pane's size
+type Player struct {
+	frames  []Frame
+	trimmed bool
+	cols    int
+	rows    int
+	latin1  bool
+	index   int // Frames up to and including index have been replayed
+	term    *parser.Terminal
+}
+
+// NewPlayer starts at the last frame, showing the screen as it is now
+func NewPlayer(frames []Frame, trimmed bool, cols, rows int, latin1 bool) *Player {
+	p := &Player{frames: frames, trimmed: trimmed, cols: cols, rows: rows, latin1: latin1}
+	p.Seek(len(frames) - 1)
+	return p
+}
+
+// Len returns the number of frames
+func (p *Player) Len() int {
+	return len(p.frames)
+}
+
+// Index returns the frame being shown
+func (p *Player) Index() int {
+	return p.index
+}
+
+// Trimmed reports whether output before the first frame was dropped, so
+// the replayed screen may be missing state set up earlier
+func (p *Player) Trimmed() bool {
+	return p.trimmed
+}
+
+// Frame returns the frame being shown
+func (p *Player) Frame() (Frame, bool) {
+	if p.index < 0 || p.index >= len(p.frames) {
+		return Frame{}, false
+	}
+	return p.frames[p.index], true
+}
+
+// Grid returns the replayed screen
+func (p *Player) Grid() *grid.Grid {
+	return p.term.GetGrid()
+}
+
+// Step moves delta frames forward, or backward when negative
+func (p *Player) Step(delta int) {
+	p.Seek(p.index + delta)
+}
+
+// Seek shows the screen after frame index. Moving forward continues from
+// the current screen; moving backward replays from the first frame.
+func (p *Player) Seek(index int) {
+	if index >= len(p.frames) {
+		index = len(p.frames) - 1
+	}
+	if index < 0 {
+		index = 0
+	}
+	from := p.index + 1
+	if p.term == nil || index < p.index {
+		p.term = parser.NewTerminal(p.cols, p.rows)
+		p.term.SetLatin1(p.latin1)
+		from = 0
+	}
+	for i := from; i <= index && i < len(p.frames); i++ {
+		p.term.Process(p.frames[i].Data)
+	}
+	p.index = index
+}
@@ -25,6 +25,7 @@ type Result struct {
 	Title   string
 	URL     string
 	Snippet string
+	Text    string // Full text shown as the preview of results that are not web pages
 }
 
 type Panel struct {
@@ -38,6 +39,9 @@ type Panel struct {
 	ResultsScroll    int
 	Mode             Mode
 	ProxyEnabled     bool
+	Semantic         bool // Search the embedded scrollback instead of the web
+	WebEnabled       bool
+	SemanticEnabled  bool
 	Focused          bool
 	PreviewTitle     string
 	PreviewURL       string
@@ -49,6 +53,8 @@ type Panel struct {
 	Loading          bool
 	SearchID         int
 	PreviewID        int
+	KeepState        bool // Keep the preview and its scroll when the panel is closed
+	previewOK        bool // The preview loaded, so it can be shown again without fetching
 
 	// Search history
 	History      []string // Previous search queries
@@ -92,17 +98,73 @@ func New() *Panel {
 }
 
 func (p *Panel) Toggle() {
-	p.Open = !p.Open
 	if p.Open {
-		p.Focused = true
+		p.Close()
+		return
+	}
+	p.Open = true
+	p.Focused = true
+}
+
+// Close hides the panel. Unless KeepState is on, it reopens on the results
+// rather than the preview.
+func (p *Panel) Close() {
+	p.Open = false
+	p.SelectionActive = false
+	if !p.KeepState {
+		p.CloseResult()
+	}
+}
+
+// CloseResult goes back from the preview to the results. With KeepState the
+// preview's scroll is kept for when the same result is opened again.
+func (p *Panel) CloseResult() {
+	p.Mode = ModeResults
+	if !p.KeepState {
+		p.PreviewScroll = 0
+	}
+}
+
+// ReopenPreview shows the preview again when it is of result and loaded
+// fine, and reports whether it did
+func (p *Panel) ReopenPreview(result Result) bool {
+	if !p.KeepState || !p.previewOK || p.Loading || len(p.PreviewLines) == 0 || result.URL != p.PreviewURL || result.Title != p.PreviewTitle {
+		return false
 	}
+	p.Mode = ModePreview
+	return true
 }
 
-func (p *Panel) SetEnabled(enabled bool) {
-	p.Enabled = enabled
-	if !enabled {
+// SetEnabled allows the panel when web search or semantic scrollback search
+// is on. Without web search the panel stays in semantic mode.
+func (p *Panel) SetEnabled(web, semantic bool) {
+	p.Enabled = web || semantic
+	p.WebEnabled = web
+	p.SemanticEnabled = semantic
+	if !p.Enabled {
 		p.Open = false
 	}
+	if !web && semantic {
+		p.Semantic = true
+	} else if !semantic {
+		p.Semantic = false
+	}
+}
+
+// ToggleSemantic switches between web and scrollback search, when both are
+// enabled, and reports whether the mode changed
+func (p *Panel) ToggleSemantic() bool {
+	if !p.SemanticEnabled || !p.WebEnabled {
+		return false
+	}
+	p.Semantic = !p.Semantic
+	p.Mode = ModeResults
+	p.Results = nil
+	p.Selected = 0
+	p.ResultsScroll = 0
+	p.LastQuery = ""
+	p.QueryDirty = p.Query != ""
+	return true
 }
 
 func (p *Panel) SetQuery(text string) {
@@ -112,6 +174,7 @@ func (p *Panel) SetQuery(text string) {
 		p.Mode = ModeResults
 		p.PreviewLines = nil
 		p.PreviewScroll = 0
+		p.previewOK = false
 	}
 }
 
@@ -155,6 +218,7 @@ func (p *Panel) SetPreview(url, title string, lines []string, err error) {
 	p.PreviewScroll = 0
 	p.PreviewWrapped = nil
 	p.PreviewWrapChars = 0
+	p.previewOK = err == nil
 	if err != nil {
 		p.Status = "Preview failed"
 		p.PreviewLines = []string{"Failed to load preview."}
@@ -166,6 +230,19 @@ func (p *Panel) SetPreview(url, title string, lines []string, err error) {
 	p.PreviewLines = lines
 }
 
+// ReaderText returns the previewed page as a document for the reader pane:
+// a bold title, the URL, then the page text
+func (p *Panel) ReaderText() string {
+	var b strings.Builder
+	b.WriteString("\x1b[1m" + p.PreviewTitle + "\x1b[0m\n")
+	b.WriteString("\x1b[2m" + p.PreviewURL + "\x1b[0m\n\n")
+	for _, line := range p.PreviewLines {
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
 func (p *Panel) ResultCount() int {
 	return len(p.Results)
 }
@@ -0,0 +1,218 @@
+package semantic
+
+import (
+	"encoding/gob"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/javanhut/RavenTerminal/src/config"
+	"github.com/javanhut/RavenTerminal/src/redact"
+)
+
+// MaxChunks caps the indexed chunks; the oldest are dropped first
+const MaxChunks = 5000
+
+// ChunkLines is how many scrollback lines are embedded together
+const ChunkLines = 20
+
+// Chunk is a block of scrollback text with its embedding
+type Chunk struct {
+	Time   time.Time
+	Source string // Working directory or title of the pane it came from
+	Text   string
+	Vector []float32
+}
+
+// Match is a chunk found by Search with its similarity to the query
+type Match struct {
+	Chunk
+	Score float32
+}
+
+type indexFile struct {
+	Model  string
+	Chunks []Chunk
+}
+
+// Index is a local vector index of embedded scrollback, kept across sessions.
+// Vectors from different models cannot be compared, so changing the model
+// starts the index over.
+type Index struct {
+	mu     sync.Mutex
+	path   string
+	model  string
+	chunks []Chunk
+	texts  map[string]bool // Indexed texts, so repeated output is embedded once
+	dirty  bool
+}
+
+// DefaultPath returns the index file in the state directory
+func DefaultPath() string {
+	return filepath.Join(config.GetStateDir(), "semantic-index.gob")
+}
+
+// Load reads the index from path. A missing file yields an empty index.
+func Load(path string) (*Index, error) {
+	ix := &Index{path: path, texts: make(map[string]bool)}
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ix, nil
+		}
+		return ix, err
+	}
+	defer f.Close()
+	var file indexFile
+	if err := gob.NewDecoder(f).Decode(&file); err != nil {
+		return ix, err
+	}
+	ix.model = file.Model
+	ix.chunks = file.Chunks
+	for _, chunk := range ix.chunks {
+		ix.texts[chunk.Text] = true
+	}
+	return ix, nil
+}
+
+// Len returns the number of indexed chunks
+func (ix *Index) Len() int {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	return len(ix.chunks)
+}
+
+// Has reports whether text is already indexed for model
+func (ix *Index) Has(model, text string) bool {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	return ix.model == model && ix.texts[text]
+}
+
+// Add stores chunks embedded by model
+func (ix *Index) Add(model string, chunks []Chunk) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if model != ix.model {
+		ix.model = model
+		ix.chunks = nil
+		ix.texts = make(map[string]bool)
+	}
+	for _, chunk := range chunks {
+		if ix.texts[chunk.Text] || len(chunk.Vector) == 0 {
+			continue
+		}
+		ix.texts[chunk.Text] = true
+		ix.chunks = append(ix.chunks, chunk)
+		ix.dirty = true
+	}
+	if drop := len(ix.chunks) - MaxChunks; drop > 0 {
+		for _, chunk := range ix.chunks[:drop] {
+			delete(ix.texts, chunk.Text)
+		}
+		ix.chunks = append(ix.chunks[:0], ix.chunks[drop:]...)
+	}
+}
+
+// Clear forgets every chunk
+func (ix *Index) Clear() {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.chunks = nil
+	ix.texts = make(map[string]bool)
+	ix.dirty = true
+}
+
+// Search returns the chunks most similar to vector, best first. Nothing is
+// returned when the index was built by a different model.
+func (ix *Index) Search(model string, vector []float32, limit int) []Match {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if model != ix.model {
+		return nil
+	}
+	matches := make([]Match, 0, len(ix.chunks))
+	for _, chunk := range ix.chunks {
+		if len(chunk.Vector) != len(vector) {
+			continue
+		}
+		matches = append(matches, Match{Chunk: chunk, Score: cosine(vector, chunk.Vector)})
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+func cosine(a, b []float32) float32 {
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return float32(dot / (math.Sqrt(normA) * math.Sqrt(normB)))
+}
+
+// Save writes the index to disk if it changed since the last save
+func (ix *Index) Save() error {
+	ix.mu.Lock()
+	if !ix.dirty {
+		ix.mu.Unlock()
+		return nil
+	}
+	file := indexFile{Model: ix.model, Chunks: append([]Chunk(nil), ix.chunks...)}
+	ix.dirty = false
+	ix.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(ix.path), 0755); err != nil {
+		return err
+	}
+	tmp := ix.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(f).Encode(file); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, ix.path)
+}
+
+// Split cuts text into chunks of ChunkLines lines, skipping chunks that are
+// blank. Each line goes through redactor first, so secrets are neither sent
+// to the embedding model nor stored in the index.
+func Split(text string, redactor *redact.Redactor) []string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = redactor.Redact(line)
+	}
+	var chunks []string
+	for start := 0; start < len(lines); start += ChunkLines {
+		end := start + ChunkLines
+		if end > len(lines) {
+			end = len(lines)
+		}
+		chunk := strings.TrimSpace(strings.Join(lines[start:end], "\n"))
+		if chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+	}
+	return chunks
+}
@@ -0,0 +1,137 @@
+package session
+
+import (
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/javanhut/RavenTerminal/src/config"
+	"github.com/javanhut/RavenTerminal/src/procmon"
+)
+
+// remoteCommands are process names that mean a pane is talking to another host
+var remoteCommands = map[string]bool{
+	"ssh":         true,
+	"autossh":     true,
+	"mosh-client": true,
+	"et":          true,
+}
+
+// State describes who and where a pane's shell is running
+type State struct {
+	Root   bool // The shell or a program it started runs as root
+	Remote bool // The pane is connected to another host
+}
+
+// Detect classifies a pane from its process tree, the hostname the shell
+// reported with OSC 7 and its window title. A remote root shell can only be
+// seen through its title, which most shells set to "user@host: dir".
+func Detect(procs []procmon.Process, reportedHost, localHost, title string) State {
+	var state State
+	for _, proc := range procs {
+		if proc.UID == 0 {
+			state.Root = true
+		}
+		if remoteCommands[proc.Name] {
+			state.Remote = true
+		}
+	}
+	if strings.HasPrefix(strings.TrimSpace(title), "root@") {
+		state.Root = true
+	}
+	if reportedHost != "" && !SameHost(reportedHost, localHost) {
+		state.Remote = true
+	}
+	return state
+}
+
+// SameHost reports whether two hostnames name the same machine, ignoring case
+// and domain suffixes. "localhost" always matches.
+func SameHost(a, b string) bool {
+	short := func(host string) string {
+		host = strings.ToLower(strings.TrimSpace(host))
+		if i := strings.IndexByte(host, '.'); i > 0 {
+			host = host[:i]
+		}
+		return host
+	}
+	a, b = short(a), short(b)
+	return a == "localhost" || a == b
+}
+
+// MatchHost returns the profile for a remote host. Keys are hostnames or
+// shell patterns such as "prod-*"; an exact key wins, then the first matching
+// pattern in sorted order.
+func MatchHost(profiles map[string]config.HostProfile, host string) (config.HostProfile, bool) {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if host == "" || len(profiles) == 0 {
+		return config.HostProfile{}, false
+	}
+	keys := make([]string, 0, len(profiles))
+	for key, profile := range profiles {
+		if strings.ToLower(key) == host {
+			return profile, true
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if ok, _ := path.Match(strings.ToLower(key), host); ok {
+			return profiles[key], true
+		}
+	}
+	return config.HostProfile{}, false
+}
+
+// MatchDir returns the profile for a working directory. Keys are directories
+// or shell patterns such as "~/work/prod-*", and match the directory itself
+// and everything below it. When several keys match, the longest wins, so a
+// rule for a subdirectory overrides one for its parent.
+func MatchDir(profiles map[string]config.DirProfile, dir string) (config.DirProfile, bool) {
+	dir = path.Clean(strings.TrimSpace(dir))
+	if dir == "." || len(profiles) == 0 {
+		return config.DirProfile{}, false
+	}
+	keys := make([]string, 0, len(profiles))
+	for key := range profiles {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	best := ""
+	for _, key := range keys {
+		pattern := path.Clean(expandHome(key))
+		if len(key) <= len(best) || !dirMatches(pattern, dir) {
+			continue
+		}
+		best = key
+	}
+	if best == "" {
+		return config.DirProfile{}, false
+	}
+	return profiles[best], true
+}
+
+// dirMatches reports whether dir or one of its parents matches pattern
+func dirMatches(pattern, dir string) bool {
+	for {
+		if ok, _ := path.Match(pattern, dir); ok || pattern == dir {
+			return true
+		}
+		parent := path.Dir(dir)
+		if parent == dir {
+			return false
+		}
+		dir = parent
+	}
+}
+
+// expandHome replaces a leading ~ with the user's home directory
+func expandHome(dir string) string {
+	if dir == "~" || strings.HasPrefix(dir, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return home + dir[1:]
+		}
+	}
+	return dir
+}
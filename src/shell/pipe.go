@@ -0,0 +1,143 @@
+package shell
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// pipeBuffer is how many output chunks may wait for a slow pipe consumer
+// before further output is dropped rather than stalling the terminal
+const pipeBuffer = 256
+
+// pipeFlushTimeout bounds how long a closed pipe keeps flushing queued output
+const pipeFlushTimeout = 2 * time.Second
+
+// Pipe streams a copy of a pane's PTY output to a command or a file, like
+// tmux pipe-pane. A target of ">file" truncates the file, ">>file" appends
+// to it, and anything else is run with sh -c and fed on standard input.
+type Pipe struct {
+	Target  string
+	out     io.WriteCloser
+	cmd     *exec.Cmd
+	chunks  chan []byte
+	done    chan struct{}
+	mu      sync.Mutex
+	closed  bool
+	dropped int
+}
+
+// StartPipe opens the pipe target, running commands in dir
+func StartPipe(target, dir string) (*Pipe, error) {
+	target = strings.TrimSpace(target)
+	p := &Pipe{
+		Target: target,
+		chunks: make(chan []byte, pipeBuffer),
+		done:   make(chan struct{}),
+	}
+
+	switch {
+	case strings.HasPrefix(target, ">>"):
+		file, err := os.OpenFile(expandHome(strings.TrimSpace(target[2:])), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return nil, err
+		}
+		p.out = file
+	case strings.HasPrefix(target, ">"):
+		file, err := os.OpenFile(expandHome(strings.TrimSpace(target[1:])), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+		if err != nil {
+			return nil, err
+		}
+		p.out = file
+	default:
+		cmd := exec.Command("sh", "-c", target)
+		cmd.Dir = dir
+		stdin, err := cmd.StdinPipe()
+		if err != nil {
+			return nil, err
+		}
+		if err := cmd.Start(); err != nil {
+			return nil, err
+		}
+		p.cmd = cmd
+		p.out = stdin
+	}
+
+	go p.writeLoop()
+	return p, nil
+}
+
+// writeLoop copies queued output to the target. After a write error, such as
+// the command exiting, output is still drained so Write never blocks.
+func (p *Pipe) writeLoop() {
+	defer close(p.done)
+	failed := false
+	for chunk := range p.chunks {
+		if failed {
+			continue
+		}
+		if _, err := p.out.Write(chunk); err != nil {
+			failed = true
+		}
+	}
+}
+
+// Write queues a copy of data for the target without blocking
+func (p *Pipe) Write(data []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return
+	}
+	select {
+	case p.chunks <- append([]byte(nil), data...):
+	default:
+		p.dropped++
+	}
+}
+
+// Dropped returns how many output chunks were lost because the target fell behind
+func (p *Pipe) Dropped() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.dropped
+}
+
+// Close stops queueing output. Queued output is flushed in the background
+// for up to pipeFlushTimeout, then the target is closed and a command is
+// reaped once it exits.
+func (p *Pipe) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return
+	}
+	p.closed = true
+	close(p.chunks)
+
+	go func() {
+		select {
+		case <-p.done:
+		case <-time.After(pipeFlushTimeout):
+		}
+		// Closing the target also unblocks a write to a command that stopped reading
+		p.out.Close()
+		<-p.done
+		if p.cmd != nil {
+			p.cmd.Wait()
+		}
+	}()
+}
+
+// expandHome replaces a leading ~/ with the user's home directory
+func expandHome(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return home + path[1:]
+		}
+	}
+	return path
+}
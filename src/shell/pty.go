@@ -10,9 +10,14 @@ import (
 	"strings"
 	"sync"
 	"syscall"
+	"unsafe"
 
 	"github.com/creack/pty"
 	"github.com/javanhut/RavenTerminal/src/config"
+	"github.com/javanhut/RavenTerminal/src/ipc"
+	"github.com/javanhut/RavenTerminal/src/logging"
+	"github.com/javanhut/RavenTerminal/src/session"
+	"github.com/javanhut/RavenTerminal/src/version"
 )
 
 // PtySession manages a pseudo-terminal connection to a shell
@@ -22,10 +27,23 @@ type PtySession struct {
 	mu       sync.Mutex
 	exited   bool
 	exitedMu sync.Mutex
+	exitCode int
+	cfg      *config.Config
 }
 
 // NewPtySession creates a new PTY session with a login shell
 func NewPtySession(cols, rows uint16, startDir string) (*PtySession, error) {
+	return startSession(cols, rows, startDir, "")
+}
+
+// NewCommandSession creates a PTY session that runs command with the user's
+// shell -c instead of an interactive shell; the session exits with it
+func NewCommandSession(cols, rows uint16, startDir, command string) (*PtySession, error) {
+	return startSession(cols, rows, startDir, command)
+}
+
+// startSession starts the shell, running command if it is not empty
+func startSession(cols, rows uint16, startDir, command string) (*PtySession, error) {
 	// Load config
 	cfg, err := config.Load()
 	if err != nil {
@@ -56,7 +74,9 @@ func NewPtySession(cols, rows uint16, startDir string) (*PtySession, error) {
 
 	// Build shell command based on config
 	var cmd *exec.Cmd
-	if cfg.Shell.SourceRC {
+	if command != "" {
+		cmd = exec.Command(shell, "-c", command)
+	} else if cfg.Shell.SourceRC {
 		// Source user's rc files - run as interactive login shell
 		switch shellBase {
 		case "bash":
@@ -110,15 +130,23 @@ func NewPtySession(cols, rows uint16, startDir string) (*PtySession, error) {
 	env = replaceEnv(env, "TERM", "xterm-256color")
 	env = replaceEnv(env, "COLORTERM", "truecolor")
 	env = replaceEnv(env, "TERM_PROGRAM", "RavenTerminal")
-	env = replaceEnv(env, "TERM_PROGRAM_VERSION", "1.0")
+	env = replaceEnv(env, "TERM_PROGRAM_VERSION", strings.TrimPrefix(version.Version, "v"))
 	env = replaceEnv(env, "RAVEN_TERMINAL", "1")
+	// Shell integration scripts look for the control socket under the shorter name too
+	if socket := os.Getenv(ipc.SocketEnv); socket != "" {
+		env = replaceEnv(env, "RAVEN_SOCKET", socket)
+	}
 	env = replaceEnv(env, "HOME", currentUser.HomeDir)
 	env = replaceEnv(env, "USER", currentUser.Username)
 	env = replaceEnv(env, "SHELL", shell)
 	env = replaceEnv(env, "COLUMNS", strconv.Itoa(int(cols)))
 	env = replaceEnv(env, "LINES", strconv.Itoa(int(rows)))
-	env = replaceEnv(env, "LANG", "en_US.UTF-8")
-	env = replaceEnv(env, "LC_ALL", "en_US.UTF-8")
+	locale := "en_US.UTF-8"
+	if cfg.Terminal.Latin1 {
+		locale = "en_US.ISO-8859-1"
+	}
+	env = replaceEnv(env, "LANG", locale)
+	env = replaceEnv(env, "LC_ALL", locale)
 	env = replaceEnv(env, "XDG_RUNTIME_DIR", xdgRuntimeDir)
 	env = replaceEnv(env, "LS_COLORS", "rs=0:di=38;5;110:ln=38;5;109:mh=38;5;109:pi=38;5;173:so=38;5;173:do=38;5;173:bd=38;5;180:cd=38;5;180:or=38;5;196:mi=38;5;196:su=38;5;160:sg=38;5;160:tw=38;5;110:ow=38;5;110:st=38;5;150:ex=38;5;114:fi=38;5;253:*.go=38;5;150:*.rs=38;5;179:*.js=38;5;178:*.ts=38;5;178:*.json=38;5;173:*.md=38;5;109:*.txt=38;5;245:*.png=38;5;176:*.jpg=38;5;176:*.jpeg=38;5;176:*.svg=38;5;176:*.zip=38;5;173:*.tar=38;5;173:*.gz=38;5;173:*.mp3=38;5;140:*.mp4=38;5;140")
 
@@ -136,6 +164,17 @@ func NewPtySession(cols, rows uint16, startDir string) (*PtySession, error) {
 		env = replaceEnv(env, k, v)
 	}
 
+	// Add env from the [directories] profile of the start directory
+	profileDir := startDir
+	if profileDir == "" {
+		profileDir = currentUser.HomeDir
+	}
+	if profile, ok := session.MatchDir(cfg.Directories, profileDir); ok {
+		for k, v := range profile.Env {
+			env = replaceEnv(env, k, v)
+		}
+	}
+
 	// For zsh, set up custom init by prepending to .zshrc
 	if shellBase == "zsh" && initScriptPath != "" {
 		// Create a custom ZDOTDIR to source our init script
@@ -144,7 +183,7 @@ func NewPtySession(cols, rows uint16, startDir string) (*PtySession, error) {
 	}
 
 	// For bash without sourcing rc, we need to run the init script
-	if shellBase == "bash" && !cfg.Shell.SourceRC && initScriptPath != "" {
+	if shellBase == "bash" && !cfg.Shell.SourceRC && initScriptPath != "" && command == "" {
 		env = replaceEnv(env, "BASH_ENV", initScriptPath)
 	}
 
@@ -164,13 +203,16 @@ func NewPtySession(cols, rows uint16, startDir string) (*PtySession, error) {
 		Rows: rows,
 	})
 	if err != nil {
+		logging.Errorf(logging.PTY, "Failed to start %s: %v", shell, err)
 		return nil, err
 	}
+	logging.Debugf(logging.PTY, "Started %s (pid %d, %dx%d) in %s", strings.Join(cmd.Args, " "), cmd.Process.Pid, cols, rows, cmd.Dir)
 
 	session := &PtySession{
 		cmd:    cmd,
 		pty:    ptmx,
 		exited: false,
+		cfg:    cfg,
 	}
 
 	// Monitor for process exit
@@ -178,6 +220,7 @@ func NewPtySession(cols, rows uint16, startDir string) (*PtySession, error) {
 		cmd.Wait()
 		session.exitedMu.Lock()
 		session.exited = true
+		session.exitCode = cmd.ProcessState.ExitCode()
 		session.exitedMu.Unlock()
 	}()
 
@@ -194,6 +237,11 @@ func replaceEnv(env []string, key, value string) []string {
 	return append(env, prefix+value)
 }
 
+// Config returns the configuration the session was started with.
+func (p *PtySession) Config() *config.Config {
+	return p.cfg
+}
+
 // CurrentDir returns the process working directory if available.
 func (p *PtySession) CurrentDir() string {
 	if p == nil || p.cmd == nil || p.cmd.Process == nil {
@@ -273,6 +321,61 @@ func (p *PtySession) Resize(cols, rows uint16) error {
 	})
 }
 
+// PID returns the shell process ID, or 0 if the process has not started.
+func (p *PtySession) PID() int {
+	if p == nil || p.cmd == nil || p.cmd.Process == nil {
+		return 0
+	}
+	return p.cmd.Process.Pid
+}
+
+// SecureInput reports whether the foreground program is reading a secret,
+// e.g. a sudo or ssh password prompt. Those turn off ECHO but stay in canonical
+// mode, unlike line editors such as readline which disable both.
+func (p *PtySession) SecureInput() bool {
+	if p == nil || p.pty == nil {
+		return false
+	}
+	conn, err := p.pty.SyscallConn()
+	if err != nil {
+		return false
+	}
+	// Fd() would switch the PTY to blocking mode, so go through the raw conn
+	var t syscall.Termios
+	var errno syscall.Errno
+	if err := conn.Control(func(fd uintptr) {
+		_, _, errno = syscall.Syscall(syscall.SYS_IOCTL, fd, uintptr(ioctlGetTermios), uintptr(unsafe.Pointer(&t)))
+	}); err != nil || errno != 0 {
+		return false
+	}
+	return t.Lflag&syscall.ECHO == 0 && t.Lflag&syscall.ICANON != 0
+}
+
+// ForegroundProcess returns the name of the program in the terminal's
+// foreground, such as "vim" or the shell itself, or "" when it cannot be
+// read. Names come from /proc, where the kernel cuts them to 15 characters.
+func (p *PtySession) ForegroundProcess() string {
+	if p == nil || p.pty == nil {
+		return ""
+	}
+	conn, err := p.pty.SyscallConn()
+	if err != nil {
+		return ""
+	}
+	var pgid int32
+	var errno syscall.Errno
+	if err := conn.Control(func(fd uintptr) {
+		_, _, errno = syscall.Syscall(syscall.SYS_IOCTL, fd, uintptr(syscall.TIOCGPGRP), uintptr(unsafe.Pointer(&pgid)))
+	}); err != nil || errno != 0 || pgid <= 0 {
+		return ""
+	}
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/comm", pgid))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
 // HasExited returns true if the shell process has exited
 func (p *PtySession) HasExited() bool {
 	p.exitedMu.Lock()
@@ -280,6 +383,14 @@ func (p *PtySession) HasExited() bool {
 	return p.exited
 }
 
+// ExitCode returns the shell's exit status once it has exited; -1 means it
+// was killed by a signal
+func (p *PtySession) ExitCode() (int, bool) {
+	p.exitedMu.Lock()
+	defer p.exitedMu.Unlock()
+	return p.exitCode, p.exited
+}
+
 // Close closes the PTY session
 func (p *PtySession) Close() error {
 	p.mu.Lock()
@@ -0,0 +1,5 @@
+package shell
+
+import "syscall"
+
+const ioctlGetTermios = syscall.TCGETS
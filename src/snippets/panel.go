@@ -0,0 +1,237 @@
+package snippets
+
+import "github.com/javanhut/RavenTerminal/src/config"
+
+type Mode int
+
+const (
+	ModeList Mode = iota
+	ModePrompt
+)
+
+// Item is a snippet in the filtered list along with its index in the config
+type Item struct {
+	Index   int
+	Snippet config.Snippet
+}
+
+type Panel struct {
+	Open     bool
+	Focused  bool
+	Mode     Mode
+	Query    string
+	Items    []Item
+	Selected int
+	Scroll   int
+
+	// Placeholder prompting for the chosen snippet
+	Pending config.Snippet
+	Fields  []Placeholder
+	Values  []string
+	Field   int
+}
+
+type Layout struct {
+	PanelX       float32
+	PanelY       float32
+	PanelWidth   float32
+	PanelHeight  float32
+	ContentX     float32
+	ContentWidth float32
+	LineHeight   float32
+	HeaderY      float32
+	InputBoxY    float32
+	ListStart    float32
+	ListEnd      float32
+	FooterY      float32
+	VisibleLines int
+}
+
+func NewPanel() *Panel {
+	return &Panel{}
+}
+
+func (p *Panel) Toggle() {
+	p.Open = !p.Open
+	if p.Open {
+		p.Focused = true
+		p.Mode = ModeList
+		p.Query = ""
+		p.Selected = 0
+		p.Scroll = 0
+	}
+}
+
+// Filter rebuilds the list from all snippets using the current query
+func (p *Panel) Filter(all []config.Snippet) {
+	p.Items = p.Items[:0]
+	for i, snippet := range all {
+		if Matches(snippet.Name, snippet.Description, p.Query) {
+			p.Items = append(p.Items, Item{Index: i, Snippet: snippet})
+		}
+	}
+	p.Selected = 0
+	p.Scroll = 0
+}
+
+// SelectedItem returns the currently selected snippet
+func (p *Panel) SelectedItem() (Item, bool) {
+	if p.Selected < 0 || p.Selected >= len(p.Items) {
+		return Item{}, false
+	}
+	return p.Items[p.Selected], true
+}
+
+// Begin starts inserting a snippet. It returns true when the snippet has no
+// placeholders and can be inserted immediately.
+func (p *Panel) Begin(snippet config.Snippet) bool {
+	p.Pending = snippet
+	p.Fields = Placeholders(snippet.Body)
+	if len(p.Fields) == 0 {
+		return true
+	}
+	p.Values = make([]string, len(p.Fields))
+	for i, field := range p.Fields {
+		p.Values[i] = field.Default
+	}
+	p.Field = 0
+	p.Mode = ModePrompt
+	return false
+}
+
+// NextField advances to the next placeholder. It returns true after the last one.
+func (p *Panel) NextField() bool {
+	if p.Field+1 >= len(p.Fields) {
+		return true
+	}
+	p.Field++
+	return false
+}
+
+// PrevField moves back to the previous placeholder
+func (p *Panel) PrevField() {
+	if p.Field > 0 {
+		p.Field--
+	}
+}
+
+// CancelPrompt abandons placeholder entry and returns to the list
+func (p *Panel) CancelPrompt() {
+	p.Mode = ModeList
+	p.Fields = nil
+	p.Values = nil
+	p.Field = 0
+}
+
+// Result returns the pending snippet with the entered placeholder values
+func (p *Panel) Result() string {
+	values := make(map[string]string, len(p.Fields))
+	for i, field := range p.Fields {
+		values[field.Name] = p.Values[i]
+	}
+	return Expand(p.Pending.Body, values)
+}
+
+// AppendRune types into the active placeholder or the filter query
+func (p *Panel) AppendRune(r rune) {
+	if p.Mode == ModePrompt {
+		p.Values[p.Field] += string(r)
+		return
+	}
+	p.Query += string(r)
+}
+
+// Backspace deletes the last rune of the active placeholder or the filter query
+func (p *Panel) Backspace() {
+	target := &p.Query
+	if p.Mode == ModePrompt {
+		target = &p.Values[p.Field]
+	}
+	runes := []rune(*target)
+	if len(runes) == 0 {
+		return
+	}
+	*target = string(runes[:len(runes)-1])
+}
+
+func (p *Panel) MoveSelection(delta int, visibleLines int) {
+	if len(p.Items) == 0 {
+		return
+	}
+	p.Selected += delta
+	if p.Selected < 0 {
+		p.Selected = 0
+	}
+	if p.Selected >= len(p.Items) {
+		p.Selected = len(p.Items) - 1
+	}
+	if visibleLines <= 0 {
+		return
+	}
+	if p.Selected < p.Scroll {
+		p.Scroll = p.Selected
+	}
+	if p.Selected >= p.Scroll+visibleLines {
+		p.Scroll = p.Selected - visibleLines + 1
+	}
+}
+
+func (p *Panel) Layout(width, height int, cellWidth, cellHeight float32) Layout {
+	panelWidth := float32(width) * 0.5
+	minPanelWidth := float32(420)
+	if cellWidth > 0 {
+		wideMin := cellWidth * 48
+		if wideMin > minPanelWidth {
+			minPanelWidth = wideMin
+		}
+	}
+	if panelWidth < minPanelWidth {
+		panelWidth = minPanelWidth
+	}
+	if panelWidth > 820 {
+		panelWidth = 820
+	}
+	maxWidth := float32(width) - 20
+	if panelWidth > maxWidth {
+		panelWidth = maxWidth
+	}
+
+	lineHeight := cellHeight * 1.35
+	panelHeight := lineHeight * 16
+	if panelHeight > float32(height)-40 {
+		panelHeight = float32(height) - 40
+	}
+
+	// Centered near the top like a command palette
+	panelX := (float32(width) - panelWidth) / 2
+	panelY := float32(height) * 0.12
+
+	contentX := panelX + 18
+	contentWidth := panelWidth - 36
+	headerY := panelY + lineHeight*1.2
+	inputBoxY := headerY + lineHeight*0.6
+	listStart := inputBoxY + lineHeight*2.2
+	footerY := panelY + panelHeight - lineHeight*0.6
+	listEnd := footerY - lineHeight*1.2
+
+	visibleLines := int((listEnd - listStart) / lineHeight)
+	if visibleLines < 1 {
+		visibleLines = 1
+	}
+
+	return Layout{
+		PanelX:       panelX,
+		PanelY:       panelY,
+		PanelWidth:   panelWidth,
+		PanelHeight:  panelHeight,
+		ContentX:     contentX,
+		ContentWidth: contentWidth,
+		LineHeight:   lineHeight,
+		HeaderY:      headerY,
+		InputBoxY:    inputBoxY,
+		ListStart:    listStart,
+		ListEnd:      listEnd,
+		FooterY:      footerY,
+		VisibleLines: visibleLines,
+	}
+}
@@ -0,0 +1,53 @@
+package snippets
+
+import (
+	"regexp"
+	"strings"
+)
+
+// placeholderPattern matches ${name} and ${name:default}
+var placeholderPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_-]*)(?::([^}]*))?\}`)
+
+// Placeholder is a value the user is prompted for before a snippet is inserted
+type Placeholder struct {
+	Name    string
+	Default string
+}
+
+// Placeholders returns the distinct placeholders in body, in order of first use.
+// The first default given for a name wins.
+func Placeholders(body string) []Placeholder {
+	var result []Placeholder
+	seen := make(map[string]bool)
+	for _, match := range placeholderPattern.FindAllStringSubmatch(body, -1) {
+		name := match[1]
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		result = append(result, Placeholder{Name: name, Default: match[2]})
+	}
+	return result
+}
+
+// Expand substitutes placeholder values into body. Placeholders without a
+// value fall back to their default.
+func Expand(body string, values map[string]string) string {
+	return placeholderPattern.ReplaceAllStringFunc(body, func(match string) string {
+		parts := placeholderPattern.FindStringSubmatch(match)
+		if value, ok := values[parts[1]]; ok {
+			return value
+		}
+		return parts[2]
+	})
+}
+
+// Matches reports whether a snippet name or description contains query (case-insensitive)
+func Matches(name, description, query string) bool {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return true
+	}
+	return strings.Contains(strings.ToLower(name), query) ||
+		strings.Contains(strings.ToLower(description), query)
+}
@@ -0,0 +1,68 @@
+package startup
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// Flag enables the startup timing report
+const Flag = "--profile-startup"
+
+// Phase is a named step of startup and how long it took
+type Phase struct {
+	Name     string
+	Duration time.Duration
+}
+
+// Profile times the phases of startup. A disabled profile records nothing.
+type Profile struct {
+	enabled  bool
+	start    time.Time
+	last     time.Time
+	phases   []Phase
+	reported bool
+}
+
+// NewProfile starts timing from now
+func NewProfile(enabled bool) *Profile {
+	now := time.Now()
+	return &Profile{enabled: enabled, start: now, last: now}
+}
+
+// Enabled reports whether phases are being recorded
+func (p *Profile) Enabled() bool {
+	return p.enabled
+}
+
+// Mark ends the current phase, naming it
+func (p *Profile) Mark(name string) {
+	if !p.enabled || p.reported {
+		return
+	}
+	now := time.Now()
+	p.phases = append(p.phases, Phase{Name: name, Duration: now.Sub(p.last)})
+	p.last = now
+}
+
+// Report writes each phase and the total once; later calls do nothing
+func (p *Profile) Report(w io.Writer) {
+	if !p.enabled || p.reported {
+		return
+	}
+	p.reported = true
+
+	width := len("total")
+	for _, phase := range p.phases {
+		width = max(width, len(phase.Name))
+	}
+	fmt.Fprintln(w, "startup profile:")
+	for _, phase := range p.phases {
+		fmt.Fprintf(w, "  %-*s %8.1f ms\n", width, phase.Name, milliseconds(phase.Duration))
+	}
+	fmt.Fprintf(w, "  %-*s %8.1f ms\n", width, "total", milliseconds(p.last.Sub(p.start)))
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -1,13 +1,37 @@
 package tab
 
 import (
+	"errors"
+	"github.com/javanhut/RavenTerminal/src/config"
+	"github.com/javanhut/RavenTerminal/src/devserver"
+	"github.com/javanhut/RavenTerminal/src/inspector"
 	"github.com/javanhut/RavenTerminal/src/parser"
+	"github.com/javanhut/RavenTerminal/src/printer"
+	"github.com/javanhut/RavenTerminal/src/replay"
 	"github.com/javanhut/RavenTerminal/src/shell"
 	"sync"
+	"time"
 )
 
-const MaxTabs = 10
-const MaxPanes = 16
+// DefaultMaxTabs and DefaultMaxPanes are the tab and per-tab pane limits
+// used until [terminal] max_tabs and max_panes are applied
+const DefaultMaxTabs = 10
+const DefaultMaxPanes = 16
+
+// TimestampGutterCols is the width of the timestamp gutter: "15:04:05" and a gap
+const TimestampGutterCols = 9
+
+// LineNumberGutterCols is the width of the line number gutter: seven digits and a gap
+const LineNumberGutterCols = 8
+
+// ErrPaneTooSmall is returned when a split would shrink a pane below the minimum grid size.
+var ErrPaneTooSmall = errors.New("pane too small to split")
+
+// ErrTabLimit is returned when a new tab would pass the tab limit.
+var ErrTabLimit = errors.New("tab limit reached")
+
+// ErrPaneLimit is returned when a split would pass the tab's pane limit.
+var ErrPaneLimit = errors.New("pane limit reached")
 
 // SplitDirection indicates how a node is split
 type SplitDirection int
@@ -33,6 +57,11 @@ const (
 	maxSplitRatio = 0.9
 )
 
+const (
+	minPaneFontScale = 0.5
+	maxPaneFontScale = 2.5
+)
+
 // SplitNode represents a node in the split tree
 // It can either be a leaf (containing a Pane) or a container (containing children)
 type SplitNode struct {
@@ -57,10 +86,30 @@ func (n *SplitNode) IsLeaf() bool {
 type Pane struct {
 	Terminal *parser.Terminal
 	pty      *shell.PtySession
+	command  string        // Command run instead of a shell; empty for a shell
+	done     chan struct{} // Closed when readLoop stops reading pty
 	id       int
 	exited   bool
 	exitedMu sync.Mutex
 	readerMu sync.Mutex
+	devURLs  *devserver.Detector
+	pipeMu   sync.Mutex
+	pipe     *shell.Pipe
+
+	inspectMu sync.Mutex
+	inspect   *inspector.Recorder // Decodes output for the escape sequence inspector
+	replay    *replay.Buffer      // Recent output for raven-rewind
+
+	title string // Set when the pane was opened for a custom command
+
+	timestamps  bool // Show when each row arrived in a gutter
+	lineNumbers bool // Show each row's line number in a gutter
+
+	activityMu sync.Mutex
+	lastActive time.Time // Last PTY input or output
+
+	pauseMu sync.Mutex
+	resume  chan struct{} // Closed when output resumes; nil while it flows
 }
 
 // NewPane creates a new terminal pane
@@ -69,25 +118,53 @@ func NewPane(id int, cols, rows uint16, startDir string) (*Pane, error) {
 	if err != nil {
 		return nil, err
 	}
+	return newPane(id, cols, rows, pty), nil
+}
+
+// NewCommandPane creates a pane that runs command instead of an interactive
+// shell and exits when it finishes
+func NewCommandPane(id int, cols, rows uint16, startDir, command string) (*Pane, error) {
+	pty, err := shell.NewCommandSession(cols, rows, startDir, command)
+	if err != nil {
+		return nil, err
+	}
+	pane := newPane(id, cols, rows, pty)
+	pane.command = command
+	return pane, nil
+}
+
+// newPane wraps a started PTY session in a pane and starts reading from it
+func newPane(id int, cols, rows uint16, pty *shell.PtySession) *Pane {
 
 	pane := &Pane{
 		Terminal: parser.NewTerminal(int(cols), int(rows)),
 		pty:      pty,
+		done:     make(chan struct{}),
 		id:       id,
 		exited:   false,
+		devURLs:  devserver.NewDetector(),
+		replay:   replay.NewBuffer(0),
+
+		lastActive: time.Now(),
 	}
 	pane.Terminal.SetResponseWriter(func(data []byte) {
-		_, _ = pty.Write(data)
+		_, _ = pane.pty.Write(data)
 	})
+	pane.ApplyConfig(pty.Config())
+	pane.setEncoding(pty.Config())
+	if cfg := pty.Config(); cfg != nil {
+		pane.lineNumbers = cfg.Appearance.LineNumbers
+	}
 
 	// Start reader goroutine
 	go pane.readLoop()
 
-	return pane, nil
+	return pane
 }
 
 // readLoop continuously reads from the PTY and processes output
 func (p *Pane) readLoop() {
+	defer close(p.done)
 	buf := make([]byte, 4096)
 	for {
 		n, err := p.pty.Read(buf)
@@ -97,19 +174,139 @@ func (p *Pane) readLoop() {
 			p.exitedMu.Unlock()
 			return
 		}
+		p.waitWhilePaused()
 
 		p.readerMu.Lock()
 		p.Terminal.Process(buf[:n])
 		p.readerMu.Unlock()
+		p.replay.Write(buf[:n])
+		p.devURLs.Feed(buf[:n])
+		p.touch()
+
+		p.pipeMu.Lock()
+		if p.pipe != nil {
+			p.pipe.Write(buf[:n])
+		}
+		p.pipeMu.Unlock()
+
+		p.inspectMu.Lock()
+		if p.inspect != nil {
+			p.inspect.Write(buf[:n])
+		}
+		p.inspectMu.Unlock()
+	}
+}
+
+// SetPaused stops or resumes reading the pane's output. While paused the
+// kernel's PTY buffer fills and the program blocks on its next write, like
+// XOFF, so nothing is lost and it carries on where it stopped on resume.
+func (p *Pane) SetPaused(paused bool) {
+	p.pauseMu.Lock()
+	defer p.pauseMu.Unlock()
+	if paused && p.resume == nil {
+		p.resume = make(chan struct{})
+	} else if !paused && p.resume != nil {
+		close(p.resume)
+		p.resume = nil
+	}
+}
+
+// Paused reports whether the pane's output is paused
+func (p *Pane) Paused() bool {
+	p.pauseMu.Lock()
+	defer p.pauseMu.Unlock()
+	return p.resume != nil
+}
+
+// waitWhilePaused blocks the reader until output resumes
+func (p *Pane) waitWhilePaused() {
+	p.pauseMu.Lock()
+	resume := p.resume
+	p.pauseMu.Unlock()
+	if resume != nil {
+		<-resume
+	}
+}
+
+// StartPipe streams a copy of the pane's output to target, replacing any
+// previous pipe. See shell.StartPipe for the target syntax.
+func (p *Pane) StartPipe(target string) error {
+	pipe, err := shell.StartPipe(target, p.CurrentDir())
+	if err != nil {
+		return err
+	}
+	p.pipeMu.Lock()
+	previous := p.pipe
+	p.pipe = pipe
+	p.pipeMu.Unlock()
+	if previous != nil {
+		previous.Close()
+	}
+	return nil
+}
+
+// StopPipe stops streaming the pane's output and returns the pipe that was
+// running, or nil if there was none
+func (p *Pane) StopPipe() *shell.Pipe {
+	p.pipeMu.Lock()
+	pipe := p.pipe
+	p.pipe = nil
+	p.pipeMu.Unlock()
+	if pipe != nil {
+		pipe.Close()
+	}
+	return pipe
+}
+
+// PipeTarget returns where the pane's output is being piped, or ""
+func (p *Pane) PipeTarget() string {
+	p.pipeMu.Lock()
+	defer p.pipeMu.Unlock()
+	if p.pipe == nil {
+		return ""
 	}
+	return p.pipe.Target
+}
+
+// StartInspect begins decoding the pane's output for the inspector and
+// returns the recorder, reusing one that is already running
+func (p *Pane) StartInspect() *inspector.Recorder {
+	p.inspectMu.Lock()
+	defer p.inspectMu.Unlock()
+	if p.inspect == nil {
+		p.inspect = inspector.NewRecorder()
+	}
+	return p.inspect
+}
+
+// StopInspect stops decoding the pane's output
+func (p *Pane) StopInspect() {
+	p.inspectMu.Lock()
+	p.inspect = nil
+	p.inspectMu.Unlock()
 }
 
 // Write writes data to the PTY
 func (p *Pane) Write(data []byte) error {
+	p.touch()
 	_, err := p.pty.Write(data)
 	return err
 }
 
+// touch records PTY activity
+func (p *Pane) touch() {
+	p.activityMu.Lock()
+	p.lastActive = time.Now()
+	p.activityMu.Unlock()
+}
+
+// LastActive returns when the pane last received input or printed output
+func (p *Pane) LastActive() time.Time {
+	p.activityMu.Lock()
+	defer p.activityMu.Unlock()
+	return p.lastActive
+}
+
 // HasExited returns true if the shell has exited
 func (p *Pane) HasExited() bool {
 	p.exitedMu.Lock()
@@ -117,6 +314,69 @@ func (p *Pane) HasExited() bool {
 	return p.exited || p.pty.HasExited()
 }
 
+// ExitCode returns the shell's exit status once it has exited and all of its
+// output has been read
+func (p *Pane) ExitCode() (int, bool) {
+	p.exitedMu.Lock()
+	drained := p.exited
+	p.exitedMu.Unlock()
+	code, exited := p.pty.ExitCode()
+	return code, drained && exited
+}
+
+// Respawn kills the pane's shell, or the command it was started with, and
+// starts it again in the directory it was last in, keeping the screen and
+// scrollback. A shell that has already exited is simply started again.
+func (p *Pane) Respawn() error {
+	grid := p.Terminal.GetGrid()
+	cols, rows, dir := uint16(grid.Cols), uint16(grid.Rows), p.CurrentDir()
+	var pty *shell.PtySession
+	var err error
+	if p.command != "" {
+		pty, err = shell.NewCommandSession(cols, rows, dir, p.command)
+	} else {
+		pty, err = shell.NewPtySession(cols, rows, dir)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Let the old reader finish so it can't mark the new session exited
+	p.SetPaused(false)
+	p.pty.Close()
+	<-p.done
+
+	p.pty = pty
+	p.done = make(chan struct{})
+	p.exitedMu.Lock()
+	p.exited = false
+	p.exitedMu.Unlock()
+	p.ApplyConfig(pty.Config())
+	p.setEncoding(pty.Config())
+	p.touch()
+
+	go p.readLoop()
+	return nil
+}
+
+// SetTitle names the pane in its corner and in its tab's label
+func (p *Pane) SetTitle(title string) {
+	p.title = title
+}
+
+// Title returns the pane's title, or "" if it has none
+func (p *Pane) Title() string {
+	if p == nil {
+		return ""
+	}
+	return p.title
+}
+
+// Command returns the command the pane was started with, or "" for a shell
+func (p *Pane) Command() string {
+	return p.command
+}
+
 // Resize resizes the pane
 func (p *Pane) Resize(cols, rows uint16) {
 	p.readerMu.Lock()
@@ -125,8 +385,100 @@ func (p *Pane) Resize(cols, rows uint16) {
 	p.pty.Resize(cols, rows)
 }
 
+// setEncoding decodes the pane's output as Latin-1 when the shell was
+// started with a Latin-1 locale. The locale is fixed when the shell starts,
+// so later changes to the setting only reach new panes.
+func (p *Pane) setEncoding(cfg *config.Config) {
+	p.Terminal.SetLatin1(cfg != nil && cfg.Terminal.Latin1)
+}
+
+// ApplyConfig applies terminal emulation settings to the pane.
+func (p *Pane) ApplyConfig(cfg *config.Config) {
+	if cfg == nil {
+		return
+	}
+	p.Terminal.SetAltScreenCapture(cfg.Terminal.AltScreenCapture)
+	p.Terminal.SetAltScreenMark(cfg.Terminal.AltScreenMark)
+	p.replay.SetLimit(cfg.Terminal.ReplayBufferKB << 10)
+	if cfg.Terminal.PrintFile != "" {
+		p.Terminal.SetPrinter(printer.AppendTo(cfg.Terminal.PrintFile))
+	} else {
+		p.Terminal.SetPrinter(nil)
+	}
+}
+
+// Replay returns the pane's recent output, oldest first, and whether older
+// output was dropped
+func (p *Pane) Replay() ([]replay.Frame, bool) {
+	return p.replay.Frames()
+}
+
+// DevServerURLs returns local dev-server URLs detected in the pane output
+func (p *Pane) DevServerURLs() []string {
+	return p.devURLs.URLs()
+}
+
+// DevServerVersion changes whenever a new dev-server URL is detected
+func (p *Pane) DevServerVersion() int {
+	return p.devURLs.Version()
+}
+
+// PID returns the process ID of the pane's shell
+func (p *Pane) PID() int {
+	if p == nil || p.pty == nil {
+		return 0
+	}
+	return p.pty.PID()
+}
+
+// SecureInput reports whether the pane's foreground program is reading a password
+func (p *Pane) SecureInput() bool {
+	if p == nil || p.pty == nil {
+		return false
+	}
+	return p.pty.SecureInput()
+}
+
+// ForegroundProcess returns the name of the program in the foreground of the
+// pane's terminal, or "" when it is not known
+func (p *Pane) ForegroundProcess() string {
+	if p == nil || p.pty == nil {
+		return ""
+	}
+	return p.pty.ForegroundProcess()
+}
+
+// FontScale returns the pane's font scale relative to the renderer font
+func (p *Pane) FontScale() float32 {
+	return p.Terminal.GetGrid().FontScale()
+}
+
+// Timestamps reports whether the pane shows the timestamp gutter
+func (p *Pane) Timestamps() bool {
+	return p.timestamps
+}
+
+// LineNumbers reports whether the pane shows the line number gutter
+func (p *Pane) LineNumbers() bool {
+	return p.lineNumbers
+}
+
+// GutterCols returns how many columns the pane's gutters take beside its grid
+func (p *Pane) GutterCols() int {
+	cols := 0
+	if p.lineNumbers {
+		cols += LineNumberGutterCols
+	}
+	if p.timestamps {
+		cols += TimestampGutterCols
+	}
+	return cols
+}
+
 // Close closes the pane
 func (p *Pane) Close() {
+	p.SetPaused(false)
+	p.StopPipe()
 	p.pty.Close()
 }
 
@@ -168,6 +520,11 @@ type Tab struct {
 	nextPaneID int
 	cols       uint16
 	rows       uint16
+	minCols    uint16
+	minRows    uint16
+	gitLabel   string
+	group      string // Name of the tab group it belongs to; empty for none
+	maxPanes   int    // Most panes the tab may hold; 0 for no limit
 	mu         sync.Mutex
 }
 
@@ -193,6 +550,11 @@ func NewTab(id int, cols, rows uint16, startDir string) (*Tab, error) {
 		nextPaneID: 2,
 		cols:       cols,
 		rows:       rows,
+		maxPanes:   DefaultMaxPanes,
+	}
+	// Leave room for a gutter the pane starts with
+	if pane.GutterCols() > 0 {
+		tab.resizeNode(tab.root, 0, 0, 1.0, 1.0)
 	}
 
 	return tab, nil
@@ -217,16 +579,21 @@ func countPanesInNode(node *SplitNode) int {
 	return count
 }
 
+// full reports whether the tab has as many panes as its limit allows
+func (t *Tab) full() bool {
+	return t.maxPanes > 0 && t.countPanes() >= t.maxPanes
+}
+
 // SplitVertical splits the current pane vertically (side by side)
 func (t *Tab) SplitVertical() error {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
-	if t.countPanes() >= MaxPanes {
-		return nil
+	if t.full() {
+		return ErrPaneLimit
 	}
 
-	return t.splitActivePane(SplitVertical)
+	return t.splitActivePane(SplitVertical, "")
 }
 
 // SplitHorizontal splits the current pane horizontally (stacked)
@@ -234,22 +601,47 @@ func (t *Tab) SplitHorizontal() error {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
-	if t.countPanes() >= MaxPanes {
-		return nil
+	if t.full() {
+		return ErrPaneLimit
 	}
 
-	return t.splitActivePane(SplitHorizontal)
+	return t.splitActivePane(SplitHorizontal, "")
+}
+
+// SplitRun splits the current pane side by side and runs command in the new
+// pane instead of a shell
+func (t *Tab) SplitRun(command string) (*Pane, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.full() {
+		return nil, ErrPaneLimit
+	}
+	if err := t.splitActivePane(SplitVertical, command); err != nil {
+		return nil, err
+	}
+	return t.activeNode.Pane, nil
 }
 
-// splitActivePane splits the active pane in the given direction
-func (t *Tab) splitActivePane(dir SplitDirection) error {
+// splitActivePane splits the active pane in the given direction, running
+// command in the new pane when it is not empty
+func (t *Tab) splitActivePane(dir SplitDirection, command string) error {
 	if t.activeNode == nil || !t.activeNode.IsLeaf() {
 		return nil
 	}
+	if !t.canSplit(dir) {
+		return ErrPaneTooSmall
+	}
 
 	// Create new pane
 	startDir := t.activeNode.Pane.CurrentDir()
-	newPane, err := NewPane(t.nextPaneID, t.cols/2, t.rows/2, startDir)
+	var newPane *Pane
+	var err error
+	if command != "" {
+		newPane, err = NewCommandPane(t.nextPaneID, t.cols/2, t.rows/2, startDir, command)
+	} else {
+		newPane, err = NewPane(t.nextPaneID, t.cols/2, t.rows/2, startDir)
+	}
 	if err != nil {
 		return err
 	}
@@ -300,31 +692,49 @@ func (t *Tab) ClosePane() {
 	if t.activeNode == nil || !t.activeNode.IsLeaf() {
 		return
 	}
+	t.removeLeaf(t.activeNode)
+}
+
+// RemovePane closes a pane of the tab, keeping the focus where it was unless
+// the pane had it. The last pane of a tab is never removed.
+func (t *Tab) RemovePane(pane *Pane) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var node *SplitNode
+	t.findNodeForPane(t.root, pane, &node)
+	if node == nil {
+		return false
+	}
+	return t.removeLeaf(node)
+}
 
+// removeLeaf closes a leaf's pane and gives its space to its sibling (internal, no lock)
+func (t *Tab) removeLeaf(node *SplitNode) bool {
 	// Don't close the last pane
 	if t.countPanes() <= 1 {
-		return
+		return false
 	}
 
-	parent := t.activeNode.Parent
+	parent := node.Parent
 	if parent == nil {
-		return // Can't close root
+		return false // Can't close root
 	}
 
 	// Close the pane
-	t.activeNode.Pane.Close()
+	node.Pane.Close()
 
 	// Find sibling
 	var sibling *SplitNode
 	for _, child := range parent.Children {
-		if child != t.activeNode {
+		if child != node {
 			sibling = child
 			break
 		}
 	}
 
 	if sibling == nil {
-		return
+		return false
 	}
 
 	// Replace parent with sibling
@@ -345,11 +755,14 @@ func (t *Tab) ClosePane() {
 	}
 
 	// Set active to sibling (or first leaf in sibling if it's a container)
-	t.activeNode = t.findFirstLeaf(sibling)
-	t.updateTerminalRef()
+	if t.activeNode == node {
+		t.activeNode = t.findFirstLeaf(sibling)
+		t.updateTerminalRef()
+	}
 
 	// Recalculate sizes
 	t.resizeNode(t.root, 0, 0, 1.0, 1.0)
+	return true
 }
 
 // findFirstLeaf finds the first leaf node in a subtree
@@ -481,7 +894,12 @@ func (t *Tab) ResizeActivePane(direction ResizeDirection, delta float64) bool {
 			if ratio == parent.Ratio {
 				return false
 			}
+			oldRatio := parent.Ratio
 			parent.Ratio = ratio
+			if !t.fitsMinSize() {
+				parent.Ratio = oldRatio
+				return false
+			}
 			t.resizeNode(t.root, 0, 0, 1.0, 1.0)
 			return true
 		}
@@ -491,6 +909,124 @@ func (t *Tab) ResizeActivePane(direction ResizeDirection, delta float64) bool {
 	return false
 }
 
+// ZoomActivePane adjusts the font scale of the active pane by delta and
+// recalculates pane sizes. A zero delta resets the pane to the default scale.
+func (t *Tab) ZoomActivePane(delta float32) float32 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.activeNode == nil || t.activeNode.Pane == nil {
+		return 1.0
+	}
+
+	g := t.activeNode.Pane.Terminal.GetGrid()
+	scale := float32(1.0)
+	if delta != 0 {
+		scale = g.FontScale() + delta
+		if scale < minPaneFontScale {
+			scale = minPaneFontScale
+		}
+		if scale > maxPaneFontScale {
+			scale = maxPaneFontScale
+		}
+	}
+	g.SetFontScale(scale)
+	t.resizeNode(t.root, 0, 0, 1.0, 1.0)
+	return scale
+}
+
+// ToggleTimestamps shows or hides the active pane's timestamp gutter and
+// recalculates pane sizes. It returns whether the gutter is now shown.
+func (t *Tab) ToggleTimestamps() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.activeNode == nil || t.activeNode.Pane == nil {
+		return false
+	}
+	pane := t.activeNode.Pane
+	pane.timestamps = !pane.timestamps
+	t.resizeNode(t.root, 0, 0, 1.0, 1.0)
+	return pane.timestamps
+}
+
+// ToggleLineNumbers shows or hides the active pane's line number gutter and
+// recalculates pane sizes. It returns whether the gutter is now shown.
+func (t *Tab) ToggleLineNumbers() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.activeNode == nil || t.activeNode.Pane == nil {
+		return false
+	}
+	pane := t.activeNode.Pane
+	pane.lineNumbers = !pane.lineNumbers
+	t.resizeNode(t.root, 0, 0, 1.0, 1.0)
+	return pane.lineNumbers
+}
+
+// paneGridSize returns the grid size for a pane occupying the given fraction
+// of the tab, shrinking or growing with the pane zoom and leaving room for
+// the gutters.
+func (t *Tab) paneGridSize(pane *Pane, width, height float32) (uint16, uint16) {
+	scale := pane.FontScale()
+	cols := int(float32(t.cols) * width / scale)
+	cols = max(cols-pane.GutterCols(), 0)
+	rows := uint16(float32(t.rows) * height / scale)
+	return uint16(cols), rows
+}
+
+// fitsMinSize reports whether every pane in the layout meets the minimum grid size.
+func (t *Tab) fitsMinSize() bool {
+	var layouts []PaneLayout
+	t.collectLayouts(t.root, 0, 0, 1.0, 1.0, &layouts)
+	for _, layout := range layouts {
+		cols, rows := t.paneGridSize(layout.Pane, layout.Width, layout.Height)
+		if cols < t.minCols || rows < t.minRows {
+			return false
+		}
+	}
+	return true
+}
+
+// canSplit reports whether halving the active pane keeps it above the minimum grid size.
+func (t *Tab) canSplit(dir SplitDirection) bool {
+	var layouts []PaneLayout
+	t.collectLayouts(t.root, 0, 0, 1.0, 1.0, &layouts)
+	for _, layout := range layouts {
+		if layout.Pane != t.activeNode.Pane {
+			continue
+		}
+		width, height := layout.Width, layout.Height
+		if dir == SplitVertical {
+			width /= 2
+		} else {
+			height /= 2
+		}
+		cols, rows := t.paneGridSize(layout.Pane, width, height)
+		return cols >= t.minCols && rows >= t.minRows
+	}
+	return true
+}
+
+// SetMinSize sets the minimum grid size for panes in this tab.
+func (t *Tab) SetMinSize(cols, rows uint16) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.minCols = cols
+	t.minRows = rows
+	t.resizeNode(t.root, 0, 0, 1.0, 1.0)
+}
+
+// SetMaxPanes sets the most panes the tab may hold; 0 removes the limit.
+// Panes already open stay open.
+func (t *Tab) SetMaxPanes(n int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.maxPanes = n
+}
+
 // updateTerminalRef updates the Terminal reference to point to active pane
 func (t *Tab) updateTerminalRef() {
 	if t.activeNode != nil && t.activeNode.IsLeaf() && t.activeNode.Pane != nil {
@@ -506,9 +1042,13 @@ func (t *Tab) resizeNode(node *SplitNode, x, y, width, height float32) {
 	}
 
 	if node.IsLeaf() {
-		// Calculate actual pixel dimensions
-		cols := uint16(float32(t.cols) * width)
-		rows := uint16(float32(t.rows) * height)
+		cols, rows := t.paneGridSize(node.Pane, width, height)
+		if cols < t.minCols {
+			cols = t.minCols
+		}
+		if rows < t.minRows {
+			rows = t.minRows
+		}
 		if cols < 1 {
 			cols = 1
 		}
@@ -698,6 +1238,17 @@ func (t *Tab) Write(data []byte) error {
 	return nil
 }
 
+// SecureInput reports whether the active pane is reading a password
+func (t *Tab) SecureInput() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.activeNode != nil && t.activeNode.IsLeaf() {
+		return t.activeNode.Pane.SecureInput()
+	}
+	return false
+}
+
 // HasExited returns true if all panes have exited
 func (t *Tab) HasExited() bool {
 	t.mu.Lock()
@@ -803,6 +1354,17 @@ func (t *Tab) ActivePaneIndex() int {
 	return 0
 }
 
+// PaneIndex returns the position of pane among the tab's panes, or -1 if it
+// is not in this tab
+func (t *Tab) PaneIndex(pane *Pane) int {
+	for i, p := range t.GetPanes() {
+		if p == pane {
+			return i
+		}
+	}
+	return -1
+}
+
 // GetSplitDirection returns the root split direction (for backward compatibility)
 func (t *Tab) GetSplitDirection() SplitDirection {
 	t.mu.Lock()
@@ -825,19 +1387,55 @@ func (t *Tab) ActiveDir() string {
 	return t.activeNode.Pane.CurrentDir()
 }
 
+// SetGitStatus sets the compact git indicator shown for this tab in the tab bar
+func (t *Tab) SetGitStatus(label string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.gitLabel = label
+}
+
+// GitStatus returns the tab's git indicator, or "" outside a repository
+func (t *Tab) GitStatus() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.gitLabel
+}
+
+// Group returns the name of the tab's group, or "" if it is in none
+func (t *Tab) Group() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.group
+}
+
+// TabGroup describes a named group of tabs
+type TabGroup struct {
+	Name      string
+	Tabs      int  // Open tabs in the group
+	Collapsed bool // Shown as one line in the tab bar
+}
+
 // TabManager manages multiple terminal tabs
 type TabManager struct {
 	tabs        []*Tab
 	activeIndex int
+	history     []*Tab          // Tabs in the order they were last active, most recent first
+	collapsed   map[string]bool // Tab groups folded to one line in the tab bar
+	maxTabs     int             // Most tabs that may be open; 0 for no limit
+	maxPanes    int             // Most panes each tab may hold; 0 for no limit
 	cols        uint16
 	rows        uint16
+	minCols     uint16
+	minRows     uint16
 	mu          sync.RWMutex
 }
 
 // NewTabManager creates a new tab manager
 func NewTabManager(cols, rows uint16) (*TabManager, error) {
 	tm := &TabManager{
-		tabs:        make([]*Tab, 0, MaxTabs),
+		tabs:        make([]*Tab, 0, DefaultMaxTabs),
+		maxTabs:     DefaultMaxTabs,
+		maxPanes:    DefaultMaxPanes,
 		activeIndex: 0,
 		cols:        cols,
 		rows:        rows,
@@ -856,8 +1454,8 @@ func (tm *TabManager) NewTab() error {
 	tm.mu.Lock()
 	defer tm.mu.Unlock()
 
-	if len(tm.tabs) >= MaxTabs {
-		return nil // Silently ignore if at max
+	if tm.maxTabs > 0 && len(tm.tabs) >= tm.maxTabs {
+		return ErrTabLimit
 	}
 
 	// New tab ID is based on current tab count + 1
@@ -872,13 +1470,65 @@ func (tm *TabManager) NewTab() error {
 	if err != nil {
 		return err
 	}
+	tab.SetMinSize(tm.minCols, tm.minRows)
+	tab.SetMaxPanes(tm.maxPanes)
 
 	tm.tabs = append(tm.tabs, tab)
-	tm.activeIndex = len(tm.tabs) - 1
+	tm.setActive(len(tm.tabs) - 1)
 
 	return nil
 }
 
+// setActive switches to the tab at index i and moves it to the front of the
+// history
+func (tm *TabManager) setActive(i int) {
+	tm.activeIndex = i
+	tm.forget(tm.tabs[i])
+	tm.history = append([]*Tab{tm.tabs[i]}, tm.history...)
+}
+
+// forget drops t from the history
+func (tm *TabManager) forget(t *Tab) {
+	for i, candidate := range tm.history {
+		if candidate == t {
+			tm.history = append(tm.history[:i], tm.history[i+1:]...)
+			return
+		}
+	}
+}
+
+// recentLocked returns the open tabs, the active one first and the rest in
+// the order they were last active
+func (tm *TabManager) recentLocked() []*Tab {
+	if len(tm.tabs) == 0 {
+		return nil
+	}
+	active := tm.tabs[tm.activeIndex]
+	result := make([]*Tab, 0, len(tm.tabs))
+	result = append(result, active)
+	seen := map[*Tab]bool{active: true}
+	for _, t := range tm.history {
+		if !seen[t] {
+			seen[t] = true
+			result = append(result, t)
+		}
+	}
+	for _, t := range tm.tabs {
+		if !seen[t] {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// RecentTabs returns the open tabs, the active one first and the rest in the
+// order they were last active
+func (tm *TabManager) RecentTabs() []*Tab {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	return tm.recentLocked()
+}
+
 // renumberTabs reassigns sequential IDs to all tabs
 func (tm *TabManager) renumberTabs() {
 	for i, t := range tm.tabs {
@@ -896,6 +1546,7 @@ func (tm *TabManager) CloseCurrentTab() {
 	}
 
 	tm.tabs[tm.activeIndex].Close()
+	tm.forget(tm.tabs[tm.activeIndex])
 	tm.tabs = append(tm.tabs[:tm.activeIndex], tm.tabs[tm.activeIndex+1:]...)
 
 	if tm.activeIndex >= len(tm.tabs) {
@@ -904,6 +1555,32 @@ func (tm *TabManager) CloseCurrentTab() {
 
 	// Renumber remaining tabs to keep IDs sequential
 	tm.renumberTabs()
+	tm.pruneGroupsLocked()
+}
+
+// CloseTab closes t, reporting false if it is the last tab or no longer open
+func (tm *TabManager) CloseTab(t *Tab) bool {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	if len(tm.tabs) <= 1 {
+		return false // Keep at least one tab
+	}
+	for i, candidate := range tm.tabs {
+		if candidate != t {
+			continue
+		}
+		t.Close()
+		tm.forget(t)
+		tm.tabs = append(tm.tabs[:i], tm.tabs[i+1:]...)
+		if tm.activeIndex > i || tm.activeIndex >= len(tm.tabs) {
+			tm.activeIndex--
+		}
+		tm.renumberTabs()
+		tm.pruneGroupsLocked()
+		return true
+	}
+	return false
 }
 
 // NextTab switches to the next tab
@@ -912,7 +1589,7 @@ func (tm *TabManager) NextTab() {
 	defer tm.mu.Unlock()
 
 	if len(tm.tabs) > 1 {
-		tm.activeIndex = (tm.activeIndex + 1) % len(tm.tabs)
+		tm.setActive((tm.activeIndex + 1) % len(tm.tabs))
 	}
 }
 
@@ -922,8 +1599,39 @@ func (tm *TabManager) PrevTab() {
 	defer tm.mu.Unlock()
 
 	if len(tm.tabs) > 1 {
-		tm.activeIndex = (tm.activeIndex - 1 + len(tm.tabs)) % len(tm.tabs)
+		tm.setActive((tm.activeIndex - 1 + len(tm.tabs)) % len(tm.tabs))
+	}
+}
+
+// SelectTab switches to the tab at index i, reporting false if there is none
+func (tm *TabManager) SelectTab(i int) bool {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	if i < 0 || i >= len(tm.tabs) {
+		return false
 	}
+	tm.setActive(i)
+	return true
+}
+
+// LastTab switches back to the tab that was active before the current one,
+// reporting false if there is only one tab
+func (tm *TabManager) LastTab() bool {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	recent := tm.recentLocked()
+	if len(recent) < 2 {
+		return false
+	}
+	for i, candidate := range tm.tabs {
+		if candidate == recent[1] {
+			tm.setActive(i)
+			break
+		}
+	}
+	return true
 }
 
 // ActiveTab returns the currently active tab
@@ -937,6 +1645,153 @@ func (tm *TabManager) ActiveTab() *Tab {
 	return tm.tabs[tm.activeIndex]
 }
 
+// SetActiveTab switches to t, reporting false if it is no longer open
+func (tm *TabManager) SetActiveTab(t *Tab) bool {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	for i, candidate := range tm.tabs {
+		if candidate == t {
+			tm.setActive(i)
+			return true
+		}
+	}
+	return false
+}
+
+// SetGroup puts t in the named tab group, or takes it out of its group when
+// name is empty
+func (tm *TabManager) SetGroup(t *Tab, name string) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	t.mu.Lock()
+	t.group = name
+	t.mu.Unlock()
+	tm.pruneGroupsLocked()
+}
+
+// Groups returns the tab groups in the order their first tabs appear
+func (tm *TabManager) Groups() []TabGroup {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	var groups []TabGroup
+	index := make(map[string]int)
+	for _, t := range tm.tabs {
+		name := t.Group()
+		if name == "" {
+			continue
+		}
+		i, ok := index[name]
+		if !ok {
+			i = len(groups)
+			index[name] = i
+			groups = append(groups, TabGroup{Name: name, Collapsed: tm.collapsed[name]})
+		}
+		groups[i].Tabs++
+	}
+	return groups
+}
+
+// GroupCollapsed reports whether the named group is folded in the tab bar
+func (tm *TabManager) GroupCollapsed(name string) bool {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	return tm.collapsed[name]
+}
+
+// SetGroupCollapsed folds the named group to one line in the tab bar, or
+// unfolds it, reporting false if no tab is in the group
+func (tm *TabManager) SetGroupCollapsed(name string, collapsed bool) bool {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	if !tm.hasGroupLocked(name) {
+		return false
+	}
+	if collapsed {
+		if tm.collapsed == nil {
+			tm.collapsed = make(map[string]bool)
+		}
+		tm.collapsed[name] = true
+	} else {
+		delete(tm.collapsed, name)
+	}
+	return true
+}
+
+// CloseGroup closes every tab in the named group and returns how many it
+// closed. If the group holds every tab, the active one is kept open.
+func (tm *TabManager) CloseGroup(name string) int {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	if name == "" {
+		return 0
+	}
+	active := tm.tabs[tm.activeIndex]
+	var kept, closing []*Tab
+	for _, t := range tm.tabs {
+		if t.Group() == name {
+			closing = append(closing, t)
+		} else {
+			kept = append(kept, t)
+		}
+	}
+	if len(kept) == 0 {
+		for i, t := range closing {
+			if t == active {
+				kept = []*Tab{t}
+				closing = append(closing[:i], closing[i+1:]...)
+				break
+			}
+		}
+	}
+	for _, t := range closing {
+		t.Close()
+		tm.forget(t)
+	}
+	if len(closing) == 0 {
+		return 0
+	}
+
+	tm.tabs = kept
+	// Land on the most recently used tab left open
+	next := 0
+	if len(tm.history) > 0 {
+		for i, t := range tm.tabs {
+			if t == tm.history[0] {
+				next = i
+				break
+			}
+		}
+	}
+	tm.setActive(next)
+	tm.renumberTabs()
+	tm.pruneGroupsLocked()
+	return len(closing)
+}
+
+// hasGroupLocked reports whether any open tab is in the named group
+func (tm *TabManager) hasGroupLocked(name string) bool {
+	for _, t := range tm.tabs {
+		if name != "" && t.Group() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// pruneGroupsLocked forgets the folded state of groups left without tabs
+func (tm *TabManager) pruneGroupsLocked() {
+	for name := range tm.collapsed {
+		if !tm.hasGroupLocked(name) {
+			delete(tm.collapsed, name)
+		}
+	}
+}
+
 // ResizeAll resizes all tabs
 func (tm *TabManager) ResizeAll(cols, rows uint16) {
 	tm.mu.Lock()
@@ -950,6 +1805,39 @@ func (tm *TabManager) ResizeAll(cols, rows uint16) {
 	}
 }
 
+// ApplyConfig applies terminal settings to every pane in every tab
+func (tm *TabManager) ApplyConfig(cfg *config.Config) {
+	if cfg == nil {
+		return
+	}
+
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	tm.minCols = uint16(clampMinSize(cfg.Terminal.MinCols))
+	tm.minRows = uint16(clampMinSize(cfg.Terminal.MinRows))
+	tm.maxTabs = max(cfg.Terminal.MaxTabs, 0)
+	tm.maxPanes = max(cfg.Terminal.MaxPanes, 0)
+	for _, tab := range tm.tabs {
+		tab.SetMinSize(tm.minCols, tm.minRows)
+		tab.SetMaxPanes(tm.maxPanes)
+		for _, pane := range tab.GetPanes() {
+			pane.ApplyConfig(cfg)
+		}
+	}
+}
+
+// clampMinSize keeps a configured minimum dimension within a sane range
+func clampMinSize(value int) int {
+	if value < 1 {
+		return 1
+	}
+	if value > 500 {
+		return 500
+	}
+	return value
+}
+
 // CleanupExited removes exited tabs
 func (tm *TabManager) CleanupExited() {
 	tm.mu.Lock()
@@ -961,6 +1849,7 @@ func (tm *TabManager) CleanupExited() {
 			activeTabs = append(activeTabs, tab)
 		} else {
 			tab.Close()
+			tm.forget(tab)
 		}
 	}
 
@@ -991,6 +1880,20 @@ func (tm *TabManager) AllExited() bool {
 	return true
 }
 
+// MaxTabs returns the most tabs that may be open, or 0 for no limit
+func (tm *TabManager) MaxTabs() int {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	return tm.maxTabs
+}
+
+// MaxPanes returns the most panes each tab may hold, or 0 for no limit
+func (tm *TabManager) MaxPanes() int {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	return tm.maxPanes
+}
+
 // TabCount returns the number of tabs
 func (tm *TabManager) TabCount() int {
 	tm.mu.RLock()
@@ -0,0 +1,183 @@
+package update
+
+import (
+	"strings"
+
+	"github.com/javanhut/RavenTerminal/src/version"
+)
+
+// Panel shows a release's changelog and offers to download it
+type Panel struct {
+	Open        bool
+	Focused     bool
+	Release     Release
+	Available   bool   // The release is newer than the running version
+	Status      string // Check, download or install progress
+	Downloading bool
+	Scroll      int
+
+	wrapped   []string
+	wrapChars int
+}
+
+type Layout struct {
+	PanelX       float32
+	PanelY       float32
+	PanelWidth   float32
+	PanelHeight  float32
+	ContentX     float32
+	ContentWidth float32
+	LineHeight   float32
+	HeaderY      float32
+	StatusY      float32
+	ListStart    float32
+	ListEnd      float32
+	FooterY      float32
+	VisibleLines int
+}
+
+func NewPanel() *Panel {
+	return &Panel{}
+}
+
+func (p *Panel) Toggle() {
+	p.Open = !p.Open
+	if p.Open {
+		p.Focused = true
+	}
+}
+
+// SetRelease shows release, noting whether it is newer than the running version
+func (p *Panel) SetRelease(release Release) {
+	p.Release = release
+	p.Available = Newer(release.Tag, version.Version)
+	p.Scroll = 0
+	p.wrapped = nil
+	p.wrapChars = 0
+	switch {
+	case p.Available:
+		p.Status = "Update available: " + version.Version + " -> " + release.Tag
+	case version.Version == "dev":
+		p.Status = "Development build; latest release is " + release.Tag
+	default:
+		p.Status = "Up to date (" + version.Version + ")"
+	}
+}
+
+// Lines returns the changelog wrapped to maxChars
+func (p *Panel) Lines(maxChars int) []string {
+	if p.wrapChars == maxChars && p.wrapped != nil {
+		return p.wrapped
+	}
+	p.wrapChars = maxChars
+	p.wrapped = []string{}
+	notes := strings.ReplaceAll(p.Release.Notes, "\r\n", "\n")
+	if strings.TrimSpace(notes) == "" {
+		notes = "No changelog was published with this release."
+	}
+	for _, line := range strings.Split(notes, "\n") {
+		p.wrapped = append(p.wrapped, wrap(strings.TrimRight(line, " "), maxChars)...)
+	}
+	return p.wrapped
+}
+
+// ScrollBy moves the changelog by delta lines
+func (p *Panel) ScrollBy(delta, visibleLines int) {
+	maxScroll := len(p.wrapped) - visibleLines
+	if maxScroll < 0 {
+		maxScroll = 0
+	}
+	p.Scroll += delta
+	if p.Scroll > maxScroll {
+		p.Scroll = maxScroll
+	}
+	if p.Scroll < 0 {
+		p.Scroll = 0
+	}
+}
+
+// wrap breaks line at spaces so no piece is longer than maxChars
+func wrap(line string, maxChars int) []string {
+	runes := []rune(line)
+	if maxChars < 1 || len(runes) <= maxChars {
+		return []string{line}
+	}
+	var out []string
+	for len(runes) > maxChars {
+		cut := maxChars
+		for i := maxChars; i > maxChars/2; i-- {
+			if runes[i] == ' ' {
+				cut = i
+				break
+			}
+		}
+		out = append(out, strings.TrimRight(string(runes[:cut]), " "))
+		runes = []rune(strings.TrimLeft(string(runes[cut:]), " "))
+	}
+	if len(runes) > 0 {
+		out = append(out, string(runes))
+	}
+	return out
+}
+
+func (p *Panel) Layout(width, height int, cellWidth, cellHeight float32) Layout {
+	panelWidth := float32(width) * 0.45
+	minPanelWidth := float32(380)
+	if cellWidth > 0 {
+		wideMin := cellWidth * 44
+		if wideMin > minPanelWidth {
+			minPanelWidth = wideMin
+		}
+	}
+	if panelWidth < minPanelWidth {
+		panelWidth = minPanelWidth
+	}
+	if panelWidth > 720 {
+		panelWidth = 720
+	}
+	maxWidth := float32(width) - 20
+	if panelWidth > maxWidth {
+		panelWidth = maxWidth
+	}
+
+	panelHeight := float32(height) - 30
+	if panelHeight < 240 {
+		panelHeight = 240
+	}
+	if panelHeight > float32(height)-20 {
+		panelHeight = float32(height) - 20
+	}
+
+	panelX := (float32(width) - panelWidth) / 2
+	panelY := float32(10)
+
+	lineHeight := cellHeight * 1.35
+	contentX := panelX + 18
+	contentWidth := panelWidth - 36
+	headerY := panelY + lineHeight*1.2
+	statusY := headerY + lineHeight*1.1
+	listStart := statusY + lineHeight*1.5
+	footerY := panelY + panelHeight - lineHeight*0.6
+	listEnd := footerY - lineHeight*1.2
+
+	visibleLines := int((listEnd - listStart) / lineHeight)
+	if visibleLines < 1 {
+		visibleLines = 1
+	}
+
+	return Layout{
+		PanelX:       panelX,
+		PanelY:       panelY,
+		PanelWidth:   panelWidth,
+		PanelHeight:  panelHeight,
+		ContentX:     contentX,
+		ContentWidth: contentWidth,
+		LineHeight:   lineHeight,
+		HeaderY:      headerY,
+		StatusY:      statusY,
+		ListStart:    listStart,
+		ListEnd:      listEnd,
+		FooterY:      footerY,
+		VisibleLines: visibleLines,
+	}
+}
@@ -0,0 +1,378 @@
+package update
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/javanhut/RavenTerminal/src/config"
+	"github.com/javanhut/RavenTerminal/src/netconf"
+	"github.com/javanhut/RavenTerminal/src/version"
+)
+
+// LatestURL is the GitHub API endpoint of the newest release
+const LatestURL = "https://api.github.com/repos/javanhut/RavenTerminal/releases/latest"
+
+// CheckInterval is how long to wait between automatic checks
+const CheckInterval = 24 * time.Hour
+
+// DownloadTimeout is how long fetching a release archive may take
+const DownloadTimeout = 10 * time.Minute
+
+// binaryName is the file taken out of a release archive
+const binaryName = "raven-terminal"
+
+// checksumsName is the release asset listing the SHA-256 of each archive, in
+// the format sha256sum writes
+const checksumsName = "SHA256SUMS"
+
+// Release is a published release
+type Release struct {
+	Tag    string  `json:"tag_name"`
+	Name   string  `json:"name"`
+	Notes  string  `json:"body"` // Changelog, in Markdown
+	URL    string  `json:"html_url"`
+	Assets []Asset `json:"assets"`
+}
+
+// Asset is a file attached to a release
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// Latest fetches the newest release
+func Latest(ctx context.Context) (Release, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, LatestURL, nil)
+	if err != nil {
+		return Release{}, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("User-Agent", "raven-terminal/"+version.Version)
+	resp, err := netconf.Client(0).Do(req)
+	if err != nil {
+		return Release{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Release{}, fmt.Errorf("release check failed: %s", resp.Status)
+	}
+	var release Release
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&release); err != nil {
+		return Release{}, err
+	}
+	if release.Tag == "" {
+		return Release{}, errors.New("release has no tag")
+	}
+	return release, nil
+}
+
+// Newer reports whether tag is a later version than current. Versions are
+// compared number by number, so v1.10.0 is newer than v1.9.2; a current
+// version that is not a release, such as "dev", is never older.
+func Newer(tag, current string) bool {
+	latest, ok := parseVersion(tag)
+	if !ok {
+		return false
+	}
+	running, ok := parseVersion(current)
+	if !ok {
+		return false
+	}
+	for i := 0; i < len(latest) || i < len(running); i++ {
+		var a, b int
+		if i < len(latest) {
+			a = latest[i]
+		}
+		if i < len(running) {
+			b = running[i]
+		}
+		if a != b {
+			return a > b
+		}
+	}
+	return false
+}
+
+// parseVersion reads v1.2.3, ignoring a suffix such as -rc1 or a git describe
+// tail like -4-gabc123
+func parseVersion(version string) ([]int, bool) {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	version, _, _ = strings.Cut(version, "-")
+	if version == "" {
+		return nil, false
+	}
+	var parts []int
+	for _, field := range strings.Split(version, ".") {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, false
+		}
+		parts = append(parts, n)
+	}
+	return parts, true
+}
+
+// AssetFor returns the release archive built for this system, named exactly
+// raven-terminal_<os>_<arch>.tar.gz, such as raven-terminal_linux_amd64.tar.gz
+func AssetFor(release Release) (Asset, bool) {
+	return findAsset(release, binaryName+"_"+runtime.GOOS+"_"+runtime.GOARCH+".tar.gz")
+}
+
+func findAsset(release Release, name string) (Asset, bool) {
+	for _, asset := range release.Assets {
+		if asset.Name == name {
+			return asset, true
+		}
+	}
+	return Asset{}, false
+}
+
+// CanInstall reports whether the running binary can be replaced, which is
+// the case when it was unpacked from an archive into a directory the user
+// owns. Package manager and system-wide installs are left alone.
+func CanInstall() bool {
+	exe, err := executable()
+	if err != nil {
+		return false
+	}
+	return syscall.Access(filepath.Dir(exe), 2 /* W_OK */) == nil
+}
+
+func executable() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(exe)
+}
+
+// stagedPath is where a downloaded binary waits to be installed
+func stagedPath() string {
+	return filepath.Join(config.GetCacheDir(), "update", binaryName)
+}
+
+// Download fetches asset of release, checks it against the SHA-256 the
+// release's SHA256SUMS lists for it, and unpacks its raven-terminal binary
+// into the cache, to be installed by ApplyPending when the terminal exits.
+// A release without SHA256SUMS, or an archive that does not match it, is
+// refused and nothing is staged.
+func Download(ctx context.Context, release Release, asset Asset) error {
+	ctx, cancel := context.WithTimeout(ctx, DownloadTimeout)
+	defer cancel()
+	sums, ok := findAsset(release, checksumsName)
+	if !ok {
+		return fmt.Errorf("release %s has no %s to verify the download against", release.Tag, checksumsName)
+	}
+	want, err := fetchChecksum(ctx, sums, asset.Name)
+	if err != nil {
+		return err
+	}
+
+	archivePath := stagedPath() + ".tar.gz"
+	if err := os.MkdirAll(filepath.Dir(archivePath), 0700); err != nil {
+		return err
+	}
+	defer os.Remove(archivePath)
+	got, err := fetchArchive(ctx, asset, archivePath)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(got, want) {
+		return fmt.Errorf("%s does not match its SHA-256 in %s; not installing it", asset.Name, checksumsName)
+	}
+
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	archive := tar.NewReader(gz)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return fmt.Errorf("%s has no %s binary", asset.Name, binaryName)
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag == tar.TypeReg && filepath.Base(header.Name) == binaryName {
+			return stage(archive)
+		}
+	}
+}
+
+// get starts a GET of url, failing on any status but 200 OK
+func get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "raven-terminal/"+version.Version)
+	resp, err := netconf.Client(0).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("download failed: %s", resp.Status)
+	}
+	return resp, nil
+}
+
+// fetchChecksum returns the SHA-256 the checksums asset lists for name
+func fetchChecksum(ctx context.Context, sums Asset, name string) ([]byte, error) {
+	resp, err := get(ctx, sums.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return parseChecksum(io.LimitReader(resp.Body, 1<<20), name)
+}
+
+// parseChecksum finds name in sha256sum output, where each line is a hex
+// digest, a space, and the file name, marked with * when read as binary
+func parseChecksum(r io.Reader, name string) ([]byte, error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		digest, file, ok := strings.Cut(scanner.Text(), " ")
+		if !ok || strings.TrimPrefix(strings.TrimLeft(file, " "), "*") != name {
+			continue
+		}
+		sum, err := hex.DecodeString(digest)
+		if err != nil || len(sum) != sha256.Size {
+			return nil, fmt.Errorf("%s has a malformed entry for %s", checksumsName, name)
+		}
+		return sum, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("%s lists no checksum for %s", checksumsName, name)
+}
+
+// fetchArchive downloads asset to path and returns its SHA-256
+func fetchArchive(ctx context.Context, asset Asset, path string) ([]byte, error) {
+	resp, err := get(ctx, asset.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(f, hash), resp.Body); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+	return hash.Sum(nil), nil
+}
+
+func stage(r io.Reader) error {
+	path := stagedPath()
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Pending reports whether a downloaded update is waiting to be installed
+func Pending() bool {
+	_, err := os.Stat(stagedPath())
+	return err == nil
+}
+
+// ApplyPending replaces the running binary with a downloaded update, if
+// there is one. The binary is copied next to the executable first so the
+// final rename cannot leave a half-written file behind.
+func ApplyPending() error {
+	staged := stagedPath()
+	src, err := os.Open(staged)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer src.Close()
+	exe, err := executable()
+	if err != nil {
+		return err
+	}
+	next := filepath.Join(filepath.Dir(exe), "."+binaryName+".new")
+	dst, err := os.OpenFile(next, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		os.Remove(next)
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(next)
+		return err
+	}
+	if err := os.Rename(next, exe); err != nil {
+		os.Remove(next)
+		return err
+	}
+	return os.Remove(staged)
+}
+
+// Due reports whether CheckInterval has passed since the last automatic check
+func Due() bool {
+	info, err := os.Stat(checkedPath())
+	return err != nil || time.Since(info.ModTime()) >= CheckInterval
+}
+
+// MarkChecked records that a check was made now
+func MarkChecked() error {
+	path := checkedPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(time.Now().Format(time.RFC3339)+"\n"), 0644)
+}
+
+func checkedPath() string {
+	return filepath.Join(config.GetStateDir(), "update-checked")
+}
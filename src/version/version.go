@@ -0,0 +1,6 @@
+package version
+
+// Version is the running release, set at build time with
+// -ldflags "-X github.com/javanhut/RavenTerminal/src/version.Version=v1.2.3".
+// Builds without it report "dev" and are never offered updates.
+var Version = "dev"
@@ -0,0 +1,135 @@
+package watch
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// PollInterval is how often watched paths are scanned for changes
+const PollInterval = 500 * time.Millisecond
+
+// MaxFiles caps how many files one watcher tracks so a huge tree cannot stall it
+const MaxFiles = 5000
+
+// skipDirs are directories never descended into
+var skipDirs = map[string]bool{
+	".git":         true,
+	".hg":          true,
+	".ivaldi":      true,
+	"node_modules": true,
+	"target":       true,
+	"dist":         true,
+	"build":        true,
+}
+
+// stamp identifies a version of a file
+type stamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// Watcher polls files and directory trees and reports when any file is
+// added, removed or modified. Polling keeps it portable and dependency free.
+type Watcher struct {
+	paths   []string
+	changes chan struct{}
+	stop    chan struct{}
+	once    sync.Once
+}
+
+// Start begins polling paths. Relative paths are resolved against dir.
+func Start(paths []string, dir string) *Watcher {
+	resolved := make([]string, 0, len(paths))
+	for _, path := range paths {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		resolved = append(resolved, filepath.Clean(path))
+	}
+	w := &Watcher{
+		paths:   resolved,
+		changes: make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+	}
+	go w.loop()
+	return w
+}
+
+// Changes receives a value after files change. Changes seen before the
+// previous one was received are merged into it.
+func (w *Watcher) Changes() <-chan struct{} {
+	return w.changes
+}
+
+// Stop ends polling
+func (w *Watcher) Stop() {
+	w.once.Do(func() { close(w.stop) })
+}
+
+func (w *Watcher) loop() {
+	ticker := time.NewTicker(PollInterval)
+	defer ticker.Stop()
+	previous := w.scan()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+		}
+		current := w.scan()
+		if !equal(previous, current) {
+			select {
+			case w.changes <- struct{}{}:
+			default:
+			}
+		}
+		previous = current
+	}
+}
+
+// scan stamps every file under the watched paths
+func (w *Watcher) scan() map[string]stamp {
+	stamps := make(map[string]stamp)
+	for _, root := range w.paths {
+		filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if len(stamps) >= MaxFiles {
+				return filepath.SkipAll
+			}
+			if entry.IsDir() {
+				if path != root && skipDirs[entry.Name()] {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			info, err := entry.Info()
+			if err != nil {
+				return nil
+			}
+			stamps[path] = stamp{modTime: info.ModTime(), size: info.Size()}
+			return nil
+		})
+		// A watched file that does not exist yet shows up once it is created
+		if _, err := os.Stat(root); err != nil {
+			stamps[root] = stamp{}
+		}
+	}
+	return stamps
+}
+
+func equal(a, b map[string]stamp) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for path, s := range a {
+		if other, ok := b[path]; !ok || other != s {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,125 @@
+package websearch
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// DefaultBangs maps the built-in !bang shortcuts to the sites they search.
+// Bangs from the config are added to these and replace same-named ones.
+var DefaultBangs = map[string]string{
+	"go":   "pkg.go.dev",
+	"mdn":  "developer.mozilla.org",
+	"gh":   "github.com",
+	"so":   "stackoverflow.com",
+	"py":   "docs.python.org",
+	"rs":   "docs.rs",
+	"npm":  "npmjs.com",
+	"arch": "wiki.archlinux.org",
+	"man":  "man7.org",
+	"wiki": "en.wikipedia.org",
+}
+
+// Query is a search with its !bang shortcuts and site: filters taken out
+type Query struct {
+	Terms string
+	Sites []string // Domains results must come from; empty allows any
+}
+
+// ParseQuery expands !bang shortcuts into site filters and collects site:
+// filters. A bang or site: filter can appear anywhere in the query. Unknown
+// bangs are an error so a typo is not searched for literally.
+func ParseQuery(query string, bangs map[string]string) (Query, error) {
+	var q Query
+	var terms []string
+	for _, word := range strings.Fields(query) {
+		switch {
+		case len(word) > 1 && word[0] == '!':
+			name := strings.ToLower(word[1:])
+			sites, ok := bangs[name]
+			if !ok {
+				sites, ok = DefaultBangs[name]
+			}
+			if !ok {
+				return Query{}, fmt.Errorf("unknown bang !%s", name)
+			}
+			for _, site := range strings.FieldsFunc(sites, isSiteSeparator) {
+				q.addSite(site)
+			}
+		case strings.HasPrefix(strings.ToLower(word), "site:") && len(word) > len("site:"):
+			q.addSite(word[len("site:"):])
+		default:
+			terms = append(terms, word)
+		}
+	}
+	q.Terms = strings.Join(terms, " ")
+	if q.Terms == "" {
+		return Query{}, fmt.Errorf("nothing to search for")
+	}
+	return q, nil
+}
+
+func isSiteSeparator(r rune) bool {
+	return r == ',' || r == ' '
+}
+
+func (q *Query) addSite(site string) {
+	site = strings.ToLower(strings.Trim(site, "/"))
+	site = strings.TrimPrefix(strings.TrimPrefix(site, "https://"), "http://")
+	if site == "" {
+		return
+	}
+	for _, existing := range q.Sites {
+		if existing == site {
+			return
+		}
+	}
+	q.Sites = append(q.Sites, site)
+}
+
+// String returns the query to send to the provider. Several sites are
+// joined with OR; results are checked against them again by Filter.
+func (q Query) String() string {
+	if len(q.Sites) == 0 {
+		return q.Terms
+	}
+	filters := make([]string, len(q.Sites))
+	for i, site := range q.Sites {
+		filters[i] = "site:" + site
+	}
+	return q.Terms + " " + strings.Join(filters, " OR ")
+}
+
+// Filter keeps the results whose host is one of the query's sites or a subdomain of one
+func (q Query) Filter(results []Result) []Result {
+	if len(q.Sites) == 0 {
+		return results
+	}
+	kept := results[:0]
+	for _, result := range results {
+		if q.allows(result.URL) {
+			kept = append(kept, result)
+		}
+	}
+	return kept
+}
+
+func (q Query) allows(rawURL string) bool {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(parsed.Hostname())
+	for _, site := range q.Sites {
+		// A site may include a path, such as github.com/golang
+		domain, path, _ := strings.Cut(site, "/")
+		if host != domain && !strings.HasSuffix(host, "."+domain) {
+			continue
+		}
+		if path == "" || strings.HasPrefix(strings.TrimPrefix(parsed.Path, "/"), path) {
+			return true
+		}
+	}
+	return false
+}
@@ -11,6 +11,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/javanhut/RavenTerminal/src/logging"
+	"github.com/javanhut/RavenTerminal/src/netconf"
 	"golang.org/x/net/html"
 )
 
@@ -64,11 +66,14 @@ func doWithRetry(ctx context.Context, client *http.Client, req *http.Request) (*
 			req = newReq
 		}
 
+		logging.Debugf(logging.Search, "%s %s (attempt %d)", req.Method, req.URL.Redacted(), attempt+1)
 		resp, err := client.Do(req)
 		if err != nil {
+			logging.Debugf(logging.Search, "%s failed: %v", req.URL.Host, err)
 			lastErr = err
 			continue
 		}
+		logging.Debugf(logging.Search, "%s returned %s", req.URL.Host, resp.Status)
 
 		// Retry on server errors (5xx) or rate limiting (429)
 		if resp.StatusCode >= 500 || resp.StatusCode == 429 {
@@ -110,7 +115,7 @@ func SearchDuckDuckGo(ctx context.Context, query string, maxResults int) ([]Resu
 	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
 	req.Header.Set("Accept-Language", "en-US,en;q=0.5")
 
-	client := &http.Client{Timeout: 10 * time.Second}
+	client := netconf.Client(netconf.SearchTimeout())
 	resp, err := doWithRetry(ctx, client, req)
 	if err != nil {
 		return nil, fmt.Errorf("search request failed: %w", err)
@@ -181,16 +186,14 @@ func FetchText(ctx context.Context, pageURL string, maxChars int, useReaderProxy
 	}
 
 	// Create client that follows redirects
-	client := &http.Client{
-		Timeout: 15 * time.Second,
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			if len(via) >= 10 {
-				return errors.New("too many redirects")
-			}
-			// Update user agent on redirect
-			req.Header.Set("User-Agent", getRandomUserAgent())
-			return nil
-		},
+	client := netconf.Client(netconf.SearchTimeout())
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return errors.New("too many redirects")
+		}
+		// Update user agent on redirect
+		req.Header.Set("User-Agent", getRandomUserAgent())
+		return nil
 	}
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
@@ -601,7 +604,7 @@ func fetchViaReaderProxy(ctx context.Context, pageURL string, maxChars int, prox
 		}
 	}
 
-	client := &http.Client{Timeout: 15 * time.Second}
+	client := netconf.Client(netconf.SearchTimeout())
 	var lastErr error
 
 	for _, base := range proxies {
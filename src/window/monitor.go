@@ -0,0 +1,204 @@
+package window
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/go-gl/glfw/v3.3/glfw"
+
+	"github.com/javanhut/RavenTerminal/src/config"
+	"github.com/javanhut/RavenTerminal/src/logging"
+)
+
+// defaultRefreshRate is assumed for monitors that do not report one
+const defaultRefreshRate = 60
+
+// Placement is the window's position and size in screen coordinates
+type Placement struct {
+	X      int `toml:"x"`
+	Y      int `toml:"y"`
+	Width  int `toml:"width"`
+	Height int `toml:"height"`
+}
+
+// placementFile holds the last windowed placement on each monitor, by monitor name
+type placementFile struct {
+	Last     string               `toml:"last"` // Monitor the window was on when it was last saved
+	Monitors map[string]Placement `toml:"monitors"`
+}
+
+func placementPath() string {
+	return filepath.Join(config.GetStateDir(), "window.toml")
+}
+
+// trackMonitor keeps the refresh interval of the monitor the window is on
+// current as the window moves and monitors are plugged in or changed
+func (w *Window) trackMonitor() {
+	w.updateMonitor()
+	w.glfw.SetPosCallback(func(*glfw.Window, int, int) {
+		w.updateMonitor()
+	})
+	glfw.SetMonitorCallback(func(*glfw.Monitor, glfw.PeripheralEvent) {
+		w.updateMonitor()
+	})
+}
+
+func (w *Window) updateMonitor() {
+	rate := defaultRefreshRate
+	if m := w.Monitor(); m != nil {
+		if mode := m.GetVideoMode(); mode != nil && mode.RefreshRate > 0 {
+			rate = mode.RefreshRate
+		}
+	}
+	w.refreshInterval = time.Second / time.Duration(rate)
+}
+
+// RefreshInterval returns how long one refresh of the window's monitor takes
+func (w *Window) RefreshInterval() time.Duration {
+	if w.refreshInterval <= 0 {
+		return time.Second / defaultRefreshRate
+	}
+	return w.refreshInterval
+}
+
+// Monitor returns the monitor the window is on: its fullscreen monitor, the
+// one under the center of the window, or else the primary monitor
+func (w *Window) Monitor() *glfw.Monitor {
+	if m := w.glfw.GetMonitor(); m != nil {
+		return m
+	}
+	x, y := w.glfw.GetPos()
+	width, height := w.glfw.GetSize()
+	if m := monitorAt(x+width/2, y+height/2); m != nil {
+		return m
+	}
+	return glfw.GetPrimaryMonitor()
+}
+
+// monitorAt returns the monitor showing screen point x, y, or nil
+func monitorAt(x, y int) *glfw.Monitor {
+	for _, m := range glfw.GetMonitors() {
+		mode := m.GetVideoMode()
+		if mode == nil {
+			continue
+		}
+		mx, my := m.GetPos()
+		if x >= mx && x < mx+mode.Width && y >= my && y < my+mode.Height {
+			return m
+		}
+	}
+	return nil
+}
+
+// SetFullscreenMonitor chooses the monitor fullscreen uses: "" or "current"
+// for the one the window is on, "primary", a number counting monitors from
+// 1, or a monitor name such as "DP-1"
+func (w *Window) SetFullscreenMonitor(name string) {
+	w.fullscreenMonitor = strings.TrimSpace(name)
+}
+
+func (w *Window) fullscreenTarget() *glfw.Monitor {
+	switch strings.ToLower(w.fullscreenMonitor) {
+	case "", "current":
+		return w.Monitor()
+	case "primary":
+		return glfw.GetPrimaryMonitor()
+	}
+	monitors := glfw.GetMonitors()
+	if n, err := strconv.Atoi(w.fullscreenMonitor); err == nil && n >= 1 && n <= len(monitors) {
+		return monitors[n-1]
+	}
+	for _, m := range monitors {
+		if strings.EqualFold(m.GetName(), w.fullscreenMonitor) {
+			return m
+		}
+	}
+	logging.Warnf(logging.App, "Fullscreen monitor %q is not connected; using the current one", w.fullscreenMonitor)
+	return w.Monitor()
+}
+
+// MonitorNames lists the connected monitors in the order fullscreen_monitor numbers them
+func MonitorNames() []string {
+	var names []string
+	for _, m := range glfw.GetMonitors() {
+		names = append(names, m.GetName())
+	}
+	return names
+}
+
+// SavePlacement records the window's windowed position and size as its
+// placement on the monitor it is on
+func (w *Window) SavePlacement() error {
+	p := Placement{X: w.savedX, Y: w.savedY, Width: w.savedWidth, Height: w.savedHeight}
+	if !w.isFullscreen {
+		p.X, p.Y = w.glfw.GetPos()
+		p.Width, p.Height = w.glfw.GetSize()
+	}
+	m := monitorAt(p.X+p.Width/2, p.Y+p.Height/2)
+	if m == nil {
+		m = glfw.GetPrimaryMonitor()
+	}
+	if m == nil || p.Width <= 0 || p.Height <= 0 {
+		return nil
+	}
+
+	file := loadPlacements()
+	file.Last = m.GetName()
+	file.Monitors[file.Last] = p
+
+	path := placementPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return toml.NewEncoder(f).Encode(file)
+}
+
+// RestorePlacement puts the window back where it was on the monitor it was
+// last on, or where it last was on the primary monitor when that one is
+// not connected. It reports whether a placement was applied.
+func (w *Window) RestorePlacement() bool {
+	file := loadPlacements()
+	connected := make(map[string]bool)
+	for _, m := range glfw.GetMonitors() {
+		connected[m.GetName()] = true
+	}
+	name := file.Last
+	if !connected[name] {
+		primary := glfw.GetPrimaryMonitor()
+		if primary == nil {
+			return false
+		}
+		name = primary.GetName()
+	}
+	p, ok := file.Monitors[name]
+	if !ok || p.Width <= 0 || p.Height <= 0 {
+		return false
+	}
+	w.glfw.SetSize(p.Width, p.Height)
+	// Only move the window if the saved spot is still on screen
+	if monitorAt(p.X+p.Width/2, p.Y+p.Height/2) != nil {
+		w.glfw.SetPos(p.X, p.Y)
+	}
+	w.updateMonitor()
+	return true
+}
+
+func loadPlacements() placementFile {
+	file := placementFile{}
+	if _, err := toml.DecodeFile(placementPath(), &file); err != nil && !os.IsNotExist(err) {
+		logging.Warnf(logging.App, "Failed to read window placement: %v", err)
+	}
+	if file.Monitors == nil {
+		file.Monitors = make(map[string]Placement)
+	}
+	return file
+}
@@ -3,12 +3,17 @@ package window
 import (
 	"fmt"
 	"image"
+	"os"
 	"runtime"
+	"strings"
+	"time"
 
+	legacygl "github.com/go-gl/gl/v2.1/gl"
 	"github.com/go-gl/gl/v4.1-core/gl"
 	"github.com/go-gl/glfw/v3.3/glfw"
 
 	"github.com/javanhut/RavenTerminal/src/assets"
+	"github.com/javanhut/RavenTerminal/src/logging"
 )
 
 func init() {
@@ -18,9 +23,10 @@ func init() {
 
 // Config holds window configuration
 type Config struct {
-	Width  int
-	Height int
-	Title  string
+	Width    int
+	Height   int
+	Title    string
+	Software bool // Skip OpenGL 4.1 and create the context for the software renderer
 }
 
 // DefaultConfig returns the default window configuration
@@ -38,24 +44,80 @@ type Window struct {
 	width        int
 	height       int
 	config       Config
+	software     bool // Legacy context for the software renderer
 	isFullscreen bool
 	savedX       int
 	savedY       int
 	savedWidth   int
 	savedHeight  int
+
+	fullscreenMonitor string        // Monitor fullscreen uses; see SetFullscreenMonitor
+	refreshInterval   time.Duration // One refresh of the monitor the window is on
+	vsync             string        // Swap interval mode, see SetVSync
 }
 
-// NewWindow creates a new GLFW window with OpenGL context
+// NewWindow creates a new GLFW window with OpenGL context. When OpenGL 4.1
+// core is unavailable it falls back to an OpenGL 2.1 context, then to Mesa's
+// CPU driver, for the software renderer to present frames through.
 func NewWindow(config Config) (*Window, error) {
 	if err := glfw.Init(); err != nil {
 		return nil, fmt.Errorf("failed to initialize GLFW: %w", err)
 	}
 
-	// OpenGL context hints
-	glfw.WindowHint(glfw.ContextVersionMajor, 4)
-	glfw.WindowHint(glfw.ContextVersionMinor, 1)
-	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
-	glfw.WindowHint(glfw.OpenGLForwardCompatible, glfw.True)
+	var window *glfw.Window
+	var err error
+	software := config.Software
+	if !software {
+		window, err = createWindow(config, true)
+		if err != nil {
+			logging.Warnf(logging.Render, "OpenGL 4.1 is unavailable, using the software renderer: %v", err)
+			software = true
+		}
+	}
+	if software {
+		window, err = createWindow(config, false)
+		if err != nil && os.Getenv("LIBGL_ALWAYS_SOFTWARE") == "" {
+			// Mesa's CPU driver works without a GPU or with a broken driver
+			logging.Warnf(logging.Render, "OpenGL 2.1 is unavailable, retrying with LIBGL_ALWAYS_SOFTWARE=1: %v", err)
+			os.Setenv("LIBGL_ALWAYS_SOFTWARE", "1")
+			window, err = createWindow(config, false)
+		}
+		if err != nil {
+			glfw.Terminate()
+			return nil, err
+		}
+	}
+
+	w := &Window{
+		glfw:     window,
+		width:    config.Width,
+		height:   config.Height,
+		config:   config,
+		software: software,
+	}
+
+	w.SetVSync(VSyncOn)
+
+	// Load and set application icon
+	w.loadIcon()
+	w.trackMonitor()
+
+	return w, nil
+}
+
+// createWindow opens a window with an OpenGL 4.1 core context, or a 2.1
+// context when core is false, and loads the matching GL functions
+func createWindow(config Config, core bool) (*glfw.Window, error) {
+	glfw.DefaultWindowHints()
+	if core {
+		glfw.WindowHint(glfw.ContextVersionMajor, 4)
+		glfw.WindowHint(glfw.ContextVersionMinor, 1)
+		glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
+		glfw.WindowHint(glfw.OpenGLForwardCompatible, glfw.True)
+	} else {
+		glfw.WindowHint(glfw.ContextVersionMajor, 2)
+		glfw.WindowHint(glfw.ContextVersionMinor, 1)
+	}
 	glfw.WindowHint(glfw.Resizable, glfw.True)
 	glfw.WindowHint(glfw.DoubleBuffer, glfw.True)
 
@@ -65,37 +127,72 @@ func NewWindow(config Config) (*Window, error) {
 
 	window, err := glfw.CreateWindow(config.Width, config.Height, config.Title, nil, nil)
 	if err != nil {
-		glfw.Terminate()
 		return nil, fmt.Errorf("failed to create window: %w", err)
 	}
 
 	window.MakeContextCurrent()
 
 	// Initialize OpenGL
-	if err := gl.Init(); err != nil {
+	if core {
+		err = gl.Init()
+	} else {
+		err = legacygl.Init()
+	}
+	if err != nil {
 		window.Destroy()
-		glfw.Terminate()
 		return nil, fmt.Errorf("failed to initialize OpenGL: %w", err)
 	}
 
-	// Enable VSync
-	glfw.SwapInterval(1)
-
 	// Enable blending for text rendering
-	gl.Enable(gl.BLEND)
-	gl.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
+	if core {
+		gl.Enable(gl.BLEND)
+		gl.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
+	}
+	return window, nil
+}
 
-	w := &Window{
-		glfw:   window,
-		width:  config.Width,
-		height: config.Height,
-		config: config,
+// Swap interval modes accepted by SetVSync
+const (
+	VSyncOn       = "on"       // Wait for every vertical blank
+	VSyncOff      = "off"      // Swap immediately
+	VSyncAdaptive = "adaptive" // Wait for vertical blanks, but swap immediately when a frame is late
+)
+
+// SetVSync sets how buffer swaps wait for the monitor and returns the mode
+// applied. Adaptive sync needs the driver's swap_control_tear extension and
+// falls back to on without it; unknown modes also mean on.
+func (w *Window) SetVSync(mode string) string {
+	mode = strings.ToLower(strings.TrimSpace(mode))
+	switch mode {
+	case VSyncOff:
+		glfw.SwapInterval(0)
+	case VSyncAdaptive:
+		if glfw.ExtensionSupported("GLX_EXT_swap_control_tear") || glfw.ExtensionSupported("WGL_EXT_swap_control_tear") {
+			glfw.SwapInterval(-1)
+			break
+		}
+		logging.Infof(logging.Render, "Adaptive vsync is not supported by this driver; using vsync")
+		mode = VSyncOn
+		glfw.SwapInterval(1)
+	default:
+		if mode != VSyncOn && mode != "" {
+			logging.Warnf(logging.Render, "Unknown vsync mode %q; using on", mode)
+		}
+		mode = VSyncOn
+		glfw.SwapInterval(1)
 	}
+	w.vsync = mode
+	return mode
+}
 
-	// Load and set application icon
-	w.loadIcon()
+// VSync returns the swap interval mode in use
+func (w *Window) VSync() string {
+	return w.vsync
+}
 
-	return w, nil
+// Software reports whether frames must be drawn by the software renderer
+func (w *Window) Software() bool {
+	return w.software
 }
 
 // GLFW returns the underlying GLFW window
@@ -130,12 +227,21 @@ func (w *Window) SwapBuffers() {
 
 // Clear clears the screen with the given color
 func (w *Window) Clear(r, g, b, a float32) {
+	if w.software {
+		legacygl.ClearColor(r, g, b, a)
+		legacygl.Clear(legacygl.COLOR_BUFFER_BIT)
+		return
+	}
 	gl.ClearColor(r, g, b, a)
 	gl.Clear(gl.COLOR_BUFFER_BIT)
 }
 
 // SetViewport sets the OpenGL viewport
 func (w *Window) SetViewport(width, height int) {
+	if w.software {
+		legacygl.Viewport(0, 0, int32(width), int32(height))
+		return
+	}
 	gl.Viewport(0, 0, int32(width), int32(height))
 }
 
@@ -150,12 +256,16 @@ func (w *Window) ToggleFullscreen() {
 		w.savedX, w.savedY = w.glfw.GetPos()
 		w.savedWidth, w.savedHeight = w.glfw.GetSize()
 
-		// Enter fullscreen on primary monitor
-		monitor := glfw.GetPrimaryMonitor()
+		// Enter fullscreen on the chosen monitor at its own refresh rate
+		monitor := w.fullscreenTarget()
+		if monitor == nil {
+			return
+		}
 		mode := monitor.GetVideoMode()
 		w.glfw.SetMonitor(monitor, 0, 0, mode.Width, mode.Height, mode.RefreshRate)
 		w.isFullscreen = true
 	}
+	w.updateMonitor()
 }
 
 // IsFullscreen returns whether the window is in fullscreen mode
@@ -163,6 +273,32 @@ func (w *Window) IsFullscreen() bool {
 	return w.isFullscreen
 }
 
+// GraphicsInfo describes the OpenGL driver and GLFW build for bug reports.
+// It must be called on the main thread.
+func (w *Window) GraphicsInfo() []string {
+	getString := func(name uint32) string {
+		if w.software {
+			return legacygl.GoStr(legacygl.GetString(name))
+		}
+		return gl.GoStr(gl.GetString(name))
+	}
+	info := []string{
+		"OpenGL vendor: " + getString(gl.VENDOR),
+		"OpenGL renderer: " + getString(gl.RENDERER),
+		"OpenGL version: " + getString(gl.VERSION),
+		"GLSL version: " + getString(gl.SHADING_LANGUAGE_VERSION),
+		"GLFW: " + glfw.GetVersionString(),
+	}
+	if monitor := glfw.GetPrimaryMonitor(); monitor != nil {
+		if mode := monitor.GetVideoMode(); mode != nil {
+			info = append(info, fmt.Sprintf("Primary monitor: %s %dx%d@%dHz", monitor.GetName(), mode.Width, mode.Height, mode.RefreshRate))
+		}
+		scaleX, scaleY := monitor.GetContentScale()
+		info = append(info, fmt.Sprintf("Content scale: %.2fx%.2f", scaleX, scaleY))
+	}
+	return info
+}
+
 // loadIcon attempts to load and set the application icon
 func (w *Window) loadIcon() {
 	icons := assets.LoadMultiSizeIcons()
@@ -188,3 +324,9 @@ func (w *Window) Destroy() {
 func PollEvents() {
 	glfw.PollEvents()
 }
+
+// WaitEventsTimeout sleeps until an event arrives or timeout passes, then
+// processes pending events
+func WaitEventsTimeout(timeout time.Duration) {
+	glfw.WaitEventsTimeout(timeout.Seconds())
+}